@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Viktor25104/cortex/backend/logging"
+	"github.com/Viktor25104/cortex/backend/scanner"
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary      Get scan queue state
+// @Description  Report whether the scan queue is paused, who paused it and when, and how many tasks are waiting. Each scan mode has its own queue and worker pool; pools lists their depth and the pool sizes of the answering node.
+// @Tags         Admin
+// @Produce      json
+// @Success      200  {object}  QueueStatus    "Current queue state. Example: {\"paused\":false,\"depth\":3,\"pools\":[{\"mode\":\"connect\",\"workers\":5,\"depth\":0},{\"mode\":\"syn\",\"workers\":2,\"depth\":1},{\"mode\":\"udp\",\"workers\":2,\"depth\":2}]}"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      403  {object}  ErrorResponse  "The API key lacks administrative rights. Example: {\"error\":\"admin privileges required\"}"
+// @Failure      500  {object}  ErrorResponse  "Queue state could not be read. Example: {\"error\":\"failed to read queue state\"}"
+// @Security     ApiKeyAuth
+// @Router       /admin/queue [get]
+func (s *Server) queueStatusHandler(c *gin.Context) {
+	s.respondQueueStatus(c)
+}
+
+// @Summary      Pause the scan queue
+// @Description  Stop workers from taking new tasks, for maintenance windows and incident freezes. Running tasks finish normally and new submissions are still accepted and queued. Pausing an already paused queue refreshes the pause metadata.
+// @Tags         Admin
+// @Produce      json
+// @Success      200  {object}  QueueStatus    "Queue paused. Example: {\"paused\":true,\"paused_at\":\"2024-01-02T15:04:05Z\",\"paused_by\":\"admin\",\"depth\":3}"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      403  {object}  ErrorResponse  "The API key lacks administrative rights. Example: {\"error\":\"admin privileges required\"}"
+// @Failure      500  {object}  ErrorResponse  "Queue state could not be changed. Example: {\"error\":\"failed to pause queue\"}"
+// @Security     ApiKeyAuth
+// @Router       /admin/queue/pause [post]
+func (s *Server) pauseQueueHandler(c *gin.Context) {
+	principal := principalFrom(c)
+	if err := s.store.PauseQueue(principal.Name); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to pause queue"})
+		return
+	}
+	logging.Logger().Warn("scan queue paused", "by", principal.Name, "client_ip", c.ClientIP())
+	s.respondQueueStatus(c)
+}
+
+// @Summary      Resume the scan queue
+// @Description  Let workers take tasks from the queue again after a pause. Resuming a running queue is a no-op.
+// @Tags         Admin
+// @Produce      json
+// @Success      200  {object}  QueueStatus    "Queue running. Example: {\"paused\":false,\"depth\":3}"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      403  {object}  ErrorResponse  "The API key lacks administrative rights. Example: {\"error\":\"admin privileges required\"}"
+// @Failure      500  {object}  ErrorResponse  "Queue state could not be changed. Example: {\"error\":\"failed to resume queue\"}"
+// @Security     ApiKeyAuth
+// @Router       /admin/queue/resume [post]
+func (s *Server) resumeQueueHandler(c *gin.Context) {
+	principal := principalFrom(c)
+	if err := s.store.ResumeQueue(); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to resume queue"})
+		return
+	}
+	logging.Logger().Info("scan queue resumed", "by", principal.Name, "client_ip", c.ClientIP())
+	s.respondQueueStatus(c)
+}
+
+// @Summary      List worker nodes
+// @Description  List the processes currently taking scans from the queue: API servers running worker pools and standalone workers started with cortex --worker. Every node renews its entry each CORTEX_WORKER_HEARTBEAT; nodes silent for CORTEX_WORKER_STALE_AFTER are dropped.
+// @Tags         Admin
+// @Produce      json
+// @Success      200  {array}   WorkerNode     "Registered nodes ordered by ID. Example: [{\"id\":\"scanner-eu-1\",\"hostname\":\"scanner-eu-1.internal\",\"role\":\"worker\",\"version\":\"v1.4.0\",\"capabilities\":[\"connect\",\"syn\",\"udp\"],\"workers\":{\"connect\":5,\"syn\":2,\"udp\":2},\"started_at\":\"2024-01-02T15:04:05Z\",\"last_seen\":\"2024-01-02T15:10:05Z\"}]"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      403  {object}  ErrorResponse  "The API key lacks administrative rights. Example: {\"error\":\"admin privileges required\"}"
+// @Failure      500  {object}  ErrorResponse  "The registry could not be read. Example: {\"error\":\"failed to list workers\"}"
+// @Security     ApiKeyAuth
+// @Router       /admin/workers [get]
+func (s *Server) listWorkersHandler(c *gin.Context) {
+	nodes, err := s.store.ListWorkerNodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to list workers"})
+		return
+	}
+	c.JSON(http.StatusOK, nodes)
+}
+
+func (s *Server) respondQueueStatus(c *gin.Context) {
+	status, err := s.store.QueueStatus()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to read queue state"})
+		return
+	}
+	for i := range status.Pools {
+		status.Pools[i].Workers = s.pools.Config().Size(scanner.Mode(status.Pools[i].Mode))
+	}
+	c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,318 @@
+package api
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Viktor25104/cortex/backend/scanner"
+)
+
+// Config holds every setting of the API server. It is loaded once at startup
+// by LoadConfig and again on every SIGHUP.
+type Config struct {
+	// WorkerMode runs only the worker pools, without the HTTP API; see
+	// RunWorker. Settings of the API are then neither read nor checked.
+	WorkerMode bool
+	// WorkerID names this node in the worker registry; empty derives it
+	// from the hostname.
+	WorkerID string
+	// Addr is the host:port the API listens on.
+	Addr string
+	// HTTP tunes the timeouts and protocols of the API's HTTP server.
+	HTTP HTTPServerConfig
+	// RedisAddr is the host:port of the Redis server holding all state, or
+	// only the rate limits when StoreBackend is postgres.
+	RedisAddr string
+	// StoreBackend selects where tasks are persisted: redis or postgres.
+	StoreBackend string
+	// PostgresDSN is the connection string of the PostgreSQL store.
+	PostgresDSN string
+	// ProbesFile is the nmap-service-probes file used for service detection.
+	ProbesFile string
+
+	// APIKeys maps bearer tokens to the principals they authenticate.
+	APIKeys map[string]Principal
+	// ClientIdentities maps client certificate identities to principals.
+	ClientIdentities map[string]Principal
+	TLS              TLSConfig
+	TLSEnabled       bool
+	// JWT configures bearer JWTs as an alternative to API keys.
+	JWT        JWTConfig
+	JWTEnabled bool
+
+	// MaxBodyBytes and MaxUploadBytes limit request bodies; the latter
+	// applies to POST /scans/upload only.
+	MaxBodyBytes     int64
+	MaxUploadBytes   int64
+	RateLimit        RateLimitConfig
+	RateLimitEnabled bool
+
+	Blocklist *scanner.Blocklist
+	// GlobalRate is the fleet-wide probes per second; zero disables it.
+	GlobalRate     float64
+	Workers        WorkerConfig
+	Notifier       NotifierConfig
+	StatsD         StatsDConfig
+	Janitor        JanitorConfig
+	JanitorEnabled bool
+}
+
+// LoadConfig reads the server configuration from the environment, which Run
+// has already merged with .env, and from the command line flags in args, which
+// take precedence. Every setting is validated and all problems are reported
+// together, so a misconfigured server refuses to start instead of failing on
+// first use.
+func LoadConfig(args []string) (*Config, error) {
+	cfg := &Config{}
+	fs := flag.NewFlagSet("cortex --server", flag.ContinueOnError)
+	fs.Bool("server", true, "run the API server")
+	fs.BoolVar(&cfg.WorkerMode, "worker", false, "run only the worker pools, without the API")
+	fs.StringVar(&cfg.WorkerID, "worker-id", os.Getenv("CORTEX_WORKER_ID"), "name of this node in the worker registry (env CORTEX_WORKER_ID)")
+	fs.StringVar(&cfg.Addr, "addr", defaultListenAddr(), "listen address (env CORTEX_LISTEN_ADDR, CORTEX_PORT)")
+	fs.StringVar(&cfg.RedisAddr, "redis-addr", getenv("REDIS_ADDR", "localhost:6379"), "Redis address (env REDIS_ADDR)")
+	fs.StringVar(&cfg.ProbesFile, "probes", getenv("CORTEX_PROBES_FILE", "nmap-service-probes"), "service probe definitions (env CORTEX_PROBES_FILE)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	var err error
+
+	if !cfg.WorkerMode {
+		check(checkListenAddr(cfg.Addr))
+	}
+	if _, _, err := net.SplitHostPort(cfg.RedisAddr); err != nil {
+		check(fmt.Errorf("REDIS_ADDR must be host:port: %w", err))
+	}
+	cfg.StoreBackend = strings.ToLower(getenv("STORE_BACKEND", "redis"))
+	cfg.PostgresDSN = os.Getenv("POSTGRES_DSN")
+	switch cfg.StoreBackend {
+	case "redis":
+	case "postgres":
+		if cfg.PostgresDSN == "" {
+			check(fmt.Errorf("STORE_BACKEND=postgres requires POSTGRES_DSN"))
+		}
+	default:
+		check(fmt.Errorf("STORE_BACKEND must be redis or postgres, got %q", cfg.StoreBackend))
+	}
+	if info, err := os.Stat(cfg.ProbesFile); err != nil {
+		check(fmt.Errorf("CORTEX_PROBES_FILE: %w", err))
+	} else if info.IsDir() {
+		check(fmt.Errorf("CORTEX_PROBES_FILE: %s is a directory", cfg.ProbesFile))
+	}
+
+	if !cfg.WorkerMode {
+		cfg.JWT, cfg.JWTEnabled, err = loadJWTConfig()
+		check(err)
+		// With JWT authentication the static keys are optional
+		if apiKey := os.Getenv("CORTEX_API_KEY"); apiKey != "" {
+			cfg.APIKeys, err = loadAPIKeys(apiKey, os.Getenv("CORTEX_ADMIN_API_KEY"), os.Getenv("CORTEX_API_KEYS"))
+			check(err)
+		} else if !cfg.JWTEnabled {
+			check(fmt.Errorf("CORTEX_API_KEY environment variable is required unless CORTEX_JWT_JWKS_URL is set"))
+		} else if os.Getenv("CORTEX_ADMIN_API_KEY") != "" || os.Getenv("CORTEX_API_KEYS") != "" {
+			check(fmt.Errorf("CORTEX_ADMIN_API_KEY and CORTEX_API_KEYS require CORTEX_API_KEY"))
+		}
+		cfg.ClientIdentities, err = loadClientIdentities(os.Getenv("CORTEX_TLS_CLIENT_IDENTITIES"))
+		check(err)
+		cfg.TLS, cfg.TLSEnabled, err = loadTLSConfig()
+		check(err)
+		cfg.HTTP, err = loadHTTPServerConfig()
+		check(err)
+		if len(cfg.ClientIdentities) > 0 && cfg.TLS.ClientCAFile == "" {
+			check(fmt.Errorf("CORTEX_TLS_CLIENT_IDENTITIES requires CORTEX_TLS_CLIENT_CA_FILE"))
+		}
+
+		cfg.MaxBodyBytes, err = getenvInt("CORTEX_MAX_BODY_BYTES", 1<<20)
+		check(err)
+		if err == nil && cfg.MaxBodyBytes <= 0 {
+			check(fmt.Errorf("CORTEX_MAX_BODY_BYTES must be positive"))
+		}
+		cfg.MaxUploadBytes, err = getenvInt("CORTEX_MAX_UPLOAD_BYTES", 16<<20)
+		check(err)
+		if err == nil && cfg.MaxUploadBytes <= 0 {
+			check(fmt.Errorf("CORTEX_MAX_UPLOAD_BYTES must be positive"))
+		}
+		cfg.RateLimit, cfg.RateLimitEnabled, err = loadRateLimitConfig()
+		check(err)
+	}
+
+	cfg.Blocklist, err = scanner.BlocklistFromEnv()
+	check(err)
+	cfg.GlobalRate, err = loadGlobalRate()
+	check(err)
+	cfg.Workers, err = loadWorkerConfig()
+	check(err)
+	cfg.Notifier, err = loadNotifierConfig()
+	check(err)
+	cfg.StatsD, err = loadStatsDConfig()
+	check(err)
+	// Workers need the retention too: the task keys they write expire with it
+	cfg.Janitor, cfg.JanitorEnabled, err = loadJanitorConfig()
+	check(err)
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return cfg, nil
+}
+
+// LogValue describes cfg for the startup log. Credentials are reduced to
+// counts and the webhook URL, whose path often embeds a token, to its host.
+func (cfg *Config) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Bool("worker_mode", cfg.WorkerMode),
+		slog.String("worker_id", cfg.WorkerID),
+		slog.String("addr", cfg.Addr),
+		slog.String("redis_addr", cfg.RedisAddr),
+		slog.String("store_backend", cfg.StoreBackend),
+		slog.String("probes_file", cfg.ProbesFile),
+		slog.Int("api_keys", len(cfg.APIKeys)),
+		slog.Int("client_identities", len(cfg.ClientIdentities)),
+		slog.Bool("jwt", cfg.JWTEnabled),
+		slog.String("jwt_jwks_url", cfg.JWT.JWKSURL),
+		slog.String("jwt_issuer", cfg.JWT.Issuer),
+		slog.Bool("tls", cfg.TLSEnabled),
+		slog.String("tls_client_auth", cfg.TLS.ClientAuth),
+		slog.Bool("tls_self_signed", cfg.TLS.SelfSigned),
+		slog.Duration("read_timeout", cfg.HTTP.ReadTimeout),
+		slog.Duration("write_timeout", cfg.HTTP.WriteTimeout),
+		slog.Duration("idle_timeout", cfg.HTTP.IdleTimeout),
+		slog.Bool("http2", cfg.HTTP.HTTP2),
+		slog.Bool("h2c", cfg.HTTP.H2C),
+		slog.Int64("max_body_bytes", cfg.MaxBodyBytes),
+		slog.Int64("max_upload_bytes", cfg.MaxUploadBytes),
+		slog.Bool("rate_limit", cfg.RateLimitEnabled),
+		slog.Int64("rate_limit_read", cfg.RateLimit.ReadLimit),
+		slog.Int64("rate_limit_write", cfg.RateLimit.WriteLimit),
+		slog.Duration("rate_limit_window", cfg.RateLimit.Window),
+		slog.String("rate_limit_key", cfg.RateLimit.KeyStrategy),
+		slog.Int("blocked_ranges", cfg.Blocklist.Len()),
+		slog.Float64("global_rate", cfg.GlobalRate),
+		slog.Int("workers_connect", cfg.Workers.Connect),
+		slog.Int("workers_syn", cfg.Workers.Syn),
+		slog.Int("workers_udp", cfg.Workers.UDP),
+		slog.Int("max_running_per_key", cfg.Workers.MaxRunningPerKey),
+		slog.Duration("result_reuse_max", cfg.Workers.ResultTTL),
+		slog.Duration("worker_stale_after", cfg.Workers.StaleAfter),
+		slog.String("webhook_host", webhookHost(cfg.Notifier.URL)),
+		slog.Duration("webhook_timeout", cfg.Notifier.Timeout),
+		slog.String("statsd_addr", cfg.StatsD.Addr),
+		slog.Duration("task_retention", cfg.Janitor.Retention),
+		slog.String("task_archive_dir", cfg.Janitor.ArchiveDir),
+	)
+}
+
+// webhookHost returns the host of a webhook URL, or "" when none is set.
+func webhookHost(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
+
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// loadRateLimitConfig reads rate limiter settings from the environment:
+// CORTEX_RATE_LIMIT (requests per window for both tiers), CORTEX_RATE_LIMIT_READ
+// and CORTEX_RATE_LIMIT_WRITE (per-tier overrides, default 300 and 100),
+// CORTEX_RATE_LIMIT_WINDOW (Go duration, default 1m), CORTEX_RATE_LIMIT_KEY
+// (ip, apikey or namespace, default ip) and CORTEX_RATE_LIMIT_ENABLED (default true; set
+// false for trusted internal deployments).
+func loadRateLimitConfig() (RateLimitConfig, bool, error) {
+	cfg := RateLimitConfig{ReadLimit: 300, WriteLimit: 100, Window: time.Minute, KeyStrategy: RateLimitByIP}
+
+	enabled, err := getenvBool("CORTEX_RATE_LIMIT_ENABLED", true)
+	if err != nil {
+		return cfg, false, err
+	}
+
+	shared, err := getenvInt("CORTEX_RATE_LIMIT", 0)
+	if err != nil {
+		return cfg, false, err
+	}
+	if shared > 0 {
+		cfg.ReadLimit, cfg.WriteLimit = shared, shared
+	}
+
+	if cfg.ReadLimit, err = getenvInt("CORTEX_RATE_LIMIT_READ", cfg.ReadLimit); err != nil {
+		return cfg, false, err
+	}
+	if cfg.WriteLimit, err = getenvInt("CORTEX_RATE_LIMIT_WRITE", cfg.WriteLimit); err != nil {
+		return cfg, false, err
+	}
+	if shared < 0 || cfg.ReadLimit <= 0 || cfg.WriteLimit <= 0 {
+		return cfg, false, fmt.Errorf("rate limits must be positive (read=%d, write=%d)", cfg.ReadLimit, cfg.WriteLimit)
+	}
+
+	if cfg.Window, err = getenvDuration("CORTEX_RATE_LIMIT_WINDOW", cfg.Window); err != nil {
+		return cfg, false, err
+	}
+	if cfg.Window < time.Second {
+		return cfg, false, fmt.Errorf("CORTEX_RATE_LIMIT_WINDOW must be at least 1s")
+	}
+
+	cfg.KeyStrategy = strings.ToLower(getenv("CORTEX_RATE_LIMIT_KEY", cfg.KeyStrategy))
+	switch cfg.KeyStrategy {
+	case RateLimitByIP, RateLimitByAPIKey, RateLimitByNamespace:
+	default:
+		return cfg, false, fmt.Errorf("CORTEX_RATE_LIMIT_KEY must be %q, %q or %q", RateLimitByIP, RateLimitByAPIKey, RateLimitByNamespace)
+	}
+
+	return cfg, enabled, nil
+}
+
+func getenvInt(key string, fallback int64) (int64, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer: %w", key, err)
+	}
+	return value, nil
+}
+
+func getenvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration such as 30s or 1m: %w", key, err)
+	}
+	return value, nil
+}
+
+func getenvBool(key string, fallback bool) (bool, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false: %w", key, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+	// Named schedule time zones must resolve on hosts without a zoneinfo
+	// database, such as Windows servers and scratch containers.
+	_ "time/tzdata"
+)
+
+// cronSchedule is a parsed five-field cron expression: minute, hour, day of
+// month, month and day of week, each a bit set of the values it matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny record an unrestricted day field. When both day
+	// fields are restricted a day matching either one is due, as in cron(8).
+	domAny, dowAny bool
+}
+
+// cronField describes the range and value names of one cron field.
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	// 7 is Sunday as well as 0
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// cronMacros are the nicknames cron(8) accepts for common expressions.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronHorizon bounds the search for the next run, so an expression that can
+// never match, such as February 30th, is detected.
+const cronHorizon = 5 * 366 * 24 * time.Hour
+
+// parseCron parses a standard five-field cron expression or one of
+// cronMacros. Fields take *, values, ranges (1-5), lists (1,15) and steps
+// (*/15, 0-30/10); months and days of the week also take three-letter names.
+func parseCron(expr string) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression must have %d fields (minute hour day-of-month month day-of-week) or be a macro such as @daily, got %d", len(cronFields), len(parts))
+	}
+
+	var sets [5]uint64
+	for i, field := range cronFields {
+		set, err := parseCronField(parts[i], field)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+	schedule := &cronSchedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: parts[2] == "*" || parts[2] == "?",
+		dowAny: parts[4] == "*" || parts[4] == "?",
+	}
+	if schedule.next(time.Now().UTC()).IsZero() {
+		return nil, fmt.Errorf("cron expression %q never matches", expr)
+	}
+	return schedule, nil
+}
+
+// parseCronField parses one comma-separated field into the set of values it
+// matches.
+func parseCronField(raw string, field cronField) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(raw, ",") {
+		spec, stepText, stepped := strings.Cut(item, "/")
+		step := 1
+		if stepped {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s field %q", stepText, field.name, raw)
+			}
+			step = n
+		}
+
+		low, high := field.min, field.max
+		switch {
+		case spec == "*" || spec == "?":
+		case strings.Contains(spec, "-"):
+			first, last, _ := strings.Cut(spec, "-")
+			var err error
+			if low, err = cronValue(first, field); err != nil {
+				return 0, err
+			}
+			if high, err = cronValue(last, field); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("range %q in %s field starts after it ends", spec, field.name)
+			}
+		default:
+			value, err := cronValue(spec, field)
+			if err != nil {
+				return 0, err
+			}
+			low = value
+			if !stepped {
+				high = value
+			}
+		}
+		for value := low; value <= high; value += step {
+			set |= 1 << value
+		}
+	}
+	return set, nil
+}
+
+// cronValue parses a number or name of field and checks its range.
+func cronValue(text string, field cronField) (int, error) {
+	for i, name := range field.names {
+		if strings.EqualFold(text, name) {
+			return i + field.min, nil
+		}
+	}
+	value, err := strconv.Atoi(text)
+	if err != nil || value < field.min || value > field.max {
+		return 0, fmt.Errorf("%s field value %q must be %d-%d", field.name, text, field.min, field.max)
+	}
+	return value, nil
+}
+
+// next returns the first minute strictly after after that s matches, in the
+// location of after, or the zero time when there is none within cronHorizon.
+func (s *cronSchedule) next(after time.Time) time.Time {
+	loc := after.Location()
+	t := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := after.Add(cronHorizon)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			// Jump straight to the next matching minute of the hour
+			rest := s.minute >> uint(t.Minute())
+			if rest == 0 {
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			} else {
+				t = t.Add(time.Duration(bits.TrailingZeros64(rest)) * time.Minute)
+			}
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches applies the day of month and day of week fields to t.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
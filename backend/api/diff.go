@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Viktor25104/cortex/backend/scanner"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary      Compare two scans
+// @Description  Compare the results of two completed scans, typically two runs against the same targets. The task in the path is the baseline and otherId the later scan: ports open in otherId but not in the baseline are reported as opened, ports open in the baseline but no longer open as closed, and ports open in both whose service fingerprint, product or version differs as service_changed. Ports otherId did not probe are ignored, so comparing against a narrower scan does not report everything outside its range as closed.
+// @Tags         Scans
+// @Produce      json
+// @Param        id       path      string            true  "Baseline Scan Task ID (UUID v4)"
+// @Param        otherId  path      string            true  "Later Scan Task ID (UUID v4)"
+// @Success      200      {object}  ScanDiffResponse  "Differences between the two scans"
+// @Failure      400      {object}  ErrorResponse     "Malformed task identifier. Example: {\"error\":\"invalid task id format\"}"
+// @Failure      401      {object}  ErrorResponse     "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      404      {object}  ErrorResponse     "Either task does not exist. Example: {\"error\":\"task 5b0e7c1a-9d2f-4e3b-8a6c-2f1d0e9b7a44 not found\"}"
+// @Failure      409      {object}  ErrorResponse     "Either task has not completed. Example: {\"error\":\"task 5b0e7c1a-9d2f-4e3b-8a6c-2f1d0e9b7a44 is running, not completed\"}"
+// @Failure      429      {object}  ErrorResponse     "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500      {object}  ErrorResponse     "Internal error when loading a task. Example: {\"error\":\"failed to load task\"}"
+// @Security     ApiKeyAuth
+// @Router       /scans/{id}/diff/{otherId} [get]
+func (s *Server) diffScansHandler(c *gin.Context) {
+	ids := []string{c.Param("id"), c.Param("otherId")}
+	tasks := make([]*ScanTask, len(ids))
+	for _, id := range ids {
+		if !uuidV4Pattern.MatchString(id) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid task id format"})
+			return
+		}
+	}
+	for i, id := range ids {
+		task, err := s.tasks(c).GetTask(id)
+		if err != nil {
+			if err == ErrTaskNotFound {
+				c.JSON(http.StatusNotFound, ErrorResponse{Error: fmt.Sprintf("task %s not found", id)})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load task"})
+			return
+		}
+		if task.Status != "completed" {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("task %s is %s, not completed", id, task.Status)})
+			return
+		}
+		tasks[i] = task
+	}
+
+	changes := scanner.DiffResults(tasks[0].Results, tasks[1].Results)
+	if changes == nil {
+		changes = []scanner.ResultChange{}
+	}
+	counts := scanner.CountChanges(changes)
+	c.JSON(http.StatusOK, ScanDiffResponse{
+		Baseline:       tasks[0].ID,
+		Current:        tasks[1].ID,
+		Opened:         counts.Opened,
+		Closed:         counts.Closed,
+		ServiceChanged: counts.ServiceChanged,
+		Changes:        changes,
+	})
+}
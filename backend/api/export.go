@@ -0,0 +1,103 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Viktor25104/cortex/backend/logging"
+	"github.com/Viktor25104/cortex/backend/scanner"
+	"github.com/Viktor25104/cortex/backend/version"
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary      Export scan results
+// @Description  Download the results of a finished scan in a format other tools read. format=xml (the default) produces the XML nmap writes with -oX: one host element per scanned host with its address, hostname and up or down status, port elements with state and reason, service elements with the product, version and CPE of identified services, and script elements for raw banners, TLS certificates and check findings. Tools that import nmap XML (Metasploit db_import, vulnerability managers, ndiff-style tooling) can consume it directly.
+// @Description  format=grep produces nmap's greppable -oG format, a Status and a Ports line per host, and format=csv one row per result with a header row (host, address, port, protocol, state, service, product, version, info, tls, cpe, service_guess).
+// @Tags         Scans
+// @Produce      application/xml
+// @Produce      text/plain
+// @Produce      text/csv
+// @Param        id      path      string         true   "Scan Task ID (UUID v4)"
+// @Param        format  query     string         false  "Export format (default xml)" Enums(xml, grep, csv)
+// @Success      200     {string}  string         "The exported results. Example: <nmaprun scanner=\"cortex\" ...><host><status state=\"up\" .../><address addr=\"45.33.32.156\" addrtype=\"ipv4\"/><ports><port protocol=\"tcp\" portid=\"22\"><state state=\"open\" .../><service name=\"ssh\" product=\"OpenSSH\" .../></port></ports></host></nmaprun>"
+// @Failure      400     {object}  ErrorResponse  "Malformed task identifier or unknown format. Example: {\"error\":\"format must be one of: xml grep csv\"}"
+// @Failure      401     {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      404     {object}  ErrorResponse  "Task with the provided ID does not exist. Example: {\"error\":\"task not found\"}"
+// @Failure      409     {object}  ErrorResponse  "The scan has not finished. Example: {\"error\":\"task is running; export it once it finishes\"}"
+// @Failure      429     {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500     {object}  ErrorResponse  "Internal error when loading the task. Example: {\"error\":\"failed to load task\"}"
+// @Security     ApiKeyAuth
+// @Router       /scans/{id}/export [get]
+func (s *Server) exportScanHandler(c *gin.Context) {
+	id := c.Param("id")
+	if !uuidV4Pattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid task id format"})
+		return
+	}
+	format := c.DefaultQuery("format", scanner.ExportXML)
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "format must be one of: " + strings.Join(scanner.ExportFormats, " ")})
+		return
+	}
+	task, err := s.tasks(c).GetTask(id)
+	if err != nil {
+		if err == ErrTaskNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load task"})
+		return
+	}
+	if !taskFinished(task.Status) {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("task is %s; export it once it finishes", task.Status)})
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="cortex-%s.%s"`, task.ID, exportExtensions[format]))
+	c.Status(http.StatusOK)
+	if err := scanner.WriteExport(c.Writer, format, task.Results, exportInfo(task)); err != nil {
+		// Headers are gone already; the client sees a truncated document
+		logging.Logger().Error("scan export aborted", "task_id", id, "error", err)
+	}
+}
+
+// exportContentTypes and exportExtensions give the media type and file name
+// extension of each export format.
+var (
+	exportContentTypes = map[string]string{
+		scanner.ExportXML:       "application/xml; charset=utf-8",
+		scanner.ExportGreppable: "text/plain; charset=utf-8",
+		scanner.ExportCSV:       "text/csv; charset=utf-8",
+	}
+	exportExtensions = map[string]string{
+		scanner.ExportXML:       "xml",
+		scanner.ExportGreppable: "gnmap",
+		scanner.ExportCSV:       "csv",
+	}
+)
+
+// exportInfo describes task for its nmap XML export.
+func exportInfo(task *ScanTask) scanner.ExportInfo {
+	mode := scanner.Mode(task.Mode)
+	info := scanner.ExportInfo{
+		Args:    fmt.Sprintf("cortex scan %s: %s %s %s", task.ID, task.Mode, task.Ports, strings.Join(task.Hosts, " ")),
+		Mode:    mode,
+		Start:   task.CreatedAt,
+		Hosts:   task.HostSummaries,
+		Version: version.Version,
+	}
+	if task.CompletedAt != nil {
+		info.End = *task.CompletedAt
+	}
+	if spec, err := scanner.ParsePortSpec(task.Ports); err == nil {
+		protocol := "tcp"
+		if mode == scanner.ModeUDP {
+			protocol = "udp"
+		}
+		info.Ports = spec.Ports(protocol)
+	}
+	return info
+}
@@ -2,28 +2,96 @@ package api
 
 import (
 	"crypto/rand"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"regexp"
+	"strconv"
 	"time"
 
+	"github.com/Viktor25104/cortex/backend/logging"
+	"github.com/Viktor25104/cortex/backend/scanner"
+	"github.com/Viktor25104/cortex/backend/version"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Server bundles dependencies for HTTP handlers.
 type Server struct {
-	store TaskStore
+	store     TaskStore
+	blocklist *scanner.Blocklist
+	pools     *WorkerPools
 }
 
-// NewServer creates a new API server instance.
-func NewServer(store TaskStore) *Server {
-	return &Server{store: store}
+// NewServer creates a new API server instance. Submissions naming an IP
+// inside blocklist are rejected; hostnames are checked by workers after resolution.
+// pools describes the local pool sizes reported by the admin queue status.
+func NewServer(store TaskStore, blocklist *scanner.Blocklist, pools *WorkerPools) *Server {
+	registerJSONTagNames()
+	return &Server{store: store, blocklist: blocklist, pools: pools}
 }
 
 // RegisterRoutes attaches handlers to the provided Gin router group.
 func (s *Server) RegisterRoutes(routes gin.IRoutes) {
 	routes.POST("/scans", s.createScanHandler)
+	routes.POST("/scans/upload", s.uploadScanHandler)
+	routes.POST("/scans/estimate", s.estimateScanHandler)
+	routes.GET("/scans", s.listScansHandler)
 	routes.GET("/scans/:id", s.getScanHandler)
+	routes.DELETE("/scans/:id", RequireAdmin(), s.deleteScanHandler)
+	routes.DELETE("/scans/:id/results", s.purgeResultsHandler)
+	routes.GET("/scans/:id/results/stream", s.streamResultsHandler)
+	routes.GET("/scans/:id/export", s.exportScanHandler)
+	routes.GET("/scans/:id/diff/:otherId", s.diffScansHandler)
+	routes.GET("/scans/:id/events", s.scanEventsHandler)
+	routes.POST("/scans/:id/pause", s.pauseScanHandler)
+	routes.POST("/scans/:id/resume", s.resumeScanHandler)
+	routes.POST("/scans/:id/cancel", s.cancelScanHandler)
+	routes.GET("/version", s.versionHandler)
+	routes.GET("/stats", s.statsHandler)
+
+	routes.GET("/hosts", s.listHostsHandler)
+	routes.GET("/services", s.listServicesHandler)
+	routes.GET("/inventory", s.listInventoryHandler)
+	routes.GET("/inventory/:host", s.getInventoryHostHandler)
+
+	routes.POST("/monitors", s.createMonitorHandler)
+	routes.GET("/monitors", s.listMonitorsHandler)
+	routes.GET("/monitors/drift", s.driftReportHandler)
+	routes.GET("/monitors/:id", s.getMonitorHandler)
+	routes.DELETE("/monitors/:id", s.deleteMonitorHandler)
+
+	routes.POST("/schedules", s.createScheduleHandler)
+	routes.GET("/schedules", s.listSchedulesHandler)
+	routes.GET("/schedules/:id", s.getScheduleHandler)
+	routes.POST("/schedules/:id/pause", s.pauseScheduleHandler)
+	routes.POST("/schedules/:id/resume", s.resumeScheduleHandler)
+	routes.DELETE("/schedules/:id", s.deleteScheduleHandler)
+
+	routes.POST("/templates", s.createTemplateHandler)
+	routes.GET("/templates", s.listTemplatesHandler)
+	routes.GET("/templates/:id", s.getTemplateHandler)
+	routes.DELETE("/templates/:id", s.deleteTemplateHandler)
+
+	routes.GET("/admin/queue", RequireAdmin(), s.queueStatusHandler)
+	routes.GET("/admin/workers", RequireAdmin(), s.listWorkersHandler)
+	routes.POST("/admin/keys", RequireAdmin(), s.createAPIKeyHandler)
+	routes.GET("/admin/keys", RequireAdmin(), s.listAPIKeysHandler)
+	routes.DELETE("/admin/keys/:id", RequireAdmin(), s.revokeAPIKeyHandler)
+	routes.POST("/admin/queue/pause", RequireAdmin(), s.pauseQueueHandler)
+	routes.POST("/admin/queue/resume", RequireAdmin(), s.resumeQueueHandler)
+}
+
+// tasks returns the store view for the caller's tenant namespace.
+func (s *Server) tasks(c *gin.Context) TaskStore {
+	return s.store.Namespace(principalFrom(c).Namespace)
 }
 
 var uuidV4Pattern = regexp.MustCompile(`^[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-[1-5][a-fA-F0-9]{3}-[abAB89][a-fA-F0-9]{3}-[a-fA-F0-9]{12}$`)
@@ -31,24 +99,49 @@ var uuidV4Pattern = regexp.MustCompile(`^[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-[1-5][a-f
 // @Summary      Create a new scan task
 // @Description  Submit a scan definition and let Cortex execute it asynchronously. The handler validates input, persists the task, and enqueues it for background workers before returning a UUID.
 // @Description  **Lifecycle**: POST /scans immediately answers with HTTP 202 Accepted plus the task identifier. Clients must poll GET /scans/{id} to observe status transitions (pending → running → completed/failed). Actual port findings are attached only after completion.
+// @Description  **Result reuse**: with reuse_within set, ports another scan of the caller probed within that window are not probed again; their results are copied and marked reused. The window is capped by the server's CORTEX_RESULT_REUSE_MAX and rejected when reuse is disabled.
+// @Description  **Sharding**: with shard_size set and more hosts than that, the scan is split into shard tasks of at most shard_size hosts that are queued separately, so several workers scan in parallel. The returned task lists them in shards and carries the combined results once the last shard finishes.
 // @Description  **Common pitfalls**: malformed JSON, unsupported modes, or exceeding rate limits will return structured error responses containing a human-readable explanation.
 // @Tags         Scans
 // @Accept       json
 // @Produce      json
 // @Param        scanRequest  body      CreateScanRequest      true  "Scan request parameters"
 // @Success      202          {object}  ScanAcceptedResponse  "Scan accepted. Poll GET /scans/{id} to track progress. Example: {\"id\":\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\",\"status\":\"pending\"}"
-// @Failure      400          {object}  ErrorResponse         "Malformed JSON body or failed validation. Example: {\"error\":\"invalid request payload: validation failed on 'mode'\"}"
+// @Failure      400          {object}  ValidationErrorResponse  "Malformed JSON body or failed validation. Example: {\"error\":\"invalid request payload\",\"details\":[{\"field\":\"mode\",\"rule\":\"oneof\",\"message\":\"mode must be one of: connect syn udp\"}]}"
 // @Failure      401          {object}  ErrorResponse         "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      413          {object}  ErrorResponse         "Request body larger than the configured limit. Example: {\"error\":\"request body exceeds the 1048576 byte limit\"}"
 // @Failure      429          {object}  ErrorResponse         "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
 // @Failure      500          {object}  ErrorResponse         "Internal error while persisting or queueing the task. Example: {\"error\":\"failed to persist task\"}"
 // @Security     ApiKeyAuth
 // @Router       /scans [post]
 func (s *Server) createScanHandler(c *gin.Context) {
 	var req CreateScanRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("invalid request payload: %v", err)})
+	if !s.bindScanRequest(c, &req) {
 		return
 	}
+	s.submitScan(c, &req)
+}
+
+// submitScan persists and queues the validated scan request req and writes
+// the response.
+func (s *Server) submitScan(c *gin.Context, req *CreateScanRequest) {
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+	ctx, span := tracer.Start(ctx, "scan.submit", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+	tasks := s.tasks(c)
+	if req.Baseline != "" {
+		if _, err := tasks.GetTask(req.Baseline); err != nil {
+			if err != ErrTaskNotFound {
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load baseline task"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+				Error:   "invalid request payload",
+				Details: []FieldError{{Field: "baseline", Rule: "exists", Message: fmt.Sprintf("baseline task %s not found", req.Baseline)}},
+			})
+			return
+		}
+	}
 
 	taskID, err := generateUUID()
 	if err != nil {
@@ -56,26 +149,41 @@ func (s *Server) createScanHandler(c *gin.Context) {
 		return
 	}
 
-	task := &ScanTask{
-		ID:        taskID,
-		Status:    "pending",
-		Hosts:     req.Hosts,
-		Ports:     req.Ports,
-		Mode:      req.Mode,
-		CreatedAt: time.Now().UTC(),
+	task := scanTaskFromRequest(taskID, req, principalFrom(c).Name, time.Now().UTC())
+	modes := targetModes(task)
+	span.SetAttributes(
+		attribute.String("scan.id", task.ID),
+		attribute.String("scan.mode", task.Mode),
+		attribute.Int("scan.hosts", len(task.Hosts)),
+		attribute.String("cortex.namespace", principalFrom(c).Namespace),
+	)
+	injectTrace(ctx, task)
+
+	if (req.ShardSize > 0 && len(req.Hosts) > req.ShardSize) || len(modes) > 1 {
+		err := traceStore(ctx, tasks, "CreateShards", func() error { return createShards(tasks, task, req.ShardSize) })
+		span.SetAttributes(attribute.Int("scan.shards", len(task.Shards)))
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, ScanAcceptedResponse{ID: task.ID, Status: task.Status})
+		return
 	}
 
-	if err := s.store.CreateTask(task); err != nil {
+	if err := traceStore(ctx, tasks, "CreateTask", func() error { return tasks.CreateTask(task) }); err != nil {
+		span.SetStatus(codes.Error, "failed to persist task")
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to persist task"})
 		return
 	}
 
-	if err := s.store.PushToQueue(task.ID); err != nil {
+	if err := traceStore(ctx, tasks, "PushToQueue", func() error { return tasks.PushToQueue(task.ID, task.Mode) }); err != nil {
+		span.SetStatus(codes.Error, "failed to queue task")
 		task.Status = "failed"
 		task.Error = "failed to queue task"
 		now := time.Now().UTC()
 		task.CompletedAt = &now
-		_ = s.store.UpdateTask(task)
+		_ = tasks.UpdateTask(task)
 
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to queue task"})
 		return
@@ -84,15 +192,234 @@ func (s *Server) createScanHandler(c *gin.Context) {
 	c.JSON(http.StatusAccepted, ScanAcceptedResponse{ID: task.ID, Status: task.Status})
 }
 
+// scanTaskFromRequest builds the pending task of a validated scan request
+// submitted by owner. Targets needing a single mode set the task's mode.
+func scanTaskFromRequest(id string, req *CreateScanRequest, owner string, now time.Time) *ScanTask {
+	task := &ScanTask{
+		ID:               id,
+		Status:           "pending",
+		Hosts:            req.Hosts,
+		Targets:          req.Targets,
+		Ports:            req.Ports,
+		Mode:             req.Mode,
+		HostRate:         req.HostRate,
+		MaxRate:          req.MaxRate,
+		Timing:           req.Timing,
+		MaxParallelism:   req.MaxParallelism,
+		MaxDuration:      req.MaxDurationSeconds,
+		MinHostGroup:     req.MinHostGroup,
+		MaxHostGroup:     req.MaxHostGroup,
+		MaxRetries:       req.MaxRetries,
+		RetryBackoff:     req.RetryBackoff,
+		AllAddresses:     req.AllAddresses,
+		Prefer:           req.Prefer,
+		IPVersion:        req.IPVersion,
+		DNSServers:       req.DNSServers,
+		VersionIntensity: req.VersionIntensity,
+		NoFallback:       req.NoFallback,
+		Checks:           req.Checks,
+		HTTPPaths:        req.HTTPPaths,
+		Tags:             req.Tags,
+		RDAP:             req.RDAP,
+		ResolvePTR:       req.ResolvePTR,
+		DetectTarpits:    req.DetectTarpits || req.TarpitDowngrade,
+		Discovery:        req.Discovery,
+		TarpitDowngrade:  req.TarpitDowngrade,
+		Baseline:         req.Baseline,
+		ReuseWithin:      req.ReuseWithin,
+		CallbackURL:      req.CallbackURL,
+		Template:         req.TemplateID,
+		Owner:            owner,
+		CreatedAt:        now,
+	}
+	if modes := targetModes(task); len(modes) == 1 {
+		task.Mode = modes[0]
+	}
+	return task
+}
+
+// createShards splits parent into one task per scan mode its targets need,
+// each cut into tasks of at most shardSize hosts when shardSize is positive,
+// persists them with parent and queues the shards. The parent itself is never
+// queued; the worker finishing the last shard completes it. The returned
+// error is the message for the client.
+func createShards(store TaskStore, parent *ScanTask, shardSize int) error {
+	var shards []*ScanTask
+	for _, group := range modeGroups(parent) {
+		size := shardSize
+		if size <= 0 {
+			size = len(group.hosts)
+		}
+		for start := 0; start < len(group.hosts); start += size {
+			end := start + size
+			if end > len(group.hosts) {
+				end = len(group.hosts)
+			}
+			id, err := generateUUID()
+			if err != nil {
+				return errors.New("failed to generate task id")
+			}
+			shard := *parent
+			shard.ID = id
+			shard.Mode = group.mode
+			shard.Hosts = group.hosts[start:end]
+			if len(group.targets) > 0 {
+				shard.Targets = group.targets[start:end]
+			}
+			shard.Parent = parent.ID
+			shard.Baseline = ""
+			shard.CallbackURL = ""
+			shard.Tags = nil
+			shards = append(shards, &shard)
+			parent.Shards = append(parent.Shards, id)
+		}
+	}
+
+	for _, shard := range shards {
+		if err := store.CreateTask(shard); err != nil {
+			return errors.New("failed to persist task")
+		}
+	}
+	if err := store.CreateTask(parent); err != nil {
+		return errors.New("failed to persist task")
+	}
+
+	for i, shard := range shards {
+		if err := store.PushToQueue(shard.ID, shard.Mode); err != nil {
+			for _, queued := range shards[:i] {
+				_ = store.RemoveFromQueue(queued.ID)
+			}
+			parent.Status = "failed"
+			parent.Error = "failed to queue task"
+			now := time.Now().UTC()
+			parent.CompletedAt = &now
+			_ = store.UpdateTask(parent)
+			return errors.New("failed to queue task")
+		}
+	}
+	return nil
+}
+
+// estimateHistoryLimit caps how many recent tasks are read to measure throughput.
+const estimateHistoryLimit = 50
+
+// @Summary      Estimate a scan before submitting it
+// @Description  Validate a scan definition exactly like POST /scans and predict its size and duration without queueing anything. Hostnames are resolved when all_addresses or prefer=both is set or a blocklist is configured, so the answer reflects the targets a worker would actually probe.
+// @Description  **Prediction**: when recent completed scans of the same mode exist, the duration is derived from their measured probes per second (basis history). Otherwise it is computed from worker count, the timing template's initial probe timeout and parallelism, max_rate and host_rate, assuming every port is filtered (basis timing).
+// @Tags         Scans
+// @Accept       json
+// @Produce      json
+// @Param        scanRequest  body      CreateScanRequest        true  "Scan request parameters"
+// @Success      200          {object}  ScanEstimateResponse     "Predicted scan size and duration. Example: {\"targets\":2,\"ports\":1024,\"jobs\":2048,\"predicted_seconds\":41.5,\"basis\":\"history\",\"historical_throughput\":49.3,\"samples\":12}"
+// @Failure      400          {object}  ValidationErrorResponse  "Malformed JSON body or failed validation. Example: {\"error\":\"invalid request payload\",\"details\":[{\"field\":\"ports\",\"rule\":\"format\",\"message\":\"invalid port range format. Use startPort-endPort\"}]}"
+// @Failure      401          {object}  ErrorResponse            "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      413          {object}  ErrorResponse            "Request body larger than the configured limit. Example: {\"error\":\"request body exceeds the 1048576 byte limit\"}"
+// @Failure      429          {object}  ErrorResponse            "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500          {object}  ErrorResponse            "Internal error while loading scan history. Example: {\"error\":\"failed to load scan history\"}"
+// @Security     ApiKeyAuth
+// @Router       /scans/estimate [post]
+func (s *Server) estimateScanHandler(c *gin.Context) {
+	var req CreateScanRequest
+	if !s.bindScanRequest(c, &req) {
+		return
+	}
+
+	ports := 0
+	if req.Ports != "" {
+		// checkScanRequest already rejected a malformed specification
+		selected, _ := parsePorts(req.Ports, req.Mode)
+		ports = len(selected)
+	}
+	// bindScanRequest already rejected malformed target ports
+	hostPorts, _ := taskHostPorts(&ScanTask{Mode: req.Mode, Targets: req.Targets})
+	mode, err := scanner.ParseMode(req.Mode)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+			Error:   "invalid request payload",
+			Details: []FieldError{{Field: "mode", Rule: "oneof", Message: err.Error()}},
+		})
+		return
+	}
+
+	var warnings []string
+	estimate := scanner.EstimateScan(req.Hosts, ports, mode, scanner.ScanOptions{
+		HostPorts:    hostPorts,
+		Rate:         req.MaxRate,
+		HostRate:     req.HostRate,
+		Timing:       scanTiming(req.Timing, req.MaxParallelism, req.MaxRetries, req.RetryBackoff),
+		AllAddresses: req.AllAddresses,
+		Prefer:       scanner.AddressPreference(req.Prefer),
+		IPVersion:    req.IPVersion,
+		DNSServers:   req.DNSServers,
+		Blocklist:    s.blocklist,
+		OnBlocked: func(host, reason string) {
+			warnings = append(warnings, fmt.Sprintf("skipped %s: %s", host, reason))
+		},
+	})
+
+	throughput, samples, err := historicalThroughput(s.tasks(c), req.Mode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load scan history"})
+		return
+	}
+
+	response := ScanEstimateResponse{
+		Targets:          estimate.Targets,
+		Ports:            estimate.Ports,
+		Jobs:             estimate.Jobs,
+		PredictedSeconds: estimate.Duration.Seconds(),
+		Basis:            "timing",
+		Warnings:         warnings,
+	}
+	if samples > 0 {
+		response.PredictedSeconds = float64(estimate.Jobs) / throughput
+		response.Basis = "history"
+		response.HistoricalThroughput = throughput
+		response.Samples = samples
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// historicalThroughput measures the probes per second achieved by the most
+// recent completed tasks of mode. Durations run from creation to completion,
+// so queue wait is part of the measured rate. Shards are ignored because
+// their parent already covers them, as are tasks whose size cannot be derived.
+func historicalThroughput(store TaskStore, mode string) (float64, int, error) {
+	tasks, err := store.ListTasks(TaskQuery{NewestFirst: true, Limit: estimateHistoryLimit})
+	if err != nil {
+		return 0, 0, err
+	}
+	var jobs, seconds float64
+	samples := 0
+	for _, task := range tasks {
+		if task.Status != "completed" || task.Mode != mode || task.CompletedAt == nil || task.Parent != "" {
+			continue
+		}
+		elapsed := task.CompletedAt.Sub(task.CreatedAt).Seconds()
+		taskJobs, err := taskJobs(task)
+		if err != nil || elapsed <= 0 {
+			continue
+		}
+		jobs += float64(taskJobs)
+		seconds += elapsed
+		samples++
+	}
+	if samples == 0 || jobs == 0 {
+		return 0, 0, nil
+	}
+	return jobs / seconds, samples, nil
+}
+
 // @Summary      Get scan status and results
 // @Description  Retrieve a live snapshot of a scan task. Supply the UUID obtained from POST /scans and poll this endpoint until the lifecycle reaches completed.
-// @Description  **Polling guidance**: responses with status pending or running will include metadata but results remains empty. Once the task is completed, results contains every observed port state and optional service fingerprints. If the task fails, the error field clarifies the reason.
+// @Description  **Polling guidance**: responses with status pending or running will include metadata but results remains empty unless partial=true is given, which fills results with the ports probed so far, in the order they finished. Once the task is completed, results contains every observed port state and optional service fingerprints. If the task fails, the error field clarifies the reason. GET /scans/{id}/events pushes results as they are found instead.
 // @Description  **Error handling**: invalid UUIDs, missing authorization, rate limiting, or unknown tasks all return structured ErrorResponse payloads so clients can adjust behavior programmatically.
 // @Tags         Scans
 // @Produce      json
-// @Param        id   path      string      true  "Scan Task ID (UUID v4)"
+// @Param        id       path      string      true   "Scan Task ID (UUID v4)"
+// @Param        partial  query     bool        false  "Include the results found so far while the task is unfinished"
 // @Success      200  {object}  ScanTask    "Current task snapshot including results when completed. Example: {\"id\":\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\",\"status\":\"completed\",\"results\":[{\"host\":\"scanme.nmap.org\",\"port\":443,\"state\":\"Open\",\"service\":\"https\"}]}"
-// @Failure      400  {object}  ErrorResponse  "Malformed task identifier. Example: {\"error\":\"invalid task id format\"}"
+// @Failure      400  {object}  ErrorResponse  "Malformed task identifier or partial flag. Example: {\"error\":\"invalid task id format\"}"
 // @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
 // @Failure      404  {object}  ErrorResponse  "Task with the provided ID does not exist. Example: {\"error\":\"task not found\"}"
 // @Failure      429  {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
@@ -105,7 +432,16 @@ func (s *Server) getScanHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid task id format"})
 		return
 	}
-	task, err := s.store.GetTask(id)
+	partial := false
+	if raw := c.Query("partial"); raw != "" {
+		var err error
+		if partial, err = strconv.ParseBool(raw); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "partial must be true or false"})
+			return
+		}
+	}
+	tasks := s.tasks(c)
+	task, err := tasks.GetTask(id)
 	if err != nil {
 		if err == ErrTaskNotFound {
 			c.JSON(http.StatusNotFound, ErrorResponse{Error: "task not found"})
@@ -115,9 +451,622 @@ func (s *Server) getScanHandler(c *gin.Context) {
 		return
 	}
 
+	shardProgress(task)
+	if partial && !taskFinished(task.Status) {
+		if task.Results, err = partialTaskResults(tasks, task); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load task"})
+			return
+		}
+	}
 	c.JSON(http.StatusOK, task)
 }
 
+const (
+	// defaultScanListLimit is the page size of GET /scans without a limit.
+	defaultScanListLimit = 50
+	// maxScanListLimit caps the page size of GET /scans.
+	maxScanListLimit = 500
+)
+
+// @Summary      List scans
+// @Description  Enumerate the caller's scan tasks, newest first, narrowed by optional filters and paged with limit and offset. Shards of sharded scans are not listed separately; they appear in the shards of their task.
+// @Description  **Paging**: request the following page with the next_offset of the response until it is absent. Tasks created between requests shift later pages, so use created_after to pin the window when that matters.
+// @Tags         Scans
+// @Produce      json
+// @Param        status         query     string            false  "Only tasks in this state" Enums(pending, held, running, pausing, paused, cancelling, cancelled, completed, failed)
+// @Param        mode           query     string            false  "Only tasks of this scan mode" Enums(connect, syn, udp)
+// @Param        owner          query     string            false  "Only tasks submitted by this API key name or JWT subject; me selects the caller's own"
+// @Param        created_after  query     string            false  "Only tasks created at or after this RFC3339 timestamp"
+// @Param        limit          query     int               false  "Page size, 1-500 (default 50)"
+// @Param        offset         query     int               false  "Matching tasks to skip (default 0)"
+// @Success      200            {object}  ScanListResponse  "One page of tasks. Example: {\"count\":1,\"scans\":[{\"id\":\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\",\"status\":\"completed\",\"hosts\":[\"scanme.nmap.org\"],\"ports\":\"1-1024\",\"mode\":\"connect\",\"created_at\":\"2024-01-02T15:04:05Z\"}],\"next_offset\":50}"
+// @Failure      400            {object}  ValidationErrorResponse  "Invalid filter or paging value. Example: {\"error\":\"invalid request payload\",\"details\":[{\"field\":\"limit\",\"rule\":\"range\",\"message\":\"limit must be an integer between 1 and 500\"}]}"
+// @Failure      401            {object}  ErrorResponse     "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      429            {object}  ErrorResponse     "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500            {object}  ErrorResponse     "Internal error while loading tasks. Example: {\"error\":\"failed to list tasks\"}"
+// @Security     ApiKeyAuth
+// @Router       /scans [get]
+func (s *Server) listScansHandler(c *gin.Context) {
+	query := TaskQuery{
+		Status:      c.Query("status"),
+		Mode:        c.Query("mode"),
+		Owner:       c.Query("owner"),
+		TopLevel:    true,
+		Limit:       defaultScanListLimit,
+		NewestFirst: true,
+	}
+	if query.Owner == "me" {
+		query.Owner = principalFrom(c).Name
+	}
+	var details []FieldError
+	switch query.Status {
+	case "", "pending", "held", "running", "pausing", "paused", "cancelling", "cancelled", "completed", "failed":
+	default:
+		details = append(details, FieldError{Field: "status", Rule: "oneof", Message: "status must be one of: pending held running pausing paused cancelling cancelled completed failed"})
+	}
+	if query.Mode != "" {
+		if _, err := scanner.ParseMode(query.Mode); err != nil {
+			details = append(details, FieldError{Field: "mode", Rule: "oneof", Message: "mode must be one of: connect syn udp fin null xmas ack"})
+		}
+	}
+	if raw := c.Query("created_after"); raw != "" {
+		createdAfter, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			details = append(details, FieldError{Field: "created_after", Rule: "datetime", Message: "created_after must be an RFC3339 timestamp such as 2024-01-02T15:04:05Z"})
+		}
+		query.CreatedAfter = createdAfter
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxScanListLimit {
+			details = append(details, FieldError{Field: "limit", Rule: "range", Message: fmt.Sprintf("limit must be an integer between 1 and %d", maxScanListLimit)})
+		}
+		query.Limit = limit
+	}
+	if raw := c.Query("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			details = append(details, FieldError{Field: "offset", Rule: "min", Message: "offset must be a non-negative integer"})
+		}
+		query.Offset = offset
+	}
+	if len(details) > 0 {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{Error: "invalid request payload", Details: details})
+		return
+	}
+
+	// One extra task tells whether another page follows
+	pageSize := query.Limit
+	query.Limit++
+	tasks, err := s.tasks(c).ListTasks(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to list tasks"})
+		return
+	}
+	response := ScanListResponse{Scans: []ScanTask{}}
+	if len(tasks) > pageSize {
+		tasks = tasks[:pageSize]
+		next := query.Offset + pageSize
+		response.NextOffset = &next
+	}
+	for _, task := range tasks {
+		task.Results, task.HostSummaries, task.Changes = nil, nil, nil
+		shardProgress(task)
+		response.Scans = append(response.Scans, *task)
+	}
+	response.Count = len(response.Scans)
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary      Pause a scan
+// @Description  Stop dispatching the probes of a pending or running scan, for example when it impacts production. A pending task is taken off the queue and paused at once. A running task reports pausing while its worker lets in-flight probes finish, then paused; the results collected so far are stored with the task.
+// @Description  **Sharding**: pausing a sharded task pauses each of its unfinished shards, which carry the paused state; the task itself keeps its status.
+// @Tags         Scans
+// @Produce      json
+// @Param        id   path      string             true  "Scan Task ID (UUID v4)"
+// @Success      200  {object}  ScanStateResponse  "Pause accepted. Example: {\"id\":\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\",\"status\":\"pausing\"}"
+// @Failure      400  {object}  ErrorResponse      "Malformed task identifier. Example: {\"error\":\"invalid task id format\"}"
+// @Failure      401  {object}  ErrorResponse      "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      404  {object}  ErrorResponse      "Task with the provided ID does not exist. Example: {\"error\":\"task not found\"}"
+// @Failure      409  {object}  ErrorResponse      "Task is neither pending nor running. Example: {\"error\":\"task is completed and cannot be paused\"}"
+// @Failure      429  {object}  ErrorResponse      "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse      "Internal error while updating the task. Example: {\"error\":\"failed to pause task\"}"
+// @Security     ApiKeyAuth
+// @Router       /scans/{id}/pause [post]
+func (s *Server) pauseScanHandler(c *gin.Context) {
+	s.changePauseState(c, true)
+}
+
+// @Summary      Resume a paused scan
+// @Description  Queue a paused scan again. The worker that picks it up keeps the results collected before the pause and only probes the remaining ports. Resuming a task that is still pausing withdraws the pause.
+// @Description  **Sharding**: resuming a sharded task resumes each of its paused shards.
+// @Tags         Scans
+// @Produce      json
+// @Param        id   path      string             true  "Scan Task ID (UUID v4)"
+// @Success      200  {object}  ScanStateResponse  "Resume accepted. Example: {\"id\":\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\",\"status\":\"pending\"}"
+// @Failure      400  {object}  ErrorResponse      "Malformed task identifier. Example: {\"error\":\"invalid task id format\"}"
+// @Failure      401  {object}  ErrorResponse      "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      404  {object}  ErrorResponse      "Task with the provided ID does not exist. Example: {\"error\":\"task not found\"}"
+// @Failure      409  {object}  ErrorResponse      "Task is not paused. Example: {\"error\":\"task is running and cannot be resumed\"}"
+// @Failure      429  {object}  ErrorResponse      "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse      "Internal error while updating or queueing the task. Example: {\"error\":\"failed to queue task\"}"
+// @Security     ApiKeyAuth
+// @Router       /scans/{id}/resume [post]
+func (s *Server) resumeScanHandler(c *gin.Context) {
+	s.changePauseState(c, false)
+}
+
+// changePauseState pauses or resumes the task named in the request path, or
+// every shard of it, and writes the response.
+func (s *Server) changePauseState(c *gin.Context, pause bool) {
+	id := c.Param("id")
+	if !uuidV4Pattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid task id format"})
+		return
+	}
+	tasks := s.tasks(c)
+	task, err := tasks.GetTask(id)
+	if err != nil {
+		if err == ErrTaskNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load task"})
+		return
+	}
+
+	action, failure := "resumed", "failed to resume task"
+	if pause {
+		action, failure = "paused", "failed to pause task"
+	}
+	ids := []string{task.ID}
+	if len(task.Shards) > 0 {
+		ids = task.Shards
+	}
+	// A shard that moved reports the status a client most needs to know:
+	// pausing over paused, pending over running
+	var changed []string
+	status := task.Status
+	for _, taskID := range ids {
+		var next string
+		var moved bool
+		if pause {
+			next, moved, err = tasks.PauseTask(taskID)
+		} else {
+			next, moved, err = tasks.ResumeTask(taskID)
+		}
+		if err == ErrTaskNotFound && len(task.Shards) > 0 {
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: failure})
+			return
+		}
+		if !moved {
+			status = next
+			continue
+		}
+		switch next {
+		case "paused":
+			// A worker that pops the entry anyway skips paused tasks
+			if err := tasks.RemoveFromQueue(taskID); err != nil {
+				logging.Logger().Warn("paused task left in queue", "task_id", taskID, "error", err)
+			}
+		case "pending":
+			mode := task.Mode
+			if taskID != task.ID {
+				shard, err := tasks.GetTask(taskID)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load task"})
+					return
+				}
+				mode = shard.Mode
+			}
+			if err := tasks.PushToQueue(taskID, mode); err != nil {
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to queue task"})
+				return
+			}
+		}
+		changed = append(changed, next)
+	}
+
+	if len(changed) == 0 {
+		message := fmt.Sprintf("task is %s and cannot be %s", status, action)
+		if len(task.Shards) > 0 {
+			message = fmt.Sprintf("no shard of the task can be %s", action)
+		}
+		c.JSON(http.StatusConflict, ErrorResponse{Error: message})
+		return
+	}
+	status = changed[0]
+	for _, preferred := range []string{"pausing", "pending"} {
+		if containsString(changed, preferred) {
+			status = preferred
+		}
+	}
+	c.JSON(http.StatusOK, ScanStateResponse{ID: task.ID, Status: status})
+}
+
+// @Summary      Cancel a scan
+// @Description  Stop a scan for good. A pending, held or paused task is taken off the queue and cancelled at once. A running task reports cancelling while its worker lets in-flight probes finish, then cancelled. Cancelled is a terminal state: the results collected so far are kept, but no completion webhook is sent and the inventory is not updated.
+// @Description  **Sharding**: cancelling a sharded task cancels each of its unfinished shards. The task becomes cancelled with the results of every shard once the last one stopped.
+// @Tags         Scans
+// @Produce      json
+// @Param        id   path      string             true  "Scan Task ID (UUID v4)"
+// @Success      200  {object}  ScanStateResponse  "Cancellation accepted. Example: {\"id\":\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\",\"status\":\"cancelling\"}"
+// @Failure      400  {object}  ErrorResponse      "Malformed task identifier. Example: {\"error\":\"invalid task id format\"}"
+// @Failure      401  {object}  ErrorResponse      "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      404  {object}  ErrorResponse      "Task with the provided ID does not exist. Example: {\"error\":\"task not found\"}"
+// @Failure      409  {object}  ErrorResponse      "Task already finished. Example: {\"error\":\"task is completed and cannot be cancelled\"}"
+// @Failure      429  {object}  ErrorResponse      "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse      "Internal error while updating the task. Example: {\"error\":\"failed to cancel task\"}"
+// @Security     ApiKeyAuth
+// @Router       /scans/{id}/cancel [post]
+func (s *Server) cancelScanHandler(c *gin.Context) {
+	id := c.Param("id")
+	if !uuidV4Pattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid task id format"})
+		return
+	}
+	namespace := principalFrom(c).Namespace
+	tasks := s.tasks(c)
+	task, err := tasks.GetTask(id)
+	if err != nil {
+		if err == ErrTaskNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load task"})
+		return
+	}
+
+	ids := []string{task.ID}
+	if len(task.Shards) > 0 {
+		ids = task.Shards
+	}
+	var changed []string
+	status := task.Status
+	for _, taskID := range ids {
+		next, moved, err := tasks.CancelTask(taskID)
+		if err == ErrTaskNotFound && len(task.Shards) > 0 {
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to cancel task"})
+			return
+		}
+		if !moved {
+			status = next
+			continue
+		}
+		if next == "cancelled" {
+			// No worker runs the task, so nobody else finishes it
+			if err := tasks.RemoveFromQueue(taskID); err != nil {
+				logging.Logger().Warn("cancelled task left in queue", "task_id", taskID, "error", err)
+			}
+			if err := finishCancelled(namespace, tasks, taskID); err != nil {
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to cancel task"})
+				return
+			}
+		}
+		changed = append(changed, next)
+	}
+
+	if len(changed) == 0 {
+		message := fmt.Sprintf("task is %s and cannot be cancelled", status)
+		if len(task.Shards) > 0 {
+			message = "no shard of the task can be cancelled"
+		}
+		c.JSON(http.StatusConflict, ErrorResponse{Error: message})
+		return
+	}
+	status = changed[0]
+	if containsString(changed, "cancelling") {
+		status = "cancelling"
+	}
+	c.JSON(http.StatusOK, ScanStateResponse{ID: task.ID, Status: status})
+}
+
+// finishCancelled stamps the completion time of task id, which was cancelled
+// before a worker ran it, and finishes it like a worker would: a shard is
+// counted on its parent, which is finished once every shard stopped.
+func finishCancelled(namespace string, tasks TaskStore, id string) error {
+	task, err := tasks.GetTask(id)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	task.CompletedAt = &now
+	if task.Parent == "" {
+		finishTask(namespace, tasks, task, nil)
+		return nil
+	}
+	if err := tasks.UpdateTask(task); err != nil {
+		return err
+	}
+	parent, err := finishShard(tasks, task)
+	if err != nil {
+		return err
+	}
+	if parent != nil {
+		finishTask(namespace, tasks, parent, nil)
+	}
+	return nil
+}
+
+// streamFlushEvery is how many results are written between flushes of a
+// results stream.
+const streamFlushEvery = 500
+
+// @Summary      Stream scan results as NDJSON
+// @Description  Download the results of a scan task as newline-delimited JSON, one ScanResult object per line, written as they are decoded from the store. Use it instead of GET /scans/{id} for scans with too many results to receive as one JSON document.
+// @Description  **Backpressure**: results are fetched from the store a page at a time and decoded only as fast as the client reads them, so the server holds neither the whole response nor all results of the task. A task whose results are rewritten during the download, as by a purge, can yield a mix of both. The X-Task-Status header carries the task status; pending and running tasks have no results yet and yield an empty body.
+// @Description  **Truncation**: if the stored results cannot be decoded part way, the stream ends early. Compare the line count with GET /scans/{id} when completeness matters.
+// @Tags         Scans
+// @Produce      application/x-ndjson
+// @Param        id   path      string      true  "Scan Task ID (UUID v4)"
+// @Success      200  {object}  scanner.ScanResult  "One result per line. Example: {\"host\":\"scanme.nmap.org\",\"port\":443,\"state\":\"Open\",\"service\":\"https\"}"
+// @Failure      400  {object}  ErrorResponse  "Malformed task identifier. Example: {\"error\":\"invalid task id format\"}"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      404  {object}  ErrorResponse  "Task with the provided ID does not exist. Example: {\"error\":\"task not found\"}"
+// @Failure      429  {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse  "Internal error when loading the results. Example: {\"error\":\"failed to load results\"}"
+// @Security     ApiKeyAuth
+// @Router       /scans/{id}/results/stream [get]
+func (s *Server) streamResultsHandler(c *gin.Context) {
+	id := c.Param("id")
+	if !uuidV4Pattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid task id format"})
+		return
+	}
+	reader, err := s.tasks(c).TaskResults(id)
+	if err != nil {
+		if err == ErrTaskNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load results"})
+		return
+	}
+
+	clearStreamDeadlines(c.Writer)
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("X-Task-Status", reader.Status)
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+	for written := 1; ; written++ {
+		result, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Headers are gone already; all that is left is to end the stream
+			logging.Logger().Error("results stream aborted", "task_id", id, "error", err)
+			return
+		}
+		// A write fails once the client went away
+		if err := encoder.Encode(result); err != nil {
+			return
+		}
+		if written%streamFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+	}
+	c.Writer.Flush()
+}
+
+// @Summary      Get build information
+// @Description  Report the version, source revision, and build date of the running Cortex server so bug reports and fleet inventories can pin exact builds.
+// @Tags         System
+// @Produce      json
+// @Success      200  {object}  version.Info   "Build metadata. Example: {\"version\":\"v1.2.0\",\"commit\":\"4f1c2a9e7b3d\",\"build_date\":\"2024-01-02T15:04:05Z\",\"go_version\":\"go1.24.0\",\"platform\":\"linux/amd64\"}"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      429  {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Security     ApiKeyAuth
+// @Router       /version [get]
+func (s *Server) versionHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, version.Get())
+}
+
+// bindScanRequest decodes and validates a scan submission, writing the error
+// response and returning false when the request is rejected. When the body
+// names a template_id, the template's fields are decoded first and the body
+// replaces those it sets.
+func (s *Server) bindScanRequest(c *gin.Context, req *CreateScanRequest) bool {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			abortBodyTooLarge(c, tooLarge.Limit)
+			return false
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "failed to read request body"})
+		return false
+	}
+	var ref struct {
+		TemplateID string `json:"template_id"`
+	}
+	// A malformed body or template_id is reported by the binding below
+	if json.Unmarshal(body, &ref) == nil && uuidV4Pattern.MatchString(ref.TemplateID) {
+		template, err := s.tasks(c).GetTemplate(ref.TemplateID)
+		if err != nil {
+			if err != ErrTemplateNotFound {
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load template"})
+				return false
+			}
+			c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+				Error:   "invalid request payload",
+				Details: []FieldError{{Field: "template_id", Rule: "exists", Message: fmt.Sprintf("template %s not found", ref.TemplateID)}},
+			})
+			return false
+		}
+		*req = template.Scan
+	}
+	if err := binding.JSON.BindBody(body, req); err != nil {
+		c.JSON(http.StatusBadRequest, newValidationErrorResponse(err))
+		return false
+	}
+	return s.checkScanRequest(c, req)
+}
+
+// checkScanRequest applies the validation that struct tags cannot express to
+// a decoded scan submission, writing the error response and returning false
+// when the request is rejected.
+func (s *Server) checkScanRequest(c *gin.Context, req *CreateScanRequest) bool {
+	if req.TopPorts > 0 {
+		ports, err := scanner.DefaultServices().TopPorts(req.TopPorts, modeProtocol(req.Mode))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+				Error:   "invalid request payload",
+				Details: []FieldError{{Field: "top_ports", Rule: "max", Message: err.Error()}},
+			})
+			return false
+		}
+		req.Ports = scanner.FormatPortList(ports)
+	}
+	if req.Ports != "" {
+		if _, err := parsePorts(req.Ports, req.Mode); err != nil {
+			c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+				Error:   "invalid request payload",
+				Details: []FieldError{{Field: "ports", Rule: "format", Message: err.Error()}},
+			})
+			return false
+		}
+	}
+
+	if len(req.Targets) > 0 {
+		if details := targetErrors(req); len(details) > 0 {
+			c.JSON(http.StatusBadRequest, ValidationErrorResponse{Error: "invalid request payload", Details: details})
+			return false
+		}
+	}
+
+	checks, err := scanner.ParseChecks(req.Checks)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+			Error:   "invalid request payload",
+			Details: []FieldError{{Field: "checks", Rule: "oneof", Message: err.Error()}},
+		})
+		return false
+	}
+	if len(req.HTTPPaths) > 0 && !scanner.SetHTTPPaths(checks, req.HTTPPaths...) {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+			Error:   "invalid request payload",
+			Details: []FieldError{{Field: "http_paths", Rule: "required_with", Message: "http_paths requires the http check among checks"}},
+		})
+		return false
+	}
+
+	if req.CallbackURL != "" {
+		if err := checkCallbackURL(c.Request.Context(), req.CallbackURL, s.blocklist); err != nil {
+			c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+				Error:   "invalid request payload",
+				Details: []FieldError{{Field: "callback_url", Rule: "url", Message: err.Error()}},
+			})
+			return false
+		}
+	}
+
+	if req.MinHostGroup > 0 && (req.MaxHostGroup == 0 || req.MinHostGroup > req.MaxHostGroup) {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+			Error:   "invalid request payload",
+			Details: []FieldError{{Field: "min_hostgroup", Rule: "ltefield", Message: "min_hostgroup requires max_hostgroup and must not exceed it"}},
+		})
+		return false
+	}
+
+	if len(req.DNSServers) > 0 {
+		servers, err := scanner.ParseDNSServers(req.DNSServers)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+				Error:   "invalid request payload",
+				Details: []FieldError{{Field: "dns_servers", Rule: "ip", Message: err.Error()}},
+			})
+			return false
+		}
+		req.DNSServers = servers
+	}
+
+	if req.RetryBackoff != "" {
+		if backoff, err := time.ParseDuration(req.RetryBackoff); err != nil || backoff <= 0 || backoff > maxRetryBackoff {
+			c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+				Error:   "invalid request payload",
+				Details: []FieldError{{Field: "retry_backoff", Rule: "duration", Message: fmt.Sprintf("retry_backoff must be a positive Go duration such as 250ms, at most %s", maxRetryBackoff)}},
+			})
+			return false
+		}
+	}
+
+	if req.ReuseWithin != "" {
+		if detail, ok := s.checkReuseWithin(req.ReuseWithin); !ok {
+			c.JSON(http.StatusBadRequest, ValidationErrorResponse{Error: "invalid request payload", Details: []FieldError{detail}})
+			return false
+		}
+	}
+
+	if len(req.Hosts) > 0 {
+		hosts, err := scanner.ExpandTargets(req.Hosts, req.Exclude, scanner.DefaultMaxTargets)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+				Error:   "invalid request payload",
+				Details: []FieldError{{Field: "hosts", Rule: "targets", Message: err.Error()}},
+			})
+			return false
+		}
+		if len(hosts) == 0 {
+			c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+				Error:   "invalid request payload",
+				Details: []FieldError{{Field: "exclude", Rule: "excludes_all", Message: "exclude removes every host"}},
+			})
+			return false
+		}
+		req.Hosts = hosts
+	}
+
+	if details := blockedHostErrors(req.Hosts, s.blocklist); len(details) > 0 {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{Error: "invalid request payload", Details: details})
+		return false
+	}
+	return true
+}
+
+// maxRetryBackoff caps the retry_backoff of a request; the wait doubles with
+// every retry, so larger values would stall silent ports for minutes.
+const maxRetryBackoff = 10 * time.Second
+
+// checkReuseWithin validates a requested result reuse window against the
+// server's CORTEX_RESULT_REUSE_MAX.
+func (s *Server) checkReuseWithin(raw string) (FieldError, bool) {
+	within, err := time.ParseDuration(raw)
+	resultTTL := s.pools.Config().ResultTTL
+	switch {
+	case resultTTL <= 0:
+		return FieldError{Field: "reuse_within", Rule: "enabled", Message: "result reuse is disabled on this server"}, false
+	case err != nil:
+		return FieldError{Field: "reuse_within", Rule: "duration", Message: fmt.Sprintf("reuse_within must be a Go duration such as 15m: %v", err)}, false
+	case within <= 0:
+		return FieldError{Field: "reuse_within", Rule: "min", Message: "reuse_within must be positive"}, false
+	case within > resultTTL:
+		return FieldError{Field: "reuse_within", Rule: "max", Message: fmt.Sprintf("reuse_within must not exceed %s", resultTTL)}, false
+	}
+	return FieldError{}, true
+}
+
+// blockedHostErrors reports every IP literal that falls in a blocked range.
+func blockedHostErrors(hosts []string, blocklist *scanner.Blocklist) []FieldError {
+	var details []FieldError
+	for i, host := range hosts {
+		if network, blocked := blocklist.MatchLiteral(host); blocked {
+			details = append(details, FieldError{
+				Field:   fmt.Sprintf("hosts[%d]", i),
+				Rule:    "blocked",
+				Message: fmt.Sprintf("%s is in blocked range %s", host, network),
+			})
+		}
+	}
+	return details
+}
+
 func generateUUID() (string, error) {
 	b := make([]byte, 16)
 	if _, err := rand.Read(b); err != nil {
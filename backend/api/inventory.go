@@ -0,0 +1,269 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Viktor25104/cortex/backend/scanner"
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary      List the asset inventory
+// @Description  Return the aggregated inventory of every host the caller has scanned: currently open ports with their latest service fingerprint and first/last seen timestamps. The inventory is updated by every completed scan and is kept after the scans themselves expire.
+// @Tags         Inventory
+// @Produce      json
+// @Success      200  {array}   InventoryHost  "Inventory records sorted by host."
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      429  {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse  "Internal error while loading the inventory. Example: {\"error\":\"failed to load inventory\"}"
+// @Security     ApiKeyAuth
+// @Router       /inventory [get]
+func (s *Server) listInventoryHandler(c *gin.Context) {
+	records, err := s.tasks(c).ListInventory()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load inventory"})
+		return
+	}
+	c.JSON(http.StatusOK, records)
+}
+
+// @Summary      Get the inventory of one host
+// @Description  Return the aggregated inventory record of a single host as submitted in scans.
+// @Tags         Inventory
+// @Produce      json
+// @Param        host  path      string         true  "Host as submitted in scans"
+// @Success      200   {object}  InventoryHost  "Inventory record of the host."
+// @Failure      401   {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      404   {object}  ErrorResponse  "The host has never been scanned. Example: {\"error\":\"host not found\"}"
+// @Failure      429   {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500   {object}  ErrorResponse  "Internal error while loading the inventory. Example: {\"error\":\"failed to load inventory\"}"
+// @Security     ApiKeyAuth
+// @Router       /inventory/{host} [get]
+func (s *Server) getInventoryHostHandler(c *gin.Context) {
+	record, err := s.tasks(c).GetInventoryHost(c.Param("host"))
+	if err != nil {
+		if err == ErrHostNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "host not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load inventory"})
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
+// @Summary      Find hosts by open port, service or tag
+// @Description  List every host ever scanned with its currently open ports and last scan time, narrowed by optional filters. All filters must match; port and service must match the same open port. For example ?port=3389 answers where anything is listening on 3389.
+// @Tags         Inventory
+// @Produce      json
+// @Param        port      query     int               false  "Only hosts with this port currently open"
+// @Param        protocol  query     string            false  "Restrict the port and service filters to tcp or udp" Enums(tcp, udp)
+// @Param        service   query     string            false  "Only hosts with an open port whose service fingerprint contains this text (case-insensitive)"
+// @Param        tag       query     string            false  "Only hosts carrying this tag"
+// @Success      200       {object}  HostListResponse  "Matching hosts. Example: {\"count\":1,\"hosts\":[{\"host\":\"203.0.113.50\",\"last_seen\":\"2024-01-09T15:04:05Z\",\"ports\":[{\"port\":3389,\"protocol\":\"tcp\",\"service\":\"ms-wbt-server\"}]}]}"
+// @Failure      400       {object}  ValidationErrorResponse  "Invalid filter value. Example: {\"error\":\"invalid request payload\",\"details\":[{\"field\":\"port\",\"rule\":\"range\",\"message\":\"port must be an integer between 0 and 65535\"}]}"
+// @Failure      401       {object}  ErrorResponse     "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      429       {object}  ErrorResponse     "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500       {object}  ErrorResponse     "Internal error while loading the inventory. Example: {\"error\":\"failed to load inventory\"}"
+// @Security     ApiKeyAuth
+// @Router       /hosts [get]
+func (s *Server) listHostsHandler(c *gin.Context) {
+	filter := hostFilter{
+		protocol: c.Query("protocol"),
+		service:  strings.ToLower(c.Query("service")),
+		tag:      c.Query("tag"),
+		port:     -1,
+	}
+	var details []FieldError
+	if raw := c.Query("port"); raw != "" {
+		port, err := strconv.Atoi(raw)
+		if err != nil || port < 0 || port > 65535 {
+			details = append(details, FieldError{Field: "port", Rule: "range", Message: "port must be an integer between 0 and 65535"})
+		}
+		filter.port = port
+	}
+	if filter.protocol != "" && filter.protocol != "tcp" && filter.protocol != "udp" {
+		details = append(details, FieldError{Field: "protocol", Rule: "oneof", Message: "protocol must be one of: tcp udp"})
+	}
+	if len(details) > 0 {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{Error: "invalid request payload", Details: details})
+		return
+	}
+
+	records, err := s.tasks(c).ListInventory()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load inventory"})
+		return
+	}
+	response := HostListResponse{Hosts: []InventoryHost{}}
+	for _, record := range records {
+		if filter.matches(record) {
+			response.Hosts = append(response.Hosts, *record)
+		}
+	}
+	response.Count = len(response.Hosts)
+	c.JSON(http.StatusOK, response)
+}
+
+// hostFilter holds the GET /hosts query filters. A port of -1 matches any port.
+type hostFilter struct {
+	port     int
+	protocol string
+	service  string
+	tag      string
+}
+
+func (f hostFilter) matches(record *InventoryHost) bool {
+	if f.tag != "" && !containsString(record.Tags, f.tag) {
+		return false
+	}
+	if f.port < 0 && f.protocol == "" && f.service == "" {
+		return true
+	}
+	for _, port := range record.Ports {
+		if f.port >= 0 && port.Port != f.port {
+			continue
+		}
+		if f.protocol != "" && port.Protocol != f.protocol {
+			continue
+		}
+		if f.service != "" && !strings.Contains(strings.ToLower(port.Service), f.service) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// inventoryObservation is what one completed task saw on one host.
+type inventoryObservation struct {
+	addresses []string
+	probed    map[int]bool
+	open      map[int]scanner.ScanResult
+}
+
+// updateInventory folds the results of a completed task into the inventory
+// record of every host it covered. Ports the task probed and found open are
+// added or refreshed, ports it probed and found anything else are removed,
+// and ports outside its range keep their previous state.
+func updateInventory(store TaskStore, task *ScanTask) error {
+	protocol := taskProtocol(task)
+	seenAt := time.Now().UTC()
+	if task.CompletedAt != nil {
+		seenAt = *task.CompletedAt
+	}
+
+	observations := make(map[string]*inventoryObservation)
+	for _, result := range task.Results {
+		observation := observations[result.Host]
+		if observation == nil {
+			observation = &inventoryObservation{probed: make(map[int]bool), open: make(map[int]scanner.ScanResult)}
+			observations[result.Host] = observation
+		}
+		if result.Address != "" && !containsString(observation.addresses, result.Address) {
+			observation.addresses = append(observation.addresses, result.Address)
+		}
+		observation.probed[result.Port] = true
+		if result.State == "Open" {
+			observation.open[result.Port] = result
+		}
+	}
+
+	// Targets of a manifest may be scanned in their own mode and carry
+	// their own tags
+	targets := make(map[string]ScanTarget, len(task.Targets))
+	for _, target := range task.Targets {
+		targets[target.Host] = target
+	}
+
+	for host, observation := range observations {
+		hostProtocol, tags := protocol, task.Tags
+		if target, ok := targets[host]; ok {
+			hostProtocol = modeProtocol(targetMode(task, target))
+			tags = append(append([]string(nil), task.Tags...), target.Tags...)
+		}
+		err := store.UpdateInventoryHost(host, func(record *InventoryHost) {
+			applyObservation(record, observation, hostProtocol, task.ID, seenAt)
+			for _, tag := range tags {
+				if !containsString(record.Tags, tag) {
+					record.Tags = append(record.Tags, tag)
+				}
+			}
+			sort.Strings(record.Tags)
+		})
+		if err != nil {
+			return fmt.Errorf("host %s: %w", host, err)
+		}
+	}
+	return nil
+}
+
+// applyObservation merges one task's view of a host into its inventory record.
+// Older tasks finishing late never roll a record back.
+func applyObservation(record *InventoryHost, observation *inventoryObservation, protocol, taskID string, seenAt time.Time) {
+	if record.FirstSeen.IsZero() || seenAt.Before(record.FirstSeen) {
+		record.FirstSeen = seenAt
+	}
+	if seenAt.Before(record.LastSeen) {
+		return
+	}
+	record.LastSeen = seenAt
+	record.LastTaskID = taskID
+	for _, address := range observation.addresses {
+		if !containsString(record.Addresses, address) {
+			record.Addresses = append(record.Addresses, address)
+		}
+	}
+	sort.Strings(record.Addresses)
+
+	ports := make([]InventoryPort, 0, len(record.Ports)+len(observation.open))
+	known := make(map[int]bool)
+	for _, port := range record.Ports {
+		if port.Protocol != protocol || !observation.probed[port.Port] {
+			ports = append(ports, port)
+			continue
+		}
+		result, open := observation.open[port.Port]
+		if !open {
+			continue
+		}
+		port.Service, port.Product, port.Version = result.Service, result.Product, result.Version
+		port.LastSeen = seenAt
+		ports = append(ports, port)
+		known[port.Port] = true
+	}
+	for number, result := range observation.open {
+		if known[number] {
+			continue
+		}
+		ports = append(ports, InventoryPort{
+			Port:      number,
+			Protocol:  protocol,
+			Service:   result.Service,
+			Product:   result.Product,
+			Version:   result.Version,
+			FirstSeen: seenAt,
+			LastSeen:  seenAt,
+		})
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Protocol != ports[j].Protocol {
+			return ports[i].Protocol < ports[j].Protocol
+		}
+		return ports[i].Port < ports[j].Port
+	})
+	record.Ports = ports
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// JanitorConfig controls the retention janitor.
+type JanitorConfig struct {
+	// Retention is how long finished tasks are kept after creation.
+	Retention time.Duration
+	// Interval is the delay between sweeps.
+	Interval time.Duration
+	// ArchiveDir, when set, receives finished tasks as NDJSON before they are deleted.
+	ArchiveDir string
+}
+
+// JanitorReport summarizes what a single sweep removed.
+type JanitorReport struct {
+	Deleted       int
+	Archived      int
+	OrphanedQueue int
+	SkippedActive int
+}
+
+// Janitor periodically removes finished tasks older than the retention window
+// and queue entries whose task no longer exists.
+type Janitor struct {
+	store  TaskStore
+	cfg    JanitorConfig
+	logger *slog.Logger
+}
+
+// NewJanitor constructs a janitor for the given store.
+func NewJanitor(store TaskStore, cfg JanitorConfig, logger *slog.Logger) *Janitor {
+	return &Janitor{store: store, cfg: cfg, logger: logger}
+}
+
+// Start runs a sweep immediately and then every configured interval in the background.
+func (j *Janitor) Start() {
+	go func() {
+		ticker := time.NewTicker(j.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			j.runOnce()
+			<-ticker.C
+		}
+	}()
+}
+
+func (j *Janitor) runOnce() {
+	started := time.Now()
+	report, err := j.Sweep(started.UTC())
+	if err != nil {
+		j.logger.Error("janitor sweep failed", "error", err,
+			"deleted", report.Deleted, "archived", report.Archived, "orphaned_queue", report.OrphanedQueue)
+		return
+	}
+	j.logger.Info("janitor sweep completed",
+		"deleted", report.Deleted,
+		"archived", report.Archived,
+		"orphaned_queue", report.OrphanedQueue,
+		"skipped_active", report.SkippedActive,
+		"retention", j.cfg.Retention.String(),
+		"duration_ms", time.Since(started).Milliseconds(),
+	)
+}
+
+// Sweep removes finished tasks created before now minus the retention window
+// and cleans up orphaned queue entries in every namespace. Pending and running
+// tasks are never removed, however old they are.
+func (j *Janitor) Sweep(now time.Time) (JanitorReport, error) {
+	var report JanitorReport
+
+	namespaces, err := j.store.Namespaces()
+	if err != nil {
+		return report, fmt.Errorf("list namespaces: %w", err)
+	}
+	for _, namespace := range namespaces {
+		if err := j.sweepNamespace(namespace, now, &report); err != nil {
+			return report, fmt.Errorf("namespace %s: %w", namespace, err)
+		}
+	}
+	return report, nil
+}
+
+func (j *Janitor) sweepNamespace(namespace string, now time.Time, report *JanitorReport) error {
+	store := j.store.Namespace(namespace)
+	tasks, err := store.ListTasks(TaskQuery{CreatedBefore: now.Add(-j.cfg.Retention)})
+	if err != nil {
+		return fmt.Errorf("list expired tasks: %w", err)
+	}
+
+	var expired []*ScanTask
+	for _, task := range tasks {
+		if task.Status != "completed" && task.Status != "failed" && task.Status != "cancelled" {
+			report.SkippedActive++
+			continue
+		}
+		expired = append(expired, task)
+	}
+
+	if j.cfg.ArchiveDir != "" && len(expired) > 0 {
+		if err := j.archive(now, namespace, expired); err != nil {
+			return err
+		}
+		report.Archived += len(expired)
+	}
+
+	for _, task := range expired {
+		if err := store.DeleteTask(task.ID); err != nil {
+			return fmt.Errorf("delete task %s: %w", task.ID, err)
+		}
+		report.Deleted++
+	}
+
+	queued, err := store.QueuedTaskIDs()
+	if err != nil {
+		return fmt.Errorf("read queue: %w", err)
+	}
+	for _, id := range queued {
+		if _, err := store.GetTask(id); !errors.Is(err, ErrTaskNotFound) {
+			continue
+		}
+		if err := store.RemoveFromQueue(id); err != nil {
+			return fmt.Errorf("remove orphaned queue entry %s: %w", id, err)
+		}
+		report.OrphanedQueue++
+	}
+
+	return nil
+}
+
+// archive appends tasks to a daily NDJSON file in the archive directory.
+// Tenant namespaces other than the default get their own files.
+func (j *Janitor) archive(now time.Time, namespace string, tasks []*ScanTask) error {
+	if err := os.MkdirAll(j.cfg.ArchiveDir, 0o750); err != nil {
+		return fmt.Errorf("create archive dir: %w", err)
+	}
+	name := fmt.Sprintf("tasks-%s.ndjson", now.Format("2006-01-02"))
+	if namespace != DefaultNamespace {
+		name = fmt.Sprintf("tasks-%s-%s.ndjson", namespace, now.Format("2006-01-02"))
+	}
+	path := filepath.Join(j.cfg.ArchiveDir, name)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("open archive file: %w", err)
+	}
+
+	encoder := json.NewEncoder(file)
+	for _, task := range tasks {
+		if err := encoder.Encode(task); err != nil {
+			file.Close()
+			return fmt.Errorf("archive task %s: %w", task.ID, err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("close archive file: %w", err)
+	}
+	return nil
+}
+
+// taskKeyGrace is how long a finished task's Redis key outlives the
+// retention window, so the janitor gets to archive it before Redis expires it.
+const taskKeyGrace = 24 * time.Hour
+
+// loadJanitorConfig reads retention settings from the environment:
+// CORTEX_TASK_TTL (Go duration, default 168h; 0 disables the janitor; the
+// older name CORTEX_TASK_RETENTION is read when it is unset),
+// CORTEX_TASK_JANITOR_INTERVAL (default 10m) and CORTEX_TASK_ARCHIVE_DIR
+// (optional directory for NDJSON archives of removed tasks).
+func loadJanitorConfig() (JanitorConfig, bool, error) {
+	cfg := JanitorConfig{Retention: 7 * 24 * time.Hour, Interval: 10 * time.Minute}
+
+	name := "CORTEX_TASK_TTL"
+	if os.Getenv(name) == "" && os.Getenv("CORTEX_TASK_RETENTION") != "" {
+		name = "CORTEX_TASK_RETENTION"
+	}
+	var err error
+	if cfg.Retention, err = getenvDuration(name, cfg.Retention); err != nil {
+		return cfg, false, err
+	}
+	if cfg.Retention < 0 {
+		return cfg, false, fmt.Errorf("%s must not be negative", name)
+	}
+	if cfg.Interval, err = getenvDuration("CORTEX_TASK_JANITOR_INTERVAL", cfg.Interval); err != nil {
+		return cfg, false, err
+	}
+	if cfg.Interval < time.Second {
+		return cfg, false, fmt.Errorf("CORTEX_TASK_JANITOR_INTERVAL must be at least 1s")
+	}
+	cfg.ArchiveDir = os.Getenv("CORTEX_TASK_ARCHIVE_DIR")
+
+	return cfg, cfg.Retention > 0, nil
+}
@@ -0,0 +1,390 @@
+package api
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+)
+
+// JWTConfig configures authentication with JSON Web Tokens issued by an
+// OpenID Connect provider, as an alternative to API keys.
+type JWTConfig struct {
+	// JWKSURL serves the provider's signing keys as a JSON Web Key Set.
+	JWKSURL string
+	// Issuer and Audience, when set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// RolesClaim names the claim listing the caller's roles or groups; a
+	// dotted name such as realm_access.roles reaches into nested objects.
+	RolesClaim string
+	// RoleMap maps claim values to Cortex roles. Values that are role names
+	// themselves map to that role.
+	RoleMap map[string]string
+	// DefaultRole is given to tokens without a mapped role; empty rejects them.
+	DefaultRole string
+	// NamespaceClaim names the claim holding the caller's tenant namespace;
+	// without it, or when the claim is missing, callers use the default namespace.
+	NamespaceClaim string
+	// JWKSRefresh is how long fetched keys are used before the set is fetched again.
+	JWKSRefresh time.Duration
+}
+
+// loadJWTConfig reads JWT authentication settings from the environment:
+// CORTEX_JWT_JWKS_URL enables it, CORTEX_JWT_ISSUER and CORTEX_JWT_AUDIENCE
+// are checked against the token, CORTEX_JWT_ROLES_CLAIM (default roles),
+// CORTEX_JWT_ROLE_MAP (value=role pairs) and CORTEX_JWT_DEFAULT_ROLE decide
+// the role, CORTEX_JWT_NAMESPACE_CLAIM the namespace and
+// CORTEX_JWT_JWKS_REFRESH (default 15m) how often keys are fetched. The
+// boolean result reports whether JWT authentication is enabled.
+func loadJWTConfig() (JWTConfig, bool, error) {
+	cfg := JWTConfig{
+		JWKSURL:        os.Getenv("CORTEX_JWT_JWKS_URL"),
+		Issuer:         os.Getenv("CORTEX_JWT_ISSUER"),
+		Audience:       os.Getenv("CORTEX_JWT_AUDIENCE"),
+		RolesClaim:     getenv("CORTEX_JWT_ROLES_CLAIM", "roles"),
+		DefaultRole:    os.Getenv("CORTEX_JWT_DEFAULT_ROLE"),
+		NamespaceClaim: os.Getenv("CORTEX_JWT_NAMESPACE_CLAIM"),
+		RoleMap:        make(map[string]string),
+	}
+	if cfg.JWKSURL == "" {
+		return cfg, false, nil
+	}
+	if u, err := url.Parse(cfg.JWKSURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return cfg, false, fmt.Errorf("CORTEX_JWT_JWKS_URL must be an http or https URL")
+	}
+	var err error
+	if cfg.JWKSRefresh, err = getenvDuration("CORTEX_JWT_JWKS_REFRESH", 15*time.Minute); err != nil {
+		return cfg, false, err
+	}
+	if cfg.JWKSRefresh < time.Minute {
+		return cfg, false, fmt.Errorf("CORTEX_JWT_JWKS_REFRESH must be at least 1m")
+	}
+	if cfg.DefaultRole != "" && !validRole(cfg.DefaultRole) {
+		return cfg, false, fmt.Errorf("CORTEX_JWT_DEFAULT_ROLE must be admin, submit or read-only")
+	}
+	for i, pair := range strings.Split(os.Getenv("CORTEX_JWT_ROLE_MAP"), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		value, role, ok := strings.Cut(pair, "=")
+		if !ok || value == "" || !validRole(role) {
+			return cfg, false, fmt.Errorf("CORTEX_JWT_ROLE_MAP entry %d must have the form value=role with role admin, submit or read-only", i+1)
+		}
+		cfg.RoleMap[value] = role
+	}
+	return cfg, true, nil
+}
+
+func validRole(role string) bool {
+	return role == RoleAdmin || role == RoleSubmit || role == RoleReadOnly
+}
+
+// jwksMinRefetch is how soon after a fetch attempt the next one may start, so
+// forged key IDs or an unreachable provider cannot make every request wait
+// on a fetch.
+const jwksMinRefetch = 30 * time.Second
+
+// jwtLeeway tolerates clock skew between Cortex and the provider.
+const jwtLeeway = 30 * time.Second
+
+// JWTVerifier validates bearer JWTs against the keys of a JWKS URL and maps
+// their claims to principals. Keys are cached and fetched again after
+// JWKSRefresh, or earlier when a token names a key the cache lacks.
+type JWTVerifier struct {
+	cfg    JWTConfig
+	client *http.Client
+	parser *jwt.Parser
+	logger *slog.Logger
+
+	// refreshes runs one key set fetch at a time for all requests needing it
+	refreshes singleflight.Group
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetched   time.Time
+	attempted time.Time
+}
+
+// NewJWTVerifier returns a verifier for cfg. Keys are fetched on first use.
+func NewJWTVerifier(cfg JWTConfig, logger *slog.Logger) *JWTVerifier {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtLeeway),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+	return &JWTVerifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		parser: jwt.NewParser(options...),
+		logger: logger,
+	}
+}
+
+// looksLikeJWT reports whether token has the three dot-separated parts of a
+// signed JWT, which API keys never have.
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Principal validates token and returns the caller it identifies: the sub
+// claim names it, the roles claim sets its rights and the namespace claim
+// its tenant.
+func (v *JWTVerifier) Principal(token string) (Principal, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(token, claims, v.key); err != nil {
+		return Principal{}, err
+	}
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return Principal{}, errors.New("token has no sub claim")
+	}
+
+	role := v.role(claimValues(claims, v.cfg.RolesClaim))
+	if role == "" {
+		return Principal{}, fmt.Errorf("token of %s grants no role", subject)
+	}
+	namespace := DefaultNamespace
+	if v.cfg.NamespaceClaim != "" {
+		if values := claimValues(claims, v.cfg.NamespaceClaim); len(values) > 0 {
+			namespace = values[0]
+		}
+	}
+	if !namespacePattern.MatchString(namespace) {
+		return Principal{}, fmt.Errorf("token of %s names invalid namespace %q", subject, namespace)
+	}
+	return Principal{
+		Name:      subject,
+		Namespace: namespace,
+		Admin:     role == RoleAdmin,
+		ReadOnly:  role == RoleReadOnly,
+	}, nil
+}
+
+// role returns the most privileged role that values map to, or the default
+// role when none does.
+func (v *JWTVerifier) role(values []string) string {
+	rank := map[string]int{RoleReadOnly: 1, RoleSubmit: 2, RoleAdmin: 3}
+	best := ""
+	for _, value := range values {
+		role, ok := v.cfg.RoleMap[value]
+		if !ok && validRole(value) {
+			role = value
+		}
+		if rank[role] > rank[best] {
+			best = role
+		}
+	}
+	if best == "" {
+		return v.cfg.DefaultRole
+	}
+	return best
+}
+
+// claimValues returns the string values of the claim at path, a dotted name
+// into nested objects. A string claim is split at spaces, as OAuth scopes are.
+func claimValues(claims jwt.MapClaims, path string) []string {
+	var value interface{} = map[string]interface{}(claims)
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	switch value := value.(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// key returns the public key that signed token, by its kid header. A known
+// key is served from the cache, which is refreshed in the background once it
+// is stale; only a token signed by an unknown key waits for the key set.
+func (v *JWTVerifier) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	v.mu.Lock()
+	key, known := v.keys[kid]
+	refresh := (!known || time.Since(v.fetched) > v.cfg.JWKSRefresh) && time.Since(v.attempted) > jwksMinRefetch
+	v.mu.Unlock()
+
+	if refresh && known {
+		go v.refreshKeys()
+	} else if refresh {
+		keys, err := v.refreshKeys()
+		if err != nil {
+			return nil, fmt.Errorf("fetch signing keys: %w", err)
+		}
+		key, known = keys[kid]
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// refreshKeys fetches the key set, once for all callers that ask at the same
+// time and at most once per jwksMinRefetch, and caches it. The lock is not
+// held during the fetch, so cached keys stay available while it runs; when
+// the provider is unreachable they are kept.
+func (v *JWTVerifier) refreshKeys() (map[string]crypto.PublicKey, error) {
+	keys, err, _ := v.refreshes.Do("jwks", func() (interface{}, error) {
+		v.mu.Lock()
+		if time.Since(v.attempted) <= jwksMinRefetch {
+			keys := v.keys
+			v.mu.Unlock()
+			return keys, nil
+		}
+		v.attempted = time.Now()
+		v.mu.Unlock()
+
+		keys, err := v.fetchKeys()
+		if err != nil {
+			v.logger.Warn("failed to fetch jwt signing keys", "url", v.cfg.JWKSURL, "error", err)
+			return nil, err
+		}
+		v.mu.Lock()
+		v.keys, v.fetched = keys, time.Now()
+		v.mu.Unlock()
+		return keys, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys.(map[string]crypto.PublicKey), nil
+}
+
+// jsonWebKey holds the members of a JSON Web Key that describe public keys.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// maxJWKSBytes caps the size of a fetched key set.
+const maxJWKSBytes = 1 << 20
+
+// fetchKeys downloads the key set and returns its signing keys by key ID.
+// Keys of unsupported types are skipped.
+func (v *JWTVerifier) fetchKeys() (map[string]crypto.PublicKey, error) {
+	resp, err := v.client.Get(v.cfg.JWKSURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSBytes)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode key set: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			v.logger.Warn("skipping jwt signing key", "kid", jwk.Kid, "error", err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+// publicKey decodes k into an RSA, ECDSA or Ed25519 public key.
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("rsa exponent out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
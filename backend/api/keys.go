@@ -0,0 +1,168 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Viktor25104/cortex/backend/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// Roles of managed API keys.
+const (
+	RoleAdmin    = "admin"
+	RoleSubmit   = "submit"
+	RoleReadOnly = "read-only"
+)
+
+// managedKeyPrefix starts every managed API key, so that only such keys are
+// looked up in the store.
+const managedKeyPrefix = "ctx_"
+
+// managedKeyShownLength is how much of a managed key APIKey.Prefix keeps.
+const managedKeyShownLength = len(managedKeyPrefix) + 8
+
+// generateAPIKey returns a new managed API key with 256 random bits.
+func generateAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return managedKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashAPIKey returns the hex SHA-256 under which a managed key is stored. The
+// keys are random, so an unsalted fast hash is enough.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// principal returns the caller authenticated by k.
+func (k *APIKey) principal() Principal {
+	return Principal{
+		Name:       k.Name,
+		Namespace:  k.Namespace,
+		Admin:      k.Role == RoleAdmin,
+		ReadOnly:   k.Role == RoleReadOnly,
+		KeyID:      k.ID,
+		ReadLimit:  k.ReadLimit,
+		WriteLimit: k.WriteLimit,
+	}
+}
+
+// @Summary      Create an API key
+// @Description  Create a bearer key for a tenant namespace with a role and, optionally, rate limits of its own. The key is returned only in this response; the server keeps just its hash. Keys configured through CORTEX_API_KEY and CORTEX_API_KEYS keep working alongside managed keys.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        keyRequest  body      CreateAPIKeyRequest      true  "Key definition"
+// @Success      201         {object}  CreatedAPIKey            "Key created. Example: {\"id\":\"6b1d2f0e-7c3a-4e59-8f21-0a9b8c7d6e5f\",\"name\":\"ci-pipeline\",\"prefix\":\"ctx_Yq3v9Kd1\",\"namespace\":\"default\",\"role\":\"submit\",\"write_limit\":20,\"created_at\":\"2024-01-02T15:04:05Z\",\"created_by\":\"admin\",\"key\":\"ctx_Yq3v9Kd1c8Zr2Lw0Hs5Nt7Bx4Ma6Pe1Vf9Gj3Qu0Ek\"}"
+// @Failure      400         {object}  ValidationErrorResponse  "Malformed JSON body or failed validation. Example: {\"error\":\"invalid request payload\",\"details\":[{\"field\":\"role\",\"rule\":\"oneof\",\"message\":\"role must be one of: admin submit read-only\"}]}"
+// @Failure      401         {object}  ErrorResponse            "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      403         {object}  ErrorResponse            "The API key lacks administrative rights. Example: {\"error\":\"admin privileges required\"}"
+// @Failure      500         {object}  ErrorResponse            "The key could not be stored. Example: {\"error\":\"failed to persist api key\"}"
+// @Security     ApiKeyAuth
+// @Router       /admin/keys [post]
+func (s *Server) createAPIKeyHandler(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, newValidationErrorResponse(err))
+		return
+	}
+	if req.Namespace == "" {
+		req.Namespace = DefaultNamespace
+	}
+	if !namespacePattern.MatchString(req.Namespace) {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{Error: "invalid request payload", Details: []FieldError{
+			{Field: "namespace", Rule: "format", Message: "namespace must be 1-63 lowercase letters, digits, '-' or '_'"},
+		}})
+		return
+	}
+
+	id, err := generateUUID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to generate api key"})
+		return
+	}
+	secret, err := generateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to generate api key"})
+		return
+	}
+	principal := principalFrom(c)
+	key := APIKey{
+		ID:         id,
+		Name:       req.Name,
+		Prefix:     secret[:managedKeyShownLength],
+		Namespace:  req.Namespace,
+		Role:       req.Role,
+		ReadLimit:  req.ReadLimit,
+		WriteLimit: req.WriteLimit,
+		CreatedAt:  time.Now().UTC(),
+		CreatedBy:  principal.Name,
+	}
+	if err := s.store.CreateAPIKey(&key, hashAPIKey(secret)); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to persist api key"})
+		return
+	}
+	logging.Logger().Info("api key created", "key_id", key.ID, "name", key.Name, "namespace", key.Namespace,
+		"role", key.Role, "by", principal.Name, "client_ip", c.ClientIP())
+	c.JSON(http.StatusCreated, CreatedAPIKey{APIKey: key, Key: secret})
+}
+
+// @Summary      List API keys
+// @Description  List every managed API key, revoked ones included, oldest first. The keys themselves are never shown again; prefix identifies them.
+// @Tags         Admin
+// @Produce      json
+// @Success      200  {array}   APIKey         "Managed keys. Example: [{\"id\":\"6b1d2f0e-7c3a-4e59-8f21-0a9b8c7d6e5f\",\"name\":\"ci-pipeline\",\"prefix\":\"ctx_Yq3v9Kd1\",\"namespace\":\"default\",\"role\":\"submit\",\"created_at\":\"2024-01-02T15:04:05Z\",\"created_by\":\"admin\"}]"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      403  {object}  ErrorResponse  "The API key lacks administrative rights. Example: {\"error\":\"admin privileges required\"}"
+// @Failure      500  {object}  ErrorResponse  "The keys could not be read. Example: {\"error\":\"failed to list api keys\"}"
+// @Security     ApiKeyAuth
+// @Router       /admin/keys [get]
+func (s *Server) listAPIKeysHandler(c *gin.Context) {
+	keys, err := s.store.ListAPIKeys()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to list api keys"})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// @Summary      Revoke an API key
+// @Description  Stop a managed API key from authenticating, effective with the next request. The key's record is kept with revoked_at set; scans it submitted are unaffected. Revoking a revoked key returns it unchanged.
+// @Tags         Admin
+// @Produce      json
+// @Param        id   path      string         true  "API key ID (UUID v4)"
+// @Success      200  {object}  APIKey         "Key revoked. Example: {\"id\":\"6b1d2f0e-7c3a-4e59-8f21-0a9b8c7d6e5f\",\"name\":\"ci-pipeline\",\"prefix\":\"ctx_Yq3v9Kd1\",\"namespace\":\"default\",\"role\":\"submit\",\"created_at\":\"2024-01-02T15:04:05Z\",\"created_by\":\"admin\",\"revoked_at\":\"2024-02-01T09:00:00Z\"}"
+// @Failure      400  {object}  ErrorResponse  "Malformed key identifier. Example: {\"error\":\"invalid api key id format\"}"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      403  {object}  ErrorResponse  "The API key lacks administrative rights. Example: {\"error\":\"admin privileges required\"}"
+// @Failure      404  {object}  ErrorResponse  "No key with this ID exists. Example: {\"error\":\"api key not found\"}"
+// @Failure      500  {object}  ErrorResponse  "The key could not be revoked. Example: {\"error\":\"failed to revoke api key\"}"
+// @Security     ApiKeyAuth
+// @Router       /admin/keys/{id} [delete]
+func (s *Server) revokeAPIKeyHandler(c *gin.Context) {
+	id := c.Param("id")
+	if !uuidV4Pattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid api key id format"})
+		return
+	}
+	key, err := s.store.RevokeAPIKey(id)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "api key not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to revoke api key"})
+		return
+	}
+	logging.Logger().Warn("api key revoked", "key_id", key.ID, "name", key.Name, "by", principalFrom(c).Name, "client_ip", c.ClientIP())
+	c.JSON(http.StatusOK, key)
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// HTTPServerConfig tunes the HTTP server of the API.
+type HTTPServerConfig struct {
+	// ReadHeaderTimeout and ReadTimeout bound reading the headers and the
+	// whole request; WriteTimeout bounds writing the response and
+	// IdleTimeout how long a keep-alive connection may wait for the next
+	// request. Zero means no limit.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// HTTP2 offers HTTP/2 to TLS clients; H2C also accepts it without TLS,
+	// for proxies that speak cleartext HTTP/2 to the API.
+	HTTP2 bool
+	H2C   bool
+}
+
+// defaultListenAddr returns the listen address from CORTEX_LISTEN_ADDR, or
+// the older CORTEX_ADDR, with the port replaced by CORTEX_PORT when set.
+func defaultListenAddr() string {
+	addr := getenv("CORTEX_LISTEN_ADDR", getenv("CORTEX_ADDR", "0.0.0.0:8080"))
+	if port := os.Getenv("CORTEX_PORT"); port != "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			addr = net.JoinHostPort(host, port)
+		}
+	}
+	return addr
+}
+
+// checkListenAddr verifies that addr is host:port with a usable port.
+func checkListenAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("CORTEX_LISTEN_ADDR must be host:port: %w", err)
+	}
+	if number, err := strconv.Atoi(port); err != nil || number < 0 || number > 65535 {
+		return fmt.Errorf("CORTEX_LISTEN_ADDR port %q must be a number from 0 to 65535", port)
+	}
+	return nil
+}
+
+// loadHTTPServerConfig reads the HTTP server settings from the environment:
+// CORTEX_READ_HEADER_TIMEOUT (default 10s, which guards against slow
+// clients), CORTEX_READ_TIMEOUT and CORTEX_WRITE_TIMEOUT (default 0, as
+// either would cut off result streams and server-sent events once it
+// passes), CORTEX_IDLE_TIMEOUT (default 2m), CORTEX_HTTP2 (default true)
+// and CORTEX_H2C (default false).
+func loadHTTPServerConfig() (HTTPServerConfig, error) {
+	var cfg HTTPServerConfig
+	var err error
+	for _, timeout := range []struct {
+		key      string
+		value    *time.Duration
+		fallback time.Duration
+	}{
+		{"CORTEX_READ_HEADER_TIMEOUT", &cfg.ReadHeaderTimeout, 10 * time.Second},
+		{"CORTEX_READ_TIMEOUT", &cfg.ReadTimeout, 0},
+		{"CORTEX_WRITE_TIMEOUT", &cfg.WriteTimeout, 0},
+		{"CORTEX_IDLE_TIMEOUT", &cfg.IdleTimeout, 2 * time.Minute},
+	} {
+		if *timeout.value, err = getenvDuration(timeout.key, timeout.fallback); err != nil {
+			return cfg, err
+		}
+		if *timeout.value < 0 {
+			return cfg, fmt.Errorf("%s must not be negative", timeout.key)
+		}
+	}
+	if cfg.HTTP2, err = getenvBool("CORTEX_HTTP2", true); err != nil {
+		return cfg, err
+	}
+	if cfg.H2C, err = getenvBool("CORTEX_H2C", false); err != nil {
+		return cfg, err
+	}
+	if cfg.H2C && !cfg.HTTP2 {
+		return cfg, fmt.Errorf("CORTEX_H2C requires CORTEX_HTTP2")
+	}
+	return cfg, nil
+}
+
+// newHTTPServer returns the server for handler configured by cfg, with the
+// TLS settings in place when TLS is enabled.
+func newHTTPServer(cfg *Config, handler http.Handler) (*http.Server, error) {
+	server := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+		Protocols:         new(http.Protocols),
+	}
+	server.Protocols.SetHTTP1(true)
+	server.Protocols.SetHTTP2(cfg.HTTP.HTTP2)
+	server.Protocols.SetUnencryptedHTTP2(cfg.HTTP.H2C)
+	if !cfg.TLSEnabled {
+		return server, nil
+	}
+	serverTLS, err := cfg.TLS.serverTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	server.TLSConfig = serverTLS
+	return server, nil
+}
+
+// clearStreamDeadlines lifts the read and write deadlines the server set
+// for the request, so a long-lived stream outlives CORTEX_READ_TIMEOUT and
+// CORTEX_WRITE_TIMEOUT. Streams end when the client goes away instead.
+func clearStreamDeadlines(w http.ResponseWriter) {
+	controller := http.NewResponseController(w)
+	_ = controller.SetReadDeadline(time.Time{})
+	_ = controller.SetWriteDeadline(time.Time{})
+}
+
+// selfSignedValidity is how long a generated development certificate is valid.
+const selfSignedValidity = 90 * 24 * time.Hour
+
+// selfSignedCertificate generates a throwaway ECDSA certificate for
+// localhost, the loopback addresses and the machine's hostname. Clients
+// cannot verify it; it only suits development.
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	names := []string{"localhost"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "localhost" {
+		names = append(names, hostname)
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "cortex development certificate"},
+		DNSNames:              names,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -2,10 +2,15 @@ package api
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -46,59 +51,273 @@ func RequestLoggingMiddleware(logger *slog.Logger) gin.HandlerFunc {
 	}
 }
 
-// AuthMiddleware enforces API key authentication using a constant time comparison.
-func AuthMiddleware(expectedKey string, logger *slog.Logger) gin.HandlerFunc {
-	expected := []byte(expectedKey)
+// Principal identifies the authenticated caller of a request.
+type Principal struct {
+	// Name labels the credential in logs and audit fields; it is never the secret itself.
+	Name string
+	// Namespace is the tenant whose tasks the caller may see and create.
+	Namespace string
+	// Admin grants access to administrative endpoints.
+	Admin bool
+	// ReadOnly restricts the caller to read requests.
+	ReadOnly bool
+	// KeyID identifies a managed API key; ReadLimit and WriteLimit, when
+	// set, replace the configured rate limits for it.
+	KeyID      string
+	ReadLimit  int64
+	WriteLimit int64
+}
+
+const principalContextKey = "cortex.principal"
+
+// Credentials lists everything AuthMiddleware accepts as proof of identity.
+type Credentials struct {
+	// APIKeys maps bearer tokens to principals.
+	APIKeys map[string]Principal
+	// ClientCerts maps a verified client certificate CN or SAN to a principal.
+	ClientCerts map[string]Principal
+	// Keys holds the API keys managed through the admin API, when set.
+	Keys TaskStore
+	// JWT validates bearer JWTs, when set.
+	JWT *JWTVerifier
+}
+
+// AuthMiddleware authenticates callers and stores the resulting Principal in the
+// context. A verified TLS client certificate whose CN or SAN is mapped to an
+// identity is accepted without a bearer token; otherwise the bearer token is
+// compared in constant time against every configured API key, managed keys
+// are looked up by their hash and JWTs are validated against the provider's
+// signing keys. Read-only callers are refused anything
+// but read requests.
+func AuthMiddleware(creds Credentials, logger *slog.Logger) gin.HandlerFunc {
+	authenticate := authenticator(creds, logger)
 	return func(c *gin.Context) {
+		principal, ok := authenticate(c)
+		if !ok {
+			return
+		}
+		if principal.ReadOnly && routeClass(c.Request.Method) != routeClassRead {
+			c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{Error: "api key is read-only"})
+			return
+		}
+		c.Set(principalContextKey, principal)
+		c.Next()
+	}
+}
+
+// authenticator returns the function identifying the caller of a request. It
+// aborts the request and reports false when the caller cannot be identified.
+func authenticator(creds Credentials, logger *slog.Logger) func(c *gin.Context) (Principal, bool) {
+	return func(c *gin.Context) (Principal, bool) {
+		if principal, ok := clientCertPrincipal(c, creds.ClientCerts); ok {
+			return principal, true
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			unauthorized(c)
 			logger.Warn("missing authorization header", "client_ip", c.ClientIP())
-			return
+			return Principal{}, false
 		}
 
 		if !strings.HasPrefix(authHeader, "Bearer ") {
 			unauthorized(c)
 			logger.Warn("unsupported authorization header", "client_ip", c.ClientIP())
-			return
+			return Principal{}, false
 		}
 
 		providedToken := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
 		provided := []byte(providedToken)
-		if len(provided) != len(expected) || subtle.ConstantTimeCompare(provided, expected) != 1 {
-			unauthorized(c)
-			logger.Warn("invalid api key", "client_ip", c.ClientIP())
-			return
+
+		// Compare against every key so the response time does not reveal which one matched
+		var principal Principal
+		matched := false
+		for key, candidate := range creds.APIKeys {
+			expected := []byte(key)
+			if len(provided) == len(expected) && subtle.ConstantTimeCompare(provided, expected) == 1 {
+				principal = candidate
+				matched = true
+			}
 		}
+		if matched {
+			return principal, true
+		}
+
+		if creds.JWT != nil && looksLikeJWT(providedToken) {
+			principal, err := creds.JWT.Principal(providedToken)
+			if err != nil {
+				unauthorized(c)
+				logger.Warn("invalid jwt", "client_ip", c.ClientIP(), "error", err)
+				return Principal{}, false
+			}
+			return principal, true
+		}
+
+		if creds.Keys != nil && strings.HasPrefix(providedToken, managedKeyPrefix) {
+			key, err := creds.Keys.APIKeyByHash(hashAPIKey(providedToken))
+			if err == nil {
+				return key.principal(), true
+			}
+			if !errors.Is(err, ErrAPIKeyNotFound) {
+				logger.Error("failed to look up api key", "error", err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: "internal server error"})
+				return Principal{}, false
+			}
+		}
+		unauthorized(c)
+		logger.Warn("invalid api key", "client_ip", c.ClientIP())
+		return Principal{}, false
+	}
+}
+
+// clientCertPrincipal maps the verified client certificate of a TLS connection
+// to a principal. Unverified certificates are never consulted.
+func clientCertPrincipal(c *gin.Context, identities map[string]Principal) (Principal, bool) {
+	state := c.Request.TLS
+	if len(identities) == 0 || state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return Principal{}, false
+	}
+	for _, name := range certificateIdentities(state.VerifiedChains[0][0]) {
+		if principal, ok := identities[name]; ok {
+			return principal, true
+		}
+	}
+	return Principal{}, false
+}
 
+// RequireAdmin rejects callers whose Principal lacks administrative rights.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !principalFrom(c).Admin {
+			c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{Error: "admin privileges required"})
+			return
+		}
 		c.Next()
 	}
 }
 
+// principalFrom returns the Principal stored by AuthMiddleware, or the zero value.
+func principalFrom(c *gin.Context) Principal {
+	if value, ok := c.Get(principalContextKey); ok {
+		if principal, ok := value.(Principal); ok {
+			return principal
+		}
+	}
+	return Principal{}
+}
+
 func unauthorized(c *gin.Context) {
 	c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
 }
 
-// RateLimitMiddleware enforces a per-IP rate limit backed by Redis.
-func RateLimitMiddleware(client *redis.Client, limit int64, window time.Duration, logger *slog.Logger) gin.HandlerFunc {
+// Rate limit key strategies supported by RateLimitMiddleware.
+const (
+	RateLimitByIP        = "ip"
+	RateLimitByAPIKey    = "apikey"
+	RateLimitByNamespace = "namespace"
+)
+
+// Route classes used to give cheap reads and expensive writes separate buckets.
+const (
+	routeClassRead  = "read"
+	routeClassWrite = "write"
+)
+
+// RateLimitConfig tunes RateLimitMiddleware.
+type RateLimitConfig struct {
+	// ReadLimit is the number of read requests (GET, HEAD) allowed per window.
+	ReadLimit int64
+	// WriteLimit is the number of write requests (POST, DELETE, ...) allowed per window.
+	WriteLimit int64
+	// Window is the length of the counting window.
+	Window time.Duration
+	// KeyStrategy selects what a counter is attributed to: RateLimitByIP,
+	// RateLimitByAPIKey or RateLimitByNamespace.
+	KeyStrategy string
+}
+
+// rateLimitScript implements the generic cell rate algorithm (GCRA). The key
+// holds the theoretical arrival time (TAT) in milliseconds: each accepted request
+// pushes it one emission interval (window / limit) further, and a request is
+// rejected while the TAT lies more than a full window ahead of now. This allows
+// a burst of up to limit requests but, unlike a fixed window, never 2x limit
+// across a window boundary. The script runs atomically in Redis and reads the
+// Redis clock, so concurrent API replicas share one consistent view.
+// It returns {allowed, remaining, retry_after_ms}. TIME before a write needs
+// effects replication, the default since Redis 5.
+var rateLimitScript = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local window = interval * limit
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if tat == nil or tat < now then
+  tat = now
+end
+local next_tat = tat + interval
+local allow_at = next_tat - window
+if allow_at > now then
+  return {0, 0, math.ceil(allow_at - now)}
+end
+redis.call('SET', KEYS[1], tostring(next_tat), 'PX', math.ceil(next_tat - now))
+return {1, math.floor((now - allow_at) / interval), 0}
+`)
+
+// RateLimitMiddleware enforces a rate limit backed by Redis, counted per client IP
+// or per API key depending on the configured strategy. Reads and writes are
+// tracked in separate buckets with their own limits so polling clients are not
+// starved by scan submissions and vice versa. Limits are enforced with GCRA
+// (see rateLimitScript): limit requests may arrive at once, after which capacity
+// returns steadily at limit per window.
+// Managed API keys with a read or write limit of their own are counted in a
+// bucket per key against that limit instead.
+// Every response carries X-RateLimit-Limit and X-RateLimit-Remaining headers;
+// rejected requests additionally carry Retry-After so clients can back off.
+// The configuration is read from limits on every request so it can be swapped
+// at runtime; while limits holds nil, requests pass unlimited.
+func RateLimitMiddleware(client *redis.Client, limits *atomic.Pointer[RateLimitConfig], logger *slog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		cfg := limits.Load()
+		if cfg == nil {
+			c.Next()
+			return
+		}
+		window := cfg.Window
 		ctx := c.Request.Context()
 		if ctx == nil {
 			ctx = context.Background()
 		}
 
-		key := fmt.Sprintf("ratelimit:%s", c.ClientIP())
-		pipe := client.TxPipeline()
-		counter := pipe.Incr(ctx, key)
-		pipe.Expire(ctx, key, window)
-		if _, err := pipe.Exec(ctx); err != nil {
+		class := routeClass(c.Request.Method)
+		principal := principalFrom(c)
+		limit, override := cfg.WriteLimit, principal.WriteLimit
+		if class == routeClassRead {
+			limit, override = cfg.ReadLimit, principal.ReadLimit
+		}
+		bucket := rateLimitKey(c, cfg.KeyStrategy)
+		if override > 0 {
+			// A key with limits of its own does not share its bucket
+			limit, bucket = override, fmt.Sprintf("keyid:%s", principal.KeyID)
+		}
+
+		key := fmt.Sprintf("ratelimit:%s:%s", class, bucket)
+		interval := float64(window.Milliseconds()) / float64(limit)
+		reply, err := rateLimitScript.Run(ctx, client, []string{key},
+			strconv.FormatFloat(interval, 'f', -1, 64), limit).Int64Slice()
+		if err != nil || len(reply) != 3 {
 			logger.Error("rate limiter redis error", "error", err)
 			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: "internal server error"})
 			return
 		}
+		allowed, remaining := reply[0] == 1, reply[1]
+		retryAfter := time.Duration(reply[2]) * time.Millisecond
+
+		setRateLimitHeaders(c, limit, remaining, allowed, retryAfter)
 
-		if counter.Val() > limit {
-			logger.Warn("rate limit exceeded", "client_ip", c.ClientIP(), "count", counter.Val())
+		if !allowed {
+			logger.Warn("rate limit exceeded", "client_ip", c.ClientIP(), "route_class", class, "retry_after", retryAfter)
 			c.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorResponse{Error: "rate limit exceeded"})
 			return
 		}
@@ -107,6 +326,82 @@ func RateLimitMiddleware(client *redis.Client, limit int64, window time.Duration
 	}
 }
 
+// routeClass buckets a request into the read or write rate limit tier.
+func routeClass(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return routeClassRead
+	default:
+		return routeClassWrite
+	}
+}
+
+// rateLimitKey identifies the caller for counting purposes. API keys are hashed so
+// raw credentials never end up in Redis key names. The namespace strategy gives
+// every tenant one shared quota regardless of how many keys or hosts it uses.
+func rateLimitKey(c *gin.Context, strategy string) string {
+	if strategy == RateLimitByNamespace {
+		return fmt.Sprintf("ns:%s", principalFrom(c).Namespace)
+	}
+	if strategy == RateLimitByAPIKey {
+		token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+		if token != "" {
+			sum := sha256.Sum256([]byte(token))
+			return fmt.Sprintf("key:%s", hex.EncodeToString(sum[:8]))
+		}
+	}
+	return c.ClientIP()
+}
+
+// setRateLimitHeaders publishes the caller's quota as computed by the limiter.
+// Retry-After is only sent on rejected requests and is rounded up to whole seconds.
+func setRateLimitHeaders(c *gin.Context, limit, remaining int64, allowed bool, retryAfter time.Duration) {
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	headers := c.Writer.Header()
+	headers.Set("X-RateLimit-Limit", strconv.FormatInt(limit, 10))
+	headers.Set("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+
+	if !allowed {
+		retry := int64((retryAfter + time.Second - 1) / time.Second)
+		if retry < 1 {
+			retry = 1
+		}
+		headers.Set("Retry-After", strconv.FormatInt(retry, 10))
+	}
+}
+
+// BodySizeLimitMiddleware caps request bodies at maxBytes, or at the limit
+// routeLimits gives for the matched route path. Requests announcing a larger
+// Content-Length are rejected up front with 413; bodies without a length are
+// cut off while being read so handlers can answer 413 as well.
+func BodySizeLimitMiddleware(maxBytes int64, routeLimits map[string]int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		maxBytes := maxBytes
+		if limit, ok := routeLimits[c.FullPath()]; ok {
+			maxBytes = limit
+		}
+		if c.Request.ContentLength > maxBytes {
+			abortBodyTooLarge(c, maxBytes)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
+
+func abortBodyTooLarge(c *gin.Context, maxBytes int64) {
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, ErrorResponse{
+		Error: fmt.Sprintf("request body exceeds the %d byte limit", maxBytes),
+	})
+}
+
 // SecurityHeadersMiddleware adds standard security headers to each response.
 func SecurityHeadersMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -0,0 +1,434 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// minMonitorInterval is the shortest accepted delay between verification scans.
+const minMonitorInterval = time.Minute
+
+// monitorSchedulerTick is how often the scheduler looks for due monitors.
+const monitorSchedulerTick = 30 * time.Second
+
+// monitorSchedulerLease is the lease the API process queueing verification
+// scans holds, and monitorSchedulerLeaseTTL how long it outlives its last
+// renewal.
+const (
+	monitorSchedulerLease    = "monitors"
+	monitorSchedulerLeaseTTL = 3 * monitorSchedulerTick
+)
+
+// monitorSaveAttempts bounds how often a finished verification scan is
+// evaluated again because its monitor changed while it was evaluated.
+const monitorSaveAttempts = 3
+
+// @Summary      Create a monitor
+// @Description  Declare the desired state of an asset (the ports expected to be open within a range) and how often to verify it. The scheduler queues a verification scan right away and then once per interval; each scan is diffed against the previous one, so the completion webhook fires only when something changed.
+// @Tags         Monitors
+// @Accept       json
+// @Produce      json
+// @Param        monitorRequest  body      CreateMonitorRequest     true  "Monitor definition"
+// @Success      201             {object}  Monitor                  "Monitor created. Example: {\"id\":\"0f8e3a6d-2c41-4b9e-9a57-3d6c1e2b4f80\",\"host\":\"203.0.113.50\",\"ports\":\"1-1024\",\"mode\":\"connect\",\"expected_open\":[80,443],\"interval\":\"1h\",\"created_at\":\"2024-01-02T15:04:05Z\"}"
+// @Failure      400             {object}  ValidationErrorResponse  "Malformed JSON body or failed validation. Example: {\"error\":\"invalid request payload\",\"details\":[{\"field\":\"interval\",\"rule\":\"min\",\"message\":\"interval must be at least 1m0s\"}]}"
+// @Failure      401             {object}  ErrorResponse            "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      429             {object}  ErrorResponse            "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500             {object}  ErrorResponse            "Internal error while persisting the monitor. Example: {\"error\":\"failed to persist monitor\"}"
+// @Security     ApiKeyAuth
+// @Router       /monitors [post]
+func (s *Server) createMonitorHandler(c *gin.Context) {
+	var req CreateMonitorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, newValidationErrorResponse(err))
+		return
+	}
+	if details := s.monitorRequestErrors(req); len(details) > 0 {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{Error: "invalid request payload", Details: details})
+		return
+	}
+
+	id, err := generateUUID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to generate monitor id"})
+		return
+	}
+	expected := append([]int{}, req.ExpectedOpen...)
+	sort.Ints(expected)
+	monitor := &Monitor{
+		ID:           id,
+		Name:         req.Name,
+		Host:         req.Host,
+		Ports:        req.Ports,
+		Mode:         req.Mode,
+		ExpectedOpen: expected,
+		Interval:     req.Interval,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if err := s.tasks(c).SaveMonitor(monitor); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to persist monitor"})
+		return
+	}
+	c.JSON(http.StatusCreated, monitor)
+}
+
+// monitorRequestErrors validates the parts of a monitor definition that
+// binding tags cannot express.
+func (s *Server) monitorRequestErrors(req CreateMonitorRequest) []FieldError {
+	var details []FieldError
+	if interval, err := time.ParseDuration(req.Interval); err != nil {
+		details = append(details, FieldError{Field: "interval", Rule: "duration", Message: fmt.Sprintf("interval must be a Go duration such as 15m or 1h: %v", err)})
+	} else if interval < minMonitorInterval {
+		details = append(details, FieldError{Field: "interval", Rule: "min", Message: fmt.Sprintf("interval must be at least %s", minMonitorInterval)})
+	}
+
+	ports, err := parsePorts(req.Ports, req.Mode)
+	if err != nil {
+		details = append(details, FieldError{Field: "ports", Rule: "format", Message: err.Error()})
+	} else {
+		monitored := make(map[int]bool, len(ports))
+		for _, port := range ports {
+			monitored[port] = true
+		}
+		for i, port := range req.ExpectedOpen {
+			if !monitored[port] {
+				details = append(details, FieldError{
+					Field:   fmt.Sprintf("expected_open[%d]", i),
+					Rule:    "range",
+					Message: fmt.Sprintf("port %d is not among the monitored ports %s", port, req.Ports),
+				})
+			}
+		}
+	}
+
+	if network, blocked := s.blocklist.MatchLiteral(req.Host); blocked {
+		details = append(details, FieldError{Field: "host", Rule: "blocked", Message: fmt.Sprintf("%s is in blocked range %s", req.Host, network)})
+	}
+	return details
+}
+
+// @Summary      List monitors
+// @Description  Return every monitor of the caller with the outcome of its last verification scan, oldest first.
+// @Tags         Monitors
+// @Produce      json
+// @Success      200  {array}   Monitor        "Monitors of the caller."
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      429  {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse  "Internal error while loading monitors. Example: {\"error\":\"failed to load monitors\"}"
+// @Security     ApiKeyAuth
+// @Router       /monitors [get]
+func (s *Server) listMonitorsHandler(c *gin.Context) {
+	monitors, err := s.tasks(c).ListMonitors()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load monitors"})
+		return
+	}
+	c.JSON(http.StatusOK, monitors)
+}
+
+// @Summary      Report drifted assets
+// @Description  List the monitors whose last verification scan found ports open that should not be, or expected ports that were not open. Monitors without a finished verification are counted as unchecked.
+// @Tags         Monitors
+// @Produce      json
+// @Success      200  {object}  DriftReport    "Assets out of compliance. Example: {\"monitors\":12,\"unchecked\":1,\"drifted\":[{\"id\":\"0f8e3a6d-2c41-4b9e-9a57-3d6c1e2b4f80\",\"host\":\"203.0.113.50\",\"compliant\":false,\"unexpected\":[3389]}]}"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      429  {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse  "Internal error while loading monitors. Example: {\"error\":\"failed to load monitors\"}"
+// @Security     ApiKeyAuth
+// @Router       /monitors/drift [get]
+func (s *Server) driftReportHandler(c *gin.Context) {
+	monitors, err := s.tasks(c).ListMonitors()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load monitors"})
+		return
+	}
+	report := DriftReport{Monitors: len(monitors), Drifted: []Monitor{}}
+	for _, monitor := range monitors {
+		switch {
+		case monitor.Compliant == nil:
+			report.Unchecked++
+		case !*monitor.Compliant:
+			report.Drifted = append(report.Drifted, *monitor)
+		}
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary      Get a monitor
+// @Description  Return one monitor with the outcome of its last verification scan.
+// @Tags         Monitors
+// @Produce      json
+// @Param        id   path      string         true  "Monitor ID (UUID v4)"
+// @Success      200  {object}  Monitor        "Monitor definition and last verification outcome."
+// @Failure      400  {object}  ErrorResponse  "Malformed monitor identifier. Example: {\"error\":\"invalid monitor id format\"}"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      404  {object}  ErrorResponse  "Monitor with the provided ID does not exist. Example: {\"error\":\"monitor not found\"}"
+// @Failure      429  {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse  "Internal error when loading the monitor. Example: {\"error\":\"failed to load monitor\"}"
+// @Security     ApiKeyAuth
+// @Router       /monitors/{id} [get]
+func (s *Server) getMonitorHandler(c *gin.Context) {
+	id := c.Param("id")
+	if !uuidV4Pattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid monitor id format"})
+		return
+	}
+	monitor, err := s.tasks(c).GetMonitor(id)
+	if err != nil {
+		if err == ErrMonitorNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "monitor not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load monitor"})
+		return
+	}
+	c.JSON(http.StatusOK, monitor)
+}
+
+// @Summary      Delete a monitor
+// @Description  Stop verifying an asset. Verification scans already queued still run, and past scans are kept until the retention janitor removes them.
+// @Tags         Monitors
+// @Param        id   path      string         true  "Monitor ID (UUID v4)"
+// @Success      204  "Monitor deleted."
+// @Failure      400  {object}  ErrorResponse  "Malformed monitor identifier. Example: {\"error\":\"invalid monitor id format\"}"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      429  {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse  "Internal error when deleting the monitor. Example: {\"error\":\"failed to delete monitor\"}"
+// @Security     ApiKeyAuth
+// @Router       /monitors/{id} [delete]
+func (s *Server) deleteMonitorHandler(c *gin.Context) {
+	id := c.Param("id")
+	if !uuidV4Pattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid monitor id format"})
+		return
+	}
+	if err := s.tasks(c).DeleteMonitor(id); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to delete monitor"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// MonitorScheduler queues verification scans for monitors whose interval has
+// elapsed. A monitor whose previous scan is still pending or running is
+// skipped so slow scans do not pile up. Only the replica holding the
+// scheduler lease queues scans.
+type MonitorScheduler struct {
+	store  TaskStore
+	holder string
+	logger *slog.Logger
+	leader bool
+}
+
+// NewMonitorScheduler constructs a scheduler for the given store.
+func NewMonitorScheduler(store TaskStore, logger *slog.Logger) *MonitorScheduler {
+	hostname, _ := os.Hostname()
+	return &MonitorScheduler{
+		store:  store,
+		holder: fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano()),
+		logger: logger,
+	}
+}
+
+// Start checks for due monitors immediately and then periodically in the background.
+func (m *MonitorScheduler) Start() {
+	go func() {
+		ticker := time.NewTicker(monitorSchedulerTick)
+		defer ticker.Stop()
+		for {
+			m.tick(time.Now().UTC())
+			<-ticker.C
+		}
+	}()
+}
+
+// tick renews the monitor scheduler lease and, while this process holds it,
+// queues the verification scans that are due.
+func (m *MonitorScheduler) tick(now time.Time) {
+	leader, err := m.store.AcquireLease(monitorSchedulerLease, m.holder, monitorSchedulerLeaseTTL)
+	if err != nil {
+		m.logger.Error("monitor scheduler lease renewal failed", "error", err)
+		leader = false
+	}
+	if leader != m.leader {
+		m.leader = leader
+		m.logger.Info("monitor scheduler leadership changed", "leader", leader, "holder", m.holder)
+	}
+	if !leader {
+		return
+	}
+	if queued, err := m.Schedule(now); err != nil {
+		m.logger.Error("monitor scheduling failed", "error", err, "queued", queued)
+	} else if queued > 0 {
+		m.logger.Info("monitor verification scans queued", "count", queued)
+	}
+}
+
+// Schedule queues a verification scan for every due monitor in every
+// namespace and returns how many were queued. A monitor that fails is
+// reported and the others are still verified.
+func (m *MonitorScheduler) Schedule(now time.Time) (int, error) {
+	namespaces, err := m.store.Namespaces()
+	if err != nil {
+		return 0, fmt.Errorf("list namespaces: %w", err)
+	}
+	queued := 0
+	var errs []error
+	for _, namespace := range namespaces {
+		store := m.store.Namespace(namespace)
+		monitors, err := store.ListMonitors()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", namespace, err))
+			continue
+		}
+		for _, monitor := range monitors {
+			due, baseline, err := monitorDue(store, monitor, now)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("monitor %s: %w", monitor.ID, err))
+				continue
+			}
+			if !due {
+				continue
+			}
+			ran, err := queueVerification(store, monitor, baseline, now)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("monitor %s: %w", monitor.ID, err))
+				continue
+			}
+			if ran {
+				queued++
+			}
+		}
+	}
+	return queued, errors.Join(errs...)
+}
+
+// monitorDue reports whether monitor needs a verification scan at now and,
+// if so, which completed task the new scan should be diffed against.
+func monitorDue(store TaskStore, monitor *Monitor, now time.Time) (bool, string, error) {
+	interval, err := time.ParseDuration(monitor.Interval)
+	if err != nil {
+		return false, "", err
+	}
+	if monitor.LastRunAt != nil && now.Sub(*monitor.LastRunAt) < interval {
+		return false, "", nil
+	}
+	if monitor.LastTaskID == "" {
+		return true, "", nil
+	}
+	last, err := store.GetTask(monitor.LastTaskID)
+	if err == ErrTaskNotFound {
+		return true, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	switch last.Status {
+	case "pending", "held", "running", "pausing", "paused", "cancelling":
+		return false, "", nil
+	case "completed":
+		return true, last.ID, nil
+	default:
+		return true, "", nil
+	}
+}
+
+// queueVerification creates and queues the next verification scan of
+// monitor. The monitor is pointed at the scan before the scan is created, so
+// a monitor deleted or changed since it was loaded gets none; false is
+// reported then.
+func queueVerification(store TaskStore, monitor *Monitor, baseline string, now time.Time) (bool, error) {
+	taskID, err := generateUUID()
+	if err != nil {
+		return false, err
+	}
+	previous := *monitor
+	monitor.LastTaskID = taskID
+	monitor.LastRunAt = &now
+	if claimed, err := store.ReplaceMonitor(&previous, monitor); err != nil || !claimed {
+		return false, err
+	}
+
+	task := &ScanTask{
+		ID:        taskID,
+		Status:    "pending",
+		Hosts:     []string{monitor.Host},
+		Ports:     monitor.Ports,
+		Mode:      monitor.Mode,
+		Monitor:   monitor.ID,
+		Baseline:  baseline,
+		CreatedAt: now,
+	}
+	if err := store.CreateTask(task); err != nil {
+		return false, fmt.Errorf("persist task: %w", err)
+	}
+	if err := store.PushToQueue(task.ID, task.Mode); err != nil {
+		task.Status = "failed"
+		task.Error = "failed to queue task"
+		task.CompletedAt = &now
+		_ = store.UpdateTask(task)
+		return false, fmt.Errorf("queue task: %w", err)
+	}
+	return true, nil
+}
+
+// recordVerification evaluates a finished verification scan against the
+// desired state of the monitor that scheduled it. Results of scans that were
+// superseded by a newer one, or whose monitor was deleted, are ignored; a
+// monitor that changes while the scan is evaluated is loaded again.
+func recordVerification(store TaskStore, task *ScanTask) error {
+	for attempt := 0; attempt < monitorSaveAttempts; attempt++ {
+		monitor, err := store.GetMonitor(task.Monitor)
+		if err == ErrMonitorNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if monitor.LastTaskID != task.ID {
+			return nil
+		}
+		previous := *monitor
+		evaluateVerification(monitor, task)
+		if replaced, err := store.ReplaceMonitor(&previous, monitor); err != nil || replaced {
+			return err
+		}
+	}
+	return fmt.Errorf("monitor %s kept changing while scan %s was recorded", task.Monitor, task.ID)
+}
+
+// evaluateVerification sets the verification outcome of monitor from its
+// finished scan task.
+func evaluateVerification(monitor *Monitor, task *ScanTask) {
+	monitor.CheckedAt = task.CompletedAt
+	monitor.Compliant, monitor.Unexpected, monitor.Missing, monitor.Error = nil, nil, nil, ""
+	if task.Status != "completed" {
+		monitor.Error = task.Error
+		return
+	}
+
+	open := make(map[int]bool)
+	for _, result := range task.Results {
+		if result.State == "Open" {
+			open[result.Port] = true
+		}
+	}
+	for _, port := range monitor.ExpectedOpen {
+		if !open[port] {
+			monitor.Missing = append(monitor.Missing, port)
+		}
+		delete(open, port)
+	}
+	for port := range open {
+		monitor.Unexpected = append(monitor.Unexpected, port)
+	}
+	sort.Ints(monitor.Unexpected)
+	compliant := len(monitor.Unexpected) == 0 && len(monitor.Missing) == 0
+	monitor.Compliant = &compliant
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/Viktor25104/cortex/backend/logging"
+	"github.com/Viktor25104/cortex/backend/scanner"
+	"github.com/Viktor25104/cortex/backend/version"
+	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// shutdownTimeout bounds how long a stopping process waits for in-flight
+// requests to finish and for buffered spans to be exported.
+const shutdownTimeout = 10 * time.Second
+
+// node holds what an API server and a standalone worker share: the store,
+// the Redis connection and the worker pools taking scans from the queue.
+type node struct {
+	redis   *redis.Client
+	store   TaskStore
+	pools   *WorkerPools
+	metrics *Metrics
+	// tracing is nil unless spans are exported
+	tracing *sdktrace.TracerProvider
+}
+
+// startNode connects to the stores, starts the worker pools and the queue
+// reaper and registers the process in the worker registry.
+func startNode(cfg *Config, logger *slog.Logger) (*node, error) {
+	redisClient := redis.NewClient(&redis.Options{Addr: cfg.RedisAddr})
+
+	if err := redisClient.Ping(context.Background()).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", cfg.RedisAddr, err)
+	}
+
+	// Redis keeps the rate limits and the fleet-wide pacer with either store
+	var store TaskStore
+	if cfg.StoreBackend == "postgres" {
+		postgresStore, err := OpenPostgresStore(cfg.PostgresDSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open postgres store: %w", err)
+		}
+		logger.Info("postgres task store ready", "migrations", len(postgresMigrations))
+		store = postgresStore
+	} else {
+		redisStore := NewRedisStore(redisClient)
+		if cfg.JanitorEnabled {
+			redisStore.SetTaskTTL(cfg.Janitor.Retention + taskKeyGrace)
+		}
+		if indexed, err := redisStore.IndexExistingTasks(); err != nil {
+			logger.Warn("failed to index existing tasks", "error", err)
+		} else if indexed > 0 {
+			logger.Info("indexed existing tasks", "count", indexed)
+		}
+		store = redisStore
+	}
+
+	serviceName := "cortex-api"
+	if cfg.WorkerMode {
+		serviceName = "cortex-worker"
+	}
+	tracing, err := setupTracing(serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	if tracing != nil {
+		logger.Info("opentelemetry tracing enabled", "service", serviceName)
+	}
+
+	probes, stats, err := scanner.LoadProbes(cfg.ProbesFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load probes: %w", err)
+	}
+	if len(stats.ErrorLines) > 0 {
+		logger.Warn("probe loader reported warnings", "count", len(stats.ErrorLines))
+	}
+
+	probeCache := scanner.NewProbeCache(probes)
+
+	if cfg.Blocklist.Len() > 0 {
+		logger.Info("blocked target ranges loaded", "count", cfg.Blocklist.Len())
+	}
+
+	notifier := NewNotifier(cfg.Notifier, cfg.Blocklist, logger)
+	if cfg.Notifier.URL != "" {
+		logger.Info("completion webhook enabled", "host", webhookHost(cfg.Notifier.URL))
+	}
+	NewCallbackDispatcher(store, notifier, logger).Start()
+
+	var pacer scanner.Pacer
+	if cfg.GlobalRate > 0 {
+		pacer = NewRedisPacer(redisClient, cfg.GlobalRate, logger)
+		logger.Info("fleet-wide probe rate enabled", "probes_per_second", cfg.GlobalRate)
+	}
+
+	metrics, err := NewMetrics(cfg.StatsD, logger)
+	if err != nil {
+		return nil, err
+	}
+	if metrics != nil {
+		logger.Info("statsd metrics enabled", "addr", cfg.StatsD.Addr, "dogstatsd", cfg.StatsD.DogStatsD)
+	}
+	metrics.StartQueueGauges(store)
+
+	capabilities := nodeCapabilities()
+	if cfg.WorkerMode {
+		restrictToCapabilities(&cfg.Workers, capabilities, logger)
+	}
+	pools := StartWorkers(store, probeCache, cfg.Blocklist, notifier, metrics, pacer, cfg.Workers)
+	logger.Info("worker pools started", "connect", cfg.Workers.Connect, "syn", cfg.Workers.Syn, "udp", cfg.Workers.UDP)
+	NewQueueReaper(store, cfg.Workers.StaleAfter, logger).Start()
+
+	self, err := newWorkerNode(cfg, capabilities)
+	if err != nil {
+		return nil, err
+	}
+	go registerNode(store, self, pools, logger)
+	logger.Info("registering worker node", "id", self.ID, "role", self.Role, "capabilities", self.Capabilities)
+	return &node{redis: redisClient, store: store, pools: pools, metrics: metrics, tracing: tracing}, nil
+}
+
+// shutdown exports the spans the node still buffers.
+func (n *node) shutdown(ctx context.Context) error {
+	if n.tracing == nil {
+		return nil
+	}
+	return n.tracing.Shutdown(ctx)
+}
+
+// serveUntilStopped runs serve until it fails or the process is asked to
+// stop, in which case httpServer finishes the requests in flight first.
+// Either way the node is shut down afterwards.
+func serveUntilStopped(httpServer *http.Server, serve func() error, node *node, logger *slog.Logger) error {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	served := make(chan error, 1)
+	go func() { served <- serve() }()
+
+	var err error
+	select {
+	case err = <-served:
+	case sig := <-stop:
+		logger.Info("shutting down", "signal", sig.String())
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		err = httpServer.Shutdown(ctx)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if shutdownErr := node.shutdown(ctx); shutdownErr != nil {
+		logger.Error("failed to flush traces", "error", shutdownErr)
+	}
+	return err
+}
+
+// RunWorker runs a standalone worker: it takes scans from the same queue as
+// the API servers and stores their results, but serves no HTTP requests, so
+// scanning capacity can grow separately from the API. It runs until the
+// process is interrupted or terminated.
+func RunWorker(args []string) error {
+	logging.Configure()
+	logger := logging.Logger()
+
+	if err := godotenv.Load(); err != nil {
+		logger.Warn("failed to load .env file", "error", err)
+	}
+
+	cfg, err := LoadConfig(args)
+	if err != nil {
+		return err
+	}
+	logger.Info("configuration loaded", "config", cfg)
+
+	node, err := startNode(cfg, logger)
+	if err != nil {
+		return err
+	}
+	logger.Info("starting Cortex worker", "version", version.Version, "commit", version.Commit)
+	// Workers have no rate limits; reloads only touch probes and pools
+	go watchReloads(args, node.pools, &atomic.Pointer[RateLimitConfig]{}, logger)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	logger.Info("shutting down", "signal", (<-stop).String())
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return node.shutdown(ctx)
+}
+
+// nodeCapabilities lists the scan modes this process can run. SYN and UDP
+// scans need raw packet privileges.
+func nodeCapabilities() []string {
+	var capabilities []string
+	for _, mode := range QueueModes {
+		if _, _, _, _, err := scanner.WorkerForMode(mode, false); err == nil {
+			capabilities = append(capabilities, string(mode))
+		}
+	}
+	return capabilities
+}
+
+// restrictToCapabilities empties the pools of the modes missing from
+// capabilities, so that a standalone worker leaves those scans to nodes able
+// to run them instead of downgrading or failing them.
+func restrictToCapabilities(workers *WorkerConfig, capabilities []string, logger *slog.Logger) {
+	for _, pool := range []struct {
+		mode scanner.Mode
+		size *int
+	}{
+		{scanner.ModeSyn, &workers.Syn},
+		{scanner.ModeUDP, &workers.UDP},
+	} {
+		if *pool.size > 0 && !slices.Contains(capabilities, string(pool.mode)) {
+			logger.Warn("worker pool disabled, this node cannot run its scans", "mode", pool.mode, "workers", *pool.size)
+			*pool.size = 0
+		}
+	}
+}
+
+// newWorkerNode describes this process for the worker registry.
+func newWorkerNode(cfg *Config, capabilities []string) (*WorkerNode, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hostname: %w", err)
+	}
+	id := cfg.WorkerID
+	if id == "" {
+		id = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	role := "api"
+	if cfg.WorkerMode {
+		role = "worker"
+	}
+	now := time.Now().UTC()
+	return &WorkerNode{
+		ID:           id,
+		Hostname:     hostname,
+		Role:         role,
+		Version:      version.Version,
+		Capabilities: capabilities,
+		StartedAt:    now,
+		LastSeen:     now,
+	}, nil
+}
+
+// registerNode keeps self in the worker registry, renewing its record with
+// the current pool sizes every heartbeat interval.
+func registerNode(store TaskStore, self *WorkerNode, pools *WorkerPools, logger *slog.Logger) {
+	for {
+		cfg := pools.Config()
+		record := *self
+		record.LastSeen = time.Now().UTC()
+		record.Workers = make(map[string]int, len(QueueModes))
+		for _, mode := range QueueModes {
+			record.Workers[string(mode)] = cfg.Size(mode)
+		}
+		if err := store.RegisterWorkerNode(&record, cfg.StaleAfter); err != nil {
+			logger.Error("failed to renew worker registration", "id", self.ID, "error", err)
+		}
+		time.Sleep(cfg.HeartbeatInterval)
+	}
+}
@@ -0,0 +1,444 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Viktor25104/cortex/backend/scanner"
+)
+
+// NotifierConfig controls the completion webhook and the callbacks of tasks
+// submitted with a callback_url.
+type NotifierConfig struct {
+	// URL receives a POST with a TaskNotification for every notifiable task.
+	URL string
+	// Timeout bounds a single webhook or callback delivery.
+	Timeout time.Duration
+	// Secret, when set, signs every delivery with HMAC-SHA256.
+	Secret string
+	// CallbackAttempts is how often a callback is tried before it is
+	// recorded as failed.
+	CallbackAttempts int
+}
+
+// callbackBackoff is the delay before the second callback attempt; it
+// doubles with every further attempt up to maxCallbackBackoff.
+const (
+	callbackBackoff    = 2 * time.Second
+	maxCallbackBackoff = 5 * time.Minute
+)
+
+// callbackDispatchTick is how often due callbacks are looked for, and
+// callbackLease the lease the process delivering them holds, which outlives
+// its last renewal by callbackLeaseTTL.
+const (
+	callbackDispatchTick = 2 * time.Second
+	callbackLease        = "callbacks"
+	callbackLeaseTTL     = 15 * callbackDispatchTick
+	// callbackConcurrency caps the deliveries one tick makes at once.
+	callbackConcurrency = 16
+)
+
+// callbackResolveTimeout bounds resolving the host of a submitted
+// callback_url.
+const callbackResolveTimeout = 5 * time.Second
+
+// TaskNotification is the JSON body posted to the webhook when a task completes.
+type TaskNotification struct {
+	Event       string                 `json:"event"`
+	TaskID      string                 `json:"task_id"`
+	Namespace   string                 `json:"namespace"`
+	Status      string                 `json:"status"`
+	Hosts       []string               `json:"hosts"`
+	Baseline    string                 `json:"baseline,omitempty"`
+	Changes     []scanner.ResultChange `json:"changes,omitempty"`
+	CompletedAt *time.Time             `json:"completed_at,omitempty"`
+}
+
+// Notifier delivers task completion webhooks and task callbacks. A nil
+// Notifier sends nothing.
+type Notifier struct {
+	cfg    NotifierConfig
+	client *http.Client
+	// callbacks only connects to public addresses outside blocklist, since
+	// callback URLs come from API clients rather than the operator
+	callbacks *http.Client
+	logger    *slog.Logger
+}
+
+// NewNotifier returns a notifier for cfg. Without a URL it only delivers the
+// callbacks of tasks that ask for one, never to addresses checkCallbackIP
+// rejects under blocklist.
+func NewNotifier(cfg NotifierConfig, blocklist *scanner.Blocklist, logger *slog.Logger) *Notifier {
+	dialer := &net.Dialer{
+		Timeout: cfg.Timeout,
+		// Checked on the address actually dialled, so DNS answers that
+		// changed since the URL was accepted, and redirects, are covered
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("callback address %s is no IP address", host)
+			}
+			return checkCallbackIP(ip, blocklist)
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would dial on the callback's behalf, past the check above
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Notifier{
+		cfg:       cfg,
+		client:    &http.Client{Timeout: cfg.Timeout},
+		callbacks: &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		logger:    logger,
+	}
+}
+
+// checkCallbackIP rejects callback addresses inside the deployment rather
+// than on the internet: loopback, private, link-local, unspecified and
+// multicast addresses, and those in blocklist.
+func checkCallbackIP(ip net.IP, blocklist *scanner.Blocklist) error {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("callback address %s is not public", ip)
+	}
+	if network, blocked := blocklist.Match(ip); blocked {
+		return fmt.Errorf("callback address %s is in blocked range %s", ip, network)
+	}
+	return nil
+}
+
+// checkCallbackURL verifies that target is an absolute http or https URL
+// whose host resolves to addresses checkCallbackIP accepts, all of them.
+func checkCallbackURL(ctx context.Context, target string, blocklist *scanner.Blocklist) error {
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("callback_url must be an absolute http or https URL")
+	}
+	host := parsed.Hostname()
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		ctx, cancel := context.WithTimeout(ctx, callbackResolveTimeout)
+		defer cancel()
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return fmt.Errorf("callback_url host %s does not resolve", host)
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	for _, ip := range ips {
+		if err := checkCallbackIP(ip, blocklist); err != nil {
+			return fmt.Errorf("callback_url host %s: %w", host, err)
+		}
+	}
+	return nil
+}
+
+// TaskCompleted posts a scan.completed event for task to the configured
+// webhook, if any. Delivery failures are logged and never affect the task.
+func (n *Notifier) TaskCompleted(namespace string, task *ScanTask) {
+	if n == nil || n.cfg.URL == "" {
+		return
+	}
+	body, err := json.Marshal(TaskNotification{
+		Event:       "scan.completed",
+		TaskID:      task.ID,
+		Namespace:   namespace,
+		Status:      task.Status,
+		Hosts:       task.Hosts,
+		Baseline:    task.Baseline,
+		Changes:     task.Changes,
+		CompletedAt: task.CompletedAt,
+	})
+	if err != nil {
+		n.logger.Error("failed to encode task notification", "task_id", task.ID, "error", err)
+		return
+	}
+
+	status, err := n.post(n.client, n.cfg.URL, body)
+	if err != nil {
+		n.logger.Warn("task notification failed", "task_id", task.ID, "error", err)
+		return
+	}
+	if status >= 300 {
+		n.logger.Warn("task notification rejected", "task_id", task.ID, "status", status)
+		return
+	}
+	n.logger.Info("task notification sent", "task_id", task.ID, "changes", len(task.Changes))
+}
+
+// TaskCallback queues the delivery of task to its callback URL in store,
+// where a CallbackDispatcher picks it up, and marks the task's callback
+// pending. Tasks without a callback URL are ignored.
+func (n *Notifier) TaskCallback(store TaskStore, task *ScanTask) {
+	if n == nil || task.CallbackURL == "" {
+		return
+	}
+	// Queued first: the dispatcher treats a queued task without callback
+	// state as pending, while state without a queue entry would never go out
+	now := time.Now().UTC()
+	if err := store.QueueCallback(task.ID, now); err != nil {
+		n.logger.Error("failed to queue task callback", "task_id", task.ID, "error", err)
+		return
+	}
+	task.Callback = &CallbackDelivery{Status: "pending", NextAttemptAt: &now}
+	if err := store.UpdateCallback(task.ID, task.Callback); err != nil {
+		n.logger.Error("failed to record task callback", "task_id", task.ID, "error", err)
+	}
+}
+
+// deliverCallback makes the next delivery attempt of the callback of task
+// id, records it on the task and queues the attempt after it, with
+// exponential backoff, until the URL accepts the task or the attempts run
+// out. Callbacks of deleted tasks are dropped.
+func (n *Notifier) deliverCallback(store TaskStore, id string) error {
+	task, err := store.GetTask(id)
+	if err == ErrTaskNotFound {
+		return store.RemoveCallback(id)
+	}
+	if err != nil {
+		return err
+	}
+	delivery := task.Callback
+	if delivery == nil {
+		delivery = &CallbackDelivery{Status: "pending"}
+	}
+	if task.CallbackURL == "" || delivery.Status != "pending" {
+		return store.RemoveCallback(id)
+	}
+	// Receivers get the task as it finished, without the delivery state
+	task.Callback = nil
+	body, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	delivery.Attempts++
+	status, err := n.post(n.callbacks, task.CallbackURL, body)
+	delivery.ResponseStatus = status
+	switch {
+	case err != nil:
+		delivery.Error = err.Error()
+	case status >= 300:
+		delivery.Error = fmt.Sprintf("callback answered %d %s", status, http.StatusText(status))
+	default:
+		now := time.Now().UTC()
+		delivery.Status, delivery.Error, delivery.DeliveredAt = "delivered", "", &now
+	}
+	if delivery.Status == "pending" && delivery.Attempts >= n.cfg.CallbackAttempts {
+		delivery.Status = "failed"
+	}
+	delivery.NextAttemptAt = nil
+	if delivery.Status == "pending" {
+		next := time.Now().UTC().Add(callbackDelay(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+	if err := store.UpdateCallback(id, delivery); err == ErrTaskNotFound {
+		n.logger.Info("task callback abandoned, task deleted", "task_id", id)
+		return store.RemoveCallback(id)
+	} else if err != nil {
+		return err
+	}
+
+	switch delivery.Status {
+	case "pending":
+		return store.QueueCallback(id, *delivery.NextAttemptAt)
+	case "delivered":
+		n.logger.Info("task callback delivered", "task_id", id, "host", webhookHost(task.CallbackURL), "attempts", delivery.Attempts)
+	default:
+		n.logger.Warn("task callback failed", "task_id", id, "host", webhookHost(task.CallbackURL), "attempts", delivery.Attempts, "error", delivery.Error)
+	}
+	return store.RemoveCallback(id)
+}
+
+// callbackDelay is the wait after the given number of failed attempts:
+// callbackBackoff, doubling with every further attempt up to
+// maxCallbackBackoff.
+func callbackDelay(attempts int) time.Duration {
+	delay := callbackBackoff
+	for i := 1; i < attempts && delay < maxCallbackBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxCallbackBackoff)
+}
+
+// CallbackDispatcher delivers the task callbacks queued in the store as they
+// come due. Every process running workers runs one, but only the holder of
+// the callback lease delivers anything, so each attempt is made once, and
+// deliveries outlive the process that queued them.
+type CallbackDispatcher struct {
+	store    TaskStore
+	notifier *Notifier
+	holder   string
+	logger   *slog.Logger
+	leader   bool
+}
+
+// NewCallbackDispatcher constructs a dispatcher delivering through notifier.
+func NewCallbackDispatcher(store TaskStore, notifier *Notifier, logger *slog.Logger) *CallbackDispatcher {
+	hostname, _ := os.Hostname()
+	return &CallbackDispatcher{
+		store:    store,
+		notifier: notifier,
+		holder:   fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano()),
+		logger:   logger,
+	}
+}
+
+// Start delivers due callbacks immediately and then periodically in the
+// background.
+func (d *CallbackDispatcher) Start() {
+	go func() {
+		ticker := time.NewTicker(callbackDispatchTick)
+		defer ticker.Stop()
+		for {
+			d.tick(time.Now().UTC())
+			<-ticker.C
+		}
+	}()
+}
+
+// tick renews the callback lease and, while this process holds it, delivers
+// the callbacks that are due.
+func (d *CallbackDispatcher) tick(now time.Time) {
+	leader, err := d.store.AcquireLease(callbackLease, d.holder, callbackLeaseTTL)
+	if err != nil {
+		d.logger.Error("callback lease renewal failed", "error", err)
+		leader = false
+	}
+	if leader != d.leader {
+		d.leader = leader
+		d.logger.Info("callback dispatcher leadership changed", "leader", leader, "holder", d.holder)
+	}
+	if !leader {
+		return
+	}
+	if err := d.Dispatch(now); err != nil {
+		d.logger.Error("task callbacks failed", "error", err)
+	}
+}
+
+// Dispatch makes the next attempt of every callback due by now in every
+// namespace, up to callbackConcurrency at a time. A callback whose attempt cannot be recorded stays
+// queued and the others still go out.
+func (d *CallbackDispatcher) Dispatch(now time.Time) error {
+	namespaces, err := d.store.Namespaces()
+	if err != nil {
+		return fmt.Errorf("list namespaces: %w", err)
+	}
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, callbackConcurrency)
+	for _, namespace := range namespaces {
+		store := d.store.Namespace(namespace)
+		due, err := store.DueCallbacks(now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", namespace, err))
+			continue
+		}
+		for _, id := range due {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if err := d.notifier.deliverCallback(store, id); err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("task %s: %w", id, err))
+					mu.Unlock()
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// post sends body to target through client, signed when a secret is
+// configured, and returns the response status.
+func (n *Notifier) post(client *http.Client, target string, body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), n.cfg.Timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if n.cfg.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		request.Header.Set("X-Cortex-Timestamp", timestamp)
+		request.Header.Set("X-Cortex-Signature", "sha256="+signPayload(n.cfg.Secret, timestamp, body))
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	response.Body.Close()
+	return response.StatusCode, nil
+}
+
+// signPayload returns the hex HMAC-SHA256 of timestamp, a dot and body under
+// secret. Receivers recompute it to authenticate a delivery and reject stale
+// timestamps to stop replays.
+func signPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// loadNotifierConfig reads the webhook settings from the environment:
+// CORTEX_WEBHOOK_URL (optional http or https URL), CORTEX_WEBHOOK_TIMEOUT
+// (Go duration, default 10s), CORTEX_CALLBACK_SECRET (optional signing key)
+// and CORTEX_CALLBACK_ATTEMPTS (default 5).
+func loadNotifierConfig() (NotifierConfig, error) {
+	cfg := NotifierConfig{URL: os.Getenv("CORTEX_WEBHOOK_URL"), Secret: os.Getenv("CORTEX_CALLBACK_SECRET")}
+
+	var err error
+	if cfg.Timeout, err = getenvDuration("CORTEX_WEBHOOK_TIMEOUT", 10*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.Timeout <= 0 {
+		return cfg, fmt.Errorf("CORTEX_WEBHOOK_TIMEOUT must be positive")
+	}
+	attempts, err := getenvInt("CORTEX_CALLBACK_ATTEMPTS", 5)
+	if err != nil {
+		return cfg, err
+	}
+	if attempts < 1 {
+		return cfg, fmt.Errorf("CORTEX_CALLBACK_ATTEMPTS must be at least 1")
+	}
+	cfg.CallbackAttempts = int(attempts)
+	if cfg.URL != "" {
+		parsed, err := url.Parse(cfg.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return cfg, fmt.Errorf("CORTEX_WEBHOOK_URL must be an absolute http or https URL")
+		}
+	}
+	return cfg, nil
+}
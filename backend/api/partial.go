@@ -0,0 +1,258 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Viktor25104/cortex/backend/logging"
+	"github.com/Viktor25104/cortex/backend/scanner"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// partialFlushSize is how many results a worker buffers before it writes
+	// them to the partial results of its task.
+	partialFlushSize = 100
+	// partialFlushInterval is the longest a found result waits in the buffer.
+	partialFlushInterval = time.Second
+	// eventsPollInterval is how often GET /scans/{id}/events looks for new
+	// results and status changes.
+	eventsPollInterval = time.Second
+)
+
+// partialWriter buffers the results of a running task and writes them to its
+// partial results in batches, along with the progress of the task.
+type partialWriter struct {
+	store   TaskStore
+	taskID  string
+	started time.Time
+	mu      sync.Mutex
+	pending []scanner.ScanResult
+	// done and total are the latest job counts; written is the done count
+	// the store holds
+	done, total, written int
+}
+
+func newPartialWriter(store TaskStore, taskID string) *partialWriter {
+	return &partialWriter{store: store, taskID: taskID, started: time.Now()}
+}
+
+// progress records that done of total jobs have finished.
+func (w *partialWriter) progress(done, total int) {
+	w.mu.Lock()
+	w.done, w.total = done, total
+	w.mu.Unlock()
+}
+
+// estimate returns the percentage of jobs finished and the seconds the rest
+// are expected to take at the pace so far.
+func (w *partialWriter) estimate() (float64, int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.total == 0 || w.done == 0 {
+		return 0, 0
+	}
+	percent := math.Round(float64(w.done)*1000/float64(w.total)) / 10
+	perJob := time.Since(w.started).Seconds() / float64(w.done)
+	return min(percent, 100), int(math.Ceil(perJob * float64(max(w.total-w.done, 0))))
+}
+
+// add buffers result, writing the buffer once it holds partialFlushSize
+// results.
+func (w *partialWriter) add(result scanner.ScanResult) {
+	w.mu.Lock()
+	w.pending = append(w.pending, result)
+	full := len(w.pending) >= partialFlushSize
+	w.mu.Unlock()
+	if full {
+		w.flush()
+	}
+}
+
+// run writes the buffer every partialFlushInterval until ctx is done.
+func (w *partialWriter) run(ctx context.Context) {
+	ticker := time.NewTicker(partialFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.flush()
+		}
+	}
+}
+
+// flush writes the buffered results and the progress when it changed.
+// Readers merely see them later when a write fails, so failures are only
+// logged.
+func (w *partialWriter) flush() {
+	w.mu.Lock()
+	batch := w.pending
+	w.pending = nil
+	moved := w.done != w.written
+	w.written = w.done
+	w.mu.Unlock()
+	if err := w.store.AppendPartialResults(w.taskID, batch); err != nil {
+		logging.Logger().Warn("worker failed to write partial results", "task_id", w.taskID, "results", len(batch), "error", err)
+	}
+	if moved {
+		percent, eta := w.estimate()
+		if err := w.store.UpdateProgress(w.taskID, percent, eta); err != nil {
+			logging.Logger().Warn("worker failed to write progress", "task_id", w.taskID, "error", err)
+		}
+	}
+}
+
+// taskFinished reports whether status is terminal.
+func taskFinished(status string) bool {
+	return status == "completed" || status == "failed" || status == "cancelled"
+}
+
+// shardProgress sets the progress of an unfinished sharded task to the
+// percentage of its shards that finished; the shards track their own probes.
+func shardProgress(task *ScanTask) {
+	if len(task.Shards) > 0 && !taskFinished(task.Status) {
+		task.Progress = math.Round(float64(task.ShardsDone)*1000/float64(len(task.Shards))) / 10
+	}
+}
+
+// partialTaskResults returns the results found so far for an unfinished
+// task: its own partial results or, for a sharded task, those of every shard.
+func partialTaskResults(store TaskStore, task *ScanTask) ([]scanner.ScanResult, error) {
+	ids := []string{task.ID}
+	if len(task.Shards) > 0 {
+		ids = task.Shards
+	}
+	var results []scanner.ScanResult
+	for _, id := range ids {
+		found, err := store.PartialResults(id, 0)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, found...)
+	}
+	return results, nil
+}
+
+// @Summary      Stream scan progress as server-sent events
+// @Description  Follow a scan while it runs. Every result is sent as a result event as soon as the worker has written it, at most about a second after it was found, and every status change as a status event. The stream ends after the status event of a terminal state (completed, failed or cancelled).
+// @Description  **Reconnecting**: result events of unsharded tasks carry their position as the event id; a client that reconnects with Last-Event-ID continues after that result. Results carried over from before a pause are not sent again.
+// @Tags         Scans
+// @Produce      text/event-stream
+// @Param        id             path      string  true   "Scan Task ID (UUID v4)"
+// @Param        Last-Event-ID  header    string  false  "Id of the last result event received before reconnecting"
+// @Success      200  {object}  scanner.ScanResult  "Event stream. Example: event: result\nid: 1\ndata: {\"host\":\"scanme.nmap.org\",\"port\":22,\"state\":\"Open\",\"service\":\"ssh\"}\n\nevent: status\ndata: {\"id\":\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\",\"status\":\"completed\"}"
+// @Failure      400  {object}  ErrorResponse  "Malformed task identifier or Last-Event-ID. Example: {\"error\":\"invalid task id format\"}"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      404  {object}  ErrorResponse  "Task with the provided ID does not exist. Example: {\"error\":\"task not found\"}"
+// @Failure      429  {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse  "Internal error when loading the task. Example: {\"error\":\"failed to load task\"}"
+// @Security     ApiKeyAuth
+// @Router       /scans/{id}/events [get]
+func (s *Server) scanEventsHandler(c *gin.Context) {
+	id := c.Param("id")
+	if !uuidV4Pattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid task id format"})
+		return
+	}
+	tasks := s.tasks(c)
+	task, err := tasks.GetTask(id)
+	if err != nil {
+		if err == ErrTaskNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load task"})
+		return
+	}
+
+	// Every list is read from its own position; only an unsharded task
+	// has a single position to resume from
+	ids := []string{task.ID}
+	if len(task.Shards) > 0 {
+		ids = task.Shards
+	}
+	positions := make(map[string]int, len(ids))
+	if raw := c.GetHeader("Last-Event-ID"); raw != "" && len(task.Shards) == 0 {
+		position, err := strconv.Atoi(raw)
+		if err != nil || position < 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Last-Event-ID must be a non-negative integer"})
+			return
+		}
+		positions[task.ID] = position
+	}
+
+	clearStreamDeadlines(c.Writer)
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	ticker := time.NewTicker(eventsPollInterval)
+	defer ticker.Stop()
+	status := ""
+	for {
+		// The status is read first so that no result written before a
+		// terminal status is missed
+		current, err := tasks.TaskStatus(task.ID)
+		if err != nil {
+			logging.Logger().Error("scan events aborted", "task_id", task.ID, "error", err)
+			return
+		}
+		for _, shardID := range ids {
+			results, err := tasks.PartialResults(shardID, positions[shardID])
+			if err != nil {
+				logging.Logger().Error("scan events aborted", "task_id", task.ID, "error", err)
+				return
+			}
+			for _, result := range results {
+				positions[shardID]++
+				eventID := ""
+				if len(task.Shards) == 0 {
+					eventID = strconv.Itoa(positions[shardID])
+				}
+				if !writeEvent(c, "result", eventID, result) {
+					return
+				}
+			}
+		}
+		if current != status {
+			status = current
+			if !writeEvent(c, "status", "", ScanStateResponse{ID: task.ID, Status: status}) {
+				return
+			}
+		}
+		c.Writer.Flush()
+		if taskFinished(status) {
+			return
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeEvent writes one server-sent event with payload as JSON data. It
+// reports false once the client went away.
+func writeEvent(c *gin.Context, event, id string, payload interface{}) bool {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return false
+	}
+	message := "event: " + event + "\n"
+	if id != "" {
+		message += "id: " + id + "\n"
+	}
+	_, err = fmt.Fprintf(c.Writer, "%sdata: %s\n\n", message, data)
+	return err == nil
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary      Erase the results of a scan
+// @Description  Irreversibly remove the stored results, host summaries and baseline changes of a finished or paused scan, for data retention and right-to-erasure requests. Entries the scan left in the result reuse cache are removed too. The task itself stays with results_purged_at set.
+// @Description  **Scope**: a sharded task is purged together with its shards; shards cannot be purged on their own. Inventory records keep only service names and are not touched, and archives written by the retention janitor are outside the store.
+// @Tags         Scans
+// @Produce      json
+// @Param        id   path      string             true  "Scan Task ID (UUID v4)"
+// @Success      200  {object}  ScanPurgeResponse  "Results erased. Example: {\"id\":\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\",\"results\":1024,\"recent_results\":1024}"
+// @Failure      400  {object}  ErrorResponse      "Malformed task identifier. Example: {\"error\":\"invalid task id format\"}"
+// @Failure      401  {object}  ErrorResponse      "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      404  {object}  ErrorResponse      "Task with the provided ID does not exist. Example: {\"error\":\"task not found\"}"
+// @Failure      409  {object}  ErrorResponse      "Task is still active or is a shard. Example: {\"error\":\"task is running; pause it or wait until it finishes\"}"
+// @Failure      429  {object}  ErrorResponse      "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse      "Internal error while erasing the results. Example: {\"error\":\"failed to purge results\"}"
+// @Security     ApiKeyAuth
+// @Router       /scans/{id}/results [delete]
+func (s *Server) purgeResultsHandler(c *gin.Context) {
+	tasks := s.tasks(c)
+	group, ok := loadPurgeGroup(c, tasks)
+	if !ok {
+		return
+	}
+
+	response := ScanPurgeResponse{ID: group[0].ID}
+	now := time.Now().UTC()
+	for _, task := range group {
+		recent, err := purgeRecentResults(tasks, task)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to purge results"})
+			return
+		}
+		if err := tasks.DeletePartialResults(task.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to purge results"})
+			return
+		}
+		response.Results += len(task.Results)
+		response.RecentResults += recent
+
+		task.Results = nil
+		task.HostSummaries = nil
+		task.Changes = nil
+		task.ResultsPurgedAt = &now
+		if err := tasks.UpdateTask(task); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to purge results"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary      Delete a scan and all its data
+// @Description  Irreversibly delete a finished or paused scan task together with its results, its shards and the entries it left in the result reuse cache. Requires an admin API key.
+// @Tags         Scans
+// @Produce      json
+// @Param        id   path      string             true  "Scan Task ID (UUID v4)"
+// @Success      200  {object}  ScanPurgeResponse  "Task deleted. Example: {\"id\":\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\",\"results\":1024,\"recent_results\":1024,\"tasks\":1}"
+// @Failure      400  {object}  ErrorResponse      "Malformed task identifier. Example: {\"error\":\"invalid task id format\"}"
+// @Failure      401  {object}  ErrorResponse      "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      403  {object}  ErrorResponse      "API key lacks admin rights. Example: {\"error\":\"admin privileges required\"}"
+// @Failure      404  {object}  ErrorResponse      "Task with the provided ID does not exist. Example: {\"error\":\"task not found\"}"
+// @Failure      409  {object}  ErrorResponse      "Task is still active or is a shard. Example: {\"error\":\"task is a shard of 5b0e7c1a-9d2f-4e3b-8a6c-2f1d0e9b7a44; purge that task instead\"}"
+// @Failure      429  {object}  ErrorResponse      "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse      "Internal error while deleting the task. Example: {\"error\":\"failed to delete task\"}"
+// @Security     ApiKeyAuth
+// @Router       /scans/{id} [delete]
+func (s *Server) deleteScanHandler(c *gin.Context) {
+	tasks := s.tasks(c)
+	group, ok := loadPurgeGroup(c, tasks)
+	if !ok {
+		return
+	}
+
+	response := ScanPurgeResponse{ID: group[0].ID}
+	// Shards go first so that a failure never leaves them without a parent
+	for i := len(group) - 1; i >= 0; i-- {
+		task := group[i]
+		recent, err := purgeRecentResults(tasks, task)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to delete task"})
+			return
+		}
+		if err := tasks.RemoveFromQueue(task.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to delete task"})
+			return
+		}
+		if err := tasks.DeleteTask(task.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to delete task"})
+			return
+		}
+		response.Results += len(task.Results)
+		response.RecentResults += recent
+		response.Tasks++
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// loadPurgeGroup loads the task named in the request path followed by its
+// shards, writing the error response and returning false when it does not
+// exist or cannot be purged: shards are purged through their parent, and
+// tasks still queued or probing would write results again.
+func loadPurgeGroup(c *gin.Context, tasks TaskStore) ([]*ScanTask, bool) {
+	id := c.Param("id")
+	if !uuidV4Pattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid task id format"})
+		return nil, false
+	}
+	task, err := tasks.GetTask(id)
+	if err != nil {
+		if err == ErrTaskNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "task not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load task"})
+		return nil, false
+	}
+	if task.Parent != "" {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("task is a shard of %s; purge that task instead", task.Parent)})
+		return nil, false
+	}
+
+	group := []*ScanTask{task}
+	for _, shardID := range task.Shards {
+		shard, err := tasks.GetTask(shardID)
+		if err == ErrTaskNotFound {
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load task"})
+			return nil, false
+		}
+		group = append(group, shard)
+	}
+	for _, member := range group {
+		switch member.Status {
+		case "completed", "failed", "paused", "cancelled":
+		default:
+			c.JSON(http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("task is %s; pause it or wait until it finishes", member.Status)})
+			return nil, false
+		}
+	}
+	return group, true
+}
+
+// purgeRecentResults removes the entries task saved for result reuse. Only
+// completed tasks save any, stamped with their completion time.
+func purgeRecentResults(tasks TaskStore, task *ScanTask) (int, error) {
+	if task.Status != "completed" || task.CompletedAt == nil || len(task.Results) == 0 {
+		return 0, nil
+	}
+	return tasks.PurgeRecentResults(taskProtocol(task), task.Results, *task.CompletedAt)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"log/slog"
+	"time"
+)
+
+// QueueReaper returns the queue entries of workers that stopped sending
+// heartbeats, as happens when a node crashes mid-scan, so that the task is
+// picked up by another worker instead of staying running forever.
+type QueueReaper struct {
+	store      TaskStore
+	staleAfter time.Duration
+	logger     *slog.Logger
+}
+
+// NewQueueReaper constructs a reaper treating entries whose last heartbeat is
+// older than staleAfter as abandoned.
+func NewQueueReaper(store TaskStore, staleAfter time.Duration, logger *slog.Logger) *QueueReaper {
+	return &QueueReaper{store: store, staleAfter: staleAfter, logger: logger}
+}
+
+// Start reaps every half staleAfter in the background.
+func (r *QueueReaper) Start() {
+	go func() {
+		ticker := time.NewTicker(r.staleAfter / 2)
+		defer ticker.Stop()
+		for range ticker.C {
+			requeued, err := r.Reap()
+			if err != nil {
+				r.logger.Error("queue reaper failed", "error", err, "requeued", requeued)
+			}
+		}
+	}()
+}
+
+// Reap handles every abandoned entry once and returns how many tasks it
+// queued again. Pending and running tasks go back to the head of their queue
+// as pending, keeping the results their worker had written so those ports
+// are not probed again. Tasks that were being paused or cancelled are
+// finished that way instead.
+func (r *QueueReaper) Reap() (int, error) {
+	claims, err := r.store.StaleQueueEntries(r.staleAfter)
+	if err != nil {
+		return 0, err
+	}
+	requeued := 0
+	for _, claim := range claims {
+		namespace, taskID := SplitQueueEntry(claim.Entry)
+		tasks := r.store.Namespace(namespace)
+		task, err := tasks.GetTask(taskID)
+		if err == ErrTaskNotFound {
+			if err := r.store.AckQueueEntry(claim.Mode, claim.Entry); err != nil {
+				return requeued, err
+			}
+			continue
+		}
+		if err != nil {
+			return requeued, err
+		}
+		partial, err := tasks.PartialResults(taskID, 0)
+		if err != nil {
+			return requeued, err
+		}
+		if len(partial) > len(task.Results) {
+			task.Results = partial
+		}
+
+		switch task.Status {
+		case "pending", "running":
+			// The task is only set pending together with the requeue, so a
+			// worker that acknowledges the entry first keeps its outcome
+			task.Status = "pending"
+			task.ETASeconds = 0
+			moved, err := tasks.RequeueTask(claim.Mode, claim.Entry, task)
+			if err != nil {
+				return requeued, err
+			}
+			if moved {
+				requeued++
+				r.logger.Warn("queue reaper requeued abandoned task", "task_id", taskID, "namespace", namespace,
+					"last_heartbeat", claim.HeartbeatAt, "results", len(task.Results))
+			}
+			continue
+		case "pausing", "cancelling":
+			// A worker that settled the task meanwhile keeps its outcome;
+			// the entry is looked at again on the next pass
+			from := task.Status
+			task.Status = map[string]string{"pausing": "paused", "cancelling": "cancelled"}[from]
+			_, updated, err := tasks.UpdateTaskIf(task, from)
+			if err != nil {
+				return requeued, err
+			}
+			if !updated {
+				continue
+			}
+			if task.Status == "cancelled" {
+				if err := finishCancelled(namespace, tasks, taskID); err != nil {
+					return requeued, err
+				}
+			}
+		}
+		r.logger.Warn("queue reaper dropped abandoned task", "task_id", taskID, "namespace", namespace,
+			"status", task.Status, "last_heartbeat", claim.HeartbeatAt)
+		if err := r.store.AckQueueEntry(claim.Mode, claim.Entry); err != nil {
+			return requeued, err
+		}
+	}
+	return requeued, nil
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/Viktor25104/cortex/backend/logging"
+	"github.com/Viktor25104/cortex/backend/scanner"
+	"github.com/joho/godotenv"
+)
+
+// watchReloads reconfigures the running server on every SIGHUP: the log file
+// is reopened for log rotation, .env is read again and the configuration is
+// reloaded with LoadConfig(args), after which the probe definitions, rate
+// limits and worker settings are swapped into limits and pools. Tasks already
+// running keep the probes and settings they started with, so a reload never
+// interrupts a scan. An invalid configuration is reported and the previous one
+// stays in effect.
+func watchReloads(args []string, pools *WorkerPools, limits *atomic.Pointer[RateLimitConfig], logger *slog.Logger) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	for range hangups {
+		reload(args, pools, limits, logger)
+	}
+}
+
+func reload(args []string, pools *WorkerPools, limits *atomic.Pointer[RateLimitConfig], logger *slog.Logger) {
+	logger.Info("reloading configuration")
+	if err := logging.Reopen(); err != nil {
+		logger.Error("failed to reopen log file", "error", err)
+	}
+	if err := godotenv.Overload(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Warn("failed to load .env file", "error", err)
+	}
+	cfg, err := LoadConfig(args)
+	if err != nil {
+		logger.Error("invalid configuration, keeping the previous one", "error", err)
+		return
+	}
+
+	if probes, stats, err := scanner.LoadProbes(cfg.ProbesFile); err != nil {
+		logger.Error("failed to reload probes, keeping the previous ones", "error", err)
+	} else {
+		if len(stats.ErrorLines) > 0 {
+			logger.Warn("probe loader reported warnings", "count", len(stats.ErrorLines))
+		}
+		pools.SetProbes(scanner.NewProbeCache(probes))
+		logger.Info("probes reloaded", "count", len(probes))
+	}
+
+	if cfg.RateLimitEnabled {
+		limits.Store(&cfg.RateLimit)
+	} else {
+		limits.Store(nil)
+		logger.Warn("rate limiting disabled by configuration")
+	}
+	if cfg.WorkerMode {
+		restrictToCapabilities(&cfg.Workers, nodeCapabilities(), logger)
+	}
+	pools.Reconfigure(cfg.Workers)
+	logger.Info("configuration reloaded", "config", cfg)
+}
@@ -0,0 +1,423 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scanSchedulerTick is how often the scheduler looks for due schedules.
+const scanSchedulerTick = 15 * time.Second
+
+// schedulerLease is the lease the API process queueing scheduled scans holds,
+// and schedulerLeaseTTL how long it outlives its last renewal, so a crashed
+// leader is replaced after a few ticks.
+const (
+	schedulerLease    = "scheduler"
+	schedulerLeaseTTL = 3 * scanSchedulerTick
+)
+
+// @Summary      Create a schedule
+// @Description  Queue a scan from a template whenever a cron expression matches, for recurring sweeps of the same targets. The template is validated like a POST /scans body when the schedule is created. Every run after the first is compared with the previous run when that one completed, so the completion webhook only fires on changes; a run that comes due while the previous scan is still unfinished is skipped and noted in last_error.
+// @Tags         Schedules
+// @Accept       json
+// @Produce      json
+// @Param        scheduleRequest  body      CreateScheduleRequest    true  "Schedule definition"
+// @Success      201              {object}  Schedule                 "Schedule created. Example: {\"id\":\"6e1f4c2a-8b3d-4a5e-9f60-7d2c1b0a9e83\",\"cron\":\"0 2 * * *\",\"timezone\":\"UTC\",\"scan\":{\"hosts\":[\"203.0.113.0/28\"],\"ports\":\"1-1024\",\"mode\":\"connect\"},\"paused\":false,\"created_at\":\"2024-01-02T15:04:05Z\",\"next_run_at\":\"2024-01-03T02:00:00Z\"}"
+// @Failure      400              {object}  ValidationErrorResponse  "Malformed JSON body or failed validation. Example: {\"error\":\"invalid request payload\",\"details\":[{\"field\":\"cron\",\"rule\":\"cron\",\"message\":\"minute field value \\\"61\\\" must be 0-59\"}]}"
+// @Failure      401              {object}  ErrorResponse            "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      429              {object}  ErrorResponse            "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500              {object}  ErrorResponse            "Internal error while persisting the schedule. Example: {\"error\":\"failed to persist schedule\"}"
+// @Security     ApiKeyAuth
+// @Router       /schedules [post]
+func (s *Server) createScheduleHandler(c *gin.Context) {
+	var req CreateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, newValidationErrorResponse(err))
+		return
+	}
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	var details []FieldError
+	cron, err := parseCron(req.Cron)
+	if err != nil {
+		details = append(details, FieldError{Field: "cron", Rule: "cron", Message: err.Error()})
+	}
+	loc, err := time.LoadLocation(req.Timezone)
+	if err != nil {
+		details = append(details, FieldError{Field: "timezone", Rule: "timezone", Message: fmt.Sprintf("timezone must be an IANA time zone such as Europe/Kyiv: %v", err)})
+	}
+	if req.Scan.TemplateID != "" {
+		details = append(details, FieldError{Field: "scan.template_id", Rule: "excluded", Message: "schedules store the full scan request; template_id cannot be set"})
+	}
+	if req.Scan.Baseline != "" {
+		details = append(details, FieldError{Field: "scan.baseline", Rule: "excluded", Message: "scheduled scans are compared with the previous run; baseline cannot be set"})
+	}
+	if len(details) > 0 {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{Error: "invalid request payload", Details: details})
+		return
+	}
+	if !s.checkScanRequest(c, &req.Scan) {
+		return
+	}
+
+	id, err := generateUUID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to generate schedule id"})
+		return
+	}
+	now := time.Now().UTC()
+	next := cron.next(now.In(loc)).UTC()
+	schedule := &Schedule{
+		ID:        id,
+		Name:      req.Name,
+		Cron:      req.Cron,
+		Timezone:  req.Timezone,
+		Scan:      req.Scan,
+		Owner:     principalFrom(c).Name,
+		CreatedAt: now,
+		NextRunAt: &next,
+	}
+	if err := s.tasks(c).SaveSchedule(schedule); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to persist schedule"})
+		return
+	}
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// @Summary      List schedules
+// @Description  Return every schedule of the caller with its next run and the scan it queued last, oldest first.
+// @Tags         Schedules
+// @Produce      json
+// @Success      200  {array}   Schedule       "Schedules of the caller."
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      429  {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse  "Internal error while loading schedules. Example: {\"error\":\"failed to load schedules\"}"
+// @Security     ApiKeyAuth
+// @Router       /schedules [get]
+func (s *Server) listSchedulesHandler(c *gin.Context) {
+	schedules, err := s.tasks(c).ListSchedules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load schedules"})
+		return
+	}
+	c.JSON(http.StatusOK, schedules)
+}
+
+// @Summary      Get a schedule
+// @Description  Return one schedule with its next run and the scan it queued last.
+// @Tags         Schedules
+// @Produce      json
+// @Param        id   path      string         true  "Schedule ID (UUID v4)"
+// @Success      200  {object}  Schedule       "Schedule definition and state."
+// @Failure      400  {object}  ErrorResponse  "Malformed schedule identifier. Example: {\"error\":\"invalid schedule id format\"}"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      404  {object}  ErrorResponse  "Schedule with the provided ID does not exist. Example: {\"error\":\"schedule not found\"}"
+// @Failure      429  {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse  "Internal error when loading the schedule. Example: {\"error\":\"failed to load schedule\"}"
+// @Security     ApiKeyAuth
+// @Router       /schedules/{id} [get]
+func (s *Server) getScheduleHandler(c *gin.Context) {
+	schedule, ok := s.loadSchedule(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// @Summary      Pause a schedule
+// @Description  Stop queueing scans for a schedule until it is resumed. Scans already queued still run. Pausing a paused schedule is not an error.
+// @Tags         Schedules
+// @Produce      json
+// @Param        id   path      string         true  "Schedule ID (UUID v4)"
+// @Success      200  {object}  Schedule       "The paused schedule."
+// @Failure      400  {object}  ErrorResponse  "Malformed schedule identifier. Example: {\"error\":\"invalid schedule id format\"}"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      404  {object}  ErrorResponse  "Schedule with the provided ID does not exist. Example: {\"error\":\"schedule not found\"}"
+// @Failure      429  {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse  "Internal error when updating the schedule. Example: {\"error\":\"failed to persist schedule\"}"
+// @Security     ApiKeyAuth
+// @Router       /schedules/{id}/pause [post]
+func (s *Server) pauseScheduleHandler(c *gin.Context) {
+	s.changeSchedulePause(c, true)
+}
+
+// @Summary      Resume a schedule
+// @Description  Let a paused schedule queue scans again, starting with the next time its cron expression matches; runs missed while it was paused are not made up. Resuming an active schedule is not an error.
+// @Tags         Schedules
+// @Produce      json
+// @Param        id   path      string         true  "Schedule ID (UUID v4)"
+// @Success      200  {object}  Schedule       "The resumed schedule with its next run."
+// @Failure      400  {object}  ErrorResponse  "Malformed schedule identifier. Example: {\"error\":\"invalid schedule id format\"}"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      404  {object}  ErrorResponse  "Schedule with the provided ID does not exist. Example: {\"error\":\"schedule not found\"}"
+// @Failure      429  {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse  "Internal error when updating the schedule. Example: {\"error\":\"failed to persist schedule\"}"
+// @Security     ApiKeyAuth
+// @Router       /schedules/{id}/resume [post]
+func (s *Server) resumeScheduleHandler(c *gin.Context) {
+	s.changeSchedulePause(c, false)
+}
+
+// changeSchedulePause pauses or resumes the schedule named in the path.
+func (s *Server) changeSchedulePause(c *gin.Context, pause bool) {
+	schedule, ok := s.loadSchedule(c)
+	if !ok {
+		return
+	}
+	if schedule.Paused != pause {
+		schedule.Paused = pause
+		schedule.NextRunAt = nil
+		if !pause {
+			next, err := nextScheduledRun(schedule, time.Now().UTC())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+				return
+			}
+			schedule.NextRunAt = next
+		}
+		if err := s.tasks(c).SaveSchedule(schedule); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to persist schedule"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// @Summary      Delete a schedule
+// @Description  Stop a schedule for good. Scans it already queued still run, and past scans are kept until the retention janitor removes them.
+// @Tags         Schedules
+// @Param        id   path      string         true  "Schedule ID (UUID v4)"
+// @Success      204  "Schedule deleted."
+// @Failure      400  {object}  ErrorResponse  "Malformed schedule identifier. Example: {\"error\":\"invalid schedule id format\"}"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      429  {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse  "Internal error when deleting the schedule. Example: {\"error\":\"failed to delete schedule\"}"
+// @Security     ApiKeyAuth
+// @Router       /schedules/{id} [delete]
+func (s *Server) deleteScheduleHandler(c *gin.Context) {
+	id := c.Param("id")
+	if !uuidV4Pattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid schedule id format"})
+		return
+	}
+	if err := s.tasks(c).DeleteSchedule(id); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to delete schedule"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// loadSchedule returns the schedule named in the path, writing the error
+// response and returning false when it cannot.
+func (s *Server) loadSchedule(c *gin.Context) (*Schedule, bool) {
+	id := c.Param("id")
+	if !uuidV4Pattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid schedule id format"})
+		return nil, false
+	}
+	schedule, err := s.tasks(c).GetSchedule(id)
+	if err != nil {
+		if err == ErrScheduleNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "schedule not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load schedule"})
+		return nil, false
+	}
+	return schedule, true
+}
+
+// nextScheduledRun returns the first time after now the cron expression of
+// schedule matches, or nil when it never does again.
+func nextScheduledRun(schedule *Schedule, now time.Time) (*time.Time, error) {
+	cron, err := parseCron(schedule.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("schedule %s: %w", schedule.ID, err)
+	}
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("schedule %s: %w", schedule.ID, err)
+	}
+	next := cron.next(now.In(loc))
+	if next.IsZero() {
+		return nil, nil
+	}
+	next = next.UTC()
+	return &next, nil
+}
+
+// ScanScheduler queues the scans of due schedules. Every API process runs
+// one, but only the holder of the scheduler lease queues anything, so a
+// schedule runs once however many replicas serve the API.
+type ScanScheduler struct {
+	store  TaskStore
+	holder string
+	logger *slog.Logger
+	leader bool
+}
+
+// NewScanScheduler constructs a scheduler for the given store.
+func NewScanScheduler(store TaskStore, logger *slog.Logger) *ScanScheduler {
+	hostname, _ := os.Hostname()
+	return &ScanScheduler{
+		store:  store,
+		holder: fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano()),
+		logger: logger,
+	}
+}
+
+// Start checks for due schedules immediately and then periodically in the background.
+func (m *ScanScheduler) Start() {
+	go func() {
+		ticker := time.NewTicker(scanSchedulerTick)
+		defer ticker.Stop()
+		for {
+			m.tick(time.Now().UTC())
+			<-ticker.C
+		}
+	}()
+}
+
+// tick renews the scheduler lease and, while this process holds it, queues
+// the scans that are due.
+func (m *ScanScheduler) tick(now time.Time) {
+	leader, err := m.store.AcquireLease(schedulerLease, m.holder, schedulerLeaseTTL)
+	if err != nil {
+		m.logger.Error("scheduler lease renewal failed", "error", err)
+		leader = false
+	}
+	if leader != m.leader {
+		m.leader = leader
+		m.logger.Info("scheduler leadership changed", "leader", leader, "holder", m.holder)
+	}
+	if !leader {
+		return
+	}
+	if queued, err := m.Schedule(now); err != nil {
+		m.logger.Error("scheduled scans failed", "error", err, "queued", queued)
+	} else if queued > 0 {
+		m.logger.Info("scheduled scans queued", "count", queued)
+	}
+}
+
+// Schedule queues a scan for every due schedule in every namespace and
+// returns how many were queued. A schedule that fails is recorded and the
+// others still run.
+func (m *ScanScheduler) Schedule(now time.Time) (int, error) {
+	namespaces, err := m.store.Namespaces()
+	if err != nil {
+		return 0, fmt.Errorf("list namespaces: %w", err)
+	}
+	queued := 0
+	var errs []error
+	for _, namespace := range namespaces {
+		store := m.store.Namespace(namespace)
+		schedules, err := store.ListSchedules()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", namespace, err))
+			continue
+		}
+		for _, schedule := range schedules {
+			if schedule.Paused || schedule.NextRunAt == nil || schedule.NextRunAt.After(now) {
+				continue
+			}
+			ran, err := runSchedule(store, schedule, now)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("schedule %s: %w", schedule.ID, err))
+				continue
+			}
+			if ran {
+				queued++
+			} else if schedule.LastError != "" {
+				m.logger.Warn("scheduled scan skipped", "schedule", schedule.ID, "namespace", namespace, "reason", schedule.LastError)
+			}
+		}
+	}
+	return queued, errors.Join(errs...)
+}
+
+// runSchedule queues the scan of a due schedule and moves it to its next
+// run. Runs missed while no scheduler held the lease are not made up. The
+// schedule is moved before its scan is queued, and only while it is stored
+// as it was loaded, so a schedule deleted or paused in the meantime queues
+// nothing. It reports false when the run was skipped, with LastError set
+// when that was because the previous scan has not finished.
+func runSchedule(store TaskStore, schedule *Schedule, now time.Time) (bool, error) {
+	next, err := nextScheduledRun(schedule, now)
+	if err != nil {
+		return false, err
+	}
+	previous := *schedule
+	schedule.NextRunAt = next
+	schedule.LastRunAt = &now
+	schedule.LastError = ""
+
+	baseline := ""
+	if schedule.LastTaskID != "" {
+		last, err := store.GetTask(schedule.LastTaskID)
+		switch {
+		case err == ErrTaskNotFound:
+		case err != nil:
+			return false, err
+		case !taskFinished(last.Status):
+			schedule.LastError = fmt.Sprintf("previous run %s is still %s", last.ID, last.Status)
+			if replaced, err := store.ReplaceSchedule(&previous, schedule); err != nil || !replaced {
+				schedule.LastError = ""
+				return false, err
+			}
+			return false, nil
+		case last.Status == "completed":
+			baseline = last.ID
+		}
+	}
+
+	taskID, err := generateUUID()
+	if err != nil {
+		return false, err
+	}
+	schedule.LastTaskID = taskID
+	if claimed, err := store.ReplaceSchedule(&previous, schedule); err != nil || !claimed {
+		return false, err
+	}
+
+	req := schedule.Scan
+	req.Baseline = baseline
+	task := scanTaskFromRequest(taskID, &req, schedule.Owner, now)
+	task.Schedule = schedule.ID
+	if err := queueScheduledTask(store, task, req.ShardSize); err != nil {
+		claimed := *schedule
+		schedule.LastError = err.Error()
+		if _, saveErr := store.ReplaceSchedule(&claimed, schedule); saveErr != nil {
+			return false, saveErr
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// queueScheduledTask persists and queues task, split into shards like a
+// submitted scan when it needs them.
+func queueScheduledTask(store TaskStore, task *ScanTask, shardSize int) error {
+	if (shardSize > 0 && len(task.Hosts) > shardSize) || len(targetModes(task)) > 1 {
+		return createShards(store, task, shardSize)
+	}
+	if err := store.CreateTask(task); err != nil {
+		return fmt.Errorf("persist task: %w", err)
+	}
+	if err := store.PushToQueue(task.ID, task.Mode); err != nil {
+		now := time.Now().UTC()
+		task.Status = "failed"
+		task.Error = "failed to queue task"
+		task.CompletedAt = &now
+		_ = store.UpdateTask(task)
+		return fmt.Errorf("queue task: %w", err)
+	}
+	return nil
+}
@@ -1,21 +1,20 @@
 package api
 
 import (
-	"context"
 	"fmt"
-	"os"
-	"time"
+	"regexp"
+	"strings"
+	"sync/atomic"
 
-	"cortex/logging"
-	"cortex/scanner"
+	"github.com/Viktor25104/cortex/backend/logging"
+	"github.com/Viktor25104/cortex/backend/version"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
-	"github.com/redis/go-redis/v9"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 
-	_ "cortex/docs"
+	_ "github.com/Viktor25104/cortex/backend/docs"
 )
 
 // @title           Cortex API
@@ -40,8 +39,18 @@ import (
 // @description    Supply the configured API key using the Authorization: Bearer <token> header.
 // @tag.name Scans
 // @tag.description Cortex orchestrates distributed port scans. Submit new jobs, inspect intermediate task state, and retrieve final findings from this tag.
+// @tag.name Inventory
+// @tag.description Per-host view aggregated from every completed scan: currently open ports, latest service fingerprints and first/last seen timestamps.
+// @tag.name Monitors
+// @tag.description Continuous verification of assets against a declared set of expected open ports, with a drift report of assets out of compliance.
+// @tag.name Templates
+// @tag.description Saved scan configurations that scan requests reference with template_id.
+// @tag.name Schedules
+// @tag.description Recurring scans: a scan template queued whenever a cron expression matches, by one API process at a time.
+// @tag.name Admin
+// @tag.description Operator endpoints for maintenance windows and incident response. Require an API key with administrative rights.
 // Run initializes dependencies and starts the API server.
-func Run() error {
+func Run(args []string) error {
 	logging.Configure()
 	logger := logging.Logger()
 
@@ -49,56 +58,109 @@ func Run() error {
 		logger.Warn("failed to load .env file", "error", err)
 	}
 
-	apiKey := os.Getenv("CORTEX_API_KEY")
-	if apiKey == "" {
-		return fmt.Errorf("CORTEX_API_KEY environment variable is required")
-	}
-
-	redisAddr := getenv("REDIS_ADDR", "localhost:6379")
-	redisClient := redis.NewClient(&redis.Options{Addr: redisAddr})
-
-	if err := redisClient.Ping(context.Background()).Err(); err != nil {
-		return fmt.Errorf("failed to connect to redis at %s: %w", redisAddr, err)
+	cfg, err := LoadConfig(args)
+	if err != nil {
+		return err
 	}
+	logger.Info("configuration loaded", "config", cfg)
 
-	store := NewRedisStore(redisClient)
-
-	probes, stats, err := scanner.LoadProbes("nmap-service-probes")
+	node, err := startNode(cfg, logger)
 	if err != nil {
-		return fmt.Errorf("failed to load probes: %w", err)
+		return err
 	}
-	if len(stats.ErrorLines) > 0 {
-		logger.Warn("probe loader reported warnings", "count", len(stats.ErrorLines))
+	store, redisClient, pools, metrics := node.store, node.redis, node.pools, node.metrics
+	NewMonitorScheduler(store, logger).Start()
+	NewScanScheduler(store, logger).Start()
+
+	if cfg.JanitorEnabled {
+		NewJanitor(store, cfg.Janitor, logger).Start()
+	} else {
+		logger.Warn("task retention janitor disabled by configuration")
 	}
 
-	probeCache := scanner.NewProbeCache(probes)
-
-	StartWorkers(store, probeCache, 5)
-
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 	router.Use(gin.Recovery())
 	router.Use(SecurityHeadersMiddleware())
 	router.Use(RequestLoggingMiddleware(logger))
+	if metrics != nil {
+		router.Use(MetricsMiddleware(metrics))
+	}
 
 	// Configure Swagger UI endpoint.
 	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	apiGroup := router.Group("/api/v1")
-	apiGroup.Use(AuthMiddleware(apiKey, logger))
-	apiGroup.Use(RateLimitMiddleware(redisClient, 100, time.Minute, logger))
+	apiGroup.Use(BodySizeLimitMiddleware(cfg.MaxBodyBytes, map[string]int64{"/api/v1/scans/upload": cfg.MaxUploadBytes}))
+	creds := Credentials{APIKeys: cfg.APIKeys, ClientCerts: cfg.ClientIdentities, Keys: store}
+	if cfg.JWTEnabled {
+		creds.JWT = NewJWTVerifier(cfg.JWT, logger)
+	}
+	apiGroup.Use(AuthMiddleware(creds, logger))
+	var rateLimits atomic.Pointer[RateLimitConfig]
+	if cfg.RateLimitEnabled {
+		rateLimits.Store(&cfg.RateLimit)
+	} else {
+		logger.Warn("rate limiting disabled by configuration")
+	}
+	apiGroup.Use(RateLimitMiddleware(redisClient, &rateLimits, logger))
 
-	server := NewServer(store)
+	server := NewServer(store, cfg.Blocklist, pools)
 	server.RegisterRoutes(apiGroup)
+	go watchReloads(args, pools, &rateLimits, logger)
+
+	httpServer, err := newHTTPServer(cfg, router)
+	if err != nil {
+		return err
+	}
+	if !cfg.TLSEnabled {
+		logger.Info("starting Cortex API server", "addr", cfg.Addr, "h2c", cfg.HTTP.H2C, "version", version.Version, "commit", version.Commit)
+		logger.Info("swagger documentation available", "path", "/docs/index.html")
+		return serveUntilStopped(httpServer, httpServer.ListenAndServe, node, logger)
+	}
 
-	logger.Info("starting Cortex API server", "addr", ":8080")
-	logger.Info("swagger documentation available", "url", "http://localhost:8080/docs/index.html")
-	return router.Run("0.0.0.0:8080")
+	if cfg.TLS.SelfSigned {
+		logger.Warn("serving a generated self-signed certificate; use it for development only")
+	}
+	logger.Info("starting Cortex API server", "addr", cfg.Addr, "tls", true, "http2", cfg.HTTP.HTTP2,
+		"client_certs", cfg.TLS.ClientCAFile != "", "client_auth", cfg.TLS.ClientAuth,
+		"version", version.Version, "commit", version.Commit)
+	logger.Info("swagger documentation available", "path", "/docs/index.html")
+	// A generated certificate is already in the TLS configuration
+	return serveUntilStopped(httpServer, func() error {
+		return httpServer.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+	}, node, logger)
 }
 
-func getenv(key, fallback string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// loadAPIKeys maps the configured bearer tokens to principals. Without a
+// dedicated CORTEX_ADMIN_API_KEY the main key keeps full administrative
+// rights so single-operator deployments keep working unchanged. tenantKeys
+// (CORTEX_API_KEYS) is a comma-separated list of key:namespace pairs that
+// bind additional keys to isolated tenant namespaces.
+func loadAPIKeys(apiKey, adminKey, tenantKeys string) (map[string]Principal, error) {
+	keys := map[string]Principal{apiKey: {Name: DefaultNamespace, Namespace: DefaultNamespace, Admin: adminKey == ""}}
+	if adminKey != "" && adminKey != apiKey {
+		keys[adminKey] = Principal{Name: "admin", Namespace: DefaultNamespace, Admin: true}
+	}
+
+	for i, pair := range strings.Split(tenantKeys, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, namespace, ok := strings.Cut(pair, ":")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("CORTEX_API_KEYS entry %d must have the form key:namespace", i+1)
+		}
+		if !namespacePattern.MatchString(namespace) {
+			return nil, fmt.Errorf("CORTEX_API_KEYS entry %d: namespace %q must be 1-63 lowercase letters, digits, '-' or '_'", i+1, namespace)
+		}
+		if _, exists := keys[key]; exists {
+			return nil, fmt.Errorf("CORTEX_API_KEYS entry %d reuses a key that is already configured", i+1)
+		}
+		keys[key] = Principal{Name: namespace, Namespace: namespace}
 	}
-	return fallback
+	return keys, nil
 }
+
+var namespacePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary      Group the inventory by service and version
+// @Description  Group every currently open port in the inventory by detected service, product and version, for rapid exposure assessment when a vulnerability is published. Text filters match case-insensitive substrings; min_version and max_version bound the version inclusively, comparing numeric segments numerically so 8.10 sorts after 8.9; max_version also covers every release it is a prefix of, so max_version=8 includes all 8.x. Ports with an unknown version never match a version bound.
+// @Tags         Inventory
+// @Produce      json
+// @Param        service      query     string               false  "Only services whose name contains this text"
+// @Param        product      query     string               false  "Only products whose name contains this text, e.g. openssh"
+// @Param        min_version  query     string               false  "Only versions at or above this one, e.g. 8.0"
+// @Param        max_version  query     string               false  "Only versions at or below this one, including its sub-releases: 8.9 also matches 8.9p1"
+// @Param        tag          query     string               false  "Only hosts carrying this tag"
+// @Success      200          {object}  ServiceListResponse  "Matching service groups. Example: {\"count\":1,\"services\":[{\"service\":\"ssh\",\"product\":\"OpenSSH\",\"version\":\"8.2p1\",\"host_count\":1,\"endpoints\":[{\"host\":\"203.0.113.50\",\"port\":22,\"protocol\":\"tcp\",\"last_seen\":\"2024-01-09T15:04:05Z\"}]}]}"
+// @Failure      401          {object}  ErrorResponse        "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      429          {object}  ErrorResponse        "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500          {object}  ErrorResponse        "Internal error while loading the inventory. Example: {\"error\":\"failed to load inventory\"}"
+// @Security     ApiKeyAuth
+// @Router       /services [get]
+func (s *Server) listServicesHandler(c *gin.Context) {
+	service := strings.ToLower(c.Query("service"))
+	product := strings.ToLower(c.Query("product"))
+	minVersion, maxVersion := c.Query("min_version"), c.Query("max_version")
+	tag := c.Query("tag")
+
+	records, err := s.tasks(c).ListInventory()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load inventory"})
+		return
+	}
+
+	groups := make(map[[3]string]*ServiceGroup)
+	hosts := make(map[[3]string]map[string]bool)
+	for _, record := range records {
+		if tag != "" && !containsString(record.Tags, tag) {
+			continue
+		}
+		for _, port := range record.Ports {
+			if service != "" && !strings.Contains(strings.ToLower(port.Service), service) {
+				continue
+			}
+			if product != "" && !strings.Contains(strings.ToLower(port.Product), product) {
+				continue
+			}
+			if (minVersion != "" || maxVersion != "") && port.Version == "" {
+				continue
+			}
+			if minVersion != "" && compareVersions(port.Version, minVersion) < 0 {
+				continue
+			}
+			if maxVersion != "" && !versionAtMost(port.Version, maxVersion) {
+				continue
+			}
+
+			key := [3]string{port.Service, port.Product, port.Version}
+			group := groups[key]
+			if group == nil {
+				group = &ServiceGroup{Service: port.Service, Product: port.Product, Version: port.Version}
+				groups[key] = group
+				hosts[key] = make(map[string]bool)
+			}
+			group.Endpoints = append(group.Endpoints, ServiceEndpoint{Host: record.Host, Port: port.Port, Protocol: port.Protocol, LastSeen: port.LastSeen})
+			hosts[key][record.Host] = true
+		}
+	}
+
+	response := ServiceListResponse{Services: make([]ServiceGroup, 0, len(groups))}
+	for key, group := range groups {
+		group.HostCount = len(hosts[key])
+		response.Services = append(response.Services, *group)
+	}
+	sort.Slice(response.Services, func(i, j int) bool {
+		a, b := response.Services[i], response.Services[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.Product != b.Product {
+			return a.Product < b.Product
+		}
+		return compareVersions(a.Version, b.Version) < 0
+	})
+	response.Count = len(response.Services)
+	c.JSON(http.StatusOK, response)
+}
+
+// compareVersions orders version strings such as "8.2p1" and "8.10" by
+// splitting them into runs of digits and non-digits; digit runs compare
+// numerically, everything else lexically. A version that is a prefix of
+// another sorts first, so "8" < "8.0".
+func compareVersions(a, b string) int {
+	left, right := versionSegments(a), versionSegments(b)
+	for i := 0; i < len(left) && i < len(right); i++ {
+		l, lErr := strconv.Atoi(left[i])
+		r, rErr := strconv.Atoi(right[i])
+		switch {
+		case lErr == nil && rErr == nil:
+			if l != r {
+				if l < r {
+					return -1
+				}
+				return 1
+			}
+		case left[i] != right[i]:
+			// A number sorts before text, so 8.2.1 < 8.2p1
+			if lErr == nil {
+				return -1
+			}
+			if rErr == nil {
+				return 1
+			}
+			return strings.Compare(strings.ToLower(left[i]), strings.ToLower(right[i]))
+		}
+	}
+	switch {
+	case len(left) < len(right):
+		return -1
+	case len(left) > len(right):
+		return 1
+	}
+	return 0
+}
+
+// versionAtMost reports whether version is at or below bound, treating bound
+// as covering every release it is a prefix of: a bound of 8.9 includes
+// 8.9p1 and 8.9.3, and a bound of 8 includes every 8.x release.
+func versionAtMost(version, bound string) bool {
+	if compareVersions(version, bound) <= 0 {
+		return true
+	}
+	versionParts, boundParts := versionSegments(version), versionSegments(bound)
+	if len(boundParts) > len(versionParts) {
+		return false
+	}
+	for i, part := range boundParts {
+		if !strings.EqualFold(part, versionParts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// versionSegments splits a version into digit and letter runs, dropping
+// separators such as dots, dashes and underscores.
+func versionSegments(version string) []string {
+	var segments []string
+	current := ""
+	digits := false
+	flush := func() {
+		if current != "" {
+			segments = append(segments, current)
+			current = ""
+		}
+	}
+	for _, r := range version {
+		isDigit := r >= '0' && r <= '9'
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !isDigit && !isLetter {
+			flush()
+			continue
+		}
+		if current != "" && isDigit != digits {
+			flush()
+		}
+		digits = isDigit
+		current += string(r)
+	}
+	flush()
+	return segments
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bounds of the GET /stats reporting window in days.
+const (
+	defaultStatsDays = 7
+	maxStatsDays     = 90
+)
+
+// topServicesLimit caps the number of services listed in the report.
+const topServicesLimit = 10
+
+// @Summary      Scan statistics
+// @Description  Summarize the caller's scans over a window of UTC calendar days ending today: scans per day, average durations, failure rates and the services most often found open. Only tasks still within the retention window can be counted.
+// @Tags         Scans
+// @Produce      json
+// @Param        days  query     int            false  "Number of UTC days to report, including today (1-90, default 7)"
+// @Success      200   {object}  StatsResponse  "Statistics for the window. Example: {\"from\":\"2024-01-03T00:00:00Z\",\"to\":\"2024-01-09T15:04:05Z\",\"scans\":120,\"completed\":112,\"failed\":6,\"failure_rate\":0.05,\"average_duration_seconds\":42.7,\"days\":[],\"top_services\":[{\"service\":\"http\",\"count\":86}]}"
+// @Failure      400   {object}  ValidationErrorResponse  "Invalid window. Example: {\"error\":\"invalid request payload\",\"details\":[{\"field\":\"days\",\"rule\":\"range\",\"message\":\"days must be an integer between 1 and 90\"}]}"
+// @Failure      401   {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      429   {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500   {object}  ErrorResponse  "Internal error while loading tasks. Example: {\"error\":\"failed to load tasks\"}"
+// @Security     ApiKeyAuth
+// @Router       /stats [get]
+func (s *Server) statsHandler(c *gin.Context) {
+	days := defaultStatsDays
+	if raw := c.Query("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxStatsDays {
+			c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+				Error:   "invalid request payload",
+				Details: []FieldError{{Field: "days", Rule: "range", Message: "days must be an integer between 1 and " + strconv.Itoa(maxStatsDays)}},
+			})
+			return
+		}
+		days = parsed
+	}
+
+	now := time.Now().UTC()
+	from := now.Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+	tasks, err := s.tasks(c).ListTasks(TaskQuery{CreatedAfter: from})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load tasks"})
+		return
+	}
+	c.JSON(http.StatusOK, buildStats(tasks, from, now, days))
+}
+
+// buildStats aggregates tasks created between from and to into a report
+// with one entry per day. Shards are counted through their parent task.
+func buildStats(tasks []*ScanTask, from, to time.Time, days int) StatsResponse {
+	report := StatsResponse{From: from, To: to, Days: make([]DailyStats, days), TopServices: []ServiceCount{}}
+	durations := make([]float64, days)
+	finished := make([]int, days)
+	for i := range report.Days {
+		report.Days[i].Date = from.AddDate(0, 0, i).Format("2006-01-02")
+	}
+
+	var totalDuration float64
+	services := make(map[string]int)
+	for _, task := range tasks {
+		day := int(task.CreatedAt.Sub(from) / (24 * time.Hour))
+		if day < 0 || day >= days || task.Parent != "" {
+			continue
+		}
+		report.Scans++
+		report.Days[day].Scans++
+		switch task.Status {
+		case "completed":
+			report.Completed++
+			report.Days[day].Completed++
+			for _, result := range task.Results {
+				if result.State == "Open" && result.Service != "" {
+					services[result.Service]++
+				}
+			}
+		case "failed":
+			report.Failed++
+			report.Days[day].Failed++
+		default:
+			continue
+		}
+		if task.CompletedAt != nil {
+			elapsed := task.CompletedAt.Sub(task.CreatedAt).Seconds()
+			totalDuration += elapsed
+			durations[day] += elapsed
+			finished[day]++
+		}
+	}
+
+	var totalFinished int
+	for i := range report.Days {
+		totalFinished += finished[i]
+		if finished[i] > 0 {
+			report.Days[i].AverageDurationSeconds = durations[i] / float64(finished[i])
+		}
+	}
+	if totalFinished > 0 {
+		report.AverageDurationSeconds = totalDuration / float64(totalFinished)
+	}
+	if done := report.Completed + report.Failed; done > 0 {
+		report.FailureRate = float64(report.Failed) / float64(done)
+	}
+
+	for service, count := range services {
+		report.TopServices = append(report.TopServices, ServiceCount{Service: service, Count: count})
+	}
+	sort.Slice(report.TopServices, func(i, j int) bool {
+		if report.TopServices[i].Count != report.TopServices[j].Count {
+			return report.TopServices[i].Count > report.TopServices[j].Count
+		}
+		return report.TopServices[i].Service < report.TopServices[j].Service
+	})
+	if len(report.TopServices) > topServicesLimit {
+		report.TopServices = report.TopServices[:topServicesLimit]
+	}
+	return report
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatsDConfig controls the StatsD metrics emitter.
+type StatsDConfig struct {
+	// Addr is the host:port of the StatsD or Datadog agent, reached over UDP.
+	Addr string
+	// Prefix is prepended to every metric name.
+	Prefix string
+	// DogStatsD adds tags in the DogStatsD "|#key:value" extension. Plain
+	// StatsD has no tags, so they are dropped.
+	DogStatsD bool
+	// Tags are attached to every metric, e.g. env:prod.
+	Tags []string
+	// GaugeInterval is how often queue depth gauges are reported.
+	GaugeInterval time.Duration
+}
+
+// Metrics pushes counters, timings and gauges to a StatsD agent. Sends are
+// fire-and-forget UDP datagrams, so a missing agent never slows requests or
+// scans down. A nil Metrics sends nothing.
+type Metrics struct {
+	cfg    StatsDConfig
+	conn   net.Conn
+	logger *slog.Logger
+}
+
+// NewMetrics returns an emitter for cfg, or nil when no address is configured.
+func NewMetrics(cfg StatsDConfig, logger *slog.Logger) (*Metrics, error) {
+	if cfg.Addr == "" {
+		return nil, nil
+	}
+	conn, err := net.Dial("udp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("statsd agent %s: %w", cfg.Addr, err)
+	}
+	return &Metrics{cfg: cfg, conn: conn, logger: logger}, nil
+}
+
+// Count adds value to the counter name.
+func (m *Metrics) Count(name string, value int64, tags ...string) {
+	m.send(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+// Timing records one duration of name in milliseconds.
+func (m *Metrics) Timing(name string, d time.Duration, tags ...string) {
+	m.send(name, strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64), "ms", tags)
+}
+
+// Gauge sets name to value.
+func (m *Metrics) Gauge(name string, value float64, tags ...string) {
+	m.send(name, strconv.FormatFloat(value, 'f', -1, 64), "g", tags)
+}
+
+func (m *Metrics) send(name, value, kind string, tags []string) {
+	if m == nil {
+		return
+	}
+	var line strings.Builder
+	line.WriteString(m.cfg.Prefix)
+	line.WriteString(name)
+	line.WriteByte(':')
+	line.WriteString(value)
+	line.WriteByte('|')
+	line.WriteString(kind)
+	if m.cfg.DogStatsD && len(m.cfg.Tags)+len(tags) > 0 {
+		line.WriteString("|#")
+		for i, tag := range append(append([]string(nil), m.cfg.Tags...), tags...) {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			line.WriteString(statsdTagReplacer.Replace(tag))
+		}
+	}
+	if _, err := m.conn.Write([]byte(line.String())); err != nil {
+		m.logger.Debug("statsd send failed", "metric", name, "error", err)
+	}
+}
+
+// statsdTagReplacer strips the characters that delimit DogStatsD fields.
+var statsdTagReplacer = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_")
+
+// MetricsMiddleware reports api.requests and api.request.duration for every
+// request, tagged with method, route and status code.
+func MetricsMiddleware(metrics *Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		tags := []string{"method:" + c.Request.Method, "route:" + route, "status:" + strconv.Itoa(c.Writer.Status())}
+		metrics.Count("api.requests", 1, tags...)
+		metrics.Timing("api.request.duration", time.Since(start), tags...)
+	}
+}
+
+// taskMetrics reports a task that reached a terminal state: a scans.completed
+// or scans.failed count, its run time and, for completed tasks, the number of
+// results and open ports.
+func (m *Metrics) taskMetrics(task *ScanTask, runTime time.Duration) {
+	if m == nil {
+		return
+	}
+	tags := []string{"mode:" + task.Mode}
+	if task.Status == "cancelled" {
+		m.Count("scans.cancelled", 1, tags...)
+		m.Timing("scans.duration", runTime, append(tags, "status:cancelled")...)
+		return
+	}
+	if task.Status != "completed" {
+		m.Count("scans.failed", 1, tags...)
+		m.Timing("scans.duration", runTime, append(tags, "status:failed")...)
+		return
+	}
+	open := 0
+	for _, result := range task.Results {
+		if result.State == "Open" {
+			open++
+		}
+	}
+	m.Count("scans.completed", 1, tags...)
+	m.Timing("scans.duration", runTime, append(tags, "status:completed")...)
+	m.Count("scans.results", int64(len(task.Results)), tags...)
+	m.Count("scans.open_ports", int64(open), tags...)
+}
+
+// StartQueueGauges reports queue.depth per mode and queue.paused every
+// GaugeInterval. It does nothing for a nil Metrics.
+func (m *Metrics) StartQueueGauges(store TaskStore) {
+	if m == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(m.cfg.GaugeInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			status, err := store.QueueStatus()
+			if err != nil {
+				m.logger.Warn("failed to read queue state for metrics", "error", err)
+				continue
+			}
+			for _, pool := range status.Pools {
+				m.Gauge("queue.depth", float64(pool.Depth), "mode:"+pool.Mode)
+			}
+			paused := 0.0
+			if status.Paused {
+				paused = 1
+			}
+			m.Gauge("queue.paused", paused)
+		}
+	}()
+}
+
+// loadStatsDConfig reads the emitter settings from the environment:
+// CORTEX_STATSD_ADDR (host:port; empty disables the emitter),
+// CORTEX_STATSD_PREFIX (default "cortex."), CORTEX_STATSD_FLAVOR (statsd or
+// dogstatsd, default statsd), CORTEX_STATSD_TAGS (comma-separated tags added
+// to every metric, dogstatsd only) and CORTEX_STATSD_GAUGE_INTERVAL (Go
+// duration, default 10s).
+func loadStatsDConfig() (StatsDConfig, error) {
+	cfg := StatsDConfig{Addr: os.Getenv("CORTEX_STATSD_ADDR"), Prefix: getenv("CORTEX_STATSD_PREFIX", "cortex.")}
+
+	switch flavor := getenv("CORTEX_STATSD_FLAVOR", "statsd"); flavor {
+	case "statsd":
+	case "dogstatsd":
+		cfg.DogStatsD = true
+	default:
+		return cfg, fmt.Errorf("CORTEX_STATSD_FLAVOR must be statsd or dogstatsd, got %q", flavor)
+	}
+	for _, tag := range strings.Split(os.Getenv("CORTEX_STATSD_TAGS"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			cfg.Tags = append(cfg.Tags, tag)
+		}
+	}
+	if len(cfg.Tags) > 0 && !cfg.DogStatsD {
+		return cfg, fmt.Errorf("CORTEX_STATSD_TAGS requires CORTEX_STATSD_FLAVOR=dogstatsd")
+	}
+
+	var err error
+	if cfg.GaugeInterval, err = getenvDuration("CORTEX_STATSD_GAUGE_INTERVAL", 10*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.GaugeInterval < time.Second {
+		return cfg, fmt.Errorf("CORTEX_STATSD_GAUGE_INTERVAL must be at least 1s")
+	}
+	if cfg.Addr != "" {
+		if _, _, err := net.SplitHostPort(cfg.Addr); err != nil {
+			return cfg, fmt.Errorf("CORTEX_STATSD_ADDR must be host:port: %w", err)
+		}
+	}
+	return cfg, nil
+}
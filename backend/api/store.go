@@ -5,9 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
-	"cortex/scanner"
+	"github.com/Viktor25104/cortex/backend/scanner"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -15,90 +19,2003 @@ import (
 type TaskStore interface {
 	CreateTask(task *ScanTask) error
 	GetTask(id string) (*ScanTask, error)
+	TaskResults(id string) (*ResultReader, error)
+	AppendPartialResults(id string, results []scanner.ScanResult) error
+	PartialResults(id string, start int) ([]scanner.ScanResult, error)
+	DeletePartialResults(id string) error
 	UpdateTask(task *ScanTask) error
-	PushToQueue(taskID string) error
-	PopFromQueue() (string, error)
+	UpdateTaskIf(task *ScanTask, from string) (string, bool, error)
+	MarkTaskRunning(id string) error
+	TaskStatus(id string) (string, error)
+	PauseTask(id string) (string, bool, error)
+	ResumeTask(id string) (string, bool, error)
+	CancelTask(id string) (string, bool, error)
+	FinishShard(id, shard string) (int, error)
+	UpdateProgress(id string, progress float64, etaSeconds int) error
+	UpdateCallback(id string, delivery *CallbackDelivery) error
+	QueueCallback(id string, at time.Time) error
+	DueCallbacks(now time.Time) ([]string, error)
+	RemoveCallback(id string) error
+	DeleteTask(id string) error
+	ListTasks(query TaskQuery) ([]*ScanTask, error)
+	PushToQueue(taskID, mode string) error
+	AcquireRunSlot(owner, taskID string, limit int) (bool, error)
+	ReleaseRunSlot(owner, taskID string) (string, error)
+	RunSlots(owner string) ([]string, error)
+	PopFromQueue(mode string) (string, error)
+	HeartbeatQueueEntry(entry string) error
+	AckQueueEntry(mode, entry string) error
+	StaleQueueEntries(staleAfter time.Duration) ([]QueueClaim, error)
+	RequeueQueueEntry(mode, entry string) (bool, error)
+	RequeueTask(mode, entry string, task *ScanTask) (bool, error)
+	RegisterWorkerNode(node *WorkerNode, ttl time.Duration) error
+	ListWorkerNodes() ([]*WorkerNode, error)
+	CreateAPIKey(key *APIKey, hash string) error
+	APIKeyByHash(hash string) (*APIKey, error)
+	ListAPIKeys() ([]*APIKey, error)
+	RevokeAPIKey(id string) (*APIKey, error)
+	QueuedTaskIDs() ([]string, error)
+	RemoveFromQueue(taskID string) error
+	PauseQueue(by string) error
+	ResumeQueue() error
+	QueueStatus() (*QueueStatus, error)
+	SaveMonitor(monitor *Monitor) error
+	ReplaceMonitor(previous, monitor *Monitor) (bool, error)
+	GetMonitor(id string) (*Monitor, error)
+	DeleteMonitor(id string) error
+	ListMonitors() ([]*Monitor, error)
+	SaveSchedule(schedule *Schedule) error
+	ReplaceSchedule(previous, schedule *Schedule) (bool, error)
+	GetSchedule(id string) (*Schedule, error)
+	DeleteSchedule(id string) error
+	ListSchedules() ([]*Schedule, error)
+	SaveTemplate(template *ScanTemplate) error
+	GetTemplate(id string) (*ScanTemplate, error)
+	DeleteTemplate(id string) error
+	ListTemplates() ([]*ScanTemplate, error)
+	AcquireLease(name, holder string, ttl time.Duration) (bool, error)
+	UpdateInventoryHost(host string, apply func(*InventoryHost)) error
+	GetInventoryHost(host string) (*InventoryHost, error)
+	ListInventory() ([]*InventoryHost, error)
+	SaveRecentResults(protocol string, results []scanner.ScanResult, observedAt time.Time, ttl time.Duration) error
+	RecentResults(protocol string, hosts []string, since time.Time) (map[string]scanner.ScanResult, error)
+	PurgeRecentResults(protocol string, results []scanner.ScanResult, observedAt time.Time) (int, error)
+	Namespace(name string) TaskStore
+	Namespaces() ([]string, error)
+}
+
+// TaskQuery narrows the tasks returned by ListTasks. Tasks are returned oldest
+// first unless NewestFirst is set.
+type TaskQuery struct {
+	// CreatedBefore keeps only tasks created strictly before this instant when set.
+	CreatedBefore time.Time
+	// CreatedAfter keeps only tasks created at or after this instant when set.
+	CreatedAfter time.Time
+	// Status keeps only tasks in this state when set.
+	Status string
+	// Mode keeps only tasks of this scan mode when set.
+	Mode string
+	// Owner keeps only tasks submitted by this principal when set.
+	Owner string
+	// TopLevel leaves out the shards of sharded tasks.
+	TopLevel bool
+	// Offset skips this many matching tasks before the first returned one.
+	Offset int
+	// Limit caps the number of returned tasks; zero means no limit.
+	Limit int
+	// NewestFirst reverses the order so Limit keeps the most recent tasks.
+	NewestFirst bool
+}
+
+// filtered reports whether q keeps only some of the tasks in its time range,
+// so that Offset and Limit cannot be applied to the index directly.
+func (q TaskQuery) filtered() bool {
+	return q.Status != "" || q.Mode != "" || q.Owner != "" || q.TopLevel
+}
+
+const (
+	// queueKey is the shared queue used before tasks were queued per mode; it
+	// is still drained by every pool. Mode queues are "scans:queue:<mode>".
+	queueKey     = "scans:queue"
+	taskIndexKey = "scans:index"
+	// monitorsKey is a hash of monitor ID to JSON-encoded monitor.
+	monitorsKey = "monitors"
+	// schedulesKey is a hash of schedule ID to JSON-encoded schedule.
+	schedulesKey = "schedules"
+	// templatesKey is a hash of scan template ID to JSON-encoded template.
+	templatesKey = "templates"
+	// callbacksKey is a sorted set of the IDs of tasks with a callback
+	// attempt to make, scored by the Unix milliseconds it is due.
+	callbacksKey = "callbacks"
+	// leaseKeyPrefix prefixes the holder of a lease shared by all API
+	// processes, "leases:<name>", which expires unless renewed.
+	leaseKeyPrefix = "leases:"
+	// inventoryKey is the set of hosts with an inventory record.
+	inventoryKey = "inventory:hosts"
+	// namespacesKey is the set of tenant namespaces that have stored tasks.
+	namespacesKey = "tenants"
+	// queuePausedKey holds who paused the queue and when; its presence pauses workers.
+	queuePausedKey = "scans:queue:paused"
+	// runSlotsKey prefixes the set of queue entries an API key has running,
+	// "scans:running:<owner>", and heldKey the list of its entries waiting
+	// for one of those slots, "scans:held:<owner>".
+	runSlotsKey = "scans:running:"
+	heldKey     = "scans:held:"
+	// processingKeyPrefix prefixes the list of entries the workers of a mode
+	// have taken but not yet acknowledged, "scans:processing:<mode>", and
+	// heartbeatsKey is a hash of those entries to the Unix milliseconds of
+	// their last heartbeat.
+	processingKeyPrefix = "scans:processing:"
+	heartbeatsKey       = "scans:heartbeats"
+	// nodesKey is the set of registered worker node IDs; each node's record
+	// is kept in "nodes:<id>" and expires unless the node renews it.
+	nodesKey = "nodes"
+	// apiKeysKey is a hash of managed API key ID to its JSON-encoded record,
+	// and apiKeyHashesKey a hash of the SHA-256 of every active key to its ID.
+	apiKeysKey      = "apikeys"
+	apiKeyHashesKey = "apikeys:hashes"
+)
+
+// queuePollInterval bounds how long a worker blocks on the queue before it
+// re-checks whether the queue has been paused.
+const queuePollInterval = time.Second
+
+// partialResultsTTL is how long the partial results of a task outlive their
+// last append, so lists of tasks whose worker died do not pile up.
+const partialResultsTTL = 24 * time.Hour
+
+// QueueClaim is a queue entry a worker has taken but not yet acknowledged.
+type QueueClaim struct {
+	Mode        string
+	Entry       string
+	HeartbeatAt time.Time
 }
 
 var (
 	// ErrTaskNotFound indicates the requested task doesn't exist in the store.
 	ErrTaskNotFound = errors.New("task not found")
+	// ErrMonitorNotFound indicates the requested monitor doesn't exist in the store.
+	ErrMonitorNotFound = errors.New("monitor not found")
+	// ErrScheduleNotFound indicates the requested schedule doesn't exist in the store.
+	ErrScheduleNotFound = errors.New("schedule not found")
+	// ErrTemplateNotFound indicates the requested scan template doesn't exist in the store.
+	ErrTemplateNotFound = errors.New("template not found")
+	// ErrHostNotFound indicates the requested host has no inventory record.
+	ErrHostNotFound = errors.New("host not found")
+	// ErrAPIKeyNotFound indicates the requested API key doesn't exist or was revoked.
+	ErrAPIKeyNotFound = errors.New("api key not found")
 )
 
-// RedisStore implements TaskStore using Redis as backend.
-type RedisStore struct {
-	client *redis.Client
+// DefaultNamespace is the tenant used by keys that are not bound to a namespace.
+// Its tasks keep the original unprefixed Redis key layout.
+const DefaultNamespace = "default"
+
+// RedisStore implements TaskStore using Redis as backend. Each store value is a
+// view of one tenant namespace; tenants other than the default keep their keys
+// under a "tenant:<name>:" prefix. The work queue is shared by all namespaces so
+// any worker can serve any tenant; entries of non-default tenants are recorded
+// as "<namespace>/<id>".
+type RedisStore struct {
+	client    *redis.Client
+	namespace string
+	prefix    string
+	taskTTL   time.Duration
+}
+
+// NewRedisStore constructs a Redis-backed task store for the default namespace.
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client, namespace: DefaultNamespace}
+}
+
+// SetTaskTTL makes the keys of finished tasks expire ttl after the task was
+// created, as a backstop for the retention janitor. Zero keeps them until
+// they are deleted. Views returned by Namespace afterwards share the setting.
+func (s *RedisStore) SetTaskTTL(ttl time.Duration) {
+	s.taskTTL = ttl
+}
+
+// Namespace returns a view of the store scoped to the given tenant namespace.
+func (s *RedisStore) Namespace(name string) TaskStore {
+	if name == "" || name == DefaultNamespace {
+		return &RedisStore{client: s.client, namespace: DefaultNamespace, taskTTL: s.taskTTL}
+	}
+	return &RedisStore{client: s.client, namespace: name, prefix: fmt.Sprintf("tenant:%s:", name), taskTTL: s.taskTTL}
+}
+
+// Namespaces lists the default namespace followed by every tenant that has stored tasks.
+func (s *RedisStore) Namespaces() ([]string, error) {
+	names, err := s.client.SMembers(context.Background(), namespacesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return append([]string{DefaultNamespace}, names...), nil
+}
+
+func (s *RedisStore) taskKey(id string) string {
+	return fmt.Sprintf("%sscan:%s", s.prefix, id)
+}
+
+// partialResultsKey is the list of results a worker found so far for task id.
+func (s *RedisStore) partialResultsKey(id string) string {
+	return s.taskKey(id) + ":partial"
+}
+
+// resultsKey is the list of the stored results of task id, one JSON result
+// per element. Tasks written before it existed keep theirs in the "results"
+// field of the task hash, which GetTask and TaskResults still read.
+func (s *RedisStore) resultsKey(id string) string {
+	return s.taskKey(id) + ":results"
+}
+
+func (s *RedisStore) indexKey() string {
+	return s.prefix + taskIndexKey
+}
+
+func (s *RedisStore) monitorsKey() string {
+	return s.prefix + monitorsKey
+}
+
+func (s *RedisStore) schedulesKey() string {
+	return s.prefix + schedulesKey
+}
+
+func (s *RedisStore) templatesKey() string {
+	return s.prefix + templatesKey
+}
+
+func (s *RedisStore) callbacksKey() string {
+	return s.prefix + callbacksKey
+}
+
+func (s *RedisStore) inventoryKey() string {
+	return s.prefix + inventoryKey
+}
+
+func (s *RedisStore) recentResultsKey(protocol, host string) string {
+	return s.prefix + "recent:" + protocol + ":" + host
+}
+
+func (s *RedisStore) inventoryHostKey(host string) string {
+	return fmt.Sprintf("%sinventory:host:%s", s.prefix, host)
+}
+
+// queueEntry qualifies a task ID with the store's namespace for the shared queue.
+func (s *RedisStore) queueEntry(taskID string) string {
+	if s.prefix == "" {
+		return taskID
+	}
+	return s.namespace + "/" + taskID
+}
+
+// SplitQueueEntry separates a queue entry into its namespace and task ID.
+func SplitQueueEntry(entry string) (namespace, taskID string) {
+	if ns, id, ok := strings.Cut(entry, "/"); ok {
+		return ns, id
+	}
+	return DefaultNamespace, entry
+}
+
+// CreateTask persists a new scan task in Redis and records it in the
+// creation-time index used for listing and retention.
+func (s *RedisStore) CreateTask(task *ScanTask) error {
+	data, err := serializeTask(task)
+	if err != nil {
+		return err
+	}
+	results, err := encodeResults(task.Results)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, s.taskKey(task.ID), data)
+	s.writeResults(ctx, pipe, task.ID, results)
+	pipe.ZAdd(ctx, s.indexKey(), redis.Z{Score: float64(task.CreatedAt.UnixMilli()), Member: task.ID})
+	if s.prefix != "" {
+		pipe.SAdd(ctx, namespacesKey, s.namespace)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// GetTask retrieves a task by ID.
+func (s *RedisStore) GetTask(id string) (*ScanTask, error) {
+	ctx := context.Background()
+	pipe := s.client.Pipeline()
+	fields := pipe.HGetAll(ctx, s.taskKey(id))
+	results := pipe.LRange(ctx, s.resultsKey(id), 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	if len(fields.Val()) == 0 {
+		return nil, ErrTaskNotFound
+	}
+	task, err := deserializeTask(fields.Val())
+	if err != nil {
+		return nil, err
+	}
+	if values := results.Val(); len(values) > 0 {
+		if task.Results, err = decodeResults(values); err != nil {
+			return nil, fmt.Errorf("results of task %s: %w", id, err)
+		}
+	}
+	return task, nil
+}
+
+// TaskResults opens the stored results of a task for reading one at a time.
+// They are fetched from the results list a page at a time as the reader
+// advances, so neither the rest of the task nor all of its results are held
+// in memory.
+func (s *RedisStore) TaskResults(id string) (*ResultReader, error) {
+	values, err := s.client.HMGet(context.Background(), s.taskKey(id), "status", "results").Result()
+	if err != nil {
+		return nil, err
+	}
+	status, ok := values[0].(string)
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	if legacy, _ := values[1].(string); legacy != "" {
+		return newLegacyResultReader(status, legacy)
+	}
+	key := s.resultsKey(id)
+	return &ResultReader{Status: status, page: func(start int) ([]string, error) {
+		return s.client.LRange(context.Background(), key, int64(start), int64(start+resultsPageSize-1)).Result()
+	}}, nil
+}
+
+// resultsBatch caps the results written by one RPUSH or INSERT.
+const resultsBatch = 1000
+
+// encodeResults returns results as JSON documents in order.
+func encodeResults(results []scanner.ScanResult) ([]interface{}, error) {
+	encoded := make([]interface{}, len(results))
+	for i, result := range results {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = string(data)
+	}
+	return encoded, nil
+}
+
+// decodeResults is the inverse of encodeResults.
+func decodeResults(values []string) ([]scanner.ScanResult, error) {
+	results := make([]scanner.ScanResult, len(values))
+	for i, value := range values {
+		if err := json.Unmarshal([]byte(value), &results[i]); err != nil {
+			return nil, fmt.Errorf("result %d: %w", i, err)
+		}
+	}
+	return results, nil
+}
+
+// writeResults queues on pipe the commands replacing the stored results of
+// task id with results, as returned by encodeResults.
+func (s *RedisStore) writeResults(ctx context.Context, pipe redis.Pipeliner, id string, results []interface{}) {
+	key := s.resultsKey(id)
+	pipe.HDel(ctx, s.taskKey(id), "results")
+	pipe.Del(ctx, key)
+	for start := 0; start < len(results); start += resultsBatch {
+		pipe.RPush(ctx, key, results[start:min(start+resultsBatch, len(results))]...)
+	}
+}
+
+// taskScriptArgs returns the arguments of a script writing task through
+// writeTaskLua: leading, the number of task fields, the field/value pairs
+// and the encoded results.
+func taskScriptArgs(task *ScanTask, leading ...interface{}) ([]interface{}, error) {
+	data, err := serializeTask(task)
+	if err != nil {
+		return nil, err
+	}
+	results, err := encodeResults(task.Results)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, 0, len(leading)+1+2*len(data)+len(results))
+	args = append(args, leading...)
+	args = append(args, 2*len(data))
+	for field, value := range data {
+		args = append(args, field, value)
+	}
+	return append(args, results...), nil
+}
+
+// AppendPartialResults adds results found by the worker running task id to
+// its partial results, which readers can fetch before the task finishes.
+func (s *RedisStore) AppendPartialResults(id string, results []scanner.ScanResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	values := make([]interface{}, len(results))
+	for i, result := range results {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		values[i] = encoded
+	}
+	ctx := context.Background()
+	key := s.partialResultsKey(id)
+	pipe := s.client.TxPipeline()
+	pipe.RPush(ctx, key, values...)
+	pipe.Expire(ctx, key, partialResultsTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// PartialResults returns the partial results of task id from position start
+// on, in the order they were found.
+func (s *RedisStore) PartialResults(id string, start int) ([]scanner.ScanResult, error) {
+	values, err := s.client.LRange(context.Background(), s.partialResultsKey(id), int64(start), -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]scanner.ScanResult, len(values))
+	for i, value := range values {
+		if err := json.Unmarshal([]byte(value), &results[i]); err != nil {
+			return nil, fmt.Errorf("partial result %d of task %s: %w", start+i, id, err)
+		}
+	}
+	return results, nil
+}
+
+// DeletePartialResults erases the partial results of task id.
+func (s *RedisStore) DeletePartialResults(id string) error {
+	return s.client.Del(context.Background(), s.partialResultsKey(id)).Err()
+}
+
+// resultsPageSize is how many stored results a ResultReader fetches at once.
+const resultsPageSize = 500
+
+// ResultReader decodes the results of one task in order, fetching them from
+// the store a page at a time. Pages are read as the caller advances, so
+// results rewritten meanwhile, as when they are purged, can end the reader
+// early or mix with the rest.
+type ResultReader struct {
+	// Status is the task status when the results were read.
+	Status string
+	// page returns up to resultsPageSize encoded results from position start.
+	page    func(start int) ([]string, error)
+	pending []string
+	fetched int
+	done    bool
+	// decoder reads results stored as one JSON array by earlier versions.
+	decoder *json.Decoder
+}
+
+// newLegacyResultReader reads the results of a task stored as the JSON array
+// raw.
+func newLegacyResultReader(status, raw string) (*ResultReader, error) {
+	reader := &ResultReader{Status: status, done: true}
+	if raw == "null" {
+		return reader, nil
+	}
+	reader.decoder = json.NewDecoder(strings.NewReader(raw))
+	if token, err := reader.decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, fmt.Errorf("stored results of task are not a JSON array")
+	}
+	return reader, nil
+}
+
+// Next returns the next result, or io.EOF once every result was read.
+func (r *ResultReader) Next() (scanner.ScanResult, error) {
+	var result scanner.ScanResult
+	if r.decoder != nil {
+		if !r.decoder.More() {
+			return result, io.EOF
+		}
+		err := r.decoder.Decode(&result)
+		return result, err
+	}
+	if len(r.pending) == 0 {
+		if r.done || r.page == nil {
+			return result, io.EOF
+		}
+		page, err := r.page(r.fetched)
+		if err != nil {
+			return result, err
+		}
+		r.pending, r.fetched, r.done = page, r.fetched+len(page), len(page) < resultsPageSize
+		if len(page) == 0 {
+			return result, io.EOF
+		}
+	}
+	err := json.Unmarshal([]byte(r.pending[0]), &result)
+	r.pending = r.pending[1:]
+	return result, err
+}
+
+// taskExpiryFloor is the least time a finished task's key is given before it
+// expires, so that a task finishing after its TTL is still seen once.
+const taskExpiryFloor = time.Hour
+
+// UpdateTask updates an existing task in Redis. With a task TTL set, the key
+// of a finished task is given its expiry.
+func (s *RedisStore) UpdateTask(task *ScanTask) error {
+	data, err := serializeTask(task)
+	if err != nil {
+		return err
+	}
+	results, err := encodeResults(task.Results)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, s.taskKey(task.ID), data)
+	s.writeResults(ctx, pipe, task.ID, results)
+	if expireAt := s.taskExpiry(task); !expireAt.IsZero() {
+		pipe.ExpireAt(ctx, s.taskKey(task.ID), expireAt)
+		pipe.ExpireAt(ctx, s.resultsKey(task.ID), expireAt)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// taskExpiry returns when the key of task expires, or the zero time while it
+// is unfinished or no task TTL is set.
+func (s *RedisStore) taskExpiry(task *ScanTask) time.Time {
+	if s.taskTTL <= 0 || !taskFinished(task.Status) {
+		return time.Time{}
+	}
+	expireAt := task.CreatedAt.Add(s.taskTTL)
+	if floor := time.Now().Add(taskExpiryFloor); expireAt.Before(floor) {
+		expireAt = floor
+	}
+	return expireAt
+}
+
+// writeTaskLua is the part of a script that writes a task from ARGV[n + 1]
+// on, n being the number of arguments before it: the count of field/value
+// arguments, those pairs for the task hash KEYS[1] and the results for its
+// results list KEYS[2], pushed in batches to stay within Lua's stack.
+const writeTaskLua = `
+local fields = tonumber(ARGV[n + 1])
+redis.call('HSET', KEYS[1], unpack(ARGV, n + 2, n + 1 + fields))
+redis.call('HDEL', KEYS[1], 'results')
+redis.call('DEL', KEYS[2])
+for i = n + 2 + fields, #ARGV, 1000 do
+  redis.call('RPUSH', KEYS[2], unpack(ARGV, i, math.min(i + 999, #ARGV)))
+end
+`
+
+// updateTaskIfScript writes a task through writeTaskLua when the status of
+// KEYS[1] is ARGV[1], expiring it at ARGV[2] (Unix milliseconds, 0 for
+// never), and replies like pauseScript.
+var updateTaskIfScript = redis.NewScript(`
+local status = redis.call('HGET', KEYS[1], 'status')
+if not status then
+  return {}
+end
+if status ~= ARGV[1] then
+  return {status, 0}
+end
+local n = 2
+` + writeTaskLua + `
+if ARGV[2] ~= '0' then
+  redis.call('PEXPIREAT', KEYS[1], ARGV[2])
+  redis.call('PEXPIREAT', KEYS[2], ARGV[2])
+end
+return {redis.call('HGET', KEYS[1], 'status'), 1}
+`)
+
+// UpdateTaskIf writes task like UpdateTask, but only while its stored status
+// is from, so a worker cannot overwrite a pause, resume or cancel that came
+// in since it loaded the task. It returns the status of the task and whether
+// it was written.
+func (s *RedisStore) UpdateTaskIf(task *ScanTask, from string) (string, bool, error) {
+	var expireAt int64
+	if at := s.taskExpiry(task); !at.IsZero() {
+		expireAt = at.UnixMilli()
+	}
+	args, err := taskScriptArgs(task, from, expireAt)
+	if err != nil {
+		return "", false, err
+	}
+	return s.runStatusScript(updateTaskIfScript, []string{s.taskKey(task.ID), s.resultsKey(task.ID)}, args...)
+}
+
+// markRunningScript moves a pending task to running and leaves tasks in any
+// other state alone.
+var markRunningScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'status') == 'pending' then
+  redis.call('HSET', KEYS[1], 'status', 'running')
+end
+return 0
+`)
+
+// MarkTaskRunning sets a pending task to running without rewriting the rest
+// of it, so every shard can flag its parent as the first one starts.
+func (s *RedisStore) MarkTaskRunning(id string) error {
+	return markRunningScript.Run(context.Background(), s.client, []string{s.taskKey(id)}).Err()
+}
+
+// TaskStatus returns the status of a task without loading the rest of it.
+func (s *RedisStore) TaskStatus(id string) (string, error) {
+	status, err := s.client.HGet(context.Background(), s.taskKey(id), "status").Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrTaskNotFound
+	}
+	return status, err
+}
+
+// pauseScript parks a pending or held task as paused and asks the worker
+// running a running task to pause it. It returns the resulting status and 1, the
+// current status and 0 when the task cannot be paused, or nothing for a
+// missing task.
+var pauseScript = redis.NewScript(`
+local status = redis.call('HGET', KEYS[1], 'status')
+if not status then
+  return {}
+end
+if status == 'pending' or status == 'held' then
+  status = 'paused'
+elseif status == 'running' then
+  status = 'pausing'
+else
+  return {status, 0}
+end
+redis.call('HSET', KEYS[1], 'status', status)
+return {status, 1}
+`)
+
+// PauseTask moves a pending or held task to paused and a running one to pausing,
+// which its worker turns into paused once in-flight probes finished. It
+// returns the status of the task and whether it changed. Removing a paused
+// task from the queue is left to the caller.
+func (s *RedisStore) PauseTask(id string) (string, bool, error) {
+	return s.runStatusScript(pauseScript, []string{s.taskKey(id)})
+}
+
+// resumeScript returns a paused task to pending and withdraws a pause its
+// worker has not acted on yet, replying like pauseScript.
+var resumeScript = redis.NewScript(`
+local status = redis.call('HGET', KEYS[1], 'status')
+if not status then
+  return {}
+end
+if status == 'paused' then
+  status = 'pending'
+elseif status == 'pausing' then
+  status = 'running'
+else
+  return {status, 0}
+end
+redis.call('HSET', KEYS[1], 'status', status)
+return {status, 1}
+`)
+
+// ResumeTask moves a paused task back to pending and a pausing one back to
+// running. It returns the status of the task and whether it changed.
+// Queueing the pending task again is left to the caller.
+func (s *RedisStore) ResumeTask(id string) (string, bool, error) {
+	return s.runStatusScript(resumeScript, []string{s.taskKey(id)})
+}
+
+// cancelScript ends a pending, held or paused task as cancelled and asks the
+// worker running a running or pausing task to cancel it, replying like
+// pauseScript.
+var cancelScript = redis.NewScript(`
+local status = redis.call('HGET', KEYS[1], 'status')
+if not status then
+  return {}
+end
+if status == 'pending' or status == 'held' or status == 'paused' then
+  status = 'cancelled'
+elseif status == 'running' or status == 'pausing' then
+  status = 'cancelling'
+else
+  return {status, 0}
+end
+redis.call('HSET', KEYS[1], 'status', status)
+return {status, 1}
+`)
+
+// CancelTask moves a task that no worker runs to cancelled and a running one
+// to cancelling, which its worker turns into cancelled once in-flight probes
+// finished. It returns the status of the task and whether it changed.
+// Removing a cancelled task from the queue and finishing it are left to the
+// caller.
+func (s *RedisStore) CancelTask(id string) (string, bool, error) {
+	return s.runStatusScript(cancelScript, []string{s.taskKey(id)})
+}
+
+func (s *RedisStore) runStatusScript(script *redis.Script, keys []string, args ...interface{}) (string, bool, error) {
+	reply, err := script.Run(context.Background(), s.client, keys, args...).Slice()
+	if err != nil {
+		return "", false, err
+	}
+	if len(reply) != 2 {
+		return "", false, ErrTaskNotFound
+	}
+	status, _ := reply[0].(string)
+	changed, _ := reply[1].(int64)
+	return status, changed == 1, nil
+}
+
+// setIfStatusScript sets the fields and values in ARGV from ARGV[2] on task
+// KEYS[1] while its status is one of the space-separated ARGV[1] and
+// replies 1. It replies 0 for a task in another status and -1 for a missing
+// one, writing nothing.
+var setIfStatusScript = redis.NewScript(`
+local status = redis.call('HGET', KEYS[1], 'status')
+if not status then
+  return -1
+end
+if not string.find(' ' .. ARGV[1] .. ' ', ' ' .. status .. ' ', 1, true) then
+  return 0
+end
+redis.call('HSET', KEYS[1], unpack(ARGV, 2))
+return 1
+`)
+
+// UpdateProgress records how far the worker running task id got without
+// rewriting the rest of the task. Progress of a task no worker runs any
+// more is dropped, so a late write cannot follow its final state; a deleted
+// task yields ErrTaskNotFound.
+func (s *RedisStore) UpdateProgress(id string, progress float64, etaSeconds int) error {
+	set, err := setIfStatusScript.Run(context.Background(), s.client, []string{s.taskKey(id)}, "running pausing cancelling",
+		"progress", strconv.FormatFloat(progress, 'f', -1, 64),
+		"eta_seconds", strconv.Itoa(etaSeconds)).Int()
+	if err != nil {
+		return err
+	}
+	if set < 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// setIfExistsScript sets the fields and values in ARGV on hash KEYS[1] and
+// replies 1, or replies 0 without creating the hash when it is missing.
+var setIfExistsScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+  return 0
+end
+redis.call('HSET', KEYS[1], unpack(ARGV))
+return 1
+`)
+
+// UpdateCallback records the delivery state of the task's callback without
+// rewriting the rest of the task. It returns ErrTaskNotFound, and writes
+// nothing, once the task was deleted.
+func (s *RedisStore) UpdateCallback(id string, delivery *CallbackDelivery) error {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	set, err := setIfExistsScript.Run(context.Background(), s.client, []string{s.taskKey(id)}, "callback", string(data)).Int()
+	if err != nil {
+		return err
+	}
+	if set == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// finishShardScript adds ARGV[1] to the comma-separated shards_finished of
+// task KEYS[1] unless it is there already, stores their number as
+// shards_done and replies with it, or with -1 when the task is missing.
+var finishShardScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+  return -1
+end
+local finished = redis.call('HGET', KEYS[1], 'shards_finished') or ''
+local count, seen = 0, false
+for shard in string.gmatch(finished, '[^,]+') do
+  count = count + 1
+  if shard == ARGV[1] then
+    seen = true
+  end
+end
+if not seen then
+  count = count + 1
+  if finished ~= '' then
+    finished = finished .. ','
+  end
+  redis.call('HSET', KEYS[1], 'shards_finished', finished .. ARGV[1])
+end
+redis.call('HSET', KEYS[1], 'shards_done', count)
+return count
+`)
+
+// FinishShard atomically records shard as finished on parent task id and
+// returns how many distinct shards have finished. Recording a shard again,
+// as a redelivered queue message does, leaves the count unchanged.
+func (s *RedisStore) FinishShard(id, shard string) (int, error) {
+	done, err := finishShardScript.Run(context.Background(), s.client, []string{s.taskKey(id)}, shard).Int()
+	if err != nil {
+		return 0, err
+	}
+	if done < 0 {
+		return 0, ErrTaskNotFound
+	}
+	return done, nil
+}
+
+// DeleteTask removes a task, its results, partial results and index entry.
+// Deleting a missing task is not an error.
+func (s *RedisStore) DeleteTask(id string) error {
+	ctx := context.Background()
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, s.taskKey(id), s.resultsKey(id), s.partialResultsKey(id))
+	pipe.ZRem(ctx, s.indexKey(), id)
+	pipe.ZRem(ctx, s.callbacksKey(), id)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// QueueCallback schedules the next callback attempt of task id for at,
+// replacing any attempt queued before.
+func (s *RedisStore) QueueCallback(id string, at time.Time) error {
+	return s.client.ZAdd(context.Background(), s.callbacksKey(), redis.Z{Score: float64(at.UnixMilli()), Member: id}).Err()
+}
+
+// DueCallbacks returns the IDs of the tasks whose callback attempt is due by
+// now, longest due first.
+func (s *RedisStore) DueCallbacks(now time.Time) ([]string, error) {
+	return s.client.ZRangeByScore(context.Background(), s.callbacksKey(), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.UnixMilli(), 10),
+	}).Result()
+}
+
+// RemoveCallback drops the queued callback attempt of task id, if any.
+func (s *RedisStore) RemoveCallback(id string) error {
+	return s.client.ZRem(context.Background(), s.callbacksKey(), id).Err()
+}
+
+// ListTasks returns tasks from the creation-time index in the order requested
+// by query. Index entries whose task hash no longer exists are dropped from
+// the index. Status, mode and shard filters only read those fields of each
+// indexed task, so full tasks are loaded for the requested page alone.
+func (s *RedisStore) ListTasks(query TaskQuery) ([]*ScanTask, error) {
+	ctx := context.Background()
+	rangeBy := &redis.ZRangeBy{Min: "-inf", Max: "+inf"}
+	if !query.CreatedBefore.IsZero() {
+		rangeBy.Max = "(" + strconv.FormatInt(query.CreatedBefore.UnixMilli(), 10)
+	}
+	if !query.CreatedAfter.IsZero() {
+		rangeBy.Min = strconv.FormatInt(query.CreatedAfter.UnixMilli(), 10)
+	}
+	if !query.filtered() && (query.Limit > 0 || query.Offset > 0) {
+		rangeBy.Offset = int64(query.Offset)
+		rangeBy.Count = int64(query.Limit)
+		if query.Limit == 0 {
+			rangeBy.Count = -1
+		}
+	}
+
+	var ids []string
+	var err error
+	if query.NewestFirst {
+		ids, err = s.client.ZRevRangeByScore(ctx, s.indexKey(), rangeBy).Result()
+	} else {
+		ids, err = s.client.ZRangeByScore(ctx, s.indexKey(), rangeBy).Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if query.filtered() {
+		if ids, err = s.filterTaskIDs(ctx, ids, query); err != nil {
+			return nil, err
+		}
+	}
+
+	tasks := make([]*ScanTask, 0, len(ids))
+	for _, id := range ids {
+		task, err := s.GetTask(id)
+		if errors.Is(err, ErrTaskNotFound) {
+			if err := s.client.ZRem(ctx, s.indexKey(), id).Err(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("load task %s: %w", id, err)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// filterTaskBatch is how many indexed tasks filterTaskIDs inspects per round
+// trip.
+const filterTaskBatch = 500
+
+// filterTaskIDs returns the ids, in order, of the tasks that match the
+// filters of query, skipping query.Offset of them and stopping after
+// query.Limit. Tasks whose hash no longer exists are left out; ListTasks
+// drops their index entries when it meets them unfiltered.
+func (s *RedisStore) filterTaskIDs(ctx context.Context, ids []string, query TaskQuery) ([]string, error) {
+	var matched []string
+	skip := query.Offset
+	for start := 0; start < len(ids); start += filterTaskBatch {
+		batch := ids[start:min(start+filterTaskBatch, len(ids))]
+		pipe := s.client.Pipeline()
+		cmds := make([]*redis.SliceCmd, len(batch))
+		for i, id := range batch {
+			cmds[i] = pipe.HMGet(ctx, s.taskKey(id), "status", "mode", "parent", "owner")
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+		for i, cmd := range cmds {
+			fields := cmd.Val()
+			status, _ := fields[0].(string)
+			mode, _ := fields[1].(string)
+			parent, _ := fields[2].(string)
+			owner, _ := fields[3].(string)
+			switch {
+			case status == "":
+				continue
+			case query.Status != "" && status != query.Status:
+				continue
+			case query.Mode != "" && mode != query.Mode:
+				continue
+			case query.Owner != "" && owner != query.Owner:
+				continue
+			case query.TopLevel && parent != "":
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			matched = append(matched, batch[i])
+			if query.Limit > 0 && len(matched) == query.Limit {
+				return matched, nil
+			}
+		}
+	}
+	return matched, nil
+}
+
+// SaveMonitor creates or replaces a monitor.
+func (s *RedisStore) SaveMonitor(monitor *Monitor) error {
+	data, err := json.Marshal(monitor)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, s.monitorsKey(), monitor.ID, data)
+	if s.prefix != "" {
+		pipe.SAdd(ctx, namespacesKey, s.namespace)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// replaceFieldScript sets field ARGV[1] of hash KEYS[1] to ARGV[3] and
+// replies 1 while it still holds ARGV[2], or replies 0 and writes nothing.
+var replaceFieldScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], ARGV[1]) ~= ARGV[2] then
+  return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+return 1
+`)
+
+// ReplaceMonitor stores monitor only while the stored monitor is still
+// previous, so the scheduler cannot bring back a deleted monitor or undo a
+// write it did not see. It reports whether monitor was stored.
+func (s *RedisStore) ReplaceMonitor(previous, monitor *Monitor) (bool, error) {
+	return s.replaceField(s.monitorsKey(), monitor.ID, previous, monitor)
+}
+
+// replaceField stores value as field of hash key while the field still
+// holds previous.
+func (s *RedisStore) replaceField(key, field string, previous, value interface{}) (bool, error) {
+	old, err := json.Marshal(previous)
+	if err != nil {
+		return false, err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+	replaced, err := replaceFieldScript.Run(context.Background(), s.client, []string{key}, field, string(old), string(data)).Int()
+	if err != nil {
+		return false, err
+	}
+	return replaced == 1, nil
+}
+
+// GetMonitor retrieves a monitor by ID.
+func (s *RedisStore) GetMonitor(id string) (*Monitor, error) {
+	raw, err := s.client.HGet(context.Background(), s.monitorsKey(), id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMonitorNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var monitor Monitor
+	if err := json.Unmarshal([]byte(raw), &monitor); err != nil {
+		return nil, err
+	}
+	return &monitor, nil
+}
+
+// DeleteMonitor removes a monitor. Deleting a missing monitor is not an error.
+func (s *RedisStore) DeleteMonitor(id string) error {
+	return s.client.HDel(context.Background(), s.monitorsKey(), id).Err()
+}
+
+// ListMonitors returns every monitor of the namespace ordered by creation time.
+func (s *RedisStore) ListMonitors() ([]*Monitor, error) {
+	entries, err := s.client.HGetAll(context.Background(), s.monitorsKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+	monitors := make([]*Monitor, 0, len(entries))
+	for id, raw := range entries {
+		var monitor Monitor
+		if err := json.Unmarshal([]byte(raw), &monitor); err != nil {
+			return nil, fmt.Errorf("load monitor %s: %w", id, err)
+		}
+		monitors = append(monitors, &monitor)
+	}
+	sort.Slice(monitors, func(i, j int) bool {
+		if !monitors[i].CreatedAt.Equal(monitors[j].CreatedAt) {
+			return monitors[i].CreatedAt.Before(monitors[j].CreatedAt)
+		}
+		return monitors[i].ID < monitors[j].ID
+	})
+	return monitors, nil
+}
+
+// SaveSchedule creates or replaces a schedule.
+func (s *RedisStore) SaveSchedule(schedule *Schedule) error {
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, s.schedulesKey(), schedule.ID, data)
+	if s.prefix != "" {
+		pipe.SAdd(ctx, namespacesKey, s.namespace)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// ReplaceSchedule stores schedule only while the stored schedule is still
+// previous, so the scheduler cannot bring back a deleted schedule or undo a
+// pause. It reports whether schedule was stored.
+func (s *RedisStore) ReplaceSchedule(previous, schedule *Schedule) (bool, error) {
+	return s.replaceField(s.schedulesKey(), schedule.ID, previous, schedule)
+}
+
+// GetSchedule retrieves a schedule by ID.
+func (s *RedisStore) GetSchedule(id string) (*Schedule, error) {
+	raw, err := s.client.HGet(context.Background(), s.schedulesKey(), id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrScheduleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var schedule Schedule
+	if err := json.Unmarshal([]byte(raw), &schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// DeleteSchedule removes a schedule. Deleting a missing schedule is not an error.
+func (s *RedisStore) DeleteSchedule(id string) error {
+	return s.client.HDel(context.Background(), s.schedulesKey(), id).Err()
+}
+
+// ListSchedules returns every schedule of the namespace ordered by creation time.
+func (s *RedisStore) ListSchedules() ([]*Schedule, error) {
+	entries, err := s.client.HGetAll(context.Background(), s.schedulesKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+	schedules := make([]*Schedule, 0, len(entries))
+	for id, raw := range entries {
+		var schedule Schedule
+		if err := json.Unmarshal([]byte(raw), &schedule); err != nil {
+			return nil, fmt.Errorf("load schedule %s: %w", id, err)
+		}
+		schedules = append(schedules, &schedule)
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		if !schedules[i].CreatedAt.Equal(schedules[j].CreatedAt) {
+			return schedules[i].CreatedAt.Before(schedules[j].CreatedAt)
+		}
+		return schedules[i].ID < schedules[j].ID
+	})
+	return schedules, nil
+}
+
+// SaveTemplate creates or replaces a scan template.
+func (s *RedisStore) SaveTemplate(template *ScanTemplate) error {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, s.templatesKey(), template.ID, data)
+	if s.prefix != "" {
+		pipe.SAdd(ctx, namespacesKey, s.namespace)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// GetTemplate retrieves a scan template by ID.
+func (s *RedisStore) GetTemplate(id string) (*ScanTemplate, error) {
+	raw, err := s.client.HGet(context.Background(), s.templatesKey(), id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var template ScanTemplate
+	if err := json.Unmarshal([]byte(raw), &template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// DeleteTemplate removes a scan template. Deleting a missing template is not an error.
+func (s *RedisStore) DeleteTemplate(id string) error {
+	return s.client.HDel(context.Background(), s.templatesKey(), id).Err()
+}
+
+// ListTemplates returns every scan template of the namespace ordered by creation time.
+func (s *RedisStore) ListTemplates() ([]*ScanTemplate, error) {
+	entries, err := s.client.HGetAll(context.Background(), s.templatesKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+	templates := make([]*ScanTemplate, 0, len(entries))
+	for id, raw := range entries {
+		var template ScanTemplate
+		if err := json.Unmarshal([]byte(raw), &template); err != nil {
+			return nil, fmt.Errorf("load template %s: %w", id, err)
+		}
+		templates = append(templates, &template)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if !templates[i].CreatedAt.Equal(templates[j].CreatedAt) {
+			return templates[i].CreatedAt.Before(templates[j].CreatedAt)
+		}
+		return templates[i].ID < templates[j].ID
+	})
+	return templates, nil
+}
+
+// acquireLeaseScript renews the lease KEYS[1] for ARGV[2] milliseconds when
+// ARGV[1] holds it, or takes it when nobody does.
+var acquireLeaseScript = redis.NewScript(`
+local holder = redis.call('GET', KEYS[1])
+if holder == ARGV[1] then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+  return 1
+end
+if not holder then
+  redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+  return 1
+end
+return 0
+`)
+
+// AcquireLease takes or renews the lease name, shared by all namespaces, for
+// holder and reports whether holder has it for the next ttl. A lease whose
+// holder stops renewing it is free again once ttl has passed.
+func (s *RedisStore) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	acquired, err := acquireLeaseScript.Run(context.Background(), s.client,
+		[]string{leaseKeyPrefix + name}, holder, ttl.Milliseconds()).Int()
+	return acquired == 1, err
+}
+
+// inventoryUpdateAttempts bounds the retries of an inventory update that keeps
+// losing races with concurrent workers.
+const inventoryUpdateAttempts = 10
+
+// UpdateInventoryHost applies a change to the inventory record of host,
+// starting from an empty record when none exists. Updates from concurrent
+// workers are serialized with optimistic locking, so apply may run more than once.
+func (s *RedisStore) UpdateInventoryHost(host string, apply func(*InventoryHost)) error {
+	ctx := context.Background()
+	key := s.inventoryHostKey(host)
+	update := func(tx *redis.Tx) error {
+		record := &InventoryHost{Host: host}
+		raw, err := tx.Get(ctx, key).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		if err == nil {
+			if err := json.Unmarshal([]byte(raw), record); err != nil {
+				return err
+			}
+		}
+		apply(record)
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, 0)
+			pipe.SAdd(ctx, s.inventoryKey(), host)
+			if s.prefix != "" {
+				pipe.SAdd(ctx, namespacesKey, s.namespace)
+			}
+			return nil
+		})
+		return err
+	}
+
+	for attempt := 0; attempt < inventoryUpdateAttempts; attempt++ {
+		err := s.client.Watch(ctx, update, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return fmt.Errorf("inventory update for %s kept conflicting with concurrent writers", host)
+}
+
+// GetInventoryHost retrieves the inventory record of host.
+func (s *RedisStore) GetInventoryHost(host string) (*InventoryHost, error) {
+	raw, err := s.client.Get(context.Background(), s.inventoryHostKey(host)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrHostNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record InventoryHost
+	if err := json.Unmarshal([]byte(raw), &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// ListInventory returns every inventory record of the namespace sorted by host.
+func (s *RedisStore) ListInventory() ([]*InventoryHost, error) {
+	ctx := context.Background()
+	hosts, err := s.client.SMembers(ctx, s.inventoryKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(hosts)
+	records := make([]*InventoryHost, 0, len(hosts))
+	for _, host := range hosts {
+		record, err := s.GetInventoryHost(host)
+		if errors.Is(err, ErrHostNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("load inventory for %s: %w", host, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// recentResult is a probe result kept for reuse by later tasks.
+type recentResult struct {
+	ObservedAt time.Time          `json:"observed_at"`
+	Result     scanner.ScanResult `json:"result"`
+}
+
+// SaveRecentResults records results probed at observedAt so tasks started
+// within ttl can reuse them. Results are kept in one hash per host and
+// protocol whose expiry is refreshed on every write; reused results are not
+// recorded again, so reuse never extends the age of an observation.
+func (s *RedisStore) SaveRecentResults(protocol string, results []scanner.ScanResult, observedAt time.Time, ttl time.Duration) error {
+	byHost := make(map[string]map[string]interface{})
+	for _, result := range results {
+		if result.Reused {
+			continue
+		}
+		encoded, err := json.Marshal(recentResult{ObservedAt: observedAt, Result: result})
+		if err != nil {
+			return err
+		}
+		if byHost[result.Host] == nil {
+			byHost[result.Host] = make(map[string]interface{})
+		}
+		byHost[result.Host][result.Address+"|"+strconv.Itoa(result.Port)] = string(encoded)
+	}
+
+	ctx := context.Background()
+	pipe := s.client.Pipeline()
+	for host, fields := range byHost {
+		key := s.recentResultsKey(protocol, host)
+		pipe.HSet(ctx, key, fields)
+		pipe.Expire(ctx, key, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// RecentResults returns the results of hosts observed at or after since,
+// keyed by RecentResultKey.
+func (s *RedisStore) RecentResults(protocol string, hosts []string, since time.Time) (map[string]scanner.ScanResult, error) {
+	ctx := context.Background()
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(hosts))
+	for i, host := range hosts {
+		cmds[i] = pipe.HGetAll(ctx, s.recentResultsKey(protocol, host))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	recent := make(map[string]scanner.ScanResult)
+	for i, cmd := range cmds {
+		for field, raw := range cmd.Val() {
+			var entry recentResult
+			if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+				return nil, fmt.Errorf("recent result %s of %s: %w", field, hosts[i], err)
+			}
+			if entry.ObservedAt.Before(since) {
+				continue
+			}
+			recent[RecentResultKey(hosts[i], entry.Result.Address, entry.Result.Port)] = entry.Result
+		}
+	}
+	return recent, nil
+}
+
+// PurgeRecentResults deletes the entries SaveRecentResults recorded for
+// results at observedAt and returns how many it deleted. Entries a later
+// observation has replaced since are kept.
+func (s *RedisStore) PurgeRecentResults(protocol string, results []scanner.ScanResult, observedAt time.Time) (int, error) {
+	byHost := make(map[string][]string)
+	for _, result := range results {
+		if !result.Reused {
+			byHost[result.Host] = append(byHost[result.Host], result.Address+"|"+strconv.Itoa(result.Port))
+		}
+	}
+
+	ctx := context.Background()
+	pipe := s.client.Pipeline()
+	cmds := make(map[string]*redis.SliceCmd, len(byHost))
+	for host, fields := range byHost {
+		cmds[host] = pipe.HMGet(ctx, s.recentResultsKey(protocol, host), fields...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	pipe = s.client.Pipeline()
+	for host, cmd := range cmds {
+		var stale []string
+		for i, value := range cmd.Val() {
+			raw, ok := value.(string)
+			if !ok {
+				continue
+			}
+			var entry recentResult
+			if err := json.Unmarshal([]byte(raw), &entry); err != nil || !entry.ObservedAt.Equal(observedAt) {
+				continue
+			}
+			stale = append(stale, byHost[host][i])
+		}
+		if len(stale) > 0 {
+			pipe.HDel(ctx, s.recentResultsKey(protocol, host), stale...)
+			purged += len(stale)
+		}
+	}
+	if purged == 0 {
+		return 0, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// RecentResultKey identifies a probed port among the results of RecentResults.
+func RecentResultKey(host, address string, port int) string {
+	return host + "|" + address + "|" + strconv.Itoa(port)
+}
+
+// IndexExistingTasks adds default namespace tasks written before the
+// creation-time index existed to that index so listing and retention can see
+// them. It returns the number of tasks that were added.
+func (s *RedisStore) IndexExistingTasks() (int, error) {
+	ctx := context.Background()
+	added := 0
+	iter := s.client.Scan(ctx, 0, "scan:*", 256).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		id := strings.TrimPrefix(key, "scan:")
+		if strings.Contains(id, ":") {
+			continue
+		}
+		if _, err := s.client.ZScore(ctx, taskIndexKey, id).Result(); err == nil {
+			continue
+		} else if !errors.Is(err, redis.Nil) {
+			return added, err
+		}
+
+		raw, err := s.client.HGet(ctx, key, "created_at").Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return added, err
+		}
+		createdAt, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return added, fmt.Errorf("task %s has invalid created_at: %w", id, err)
+		}
+		if err := s.client.ZAdd(ctx, taskIndexKey, redis.Z{Score: float64(createdAt.UnixMilli()), Member: id}).Err(); err != nil {
+			return added, err
+		}
+		added++
+	}
+	return added, iter.Err()
+}
+
+// QueueModes lists the scan modes that have their own queue and worker pool.
+var QueueModes = []scanner.Mode{scanner.ModeConnect, scanner.ModeSyn, scanner.ModeUDP}
+
+// modeQueueKey returns the queue holding tasks of mode. An unknown mode falls
+// back to the connect queue, like an empty mode does when scanning; fin, null,
+// xmas and ack scans share the syn queue and pool, as they need the same raw
+// capture access.
+func modeQueueKey(mode string) string {
+	parsed, err := scanner.ParseMode(mode)
+	if err != nil {
+		parsed = scanner.ModeConnect
+	}
+	if parsed.RawTCP() {
+		parsed = scanner.ModeSyn
+	}
+	return queueKey + ":" + string(parsed)
+}
+
+// queueKeys returns every queue key, the legacy shared queue last.
+func queueKeys() []string {
+	keys := make([]string, 0, len(QueueModes)+1)
+	for _, mode := range QueueModes {
+		keys = append(keys, modeQueueKey(string(mode)))
+	}
+	return append(keys, queueKey)
 }
 
-// NewRedisStore constructs a Redis-backed task store.
-func NewRedisStore(client *redis.Client) *RedisStore {
-	return &RedisStore{client: client}
+// PushToQueue enqueues a task ID of this namespace on the queue of its scan mode.
+func (s *RedisStore) PushToQueue(taskID, mode string) error {
+	return s.client.LPush(context.Background(), modeQueueKey(mode), s.queueEntry(taskID)).Err()
 }
 
-func (s *RedisStore) taskKey(id string) string {
-	return fmt.Sprintf("scan:%s", id)
+// acquireRunSlotScript takes one of ARGV[2] running slots of an owner for
+// the queue entry ARGV[1]. When every slot is taken it appends the entry to
+// the owner's held list and marks the task in KEYS[3] held.
+var acquireRunSlotScript = redis.NewScript(`
+if redis.call('SISMEMBER', KEYS[1], ARGV[1]) == 1 then
+  return 1
+end
+if redis.call('SCARD', KEYS[1]) < tonumber(ARGV[2]) then
+  redis.call('SADD', KEYS[1], ARGV[1])
+  return 1
+end
+redis.call('LREM', KEYS[2], 0, ARGV[1])
+redis.call('RPUSH', KEYS[2], ARGV[1])
+redis.call('HSET', KEYS[3], 'status', 'held')
+return 0
+`)
+
+// AcquireRunSlot lets a task submitted by owner run when the owner has fewer
+// than limit tasks running. Otherwise the task is set to held until
+// ReleaseRunSlot hands it back. Slots and held tasks are tracked by queue
+// entry and shared by all namespaces, like the queue.
+func (s *RedisStore) AcquireRunSlot(owner, taskID string, limit int) (bool, error) {
+	acquired, err := acquireRunSlotScript.Run(context.Background(), s.client,
+		[]string{runSlotsKey + owner, heldKey + owner, s.taskKey(taskID)}, s.queueEntry(taskID), limit).Int()
+	return acquired == 1, err
 }
 
-// CreateTask persists a new scan task in Redis.
-func (s *RedisStore) CreateTask(task *ScanTask) error {
-	data, err := serializeTask(task)
+// releaseRunSlotScript frees the slot of ARGV[1] and pops the oldest held entry.
+var releaseRunSlotScript = redis.NewScript(`
+redis.call('SREM', KEYS[1], ARGV[1])
+local next = redis.call('LPOP', KEYS[2])
+if not next then
+  return ''
+end
+return next
+`)
+
+// ReleaseRunSlot frees the slot of a task, if it has one, and returns the
+// queue entry of the oldest held task of owner, or "" when none waits. The
+// caller queues it again.
+func (s *RedisStore) ReleaseRunSlot(owner, taskID string) (string, error) {
+	return releaseRunSlotScript.Run(context.Background(), s.client,
+		[]string{runSlotsKey + owner, heldKey + owner}, s.queueEntry(taskID)).Text()
+}
+
+// RunSlots lists the queue entries owner has running.
+func (s *RedisStore) RunSlots(owner string) ([]string, error) {
+	return s.client.SMembers(context.Background(), runSlotsKey+owner).Result()
+}
+
+// processingKey returns the list of entries of mode taken by workers.
+func processingKey(mode string) string {
+	return processingKeyPrefix + queueMode(mode)
+}
+
+// PopFromQueue blocks until an entry of mode is available and the queue is not
+// paused. Entries left in the legacy shared queue are served by every mode.
+// Queues are shared by all namespaces, so the result is a raw entry to be
+// resolved with SplitQueueEntry. The entry is moved to the processing list of
+// mode in the same step and stays there until AckQueueEntry, so that a worker
+// dying mid-scan does not lose it. A task popped while a pause was being
+// applied is returned to the head of its queue.
+func (s *RedisStore) PopFromQueue(mode string) (string, error) {
+	ctx := context.Background()
+	processing := processingKey(mode)
+	for {
+		paused, err := s.queuePaused(ctx)
+		if err != nil {
+			return "", err
+		}
+		if paused {
+			time.Sleep(queuePollInterval)
+			continue
+		}
+
+		source := queueKey
+		entry, err := s.client.LMove(ctx, source, processing, "RIGHT", "LEFT").Result()
+		if errors.Is(err, redis.Nil) {
+			source = modeQueueKey(mode)
+			entry, err = s.client.BLMove(ctx, source, processing, "RIGHT", "LEFT", queuePollInterval).Result()
+		}
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if paused, err := s.queuePaused(ctx); err != nil || paused {
+			_, pushErr := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.LRem(ctx, processing, 1, entry)
+				pipe.RPush(ctx, source, entry)
+				return nil
+			})
+			if pushErr != nil {
+				return "", fmt.Errorf("requeue task %s after pause: %w", entry, pushErr)
+			}
+			if err != nil {
+				return "", err
+			}
+			continue
+		}
+		if err := s.HeartbeatQueueEntry(entry); err != nil {
+			return "", err
+		}
+		return entry, nil
+	}
+}
+
+// HeartbeatQueueEntry records that the worker holding entry is still alive.
+func (s *RedisStore) HeartbeatQueueEntry(entry string) error {
+	return s.client.HSet(context.Background(), heartbeatsKey, entry, time.Now().UnixMilli()).Err()
+}
+
+// AckQueueEntry drops entry from the processing list of mode once its
+// worker is done with it, whatever the outcome.
+func (s *RedisStore) AckQueueEntry(mode, entry string) error {
+	ctx := context.Background()
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LRem(ctx, processingKey(mode), 1, entry)
+		pipe.HDel(ctx, heartbeatsKey, entry)
+		return nil
+	})
+	return err
+}
+
+// StaleQueueEntries lists the entries taken by workers whose last heartbeat
+// is older than staleAfter. An entry without a heartbeat, left by a worker
+// that died right after taking it, is given one now and reported once it
+// has gone stale in turn.
+func (s *RedisStore) StaleQueueEntries(staleAfter time.Duration) ([]QueueClaim, error) {
+	ctx := context.Background()
+	cutoff := time.Now().Add(-staleAfter)
+	var stale []QueueClaim
+	for _, mode := range QueueModes {
+		entries, err := s.client.LRange(ctx, processingKey(string(mode)), 0, -1).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		beats, err := s.client.HMGet(ctx, heartbeatsKey, entries...).Result()
+		if err != nil {
+			return nil, err
+		}
+		for i, entry := range entries {
+			raw, ok := beats[i].(string)
+			if !ok {
+				if err := s.client.HSetNX(ctx, heartbeatsKey, entry, time.Now().UnixMilli()).Err(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			millis, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("heartbeat of queue entry %s: %w", entry, err)
+			}
+			if beat := time.UnixMilli(millis); beat.Before(cutoff) {
+				stale = append(stale, QueueClaim{Mode: string(mode), Entry: entry, HeartbeatAt: beat.UTC()})
+			}
+		}
+	}
+	return stale, nil
+}
+
+// requeueEntryScript moves ARGV[1] from the processing list KEYS[1] back to
+// the head of the queue KEYS[2] and drops its heartbeat from KEYS[3].
+var requeueEntryScript = redis.NewScript(`
+if redis.call('LREM', KEYS[1], 1, ARGV[1]) == 0 then
+  return 0
+end
+redis.call('RPUSH', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+return 1
+`)
+
+// RequeueQueueEntry puts an entry taken by a worker of mode back at the head
+// of its queue. It reports false when the entry was acknowledged meanwhile.
+func (s *RedisStore) RequeueQueueEntry(mode, entry string) (bool, error) {
+	moved, err := requeueEntryScript.Run(context.Background(), s.client,
+		[]string{processingKey(mode), modeQueueKey(mode), heartbeatsKey}, entry).Int()
+	return moved == 1, err
+}
+
+// requeueTaskScript moves ARGV[1] from the processing list KEYS[3] back to
+// the head of the queue KEYS[4], drops its heartbeat from KEYS[5] and writes
+// the task KEYS[1] through writeTaskLua, provided it is still pending or
+// running.
+var requeueTaskScript = redis.NewScript(`
+local status = redis.call('HGET', KEYS[1], 'status')
+if (status ~= 'pending' and status ~= 'running') or redis.call('LREM', KEYS[3], 1, ARGV[1]) == 0 then
+  return 0
+end
+redis.call('RPUSH', KEYS[4], ARGV[1])
+redis.call('HDEL', KEYS[5], ARGV[1])
+local n = 1
+` + writeTaskLua + `
+return 1
+`)
+
+// RequeueTask puts an entry of this namespace taken by a worker of mode back
+// at the head of its queue and writes task, set pending by the caller, in the
+// same step. Nothing changes, and it reports false, when the entry was
+// acknowledged meanwhile or the task is no longer pending or running, so a
+// worker finishing late cannot have its outcome overwritten.
+func (s *RedisStore) RequeueTask(mode, entry string, task *ScanTask) (bool, error) {
+	args, err := taskScriptArgs(task, entry)
+	if err != nil {
+		return false, err
+	}
+	moved, err := requeueTaskScript.Run(context.Background(), s.client,
+		[]string{s.taskKey(task.ID), s.resultsKey(task.ID), processingKey(mode), modeQueueKey(mode), heartbeatsKey}, args...).Int()
+	return moved == 1, err
+}
+
+// RegisterWorkerNode records node in the registry shared by all namespaces
+// for ttl; the node calls it again before then to stay listed.
+func (s *RedisStore) RegisterWorkerNode(node *WorkerNode, ttl time.Duration) error {
+	data, err := json.Marshal(node)
 	if err != nil {
 		return err
 	}
-	return s.client.HSet(context.Background(), s.taskKey(task.ID), data).Err()
+	ctx := context.Background()
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, nodesKey, node.ID)
+		pipe.Set(ctx, nodesKey+":"+node.ID, data, ttl)
+		return nil
+	})
+	return err
 }
 
-// GetTask retrieves a task by ID.
-func (s *RedisStore) GetTask(id string) (*ScanTask, error) {
-	res, err := s.client.HGetAll(context.Background(), s.taskKey(id)).Result()
+// ListWorkerNodes returns the registered worker nodes ordered by ID. Nodes
+// whose registration expired are dropped from the registry.
+func (s *RedisStore) ListWorkerNodes() ([]*WorkerNode, error) {
+	ctx := context.Background()
+	ids, err := s.client.SMembers(ctx, nodesKey).Result()
 	if err != nil {
 		return nil, err
 	}
-	if len(res) == 0 {
-		return nil, ErrTaskNotFound
+	sort.Strings(ids)
+	nodes := make([]*WorkerNode, 0, len(ids))
+	for _, id := range ids {
+		data, err := s.client.Get(ctx, nodesKey+":"+id).Bytes()
+		if errors.Is(err, redis.Nil) {
+			if err := s.client.SRem(ctx, nodesKey, id).Err(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var node WorkerNode
+		if err := json.Unmarshal(data, &node); err != nil {
+			return nil, fmt.Errorf("decode worker node %s: %w", id, err)
+		}
+		nodes = append(nodes, &node)
 	}
-	return deserializeTask(res)
+	return nodes, nil
 }
 
-// UpdateTask updates an existing task in Redis.
-func (s *RedisStore) UpdateTask(task *ScanTask) error {
-	data, err := serializeTask(task)
+// storedAPIKey is the record RedisStore keeps for a managed API key.
+type storedAPIKey struct {
+	APIKey
+	Hash string `json:"hash"`
+}
+
+// CreateAPIKey stores a managed API key, shared by all namespaces, that
+// authenticates callers presenting a key whose SHA-256 is hash.
+func (s *RedisStore) CreateAPIKey(key *APIKey, hash string) error {
+	data, err := json.Marshal(storedAPIKey{APIKey: *key, Hash: hash})
 	if err != nil {
 		return err
 	}
-	return s.client.HSet(context.Background(), s.taskKey(task.ID), data).Err()
+	ctx := context.Background()
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, apiKeysKey, key.ID, data)
+		pipe.HSet(ctx, apiKeyHashesKey, hash, key.ID)
+		return nil
+	})
+	return err
 }
 
-// PushToQueue enqueues a task ID for workers to process.
-func (s *RedisStore) PushToQueue(taskID string) error {
-	return s.client.LPush(context.Background(), "scans:queue", taskID).Err()
+// APIKeyByHash returns the active API key whose SHA-256 is hash.
+func (s *RedisStore) APIKeyByHash(hash string) (*APIKey, error) {
+	id, err := s.client.HGet(context.Background(), apiKeyHashesKey, hash).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	record, err := s.apiKey(id)
+	if err != nil {
+		return nil, err
+	}
+	if record.RevokedAt != nil {
+		return nil, ErrAPIKeyNotFound
+	}
+	return &record.APIKey, nil
+}
+
+func (s *RedisStore) apiKey(id string) (*storedAPIKey, error) {
+	raw, err := s.client.HGet(context.Background(), apiKeysKey, id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record storedAPIKey
+	if err := json.Unmarshal([]byte(raw), &record); err != nil {
+		return nil, fmt.Errorf("decode api key %s: %w", id, err)
+	}
+	return &record, nil
+}
+
+// ListAPIKeys returns every managed API key, revoked ones included, ordered
+// by creation time.
+func (s *RedisStore) ListAPIKeys() ([]*APIKey, error) {
+	entries, err := s.client.HGetAll(context.Background(), apiKeysKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*APIKey, 0, len(entries))
+	for id, raw := range entries {
+		var record storedAPIKey
+		if err := json.Unmarshal([]byte(raw), &record); err != nil {
+			return nil, fmt.Errorf("decode api key %s: %w", id, err)
+		}
+		keys = append(keys, &record.APIKey)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
 }
 
-// PopFromQueue blocks until a task ID is available.
-func (s *RedisStore) PopFromQueue() (string, error) {
-	res, err := s.client.BRPop(context.Background(), 0, "scans:queue").Result()
+// RevokeAPIKey stops the API key id from authenticating and returns its
+// record. The record is kept; revoking a revoked key changes nothing.
+func (s *RedisStore) RevokeAPIKey(id string) (*APIKey, error) {
+	record, err := s.apiKey(id)
+	if err != nil {
+		return nil, err
+	}
+	if record.RevokedAt != nil {
+		return &record.APIKey, nil
+	}
+	now := time.Now().UTC()
+	record.RevokedAt = &now
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, apiKeysKey, id, data)
+		pipe.HDel(ctx, apiKeyHashesKey, record.Hash)
+		return nil
+	})
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	return &record.APIKey, nil
+}
+
+func (s *RedisStore) queuePaused(ctx context.Context) (bool, error) {
+	n, err := s.client.Exists(ctx, queuePausedKey).Result()
+	return n > 0, err
+}
+
+// PauseQueue stops workers from taking new tasks. Submissions keep being queued.
+func (s *RedisStore) PauseQueue(by string) error {
+	return s.client.HSet(context.Background(), queuePausedKey, map[string]interface{}{
+		"paused_at": time.Now().UTC().Format(time.RFC3339Nano),
+		"paused_by": by,
+	}).Err()
+}
+
+// ResumeQueue lets workers take tasks from the queue again.
+func (s *RedisStore) ResumeQueue() error {
+	return s.client.Del(context.Background(), queuePausedKey).Err()
+}
+
+// QueueStatus reports whether the queue is paused and how many tasks are
+// waiting, in total and per mode. Pool sizes are left for the caller to fill in.
+func (s *RedisStore) QueueStatus() (*QueueStatus, error) {
+	ctx := context.Background()
+	pipe := s.client.Pipeline()
+	pause := pipe.HGetAll(ctx, queuePausedKey)
+	depths := make([]*redis.IntCmd, 0, len(QueueModes))
+	for _, mode := range QueueModes {
+		depths = append(depths, pipe.LLen(ctx, modeQueueKey(string(mode))))
+	}
+	legacy := pipe.LLen(ctx, queueKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	status := &QueueStatus{Depth: legacy.Val()}
+	for i, mode := range QueueModes {
+		status.Pools = append(status.Pools, QueuePool{Mode: string(mode), Depth: depths[i].Val()})
+		status.Depth += depths[i].Val()
+	}
+	if data := pause.Val(); len(data) > 0 {
+		status.Paused = true
+		status.PausedBy = data["paused_by"]
+		if raw := data["paused_at"]; raw != "" {
+			t, err := time.Parse(time.RFC3339Nano, raw)
+			if err != nil {
+				return nil, err
+			}
+			status.PausedAt = &t
+		}
+	}
+	return status, nil
+}
+
+// QueuedTaskIDs returns the IDs of this namespace's tasks currently waiting in any queue.
+func (s *RedisStore) QueuedTaskIDs() ([]string, error) {
+	ctx := context.Background()
+	var ids []string
+	for _, key := range queueKeys() {
+		entries, err := s.client.LRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if ns, id := SplitQueueEntry(entry); ns == s.namespace {
+				ids = append(ids, id)
+			}
+		}
 	}
-	if len(res) != 2 {
-		return "", errors.New("unexpected response size from BRPOP")
+	return ids, nil
+}
+
+// RemoveFromQueue drops every queue entry referencing taskID in this namespace.
+func (s *RedisStore) RemoveFromQueue(taskID string) error {
+	ctx := context.Background()
+	pipe := s.client.Pipeline()
+	for _, key := range queueKeys() {
+		pipe.LRem(ctx, key, 0, s.queueEntry(taskID))
 	}
-	return res[1], nil
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
+// serializeTask returns the fields of task but its results, which both stores
+// keep one per entry so they can be read a page at a time.
 func serializeTask(task *ScanTask) (map[string]interface{}, error) {
 	hosts, err := json.Marshal(task.Hosts)
 	if err != nil {
 		return nil, err
 	}
 
-	var resultsData string
-	if task.Results != nil {
-		encoded, err := json.Marshal(task.Results)
+	warnings, err := json.Marshal(task.Warnings)
+	if err != nil {
+		return nil, err
+	}
+
+	checks, err := json.Marshal(task.Checks)
+	if err != nil {
+		return nil, err
+	}
+
+	dnsServers, err := json.Marshal(task.DNSServers)
+	if err != nil {
+		return nil, err
+	}
+
+	httpPaths, err := json.Marshal(task.HTTPPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := json.Marshal(task.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := json.Marshal(task.Targets)
+	if err != nil {
+		return nil, err
+	}
+
+	shards, err := json.Marshal(task.Shards)
+	if err != nil {
+		return nil, err
+	}
+
+	var hostSummariesData string
+	if task.HostSummaries != nil {
+		encoded, err := json.Marshal(task.HostSummaries)
+		if err != nil {
+			return nil, err
+		}
+		hostSummariesData = string(encoded)
+	}
+
+	var changesData string
+	if task.Changes != nil {
+		encoded, err := json.Marshal(task.Changes)
 		if err != nil {
 			return nil, err
 		}
-		resultsData = string(encoded)
+		changesData = string(encoded)
+	}
+
+	var callbackData string
+	if task.Callback != nil {
+		encoded, err := json.Marshal(task.Callback)
+		if err != nil {
+			return nil, err
+		}
+		callbackData = string(encoded)
 	}
 
 	createdAt := task.CreatedAt.Format(time.RFC3339Nano)
@@ -106,17 +2023,71 @@ func serializeTask(task *ScanTask) (map[string]interface{}, error) {
 	if task.CompletedAt != nil {
 		completedAt = task.CompletedAt.Format(time.RFC3339Nano)
 	}
+	resultsPurgedAt := ""
+	if task.ResultsPurgedAt != nil {
+		resultsPurgedAt = task.ResultsPurgedAt.Format(time.RFC3339Nano)
+	}
+
+	intensity := ""
+	if task.VersionIntensity != nil {
+		intensity = strconv.Itoa(*task.VersionIntensity)
+	}
+	retries := ""
+	if task.MaxRetries != nil {
+		retries = strconv.Itoa(*task.MaxRetries)
+	}
 
 	return map[string]interface{}{
-		"id":           task.ID,
-		"status":       task.Status,
-		"hosts":        string(hosts),
-		"ports":        task.Ports,
-		"mode":         task.Mode,
-		"results":      resultsData,
-		"created_at":   createdAt,
-		"completed_at": completedAt,
-		"error":        task.Error,
+		"id":               task.ID,
+		"status":           task.Status,
+		"hosts":            string(hosts),
+		"targets":          string(targets),
+		"ports":            task.Ports,
+		"mode":             task.Mode,
+		"host_rate":        strconv.FormatFloat(task.HostRate, 'f', -1, 64),
+		"max_rate":         strconv.FormatFloat(task.MaxRate, 'f', -1, 64),
+		"timing":           task.Timing,
+		"max_parallelism":  strconv.Itoa(task.MaxParallelism),
+		"max_duration":     strconv.Itoa(task.MaxDuration),
+		"min_hostgroup":    strconv.Itoa(task.MinHostGroup),
+		"max_hostgroup":    strconv.Itoa(task.MaxHostGroup),
+		"max_retries":      retries,
+		"all_addresses":    strconv.FormatBool(task.AllAddresses),
+		"prefer":           task.Prefer,
+		"ip_version":       strconv.Itoa(task.IPVersion),
+		"intensity":        intensity,
+		"no_fallback":      strconv.FormatBool(task.NoFallback),
+		"checks":           string(checks),
+		"dns_servers":      string(dnsServers),
+		"http_paths":       string(httpPaths),
+		"tags":             string(tags),
+		"rdap":             strconv.FormatBool(task.RDAP),
+		"resolve_ptr":      strconv.FormatBool(task.ResolvePTR),
+		"detect_tarpits":   strconv.FormatBool(task.DetectTarpits),
+		"discovery":        strconv.FormatBool(task.Discovery),
+		"tarpit_downgrade": strconv.FormatBool(task.TarpitDowngrade),
+		"warnings":         string(warnings),
+		"host_summaries":   hostSummariesData,
+		"baseline":         task.Baseline,
+		"owner":            task.Owner,
+		"monitor":          task.Monitor,
+		"schedule":         task.Schedule,
+		"template":         task.Template,
+		"reuse_within":     task.ReuseWithin,
+		"retry_backoff":    task.RetryBackoff,
+		"parent":           task.Parent,
+		"shards":           string(shards),
+		"shards_done":      strconv.Itoa(task.ShardsDone),
+		"progress":         strconv.FormatFloat(task.Progress, 'f', -1, 64),
+		"eta_seconds":      strconv.Itoa(task.ETASeconds),
+		"changes":          changesData,
+		"callback_url":     task.CallbackURL,
+		"traceparent":      task.TraceParent,
+		"callback":         callbackData,
+		"created_at":       createdAt,
+		"completed_at":     completedAt,
+		"purged_at":        resultsPurgedAt,
+		"error":            task.Error,
 	}, nil
 }
 
@@ -128,6 +2099,7 @@ func deserializeTask(data map[string]string) (*ScanTask, error) {
 		}
 	}
 
+	// Results are only among the fields of tasks written by earlier versions
 	var results []scanner.ScanResult
 	if raw, ok := data["results"]; ok && raw != "" {
 		if err := json.Unmarshal([]byte(raw), &results); err != nil {
@@ -153,16 +2125,232 @@ func deserializeTask(data map[string]string) (*ScanTask, error) {
 		completedAt = &t
 	}
 
+	var resultsPurgedAt *time.Time
+	if raw, ok := data["purged_at"]; ok && raw != "" {
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return nil, err
+		}
+		resultsPurgedAt = &t
+	}
+
+	var hostRate float64
+	if raw, ok := data["host_rate"]; ok && raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, err
+		}
+		hostRate = v
+	}
+
+	var maxRate float64
+	if raw, ok := data["max_rate"]; ok && raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, err
+		}
+		maxRate = v
+	}
+
+	var maxParallelism int
+	if raw, ok := data["max_parallelism"]; ok && raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, err
+		}
+		maxParallelism = v
+	}
+
+	var limits [3]int
+	for i, field := range []string{"min_hostgroup", "max_hostgroup", "max_duration"} {
+		if raw, ok := data[field]; ok && raw != "" {
+			v, err := strconv.Atoi(raw)
+			if err != nil {
+				return nil, err
+			}
+			limits[i] = v
+		}
+	}
+
+	var ipVersion int
+	if raw, ok := data["ip_version"]; ok && raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, err
+		}
+		ipVersion = v
+	}
+
+	var maxRetries *int
+	if raw, ok := data["max_retries"]; ok && raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, err
+		}
+		maxRetries = &v
+	}
+
+	var intensity *int
+	if raw, ok := data["intensity"]; ok && raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, err
+		}
+		intensity = &v
+	}
+
+	var warnings []string
+	if raw, ok := data["warnings"]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &warnings); err != nil {
+			return nil, err
+		}
+	}
+
+	var checks []string
+	if raw, ok := data["checks"]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &checks); err != nil {
+			return nil, err
+		}
+	}
+
+	var dnsServers []string
+	if raw, ok := data["dns_servers"]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &dnsServers); err != nil {
+			return nil, err
+		}
+	}
+
+	var httpPaths []string
+	if raw, ok := data["http_paths"]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &httpPaths); err != nil {
+			return nil, err
+		}
+	}
+
+	var tags []string
+	if raw, ok := data["tags"]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &tags); err != nil {
+			return nil, err
+		}
+	}
+
+	var targets []ScanTarget
+	if raw, ok := data["targets"]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &targets); err != nil {
+			return nil, err
+		}
+	}
+
+	var shards []string
+	if raw, ok := data["shards"]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &shards); err != nil {
+			return nil, err
+		}
+	}
+
+	var shardsDone int
+	if raw, ok := data["shards_done"]; ok && raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, err
+		}
+		shardsDone = v
+	}
+
+	var progress float64
+	if raw, ok := data["progress"]; ok && raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, err
+		}
+		progress = v
+	}
+
+	var etaSeconds int
+	if raw, ok := data["eta_seconds"]; ok && raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, err
+		}
+		etaSeconds = v
+	}
+
+	var hostSummaries []scanner.HostSummary
+	if raw, ok := data["host_summaries"]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &hostSummaries); err != nil {
+			return nil, err
+		}
+	}
+
+	var changes []scanner.ResultChange
+	if raw, ok := data["changes"]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &changes); err != nil {
+			return nil, err
+		}
+	}
+
+	var callback *CallbackDelivery
+	if raw, ok := data["callback"]; ok && raw != "" {
+		callback = &CallbackDelivery{}
+		if err := json.Unmarshal([]byte(raw), callback); err != nil {
+			return nil, err
+		}
+	}
+
+	allAddresses := data["all_addresses"] == "true"
+
 	task := &ScanTask{
-		ID:          data["id"],
-		Status:      data["status"],
-		Hosts:       hosts,
-		Ports:       data["ports"],
-		Mode:        data["mode"],
-		Results:     results,
-		CreatedAt:   createdAt,
-		CompletedAt: completedAt,
-		Error:       data["error"],
+		ID:               data["id"],
+		Status:           data["status"],
+		Hosts:            hosts,
+		Targets:          targets,
+		Ports:            data["ports"],
+		Mode:             data["mode"],
+		HostRate:         hostRate,
+		MaxRate:          maxRate,
+		Timing:           data["timing"],
+		MaxParallelism:   maxParallelism,
+		MinHostGroup:     limits[0],
+		MaxHostGroup:     limits[1],
+		MaxDuration:      limits[2],
+		MaxRetries:       maxRetries,
+		RetryBackoff:     data["retry_backoff"],
+		AllAddresses:     allAddresses,
+		Prefer:           data["prefer"],
+		IPVersion:        ipVersion,
+		VersionIntensity: intensity,
+		NoFallback:       data["no_fallback"] == "true",
+		Checks:           checks,
+		DNSServers:       dnsServers,
+		HTTPPaths:        httpPaths,
+		Tags:             tags,
+		RDAP:             data["rdap"] == "true",
+		ResolvePTR:       data["resolve_ptr"] == "true",
+		DetectTarpits:    data["detect_tarpits"] == "true",
+		Discovery:        data["discovery"] == "true",
+		TarpitDowngrade:  data["tarpit_downgrade"] == "true",
+		HostSummaries:    hostSummaries,
+		Baseline:         data["baseline"],
+		Owner:            data["owner"],
+		Monitor:          data["monitor"],
+		Schedule:         data["schedule"],
+		Template:         data["template"],
+		ReuseWithin:      data["reuse_within"],
+		Parent:           data["parent"],
+		Shards:           shards,
+		ShardsDone:       shardsDone,
+		Progress:         progress,
+		ETASeconds:       etaSeconds,
+		Changes:          changes,
+		CallbackURL:      data["callback_url"],
+		TraceParent:      data["traceparent"],
+		Callback:         callback,
+		Warnings:         warnings,
+		Results:          results,
+		CreatedAt:        createdAt,
+		CompletedAt:      completedAt,
+		ResultsPurgedAt:  resultsPurgedAt,
+		Error:            data["error"],
 	}
 
 	return task, nil
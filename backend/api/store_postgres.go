@@ -0,0 +1,1652 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Viktor25104/cortex/backend/scanner"
+)
+
+// postgresDriver is the database/sql driver PostgresStore opens, registered
+// by store_postgres_driver.go.
+const postgresDriver = "pgx"
+
+// postgresMigrations holds the schema changes of PostgresStore in order. A
+// migration is never edited once released; changes go into a new entry.
+var postgresMigrations = []string{
+	// 1: tasks, the queue and the per-namespace records
+	`CREATE TABLE tasks (
+		namespace          text        NOT NULL,
+		id                 text        NOT NULL,
+		created_at         timestamptz NOT NULL,
+		status             text        NOT NULL,
+		mode               text        NOT NULL DEFAULT '',
+		parent             text        NOT NULL DEFAULT '',
+		fields             jsonb       NOT NULL,
+		partial_expires_at timestamptz,
+		PRIMARY KEY (namespace, id)
+	);
+	CREATE INDEX tasks_created_idx ON tasks (namespace, created_at);
+	CREATE INDEX tasks_status_idx ON tasks (namespace, status, created_at);
+	CREATE INDEX tasks_partial_expiry_idx ON tasks (partial_expires_at) WHERE partial_expires_at IS NOT NULL;
+
+	CREATE TABLE partial_results (
+		namespace text      NOT NULL,
+		task_id   text      NOT NULL,
+		seq       bigserial,
+		result    text      NOT NULL,
+		PRIMARY KEY (namespace, task_id, seq)
+	);
+
+	CREATE TABLE scan_queue (
+		seq   bigserial PRIMARY KEY,
+		mode  text      NOT NULL,
+		entry text      NOT NULL
+	);
+	CREATE INDEX scan_queue_mode_idx ON scan_queue (mode, seq);
+	CREATE TABLE queue_pause (
+		singleton boolean     PRIMARY KEY DEFAULT true CHECK (singleton),
+		paused_at timestamptz NOT NULL,
+		paused_by text        NOT NULL
+	);
+	CREATE TABLE run_slots (
+		owner text NOT NULL,
+		entry text NOT NULL,
+		PRIMARY KEY (owner, entry)
+	);
+	CREATE TABLE held_tasks (
+		seq   bigserial PRIMARY KEY,
+		owner text      NOT NULL,
+		entry text      NOT NULL
+	);
+
+	CREATE TABLE monitors (
+		namespace  text        NOT NULL,
+		id         text        NOT NULL,
+		created_at timestamptz NOT NULL,
+		data       text        NOT NULL,
+		PRIMARY KEY (namespace, id)
+	);
+	CREATE TABLE inventory (
+		namespace text NOT NULL,
+		host      text NOT NULL,
+		data      text NOT NULL,
+		PRIMARY KEY (namespace, host)
+	);
+	CREATE TABLE recent_results (
+		namespace   text        NOT NULL,
+		protocol    text        NOT NULL,
+		host        text        NOT NULL,
+		port_key    text        NOT NULL,
+		observed_at timestamptz NOT NULL,
+		expires_at  timestamptz NOT NULL,
+		result      text        NOT NULL,
+		PRIMARY KEY (namespace, protocol, host, port_key)
+	);
+	CREATE INDEX recent_results_expiry_idx ON recent_results (expires_at);`,
+
+	// 2: entries taken by workers, kept until acknowledged
+	`CREATE TABLE queue_claims (
+		seq          bigint      PRIMARY KEY,
+		mode         text        NOT NULL,
+		entry        text        NOT NULL,
+		heartbeat_at timestamptz NOT NULL
+	);
+	CREATE INDEX queue_claims_entry_idx ON queue_claims (mode, entry);`,
+
+	// 3: the worker node registry
+	`CREATE TABLE worker_nodes (
+		id         text        PRIMARY KEY,
+		data       text        NOT NULL,
+		expires_at timestamptz NOT NULL
+	);`,
+
+	// 4: managed API keys
+	`CREATE TABLE api_keys (
+		id         text        PRIMARY KEY,
+		hash       text        NOT NULL UNIQUE,
+		created_at timestamptz NOT NULL,
+		revoked    boolean     NOT NULL DEFAULT false,
+		data       text        NOT NULL
+	);`,
+
+	// 5: recurring scan schedules and the scheduler's leader lease
+	`CREATE TABLE schedules (
+		namespace  text        NOT NULL,
+		id         text        NOT NULL,
+		created_at timestamptz NOT NULL,
+		data       text        NOT NULL,
+		PRIMARY KEY (namespace, id)
+	);
+	CREATE TABLE leases (
+		name       text        PRIMARY KEY,
+		holder     text        NOT NULL,
+		expires_at timestamptz NOT NULL
+	);`,
+
+	// 6: saved scan templates
+	`CREATE TABLE scan_templates (
+		namespace  text        NOT NULL,
+		id         text        NOT NULL,
+		created_at timestamptz NOT NULL,
+		data       text        NOT NULL,
+		PRIMARY KEY (namespace, id)
+	);`,
+
+	// 7: task results, one row each instead of an array among the fields
+	`CREATE TABLE task_results (
+		namespace text    NOT NULL,
+		task_id   text    NOT NULL,
+		position  integer NOT NULL,
+		result    text    NOT NULL,
+		PRIMARY KEY (namespace, task_id, position)
+	);
+	INSERT INTO task_results (namespace, task_id, position, result)
+		SELECT t.namespace, t.id, r.position - 1, r.result::text
+		FROM tasks t, jsonb_array_elements(CASE WHEN t.fields->>'results' LIKE '[%'
+			THEN (t.fields->>'results')::jsonb ELSE '[]'::jsonb END) WITH ORDINALITY AS r (result, position);
+	UPDATE tasks SET fields = fields - 'results' WHERE fields->'results' IS NOT NULL;`,
+	// 8: task callback attempts waiting to be made
+	`CREATE TABLE callbacks (
+		namespace text        NOT NULL,
+		task_id   text        NOT NULL,
+		due_at    timestamptz NOT NULL,
+		PRIMARY KEY (namespace, task_id)
+	);
+	CREATE INDEX callbacks_due ON callbacks (due_at);`,
+}
+
+// recentResultsBatch caps the hosts RecentResults asks for per query, well
+// below PostgreSQL's limit on bind parameters.
+const recentResultsBatch = 1000
+
+// PostgresStore implements TaskStore on PostgreSQL, for deployments that keep
+// their scan history durably and query it with SQL. Tasks are rows holding
+// the same fields RedisStore keeps in a hash, with status, mode, parent and
+// creation time in columns of their own for filtering. Like RedisStore, each
+// value is a view of one tenant namespace, and the queue, run slots and queue
+// pause are shared by all namespaces.
+type PostgresStore struct {
+	db        *sql.DB
+	namespace string
+}
+
+// OpenPostgresStore connects to the PostgreSQL database at dsn and applies
+// pending schema migrations.
+func OpenPostgresStore(dsn string) (*PostgresStore, error) {
+	db, err := sql.Open(postgresDriver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	store := NewPostgresStore(db)
+	if err := store.Migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// NewPostgresStore constructs a PostgreSQL-backed task store for the default
+// namespace. The schema must be current, see Migrate.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db, namespace: DefaultNamespace}
+}
+
+// Migrate applies the migrations the database has not seen yet, recording
+// each in schema_migrations. Servers starting together take turns.
+func (s *PostgresStore) Migrate() error {
+	ctx := context.Background()
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    integer     PRIMARY KEY,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`); err != nil {
+		return err
+	}
+	for i, migration := range postgresMigrations {
+		version := i + 1
+		err := s.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, `LOCK TABLE schema_migrations IN EXCLUSIVE MODE`); err != nil {
+				return err
+			}
+			var applied bool
+			if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, version).Scan(&applied); err != nil {
+				return err
+			}
+			if applied {
+				return nil
+			}
+			if _, err := tx.ExecContext(ctx, migration); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("schema migration %d: %w", version, err)
+		}
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction, committing when it succeeds.
+func (s *PostgresStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Namespace returns a view of the store scoped to the given tenant namespace.
+func (s *PostgresStore) Namespace(name string) TaskStore {
+	if name == "" {
+		name = DefaultNamespace
+	}
+	return &PostgresStore{db: s.db, namespace: name}
+}
+
+// Namespaces lists the default namespace followed by every tenant that has
+// stored tasks, monitors, schedules, templates or inventory.
+func (s *PostgresStore) Namespaces() ([]string, error) {
+	rows, err := s.db.QueryContext(context.Background(), `
+		SELECT namespace FROM tasks WHERE namespace <> $1
+		UNION SELECT namespace FROM monitors WHERE namespace <> $1
+		UNION SELECT namespace FROM schedules WHERE namespace <> $1
+		UNION SELECT namespace FROM scan_templates WHERE namespace <> $1
+		UNION SELECT namespace FROM inventory WHERE namespace <> $1`, DefaultNamespace)
+	if err != nil {
+		return nil, err
+	}
+	names, err := scanStrings(rows)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return append([]string{DefaultNamespace}, names...), nil
+}
+
+// queueEntry qualifies a task ID with the store's namespace for the shared
+// queue, in the form RedisStore uses.
+func (s *PostgresStore) queueEntry(taskID string) string {
+	if s.namespace == DefaultNamespace {
+		return taskID
+	}
+	return s.namespace + "/" + taskID
+}
+
+// encodeTask returns the fields of task as a JSON object.
+func encodeTask(task *ScanTask) ([]byte, error) {
+	data, err := serializeTask(task)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(data)
+}
+
+// decodeTask is the inverse of encodeTask.
+func decodeTask(raw []byte) (*ScanTask, error) {
+	var data map[string]string
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	return deserializeTask(data)
+}
+
+// CreateTask persists a new scan task.
+func (s *PostgresStore) CreateTask(task *ScanTask) error {
+	fields, err := encodeTask(task)
+	if err != nil {
+		return err
+	}
+	results, err := encodeResults(task.Results)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO tasks (namespace, id, created_at, status, mode, parent, fields)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			s.namespace, task.ID, task.CreatedAt, task.Status, task.Mode, task.Parent, string(fields)); err != nil {
+			return err
+		}
+		return s.writeResults(ctx, tx, task.ID, results)
+	})
+}
+
+// GetTask retrieves a task by ID.
+func (s *PostgresStore) GetTask(id string) (*ScanTask, error) {
+	var fields []byte
+	err := s.db.QueryRowContext(context.Background(), `SELECT fields FROM tasks WHERE namespace = $1 AND id = $2`,
+		s.namespace, id).Scan(&fields)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	task, err := decodeTask(fields)
+	if err != nil {
+		return nil, err
+	}
+	return task, s.loadResults(task)
+}
+
+// loadResults reads the stored results of task into it.
+func (s *PostgresStore) loadResults(task *ScanTask) error {
+	rows, err := s.db.QueryContext(context.Background(), `
+		SELECT result FROM task_results WHERE namespace = $1 AND task_id = $2
+		ORDER BY position`, s.namespace, task.ID)
+	if err != nil {
+		return err
+	}
+	values, err := scanStrings(rows)
+	if err != nil || len(values) == 0 {
+		return err
+	}
+	if task.Results, err = decodeResults(values); err != nil {
+		return fmt.Errorf("results of task %s: %w", task.ID, err)
+	}
+	return nil
+}
+
+// writeResults replaces the stored results of task id with results, as
+// returned by encodeResults.
+func (s *PostgresStore) writeResults(ctx context.Context, tx *sql.Tx, id string, results []interface{}) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM task_results WHERE namespace = $1 AND task_id = $2`, s.namespace, id); err != nil {
+		return err
+	}
+	for start := 0; start < len(results); start += resultsBatch {
+		batch := results[start:min(start+resultsBatch, len(results))]
+		args := []interface{}{s.namespace, id}
+		values := make([]string, len(batch))
+		for i, result := range batch {
+			args = append(args, start+i, result)
+			values[i] = fmt.Sprintf("($1, $2, $%d, $%d)", len(args)-1, len(args))
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO task_results (namespace, task_id, position, result) VALUES `+strings.Join(values, ", "), args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TaskResults opens the stored results of a task for reading one at a time,
+// querying them a page at a time as the reader advances.
+func (s *PostgresStore) TaskResults(id string) (*ResultReader, error) {
+	var status string
+	err := s.db.QueryRowContext(context.Background(), `SELECT status FROM tasks WHERE namespace = $1 AND id = $2`,
+		s.namespace, id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ResultReader{Status: status, page: func(start int) ([]string, error) {
+		rows, err := s.db.QueryContext(context.Background(), `
+			SELECT result FROM task_results WHERE namespace = $1 AND task_id = $2 AND position >= $3
+			ORDER BY position LIMIT $4`, s.namespace, id, start, resultsPageSize)
+		if err != nil {
+			return nil, err
+		}
+		return scanStrings(rows)
+	}}, nil
+}
+
+// AppendPartialResults adds results found by the worker running task id to
+// its partial results, which readers can fetch before the task finishes.
+// Partial results of tasks whose last append is older than
+// partialResultsTTL are deleted on the way.
+func (s *PostgresStore) AppendPartialResults(id string, results []scanner.ScanResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	args := []interface{}{s.namespace, id}
+	values := make([]string, len(results))
+	for i, result := range results {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		args = append(args, string(encoded))
+		values[i] = fmt.Sprintf("($1, $2, $%d)", len(args))
+	}
+
+	ctx := context.Background()
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+			WITH expired AS (
+				UPDATE tasks SET partial_expires_at = NULL
+				WHERE partial_expires_at < now() RETURNING namespace, id
+			)
+			DELETE FROM partial_results p USING expired e
+			WHERE p.namespace = e.namespace AND p.task_id = e.id`); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO partial_results (namespace, task_id, result) VALUES `+strings.Join(values, ", "), args...); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE tasks SET partial_expires_at = $3 WHERE namespace = $1 AND id = $2`,
+			s.namespace, id, time.Now().Add(partialResultsTTL))
+		return err
+	})
+}
+
+// PartialResults returns the partial results of task id from position start
+// on, in the order they were found.
+func (s *PostgresStore) PartialResults(id string, start int) ([]scanner.ScanResult, error) {
+	rows, err := s.db.QueryContext(context.Background(), `
+		SELECT result FROM partial_results WHERE namespace = $1 AND task_id = $2
+		ORDER BY seq OFFSET $3`, s.namespace, id, start)
+	if err != nil {
+		return nil, err
+	}
+	values, err := scanStrings(rows)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]scanner.ScanResult, len(values))
+	for i, value := range values {
+		if err := json.Unmarshal([]byte(value), &results[i]); err != nil {
+			return nil, fmt.Errorf("partial result %d of task %s: %w", start+i, id, err)
+		}
+	}
+	return results, nil
+}
+
+// DeletePartialResults erases the partial results of task id.
+func (s *PostgresStore) DeletePartialResults(id string) error {
+	_, err := s.db.ExecContext(context.Background(), `DELETE FROM partial_results WHERE namespace = $1 AND task_id = $2`, s.namespace, id)
+	return err
+}
+
+// UpdateTask writes task, merging its fields into the stored ones like
+// RedisStore does, and creates it when it does not exist.
+func (s *PostgresStore) UpdateTask(task *ScanTask) error {
+	fields, err := encodeTask(task)
+	if err != nil {
+		return err
+	}
+	results, err := encodeResults(task.Results)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO tasks (namespace, id, created_at, status, mode, parent, fields)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (namespace, id) DO UPDATE SET
+				status = EXCLUDED.status, mode = EXCLUDED.mode, parent = EXCLUDED.parent,
+				fields = tasks.fields || EXCLUDED.fields`,
+			s.namespace, task.ID, task.CreatedAt, task.Status, task.Mode, task.Parent, string(fields)); err != nil {
+			return err
+		}
+		return s.writeResults(ctx, tx, task.ID, results)
+	})
+}
+
+// updateTaskSQL writes the fields of an existing task.
+const updateTaskSQL = `
+	UPDATE tasks SET status = $3, mode = $4, parent = $5, fields = fields || $6::jsonb
+	WHERE namespace = $1 AND id = $2`
+
+// UpdateTaskIf writes task like UpdateTask while its stored status is from,
+// like RedisStore.UpdateTaskIf.
+func (s *PostgresStore) UpdateTaskIf(task *ScanTask, from string) (string, bool, error) {
+	fields, err := encodeTask(task)
+	if err != nil {
+		return "", false, err
+	}
+	results, err := encodeResults(task.Results)
+	if err != nil {
+		return "", false, err
+	}
+	ctx := context.Background()
+	var status string
+	updated := false
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `SELECT status FROM tasks WHERE namespace = $1 AND id = $2 FOR UPDATE`,
+			s.namespace, task.ID).Scan(&status)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTaskNotFound
+		}
+		if err != nil || status != from {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, updateTaskSQL,
+			s.namespace, task.ID, task.Status, task.Mode, task.Parent, string(fields)); err != nil {
+			return err
+		}
+		if err := s.writeResults(ctx, tx, task.ID, results); err != nil {
+			return err
+		}
+		status, updated = task.Status, true
+		return nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return status, updated, nil
+}
+
+// setStatusSQL sets the status column and field of a task.
+const setStatusSQL = `UPDATE tasks SET status = $3, fields = fields || jsonb_build_object('status', $3::text) WHERE namespace = $1 AND id = $2`
+
+// MarkTaskRunning sets a pending task to running without rewriting the rest
+// of it, so every shard can flag its parent as the first one starts.
+func (s *PostgresStore) MarkTaskRunning(id string) error {
+	_, err := s.db.ExecContext(context.Background(), setStatusSQL+` AND status = 'pending'`, s.namespace, id, "running")
+	return err
+}
+
+// TaskStatus returns the status of a task without loading the rest of it.
+func (s *PostgresStore) TaskStatus(id string) (string, error) {
+	var status string
+	err := s.db.QueryRowContext(context.Background(), `SELECT status FROM tasks WHERE namespace = $1 AND id = $2`,
+		s.namespace, id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrTaskNotFound
+	}
+	return status, err
+}
+
+// PauseTask moves a pending or held task to paused and a running one to
+// pausing, like RedisStore.PauseTask.
+func (s *PostgresStore) PauseTask(id string) (string, bool, error) {
+	return s.transitionStatus(id, map[string]string{"pending": "paused", "held": "paused", "running": "pausing"})
+}
+
+// ResumeTask moves a paused task back to pending and a pausing one back to
+// running, like RedisStore.ResumeTask.
+func (s *PostgresStore) ResumeTask(id string) (string, bool, error) {
+	return s.transitionStatus(id, map[string]string{"paused": "pending", "pausing": "running"})
+}
+
+// CancelTask moves a task that no worker runs to cancelled and a running one
+// to cancelling, like RedisStore.CancelTask.
+func (s *PostgresStore) CancelTask(id string) (string, bool, error) {
+	return s.transitionStatus(id, map[string]string{
+		"pending": "cancelled", "held": "cancelled", "paused": "cancelled",
+		"running": "cancelling", "pausing": "cancelling",
+	})
+}
+
+// transitionStatus moves task id to the status next maps its current status
+// to. It returns the resulting status and whether it changed, or the current
+// status and false when next has no entry for it.
+func (s *PostgresStore) transitionStatus(id string, next map[string]string) (string, bool, error) {
+	ctx := context.Background()
+	var status string
+	changed := false
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `SELECT status FROM tasks WHERE namespace = $1 AND id = $2 FOR UPDATE`,
+			s.namespace, id).Scan(&status)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTaskNotFound
+		}
+		if err != nil {
+			return err
+		}
+		to, ok := next[status]
+		if !ok {
+			return nil
+		}
+		if _, err := tx.ExecContext(ctx, setStatusSQL, s.namespace, id, to); err != nil {
+			return err
+		}
+		status, changed = to, true
+		return nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return status, changed, nil
+}
+
+// UpdateProgress records how far the worker running task id got without
+// rewriting the rest of the task. Progress of a task no worker runs any
+// more is dropped, so a late write cannot follow its final state.
+func (s *PostgresStore) UpdateProgress(id string, progress float64, etaSeconds int) error {
+	_, err := s.db.ExecContext(context.Background(), `
+		UPDATE tasks SET fields = fields || jsonb_build_object('progress', $3::text, 'eta_seconds', $4::text)
+		WHERE namespace = $1 AND id = $2 AND status IN ('running', 'pausing', 'cancelling')`,
+		s.namespace, id, strconv.FormatFloat(progress, 'f', -1, 64), strconv.Itoa(etaSeconds))
+	return err
+}
+
+// UpdateCallback records the delivery state of the task's callback without
+// rewriting the rest of the task. It returns ErrTaskNotFound once the task
+// was deleted.
+func (s *PostgresStore) UpdateCallback(id string, delivery *CallbackDelivery) error {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	result, err := s.db.ExecContext(context.Background(), `
+		UPDATE tasks SET fields = fields || jsonb_build_object('callback', $3::text)
+		WHERE namespace = $1 AND id = $2`,
+		s.namespace, id, string(data))
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// FinishShard atomically records shard as finished on parent task id and
+// returns how many distinct shards have finished. Recording a shard again,
+// as a redelivered queue message does, leaves the count unchanged.
+func (s *PostgresStore) FinishShard(id, shard string) (int, error) {
+	ctx := context.Background()
+	var done int
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var finished string
+		err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(fields->>'shards_finished', '') FROM tasks
+			WHERE namespace = $1 AND id = $2 FOR UPDATE`, s.namespace, id).Scan(&finished)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTaskNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var shards []string
+		if finished != "" {
+			shards = strings.Split(finished, ",")
+		}
+		if !slices.Contains(shards, shard) {
+			shards = append(shards, shard)
+		}
+		done = len(shards)
+		_, err = tx.ExecContext(ctx, `
+			UPDATE tasks SET fields = fields || jsonb_build_object('shards_finished', $3::text, 'shards_done', $4::text)
+			WHERE namespace = $1 AND id = $2`,
+			s.namespace, id, strings.Join(shards, ","), strconv.Itoa(done))
+		return err
+	})
+	return done, err
+}
+
+// QueueCallback schedules the next callback attempt of task id for at,
+// replacing any attempt queued before.
+func (s *PostgresStore) QueueCallback(id string, at time.Time) error {
+	_, err := s.db.ExecContext(context.Background(), `
+		INSERT INTO callbacks (namespace, task_id, due_at) VALUES ($1, $2, $3)
+		ON CONFLICT (namespace, task_id) DO UPDATE SET due_at = EXCLUDED.due_at`,
+		s.namespace, id, at.UTC())
+	return err
+}
+
+// DueCallbacks returns the IDs of the tasks whose callback attempt is due by
+// now, longest due first.
+func (s *PostgresStore) DueCallbacks(now time.Time) ([]string, error) {
+	rows, err := s.db.QueryContext(context.Background(), `
+		SELECT task_id FROM callbacks WHERE namespace = $1 AND due_at <= $2 ORDER BY due_at, task_id`,
+		s.namespace, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return scanStrings(rows)
+}
+
+// RemoveCallback drops the queued callback attempt of task id, if any.
+func (s *PostgresStore) RemoveCallback(id string) error {
+	_, err := s.db.ExecContext(context.Background(), `DELETE FROM callbacks WHERE namespace = $1 AND task_id = $2`, s.namespace, id)
+	return err
+}
+
+// DeleteTask removes a task, its results and partial results. Deleting a
+// missing task is not an error.
+func (s *PostgresStore) DeleteTask(id string) error {
+	ctx := context.Background()
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM partial_results WHERE namespace = $1 AND task_id = $2`, s.namespace, id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM task_results WHERE namespace = $1 AND task_id = $2`, s.namespace, id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM callbacks WHERE namespace = $1 AND task_id = $2`, s.namespace, id); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM tasks WHERE namespace = $1 AND id = $2`, s.namespace, id)
+		return err
+	})
+}
+
+// ListTasks returns the tasks matching query in creation order, filtered,
+// ordered and paged by the database.
+func (s *PostgresStore) ListTasks(query TaskQuery) ([]*ScanTask, error) {
+	where := []string{"namespace = $1"}
+	args := []interface{}{s.namespace}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(condition, len(args)))
+	}
+	if !query.CreatedBefore.IsZero() {
+		add("created_at < $%d", query.CreatedBefore)
+	}
+	if !query.CreatedAfter.IsZero() {
+		add("created_at >= $%d", query.CreatedAfter)
+	}
+	if query.Status != "" {
+		add("status = $%d", query.Status)
+	}
+	if query.Mode != "" {
+		add("mode = $%d", query.Mode)
+	}
+	if query.Owner != "" {
+		add("fields->>'owner' = $%d", query.Owner)
+	}
+	if query.TopLevel {
+		where = append(where, "parent = ''")
+	}
+	statement := "SELECT fields FROM tasks WHERE " + strings.Join(where, " AND ")
+	if query.NewestFirst {
+		statement += " ORDER BY created_at DESC, id DESC"
+	} else {
+		statement += " ORDER BY created_at, id"
+	}
+	if query.Limit > 0 {
+		statement += " LIMIT " + strconv.Itoa(query.Limit)
+	}
+	if query.Offset > 0 {
+		statement += " OFFSET " + strconv.Itoa(query.Offset)
+	}
+
+	rows, err := s.db.QueryContext(context.Background(), statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tasks []*ScanTask
+	for rows.Next() {
+		var fields []byte
+		if err := rows.Scan(&fields); err != nil {
+			return nil, err
+		}
+		task, err := decodeTask(fields)
+		if err != nil {
+			return nil, fmt.Errorf("load task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	for _, task := range tasks {
+		if err := s.loadResults(task); err != nil {
+			return nil, err
+		}
+	}
+	return tasks, nil
+}
+
+// SaveMonitor creates or replaces a monitor.
+func (s *PostgresStore) SaveMonitor(monitor *Monitor) error {
+	data, err := json.Marshal(monitor)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(context.Background(), `
+		INSERT INTO monitors (namespace, id, created_at, data) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (namespace, id) DO UPDATE SET created_at = EXCLUDED.created_at, data = EXCLUDED.data`,
+		s.namespace, monitor.ID, monitor.CreatedAt, string(data))
+	return err
+}
+
+// ReplaceMonitor stores monitor only while the stored monitor is still
+// previous, so the scheduler cannot bring back a deleted monitor or undo a
+// write it did not see. It reports whether monitor was stored.
+func (s *PostgresStore) ReplaceMonitor(previous, monitor *Monitor) (bool, error) {
+	return s.replaceData("monitors", monitor.ID, previous, monitor)
+}
+
+// replaceData stores value as the data of row id of table while the row
+// still holds previous.
+func (s *PostgresStore) replaceData(table, id string, previous, value interface{}) (bool, error) {
+	old, err := json.Marshal(previous)
+	if err != nil {
+		return false, err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+	result, err := s.db.ExecContext(context.Background(),
+		`UPDATE `+table+` SET data = $4 WHERE namespace = $1 AND id = $2 AND data = $3`,
+		s.namespace, id, string(old), string(data))
+	if err != nil {
+		return false, err
+	}
+	replaced, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return replaced == 1, nil
+}
+
+// GetMonitor retrieves a monitor by ID.
+func (s *PostgresStore) GetMonitor(id string) (*Monitor, error) {
+	var raw string
+	err := s.db.QueryRowContext(context.Background(), `SELECT data FROM monitors WHERE namespace = $1 AND id = $2`,
+		s.namespace, id).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMonitorNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var monitor Monitor
+	if err := json.Unmarshal([]byte(raw), &monitor); err != nil {
+		return nil, err
+	}
+	return &monitor, nil
+}
+
+// DeleteMonitor removes a monitor. Deleting a missing monitor is not an error.
+func (s *PostgresStore) DeleteMonitor(id string) error {
+	_, err := s.db.ExecContext(context.Background(), `DELETE FROM monitors WHERE namespace = $1 AND id = $2`, s.namespace, id)
+	return err
+}
+
+// ListMonitors returns every monitor of the namespace ordered by creation time.
+func (s *PostgresStore) ListMonitors() ([]*Monitor, error) {
+	rows, err := s.db.QueryContext(context.Background(), `SELECT data FROM monitors WHERE namespace = $1 ORDER BY created_at, id`, s.namespace)
+	if err != nil {
+		return nil, err
+	}
+	values, err := scanStrings(rows)
+	if err != nil {
+		return nil, err
+	}
+	monitors := make([]*Monitor, len(values))
+	for i, raw := range values {
+		monitors[i] = &Monitor{}
+		if err := json.Unmarshal([]byte(raw), monitors[i]); err != nil {
+			return nil, fmt.Errorf("load monitor: %w", err)
+		}
+	}
+	return monitors, nil
+}
+
+// SaveSchedule creates or replaces a schedule.
+func (s *PostgresStore) SaveSchedule(schedule *Schedule) error {
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(context.Background(), `
+		INSERT INTO schedules (namespace, id, created_at, data) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (namespace, id) DO UPDATE SET created_at = EXCLUDED.created_at, data = EXCLUDED.data`,
+		s.namespace, schedule.ID, schedule.CreatedAt, string(data))
+	return err
+}
+
+// ReplaceSchedule stores schedule only while the stored schedule is still
+// previous, so the scheduler cannot bring back a deleted schedule or undo a
+// pause. It reports whether schedule was stored.
+func (s *PostgresStore) ReplaceSchedule(previous, schedule *Schedule) (bool, error) {
+	return s.replaceData("schedules", schedule.ID, previous, schedule)
+}
+
+// GetSchedule retrieves a schedule by ID.
+func (s *PostgresStore) GetSchedule(id string) (*Schedule, error) {
+	var raw string
+	err := s.db.QueryRowContext(context.Background(), `SELECT data FROM schedules WHERE namespace = $1 AND id = $2`,
+		s.namespace, id).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrScheduleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var schedule Schedule
+	if err := json.Unmarshal([]byte(raw), &schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// DeleteSchedule removes a schedule. Deleting a missing schedule is not an error.
+func (s *PostgresStore) DeleteSchedule(id string) error {
+	_, err := s.db.ExecContext(context.Background(), `DELETE FROM schedules WHERE namespace = $1 AND id = $2`, s.namespace, id)
+	return err
+}
+
+// ListSchedules returns every schedule of the namespace ordered by creation time.
+func (s *PostgresStore) ListSchedules() ([]*Schedule, error) {
+	rows, err := s.db.QueryContext(context.Background(), `SELECT data FROM schedules WHERE namespace = $1 ORDER BY created_at, id`, s.namespace)
+	if err != nil {
+		return nil, err
+	}
+	values, err := scanStrings(rows)
+	if err != nil {
+		return nil, err
+	}
+	schedules := make([]*Schedule, len(values))
+	for i, raw := range values {
+		schedules[i] = &Schedule{}
+		if err := json.Unmarshal([]byte(raw), schedules[i]); err != nil {
+			return nil, fmt.Errorf("load schedule: %w", err)
+		}
+	}
+	return schedules, nil
+}
+
+// SaveTemplate creates or replaces a scan template.
+func (s *PostgresStore) SaveTemplate(template *ScanTemplate) error {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(context.Background(), `
+		INSERT INTO scan_templates (namespace, id, created_at, data) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (namespace, id) DO UPDATE SET created_at = EXCLUDED.created_at, data = EXCLUDED.data`,
+		s.namespace, template.ID, template.CreatedAt, string(data))
+	return err
+}
+
+// GetTemplate retrieves a scan template by ID.
+func (s *PostgresStore) GetTemplate(id string) (*ScanTemplate, error) {
+	var raw string
+	err := s.db.QueryRowContext(context.Background(), `SELECT data FROM scan_templates WHERE namespace = $1 AND id = $2`,
+		s.namespace, id).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var template ScanTemplate
+	if err := json.Unmarshal([]byte(raw), &template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// DeleteTemplate removes a scan template. Deleting a missing template is not an error.
+func (s *PostgresStore) DeleteTemplate(id string) error {
+	_, err := s.db.ExecContext(context.Background(), `DELETE FROM scan_templates WHERE namespace = $1 AND id = $2`, s.namespace, id)
+	return err
+}
+
+// ListTemplates returns every scan template of the namespace ordered by creation time.
+func (s *PostgresStore) ListTemplates() ([]*ScanTemplate, error) {
+	rows, err := s.db.QueryContext(context.Background(), `SELECT data FROM scan_templates WHERE namespace = $1 ORDER BY created_at, id`, s.namespace)
+	if err != nil {
+		return nil, err
+	}
+	values, err := scanStrings(rows)
+	if err != nil {
+		return nil, err
+	}
+	templates := make([]*ScanTemplate, len(values))
+	for i, raw := range values {
+		templates[i] = &ScanTemplate{}
+		if err := json.Unmarshal([]byte(raw), templates[i]); err != nil {
+			return nil, fmt.Errorf("load template: %w", err)
+		}
+	}
+	return templates, nil
+}
+
+// AcquireLease takes or renews the lease name, shared by all namespaces, for
+// holder and reports whether holder has it for the next ttl. A lease whose
+// holder stops renewing it is free again once ttl has passed.
+func (s *PostgresStore) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	rows, err := s.db.QueryContext(context.Background(), `
+		INSERT INTO leases (name, holder, expires_at) VALUES ($1, $2, now() + $3 * interval '1 millisecond')
+		ON CONFLICT (name) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		WHERE leases.holder = EXCLUDED.holder OR leases.expires_at < now()
+		RETURNING holder`, name, holder, ttl.Milliseconds())
+	if err != nil {
+		return false, err
+	}
+	holders, err := scanStrings(rows)
+	return len(holders) == 1, err
+}
+
+// UpdateInventoryHost applies a change to the inventory record of host,
+// starting from an empty record when none exists. The row is locked while
+// apply runs, so updates from concurrent workers are serialized.
+func (s *PostgresStore) UpdateInventoryHost(host string, apply func(*InventoryHost)) error {
+	ctx := context.Background()
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO inventory (namespace, host, data) VALUES ($1, $2, '') ON CONFLICT DO NOTHING`,
+			s.namespace, host); err != nil {
+			return err
+		}
+		var raw string
+		if err := tx.QueryRowContext(ctx, `SELECT data FROM inventory WHERE namespace = $1 AND host = $2 FOR UPDATE`,
+			s.namespace, host).Scan(&raw); err != nil {
+			return err
+		}
+		record := &InventoryHost{Host: host}
+		if raw != "" {
+			if err := json.Unmarshal([]byte(raw), record); err != nil {
+				return err
+			}
+		}
+		apply(record)
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE inventory SET data = $3 WHERE namespace = $1 AND host = $2`, s.namespace, host, string(data))
+		return err
+	})
+}
+
+// GetInventoryHost retrieves the inventory record of host.
+func (s *PostgresStore) GetInventoryHost(host string) (*InventoryHost, error) {
+	var raw string
+	err := s.db.QueryRowContext(context.Background(), `SELECT data FROM inventory WHERE namespace = $1 AND host = $2`,
+		s.namespace, host).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && raw == "") {
+		return nil, ErrHostNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record InventoryHost
+	if err := json.Unmarshal([]byte(raw), &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// ListInventory returns every inventory record of the namespace sorted by host.
+func (s *PostgresStore) ListInventory() ([]*InventoryHost, error) {
+	rows, err := s.db.QueryContext(context.Background(), `SELECT data FROM inventory WHERE namespace = $1 AND data <> '' ORDER BY host`, s.namespace)
+	if err != nil {
+		return nil, err
+	}
+	values, err := scanStrings(rows)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*InventoryHost, len(values))
+	for i, raw := range values {
+		records[i] = &InventoryHost{}
+		if err := json.Unmarshal([]byte(raw), records[i]); err != nil {
+			return nil, fmt.Errorf("load inventory: %w", err)
+		}
+	}
+	return records, nil
+}
+
+// SaveRecentResults records results probed at observedAt so tasks started
+// within ttl can reuse them. Reused results are not recorded again, so reuse
+// never extends the age of an observation. Expired entries are deleted on
+// the way.
+func (s *PostgresStore) SaveRecentResults(protocol string, results []scanner.ScanResult, observedAt time.Time, ttl time.Duration) error {
+	observedAt = observedAt.Truncate(time.Microsecond)
+	expiresAt := time.Now().Add(ttl)
+	ctx := context.Background()
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM recent_results WHERE expires_at < now()`); err != nil {
+			return err
+		}
+		for _, result := range results {
+			if result.Reused {
+				continue
+			}
+			encoded, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO recent_results (namespace, protocol, host, port_key, observed_at, expires_at, result)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				ON CONFLICT (namespace, protocol, host, port_key) DO UPDATE SET
+					observed_at = EXCLUDED.observed_at, expires_at = EXCLUDED.expires_at, result = EXCLUDED.result`,
+				s.namespace, protocol, result.Host, result.Address+"|"+strconv.Itoa(result.Port), observedAt, expiresAt, string(encoded)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RecentResults returns the results of hosts observed at or after since,
+// keyed by RecentResultKey.
+func (s *PostgresStore) RecentResults(protocol string, hosts []string, since time.Time) (map[string]scanner.ScanResult, error) {
+	recent := make(map[string]scanner.ScanResult)
+	for start := 0; start < len(hosts); start += recentResultsBatch {
+		batch := hosts[start:min(start+recentResultsBatch, len(hosts))]
+		args := []interface{}{s.namespace, protocol, since}
+		placeholders := make([]string, len(batch))
+		for i, host := range batch {
+			args = append(args, host)
+			placeholders[i] = "$" + strconv.Itoa(len(args))
+		}
+		rows, err := s.db.QueryContext(context.Background(), `
+			SELECT host, result FROM recent_results
+			WHERE namespace = $1 AND protocol = $2 AND observed_at >= $3 AND expires_at > now()
+			AND host IN (`+strings.Join(placeholders, ", ")+`)`, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var host, raw string
+			if err := rows.Scan(&host, &raw); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			var result scanner.ScanResult
+			if err := json.Unmarshal([]byte(raw), &result); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("recent result of %s: %w", host, err)
+			}
+			recent[RecentResultKey(host, result.Address, result.Port)] = result
+		}
+		if err := rows.Close(); err != nil {
+			return nil, err
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return recent, nil
+}
+
+// PurgeRecentResults deletes the entries SaveRecentResults recorded for
+// results at observedAt and returns how many it deleted. Entries a later
+// observation has replaced since are kept.
+func (s *PostgresStore) PurgeRecentResults(protocol string, results []scanner.ScanResult, observedAt time.Time) (int, error) {
+	observedAt = observedAt.Truncate(time.Microsecond)
+	purged := 0
+	ctx := context.Background()
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, result := range results {
+			if result.Reused {
+				continue
+			}
+			res, err := tx.ExecContext(ctx, `
+				DELETE FROM recent_results
+				WHERE namespace = $1 AND protocol = $2 AND host = $3 AND port_key = $4 AND observed_at = $5`,
+				s.namespace, protocol, result.Host, result.Address+"|"+strconv.Itoa(result.Port), observedAt)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			purged += int(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// queueMode returns the mode column of a queue entry for mode, see
+// modeQueueKey.
+func queueMode(mode string) string {
+	return strings.TrimPrefix(modeQueueKey(mode), queueKey+":")
+}
+
+// PushToQueue enqueues a task ID of this namespace on the queue of its scan mode.
+func (s *PostgresStore) PushToQueue(taskID, mode string) error {
+	_, err := s.db.ExecContext(context.Background(), `INSERT INTO scan_queue (mode, entry) VALUES ($1, $2)`,
+		queueMode(mode), s.queueEntry(taskID))
+	return err
+}
+
+// AcquireRunSlot lets a task submitted by owner run when the owner has fewer
+// than limit tasks running. Otherwise the task is set to held until
+// ReleaseRunSlot hands it back. Slots and held tasks are tracked by queue
+// entry and shared by all namespaces, like the queue.
+func (s *PostgresStore) AcquireRunSlot(owner, taskID string, limit int) (bool, error) {
+	ctx := context.Background()
+	entry := s.queueEntry(taskID)
+	acquired := false
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, owner); err != nil {
+			return err
+		}
+		var holding bool
+		var running int
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(bool_or(entry = $2), false), count(*) FROM run_slots WHERE owner = $1`,
+			owner, entry).Scan(&holding, &running); err != nil {
+			return err
+		}
+		if holding {
+			acquired = true
+			return nil
+		}
+		if running < limit {
+			acquired = true
+			_, err := tx.ExecContext(ctx, `INSERT INTO run_slots (owner, entry) VALUES ($1, $2)`, owner, entry)
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM held_tasks WHERE owner = $1 AND entry = $2`, owner, entry); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO held_tasks (owner, entry) VALUES ($1, $2)`, owner, entry); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, setStatusSQL, s.namespace, taskID, "held")
+		return err
+	})
+	return acquired, err
+}
+
+// ReleaseRunSlot frees the slot of a task, if it has one, and returns the
+// queue entry of the oldest held task of owner, or "" when none waits. The
+// caller queues it again.
+func (s *PostgresStore) ReleaseRunSlot(owner, taskID string) (string, error) {
+	ctx := context.Background()
+	next := ""
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, owner); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM run_slots WHERE owner = $1 AND entry = $2`, owner, s.queueEntry(taskID)); err != nil {
+			return err
+		}
+		err := tx.QueryRowContext(ctx, `
+			DELETE FROM held_tasks WHERE seq = (SELECT seq FROM held_tasks WHERE owner = $1 ORDER BY seq LIMIT 1)
+			RETURNING entry`, owner).Scan(&next)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	})
+	return next, err
+}
+
+// RunSlots lists the queue entries owner has running.
+func (s *PostgresStore) RunSlots(owner string) ([]string, error) {
+	rows, err := s.db.QueryContext(context.Background(), `SELECT entry FROM run_slots WHERE owner = $1`, owner)
+	if err != nil {
+		return nil, err
+	}
+	return scanStrings(rows)
+}
+
+// PopFromQueue blocks until an entry of mode is available and the queue is
+// not paused, polling every queuePollInterval. Concurrent workers skip the
+// entries others are taking. The entry is moved to queue_claims in the same
+// statement and stays there until AckQueueEntry. The result is a raw entry to
+// be resolved with SplitQueueEntry.
+func (s *PostgresStore) PopFromQueue(mode string) (string, error) {
+	ctx := context.Background()
+	for {
+		var entry string
+		err := s.db.QueryRowContext(ctx, `
+			WITH taken AS (
+				DELETE FROM scan_queue WHERE seq = (
+					SELECT seq FROM scan_queue
+					WHERE mode = $1 AND NOT EXISTS (SELECT 1 FROM queue_pause)
+					ORDER BY seq LIMIT 1 FOR UPDATE SKIP LOCKED
+				) RETURNING seq, mode, entry
+			)
+			INSERT INTO queue_claims (seq, mode, entry, heartbeat_at)
+			SELECT seq, mode, entry, now() FROM taken
+			RETURNING entry`, queueMode(mode)).Scan(&entry)
+		if errors.Is(err, sql.ErrNoRows) {
+			time.Sleep(queuePollInterval)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return entry, nil
+	}
+}
+
+// HeartbeatQueueEntry records that the worker holding entry is still alive.
+func (s *PostgresStore) HeartbeatQueueEntry(entry string) error {
+	_, err := s.db.ExecContext(context.Background(), `UPDATE queue_claims SET heartbeat_at = now() WHERE entry = $1`, entry)
+	return err
+}
+
+// AckQueueEntry drops the claim on entry once its worker is done with it,
+// whatever the outcome.
+func (s *PostgresStore) AckQueueEntry(mode, entry string) error {
+	_, err := s.db.ExecContext(context.Background(), `
+		DELETE FROM queue_claims WHERE seq = (
+			SELECT seq FROM queue_claims WHERE mode = $1 AND entry = $2 ORDER BY seq LIMIT 1
+		)`, queueMode(mode), entry)
+	return err
+}
+
+// StaleQueueEntries lists the entries taken by workers whose last heartbeat
+// is older than staleAfter.
+func (s *PostgresStore) StaleQueueEntries(staleAfter time.Duration) ([]QueueClaim, error) {
+	rows, err := s.db.QueryContext(context.Background(), `
+		SELECT mode, entry, heartbeat_at FROM queue_claims WHERE heartbeat_at < $1 ORDER BY seq`,
+		time.Now().Add(-staleAfter).UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var stale []QueueClaim
+	for rows.Next() {
+		var claim QueueClaim
+		if err := rows.Scan(&claim.Mode, &claim.Entry, &claim.HeartbeatAt); err != nil {
+			return nil, err
+		}
+		claim.HeartbeatAt = claim.HeartbeatAt.UTC()
+		stale = append(stale, claim)
+	}
+	return stale, rows.Err()
+}
+
+// RequeueQueueEntry puts an entry taken by a worker of mode back in its
+// queue, at the place it was taken from. It reports false when the entry was
+// acknowledged meanwhile.
+func (s *PostgresStore) RequeueQueueEntry(mode, entry string) (bool, error) {
+	res, err := s.db.ExecContext(context.Background(), requeueEntrySQL, queueMode(mode), entry)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// requeueEntrySQL moves the oldest claim of an entry back to the queue,
+// keeping its place.
+const requeueEntrySQL = `
+	WITH released AS (
+		DELETE FROM queue_claims WHERE seq = (
+			SELECT seq FROM queue_claims WHERE mode = $1 AND entry = $2 ORDER BY seq LIMIT 1
+		) RETURNING seq, mode, entry
+	)
+	INSERT INTO scan_queue (seq, mode, entry) SELECT seq, mode, entry FROM released`
+
+// RequeueTask puts an entry taken by a worker of mode back in its queue and
+// writes task in the same transaction, like RedisStore.RequeueTask.
+func (s *PostgresStore) RequeueTask(mode, entry string, task *ScanTask) (bool, error) {
+	fields, err := encodeTask(task)
+	if err != nil {
+		return false, err
+	}
+	results, err := encodeResults(task.Results)
+	if err != nil {
+		return false, err
+	}
+	ctx := context.Background()
+	moved := false
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		var status string
+		err := tx.QueryRowContext(ctx, `SELECT status FROM tasks WHERE namespace = $1 AND id = $2 FOR UPDATE`,
+			s.namespace, task.ID).Scan(&status)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && status != "pending" && status != "running") {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, requeueEntrySQL, queueMode(mode), entry)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, updateTaskSQL,
+			s.namespace, task.ID, task.Status, task.Mode, task.Parent, string(fields)); err != nil {
+			return err
+		}
+		if err := s.writeResults(ctx, tx, task.ID, results); err != nil {
+			return err
+		}
+		moved = true
+		return nil
+	})
+	return moved, err
+}
+
+// RegisterWorkerNode records node in the registry shared by all namespaces
+// for ttl; the node calls it again before then to stay listed.
+func (s *PostgresStore) RegisterWorkerNode(node *WorkerNode, ttl time.Duration) error {
+	data, err := json.Marshal(node)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(context.Background(), `
+		INSERT INTO worker_nodes (id, data, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data, expires_at = EXCLUDED.expires_at`,
+		node.ID, string(data), time.Now().Add(ttl).UTC())
+	return err
+}
+
+// ListWorkerNodes returns the registered worker nodes ordered by ID. Nodes
+// whose registration expired are dropped from the registry.
+func (s *PostgresStore) ListWorkerNodes() ([]*WorkerNode, error) {
+	ctx := context.Background()
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM worker_nodes WHERE expires_at < now()`); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT data FROM worker_nodes ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	records, err := scanStrings(rows)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*WorkerNode, 0, len(records))
+	for _, data := range records {
+		var node WorkerNode
+		if err := json.Unmarshal([]byte(data), &node); err != nil {
+			return nil, fmt.Errorf("decode worker node: %w", err)
+		}
+		nodes = append(nodes, &node)
+	}
+	return nodes, nil
+}
+
+// CreateAPIKey stores a managed API key, shared by all namespaces, that
+// authenticates callers presenting a key whose SHA-256 is hash.
+func (s *PostgresStore) CreateAPIKey(key *APIKey, hash string) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(context.Background(), `
+		INSERT INTO api_keys (id, hash, created_at, data) VALUES ($1, $2, $3, $4)`,
+		key.ID, hash, key.CreatedAt, string(data))
+	return err
+}
+
+// APIKeyByHash returns the active API key whose SHA-256 is hash.
+func (s *PostgresStore) APIKeyByHash(hash string) (*APIKey, error) {
+	var raw string
+	err := s.db.QueryRowContext(context.Background(), `SELECT data FROM api_keys WHERE hash = $1 AND NOT revoked`,
+		hash).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var key APIKey
+	if err := json.Unmarshal([]byte(raw), &key); err != nil {
+		return nil, fmt.Errorf("decode api key: %w", err)
+	}
+	return &key, nil
+}
+
+// ListAPIKeys returns every managed API key, revoked ones included, ordered
+// by creation time.
+func (s *PostgresStore) ListAPIKeys() ([]*APIKey, error) {
+	rows, err := s.db.QueryContext(context.Background(), `SELECT data FROM api_keys ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	values, err := scanStrings(rows)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*APIKey, len(values))
+	for i, raw := range values {
+		keys[i] = &APIKey{}
+		if err := json.Unmarshal([]byte(raw), keys[i]); err != nil {
+			return nil, fmt.Errorf("decode api key: %w", err)
+		}
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey stops the API key id from authenticating and returns its
+// record. The record is kept; revoking a revoked key changes nothing.
+func (s *PostgresStore) RevokeAPIKey(id string) (*APIKey, error) {
+	ctx := context.Background()
+	var key APIKey
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var raw string
+		err := tx.QueryRowContext(ctx, `SELECT data FROM api_keys WHERE id = $1 FOR UPDATE`, id).Scan(&raw)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAPIKeyNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal([]byte(raw), &key); err != nil {
+			return fmt.Errorf("decode api key: %w", err)
+		}
+		if key.RevokedAt != nil {
+			return nil
+		}
+		now := time.Now().UTC()
+		key.RevokedAt = &now
+		data, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE api_keys SET revoked = true, data = $2 WHERE id = $1`, id, string(data))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// PauseQueue stops workers from taking new tasks. Submissions keep being queued.
+func (s *PostgresStore) PauseQueue(by string) error {
+	_, err := s.db.ExecContext(context.Background(), `
+		INSERT INTO queue_pause (paused_at, paused_by) VALUES ($1, $2)
+		ON CONFLICT (singleton) DO UPDATE SET paused_at = EXCLUDED.paused_at, paused_by = EXCLUDED.paused_by`,
+		time.Now().UTC(), by)
+	return err
+}
+
+// ResumeQueue lets workers take tasks from the queue again.
+func (s *PostgresStore) ResumeQueue() error {
+	_, err := s.db.ExecContext(context.Background(), `DELETE FROM queue_pause`)
+	return err
+}
+
+// QueueStatus reports whether the queue is paused and how many tasks are
+// waiting, in total and per mode. Pool sizes are left for the caller to fill in.
+func (s *PostgresStore) QueueStatus() (*QueueStatus, error) {
+	ctx := context.Background()
+	rows, err := s.db.QueryContext(ctx, `SELECT mode, count(*) FROM scan_queue GROUP BY mode`)
+	if err != nil {
+		return nil, err
+	}
+	depths := make(map[string]int64)
+	for rows.Next() {
+		var mode string
+		var depth int64
+		if err := rows.Scan(&mode, &depth); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		depths[mode] = depth
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	status := &QueueStatus{}
+	for _, mode := range QueueModes {
+		depth := depths[string(mode)]
+		status.Pools = append(status.Pools, QueuePool{Mode: string(mode), Depth: depth})
+		status.Depth += depth
+	}
+	var pausedAt time.Time
+	var pausedBy string
+	err = s.db.QueryRowContext(ctx, `SELECT paused_at, paused_by FROM queue_pause`).Scan(&pausedAt, &pausedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	pausedAt = pausedAt.UTC()
+	status.Paused, status.PausedBy, status.PausedAt = true, pausedBy, &pausedAt
+	return status, nil
+}
+
+// QueuedTaskIDs returns the IDs of this namespace's tasks currently waiting in any queue.
+func (s *PostgresStore) QueuedTaskIDs() ([]string, error) {
+	rows, err := s.db.QueryContext(context.Background(), `SELECT entry FROM scan_queue ORDER BY seq`)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := scanStrings(rows)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, entry := range entries {
+		if ns, id := SplitQueueEntry(entry); ns == s.namespace {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// RemoveFromQueue drops every queue entry referencing taskID in this namespace.
+func (s *PostgresStore) RemoveFromQueue(taskID string) error {
+	_, err := s.db.ExecContext(context.Background(), `DELETE FROM scan_queue WHERE entry = $1`, s.queueEntry(taskID))
+	return err
+}
+
+// scanStrings reads the single text column of every row and closes rows.
+func scanStrings(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
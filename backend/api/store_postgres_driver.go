@@ -0,0 +1,4 @@
+package api
+
+// The pgx driver backs PostgresStore through database/sql.
+import _ "github.com/jackc/pgx/v5/stdlib"
@@ -0,0 +1,127 @@
+package api
+
+import "fmt"
+
+// targetErrors validates the target manifest of req and fills req.Hosts with
+// its hosts, so the rest of the request handling sees the usual host list.
+func targetErrors(req *CreateScanRequest) []FieldError {
+	var details []FieldError
+	seen := make(map[string]bool, len(req.Targets))
+	req.Hosts = make([]string, 0, len(req.Targets))
+	for i, target := range req.Targets {
+		if seen[target.Host] {
+			details = append(details, FieldError{
+				Field:   fmt.Sprintf("targets[%d].host", i),
+				Rule:    "unique",
+				Message: fmt.Sprintf("%s is listed more than once", target.Host),
+			})
+		}
+		seen[target.Host] = true
+		req.Hosts = append(req.Hosts, target.Host)
+
+		switch {
+		case target.Ports != "":
+			if _, err := parsePorts(target.Ports, targetMode(&ScanTask{Mode: req.Mode}, target)); err != nil {
+				details = append(details, FieldError{Field: fmt.Sprintf("targets[%d].ports", i), Rule: "format", Message: err.Error()})
+			}
+		case req.Ports == "":
+			details = append(details, FieldError{
+				Field:   fmt.Sprintf("targets[%d].ports", i),
+				Rule:    "required",
+				Message: fmt.Sprintf("targets[%d].ports is required when ports is absent", i),
+			})
+		}
+	}
+	return details
+}
+
+// targetMode returns the mode target is scanned in within task.
+func targetMode(task *ScanTask, target ScanTarget) string {
+	if target.Mode != "" {
+		return target.Mode
+	}
+	return task.Mode
+}
+
+// targetModes lists the distinct modes the targets of task need, in the order
+// they first appear.
+func targetModes(task *ScanTask) []string {
+	var modes []string
+	for _, target := range task.Targets {
+		if mode := targetMode(task, target); !containsString(modes, mode) {
+			modes = append(modes, mode)
+		}
+	}
+	return modes
+}
+
+// taskHostPorts returns the ports of every target of task that has its own.
+func taskHostPorts(task *ScanTask) (map[string][]int, error) {
+	hostPorts := make(map[string][]int)
+	for _, target := range task.Targets {
+		if target.Ports == "" {
+			continue
+		}
+		ports, err := parsePorts(target.Ports, targetMode(task, target))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", target.Host, err)
+		}
+		hostPorts[target.Host] = ports
+	}
+	return hostPorts, nil
+}
+
+// taskJobs returns the number of ports task probes across its hosts, counting
+// each host once regardless of how many addresses it resolved to.
+func taskJobs(task *ScanTask) (int, error) {
+	hostPorts, err := taskHostPorts(task)
+	if err != nil {
+		return 0, err
+	}
+	defaultPorts := 0
+	if task.Ports != "" {
+		ports, err := parsePorts(task.Ports, task.Mode)
+		if err != nil {
+			return 0, err
+		}
+		defaultPorts = len(ports)
+	}
+	jobs := 0
+	for _, host := range task.Hosts {
+		if ports, ok := hostPorts[host]; ok {
+			jobs += len(ports)
+		} else {
+			jobs += defaultPorts
+		}
+	}
+	return jobs, nil
+}
+
+// modeGroup is the part of a task scanned in one mode.
+type modeGroup struct {
+	mode    string
+	hosts   []string
+	targets []ScanTarget
+}
+
+// modeGroups splits the hosts of task by the mode their targets need, in the
+// order each mode first appears. A task without targets is a single group.
+func modeGroups(task *ScanTask) []modeGroup {
+	if len(task.Targets) == 0 {
+		return []modeGroup{{mode: task.Mode, hosts: task.Hosts}}
+	}
+	var groups []modeGroup
+	index := make(map[string]int)
+	for _, target := range task.Targets {
+		mode := targetMode(task, target)
+		i, ok := index[mode]
+		if !ok {
+			i = len(groups)
+			index[mode] = i
+			groups = append(groups, modeGroup{mode: mode})
+		}
+		groups[i].hosts = append(groups[i].hosts, target.Host)
+		groups[i].targets = append(groups[i].targets, target)
+	}
+	return groups
+}
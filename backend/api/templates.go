@@ -0,0 +1,153 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// templateOptionalRules are the validation rules a template may fail: it
+// need not name hosts, ports or a mode, the requests using it supply them.
+var templateOptionalRules = map[string]bool{
+	"required":             true,
+	"required_without":     true,
+	"required_without_all": true,
+}
+
+// @Summary      Create a scan template
+// @Description  Save a named scan configuration, such as the ports, mode, timing and detection options used for a class of assets. POST /scans and POST /scans/estimate take its id as template_id and fill every field the request leaves out from it, so a request can be as small as {"template_id": "...", "hosts": ["10.0.0.5"]}. The saved fields are validated as in POST /scans, except that hosts, ports and mode may be left to the requests.
+// @Tags         Templates
+// @Accept       json
+// @Produce      json
+// @Param        templateRequest  body      CreateTemplateRequest    true  "Template definition"
+// @Success      201              {object}  ScanTemplate             "Template created. Example: {\"id\":\"3c9d2e1f-7a4b-4c5d-8e6f-1a2b3c4d5e6f\",\"name\":\"web-servers\",\"scan\":{\"ports\":\"80,443,8000-8100\",\"mode\":\"connect\",\"timing\":\"aggressive\",\"checks\":[\"http\",\"tls\"]},\"created_at\":\"2024-01-02T15:04:05Z\"}"
+// @Failure      400              {object}  ValidationErrorResponse  "Malformed JSON body or failed validation. Example: {\"error\":\"invalid request payload\",\"details\":[{\"field\":\"scan.mode\",\"rule\":\"oneof\",\"message\":\"mode must be one of: connect syn udp\"}]}"
+// @Failure      401              {object}  ErrorResponse            "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      429              {object}  ErrorResponse            "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500              {object}  ErrorResponse            "Internal error while persisting the template. Example: {\"error\":\"failed to persist template\"}"
+// @Security     ApiKeyAuth
+// @Router       /templates [post]
+func (s *Server) createTemplateHandler(c *gin.Context) {
+	var req CreateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, newValidationErrorResponse(err))
+		return
+	}
+	var details []FieldError
+	if req.Scan.TemplateID != "" {
+		details = append(details, FieldError{Field: "scan.template_id", Rule: "excluded", Message: "a template cannot reference another template"})
+	}
+	if err := binding.Validator.ValidateStruct(req.Scan); err != nil {
+		for _, detail := range fieldErrors(err) {
+			if !templateOptionalRules[detail.Rule] {
+				detail.Field = "scan." + detail.Field
+				details = append(details, detail)
+			}
+		}
+	}
+	if len(details) > 0 {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{Error: "invalid request payload", Details: details})
+		return
+	}
+	// Checked on a copy: the template keeps hosts and top_ports as written,
+	// to be expanded for each request
+	check := req.Scan
+	if !s.checkScanRequest(c, &check) {
+		return
+	}
+
+	id, err := generateUUID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to generate template id"})
+		return
+	}
+	template := &ScanTemplate{
+		ID:          id,
+		Name:        req.Name,
+		Description: req.Description,
+		Scan:        req.Scan,
+		Owner:       principalFrom(c).Name,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := s.tasks(c).SaveTemplate(template); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to persist template"})
+		return
+	}
+	c.JSON(http.StatusCreated, template)
+}
+
+// @Summary      List scan templates
+// @Description  Return every scan template of the caller, oldest first.
+// @Tags         Templates
+// @Produce      json
+// @Success      200  {array}   ScanTemplate   "Templates of the caller."
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      429  {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse  "Internal error while loading templates. Example: {\"error\":\"failed to load templates\"}"
+// @Security     ApiKeyAuth
+// @Router       /templates [get]
+func (s *Server) listTemplatesHandler(c *gin.Context) {
+	templates, err := s.tasks(c).ListTemplates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load templates"})
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+// @Summary      Get a scan template
+// @Description  Return one scan template with its saved fields.
+// @Tags         Templates
+// @Produce      json
+// @Param        id   path      string         true  "Template ID (UUID v4)"
+// @Success      200  {object}  ScanTemplate   "Template definition."
+// @Failure      400  {object}  ErrorResponse  "Malformed template identifier. Example: {\"error\":\"invalid template id format\"}"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      404  {object}  ErrorResponse  "Template with the provided ID does not exist. Example: {\"error\":\"template not found\"}"
+// @Failure      429  {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse  "Internal error when loading the template. Example: {\"error\":\"failed to load template\"}"
+// @Security     ApiKeyAuth
+// @Router       /templates/{id} [get]
+func (s *Server) getTemplateHandler(c *gin.Context) {
+	id := c.Param("id")
+	if !uuidV4Pattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid template id format"})
+		return
+	}
+	template, err := s.tasks(c).GetTemplate(id)
+	if err != nil {
+		if err == ErrTemplateNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "template not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to load template"})
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+// @Summary      Delete a scan template
+// @Description  Remove a scan template. Scans submitted with it are unaffected; later requests naming it are rejected.
+// @Tags         Templates
+// @Param        id   path      string         true  "Template ID (UUID v4)"
+// @Success      204  "Template deleted."
+// @Failure      400  {object}  ErrorResponse  "Malformed template identifier. Example: {\"error\":\"invalid template id format\"}"
+// @Failure      401  {object}  ErrorResponse  "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      429  {object}  ErrorResponse  "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500  {object}  ErrorResponse  "Internal error when deleting the template. Example: {\"error\":\"failed to delete template\"}"
+// @Security     ApiKeyAuth
+// @Router       /templates/{id} [delete]
+func (s *Server) deleteTemplateHandler(c *gin.Context) {
+	id := c.Param("id")
+	if !uuidV4Pattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid template id format"})
+		return
+	}
+	if err := s.tasks(c).DeleteTemplate(id); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to delete template"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// globalRateKey holds the fleet-wide probe schedule shared by every worker node.
+const globalRateKey = "scans:rate"
+
+// pacerBatchWindow is how much of the fleet budget one node reserves per Redis
+// round trip. Larger batches mean fewer round trips but burstier sending.
+const pacerBatchWindow = 20 * time.Millisecond
+
+// reserveProbesScript reserves ARGV[2] probe slots spaced ARGV[1] microseconds
+// apart on a schedule shared by all nodes and returns how many microseconds the
+// caller must wait before its first slot. The schedule is the time of the next
+// free slot; reading the Redis clock keeps nodes with skewed clocks consistent.
+var reserveProbesScript = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local count = tonumber(ARGV[2])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000000 + tonumber(clock[2])
+
+local next_free = tonumber(redis.call('GET', KEYS[1]))
+if next_free == nil or next_free < now then
+  next_free = now
+end
+local start = next_free
+next_free = next_free + interval * count
+redis.call('SET', KEYS[1], string.format('%.0f', next_free), 'PX', math.ceil((next_free - now) / 1000) + 1000)
+return math.ceil(start - now)
+`)
+
+// RedisPacer keeps the combined probe rate of every worker node under one
+// ceiling by reserving probe slots on a schedule stored in Redis. Slots are
+// reserved in small batches to limit round trips. When Redis is unreachable
+// the node falls back to pacing itself at the full rate so scans keep running.
+type RedisPacer struct {
+	client    *redis.Client
+	interval  time.Duration
+	batch     int
+	logger    *slog.Logger
+	mu        sync.Mutex
+	available int
+	fallback  time.Time
+	failing   bool
+}
+
+// NewRedisPacer returns a pacer allowing perSecond probes per second across
+// the fleet, or nil when perSecond is not positive.
+func NewRedisPacer(client *redis.Client, perSecond float64, logger *slog.Logger) *RedisPacer {
+	if perSecond <= 0 {
+		return nil
+	}
+	batch := int(math.Ceil(perSecond * pacerBatchWindow.Seconds()))
+	if batch < 1 {
+		batch = 1
+	}
+	return &RedisPacer{
+		client:   client,
+		interval: time.Duration(float64(time.Second) / perSecond),
+		batch:    batch,
+		logger:   logger,
+	}
+}
+
+// Wait blocks until the fleet-wide schedule allows another probe. Nodes
+// reserve slots while holding the lock, so waiting workers of one node queue
+// behind each other rather than reserving batches they would not use.
+func (p *RedisPacer) Wait() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.available > 0 {
+		p.available--
+		return
+	}
+
+	delay, err := p.reserve()
+	if err != nil {
+		if !p.failing {
+			p.logger.Warn("shared probe rate unavailable, pacing this node alone", "error", err)
+			p.failing = true
+		}
+		now := time.Now()
+		if p.fallback.Before(now) {
+			p.fallback = now
+		}
+		delay = p.fallback.Sub(now)
+		p.fallback = p.fallback.Add(p.interval)
+		time.Sleep(delay)
+		return
+	}
+	if p.failing {
+		p.logger.Info("shared probe rate available again")
+		p.failing = false
+	}
+	time.Sleep(delay)
+	p.available = p.batch - 1
+}
+
+func (p *RedisPacer) reserve() (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	micros, err := reserveProbesScript.Run(ctx, p.client, []string{globalRateKey},
+		strconv.FormatInt(p.interval.Microseconds(), 10), p.batch).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(micros) * time.Microsecond, nil
+}
+
+// loadGlobalRate reads CORTEX_GLOBAL_RATE, the probes per second allowed
+// across all worker nodes combined; 0 (the default) disables the shared limit.
+func loadGlobalRate() (float64, error) {
+	raw := getenv("CORTEX_GLOBAL_RATE", "0")
+	rate, err := strconv.ParseFloat(raw, 64)
+	if err != nil || rate < 0 {
+		return 0, fmt.Errorf("CORTEX_GLOBAL_RATE must be a non-negative number of probes per second, got %q", raw)
+	}
+	return rate, nil
+}
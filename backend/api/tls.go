@@ -0,0 +1,144 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Client certificate policies for CORTEX_TLS_CLIENT_AUTH.
+const (
+	ClientAuthRequire  = "require"
+	ClientAuthOptional = "optional"
+)
+
+// TLSConfig describes how the API listener serves HTTPS.
+type TLSConfig struct {
+	// CertFile and KeyFile hold the server certificate and private key in PEM format.
+	CertFile string
+	KeyFile  string
+	// SelfSigned serves a certificate generated at startup instead, for
+	// development.
+	SelfSigned bool
+	// ClientCAFile is a PEM bundle of CAs trusted to sign client certificates.
+	// When empty, client certificates are not requested.
+	ClientCAFile string
+	// ClientAuth is ClientAuthRequire or ClientAuthOptional.
+	ClientAuth string
+}
+
+// loadTLSConfig reads listener TLS settings from the environment:
+// CORTEX_TLS_CERT and CORTEX_TLS_KEY (or the older CORTEX_TLS_CERT_FILE and
+// CORTEX_TLS_KEY_FILE) enable HTTPS, CORTEX_TLS_SELF_SIGNED enables it with a
+// generated certificate, CORTEX_TLS_CLIENT_CA_FILE enables client certificate
+// verification and CORTEX_TLS_CLIENT_AUTH selects require (default) or
+// optional client certificates. The boolean result reports whether TLS is
+// enabled at all.
+func loadTLSConfig() (TLSConfig, bool, error) {
+	cfg := TLSConfig{
+		CertFile:     getenv("CORTEX_TLS_CERT", os.Getenv("CORTEX_TLS_CERT_FILE")),
+		KeyFile:      getenv("CORTEX_TLS_KEY", os.Getenv("CORTEX_TLS_KEY_FILE")),
+		ClientCAFile: os.Getenv("CORTEX_TLS_CLIENT_CA_FILE"),
+		ClientAuth:   strings.ToLower(getenv("CORTEX_TLS_CLIENT_AUTH", ClientAuthRequire)),
+	}
+
+	var err error
+	if cfg.SelfSigned, err = getenvBool("CORTEX_TLS_SELF_SIGNED", false); err != nil {
+		return cfg, false, err
+	}
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return cfg, false, fmt.Errorf("CORTEX_TLS_CERT and CORTEX_TLS_KEY must be set together")
+	}
+	if cfg.SelfSigned && cfg.CertFile != "" {
+		return cfg, false, fmt.Errorf("CORTEX_TLS_SELF_SIGNED cannot be combined with CORTEX_TLS_CERT and CORTEX_TLS_KEY")
+	}
+	if cfg.CertFile == "" && !cfg.SelfSigned {
+		if cfg.ClientCAFile != "" {
+			return cfg, false, fmt.Errorf("CORTEX_TLS_CLIENT_CA_FILE requires CORTEX_TLS_CERT and CORTEX_TLS_KEY or CORTEX_TLS_SELF_SIGNED")
+		}
+		return cfg, false, nil
+	}
+	if cfg.ClientAuth != ClientAuthRequire && cfg.ClientAuth != ClientAuthOptional {
+		return cfg, false, fmt.Errorf("CORTEX_TLS_CLIENT_AUTH must be %q or %q", ClientAuthRequire, ClientAuthOptional)
+	}
+	return cfg, true, nil
+}
+
+// serverTLSConfig builds the crypto/tls configuration for the listener.
+func (cfg TLSConfig) serverTLSConfig() (*tls.Config, error) {
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.SelfSigned {
+		cert, err := selfSignedCertificate()
+		if err != nil {
+			return nil, fmt.Errorf("generate self-signed certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	if cfg.ClientCAFile == "" {
+		return tlsCfg, nil
+	}
+
+	bundle, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("client CA bundle %s contains no PEM certificates", cfg.ClientCAFile)
+	}
+
+	tlsCfg.ClientCAs = pool
+	tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	if cfg.ClientAuth == ClientAuthOptional {
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsCfg, nil
+}
+
+// loadClientIdentities parses CORTEX_TLS_CLIENT_IDENTITIES, a comma-separated
+// list of identity=namespace entries mapping a client certificate CN or SAN
+// (DNS name, URI, or email) to a principal. Appending ":admin" to the
+// namespace grants administrative rights, e.g. ops.example.com=default:admin.
+func loadClientIdentities(raw string) (map[string]Principal, error) {
+	identities := make(map[string]Principal)
+	for i, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		identity, target, ok := strings.Cut(entry, "=")
+		if !ok || identity == "" {
+			return nil, fmt.Errorf("CORTEX_TLS_CLIENT_IDENTITIES entry %d must have the form identity=namespace", i+1)
+		}
+
+		admin := false
+		if namespace, role, hasRole := strings.Cut(target, ":"); hasRole {
+			if role != "admin" {
+				return nil, fmt.Errorf("CORTEX_TLS_CLIENT_IDENTITIES entry %d: unknown role %q", i+1, role)
+			}
+			target, admin = namespace, true
+		}
+		if !namespacePattern.MatchString(target) {
+			return nil, fmt.Errorf("CORTEX_TLS_CLIENT_IDENTITIES entry %d: namespace %q must be 1-63 lowercase letters, digits, '-' or '_'", i+1, target)
+		}
+		identities[identity] = Principal{Name: identity, Namespace: target, Admin: admin}
+	}
+	return identities, nil
+}
+
+// certificateIdentities lists the names a client certificate can be mapped by:
+// the subject common name followed by its DNS, URI, and email SANs.
+func certificateIdentities(cert *x509.Certificate) []string {
+	var names []string
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	names = append(names, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	names = append(names, cert.EmailAddresses...)
+	return names
+}
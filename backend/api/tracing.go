@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/Viktor25104/cortex/backend/version"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records the spans of scan submissions, store calls and worker runs.
+// It does nothing until setupTracing installs an exporter.
+var tracer = otel.Tracer("github.com/Viktor25104/cortex/backend/api")
+
+// setupTracing exports spans over OTLP/HTTP when OTEL_EXPORTER_OTLP_ENDPOINT
+// or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set and OTEL_SDK_DISABLED is not
+// true, and reports whether it did. The exporter, sampler and resource read
+// the other standard OTEL_* variables themselves, such as
+// OTEL_EXPORTER_OTLP_HEADERS, OTEL_TRACES_SAMPLER and OTEL_SERVICE_NAME;
+// serviceName applies when the latter is unset. W3C trace context is
+// propagated either way, so callers' traces continue into the tasks. The
+// returned provider, nil when tracing is off, must be shut down before the
+// process exits to flush the spans it still buffers.
+func setupTracing(serviceName string) (*sdktrace.TracerProvider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") ||
+		(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "") {
+		return nil, nil
+	}
+	ctx := context.Background()
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(
+			attribute.String("service.name", serviceName),
+			attribute.String("service.version", version.Version),
+		),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider, nil
+}
+
+// injectTrace records the span context of ctx on task, so that the worker
+// running it continues the same trace.
+func injectTrace(ctx context.Context, task *ScanTask) {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	task.TraceParent = carrier.Get("traceparent")
+}
+
+// taskTraceContext returns a context carrying the span context recorded on
+// task at submission, if any.
+func taskTraceContext(task *ScanTask) context.Context {
+	return otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier{"traceparent": task.TraceParent})
+}
+
+// traceStore runs op, one call of store, in a span named after the call
+// below ctx.
+func traceStore(ctx context.Context, store TaskStore, call string, op func() error) error {
+	system := "redis"
+	if _, ok := store.(*PostgresStore); ok {
+		system = "postgresql"
+	}
+	_, span := tracer.Start(ctx, "store."+call, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", system), attribute.String("db.operation", call)))
+	err := op()
+	endSpan(span, err)
+	return err
+}
+
+// endSpan marks span failed when err is set and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -3,7 +3,7 @@ package api
 import (
 	"time"
 
-	"cortex/scanner"
+	"github.com/Viktor25104/cortex/backend/scanner"
 )
 
 // ScanTask represents a scanning job managed by the API service.
@@ -11,31 +11,205 @@ type ScanTask struct {
         // ID is the immutable identifier of the scan task (UUID v4).
         ID string `json:"id" format:"uuid" example:"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678" description:"Immutable UUIDv4 identifier assigned when the task is accepted. Persist this value and reuse it for subsequent polling requests."`
         // Status reflects the asynchronous lifecycle state of the task.
-        Status string `json:"status" enums:"pending,running,completed,failed" example:"pending" description:"Current processing state. pending indicates the request is queued, running signals active probing, completed denotes success with results attached, and failed highlights an unrecoverable worker-side issue."`
+        Status string `json:"status" enums:"pending,held,running,pausing,paused,cancelling,cancelled,completed,failed" example:"pending" description:"Current processing state. pending indicates the request is queued, held that it waits because the submitting API key already runs as many scans as CORTEX_MAX_RUNNING_PER_KEY allows, running signals active probing, completed denotes success with results attached, and failed highlights an unrecoverable worker-side issue. pausing means a pause was requested and the worker is finishing its in-flight probes; paused tasks hold the results collected so far and continue after POST /scans/{id}/resume. cancelling means POST /scans/{id}/cancel was requested and the worker is finishing its in-flight probes; cancelled is terminal and keeps the results collected before the cancellation."`
         // Hosts captures every hostname or IP submitted for the scan.
-        Hosts []string `json:"hosts" example:"[\"scanme.nmap.org\",\"192.0.2.10\"]" description:"List of destination targets. Supports IPv4/IPv6 literals and resolvable domain names. The order is preserved so results can be mapped back to the original submission."`
+        Hosts []string `json:"hosts" example:"scanme.nmap.org,192.0.2.10" description:"List of destination targets. Supports IPv4/IPv6 literals and resolvable domain names. The order is preserved so results can be mapped back to the original submission. For a target manifest it lists the host of every entry."`
+        // Targets holds the per-host settings of a target manifest.
+        Targets []ScanTarget `json:"targets,omitempty" description:"Target manifest the scan was submitted with. Each entry may override ports and mode and add tags for its host."`
         // Ports defines the requested port selection as comma-separated values and ranges.
-        Ports string `json:"ports" example:"22,80,443,1000-1100" description:"Port expression combining single ports and inclusive ranges using commas (for example 22,80,443,1000-1100). Whitespace is ignored and duplicate ports are automatically de-duplicated by the scheduler."`
+        Ports string `json:"ports,omitempty" example:"22,80,443,1000-1100" description:"nmap-style port specification combining single ports and inclusive ranges using commas (for example 22,80,443,1000-1100). Ranges may omit their start or end, - selects every port and T: or U: limit the entries that follow to TCP or UDP. Whitespace is ignored and duplicate ports are automatically de-duplicated by the scheduler. A top_ports request is stored as the ports it selected."`
         // Mode determines the underlying probing strategy executed by workers.
-        Mode string `json:"mode" enums:"connect,syn,udp" example:"syn" description:"Scanner transport mode. Use connect for TCP connect() handshakes, syn for half-open SYN scanning against TCP endpoints, or udp for stateless UDP datagram probes."`
+        Mode string `json:"mode" enums:"connect,syn,udp,fin,null,xmas,ack" example:"syn" description:"Scanner transport mode. Use connect for TCP connect() handshakes, syn for half-open SYN scanning against TCP endpoints, udp for stateless UDP datagram probes, fin, null and xmas for raw TCP probes that only closed ports answer, or ack to map firewall rules."`
+        // HostRate caps probes per second sent to each individual host.
+        HostRate float64 `json:"host_rate,omitempty" example:"20" description:"Maximum probes per second sent to any single target host. Zero or absent means no per-host cap."`
+        // MaxRate caps probes per second across all hosts of the task.
+        MaxRate float64 `json:"max_rate,omitempty" example:"500" description:"Maximum probes per second across all hosts as requested. Zero or absent means no cap besides the server-wide one."`
+        // Timing names the timing template the task runs with.
+        Timing string `json:"timing,omitempty" example:"aggressive" description:"Timing template as requested: 0-5 or paranoid, sneaky, polite, normal, aggressive, insane. Absent means normal."`
+        // MaxParallelism caps the probes in flight against one host.
+        MaxParallelism int `json:"max_parallelism,omitempty" example:"10" description:"Maximum probes in flight against any single host as requested. Absent means the timing template's limit."`
+        // MaxDuration bounds how long the scan may run, in seconds.
+        MaxDuration int `json:"max_duration_seconds,omitempty" example:"3600" description:"Maximum scan duration in seconds as requested. Absent means no limit."`
+        // MinHostGroup and MaxHostGroup size the host groups scanned in turn.
+        MinHostGroup int `json:"min_hostgroup,omitempty" example:"8" description:"Hosts in the first host group as requested."`
+        MaxHostGroup int `json:"max_hostgroup,omitempty" example:"64" description:"Largest host group as requested. Absent means all hosts were scanned as one group."`
+        // MaxRetries is how often a silent probe is sent again.
+        MaxRetries *int `json:"max_retries,omitempty" example:"1" description:"Times a probe that drew no answer is sent again as requested. Absent means the timing template's count."`
+        // RetryBackoff is the wait before a silent probe is sent again.
+        RetryBackoff string `json:"retry_backoff,omitempty" example:"250ms" description:"Wait before the first retransmission of a silent syn, raw tcp or udp probe as requested, doubling for each further one. Absent means 100ms."`
+        // AllAddresses requests scanning every resolved address of each hostname.
+        AllAddresses bool `json:"all_addresses,omitempty" example:"true" description:"When true, hostnames resolving to several A/AAAA records are scanned on every address and each result carries the probed address."`
+        // Prefer selects the address family scanned on dual-stack hostnames.
+        Prefer string `json:"prefer,omitempty" enums:"ipv4,ipv6,both" example:"both" description:"Address family scanned on dual-stack hostnames as requested. Absent means ipv4."`
+        // IPVersion restricts the task to one address family.
+        IPVersion int `json:"ip_version,omitempty" enums:"4,6" example:"6" description:"Address family the task was restricted to as requested. Absent means both."`
+        // DNSServers are the name servers the task resolves targets with.
+        DNSServers []string `json:"dns_servers,omitempty" example:"1.1.1.1:53" description:"Name servers, as host:port, that answered every DNS query of the task as requested. Absent means the worker's system resolver."`
+        // VersionIntensity limits service detection to the less rare probes.
+        VersionIntensity *int `json:"version_intensity,omitempty" example:"7" description:"Rarest service probe sent to open ports as requested (0-9). Absent means 7."`
         // Results becomes populated with port findings once the task completes.
-        Results []scanner.ScanResult `json:"results,omitempty" example:"[{\\\"host\\\":\\\"scanme.nmap.org\\\",\\\"port\\\":443,\\\"state\\\":\\\"Open\\\",\\\"service\\\":\\\"https\\\"}]" description:"Collection of port states collected during scanning. Present only after the task reaches the completed status. The array is sorted by host then port for easy rendering."`
+        Results []scanner.ScanResult `json:"results,omitempty" description:"Collection of port states collected during scanning. Present only after the task reaches the completed status. The array is sorted by host then port for easy rendering."`
         // CreatedAt records when the task was created.
         CreatedAt time.Time `json:"created_at" format:"date-time" example:"2024-01-02T15:04:05Z" description:"Timestamp (UTC, RFC3339 format) when the API accepted the scan request."`
+        // Progress is the share of the task's probes finished so far.
+        Progress float64 `json:"progress" example:"42.5" description:"Percentage of the planned probes that have finished, written by the worker about every second while the task runs and 100 once it completed. A paused or cancelled task keeps the value it stopped at. For a sharded task it is the percentage of shards that finished."`
+        // ETASeconds estimates how long the running task still needs.
+        ETASeconds int `json:"eta_seconds,omitempty" example:"95" description:"Estimated seconds until the running task finishes, extrapolated from the probes finished so far. Absent before the first probe finished and once the task stopped."`
         // CompletedAt is set once the task transitions to a terminal state.
         CompletedAt *time.Time `json:"completed_at,omitempty" format:"date-time" example:"2024-01-02T15:06:30Z" description:"Timestamp (UTC, RFC3339 format) indicating when the task finished processing. Empty while the task is pending or running."`
+        // ResultsPurgedAt is set once the results were erased on request.
+        ResultsPurgedAt *time.Time `json:"results_purged_at,omitempty" format:"date-time" example:"2024-02-01T09:00:00Z" description:"Timestamp (UTC, RFC3339 format) when the results, host summaries and baseline changes of the task were erased via DELETE /scans/{id}/results. The task itself is kept."`
         // Error contains context when a task fails.
         Error string `json:"error,omitempty" example:"failed to resolve target host" description:"Diagnostic message describing why the task entered the failed status. Present only when status equals failed."`
+        // NoFallback disables the automatic SYN to connect downgrade.
+        NoFallback bool `json:"no_fallback,omitempty" example:"false" description:"When true the task fails instead of falling back to connect scanning if SYN scanning lacks privileges."`
+        // Checks lists the check modules selected for the task.
+        Checks []string `json:"checks,omitempty" example:"snmp" description:"Check modules run against open ports after the port scan. Findings are attached to the matching results."`
+        // HTTPPaths replaces the paths the http check requests.
+        HTTPPaths []string `json:"http_paths,omitempty" example:"/robots.txt,/admin/" description:"Paths the http check requested instead of its defaults. Absent means the defaults."`
+        // Tags label the task and the inventory records of its hosts.
+        Tags []string `json:"tags,omitempty" example:"prod,dmz" description:"Labels attached to the task. Hosts covered by the task inherit them in the inventory."`
+        // RDAP requests network ownership lookups for public target addresses.
+        RDAP bool `json:"rdap,omitempty" example:"true" description:"When true the worker looks up the network owner of every public target address via RDAP after scanning."`
+        // ResolvePTR requests reverse DNS lookups of the probed addresses.
+        ResolvePTR bool `json:"resolve_ptr,omitempty" example:"true" description:"When true the worker looks up the PTR record of every probed IP address and reports it as reverse_hostname on the results."`
+        // HostSummaries describes each scanned host once the task completes.
+        HostSummaries []scanner.HostSummary `json:"host_summaries,omitempty" description:"Per-host information such as the RDAP netname, organization and abuse contact, why a host looks like a tarpit, or whether host discovery found it up. Present only for completed tasks that requested rdap or discovery or flagged a tarpit."`
+        // DetectTarpits flags hosts whose open ports look fabricated.
+        DetectTarpits bool `json:"detect_tarpits,omitempty" example:"true" description:"When true hosts reporting an implausible share of open ports, or whose open ports all accept connections and then stay silent, are flagged in host_summaries and warnings."`
+        // TarpitDowngrade rewrites the open ports of flagged hosts.
+        TarpitDowngrade bool `json:"tarpit_downgrade,omitempty" example:"false" description:"When true the open ports of flagged hosts are reported with state Tarpit, so they are left out of the inventory, baseline changes and checks."`
+        // Discovery pings every host before the port scan.
+        Discovery bool `json:"discovery,omitempty" example:"true" description:"When true hosts were pinged before the port scan and those that did not answer were skipped; host_summaries lists each host as up or down."`
+        // Monitor names the monitor that scheduled this verification scan.
+        Monitor string `json:"monitor,omitempty" format:"uuid" example:"0f8e3a6d-2c41-4b9e-9a57-3d6c1e2b4f80" description:"Identifier of the monitor that scheduled this task as a verification scan. Empty for tasks submitted directly."`
+        // Schedule names the recurring schedule that queued this task.
+        Schedule string `json:"schedule,omitempty" format:"uuid" example:"6e1f4c2a-8b3d-4a5e-9f60-7d2c1b0a9e83" description:"Identifier of the schedule that queued this task. Empty for tasks submitted directly."`
+        // Template names the saved template the submission started from.
+        Template string `json:"template,omitempty" format:"uuid" example:"3c9d2e1f-7a4b-4c5d-8e6f-1a2b3c4d5e6f" description:"Identifier of the scan template the request referenced with template_id. Empty when none was used."`
+        // ReuseWithin lets the worker reuse recent results instead of probing again.
+        ReuseWithin string `json:"reuse_within,omitempty" example:"15m" description:"Maximum age, as a Go duration, of a result from another task that may be reused instead of probing the same host and port again. Reused results are marked reused."`
+        // Parent names the sharded task this task is one chunk of.
+        Parent string `json:"parent,omitempty" format:"uuid" example:"7c2b9e14-5a3d-4f6e-8b1a-0d9c8e7f6a52" description:"Identifier of the parent task when this task is one shard of a larger scan. Shards are queued and processed like any other task; the parent collects their results."`
+        // Shards lists the child tasks a sharded scan was split into.
+        Shards []string `json:"shards,omitempty" example:"1d4e6f80-2b3c-4a5d-9e8f-7a6b5c4d3e21,9a8b7c6d-5e4f-4321-8fed-cba987654321" description:"Identifiers of the shard tasks when the scan was split with shard_size. The parent stays running until every shard has finished and then carries the combined results."`
+        // ShardsDone counts the shards that reached a terminal state.
+        ShardsDone int `json:"shards_done,omitempty" example:"1" description:"Number of shards that have completed or failed so far. Compare with the length of shards to follow progress."`
+        // Owner names the API key or JWT subject that submitted the task.
+        Owner string `json:"owner,omitempty" example:"default" description:"Name of the API key, or subject of the JWT, that submitted the scan. Its running scans count against CORTEX_MAX_RUNNING_PER_KEY; GET /scans?owner= filters by it."`
+        // Baseline names an earlier task the results are compared against.
+        Baseline string `json:"baseline,omitempty" format:"uuid" example:"5b0e7c1a-9d2f-4e3b-8a6c-2f1d0e9b7a44" description:"Identifier of the earlier task this scan is compared against. Webhooks fire only when the comparison finds changes."`
+        // Changes lists differences from the baseline once the task completes.
+        Changes []scanner.ResultChange `json:"changes,omitempty" description:"Ports that opened, closed or changed service compared with the baseline task. Present only for completed tasks with a baseline and at least one change."`
+        // CallbackURL receives the finished task.
+        CallbackURL string `json:"callback_url,omitempty" example:"https://ci.example.com/hooks/cortex" description:"URL the finished task is posted to, as submitted."`
+        // TraceParent is the W3C traceparent of the submission, continued by
+        // the worker running the task. It is internal and never serialized.
+        TraceParent string `json:"-"`
+        // Callback reports the delivery of the task to CallbackURL.
+        Callback *CallbackDelivery `json:"callback,omitempty" description:"Delivery state of the POST to callback_url. Present once the task completed or failed."`
+        // Warnings lists non-fatal issues encountered while executing the task.
+        Warnings []string `json:"warnings,omitempty" example:"syn scan unavailable, fell back to connect scan: insufficient privileges for raw packet access" description:"Non-fatal issues raised by the worker, such as an automatic downgrade from syn to connect mode when raw packet access is not permitted."`
+}
+
+// CallbackDelivery reports the delivery of a finished task to its callback URL.
+type CallbackDelivery struct {
+        // Status is the state of the delivery.
+        Status string `json:"status" enums:"pending,delivered,failed" example:"delivered" description:"pending while attempts remain, delivered once the URL answered with a 2xx status, failed when every attempt was refused or timed out."`
+        // Attempts counts the POSTs made so far.
+        Attempts int `json:"attempts" example:"1" description:"Number of delivery attempts made so far."`
+        // ResponseStatus is the HTTP status of the last attempt.
+        ResponseStatus int `json:"response_status,omitempty" example:"200" description:"HTTP status code the URL answered the last attempt with. Absent when it did not answer."`
+        // Error describes why the last attempt failed.
+        Error string `json:"error,omitempty" example:"callback answered 503 Service Unavailable" description:"Why the last attempt failed. Absent once delivered."`
+        // DeliveredAt is when the URL accepted the task.
+        DeliveredAt *time.Time `json:"delivered_at,omitempty" format:"date-time" example:"2024-01-02T15:06:31Z" description:"Timestamp (UTC, RFC3339 format) the URL accepted the delivery."`
+        // NextAttemptAt is when the next attempt is due.
+        NextAttemptAt *time.Time `json:"next_attempt_at,omitempty" format:"date-time" example:"2024-01-02T15:06:35Z" description:"Timestamp (UTC, RFC3339 format) the next attempt is due. Present only while the delivery is pending; attempts are queued in the store, so they survive restarts."`
 }
 
 // CreateScanRequest is the payload for creating new scan tasks.
 type CreateScanRequest struct {
+        // TemplateID names a saved template whose fields are the defaults of the request.
+        TemplateID string `json:"template_id,omitempty" binding:"omitempty,uuid4" format:"uuid" example:"3c9d2e1f-7a4b-4c5d-8e6f-1a2b3c4d5e6f" description:"Optional identifier of a scan template created with POST /templates. Its fields are used for every field the request leaves out, so a request may consist of template_id alone or add just the hosts; fields in the request replace the template's."`
         // Hosts enumerates every hostname or IP address the scanner should probe.
-        Hosts []string `json:"hosts" binding:"required,min=1" example:"[\"scanme.nmap.org\",\"203.0.113.50\"]" description:"Targets to scan. Accepts IPv4/IPv6 addresses and domain names that resolve via DNS. Provide at least one entry; multiple hosts are processed concurrently."`
+        Hosts []string `json:"hosts" binding:"required_without=Targets,excluded_with=Targets,omitempty,min=1" example:"scanme.nmap.org,203.0.113.50" description:"Targets to scan. Accepts IPv4/IPv6 addresses, domain names that resolve via DNS, CIDR blocks such as 192.168.1.0/24, dashed IPv4 ranges such as 10.0.0.1-10.0.0.50 or 10.0.0.1-50, and comma-separated lists of these. Blocks and ranges are expanded into one host per address, at most 65536 in total. Provide at least one entry; multiple hosts are processed concurrently. Omit when targets is given."`
+        // Exclude removes hosts from the expanded Hosts.
+        Exclude []string `json:"exclude" binding:"excluded_with=Targets" example:"192.168.1.1,192.168.1.250-254" description:"Hosts, CIDR blocks and IP ranges, in the same forms as hosts, to leave out after expanding hosts. Not allowed with targets."`
+        // Targets lists hosts with their own ports, mode and tags.
+        Targets []ScanTarget `json:"targets" binding:"omitempty,dive" description:"Optional target manifest replacing hosts, for heterogeneous inventories such as web servers and databases scanned with different port sets in one task. Entries without ports or mode use the request's ports and mode. Every host may appear once."`
         // Ports expresses the desired port selection using comma-separated values and ranges.
-        Ports string `json:"ports" binding:"required" example:"443,8443,10000-10100" description:"Combination of single ports and inclusive ranges (e.g. 80,443,1000-1050). Leave no spaces for best readability; ranges must use a hyphen."`
+        Ports string `json:"ports" binding:"required_without_all=Targets TopPorts" example:"443,8443,10000-10100" description:"nmap-style port specification: single ports and inclusive ranges separated by commas (e.g. 80,443,1000-1050). A range may omit its start (-1024) or end (60000-), - alone selects all ports, and T: or U: limit the entries that follow to TCP or UDP (e.g. T:80,443,U:53); the entries for the protocol of mode are scanned. With targets it is the default for entries without ports and may be omitted when every entry has its own."`
+        // TopPorts selects the most commonly open ports instead of Ports.
+        TopPorts int `json:"top_ports" binding:"omitempty,min=1,excluded_with=Ports" example:"100" description:"Scan the given number of ports most often found open for the protocol of mode, ranked by the open frequencies of the bundled nmap-services table, instead of ports. Rejected when the table ranks fewer ports."`
         // Mode selects which worker implementation will be used for probing.
-        Mode string `json:"mode" binding:"required,oneof=connect syn udp" enums:"connect,syn,udp" example:"connect" description:"Scanning strategy. connect performs TCP connect() handshakes suitable for banner grabbing, syn uses half-open SYN probes for fast TCP discovery, udp sends UDP payloads to uncover datagram services."`
+        Mode string `json:"mode" binding:"required,oneof=connect syn udp fin null xmas ack" enums:"connect,syn,udp,fin,null,xmas,ack" example:"connect" description:"Scanning strategy. connect performs TCP connect() handshakes suitable for banner grabbing, syn uses half-open SYN probes for fast TCP discovery, udp sends UDP payloads to uncover datagram services. fin, null and xmas send segments with only FIN, no flags, or FIN, PSH and URG set: closed ports answer them with RST and are reported Closed, silent ports Open|Filtered. They need the privileges of syn scans and share their workers; stacks that ignore RFC 793, such as Windows, show every port closed. ack sends bare ACK segments to map stateful firewall rules: ports that answer with RST are reported Unfiltered, silent ones Filtered, without telling open from closed. With targets it is the default for entries without a mode."`
+        // HostRate optionally caps probes per second per target host.
+        HostRate float64 `json:"host_rate" binding:"omitempty,min=0" example:"20" description:"Optional per-host probe rate ceiling in probes per second. Use it to protect sensitive appliances that share a scan with many other targets. Zero or absent disables the cap."`
+        // MaxRate optionally caps probes per second across all hosts.
+        MaxRate float64 `json:"max_rate" binding:"omitempty,min=0" example:"500" description:"Optional ceiling on probes per second across all hosts of the scan, applied in addition to the server-wide rate. Zero or absent disables the cap."`
+        // Timing selects a timing template.
+        Timing string `json:"timing" binding:"omitempty,oneof=0 1 2 3 4 5 paranoid sneaky polite normal aggressive insane" enums:"0,1,2,3,4,5,paranoid,sneaky,polite,normal,aggressive,insane" example:"aggressive" description:"Timing template, by level like nmap's -T0 to -T5 or by name. paranoid (0), sneaky (1) and polite (2) probe one port of a host at a time with 5m, 15s and 400ms between probes and resend silent probes; normal (3, the default) adapts probe timeouts between 250ms and 10s; aggressive (4) and insane (5) cap the probe timeout at 1.25s and 300ms for fast, reliable networks. host_rate, when set, replaces the template's delay between probes."`
+        // MaxParallelism optionally caps the probes in flight against one host.
+        MaxParallelism int `json:"max_parallelism" binding:"omitempty,min=1,max=100" example:"10" description:"Optional maximum number of probes in flight against any single host, from 1 to 100. The scanner still adapts to the host below this limit. Absent uses the timing template's limit (1 for paranoid to polite, otherwise 100)."`
+        // MaxDurationSeconds optionally bounds how long the scan may run.
+        MaxDurationSeconds int `json:"max_duration_seconds" binding:"omitempty,min=1,max=604800" example:"3600" description:"Optional limit, from 1 second to 7 days, on how long the scan runs, host discovery and checks included. When it passes no further probes are sent, the probes in flight finish and the task completes with the results so far and a warning saying how many probes finished. A paused task starts the limit over when resumed. Absent means no limit."`
+        // MinHostGroup sets the size of the first host group.
+        MinHostGroup int `json:"min_hostgroup" binding:"omitempty,min=1,max=4096" example:"8" description:"Optional number of hosts in the first host group, like nmap's --min-hostgroup. Requires max_hostgroup and must not exceed it. Absent starts with a single host."`
+        // MaxHostGroup scans the targets in groups of at most this many hosts.
+        MaxHostGroup int `json:"max_hostgroup" binding:"omitempty,min=1,max=4096" example:"64" description:"Optional largest host group, like nmap's --max-hostgroup. The targets are then scanned group after group, the first of min_hostgroup hosts and each next one twice as large up to this size, so that only the hosts of one group are probed at a time, checks run per group and the results of a group are complete before the next starts. Together with max_parallelism, which caps the probes in flight against each host, it keeps large scans from flooding any one host or network. Absent scans all hosts as one group."`
+        // MaxRetries optionally sets how often a silent probe is sent again.
+        MaxRetries *int `json:"max_retries" binding:"omitempty,min=0,max=10" example:"1" description:"Optional number of times, from 0 to 10, a probe that drew no answer is sent again before the port is reported Filtered (Open|Filtered for udp). Absent uses the timing template's count (3, 2 and 1 for paranoid to polite, otherwise 0)."`
+        // RetryBackoff optionally sets the wait before a silent probe is sent again.
+        RetryBackoff string `json:"retry_backoff" example:"250ms" description:"Optional wait, as a Go duration up to 10s, before the first retransmission of a syn, fin, null, xmas, ack or udp probe that drew no answer. It doubles for every further retry, up to the largest probe timeout, so a brief burst of loss or a rate-limiting firewall has passed when the probe goes out again. Absent uses 100ms. Only matters with max_retries or a timing template that retries."`
+        // AllAddresses scans every resolved address of multi-homed hostnames.
+        AllAddresses bool `json:"all_addresses" example:"false" description:"Scan each A/AAAA record of a hostname separately instead of a single address. Results keep the hostname and add the probed address."`
+        // Prefer selects the address family scanned on dual-stack hostnames.
+        Prefer string `json:"prefer" binding:"omitempty,oneof=ipv4 ipv6 both" enums:"ipv4,ipv6,both" example:"both" description:"Address family scanned on hostnames with both A and AAAA records: ipv4 (the default) or ipv6 scan one address of that family, falling back to the other family when the host has none, and both scans one address of each family. With all_addresses, ipv4 and ipv6 keep only the addresses of that family. Every result reports its family. Raw packet modes probe IPv6 from the worker's global IPv6 address and report IPv6 targets as filtered when it has none."`
+        // IPVersion restricts the scan to one address family.
+        IPVersion int `json:"ip_version" binding:"omitempty,oneof=4 6" enums:"4,6" example:"6" description:"Optional address family to scan exclusively, like -4 and -6 of the CLI: hostnames resolve to A (4) or AAAA (6) records alone, with no fallback to the other family, and IP targets of the other family are skipped with a warning. Absent scans either family as prefer selects."`
+        // DNSServers pins the name servers targets are resolved with.
+        DNSServers []string `json:"dns_servers" binding:"omitempty,max=4" example:"10.0.0.53,1.1.1.1" description:"Optional name servers, up to 4 IP addresses with an optional port (default 53), that receive every forward and reverse DNS query of the scan instead of the worker's system resolver, tried in turn. Use it to scan with split-horizon internal names, or to avoid a resolver that filters or rewrites answers. Combine with all_addresses to scan every A/AAAA record; each result names the probed address."`
+        // VersionIntensity limits service detection to the less rare probes.
+        VersionIntensity *int `json:"version_intensity" binding:"omitempty,min=0,max=9" example:"7" description:"How many service probes a connect scan sends to each open port, from 0 to 9 (default 7). The NULL probe and the probes registered for the port are always sent; other probes are sent only when their rarity does not exceed this value, most common first. Lower values finish faster and are less noisy but identify fewer services."`
+        // Checks opts into deeper check modules for open ports.
+        Checks []string `json:"checks" example:"snmp" description:"Optional check modules to run against open ports: a module name, safe for every non-intrusive module, or all. Intrusive modules such as snmp try credentials and must be requested explicitly."`
+        // HTTPPaths replaces the paths the http check requests.
+        HTTPPaths []string `json:"http_paths" binding:"omitempty,max=20,dive,min=1,max=256" example:"/robots.txt,/admin/" description:"Optional paths, up to 20, the http check requests from every web service instead of /robots.txt, /.well-known/security.txt and /server-status. A missing leading slash is added. Requires the http check among checks."`
+        // Tags label the scan and its hosts in the inventory.
+        Tags []string `json:"tags" binding:"max=20,dive,min=1,max=64" example:"prod,dmz" description:"Optional labels for the scan. Every host the scan covers gets them in the inventory, so GET /hosts can filter by tag."`
+        // RDAP opts into network ownership lookups for public targets.
+        RDAP bool `json:"rdap" example:"false" description:"Look up netname, organization and abuse contact of every public target address via RDAP and attach them to host_summaries. Private addresses are never sent to the registry."`
+        // ResolvePTR opts into reverse DNS lookups of the probed addresses.
+        ResolvePTR bool `json:"resolve_ptr" example:"false" description:"Look up the PTR record of every IP target, and of every address probed for a hostname with all_addresses or prefer both, and report it as reverse_hostname on each result. Lookups use the worker's resolver, at most 16 at a time, each address once per task."`
+        // DetectTarpits opts into tarpit and honeypot detection.
+        DetectTarpits bool `json:"detect_tarpits" example:"false" description:"Flag hosts where an implausible share of the probed ports report open, or where in connect mode every open port accepts the connection but never answers a probe. Flagged hosts get a tarpit reason in host_summaries and a warning."`
+        // TarpitDowngrade keeps flagged hosts out of the inventory.
+        TarpitDowngrade bool `json:"tarpit_downgrade" example:"false" description:"Report the open ports of flagged hosts with state Tarpit instead of Open so likely tarpits do not pollute the inventory. Implies detect_tarpits."`
+        // Discovery skips hosts that do not answer a ping.
+        Discovery bool `json:"discovery" example:"false" description:"Ping every host before the port scan and skip the ones that do not answer: ARP for hosts on the worker's local subnet when it has raw packet access, otherwise ICMP echo and TCP connections to ports 443, 80 and 22. host_summaries lists each host with status up or down and the ping that decided it. Hosts that block all of these are missed, so leave it off (the default) when every host must be scanned."`
+        // Baseline names an earlier task of the same tenant to diff against.
+        Baseline string `json:"baseline" binding:"omitempty,uuid4" format:"uuid" example:"5b0e7c1a-9d2f-4e3b-8a6c-2f1d0e9b7a44" description:"Optional identifier of an earlier task to compare results with. On completion the worker records the ports that opened, closed or changed service in changes, and the completion webhook fires only when there is at least one change instead of on every identical run."`
+        // ReuseWithin opts into reusing recent results of other tasks.
+        ReuseWithin string `json:"reuse_within" example:"15m" description:"Optional maximum age, as a Go duration, of a result another scan of the caller produced for the same host and port. Such ports are not probed again; their result is copied and marked reused. Useful when overlapping scheduled scans would otherwise hit shared infrastructure repeatedly. Must not exceed the server's CORTEX_RESULT_REUSE_MAX."`
+        // CallbackURL receives the task once it completed or failed.
+        CallbackURL string `json:"callback_url" binding:"omitempty,url,max=2048" example:"https://ci.example.com/hooks/cortex" description:"Optional http or https URL the worker POSTs the finished task to, as the JSON of GET /scans/{id}, when the scan completed or failed. When the server has CORTEX_CALLBACK_SECRET the request carries X-Cortex-Timestamp and X-Cortex-Signature: sha256=<hex HMAC-SHA256 of the timestamp, a dot and the body>. Answers other than 2xx are retried with exponential backoff; the outcome is recorded in callback on the task. The host must resolve to public addresses only: loopback, private, link-local and blocked ranges are rejected, here and again whenever a delivery connects."`
+        // ShardSize splits large host lists into subtasks of at most this many hosts.
+        ShardSize int `json:"shard_size" binding:"omitempty,min=1" example:"256" description:"Optional maximum number of hosts per shard. When the scan lists more hosts, it is split into shard tasks that any worker can pick up, so one large scan uses the whole worker fleet. The returned task aggregates the shard results and completes when every shard has finished."`
+        // NoFallback opts out of the SYN to connect downgrade.
+        NoFallback bool `json:"no_fallback" example:"false" description:"By default a syn scan whose worker lacks raw packet privileges is downgraded to connect mode and a warning is recorded on the task. Set to true to fail the task instead."`
+}
+
+// ScanTarget is one entry of a target manifest.
+type ScanTarget struct {
+        // Host is the hostname or IP address to scan.
+        Host string `json:"host" binding:"required" example:"db1.example.com" description:"Hostname or IPv4/IPv6 address to scan."`
+        // Ports overrides the request's ports for this host.
+        Ports string `json:"ports,omitempty" example:"5432,6379" description:"Ports for this host as single ports and inclusive ranges separated by commas (e.g. 80,443,8000-8100). Absent uses the request's ports."`
+        // Mode overrides the request's scan mode for this host.
+        Mode string `json:"mode,omitempty" binding:"omitempty,oneof=connect syn udp fin null xmas ack" enums:"connect,syn,udp,fin,null,xmas,ack" example:"udp" description:"Scan mode for this host. Absent uses the request's mode. Entries of different modes are scanned as separate shards of the task."`
+        // Tags label this host in the inventory next to the request's tags.
+        Tags []string `json:"tags,omitempty" binding:"max=20,dive,min=1,max=64" example:"db" description:"Labels added to this host's inventory record in addition to the request's tags."`
 }
 
 // ScanAcceptedResponse captures the asynchronous acknowledgement returned after job submission.
@@ -46,8 +220,428 @@ type ScanAcceptedResponse struct {
         Status string `json:"status" enums:"pending" example:"pending" description:"Initial queue state assigned to every newly accepted scan request."`
 }
 
+// ScanStateResponse reports the status of a task after a pause, resume or cancel request.
+type ScanStateResponse struct {
+        // ID identifies the task the request addressed.
+        ID string `json:"id" format:"uuid" example:"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678" description:"Identifier of the paused, resumed or cancelled task."`
+        // Status is the state the task moved to.
+        Status string `json:"status" enums:"pausing,paused,running,pending,cancelling,cancelled" example:"pausing" description:"New state of the task. A running task reports pausing until its worker stops and paused afterwards; a resumed task is pending until a worker picks it up again, or running when its pause had not taken effect yet. A cancelled task reports cancelling until its worker stops and cancelled afterwards. For a sharded task it summarizes the shards."`
+}
+
+// ScanListResponse is one page of the tasks returned by GET /scans.
+type ScanListResponse struct {
+        // Count is the number of tasks on this page.
+        Count int `json:"count" example:"2" description:"Number of tasks in scans."`
+        // Scans lists the tasks of the page without their results.
+        Scans []ScanTask `json:"scans" description:"Matching tasks, newest first. Results, host summaries and changes are left out; fetch GET /scans/{id} for them."`
+        // NextOffset is the offset of the following page.
+        NextOffset *int `json:"next_offset,omitempty" example:"50" description:"Offset to request the next page with. Absent on the last page."`
+}
+
+// ScanPurgeResponse reports what a purge erased.
+type ScanPurgeResponse struct {
+        // ID identifies the purged task.
+        ID string `json:"id" format:"uuid" example:"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678" description:"Identifier of the purged task."`
+        // Results counts the erased port results.
+        Results int `json:"results" example:"1024" description:"Number of stored port results erased, including those of shards and the combined copy on a sharded task."`
+        // RecentResults counts the erased reuse cache entries.
+        RecentResults int `json:"recent_results" example:"1024" description:"Number of entries the task had left in the result reuse cache that were erased with it."`
+        // Tasks counts the deleted tasks of a full purge.
+        Tasks int `json:"tasks,omitempty" example:"3" description:"Number of tasks deleted by DELETE /scans/{id}: the task and each of its shards. Absent when only results were purged."`
+}
+
+// ScanEstimateResponse predicts the size and duration of a scan request without queueing it.
+type ScanEstimateResponse struct {
+        // Targets counts probe targets after expansion and blocklist filtering.
+        Targets int `json:"targets" example:"2" description:"Number of probe targets after expansion. With all_addresses every resolved address counts separately; blocked targets are excluded."`
+        // Ports counts the ports probed on each target.
+        Ports int `json:"ports" example:"1024" description:"Number of ports probed on each target."`
+        // Jobs is the total number of probes the scan would send.
+        Jobs int `json:"jobs" example:"2048" description:"Total number of probes, targets multiplied by ports."`
+        // PredictedSeconds is the expected wall-clock duration of the scan.
+        PredictedSeconds float64 `json:"predicted_seconds" example:"41.5" description:"Expected duration in seconds. History-based predictions run from submission to completion and so include typical queue wait; timing-based predictions cover probing only and assume filtered ports."`
+        // Basis names what the prediction was derived from.
+        Basis string `json:"basis" enums:"history,timing" example:"history" description:"history when recent completed scans of the same mode supplied a measured throughput, timing when the prediction comes from worker count, probe timeout and rate limits alone."`
+        // HistoricalThroughput is the measured probes per second of recent scans.
+        HistoricalThroughput float64 `json:"historical_throughput,omitempty" example:"49.3" description:"Probes per second achieved by recent completed scans of the same mode, measured from creation to completion."`
+        // Samples counts the completed scans the throughput was measured over.
+        Samples int `json:"samples,omitempty" example:"12" description:"Number of recent completed scans the historical throughput was measured over."`
+        // Warnings lists targets the scan would skip.
+        Warnings []string `json:"warnings,omitempty" example:"skipped scanme.nmap.org: 10.0.0.5 is in blocked range 10.0.0.0/8" description:"Targets that would be skipped, for example hostnames resolving into a blocked range."`
+}
+
+// ScanDiffResponse lists what changed between two completed scans.
+type ScanDiffResponse struct {
+        // Baseline identifies the earlier task.
+        Baseline string `json:"baseline" format:"uuid" example:"5b0e7c1a-9d2f-4e3b-8a6c-2f1d0e9b7a44" description:"Identifier of the task the comparison starts from, the id in the path."`
+        // Current identifies the later task.
+        Current string `json:"current" format:"uuid" example:"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678" description:"Identifier of the task compared against the baseline, the otherId in the path."`
+        // Opened counts ports open now that were not before.
+        Opened int `json:"opened" example:"1" description:"Number of ports open in the current scan that were not open in the baseline."`
+        // Closed counts ports no longer open.
+        Closed int `json:"closed" example:"0" description:"Number of ports open in the baseline that are not open in the current scan."`
+        // ServiceChanged counts open ports whose service changed.
+        ServiceChanged int `json:"service_changed" example:"2" description:"Number of ports open in both scans whose service fingerprint, product or version differs."`
+        // Changes lists every difference.
+        Changes []scanner.ResultChange `json:"changes" description:"Every port that opened, closed or changed service, sorted by host, address and port. Ports the current scan did not probe are ignored."`
+}
+
 // ErrorResponse provides a consistent structure for API error payloads.
 type ErrorResponse struct {
         // Error is a human-readable explanation of why the request failed.
         Error string `json:"error" example:"task not found" description:"Human readable error message describing why the request was rejected. The value is localized for operators rather than end users."`
 }
+
+// FieldError identifies a single request field that failed validation.
+type FieldError struct {
+        // Field is the JSON path of the offending field.
+        Field string `json:"field" example:"mode" description:"JSON path of the field that failed validation, for example hosts or hosts[2]. Empty when the body as a whole could not be parsed."`
+        // Rule names the validation rule that was violated.
+        Rule string `json:"rule" example:"oneof" description:"Machine-readable name of the violated rule such as required, min, oneof, type, json, or blocked."`
+        // Message explains the failure in plain language.
+        Message string `json:"message" example:"mode must be one of: connect syn udp" description:"Human readable explanation suitable for display next to the offending input."`
+}
+
+// ValidationErrorResponse is returned when a request body fails validation.
+type ValidationErrorResponse struct {
+        // Error summarizes the failure.
+        Error string `json:"error" example:"invalid request payload" description:"Short summary of the failure. Inspect details for per-field information."`
+        // Details lists every field-level problem that was detected.
+        Details []FieldError `json:"details" description:"One entry per failed field so clients can highlight exactly which inputs need attention."`
+}
+
+// QueueStatus describes the administrative state of the scan queue.
+type QueueStatus struct {
+        // Paused reports whether workers are currently prevented from taking tasks.
+        Paused bool `json:"paused" example:"true" description:"True while the queue is paused. New submissions are still accepted and wait in the queue until it is resumed."`
+        // PausedAt records when the queue was paused.
+        PausedAt *time.Time `json:"paused_at,omitempty" format:"date-time" example:"2024-01-02T15:04:05Z" description:"Timestamp (UTC, RFC3339 format) of the pause. Empty while the queue is running."`
+        // PausedBy names the credential that paused the queue.
+        PausedBy string `json:"paused_by,omitempty" example:"admin" description:"Name of the API key that paused the queue. Empty while the queue is running."`
+        // Depth is the number of tasks waiting to be picked up.
+        Depth int64 `json:"depth" example:"12" description:"Number of queued tasks waiting for a worker."`
+        // Pools breaks the queue down by scan mode.
+        Pools []QueuePool `json:"pools" description:"One entry per scan mode with its own queue and worker pool, so slow udp scans cannot starve connect scans."`
+}
+
+// QueuePool describes the queue and worker pool of one scan mode.
+type QueuePool struct {
+        // Mode is the scan mode served by the pool.
+        Mode string `json:"mode" enums:"connect,syn,udp" example:"udp" description:"Scan mode whose tasks the pool processes."`
+        // Workers is the pool size on the answering node.
+        Workers int `json:"workers" example:"2" description:"Number of workers in the pool on the node that answered the request, as set by CORTEX_WORKERS_<MODE>. Other nodes may run different sizes."`
+        // Depth is the number of tasks of this mode waiting to be picked up.
+        Depth int64 `json:"depth" example:"7" description:"Number of queued tasks of this mode waiting for a worker."`
+}
+
+// WorkerNode describes a process that takes scans from the queue: an API
+// server running worker pools or a standalone cortex --worker.
+type WorkerNode struct {
+        // ID identifies the node in the registry.
+        ID string `json:"id" example:"scanner-eu-1" description:"Identifier of the node, from CORTEX_WORKER_ID or derived from the hostname."`
+        // Hostname is the host the node runs on.
+        Hostname string `json:"hostname" example:"scanner-eu-1.internal" description:"Hostname of the machine running the node."`
+        // Role tells API servers from standalone workers.
+        Role string `json:"role" enums:"api,worker" example:"worker" description:"api for an API server running worker pools, worker for a standalone cortex --worker process."`
+        // Version is the build the node runs.
+        Version string `json:"version" example:"v1.4.0" description:"Cortex version of the node."`
+        // Capabilities lists the scan modes the node can run.
+        Capabilities []string `json:"capabilities" example:"connect,syn,udp" description:"Scan modes the node can run. syn and udp need raw packet privileges; a node without them runs no pool for those modes."`
+        // Workers is the pool size of every scan mode on the node.
+        Workers map[string]int `json:"workers" description:"Number of workers per scan mode on the node."`
+        // StartedAt is when the node started.
+        StartedAt time.Time `json:"started_at" format:"date-time" example:"2024-01-02T15:04:05Z" description:"Timestamp (UTC, RFC3339 format) the node started."`
+        // LastSeen is when the node last renewed its registration.
+        LastSeen time.Time `json:"last_seen" format:"date-time" example:"2024-01-02T15:10:05Z" description:"Timestamp (UTC, RFC3339 format) of the node's last heartbeat. Nodes silent for CORTEX_WORKER_STALE_AFTER drop out of the registry."`
+}
+
+// APIKey describes a bearer key managed through the admin API. The key itself
+// is only shown once, when it is created; the store keeps its SHA-256 hash.
+type APIKey struct {
+        // ID is the immutable identifier of the key (UUID v4).
+        ID string `json:"id" format:"uuid" example:"6b1d2f0e-7c3a-4e59-8f21-0a9b8c7d6e5f" description:"Identifier assigned when the key is created; use it to revoke the key."`
+        // Name labels the key in logs and audit fields.
+        Name string `json:"name" example:"ci-pipeline" description:"Label of the key, recorded as the owner of the scans it submits and in logs."`
+        // Prefix is the start of the key, to recognise it without the secret.
+        Prefix string `json:"prefix" example:"ctx_Yq3v9Kd1" description:"First characters of the key, enough to tell keys apart in configuration files without revealing them."`
+        // Namespace is the tenant the key acts for.
+        Namespace string `json:"namespace" example:"default" description:"Tenant namespace whose scans, monitors and inventory the key sees."`
+        // Role sets what the key may do.
+        Role string `json:"role" enums:"admin,submit,read-only" example:"submit" description:"admin may also call the /admin endpoints and delete scans, submit may create and control scans and monitors, read-only may only send GET requests."`
+        // ReadLimit and WriteLimit replace the rate limits of the key when set.
+        ReadLimit  int64 `json:"read_limit,omitempty" example:"600" description:"Read requests the key may send per rate limit window, instead of CORTEX_RATE_LIMIT_READ. The key then has a bucket of its own."`
+        WriteLimit int64 `json:"write_limit,omitempty" example:"20" description:"Write requests the key may send per rate limit window, instead of CORTEX_RATE_LIMIT_WRITE. The key then has a bucket of its own."`
+        // CreatedAt is when the key was created, and CreatedBy by whom.
+        CreatedAt time.Time `json:"created_at" format:"date-time" example:"2024-01-02T15:04:05Z" description:"Timestamp (UTC, RFC3339 format) the key was created."`
+        CreatedBy string `json:"created_by" example:"admin" description:"Name of the administrator who created the key."`
+        // RevokedAt is when the key was revoked; revoked keys are rejected.
+        RevokedAt *time.Time `json:"revoked_at,omitempty" format:"date-time" example:"2024-02-01T09:00:00Z" description:"Timestamp (UTC, RFC3339 format) the key was revoked. Revoked keys are kept for auditing but no longer authenticate."`
+}
+
+// CreateAPIKeyRequest describes a key to create.
+type CreateAPIKeyRequest struct {
+        // Name labels the key.
+        Name string `json:"name" binding:"required,max=200" example:"ci-pipeline" description:"Label of the key, recorded as the owner of the scans it submits and in logs."`
+        // Namespace is the tenant the key acts for.
+        Namespace string `json:"namespace" example:"default" description:"Tenant namespace of the key: 1-63 lowercase letters, digits, '-' or '_'. Defaults to default."`
+        // Role sets what the key may do.
+        Role string `json:"role" binding:"required,oneof=admin submit read-only" enums:"admin,submit,read-only" example:"submit" description:"admin may also call the /admin endpoints and delete scans, submit may create and control scans and monitors, read-only may only send GET requests."`
+        // ReadLimit and WriteLimit replace the rate limits of the key when set.
+        ReadLimit  int64 `json:"read_limit" binding:"min=0" example:"600" description:"Read requests per rate limit window for this key; 0 keeps CORTEX_RATE_LIMIT_READ."`
+        WriteLimit int64 `json:"write_limit" binding:"min=0" example:"20" description:"Write requests per rate limit window for this key; 0 keeps CORTEX_RATE_LIMIT_WRITE."`
+}
+
+// CreatedAPIKey is a newly created key together with its secret.
+type CreatedAPIKey struct {
+        APIKey
+        // Key is the bearer token; it cannot be retrieved again.
+        Key string `json:"key" example:"ctx_Yq3v9Kd1c8Zr2Lw0Hs5Nt7Bx4Ma6Pe1Vf9Gj3Qu0Ek" description:"The bearer token to send as Authorization: Bearer <key>. It is shown only in this response; store it safely."`
+}
+
+// Monitor declares the desired state of one asset and how often it is verified.
+type Monitor struct {
+        // ID is the immutable identifier of the monitor (UUID v4).
+        ID string `json:"id" format:"uuid" example:"0f8e3a6d-2c41-4b9e-9a57-3d6c1e2b4f80" description:"Identifier assigned when the monitor is created."`
+        // Name is an optional label for the asset.
+        Name string `json:"name,omitempty" example:"public web frontend" description:"Free-form label shown in drift reports."`
+        // Host is the asset being verified.
+        Host string `json:"host" example:"203.0.113.50" description:"Hostname or IP address of the monitored asset."`
+        // Ports are the ports covered by each verification scan.
+        Ports string `json:"ports" example:"1-1024" description:"Ports probed by every verification scan, as an nmap-style port specification such as 22,80,443 or 1-1024."`
+        // Mode is the scan mode used for verification scans.
+        Mode string `json:"mode" enums:"connect,syn,udp" example:"connect" description:"Scanner transport mode used by verification scans."`
+        // ExpectedOpen lists the ports that should be open.
+        ExpectedOpen []int `json:"expected_open" example:"80,443" description:"Ports that are expected to be open. Every other monitored port is expected to be closed or filtered."`
+        // Interval is how often the asset is verified.
+        Interval string `json:"interval" example:"1h" description:"Delay between verification scans as a Go duration."`
+        // CreatedAt records when the monitor was created.
+        CreatedAt time.Time `json:"created_at" format:"date-time" example:"2024-01-02T15:04:05Z" description:"Timestamp (UTC, RFC3339 format) when the monitor was created."`
+        // LastTaskID is the most recent verification scan.
+        LastTaskID string `json:"last_task_id,omitempty" format:"uuid" example:"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678" description:"Identifier of the most recently scheduled verification scan. Poll GET /scans/{id} for its results."`
+        // LastRunAt records when the last verification scan was queued.
+        LastRunAt *time.Time `json:"last_run_at,omitempty" format:"date-time" example:"2024-01-02T16:00:00Z" description:"Timestamp (UTC, RFC3339 format) when the last verification scan was queued."`
+        // CheckedAt records when the last verification scan finished.
+        CheckedAt *time.Time `json:"checked_at,omitempty" format:"date-time" example:"2024-01-02T16:01:12Z" description:"Timestamp (UTC, RFC3339 format) when the last verification result was evaluated. Empty until the first scan finishes."`
+        // Compliant reports whether the last verification matched the desired state.
+        Compliant *bool `json:"compliant,omitempty" example:"false" description:"True when the last verification scan found exactly the expected open ports. Absent until the first scan finishes or when it failed."`
+        // Unexpected lists ports found open that are not expected.
+        Unexpected []int `json:"unexpected,omitempty" example:"3389" description:"Ports open in the last verification scan that are not in expected_open."`
+        // Missing lists expected ports that were not open.
+        Missing []int `json:"missing,omitempty" example:"443" description:"Ports in expected_open that were not open in the last verification scan."`
+        // Error explains why the last verification could not be evaluated.
+        Error string `json:"error,omitempty" example:"insufficient privileges for raw packet access" description:"Reason the last verification scan failed. Compliance is unknown while set."`
+}
+
+// CreateMonitorRequest is the payload for creating monitors.
+type CreateMonitorRequest struct {
+        // Name is an optional label for the asset.
+        Name string `json:"name" binding:"max=200" example:"public web frontend" description:"Optional free-form label shown in drift reports."`
+        // Host is the asset to verify.
+        Host string `json:"host" binding:"required" example:"203.0.113.50" description:"Hostname or IP address of the asset. IP literals in a blocked range are rejected."`
+        // Ports are the ports covered by each verification scan.
+        Ports string `json:"ports" binding:"required" example:"1-1024" description:"Ports probed by every verification scan, as an nmap-style port specification such as 22,80,443 or 1-1024. It must contain every expected open port."`
+        // Mode selects the scan mode for verification scans.
+        Mode string `json:"mode" binding:"required,oneof=connect syn udp" enums:"connect,syn,udp" example:"connect" description:"Scanner transport mode used by verification scans."`
+        // ExpectedOpen lists the ports that should be open.
+        ExpectedOpen []int `json:"expected_open" binding:"dive,min=0,max=65535" example:"80,443" description:"Ports expected to be open. An empty list declares that nothing in the range should be listening."`
+        // Interval is how often the asset is verified.
+        Interval string `json:"interval" binding:"required" example:"1h" description:"Delay between verification scans as a Go duration; at least 1m."`
+}
+
+// ScanTemplate is a saved scan configuration requests can reference by ID.
+type ScanTemplate struct {
+        // ID is the immutable identifier of the template (UUID v4).
+        ID string `json:"id" format:"uuid" example:"3c9d2e1f-7a4b-4c5d-8e6f-1a2b3c4d5e6f" description:"Identifier to pass as template_id in POST /scans."`
+        // Name labels the template.
+        Name string `json:"name" example:"web-servers" description:"Name of the template."`
+        // Description says what the template is for.
+        Description string `json:"description,omitempty" example:"Web ports with HTTP and TLS checks" description:"Free-form description of the template."`
+        // Scan holds the saved fields of the scan request.
+        Scan CreateScanRequest `json:"scan" description:"Saved scan request fields, such as ports, mode, timing and detection options. Fields left out here must be given by the requests that use the template."`
+        // Owner names the API key or JWT subject that created the template.
+        Owner string `json:"owner,omitempty" example:"default" description:"Name of the API key, or subject of the JWT, that created the template."`
+        // CreatedAt records when the template was created.
+        CreatedAt time.Time `json:"created_at" format:"date-time" example:"2024-01-02T15:04:05Z" description:"Timestamp (UTC, RFC3339 format) when the template was created."`
+}
+
+// CreateTemplateRequest is the payload for creating scan templates.
+type CreateTemplateRequest struct {
+        // Name labels the template.
+        Name string `json:"name" binding:"required,max=200" example:"web-servers" description:"Name of the template."`
+        // Description says what the template is for.
+        Description string `json:"description" binding:"max=2000" example:"Web ports with HTTP and TLS checks" description:"Optional free-form description."`
+        // Scan holds the fields to save.
+        Scan CreateScanRequest `json:"scan" binding:"-" description:"Scan request fields to save, in the form of a POST /scans body. Every field is optional, but those that are given are validated as in POST /scans. template_id is not accepted."`
+}
+
+// Schedule queues a scan from a template whenever its cron expression matches.
+type Schedule struct {
+        // ID is the immutable identifier of the schedule (UUID v4).
+        ID string `json:"id" format:"uuid" example:"6e1f4c2a-8b3d-4a5e-9f60-7d2c1b0a9e83" description:"Identifier assigned when the schedule is created."`
+        // Name is an optional label.
+        Name string `json:"name,omitempty" example:"nightly dmz sweep" description:"Free-form label of the schedule."`
+        // Cron is the expression the scheduler follows.
+        Cron string `json:"cron" example:"0 2 * * *" description:"Standard five-field cron expression (minute hour day-of-month month day-of-week) or a macro such as @daily or @hourly."`
+        // Timezone is the zone the cron expression is evaluated in.
+        Timezone string `json:"timezone" example:"Europe/Kyiv" description:"IANA time zone the cron expression is evaluated in."`
+        // Scan is the template of every queued scan.
+        Scan CreateScanRequest `json:"scan" description:"Scan request queued at every run, as accepted by POST /scans, with hosts expanded and top_ports resolved."`
+        // Paused stops the schedule from queueing scans.
+        Paused bool `json:"paused" example:"false" description:"True while the schedule is paused with POST /schedules/{id}/pause."`
+        // Owner names the API key or JWT subject that created the schedule.
+        Owner string `json:"owner,omitempty" example:"default" description:"Name of the API key, or subject of the JWT, that created the schedule. Queued scans are submitted in its name."`
+        // CreatedAt records when the schedule was created.
+        CreatedAt time.Time `json:"created_at" format:"date-time" example:"2024-01-02T15:04:05Z" description:"Timestamp (UTC, RFC3339 format) when the schedule was created."`
+        // NextRunAt is when the scheduler queues the next scan.
+        NextRunAt *time.Time `json:"next_run_at,omitempty" format:"date-time" example:"2024-01-03T00:00:00Z" description:"Timestamp (UTC, RFC3339 format) of the next run. Absent while the schedule is paused."`
+        // LastRunAt records when the last scan was queued.
+        LastRunAt *time.Time `json:"last_run_at,omitempty" format:"date-time" example:"2024-01-02T00:00:00Z" description:"Timestamp (UTC, RFC3339 format) when the schedule last came due."`
+        // LastTaskID is the most recently queued scan.
+        LastTaskID string `json:"last_task_id,omitempty" format:"uuid" example:"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678" description:"Identifier of the most recently queued scan. Poll GET /scans/{id} for its results."`
+        // LastError explains why the last run queued no scan.
+        LastError string `json:"last_error,omitempty" example:"previous run a3f5c62e-1234-4f72-a84a-1c2d3e4f5678 is still running" description:"Reason the last run did not queue a scan, such as the previous scan still running. Empty when it did."`
+}
+
+// CreateScheduleRequest is the payload for creating schedules.
+type CreateScheduleRequest struct {
+        // Name is an optional label.
+        Name string `json:"name" binding:"max=200" example:"nightly dmz sweep" description:"Optional free-form label of the schedule."`
+        // Cron is the expression the scheduler follows.
+        Cron string `json:"cron" binding:"required" example:"0 2 * * *" description:"Standard five-field cron expression (minute hour day-of-month month day-of-week) or a macro: @yearly, @monthly, @weekly, @daily or @hourly. Fields take *, values, ranges (1-5), lists (1,15), steps (*/15) and, for months and days of the week, three-letter names."`
+        // Timezone is the zone the cron expression is evaluated in.
+        Timezone string `json:"timezone" example:"Europe/Kyiv" description:"IANA time zone the cron expression is evaluated in. Defaults to UTC."`
+        // Scan is the template of every queued scan.
+        Scan CreateScanRequest `json:"scan" binding:"required" description:"Scan request to queue at every run, validated like a POST /scans body. baseline is not accepted: every run is compared with the previous completed run of the schedule instead."`
+}
+
+// DriftReport lists monitors whose last verification did not match the desired state.
+type DriftReport struct {
+        // Monitors counts every monitor of the caller.
+        Monitors int `json:"monitors" example:"12" description:"Number of monitors defined by the caller."`
+        // Unchecked counts monitors without an evaluated verification scan.
+        Unchecked int `json:"unchecked" example:"1" description:"Monitors whose compliance is unknown because no verification scan has finished yet or the last one failed."`
+        // Drifted lists the monitors out of compliance.
+        Drifted []Monitor `json:"drifted" description:"Monitors whose last verification scan found unexpected open ports or missing expected ones."`
+}
+
+// InventoryHost is the aggregated state of one host across every completed scan.
+type InventoryHost struct {
+        // Host is the target as submitted in scans.
+        Host string `json:"host" example:"scanme.nmap.org" description:"Target host as submitted in scans."`
+        // Addresses lists the probed addresses of multi-homed hosts.
+        Addresses []string `json:"addresses,omitempty" example:"45.33.32.156" description:"Addresses probed when scans covered every address of the hostname."`
+        // FirstSeen records when the host was first scanned.
+        FirstSeen time.Time `json:"first_seen" format:"date-time" example:"2024-01-02T15:04:05Z" description:"Completion time of the first scan that covered the host."`
+        // LastSeen records when the host was last scanned.
+        LastSeen time.Time `json:"last_seen" format:"date-time" example:"2024-01-09T15:04:05Z" description:"Completion time of the latest scan that covered the host."`
+        // LastTaskID is the latest scan that covered the host.
+        LastTaskID string `json:"last_task_id" format:"uuid" example:"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678" description:"Identifier of the latest scan that covered the host."`
+        // Tags collects the tags of every scan that covered the host.
+        Tags []string `json:"tags,omitempty" example:"prod" description:"Union of the tags of every scan that covered the host, sorted."`
+        // Ports lists the ports currently known to be open.
+        Ports []InventoryPort `json:"ports" description:"Ports open in the latest scan that probed them, sorted by protocol and port. A port disappears once a later scan finds it closed or filtered; ports outside a scan's range are left untouched."`
+}
+
+// InventoryPort is an open port of an inventory host.
+type InventoryPort struct {
+        // Port is the open port number.
+        Port int `json:"port" example:"22" description:"Open port number."`
+        // Protocol is tcp or udp.
+        Protocol string `json:"protocol" enums:"tcp,udp" example:"tcp" description:"Transport protocol the port was found open on."`
+        // Service is the latest service fingerprint.
+        Service string `json:"service,omitempty" example:"ssh" description:"Service fingerprint from the latest scan that found the port open."`
+        // Product is the latest detected product.
+        Product string `json:"product,omitempty" example:"OpenSSH" description:"Product detected by the latest scan that found the port open."`
+        // Version is the latest detected product version.
+        Version string `json:"version,omitempty" example:"8.2p1" description:"Product version detected by the latest scan that found the port open."`
+        // FirstSeen records when the port was first found open.
+        FirstSeen time.Time `json:"first_seen" format:"date-time" example:"2024-01-02T15:04:05Z" description:"Completion time of the first scan that found the port open since it was last seen closed."`
+        // LastSeen records when the port was last found open.
+        LastSeen time.Time `json:"last_seen" format:"date-time" example:"2024-01-09T15:04:05Z" description:"Completion time of the latest scan that found the port open."`
+}
+
+// HostListResponse is the filtered list of inventory hosts returned by GET /hosts.
+type HostListResponse struct {
+        // Count is the number of hosts that matched the filters.
+        Count int `json:"count" example:"3" description:"Number of hosts matching every filter."`
+        // Hosts lists the matching inventory records.
+        Hosts []InventoryHost `json:"hosts" description:"Matching hosts sorted by name, each with all of its currently open ports and the time of its latest scan (last_seen)."`
+}
+
+// ServiceListResponse groups inventory ports by detected service, product and version.
+type ServiceListResponse struct {
+        // Count is the number of service groups that matched the filters.
+        Count int `json:"count" example:"2" description:"Number of service groups matching every filter."`
+        // Services lists the matching groups.
+        Services []ServiceGroup `json:"services" description:"Service groups sorted by service, product and version."`
+}
+
+// ServiceGroup is every open port running the same service, product and version.
+type ServiceGroup struct {
+        // Service is the detected service name.
+        Service string `json:"service" example:"ssh" description:"Detected service name, or the raw banner when no probe rule matched."`
+        // Product is the detected product.
+        Product string `json:"product,omitempty" example:"OpenSSH" description:"Detected product. Empty when the probe rule did not name one."`
+        // Version is the detected product version.
+        Version string `json:"version,omitempty" example:"8.2p1" description:"Detected product version. Empty when unknown."`
+        // HostCount is the number of distinct hosts in the group.
+        HostCount int `json:"host_count" example:"14" description:"Number of distinct hosts running this service."`
+        // Endpoints lists every host and port in the group.
+        Endpoints []ServiceEndpoint `json:"endpoints" description:"Every host and port running this service, sorted by host and port."`
+}
+
+// ServiceEndpoint is one open port of a service group.
+type ServiceEndpoint struct {
+        // Host is the inventory host.
+        Host string `json:"host" example:"203.0.113.50" description:"Inventory host running the service."`
+        // Port is the open port.
+        Port int `json:"port" example:"22" description:"Open port the service answered on."`
+        // Protocol is tcp or udp.
+        Protocol string `json:"protocol" enums:"tcp,udp" example:"tcp" description:"Transport protocol of the port."`
+        // LastSeen records when the port was last found open.
+        LastSeen time.Time `json:"last_seen" format:"date-time" example:"2024-01-09T15:04:05Z" description:"Completion time of the latest scan that found the port open."`
+}
+
+// StatsResponse summarizes scan activity over a reporting window.
+type StatsResponse struct {
+        // From is the start of the reporting window.
+        From time.Time `json:"from" format:"date-time" example:"2024-01-02T00:00:00Z" description:"Start of the window (UTC midnight, inclusive)."`
+        // To is the end of the reporting window.
+        To time.Time `json:"to" format:"date-time" example:"2024-01-09T15:04:05Z" description:"End of the window, the time of the request."`
+        // Scans counts the tasks created in the window.
+        Scans int `json:"scans" example:"120" description:"Tasks created in the window, in any status."`
+        // Completed counts the tasks that completed.
+        Completed int `json:"completed" example:"112" description:"Tasks that completed successfully."`
+        // Failed counts the tasks that failed.
+        Failed int `json:"failed" example:"6" description:"Tasks that failed."`
+        // FailureRate is the share of finished tasks that failed.
+        FailureRate float64 `json:"failure_rate" example:"0.05" description:"Failed tasks divided by finished (completed or failed) tasks; 0 when none finished."`
+        // AverageDurationSeconds is the mean time from submission to completion.
+        AverageDurationSeconds float64 `json:"average_duration_seconds" example:"42.7" description:"Mean seconds from submission to completion of finished tasks, queue wait included."`
+        // Days breaks the window down per calendar day.
+        Days []DailyStats `json:"days" description:"One entry per UTC day of the window, oldest first, including days without scans."`
+        // TopServices lists the most frequently discovered services.
+        TopServices []ServiceCount `json:"top_services" description:"Services found on open ports by completed tasks in the window, most frequent first."`
+}
+
+// DailyStats summarizes the scans created on one UTC day.
+type DailyStats struct {
+        // Date is the UTC day.
+        Date string `json:"date" example:"2024-01-08" description:"UTC calendar day as YYYY-MM-DD."`
+        // Scans counts the tasks created that day.
+        Scans int `json:"scans" example:"17" description:"Tasks created that day."`
+        // Completed counts those tasks that completed.
+        Completed int `json:"completed" example:"16" description:"Tasks created that day that completed."`
+        // Failed counts those tasks that failed.
+        Failed int `json:"failed" example:"1" description:"Tasks created that day that failed."`
+        // AverageDurationSeconds is the mean time from submission to completion.
+        AverageDurationSeconds float64 `json:"average_duration_seconds" example:"39.2" description:"Mean seconds from submission to completion of the day's finished tasks."`
+}
+
+// ServiceCount is how often a service was found open.
+type ServiceCount struct {
+        // Service is the detected service name.
+        Service string `json:"service" example:"http" description:"Detected service name."`
+        // Count is the number of open ports running it.
+        Count int `json:"count" example:"86" description:"Open ports running the service across completed tasks in the window."`
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gopkg.in/yaml.v3"
+)
+
+// @Summary      Create a scan from an uploaded target file
+// @Description  Submit a scan whose targets come from a file sent as multipart/form-data, for host lists too large to embed comfortably in a JSON body. The scan is validated, persisted and queued exactly like POST /scans.
+// @Description  **Target file**: the targets part is either a plain list with one host per line (blank lines and # comments are ignored) or a target manifest in YAML or JSON, given as a list of entries or as an object with a targets list. Manifest entries take the fields of the targets request property: host, ports, mode and tags.
+// @Description  **Options**: the optional options part holds every other CreateScanRequest property as a JSON object, e.g. {"ports":"1-1024","mode":"connect"}. hosts and targets are not allowed there. The body limit of this endpoint is CORTEX_MAX_UPLOAD_BYTES instead of CORTEX_MAX_BODY_BYTES.
+// @Tags         Scans
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        targets  formData  file    true   "Target file: plain host list or YAML/JSON manifest"
+// @Param        options  formData  string  false  "Scan options as a JSON CreateScanRequest without hosts and targets"
+// @Success      202      {object}  ScanAcceptedResponse     "Scan accepted. Poll GET /scans/{id} to track progress. Example: {\"id\":\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\",\"status\":\"pending\"}"
+// @Failure      400      {object}  ValidationErrorResponse  "Missing or unreadable target file, malformed options or failed validation. Example: {\"error\":\"invalid request payload\",\"details\":[{\"field\":\"targets\",\"rule\":\"required\",\"message\":\"targets file is required\"}]}"
+// @Failure      401      {object}  ErrorResponse            "Missing or incorrect API key. Example: {\"error\":\"unauthorized\"}"
+// @Failure      413      {object}  ErrorResponse            "Request body larger than the configured upload limit. Example: {\"error\":\"request body exceeds the 16777216 byte limit\"}"
+// @Failure      429      {object}  ErrorResponse            "Rate limit exceeded for the calling client. Example: {\"error\":\"rate limit exceeded\"}"
+// @Failure      500      {object}  ErrorResponse            "Internal error while persisting or queueing the task. Example: {\"error\":\"failed to persist task\"}"
+// @Security     ApiKeyAuth
+// @Router       /scans/upload [post]
+func (s *Server) uploadScanHandler(c *gin.Context) {
+	var req CreateScanRequest
+	if !s.bindUploadRequest(c, &req) {
+		return
+	}
+	s.submitScan(c, &req)
+}
+
+// bindUploadRequest decodes the options and target file of a multipart scan
+// submission into req and validates it like a JSON one, writing the error
+// response and returning false when the request is rejected.
+func (s *Server) bindUploadRequest(c *gin.Context, req *CreateScanRequest) bool {
+	reject := func(field, rule, message string) bool {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+			Error:   "invalid request payload",
+			Details: []FieldError{{Field: field, Rule: rule, Message: message}},
+		})
+		return false
+	}
+
+	header, err := c.FormFile("targets")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			abortBodyTooLarge(c, tooLarge.Limit)
+			return false
+		}
+		return reject("targets", "required", "targets file is required")
+	}
+
+	if options := c.PostForm("options"); strings.TrimSpace(options) != "" {
+		if err := json.Unmarshal([]byte(options), req); err != nil {
+			details := fieldErrors(err)
+			for i := range details {
+				details[i].Message = "options: " + details[i].Message
+			}
+			c.JSON(http.StatusBadRequest, ValidationErrorResponse{Error: "invalid request payload", Details: details})
+			return false
+		}
+		if len(req.Hosts) > 0 || len(req.Targets) > 0 {
+			return reject("options", "excluded_with", "hosts and targets come from the uploaded file and cannot be set in options")
+		}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return reject("targets", "format", fmt.Sprintf("cannot read targets file: %v", err))
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return reject("targets", "format", fmt.Sprintf("cannot read targets file: %v", err))
+	}
+	hosts, targets, err := parseTargetFile(data)
+	if err != nil {
+		return reject("targets", "format", err.Error())
+	}
+	if len(hosts) == 0 && len(targets) == 0 {
+		return reject("targets", "required", "targets file lists no hosts")
+	}
+	req.Hosts, req.Targets = hosts, targets
+
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		c.JSON(http.StatusBadRequest, newValidationErrorResponse(err))
+		return false
+	}
+	return s.checkScanRequest(c, req)
+}
+
+// parseTargetFile reads an uploaded target file. A YAML or JSON document
+// holding a list or an object is a manifest and yields targets; anything else
+// is a plain list and yields hosts, one per line.
+func parseTargetFile(data []byte) ([]string, []ScanTarget, error) {
+	var shape interface{}
+	if err := yaml.Unmarshal(data, &shape); err == nil {
+		switch shape.(type) {
+		case []interface{}, map[string]interface{}:
+			targets, err := parseTargetManifest(data, shape)
+			return nil, targets, err
+		}
+	}
+
+	var hosts []string
+	lines := bufio.NewScanner(bytes.NewReader(data))
+	lines.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for lines.Scan() {
+		line, _, _ := strings.Cut(lines.Text(), "#")
+		hosts = append(hosts, strings.Fields(line)...)
+	}
+	if err := lines.Err(); err != nil {
+		return nil, nil, fmt.Errorf("cannot read targets file: %w", err)
+	}
+	return hosts, nil, nil
+}
+
+// parseTargetManifest decodes a manifest strictly, so that misspelled keys are
+// reported instead of silently ignored.
+func parseTargetManifest(data []byte, shape interface{}) ([]ScanTarget, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if _, isList := shape.([]interface{}); isList {
+		var targets []ScanTarget
+		if err := decoder.Decode(&targets); err != nil {
+			return nil, fmt.Errorf("invalid targets manifest: %w", err)
+		}
+		return targets, nil
+	}
+	var wrapped struct {
+		Targets []ScanTarget `yaml:"targets"`
+	}
+	if err := decoder.Decode(&wrapped); err != nil {
+		return nil, fmt.Errorf("invalid targets manifest: %w", err)
+	}
+	return wrapped.Targets, nil
+}
@@ -2,33 +2,40 @@ package api
 
 import (
 	"fmt"
-	"strconv"
-	"strings"
-)
+	"time"
 
-func parsePortRange(portRange string) (int, int, error) {
-	parts := strings.Split(portRange, "-")
-	if len(parts) != 2 {
-		return 0, 0, fmt.Errorf("invalid port range format. Use startPort-endPort")
-	}
+	"github.com/Viktor25104/cortex/backend/scanner"
+)
 
-	startPort, err := strconv.Atoi(parts[0])
+// parsePorts returns the ports an nmap-style port specification selects for
+// the protocol mode scans.
+func parsePorts(spec, mode string) ([]int, error) {
+	parsed, err := scanner.ParsePortSpec(spec)
 	if err != nil {
-		return 0, 0, fmt.Errorf("start port is not a number: %s", parts[0])
+		return nil, err
 	}
-
-	endPort, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return 0, 0, fmt.Errorf("end port is not a number: %s", parts[1])
+	protocol := modeProtocol(mode)
+	ports := parsed.Ports(protocol)
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("port specification %s selects no %s ports", spec, protocol)
 	}
+	return ports, nil
+}
 
-	if startPort < 0 || startPort > 65535 || endPort < 0 || endPort > 65535 {
-		return 0, 0, fmt.Errorf("ports must be within 0-65535 range")
+// scanTiming returns the timing template named by timing with the
+// parallelism, retries and retry backoff a request overrides. Requests are
+// validated against the template names and durations, so an unknown name
+// falls back to normal and an invalid backoff to the default.
+func scanTiming(timing string, maxParallelism int, maxRetries *int, retryBackoff string) scanner.Timing {
+	parsed, _ := scanner.ParseTiming(timing)
+	if maxParallelism > 0 {
+		parsed.MaxParallelism = maxParallelism
 	}
-
-	if startPort > endPort {
-		return 0, 0, fmt.Errorf("start port must be less than or equal to end port")
+	if maxRetries != nil {
+		parsed.MaxRetries = *maxRetries
 	}
-
-	return startPort, endPort, nil
+	if backoff, err := time.ParseDuration(retryBackoff); err == nil {
+		parsed.RetryBackoff = backoff
+	}
+	return parsed
 }
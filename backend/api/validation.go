@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+var registerTagNamesOnce sync.Once
+
+// registerJSONTagNames makes validator report JSON field names (hosts, ports,
+// mode) instead of Go struct field names so errors match the request payload.
+func registerJSONTagNames() {
+	registerTagNamesOnce.Do(func() {
+		engine, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+		engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	})
+}
+
+// newValidationErrorResponse converts a binding error into a response listing
+// every offending field together with the rule it violated.
+func newValidationErrorResponse(err error) ValidationErrorResponse {
+	return ValidationErrorResponse{
+		Error:   "invalid request payload",
+		Details: fieldErrors(err),
+	}
+}
+
+// fieldErrors maps binding and decoding errors to FieldError entries.
+func fieldErrors(err error) []FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		details := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			details = append(details, FieldError{
+				Field:   fieldPath(fe),
+				Rule:    fe.Tag(),
+				Message: validationMessage(fe),
+			})
+		}
+		return details
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("%s must be of type %s", typeErr.Field, typeErr.Type.String()),
+		}}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return []FieldError{{
+			Rule:    "json",
+			Message: fmt.Sprintf("malformed JSON at offset %d: %v", syntaxErr.Offset, syntaxErr),
+		}}
+	}
+
+	return []FieldError{{Rule: "json", Message: err.Error()}}
+}
+
+// fieldPath strips the top-level struct name from the validator namespace,
+// turning "CreateScanRequest.hosts[0]" into "hosts[0]".
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if idx := strings.Index(namespace, "."); idx >= 0 {
+		return namespace[idx+1:]
+	}
+	return fe.Field()
+}
+
+// validationMessage renders a human readable explanation for a failed rule.
+func validationMessage(fe validator.FieldError) string {
+	field := fieldPath(fe)
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "required_without":
+		return fmt.Sprintf("%s is required unless %s is given", field, strings.ToLower(fe.Param()))
+	case "required_without_all":
+		names := strings.Fields(fe.Param())
+		for i, name := range names {
+			names[i] = snakeCase(name)
+		}
+		return fmt.Sprintf("%s is required unless %s is given", field, strings.Join(names, " or "))
+	case "excluded_with":
+		return fmt.Sprintf("%s cannot be combined with %s", field, strings.ToLower(fe.Param()))
+	case "min":
+		if fe.Kind() == reflect.Slice || fe.Kind() == reflect.String {
+			return fmt.Sprintf("%s must contain at least %s item(s)", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s", field, fe.Param())
+	case "max":
+		if fe.Kind() == reflect.Slice || fe.Kind() == reflect.String {
+			return fmt.Sprintf("%s must contain at most %s item(s)", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s", field, fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, fe.Param())
+	case "uuid4":
+		return fmt.Sprintf("%s must be a UUID v4 task identifier", field)
+	default:
+		return fmt.Sprintf("%s failed the %s validation", field, fe.Tag())
+	}
+}
+
+// snakeCase turns a Go field name such as TopPorts into its JSON name.
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -1,117 +1,787 @@
 package api
 
 import (
-	"strings"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"cortex/logging"
-	"cortex/scanner"
+	"github.com/Viktor25104/cortex/backend/logging"
+	"github.com/Viktor25104/cortex/backend/scanner"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var (
-	synInitOnce sync.Once
-	synInitErr  error
+// WorkerConfig sizes the worker pool of each scan mode. Every pool takes
+// tasks only from the queue of its mode, so a backlog of slow UDP scans
+// cannot hold up connect scans. A size of zero leaves that mode to other nodes.
+type WorkerConfig struct {
+	Connect int
+	Syn     int
+	UDP     int
+	// ResultTTL is how long probe results are kept for tasks that ask to
+	// reuse recent results, and the largest reuse_within they may ask for.
+	// Zero disables reuse.
+	ResultTTL time.Duration
+	// MaxRunningPerKey caps the tasks of one API key that run at the same
+	// time across all nodes; further tasks are held until one finishes.
+	// Zero means no cap.
+	MaxRunningPerKey int
+	// HeartbeatInterval is how often a worker reports that it is still
+	// working on its task, and StaleAfter how long without a heartbeat
+	// before the task is taken from it and queued again.
+	HeartbeatInterval time.Duration
+	StaleAfter        time.Duration
+}
 
-	udpInitOnce sync.Once
-	udpInitErr  error
-)
+// Size returns the pool size configured for mode.
+func (cfg WorkerConfig) Size(mode scanner.Mode) int {
+	switch mode {
+	case scanner.ModeSyn:
+		return cfg.Syn
+	case scanner.ModeUDP:
+		return cfg.UDP
+	default:
+		return cfg.Connect
+	}
+}
+
+// loadWorkerConfig reads the pool sizes from the environment:
+// CORTEX_WORKERS_CONNECT (default 5), CORTEX_WORKERS_SYN (default 2) and
+// CORTEX_WORKERS_UDP (default 2). SYN and UDP scans hold a capture handle or
+// socket per probe for the full timeout, so their pools are smaller.
+// CORTEX_RESULT_REUSE_MAX (Go duration, default 0) enables result reuse and
+// CORTEX_MAX_RUNNING_PER_KEY (default 0) caps the running tasks per API key.
+func loadWorkerConfig() (WorkerConfig, error) {
+	cfg := WorkerConfig{Connect: 5, Syn: 2, UDP: 2}
+	maxRunning, err := getenvInt("CORTEX_MAX_RUNNING_PER_KEY", 0)
+	if err != nil {
+		return cfg, err
+	}
+	if maxRunning < 0 {
+		return cfg, fmt.Errorf("CORTEX_MAX_RUNNING_PER_KEY must not be negative")
+	}
+	cfg.MaxRunningPerKey = int(maxRunning)
+	if cfg.ResultTTL, err = getenvDuration("CORTEX_RESULT_REUSE_MAX", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.ResultTTL < 0 {
+		return cfg, fmt.Errorf("CORTEX_RESULT_REUSE_MAX must not be negative")
+	}
+	if cfg.HeartbeatInterval, err = getenvDuration("CORTEX_WORKER_HEARTBEAT", 10*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.StaleAfter, err = getenvDuration("CORTEX_WORKER_STALE_AFTER", time.Minute); err != nil {
+		return cfg, err
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		return cfg, fmt.Errorf("CORTEX_WORKER_HEARTBEAT must be positive")
+	}
+	if cfg.StaleAfter < 2*cfg.HeartbeatInterval {
+		return cfg, fmt.Errorf("CORTEX_WORKER_STALE_AFTER must be at least twice CORTEX_WORKER_HEARTBEAT")
+	}
+	for _, pool := range []struct {
+		key  string
+		size *int
+	}{
+		{"CORTEX_WORKERS_CONNECT", &cfg.Connect},
+		{"CORTEX_WORKERS_SYN", &cfg.Syn},
+		{"CORTEX_WORKERS_UDP", &cfg.UDP},
+	} {
+		size, err := getenvInt(pool.key, int64(*pool.size))
+		if err != nil {
+			return cfg, err
+		}
+		if size < 0 {
+			return cfg, fmt.Errorf("%s must not be negative", pool.key)
+		}
+		*pool.size = int(size)
+	}
+	return cfg, nil
+}
 
-// StartWorkers launches background goroutines that process scan tasks.
-func StartWorkers(store TaskStore, probeCache *scanner.ProbeCache, numWorkers int) {
-	for i := 0; i < numWorkers; i++ {
-		go workerLoop(store, probeCache)
+// StartWorkers launches one pool of background goroutines per scan mode,
+// sized by cfg. Targets inside blocklist are skipped and reported as task
+// warnings. Completed tasks are reported through notifier and every finished
+// run through metrics; both may be nil. Every probe is paced by pacer when it
+// is set, typically to share a rate across nodes.
+func StartWorkers(store TaskStore, probeCache *scanner.ProbeCache, blocklist *scanner.Blocklist, notifier *Notifier, metrics *Metrics, pacer scanner.Pacer, cfg WorkerConfig) *WorkerPools {
+	pools := &WorkerPools{running: make(map[scanner.Mode]int)}
+	pools.probes.Store(probeCache)
+	pools.start = func(mode scanner.Mode) {
+		workerLoop(pools, store, string(mode), blocklist, notifier, metrics, pacer)
 	}
+	pools.Reconfigure(cfg)
+	return pools
 }
 
-func workerLoop(store TaskStore, probeCache *scanner.ProbeCache) {
-	logger := logging.Logger()
+// WorkerPools tracks the workers StartWorkers launched and lets their
+// configuration and probes change while tasks run. Every task uses the
+// settings in effect when a worker took it.
+type WorkerPools struct {
+	cfg     atomic.Pointer[WorkerConfig]
+	probes  atomic.Pointer[scanner.ProbeCache]
+	start   func(mode scanner.Mode)
+	mu      sync.Mutex
+	running map[scanner.Mode]int
+}
+
+// Config returns the worker configuration in effect.
+func (p *WorkerPools) Config() WorkerConfig {
+	return *p.cfg.Load()
+}
+
+// Reconfigure applies cfg to tasks taken from now on. Pools below their new
+// size grow at once; surplus workers exit before taking another task, so a
+// shrinking pool never interrupts a scan.
+func (p *WorkerPools) Reconfigure(cfg WorkerConfig) {
+	p.cfg.Store(&cfg)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, mode := range QueueModes {
+		for p.running[mode] < cfg.Size(mode) {
+			p.running[mode]++
+			go p.start(mode)
+		}
+	}
+}
+
+// SetProbes makes tasks taken from now on use probes.
+func (p *WorkerPools) SetProbes(probes *scanner.ProbeCache) {
+	p.probes.Store(probes)
+}
+
+// retire reports whether a worker of mode should exit because its pool
+// shrank, counting it out if so.
+func (p *WorkerPools) retire(mode scanner.Mode) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running[mode] <= p.Config().Size(mode) {
+		return false
+	}
+	p.running[mode]--
+	return true
+}
+
+func workerLoop(pools *WorkerPools, store TaskStore, mode string, blocklist *scanner.Blocklist, notifier *Notifier, metrics *Metrics, pacer scanner.Pacer) {
+	logger := logging.Logger().With("pool", mode)
 	for {
-		taskID, err := store.PopFromQueue()
+		if pools.retire(scanner.Mode(mode)) {
+			logger.Info("worker retired after pool shrank")
+			return
+		}
+		entry, err := store.PopFromQueue(mode)
 		if err != nil {
 			logger.Error("worker failed to pop task", "error", err)
 			time.Sleep(time.Second)
 			continue
 		}
 
-		task, err := store.GetTask(taskID)
-		if err != nil {
-			if err == ErrTaskNotFound {
-				logger.Warn("worker task disappeared", "task_id", taskID)
-				continue
+		stopHeartbeat := startHeartbeat(store, entry, pools.Config().HeartbeatInterval)
+		requeue := runQueueEntry(pools, store, entry, logger, blocklist, notifier, metrics, pacer)
+		stopHeartbeat()
+		if requeue {
+			if _, err := store.RequeueQueueEntry(mode, entry); err != nil {
+				logger.Error("worker failed to requeue task", "entry", entry, "error", err)
 			}
-			logger.Error("worker failed to load task", "task_id", taskID, "error", err)
 			continue
 		}
+		if err := store.AckQueueEntry(mode, entry); err != nil {
+			logger.Error("worker failed to acknowledge task", "entry", entry, "error", err)
+		}
+	}
+}
 
-		task.Status = "running"
-		task.Error = ""
-		task.Results = nil
-		task.CompletedAt = nil
-		if err := store.UpdateTask(task); err != nil {
+// runQueueEntry runs the task of a queue entry taken by a worker and records
+// its outcome. It reports whether the entry must go back to the queue, as for
+// a task resumed while its pause was being applied.
+func runQueueEntry(pools *WorkerPools, store TaskStore, entry string, logger *slog.Logger, blocklist *scanner.Blocklist, notifier *Notifier, metrics *Metrics, pacer scanner.Pacer) bool {
+	cfg := pools.Config()
+	namespace, taskID := SplitQueueEntry(entry)
+	tasks := store.Namespace(namespace)
+	task, err := tasks.GetTask(taskID)
+	if err != nil {
+		if err == ErrTaskNotFound {
+			logger.Warn("worker task disappeared", "task_id", taskID, "namespace", namespace)
+			return false
+		}
+		logger.Error("worker failed to load task", "task_id", taskID, "namespace", namespace, "error", err)
+		return false
+	}
+
+	if task.Status == "paused" || task.Status == "cancelled" {
+		logger.Info("worker skipped "+task.Status+" task", "task_id", taskID, "namespace", namespace)
+		return false
+	}
+
+	// The span continues the trace of the submission; the time the task
+	// waited in the queue is recorded as a span of its own
+	traceCtx := taskTraceContext(task)
+	if len(task.Results) == 0 {
+		_, queued := tracer.Start(traceCtx, "scan.queued", trace.WithTimestamp(task.CreatedAt),
+			trace.WithAttributes(attribute.String("scan.id", task.ID)))
+		queued.End()
+	}
+	traceCtx, span := tracer.Start(traceCtx, "scan.run", trace.WithSpanKind(trace.SpanKindConsumer), trace.WithAttributes(
+		attribute.String("scan.id", task.ID),
+		attribute.String("scan.mode", task.Mode),
+		attribute.Int("scan.hosts", len(task.Hosts)),
+		attribute.String("scan.parent", task.Parent),
+		attribute.String("cortex.namespace", namespace),
+	))
+	defer func() {
+		span.SetAttributes(attribute.String("scan.status", task.Status), attribute.Int("scan.results", len(task.Results)))
+		if task.Status == "failed" {
+			span.SetStatus(codes.Error, task.Error)
+		}
+		span.End()
+	}()
+
+	slotTaken := false
+	if cfg.MaxRunningPerKey > 0 && task.Owner != "" {
+		acquired, err := acquireRunSlot(store, tasks, task.Owner, taskID, cfg.MaxRunningPerKey)
+		switch {
+		case err != nil:
+			// Running over the cap beats stranding the task
+			logger.Error("worker failed to take a running slot", "task_id", taskID, "owner", task.Owner, "error", err)
+		case !acquired:
+			logger.Info("worker held task", "task_id", taskID, "owner", task.Owner, "limit", cfg.MaxRunningPerKey)
+			return false
+		default:
+			slotTaken = true
+		}
+	}
+
+	// Results a pending task still holds were collected before it was
+	// paused; runTask keeps them instead of probing those ports again
+	task.Status = "running"
+	task.Error = ""
+	task.Warnings = nil
+	task.HostSummaries = nil
+	task.Changes = nil
+	task.CompletedAt = nil
+	task.ETASeconds = 0
+	// Only a task still pending is started: a pause or cancel that came in
+	// since it was loaded has already been acted on by whoever sent it
+	var status string
+	marked := false
+	err = traceStore(traceCtx, tasks, "UpdateTaskIf", func() (err error) {
+		status, marked, err = tasks.UpdateTaskIf(task, "pending")
+		return err
+	})
+	if err != nil || !marked {
+		if err != nil {
 			logger.Error("worker failed to mark task running", "task_id", taskID, "error", err)
-			continue
+		} else {
+			task.Status = status
+			logger.Info("worker skipped "+status+" task", "task_id", taskID, "namespace", namespace)
+		}
+		if slotTaken {
+			releaseRunSlot(store, tasks, task.Owner, taskID)
+		}
+		return false
+	}
+	if task.Parent != "" {
+		if err := tasks.MarkTaskRunning(task.Parent); err != nil {
+			logger.Error("worker failed to mark parent task running", "task_id", task.ID, "parent", task.Parent, "error", err)
+		}
+	}
+
+	started := time.Now()
+	ctx, stop := context.WithCancelCause(traceCtx)
+	go watchStop(ctx, tasks, task.ID, stop)
+	err = runTask(ctx, tasks, task, pools.probes.Load(), blocklist, pacer)
+	stop(nil)
+	if slotTaken {
+		releaseRunSlot(store, tasks, task.Owner, task.ID)
+	}
+	if errors.Is(err, errTaskPaused) {
+		requeue, cancelled, settleErr := settlePause(tasks, task)
+		switch {
+		case settleErr != nil:
+			logger.Error("worker failed to update task", "task_id", task.ID, "error", settleErr)
+			return false
+		case requeue:
+			logger.Info("worker requeued resumed task", "task_id", task.ID, "results", len(task.Results))
+			return true
+		case !cancelled:
+			logger.Info("worker paused task", "task_id", task.ID, "status", task.Status, "results", len(task.Results))
+			return false
+		}
+		err = errTaskCancelled
+	}
+	switch {
+	case errors.Is(err, errTaskCancelled):
+		// The results collected before the cancellation are kept
+		task.Status = "cancelled"
+		logger.Info("worker cancelled task", "task_id", task.ID, "results", len(task.Results))
+	case err != nil:
+		logger.Error("worker task failed", "task_id", task.ID, "error", err)
+		task.Status = "failed"
+		task.Error = err.Error()
+		task.Results = nil
+		task.HostSummaries = nil
+	default:
+		task.Status = "completed"
+		task.Progress = 100
+	}
+	now := time.Now().UTC()
+	task.CompletedAt = &now
+	metrics.taskMetrics(task, now.Sub(started))
+	if cfg.ResultTTL > 0 && task.Status == "completed" {
+		if err := tasks.SaveRecentResults(taskProtocol(task), task.Results, now, cfg.ResultTTL); err != nil {
+			logger.Error("worker failed to save recent results", "task_id", task.ID, "error", err)
 		}
+	}
 
-		startPort, endPort, err := parsePortRange(task.Ports)
+	if task.Parent != "" {
+		if err := tasks.UpdateTask(task); err != nil {
+			logger.Error("worker failed to update task", "task_id", task.ID, "error", err)
+		}
+		parent, err := finishShard(tasks, task)
 		if err != nil {
-			failTask(task, store, err)
-			continue
+			logger.Error("worker failed to update parent task", "task_id", task.ID, "parent", task.Parent, "error", err)
+			return false
+		}
+		if parent == nil {
+			return false
 		}
+		task = parent
+	}
+	finishTask(namespace, tasks, task, notifier)
+	return false
+}
 
-		workerFunc, workerCount, err := selectWorker(task.Mode)
+// settlePause stores task, whose scan stopped for a pause, as paused. A
+// resume that came in while in-flight probes finished has withdrawn the
+// pause and left the task running; it is then set pending so the worker
+// queues it again, keeping the results collected so far. It reports whether
+// the task must be requeued, or finished as cancelled because a cancel
+// overtook the pause. Statuses are only changed from the one last seen, so
+// the pause, resume or cancel a client was last told about is the one that
+// sticks.
+func settlePause(tasks TaskStore, task *ScanTask) (requeue, cancelled bool, err error) {
+	next := map[string]string{"pausing": "paused", "running": "pending"}
+	from := "pausing"
+	for {
+		to, ok := next[from]
+		if !ok {
+			task.Status = from
+			return false, from == "cancelling", nil
+		}
+		task.Status = to
+		status, updated, err := tasks.UpdateTaskIf(task, from)
 		if err != nil {
-			failTask(task, store, err)
+			return false, false, err
+		}
+		if updated {
+			return to == "pending", false, nil
+		}
+		from = status
+	}
+}
+
+// startHeartbeat records a heartbeat for the queue entry every interval until
+// the returned function is called.
+func startHeartbeat(store TaskStore, entry string, interval time.Duration) func() {
+	ctx, stop := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := store.HeartbeatQueueEntry(entry); err != nil {
+					logging.Logger().Error("worker failed to record heartbeat", "entry", entry, "error", err)
+				}
+			}
+		}
+	}()
+	return stop
+}
+
+// acquireRunSlot takes a running slot of owner for task taskID of tasks,
+// first freeing slots whose task is no longer active, as left behind by a
+// worker that died mid-scan.
+func acquireRunSlot(store, tasks TaskStore, owner, taskID string, limit int) (bool, error) {
+	entries, err := store.RunSlots(owner)
+	if err != nil {
+		return false, err
+	}
+	if len(entries) >= limit {
+		for _, running := range entries {
+			namespace, runningID := SplitQueueEntry(running)
+			runningTasks := store.Namespace(namespace)
+			status, err := runningTasks.TaskStatus(runningID)
+			if err != nil && err != ErrTaskNotFound {
+				return false, err
+			}
+			if status != "pending" && status != "running" && status != "pausing" && status != "cancelling" {
+				releaseRunSlot(store, runningTasks, owner, runningID)
+			}
+		}
+	}
+	return tasks.AcquireRunSlot(owner, taskID, limit)
+}
+
+// releaseRunSlot frees the running slot of task taskID of tasks and queues
+// the oldest held task of owner again. Held entries whose task was paused,
+// resumed or deleted in the meantime are dropped.
+func releaseRunSlot(store, tasks TaskStore, owner, taskID string) {
+	logger := logging.Logger()
+	next, err := tasks.ReleaseRunSlot(owner, taskID)
+	for ; err == nil && next != ""; next, err = store.ReleaseRunSlot(owner, "") {
+		namespace, heldID := SplitQueueEntry(next)
+		heldTasks := store.Namespace(namespace)
+		task, err := heldTasks.GetTask(heldID)
+		if err != nil {
+			if err != ErrTaskNotFound {
+				logger.Error("worker failed to load held task", "task_id", heldID, "error", err)
+			}
 			continue
 		}
+		if task.Status != "held" {
+			continue
+		}
+		task.Status = "pending"
+		if err := heldTasks.UpdateTask(task); err != nil {
+			logger.Error("worker failed to release held task", "task_id", heldID, "error", err)
+		}
+		if err := heldTasks.PushToQueue(heldID, task.Mode); err != nil {
+			logger.Error("worker failed to queue held task", "task_id", heldID, "error", err)
+		}
+		return
+	}
+	if err != nil {
+		logger.Error("worker failed to release running slot", "owner", owner, "task_id", taskID, "error", err)
+	}
+}
 
-		results := scanner.ExecuteScan(task.Hosts, startPort, endPort, workerFunc, workerCount, probeCache)
+// pausePollInterval is how often a worker checks whether its task was asked
+// to pause or cancel.
+const pausePollInterval = 2 * time.Second
 
-		task.Status = "completed"
-		task.Results = results
-		now := time.Now().UTC()
-		task.CompletedAt = &now
+var (
+	// errTaskPaused reports that runTask stopped because its task was paused.
+	errTaskPaused = errors.New("task paused")
+	// errTaskCancelled reports that runTask stopped because its task was
+	// cancelled.
+	errTaskCancelled = errors.New("task cancelled")
+)
 
-		if err := store.UpdateTask(task); err != nil {
-			logger.Error("worker failed to update task", "task_id", task.ID, "error", err)
+// watchStop stops ctx with errTaskPaused once task id is set to pausing and
+// with errTaskCancelled once it is set to cancelling, until ctx is done.
+func watchStop(ctx context.Context, store TaskStore, id string, stop context.CancelCauseFunc) {
+	ticker := time.NewTicker(pausePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			status, err := store.TaskStatus(id)
+			if err != nil {
+				continue
+			}
+			switch status {
+			case "pausing":
+				stop(errTaskPaused)
+				return
+			case "cancelling":
+				stop(errTaskCancelled)
+				return
+			}
 		}
 	}
 }
 
-func failTask(task *ScanTask, store TaskStore, err error) {
+// runTask scans task and stores its results, warnings and host summaries on
+// it. Results the task already holds come from before a pause; their ports
+// are not probed again. When ctx is cancelled the results collected so far
+// are stored and the cause of the cancellation, errTaskPaused or
+// errTaskCancelled, is returned.
+func runTask(ctx context.Context, store TaskStore, task *ScanTask, probeCache *scanner.ProbeCache, blocklist *scanner.Blocklist, pacer scanner.Pacer) error {
+	hostPorts, err := taskHostPorts(task)
+	if err != nil {
+		return err
+	}
+
+	mode, err := scanner.ParseMode(task.Mode)
+	if err != nil {
+		return err
+	}
+
+	checks, err := scanner.ParseChecks(task.Checks)
+	if err != nil {
+		return err
+	}
+	if len(task.HTTPPaths) > 0 {
+		scanner.SetHTTPPaths(checks, task.HTTPPaths...)
+	}
+
+	reuse, err := reusableResults(store, task)
+	if err != nil {
+		task.Warnings = append(task.Warnings, fmt.Sprintf("result reuse skipped, probing every port: %v", err))
+	}
+	carried := make(map[string]scanner.ScanResult, len(task.Results))
+	for _, result := range task.Results {
+		carried[RecentResultKey(result.Host, result.Address, result.Port)] = result
+	}
+	if len(carried) > 0 {
+		recent := reuse
+		reuse = func(job scanner.ScanJob) (scanner.ScanResult, bool) {
+			if result, ok := carried[RecentResultKey(job.Host, job.Address, job.Port)]; ok {
+				return result, true
+			}
+			if recent == nil {
+				return scanner.ScanResult{}, false
+			}
+			return recent(job)
+		}
+	}
+
+	options := []scanner.Option{
+		scanner.WithMode(mode),
+		scanner.WithFallback(!task.NoFallback),
+		scanner.WithHostPorts(hostPorts),
+		scanner.WithProbes(probeCache),
+		scanner.WithRate(task.MaxRate),
+		scanner.WithHostRate(task.HostRate),
+		scanner.WithTiming(scanTiming(task.Timing, task.MaxParallelism, task.MaxRetries, task.RetryBackoff)),
+		scanner.WithAllAddresses(task.AllAddresses),
+		scanner.WithAddressPreference(scanner.AddressPreference(task.Prefer)),
+		scanner.WithIPVersion(task.IPVersion),
+		scanner.WithDNSServers(task.DNSServers...),
+		scanner.WithBlocklist(blocklist),
+		scanner.WithPacer(pacer),
+		scanner.WithChecks(checks...),
+		scanner.WithRDAP(task.RDAP),
+		scanner.WithReverseDNS(task.ResolvePTR),
+		scanner.WithReuse(reuse),
+		scanner.WithHostGroups(task.MinHostGroup, task.MaxHostGroup, nil),
+		scanner.WithMaxDuration(time.Duration(task.MaxDuration) * time.Second),
+	}
+	if task.Ports != "" {
+		ports, err := parsePorts(task.Ports, task.Mode)
+		if err != nil {
+			return err
+		}
+		options = append(options, scanner.WithPorts(ports...))
+	}
+	if task.VersionIntensity != nil {
+		options = append(options, scanner.WithVersionIntensity(*task.VersionIntensity))
+	}
+	if task.Discovery {
+		options = append(options, scanner.WithDiscovery(scanner.DiscoveryOptions{}))
+	}
+	if task.DetectTarpits {
+		options = append(options, scanner.WithTarpitDetection(scanner.TarpitOptions{Downgrade: task.TarpitDowngrade}))
+	}
+	// Carried results were written as partial results before the pause
+	partial := newPartialWriter(store, task.ID)
+	options = append(options, scanner.WithLifecycle(nil, func(result scanner.ScanResult) {
+		if _, ok := carried[RecentResultKey(result.Host, result.Address, result.Port)]; !ok {
+			partial.add(result)
+		}
+	}, nil), scanner.WithProgress(partial.progress))
+	flushCtx, stopFlushing := context.WithCancel(context.Background())
+	go partial.run(flushCtx)
+	report, err := scanner.Run(ctx, task.Hosts, options...)
+	stopFlushing()
+	partial.flush()
+	task.Progress, _ = partial.estimate()
+	task.ETASeconds = 0
+	if report != nil {
+		// Carried results are reported as reused; they were probed by
+		// this task before the pause
+		for i, result := range report.Results {
+			if previous, ok := carried[RecentResultKey(result.Host, result.Address, result.Port)]; ok {
+				report.Results[i].Reused = previous.Reused
+			}
+		}
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			if report != nil {
+				task.Results = report.Results
+			}
+			return context.Cause(ctx)
+		}
+		return err
+	}
+	if report.Mode != mode {
+		logging.Logger().Warn("worker downgraded scan mode", "task_id", task.ID, "requested", mode, "effective", report.Mode)
+	}
+	task.Warnings = append(task.Warnings, report.Warnings...)
+	task.Results = report.Results
+	task.HostSummaries = report.Hosts
+	return nil
+}
+
+// reusableResults returns a lookup of the recent results task may reuse, or
+// nil when it did not ask for reuse.
+func reusableResults(store TaskStore, task *ScanTask) (func(scanner.ScanJob) (scanner.ScanResult, bool), error) {
+	if task.ReuseWithin == "" {
+		return nil, nil
+	}
+	within, err := time.ParseDuration(task.ReuseWithin)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reuse_within: %w", err)
+	}
+	recent, err := store.RecentResults(taskProtocol(task), task.Hosts, time.Now().UTC().Add(-within))
+	if err != nil {
+		return nil, fmt.Errorf("load recent results: %w", err)
+	}
+	if len(recent) == 0 {
+		return nil, nil
+	}
+	return func(job scanner.ScanJob) (scanner.ScanResult, bool) {
+		result, ok := recent[RecentResultKey(job.Host, job.Address, job.Port)]
+		return result, ok
+	}, nil
+}
+
+// taskProtocol returns the transport probed by task; connect, syn, fin, null,
+// xmas and ack scans observe the same TCP ports.
+func taskProtocol(task *ScanTask) string {
+	return modeProtocol(task.Mode)
+}
+
+// modeProtocol returns the transport probed in mode.
+func modeProtocol(mode string) string {
+	if mode == string(scanner.ModeUDP) {
+		return "udp"
+	}
+	return "tcp"
+}
+
+// finishTask runs the follow-up of a task that reached a terminal state:
+// baseline comparison, monitor bookkeeping, persistence, the task callback,
+// inventory and the completion webhook. Shards skip it; their parent goes through it instead.
+func finishTask(namespace string, store TaskStore, task *ScanTask, notifier *Notifier) {
 	logger := logging.Logger()
-	logger.Error("worker task failed", "task_id", task.ID, "error", err)
-	task.Status = "failed"
-	task.Error = err.Error()
-	task.Results = nil
+	notify := task.Status == "completed"
+	task.Changes = nil
+	if notify && task.Baseline != "" {
+		changes, err := baselineChanges(store, task)
+		if err != nil {
+			task.Warnings = append(task.Warnings, fmt.Sprintf("baseline comparison skipped, notifying unconditionally: %v", err))
+		} else {
+			task.Changes = changes
+			notify = len(changes) > 0
+		}
+	}
+
+	// Monitors are updated while the task still reads as running so the
+	// scheduler cannot queue the next verification in between
+	if task.Monitor != "" {
+		if err := recordVerification(store, task); err != nil {
+			logger.Error("worker failed to update monitor", "task_id", task.ID, "monitor", task.Monitor, "error", err)
+		}
+	}
+	if err := store.UpdateTask(task); err != nil {
+		logger.Error("worker failed to update task", "task_id", task.ID, "error", err)
+	}
+	if task.Status == "completed" || task.Status == "failed" {
+		notifier.TaskCallback(store, task)
+	}
+	if task.Status != "completed" {
+		return
+	}
+	if err := updateInventory(store, task); err != nil {
+		logger.Error("worker failed to update inventory", "task_id", task.ID, "error", err)
+	}
+	if notify {
+		notifier.TaskCompleted(namespace, task)
+	}
+}
+
+// finishShard records shard as done on its parent, once however often the
+// shard is delivered. The worker finishing the last shard gets the parent
+// back with the combined results of all shards; every other caller gets nil.
+// The parent fails only when every shard failed.
+func finishShard(store TaskStore, shard *ScanTask) (*ScanTask, error) {
+	done, err := store.FinishShard(shard.Parent, shard.ID)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := store.GetTask(shard.Parent)
+	if err != nil {
+		return nil, err
+	}
+	if done < len(parent.Shards) {
+		return nil, nil
+	}
+
+	parent.ShardsDone = done
+	parent.Error = ""
+	parent.Warnings = nil
+	parent.Results = nil
+	parent.HostSummaries = nil
+	failed, cancelled := 0, 0
+	for _, id := range parent.Shards {
+		child, err := store.GetTask(id)
+		if err != nil {
+			failed++
+			parent.Warnings = append(parent.Warnings, fmt.Sprintf("shard %s could not be loaded: %v", id, err))
+			continue
+		}
+		if child.Status == "cancelled" {
+			cancelled++
+			parent.Warnings = append(parent.Warnings, fmt.Sprintf("shard %s was cancelled", id))
+		} else if child.Status != "completed" {
+			failed++
+			parent.Warnings = append(parent.Warnings, fmt.Sprintf("shard %s failed: %s", id, child.Error))
+			continue
+		}
+		parent.Warnings = append(parent.Warnings, child.Warnings...)
+		parent.Results = append(parent.Results, child.Results...)
+		parent.HostSummaries = append(parent.HostSummaries, child.HostSummaries...)
+	}
+	sort.SliceStable(parent.Results, func(i, j int) bool {
+		a, b := parent.Results[i], parent.Results[j]
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		return a.Port < b.Port
+	})
+
 	now := time.Now().UTC()
-	task.CompletedAt = &now
-	if updateErr := store.UpdateTask(task); updateErr != nil {
-		logger.Error("worker failed to persist failed task", "task_id", task.ID, "error", updateErr)
-	}
-}
-
-func selectWorker(mode string) (scanner.WorkerFunc, int, error) {
-	switch strings.ToLower(mode) {
-	case "syn":
-		synInitOnce.Do(func() {
-			synInitErr = scanner.InitSynScan()
-		})
-		if synInitErr != nil {
-			return nil, 0, synInitErr
-		}
-		return scanner.TCPSynWorker, 50, nil
-	case "udp":
-		udpInitOnce.Do(func() {
-			udpInitErr = scanner.InitUdpScan()
-		})
-		if udpInitErr != nil {
-			return nil, 0, udpInitErr
-		}
-		return scanner.UDPWorker, 50, nil
-	case "connect":
-		fallthrough
-	default:
-		return scanner.TCPConnectWorker, 100, nil
+	parent.CompletedAt = &now
+	parent.Status = "completed"
+	parent.Progress = 100
+	if cancelled > 0 {
+		parent.Status = "cancelled"
+	} else if failed == len(parent.Shards) {
+		parent.Status = "failed"
+		parent.Error = fmt.Sprintf("all %d shards failed", failed)
+		parent.Results = nil
+		parent.HostSummaries = nil
+	}
+	return parent, nil
+}
+
+// baselineChanges diffs the results of task against its completed baseline task.
+func baselineChanges(store TaskStore, task *ScanTask) ([]scanner.ResultChange, error) {
+	baseline, err := store.GetTask(task.Baseline)
+	if err != nil {
+		if err == ErrTaskNotFound {
+			return nil, fmt.Errorf("baseline task %s not found", task.Baseline)
+		}
+		return nil, err
+	}
+	if baseline.Status != "completed" {
+		return nil, fmt.Errorf("baseline task %s is %s, not completed", task.Baseline, baseline.Status)
 	}
+	return scanner.DiffResults(baseline.Results, task.Results), nil
 }
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// apiClient talks to a running Cortex server on behalf of admin subcommands.
+type apiClient struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// addAPIFlags registers the connection flags shared by admin subcommands.
+// Defaults come from CORTEX_URL and CORTEX_API_KEY.
+func addAPIFlags(fs *flag.FlagSet) (*string, *string) {
+	baseURL := os.Getenv("CORTEX_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	server := fs.String("server", baseURL, "Cortex server URL (env CORTEX_URL)")
+	key := fs.String("api-key", os.Getenv("CORTEX_API_KEY"), "API key with admin rights (env CORTEX_API_KEY)")
+	return server, key
+}
+
+func newAPIClient(baseURL, apiKey string) *apiClient {
+	return &apiClient{
+		baseURL: strings.TrimRight(baseURL, "/") + "/api/v1",
+		apiKey:  apiKey,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// do sends a request and decodes a successful JSON response into out.
+func (c *apiClient) do(method, path string, out interface{}) error {
+	req, err := http.NewRequest(method, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s %s: %s (HTTP %d)", method, path, apiErr.Error, resp.StatusCode)
+		}
+		return fmt.Errorf("%s %s: HTTP %d", method, path, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
+
+// queueStatus mirrors the server's queue state payload.
+type queueStatus struct {
+	Paused   bool       `json:"paused"`
+	PausedAt *time.Time `json:"paused_at"`
+	PausedBy string     `json:"paused_by"`
+	Depth    int64      `json:"depth"`
+	Pools    []struct {
+		Mode    string `json:"mode"`
+		Workers int    `json:"workers"`
+		Depth   int64  `json:"depth"`
+	} `json:"pools"`
+}
+
+// RunQueue implements the `cortex queue` admin subcommands against a running
+// server and returns the process exit code:
+//   - status: show whether the queue is paused and how many tasks wait
+//   - pause: stop workers from taking new tasks; submissions are still accepted
+//   - resume: let workers take tasks again
+func RunQueue(args []string) int {
+	if len(args) == 0 {
+		printQueueUsage()
+		return 2
+	}
+
+	var method, path string
+	switch args[0] {
+	case "status":
+		method, path = http.MethodGet, "/admin/queue"
+	case "pause":
+		method, path = http.MethodPost, "/admin/queue/pause"
+	case "resume":
+		method, path = http.MethodPost, "/admin/queue/resume"
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown queue subcommand %q\n", args[0])
+		printQueueUsage()
+		return 2
+	}
+
+	fs := flag.NewFlagSet("queue "+args[0], flag.ContinueOnError)
+	server, key := addAPIFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if *key == "" {
+		fmt.Fprintln(os.Stderr, "Error: an API key is required (--api-key or CORTEX_API_KEY)")
+		return 2
+	}
+
+	var status queueStatus
+	if err := newAPIClient(*server, *key).do(method, path, &status); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if status.Paused {
+		fmt.Printf("Queue: paused")
+		if status.PausedBy != "" {
+			fmt.Printf(" by %s", status.PausedBy)
+		}
+		if status.PausedAt != nil {
+			fmt.Printf(" at %s", status.PausedAt.Format(time.RFC3339))
+		}
+		fmt.Println()
+	} else {
+		fmt.Println("Queue: running")
+	}
+	fmt.Printf("Waiting tasks: %d\n", status.Depth)
+	for _, pool := range status.Pools {
+		fmt.Printf("  %-8s %d waiting, %d workers\n", pool.Mode+":", pool.Depth, pool.Workers)
+	}
+	return 0
+}
+
+// printQueueUsage displays the help message for queue subcommands.
+func printQueueUsage() {
+	fmt.Println("Usage: cortex queue <status|pause|resume> [--server url] [--api-key key]")
+	fmt.Println("  cortex queue status   Show whether the queue is paused and how many tasks wait")
+	fmt.Println("  cortex queue pause    Stop workers from taking new tasks (submissions are still accepted)")
+	fmt.Println("  cortex queue resume   Let workers take tasks again")
+}
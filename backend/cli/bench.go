@@ -0,0 +1,215 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Viktor25104/cortex/backend/scanner"
+)
+
+// benchBanner is what every benchmark listener answers with, so open ports
+// look like a real service and banner capture returns at once.
+var benchBanner = []byte("SSH-2.0-OpenSSH_9.6 cortex-bench\r\n")
+
+// RunBench implements `cortex bench` and returns the process exit code. It
+// starts listeners on 127.0.0.1 inside the process, scans them plus as many
+// closed ports for every combination of the requested modes and worker counts,
+// and reports end-to-end throughput in ports per second. Each combination is
+// scanned several times and the median and best rounds are reported.
+func RunBench(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	modeList := fs.String("modes", "connect,udp", "Comma-separated scan modes to measure: connect, syn, udp")
+	workerList := fs.String("workers", "10,50,100", "Comma-separated worker counts to measure")
+	open := fs.Int("open", 200, "Listening ports scanned per mode")
+	closed := fs.Int("closed", 200, "Closed ports scanned per mode")
+	rounds := fs.Int("rounds", 3, "Scans per mode and worker count")
+	probesFile := fs.String("probes", "", "Probe file for service detection; empty measures port states only")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 || *open < 0 || *closed < 0 || *open+*closed == 0 || *rounds < 1 {
+		printBenchUsage()
+		return 2
+	}
+
+	var modes []scanner.Mode
+	for _, name := range strings.Split(*modeList, ",") {
+		mode, err := scanner.ParseMode(strings.TrimSpace(name))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		modes = append(modes, mode)
+	}
+	var workerCounts []int
+	for _, raw := range strings.Split(*workerList, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || n < 1 {
+			fmt.Fprintf(os.Stderr, "Error: worker count %q must be a positive integer\n", raw)
+			return 2
+		}
+		workerCounts = append(workerCounts, n)
+	}
+
+	var probes *scanner.ProbeCache
+	if *probesFile != "" {
+		loaded, _, err := scanner.LoadProbes(*probesFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		probes = scanner.NewProbeCache(loaded)
+	}
+
+	fmt.Printf("--- Scan Benchmark (127.0.0.1, %d open + %d closed ports, %d rounds) ---\n", *open, *closed, *rounds)
+	fmt.Printf("%-8s %8s %6s %6s %12s %12s %12s\n", "MODE", "WORKERS", "PORTS", "OPEN", "MEDIAN", "BEST", "PORTS/S")
+	measured := 0
+	for _, mode := range modes {
+		target, err := startBenchTarget(mode, *open, *closed)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s listeners: %v\n", mode, err)
+			return 1
+		}
+		for _, workers := range workerCounts {
+			options := []scanner.Option{
+				scanner.WithMode(mode),
+				scanner.WithFallback(false),
+				scanner.WithPorts(target.ports...),
+				scanner.WithWorkers(workers),
+			}
+			if probes != nil {
+				options = append(options, scanner.WithProbes(probes))
+			}
+
+			durations := make([]time.Duration, 0, *rounds)
+			found := 0
+			var runErr error
+			for round := 0; round < *rounds; round++ {
+				start := time.Now()
+				report, err := scanner.Run(context.Background(), []string{"127.0.0.1"}, options...)
+				if err != nil {
+					runErr = err
+					break
+				}
+				durations = append(durations, time.Since(start))
+				found = countOpen(report.Results)
+			}
+			if runErr != nil {
+				fmt.Printf("%-8s %8d  skipped: %v\n", mode, workers, runErr)
+				break
+			}
+
+			sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+			median := durations[len(durations)/2]
+			fmt.Printf("%-8s %8d %6d %6d %12s %12s %12.0f\n", mode, workers, len(target.ports), found,
+				median.Round(time.Microsecond), durations[0].Round(time.Microsecond),
+				float64(len(target.ports))/median.Seconds())
+			if found != *open {
+				fmt.Printf("  warning: %d of %d listening ports were reported open\n", found, *open)
+			}
+			measured++
+		}
+		target.Close()
+	}
+	if measured == 0 {
+		return 1
+	}
+	return 0
+}
+
+// printBenchUsage displays the help message for the bench subcommand.
+func printBenchUsage() {
+	fmt.Println("Usage: cortex bench [--modes connect,syn,udp] [--workers 10,50,100] [--open n] [--closed n] [--rounds n] [--probes file]")
+	fmt.Println("  Scan in-process listeners on 127.0.0.1 and report throughput per mode and worker count.")
+	fmt.Println("  syn needs raw packet privileges; closed UDP ports are subject to the kernel's ICMP rate limit.")
+}
+
+// benchTarget is a set of local ports to scan: the first open ones have a
+// listener answering with benchBanner, the rest are closed.
+type benchTarget struct {
+	ports     []int
+	listeners []io.Closer
+}
+
+// startBenchTarget opens open listeners of the protocol mode scans and picks
+// closed ports by binding and releasing further ephemeral ports.
+func startBenchTarget(mode scanner.Mode, open, closed int) (*benchTarget, error) {
+	target := &benchTarget{}
+	for i := 0; i < open+closed; i++ {
+		listener, port, err := listenBench(mode)
+		if err != nil {
+			target.Close()
+			return nil, err
+		}
+		target.ports = append(target.ports, port)
+		target.listeners = append(target.listeners, listener)
+	}
+	for _, listener := range target.listeners[open:] {
+		listener.Close()
+	}
+	target.listeners = target.listeners[:open]
+	return target, nil
+}
+
+// listenBench starts one listener for mode on an ephemeral port.
+func listenBench(mode scanner.Mode) (io.Closer, int, error) {
+	if mode == scanner.ModeUDP {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		if err != nil {
+			return nil, 0, err
+		}
+		go func() {
+			buf := make([]byte, 2048)
+			for {
+				_, addr, err := conn.ReadFrom(buf)
+				if err != nil {
+					return
+				}
+				conn.WriteTo(benchBanner, addr)
+			}
+		}()
+		return conn, conn.LocalAddr().(*net.UDPAddr).Port, nil
+	}
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		return nil, 0, err
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write(benchBanner)
+			conn.Close()
+		}
+	}()
+	return listener, listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// Close stops every listener of t.
+func (t *benchTarget) Close() {
+	for _, listener := range t.listeners {
+		listener.Close()
+	}
+}
+
+// countOpen returns how many results report an open port.
+func countOpen(results []scanner.ScanResult) int {
+	open := 0
+	for _, result := range results {
+		if result.State == "Open" {
+			open++
+		}
+	}
+	return open
+}
@@ -1,14 +1,26 @@
 package cli
 
 import (
-	"cortex/logging"
-	"cortex/scanner"
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"os"
+	"os/signal"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/Viktor25104/cortex/backend/logging"
+	"github.com/Viktor25104/cortex/backend/scanner"
+	"github.com/Viktor25104/cortex/backend/version"
 )
 
 // Run is the main entry point for the CLI application.
@@ -16,12 +28,148 @@ import (
 // and orchestrates the scanning process.
 func Run() {
 	logging.Configure()
-	jsonOutput := flag.Bool("json", false, "Output results in JSON format")
+	jsonOutput := flag.Bool("json", false, "Output results in JSON format (same as --output json)")
+	outputFormat := flag.String("output", "plain", "Output format: plain, json, xml (nmap XML), grep (nmap greppable) or csv")
+	xmlFile := flag.String("oX", "", "Also write the results as nmap XML to this file")
+	grepFile := flag.String("oG", "", "Also write the results in nmap greppable format to this file")
+	allFiles := flag.String("oA", "", "Also write the results in every file format: basename.xml, basename.gnmap, basename.csv and basename.json")
 	synScan := flag.Bool("sS", false, "Use SYN scan (requires root/admin)")
 	flag.BoolVar(synScan, "syn-scan", false, "Use SYN scan (requires root/admin)")
 	udpScan := flag.Bool("sU", false, "Use UDP scan")
 	flag.BoolVar(udpScan, "udp-scan", false, "Use UDP scan")
+	finScan := flag.Bool("sF", false, "Use FIN scan (requires root/admin)")
+	flag.BoolVar(finScan, "fin-scan", false, "Use FIN scan (requires root/admin)")
+	nullScan := flag.Bool("sN", false, "Use NULL scan (requires root/admin)")
+	flag.BoolVar(nullScan, "null-scan", false, "Use NULL scan (requires root/admin)")
+	xmasScan := flag.Bool("sX", false, "Use Xmas scan (requires root/admin)")
+	flag.BoolVar(xmasScan, "xmas-scan", false, "Use Xmas scan (requires root/admin)")
+	ackScan := flag.Bool("sA", false, "Use ACK scan to map firewall rules (requires root/admin)")
+	flag.BoolVar(ackScan, "ack-scan", false, "Use ACK scan to map firewall rules (requires root/admin)")
+	rate := flag.Float64("rate", 0, "Maximum probes per second across all hosts (0 = unlimited)")
+	flag.Float64Var(rate, "max-rate", 0, "Maximum probes per second across all hosts (0 = unlimited)")
+	hostRate := flag.Float64("host-rate", 0, "Maximum probes per second sent to any single host (0 = unlimited)")
+	allAddresses := flag.Bool("all-addresses", false, "Scan every resolved address of multi-homed hostnames")
+	dnsServersFlag := flag.String("dns-servers", "", "Comma-separated name servers (IP[:port]) to resolve targets with instead of the system resolver")
+	preferFlag := flag.String("prefer", "", "Address family scanned on dual-stack hostnames: ipv4 (default), ipv6 or both; with --all-addresses ipv4/ipv6 keep only that family")
+	ipv4Only := flag.Bool("4", false, "Scan IPv4 addresses only; IPv6 targets are skipped and hostnames resolve to A records alone")
+	ipv6Only := flag.Bool("6", false, "Scan IPv6 addresses only; IPv4 targets are skipped and hostnames resolve to AAAA records alone")
+	noFallback := flag.Bool("no-fallback", false, "Abort instead of falling back to connect scan when SYN scan lacks privileges")
+	bannerBytes := flag.Int("banner-bytes", scanner.DefaultBannerMaxBytes, "Maximum bytes of a service banner to capture")
+	bannerTimeout := flag.Duration("banner-timeout", 0, "How long to wait for a service to start responding (0 = as the timing template sets, normally "+scanner.DefaultBannerReadTimeout.String()+")")
+	timingName := flag.String("timing", "", "Timing template: 0-5 or "+strings.Join(scanner.TimingNames, ", ")+" (default normal)")
+	timingLevels := make([]*bool, len(scanner.TimingNames))
+	for level, name := range scanner.TimingNames {
+		timingLevels[level] = flag.Bool(fmt.Sprintf("T%d", level), false, "Use the "+name+" timing template")
+	}
+	maxParallelism := flag.Int("max-parallelism", 0, "Maximum probes in flight against one host (0 = as the timing template sets)")
+	maxRetries := flag.Int("max-retries", -1, "Times a probe that drew no answer is sent again (-1 = as the timing template sets)")
+	retryBackoff := flag.Duration("retry-backoff", 0, "Wait before resending a silent SYN or UDP probe, doubling per attempt (0 = 100ms)")
+	initialRTT := flag.Duration("initial-rtt-timeout", 0, "Probe timeout before a host's round-trip time is known (0 = as the timing template sets)")
+	maxRTT := flag.Duration("max-rtt-timeout", 0, "Longest adaptive probe timeout (0 = as the timing template sets)")
+	bannerQuiet := flag.Duration("banner-quiet", 0, "Keep reading a banner until the service is silent this long, e.g. 300ms (0 = single read)")
+	versionIntensity := flag.Int("version-intensity", scanner.DefaultVersionIntensity, "Rarest service probe sent to a port (0-9); probes registered for the port are always sent")
+	ping := flag.Bool("ping", false, "Ping every host first (ARP on the local subnet, otherwise ICMP echo and TCP to 443, 80 and 22) and skip hosts that do not answer")
+	noPing := flag.Bool("Pn", false, "Treat every host as up and skip host discovery (the default unless --ping is given)")
+	checksFlag := flag.String("checks", "", "Comma-separated check modules to run on open ports (names, safe, or all; some are intrusive)")
+	httpPaths := flag.String("http-paths", "", "Comma-separated paths requested by the http check (default "+strings.Join(scanner.DefaultHTTPPaths, ",")+")")
+	rdap := flag.Bool("rdap", false, "Look up netname, organization and abuse contact of public targets via RDAP")
+	resolve := flag.Bool("resolve", false, "Look up the PTR name of every probed IP address")
+	flag.BoolVar(resolve, "R", false, "Look up the PTR name of every probed IP address")
+	pcapOut := flag.String("pcap-out", "", "Write every packet sent and received by SYN/UDP probes to this pcap file")
+	packetTrace := flag.Bool("packet-trace", false, "Log every probe sent and response received (timestamps, flags, sizes)")
+	blocklistFile := flag.String("blocklist", "", "File of additional never-scan CIDR blocks, one per line (adds to CORTEX_BLOCKED_RANGES)")
+	minHostGroup := flag.Int("min-hostgroup", 0, "Hosts in the first host group when --max-hostgroup is set; later groups double in size")
+	maxDuration := flag.Duration("max-duration", 0, "Stop probing after this long, e.g. 30m, and report the results so far (0 = no limit)")
+	maxHostGroup := flag.Int("max-hostgroup", 0, "Scan hosts in groups of at most this many, printing each group's results as it finishes (0 = one group)")
+	servicesFlag := flag.String("services", "", "Comma-separated service names to scan instead of a port range, e.g. http,ssh,rdp")
+	topPorts := flag.Int("top-ports", 0, "Scan the N ports most often found open according to the services table instead of a port range")
+	servicesFile := flag.String("services-file", "", "nmap-services file used to guess the service of unidentified open ports (default: bundled nmap-services table)")
+	excludeFlag := flag.String("exclude", "", "Comma-separated hosts, CIDR blocks or IP ranges to leave out of the targets")
+	maxTargets := flag.Int("max-targets", scanner.DefaultMaxTargets, "Refuse targets that expand to more addresses than this")
+	targetsFile := flag.String("targets-file", "", "YAML or JSON manifest of targets with per-host ports, mode and tags; an optional port range argument applies to entries without ports")
+	detectTarpits := flag.Bool("detect-tarpits", false, "Flag hosts where implausibly many ports are open or every open port stalls (likely tarpits or honeypots)")
+	tarpitDowngrade := flag.Bool("tarpit-downgrade", false, "Report the open ports of flagged tarpit hosts as Tarpit instead of Open (implies --detect-tarpits)")
+	eventsOutput := flag.Bool("events", false, "Stream lifecycle events (scan_config, host_started, result, host_finished, summary) as JSON lines")
+	noProgress := flag.Bool("no-progress", false, "Do not draw a progress bar on stderr while scanning")
+	profileName := flag.String("profile", "", "Apply the named profile from the profiles file; options given on the command line take precedence")
+	profilesFile := flag.String("profiles-file", defaultProfilesFile(), "YAML or JSON file of named scan profiles (env CORTEX_PROFILES)")
 	flag.Parse()
+	started := time.Now()
+
+	var profile *scanProfile
+	if *profileName != "" {
+		var err error
+		if profile, err = applyProfile(flag.CommandLine, *profileName, *profilesFile); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+	}
+
+	if *outputFormat != "plain" && *outputFormat != "json" && !slices.Contains(scanner.ExportFormats, *outputFormat) {
+		fmt.Printf("Error: --output must be plain, json or %s\n", strings.Join(scanner.ExportFormats, ", "))
+		return
+	}
+	if *jsonOutput {
+		if *outputFormat != "plain" && *outputFormat != "json" {
+			fmt.Printf("Error: --json and --output %s cannot be combined\n", *outputFormat)
+			return
+		}
+		*outputFormat = "json"
+	}
+	if *outputFormat != "plain" && *eventsOutput {
+		fmt.Printf("Error: --output %s and --events cannot be combined\n", *outputFormat)
+		return
+	}
+	// stdout carries nothing but events in --events mode
+	var info io.Writer = os.Stdout
+	if *eventsOutput {
+		info = os.Stderr
+	}
+
+	if *hostRate < 0 || *rate < 0 {
+		fmt.Println("Error: --rate and --host-rate must not be negative")
+		return
+	}
+	if *minHostGroup < 0 || *maxHostGroup < 0 || (*maxHostGroup > 0 && *minHostGroup > *maxHostGroup) {
+		fmt.Println("Error: --min-hostgroup and --max-hostgroup must not be negative and --min-hostgroup must not exceed --max-hostgroup")
+		return
+	}
+	if *bannerBytes <= 0 || *bannerTimeout < 0 || *bannerQuiet < 0 {
+		fmt.Println("Error: --banner-bytes must be positive and --banner-timeout and --banner-quiet must not be negative")
+		return
+	}
+	timing, err := parseTiming(*timingName, timingLevels)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if *maxParallelism < 0 || *maxRetries < -1 || *retryBackoff < 0 || *initialRTT < 0 || *maxRTT < 0 || *maxDuration < 0 {
+		fmt.Println("Error: --max-parallelism, --retry-backoff, --initial-rtt-timeout, --max-rtt-timeout and --max-duration must not be negative and --max-retries must be at least -1")
+		return
+	}
+	if *maxParallelism > 0 {
+		timing.MaxParallelism = *maxParallelism
+	}
+	if *maxRetries >= 0 {
+		timing.MaxRetries = *maxRetries
+	}
+	if *retryBackoff > 0 {
+		timing.RetryBackoff = *retryBackoff
+	}
+	if *initialRTT > 0 {
+		timing.InitialTimeout = *initialRTT
+	}
+	if *maxRTT > 0 {
+		timing.MaxTimeout = *maxRTT
+	}
+	if *ping && *noPing {
+		fmt.Println("Error: --ping and -Pn cannot be combined")
+		return
+	}
+	if *versionIntensity < 0 || *versionIntensity > scanner.MaxVersionIntensity {
+		fmt.Printf("Error: --version-intensity must be between 0 and %d\n", scanner.MaxVersionIntensity)
+		return
+	}
 
 	// Load probes for service detection
 	var probeCache *scanner.ProbeCache
@@ -33,120 +181,625 @@ func Run() {
 
 	// Display parsing errors if any occurred during probe file parsing
 	if len(stats.ErrorLines) > 0 {
-		fmt.Println("--- Warnings during probe file parsing ---")
+		fmt.Fprintln(info, "--- Warnings during probe file parsing ---")
 		for _, e := range stats.ErrorLines {
-			fmt.Printf("Line %d: %s\n", e.LineNumber, e.Message)
+			fmt.Fprintf(info, "Line %d: %s\n", e.LineNumber, e.Message)
 		}
-		fmt.Println("----------------------------------------")
+		fmt.Fprintln(info, "----------------------------------------")
 	}
 
 	// Display final probe loading statistics
-	fmt.Println("--- Probe Loading Summary ---")
-	fmt.Printf("Total lines processed: %d\n", stats.TotalLines)
-	fmt.Printf("Successfully loaded probes: %d\n", stats.ProbeCount)
-	fmt.Printf("Successfully loaded match rules: %d\n", stats.MatchCount)
-	fmt.Printf("Lines with parsing errors: %d\n", len(stats.ErrorLines))
-	fmt.Println("---------------------------")
+	fmt.Fprintln(info, "--- Probe Loading Summary ---")
+	fmt.Fprintf(info, "Total lines processed: %d\n", stats.TotalLines)
+	fmt.Fprintf(info, "Successfully loaded probes: %d\n", stats.ProbeCount)
+	fmt.Fprintf(info, "Successfully loaded match rules: %d\n", stats.MatchCount)
+	fmt.Fprintf(info, "Lines with parsing errors: %d\n", len(stats.ErrorLines))
+	fmt.Fprintln(info, "---------------------------")
 
 	probeCache = scanner.NewProbeCache(probes)
 
+	services := scanner.DefaultServices()
+	if *servicesFile != "" {
+		if services, err = scanner.LoadServices(*servicesFile); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Fprintf(info, "Loaded %d port names from %s\n", services.Len(), *servicesFile)
+	}
+
 	args := flag.Args()
-	if len(args) < 2 {
+	// --services and --top-ports replace the trailing port range, so every
+	// argument is a target; --targets-file replaces the targets and leaves at
+	// most a range
+	portsByFlag := *servicesFlag != "" || *topPorts != 0
+	// A profile supplies the targets when none are given and, when it sets
+	// ports, the port range, so every argument is a target
+	if profile != nil {
+		if len(args) == 0 && *targetsFile == "" {
+			args = append([]string{}, profile.Hosts...)
+		}
+		if profile.Ports != "" && !portsByFlag && (*targetsFile == "" || len(args) == 0) {
+			args = append(args, profile.Ports)
+		}
+	}
+	switch {
+	case *servicesFlag != "" && *topPorts != 0:
+		fmt.Println("Error: --services and --top-ports cannot be combined")
+		return
+	case *targetsFile != "" && portsByFlag:
+		if len(args) > 0 {
+			printUsage()
+			return
+		}
+	case *targetsFile != "":
+		if len(args) > 1 {
+			printUsage()
+			return
+		}
+	case len(args) < 2 && (!portsByFlag || len(args) < 1):
 		printUsage()
 		return
 	}
 
 	// Determine scan worker based on flags
-	if *synScan && *udpScan {
-		fmt.Println("Error: Cannot use multiple scan modes simultaneously. Choose one: Connect, SYN (-sS), or UDP (-sU)")
+	mode := scanner.ModeConnect
+	selected := 0
+	for _, choice := range []struct {
+		set  bool
+		mode scanner.Mode
+	}{
+		{*synScan, scanner.ModeSyn},
+		{*udpScan, scanner.ModeUDP},
+		{*finScan, scanner.ModeFin},
+		{*nullScan, scanner.ModeNull},
+		{*xmasScan, scanner.ModeXmas},
+		{*ackScan, scanner.ModeAck},
+	} {
+		if choice.set {
+			mode = choice.mode
+			selected++
+		}
+	}
+	if selected > 1 {
+		fmt.Println("Error: Cannot use multiple scan modes simultaneously. Choose one: Connect, SYN (-sS), UDP (-sU), FIN (-sF), NULL (-sN), Xmas (-sX) or ACK (-sA)")
 		return
 	}
 
-	var workerFunc scanner.WorkerFunc
-	var workerCount int
+	var checks []scanner.Check
+	if *checksFlag != "" {
+		if checks, err = scanner.ParseChecks(strings.Split(*checksFlag, ",")); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+	}
+	if *httpPaths != "" && !scanner.SetHTTPPaths(checks, strings.Split(*httpPaths, ",")...) {
+		fmt.Println("Error: --http-paths requires the http check (--checks http)")
+		return
+	}
 
-	if *synScan {
-		if err := scanner.InitSynScan(); err != nil {
-			logging.Logger().Error("syn scan initialization failed", "error", err)
-			os.Exit(1)
+	prefer, err := scanner.ParseAddressPreference(*preferFlag)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	ipVersion := 0
+	switch {
+	case *ipv4Only && *ipv6Only:
+		fmt.Println("Error: -4 and -6 cannot be combined")
+		return
+	case *ipv4Only:
+		ipVersion = 4
+	case *ipv6Only:
+		ipVersion = 6
+	}
+	var dnsServers []string
+	if *dnsServersFlag != "" {
+		if dnsServers, err = scanner.ParseDNSServers(strings.Split(*dnsServersFlag, ",")); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+	}
+
+	var ports []int
+	targetArgs := args
+	protocol := "tcp"
+	if mode == scanner.ModeUDP {
+		protocol = "udp"
+	}
+	if *topPorts != 0 {
+		if ports, err = services.TopPorts(*topPorts, protocol); err != nil {
+			fmt.Printf("Error: --top-ports: %v\n", err)
+			return
+		}
+		fmt.Fprintf(info, "Scanning the top %d %s ports: %s\n", *topPorts, protocol, joinPorts(ports))
+		sort.Ints(ports)
+	} else if *servicesFlag != "" {
+		if ports, err = services.Ports(strings.Split(*servicesFlag, ","), protocol); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
 		}
-		workerFunc = scanner.TCPSynWorker
-		workerCount = 50
-	} else if *udpScan {
-		if err := scanner.InitUdpScan(); err != nil {
-			logging.Logger().Error("udp scan initialization failed", "error", err)
-			os.Exit(1)
+		if len(ports) == 0 {
+			fmt.Println("Error: --services names no ports")
+			return
+		}
+		fmt.Fprintf(info, "Scanning ports %s for services %s\n", joinPorts(ports), *servicesFlag)
+	} else if len(args) > 0 {
+		targetArgs = args[:len(args)-1]
+		spec, err := scanner.ParsePortSpec(args[len(args)-1])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		if ports = spec.Ports(protocol); len(ports) == 0 {
+			fmt.Printf("Error: port specification %s selects no %s ports\n", args[len(args)-1], protocol)
+			return
+		}
+	}
+	var hosts []string
+	var manifest []manifestEntry
+	runs := []manifestRun{{mode: mode}}
+	if *targetsFile != "" {
+		if manifest, err = readManifest(*targetsFile); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		if runs, err = planManifest(manifest, mode, ports); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		for _, entry := range manifest {
+			hosts = append(hosts, entry.Host)
 		}
-		workerFunc = scanner.UDPWorker
-		workerCount = 50
 	} else {
-		// Default: TCP Connect scan
-		workerFunc = scanner.TCPConnectWorker
-		workerCount = 100
+		if hosts, err = readTargets(targetArgs, os.Stdin); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		if hosts, err = scanner.ExpandTargets(hosts, []string{*excludeFlag}, *maxTargets); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		if len(hosts) == 0 {
+			fmt.Println("Error: every target is excluded")
+			return
+		}
+		runs[0].hosts = hosts
 	}
 
-	portRange := args[len(args)-1]
-	hosts := args[:len(args)-1]
-
-	startPort, endPort, err := parsePortRange(portRange)
+	var extraBlocked []string
+	if *blocklistFile != "" {
+		if extraBlocked, err = scanner.ReadBlocklistFile(*blocklistFile); err != nil {
+			fmt.Printf("Error: failed to read blocklist: %v\n", err)
+			return
+		}
+	}
+	blocklist, err := scanner.BlocklistFromEnv(extraBlocked...)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		return
 	}
+	for _, host := range hosts {
+		if network, blocked := blocklist.MatchLiteral(host); blocked {
+			fmt.Printf("Error: target %s is in blocked range %s\n", host, network)
+			return
+		}
+	}
+
+	// capture stays a nil interface unless --pcap-out is given
+	var capture io.Writer
+	var captureBuffer *bufio.Writer
+	if *pcapOut != "" {
+		if mode == scanner.ModeConnect {
+			fmt.Println("Error: --pcap-out requires a SYN (-sS), FIN (-sF), NULL (-sN), Xmas (-sX), ACK (-sA) or UDP (-sU) scan")
+			return
+		}
+		if len(runs) > 1 {
+			fmt.Println("Error: --pcap-out cannot record a targets file that mixes scan modes")
+			return
+		}
+		file, err := os.Create(*pcapOut)
+		if err != nil {
+			fmt.Printf("Error: failed to create pcap file: %v\n", err)
+			return
+		}
+		defer file.Close()
+		captureBuffer = bufio.NewWriter(file)
+		capture = captureBuffer
+	}
 
-	// Execute the scan with probe cache
-	scanResults := scanner.ExecuteScan(hosts, startPort, endPort, workerFunc, workerCount, probeCache)
+	var tracer *slog.Logger
+	if *packetTrace {
+		tracer = logging.Logger().With("component", "packet-trace")
+	}
+
+	options := []scanner.Option{
+		scanner.WithFallback(!*noFallback),
+		scanner.WithPorts(ports...),
+		scanner.WithProbes(probeCache),
+		scanner.WithServices(services),
+		scanner.WithRate(*rate),
+		scanner.WithHostRate(*hostRate),
+		scanner.WithAllAddresses(*allAddresses),
+		scanner.WithAddressPreference(prefer),
+		scanner.WithIPVersion(ipVersion),
+		scanner.WithDNSServers(dnsServers...),
+		scanner.WithBlocklist(blocklist),
+		scanner.WithBanner(scanner.BannerOptions{MaxBytes: *bannerBytes, ReadTimeout: *bannerTimeout, QuietPeriod: *bannerQuiet}),
+		scanner.WithVersionIntensity(*versionIntensity),
+		scanner.WithTiming(timing),
+		scanner.WithChecks(checks...),
+		scanner.WithRDAP(*rdap),
+		scanner.WithReverseDNS(*resolve),
+		scanner.WithPacketCapture(capture),
+		scanner.WithPacketTrace(tracer),
+	}
+	// A terminal on stderr gets a progress bar unless something else
+	// writes there throughout the scan
+	var progress *progressBar
+	if !*noProgress && !*eventsOutput && !*packetTrace {
+		progress = newProgressBar()
+	}
+	if progress != nil {
+		options = append(options, scanner.WithProgress(progress.update))
+	}
+	// Plain output prints every finished host group right away; JSON needs
+	// the whole document and --events already streams every result
+	printed := 0
+	var onHostGroup func([]scanner.ScanResult)
+	if *maxHostGroup > 0 && *outputFormat == "plain" && !*eventsOutput {
+		onHostGroup = func(results []scanner.ScanResult) {
+			progress.clear()
+			outputPlainText(results)
+			printed += len(results)
+		}
+	}
+	options = append(options, scanner.WithHostGroups(*minHostGroup, *maxHostGroup, onHostGroup))
+	if *ping {
+		options = append(options, scanner.WithDiscovery(scanner.DiscoveryOptions{}))
+	}
+	if *detectTarpits || *tarpitDowngrade {
+		options = append(options, scanner.WithTarpitDetection(scanner.TarpitOptions{Downgrade: *tarpitDowngrade}))
+	}
+
+	var events *eventWriter
+	if *eventsOutput {
+		events = newEventWriter(os.Stdout)
+		config := eventConfig{
+			Hosts:        hosts,
+			Mode:         string(mode),
+			Rate:         *rate,
+			HostRate:     *hostRate,
+			AllAddresses: *allAddresses,
+			Prefer:       string(prefer),
+			IPVersion:    ipVersion,
+			RDAP:         *rdap,
+			Tarpits:      *detectTarpits || *tarpitDowngrade,
+			Ping:         *ping,
+		}
+		if len(ports) > 0 {
+			config.StartPort, config.EndPort = ports[0], ports[len(ports)-1]
+		}
+		if len(ports) > 0 && ports[len(ports)-1]-ports[0]+1 != len(ports) {
+			config.Ports = ports
+		}
+		config.TargetsFile = *targetsFile
+		for _, check := range checks {
+			config.Checks = append(config.Checks, check.Name())
+		}
+		events.scanConfig(config)
+		options = append(options, scanner.WithLifecycle(events.hostStarted, events.result, events.hostFinished))
+	}
+
+	// Ctrl-C stops dispatching new probes; in-flight probes finish and the
+	// results so far are reported as partial. A second Ctrl-C kills the process.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		<-interrupts
+		signal.Stop(interrupts)
+		progress.clear()
+		fmt.Fprintln(os.Stderr, "Interrupted: waiting for probes in flight, press Ctrl-C again to abort")
+		cancel()
+	}()
+
+	// Execute the scan with probe cache, once per mode of a targets file;
+	// --max-duration spans all runs
+	var report *scanner.Report
+	deadline := time.Now().Add(*maxDuration)
+	for _, run := range runs {
+		runOptions := append(append([]scanner.Option(nil), options...), scanner.WithMode(run.mode), scanner.WithHostPorts(run.hostPorts))
+		if *maxDuration > 0 {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				if report == nil {
+					// Nothing was scanned: report an empty scan with the reason
+					report = &scanner.Report{Mode: mode}
+				}
+				report.Warnings = append(report.Warnings, fmt.Sprintf("%s scan skipped: --max-duration of %s reached", run.mode, *maxDuration))
+				continue
+			}
+			runOptions = append(runOptions, scanner.WithMaxDuration(remaining))
+		}
+		var runReport *scanner.Report
+		runReport, err = scanner.Run(ctx, run.hosts, runOptions...)
+		progress.clear()
+		report = mergeReports(report, runReport)
+		if err != nil {
+			break
+		}
+	}
+	if report != nil {
+		report.Hosts = tagHosts(report.Hosts, manifest)
+	}
+	partial := errors.Is(err, context.Canceled) && report != nil
+	if events != nil {
+		events.summary(report, err, partial)
+	}
+	if err != nil && !partial {
+		logging.Logger().Error("scan failed", "mode", mode, "error", err)
+		os.Exit(1)
+	}
+	if captureBuffer != nil {
+		if err := captureBuffer.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to write pcap file: %v\n", err)
+		}
+	}
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	if report.Mode != mode {
+		fmt.Fprintf(os.Stderr, "Scan ran in %s mode (use --no-fallback to abort instead)\n", report.Mode)
+	}
+	scanResults := report.Results
+
+	exportInfo := scanner.ExportInfo{
+		Args:    strings.Join(os.Args, " "),
+		Mode:    report.Mode,
+		Ports:   ports,
+		Start:   started,
+		End:     time.Now(),
+		Hosts:   report.Hosts,
+		Version: version.Version,
+	}
+	outputFiles := map[string]string{}
+	if *allFiles != "" {
+		outputFiles[*allFiles+".xml"] = scanner.ExportXML
+		outputFiles[*allFiles+".gnmap"] = scanner.ExportGreppable
+		outputFiles[*allFiles+".csv"] = scanner.ExportCSV
+		outputFiles[*allFiles+".json"] = "json"
+	}
+	if *xmlFile != "" {
+		outputFiles[*xmlFile] = scanner.ExportXML
+	}
+	if *grepFile != "" {
+		outputFiles[*grepFile] = scanner.ExportGreppable
+	}
+	for path, format := range outputFiles {
+		err := writeOutputFile(path, func(w io.Writer) error {
+			if format == "json" {
+				return outputJSON(w, scanResults, report.Hosts, partial)
+			}
+			return scanner.WriteExport(w, format, scanResults, exportInfo)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to write %s: %v\n", path, err)
+		}
+	}
 
 	// Output results
-	if *jsonOutput {
-		outputJSON(scanResults)
-	} else {
-		outputPlainText(scanResults)
+	switch {
+	case events != nil:
+	case *outputFormat == "json":
+		if err := outputJSON(os.Stdout, scanResults, report.Hosts, partial); err != nil {
+			fmt.Printf("Error encoding to JSON: %v\n", err)
+		}
+	case *outputFormat != "plain":
+		if err := scanner.WriteExport(os.Stdout, *outputFormat, scanResults, exportInfo); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s output: %v\n", *outputFormat, err)
+		}
+	default:
+		if partial {
+			fmt.Println("PARTIAL RESULTS: scan interrupted before all ports were probed")
+		}
+		outputHostSummaries(report.Hosts)
+		outputPlainText(scanResults[printed:])
 	}
+	if partial {
+		fmt.Fprintf(os.Stderr, "Scan interrupted: %d results collected before stopping\n", len(scanResults))
+		os.Exit(130)
+	}
+}
+
+// parseTiming returns the timing template selected by --timing or one of the
+// -T0 to -T5 flags in levels; at most one may be given.
+func parseTiming(name string, levels []*bool) (scanner.Timing, error) {
+	for level, set := range levels {
+		if !*set {
+			continue
+		}
+		if name != "" {
+			return scanner.Timing{}, fmt.Errorf("only one of --timing and -T0 to -T5 may be given")
+		}
+		name = strconv.Itoa(level)
+	}
+	return scanner.ParseTiming(name)
 }
 
 // printUsage displays the help message.
 func printUsage() {
-	fmt.Println("Usage: cortex [--json] [-sS|--syn-scan|-sU|--udp-scan] host1 host2... startPort-endPort")
+	fmt.Println("Usage: cortex [--json|--output plain|json|xml|grep|csv] [-oX file] [-oG file] [-oA basename] [-sS|--syn-scan|-sU|--udp-scan|-sF|--fin-scan|-sN|--null-scan|-sX|--xmas-scan|-sA|--ack-scan] [--no-fallback] [-T0..-T5|--timing name] [--rate|--max-rate N] [--host-rate N] [--max-parallelism N] [--max-retries N] [--retry-backoff D] [--initial-rtt-timeout D] [--max-rtt-timeout D] [--all-addresses] [--prefer ipv4|ipv6|both] [-4|-6] [--dns-servers list] [--banner-bytes N] [--banner-timeout D] [--banner-quiet D] [--version-intensity 0-9] [--ping|-Pn] [--checks list] [--http-paths list] [--rdap] [-R|--resolve] [--pcap-out file] [--packet-trace] [--blocklist file] [--services-file file] [--top-ports N] [--targets-file file] [--exclude list] [--max-targets N] [--min-hostgroup N] [--max-hostgroup N] [--max-duration D] [--detect-tarpits] [--tarpit-downgrade] [--events] [--no-progress] [--profile name] [--profiles-file file] host1 host2...|- ports|--services names|--top-ports N host1 host2...|-")
+	fmt.Println("  ports is an nmap-style list such as 22,80,443,1000-1100; - scans all ports and T:/U: limit entries to TCP or UDP")
 	fmt.Println("Example: cortex --json 127.0.0.1 scanme.nmap.org 22-80")
+	fmt.Println("Example: cortex --exclude 192.168.1.1 192.168.1.0/24 10.0.0.1-50 22-443")
 	fmt.Println("Example: cortex -sS 127.0.0.1 22-80")
+	fmt.Println("Example: cortex -oX scan.xml 10.0.0.0/24 1-1024  (nmap XML for tools that import nmap output)")
+	fmt.Println("Example: cortex -oA scans/dmz --output csv 10.0.0.0/24 1-1024")
+	fmt.Println("Example: cortex -T4 --max-rate 500 10.0.0.0/24 1-1024")
 	fmt.Println("Example: cortex -sU 127.0.0.1 53-53")
+	fmt.Println("Example: cortex -sF 192.0.2.10 1-1024")
+	fmt.Println("Example: cortex -sA 192.0.2.10 1-1024")
+	fmt.Println("Example: cortex -sS --pcap-out scan.pcap 192.0.2.10 1-1024")
+	fmt.Println("Example: cortex --host-rate 20 10.0.0.5 10.0.0.6 1-1024")
+	fmt.Println("Example: cortex --banner-bytes 16384 --banner-quiet 300ms mail.example.com 25-25")
+	fmt.Println("Example: cortex --version-intensity 2 10.0.0.5 1-1024")
+	fmt.Println("Example: cortex -sU --checks snmp 10.0.0.1 161-161")
+	fmt.Println("Example: cortex --checks http --http-paths /robots.txt,/admin/ www.example.com 80-80")
+	fmt.Println("Example: cortex --events 10.0.0.0 10.0.0.1 1-1024 | jq -c 'select(.type == \"host_finished\")'")
+	fmt.Println("Example: cortex --max-hostgroup 64 - 1-1024 < hosts.txt")
+	fmt.Println("Example: cortex --ping 192.168.1.0 192.168.1.1 192.168.1.2 1-1024")
+	fmt.Println("Example: cortex --services http,ssh,rdp 10.0.0.5 10.0.0.6")
+	fmt.Println("Example: cortex --top-ports 20 10.0.0.5")
+	fmt.Println("Example: cortex 10.0.0.5 22,80,443,8000-")
+	fmt.Println("Example: cortex --targets-file targets.yaml 1-1024")
+	fmt.Println("Example: cortex --tarpit-downgrade 198.51.100.0 198.51.100.1 1-1024")
+	fmt.Println("Example: subfinder -silent -d example.com | cortex - 80-443  (- reads newline-delimited hosts from stdin)")
+	fmt.Println("Checks (--checks name,...; 'safe' selects non-intrusive, 'all' selects every check):")
+	for _, check := range scanner.AvailableChecks() {
+		intrusive := ""
+		if check.Intrusive() {
+			intrusive = " [intrusive]"
+		}
+		fmt.Printf("  %-8s %s%s\n", check.Name(), check.Description(), intrusive)
+	}
+	fmt.Println("Probe maintenance: cortex probes <validate|stats|search>")
+	fmt.Println("Queue administration: cortex queue <status|pause|resume>")
+	fmt.Println("Scan throughput benchmark: cortex bench [--modes list] [--workers list]")
+	fmt.Println("Compare two saved scans: cortex diff [--json] baseline.json current.json")
+	fmt.Println("Saved profiles: cortex --profile name [options] [host1 host2...] reads named options from --profiles-file (default " + defaultProfilesFile() + ")")
+	fmt.Println("Emulated test services: cortex mock-target [--http port] [--ssh port] [--smtp port] [--dns port] [--config file]")
+	fmt.Println("Build information: cortex version")
 }
 
-// parsePortRange extracts start and end port from string format "start-end".
-func parsePortRange(portRange string) (int, int, error) {
-	parts := strings.Split(portRange, "-")
-	if len(parts) != 2 {
-		return 0, 0, fmt.Errorf("invalid port range format. Use startPort-endPort")
+// readTargets returns the hosts named by args, replacing a "-" argument with
+// the newline-delimited hosts read from stdin so cortex can sit at the end of
+// a pipeline such as dig +short or subfinder. Blank lines and # comments are
+// skipped and the trailing dot of fully qualified names is dropped.
+func readTargets(args []string, stdin io.Reader) ([]string, error) {
+	var hosts []string
+	readStdin := false
+	for _, arg := range args {
+		if arg != "-" {
+			hosts = append(hosts, arg)
+			continue
+		}
+		if readStdin {
+			return nil, fmt.Errorf("- may be given only once")
+		}
+		readStdin = true
+
+		before := len(hosts)
+		lines := bufio.NewScanner(stdin)
+		for lines.Scan() {
+			line := strings.TrimSpace(lines.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			hosts = append(hosts, strings.TrimSuffix(line, "."))
+		}
+		if err := lines.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read targets from stdin: %w", err)
+		}
+		if len(hosts) == before {
+			return nil, fmt.Errorf("no targets read from stdin")
+		}
 	}
+	return hosts, nil
+}
 
-	startPort, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return 0, 0, fmt.Errorf("start port is not a number: %s", parts[0])
+// joinPorts formats ports as a comma-separated list.
+func joinPorts(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, port := range ports {
+		parts[i] = strconv.Itoa(port)
 	}
+	return strings.Join(parts, ",")
+}
 
-	endPort, err := strconv.Atoi(parts[1])
+// outputJSON marshals results in JSON format and writes them to w. With host
+// summaries or partial results of an interrupted scan the results are
+// wrapped in an object next to them; otherwise the results array is written
+// on its own as before.
+func outputJSON(w io.Writer, results []scanner.ScanResult, hosts []scanner.HostSummary, partial bool) error {
+	var payload interface{} = results
+	if hosts != nil || partial {
+		payload = struct {
+			Partial bool                  `json:"partial,omitempty"`
+			Hosts   []scanner.HostSummary `json:"hosts,omitempty"`
+			Results []scanner.ScanResult  `json:"results"`
+		}{partial, hosts, results}
+	}
+	jsonData, err := json.MarshalIndent(payload, "", "  ")
 	if err != nil {
-		return 0, 0, fmt.Errorf("end port is not a number: %s", parts[1])
+		return err
 	}
-
-	return startPort, endPort, nil
+	_, err = fmt.Fprintln(w, string(jsonData))
+	return err
 }
 
-// outputJSON marshals and prints results in JSON format.
-func outputJSON(results []scanner.ScanResult) {
-	jsonData, err := json.MarshalIndent(results, "", "  ")
+// writeOutputFile creates path and fills it with write.
+func writeOutputFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
 	if err != nil {
-		fmt.Printf("Error encoding to JSON: %v\n", err)
-		return
+		return err
+	}
+	buffered := bufio.NewWriter(file)
+	if err := write(buffered); err != nil {
+		file.Close()
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// outputHostSummaries prints the network owner of each host that has one,
+// manifest tags and likely tarpits.
+func outputHostSummaries(hosts []scanner.HostSummary) {
+	for _, host := range hosts {
+		if len(host.Tags) > 0 {
+			fmt.Printf("%s - tags: %s\n", host.Host, strings.Join(host.Tags, ", "))
+		}
+		if host.Owner != nil {
+			fmt.Printf("%s (%s) - %s, %s", host.Host, host.Address, host.Owner.Netname, host.Owner.Organization)
+			if host.Owner.AbuseContact != "" {
+				fmt.Printf(", abuse: %s", host.Owner.AbuseContact)
+			}
+			fmt.Println()
+		}
+		if host.Status == scanner.HostDown {
+			fmt.Printf("%s - host down (%s), not scanned\n", host.Host, host.StatusReason)
+		}
+		if host.Tarpit != "" {
+			target := host.Host
+			if host.Address != "" && host.Address != host.Host {
+				target = fmt.Sprintf("%s (%s)", host.Host, host.Address)
+			}
+			fmt.Printf("%s - LIKELY TARPIT/HONEYPOT: %s\n", target, host.Tarpit)
+		}
 	}
-	fmt.Println(string(jsonData))
 }
 
 // outputPlainText prints results in human-readable format.
 // Displays service information for open ports when available.
 func outputPlainText(results []scanner.ScanResult) {
 	for _, result := range results {
+		// Show the probed address next to the hostname when scanning every
+		// address, and otherwise the family a hostname was scanned over
+		target := result.Host
+		if result.Address != "" && result.Address != result.Host {
+			target = fmt.Sprintf("%s (%s)", result.Host, result.Address)
+		} else if result.Family != "" && net.ParseIP(result.Host) == nil {
+			target = fmt.Sprintf("%s [%s]", result.Host, result.Family)
+		}
+		// Name an address by its PTR record as nmap's scan reports do
+		if result.ReverseHostname != "" {
+			target = fmt.Sprintf("%s (%s)", result.ReverseHostname, target)
+		}
+
 		// Print results for all port states: Open, Closed, Filtered
 		if result.Service != "" {
 			// If service information is available, display it
@@ -154,10 +807,26 @@ func outputPlainText(results []scanner.ScanResult) {
 			if len(bannerLine) > 100 {
 				bannerLine = bannerLine[:100] + "..."
 			}
-			fmt.Printf("%s:%d - %s - %s\n", result.Host, result.Port, result.State, bannerLine)
+			if result.Product != "" {
+				bannerLine += " (" + strings.TrimSpace(result.Product+" "+result.Version) + ")"
+			}
+			if result.Info != "" {
+				bannerLine += " [" + result.Info + "]"
+			}
+			fmt.Printf("%s:%d - %s - %s\n", target, result.Port, result.State, bannerLine)
+		} else if result.ServiceGuess != "" {
+			// A trailing ? marks a name guessed from the port number alone
+			fmt.Printf("%s:%d - %s - %s?\n", target, result.Port, result.State, result.ServiceGuess)
 		} else {
 			// Otherwise, show only the port state
-			fmt.Printf("%s:%d - %s\n", result.Host, result.Port, result.State)
+			fmt.Printf("%s:%d - %s\n", target, result.Port, result.State)
+		}
+
+		if cert := result.Certificate; cert != nil {
+			fmt.Printf("    %s certificate: %s, issued by %s, expires %s\n", result.TLS, cert.Subject, cert.Issuer, cert.NotAfter.Format("2006-01-02"))
+		}
+		for _, finding := range result.Findings {
+			fmt.Printf("    [%s] %s: %s\n", finding.Severity, finding.Type, finding.Summary)
 		}
 	}
 }
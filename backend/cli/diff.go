@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Viktor25104/cortex/backend/scanner"
+)
+
+// RunDiff implements `cortex diff` and returns the process exit code. It
+// compares two saved scans, each either the output of --json / -oA or a task
+// fetched from the API, and lists the ports that opened, closed or changed
+// service. Like diff(1) it exits 0 when nothing changed, 1 when something did
+// and 2 on errors.
+func RunDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "Print the changes as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		printDiffUsage()
+		return 2
+	}
+
+	baseline, err := readResultsFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	current, err := readResultsFile(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	changes := scanner.DiffResults(baseline, current)
+	if *jsonOutput {
+		if changes == nil {
+			changes = []scanner.ResultChange{}
+		}
+		jsonData, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		fmt.Println(string(jsonData))
+	} else {
+		for _, change := range changes {
+			fmt.Println(formatChange(change))
+		}
+		counts := scanner.CountChanges(changes)
+		fmt.Printf("%d opened, %d closed, %d service changed\n", counts.Opened, counts.Closed, counts.ServiceChanged)
+	}
+	if len(changes) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printDiffUsage displays the help message for cortex diff.
+func printDiffUsage() {
+	fmt.Println("Usage: cortex diff [--json] <baseline.json> <current.json>")
+	fmt.Println("  Compare two scans saved with --json or -oA, or tasks fetched from GET /api/v1/scans/{id}.")
+	fmt.Println("  Exits 0 when nothing changed and 1 when a port opened, closed or changed service.")
+}
+
+// readResultsFile loads the results of a saved scan: a bare result array as
+// printed by --json, or an object with a results field as printed by --json
+// with host summaries and returned by the API.
+func readResultsFile(path string) ([]scanner.ScanResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	var results []scanner.ScanResult
+	if bytes.HasPrefix(data, []byte("[")) {
+		err = json.Unmarshal(data, &results)
+	} else {
+		var saved struct {
+			Results []scanner.ScanResult `json:"results"`
+		}
+		err = json.Unmarshal(data, &saved)
+		results = saved.Results
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return results, nil
+}
+
+// formatChange renders one change as a line marked +, - or ~.
+func formatChange(change scanner.ResultChange) string {
+	target := change.Host
+	if strings.Contains(target, ":") {
+		// Bracket IPv6 addresses so the port stays apart
+		target = "[" + target + "]"
+	}
+	if change.Address != "" && change.Address != change.Host {
+		target += " (" + change.Address + ")"
+	}
+	target = fmt.Sprintf("%s:%d", target, change.Port)
+
+	switch change.Change {
+	case scanner.ChangeOpened:
+		previous := change.PreviousState
+		if previous == "" {
+			previous = "not scanned"
+		}
+		return strings.TrimSpace(fmt.Sprintf("+ %s opened (was %s) %s", target, previous, change.Service))
+	case scanner.ChangeClosed:
+		return strings.TrimSpace(fmt.Sprintf("- %s closed (now %s) %s", target, change.State, change.PreviousService))
+	default:
+		return fmt.Sprintf("~ %s service changed: %q -> %q", target, change.PreviousService, change.Service)
+	}
+}
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Viktor25104/cortex/backend/scanner"
+)
+
+// eventsSchemaVersion is bumped whenever a field of an existing event changes
+// meaning or is removed. Adding event types or fields keeps the version.
+const eventsSchemaVersion = 1
+
+// Event types of the --events stream, in the order they occur for one scan:
+//
+//	scan_config    once, before probing: the effective scan settings in config
+//	host_started   per target, before its first probe: host and address
+//	result         per probed port, as it arrives: the port state in result;
+//	               check findings only appear in the final --json output
+//	host_finished  per target, once all its ports have a result: host,
+//	               address and the number of open ports
+//	summary        once, last: totals, warnings, host summaries and, when the
+//	               scan failed, error; partial marks a scan stopped by Ctrl-C
+//
+// Every event is one JSON object per line carrying schema_version, type and
+// time (RFC 3339, UTC). address is set only when a hostname was split into
+// its resolved addresses with --all-addresses.
+const (
+	eventTypeScanConfig   = "scan_config"
+	eventTypeHostStarted  = "host_started"
+	eventTypeResult       = "result"
+	eventTypeHostFinished = "host_finished"
+	eventTypeSummary      = "summary"
+)
+
+// event is one line of the --events stream.
+type event struct {
+	SchemaVersion int                 `json:"schema_version"`
+	Type          string              `json:"type"`
+	Time          time.Time           `json:"time"`
+	Config        *eventConfig        `json:"config,omitempty"`
+	Host          string              `json:"host,omitempty"`
+	Address       string              `json:"address,omitempty"`
+	Open          *int                `json:"open,omitempty"`
+	Result        *scanner.ScanResult `json:"result,omitempty"`
+	Summary       *scanSummary        `json:"summary,omitempty"`
+}
+
+// eventConfig describes the scan announced by scan_config.
+type eventConfig struct {
+	Hosts        []string `json:"hosts"`
+	StartPort    int      `json:"start_port"`
+	EndPort      int      `json:"end_port"`
+	Mode         string   `json:"mode"`
+	Rate         float64  `json:"rate,omitempty"`
+	HostRate     float64  `json:"host_rate,omitempty"`
+	AllAddresses bool     `json:"all_addresses,omitempty"`
+	Prefer       string   `json:"prefer,omitempty"`
+	IPVersion    int      `json:"ip_version,omitempty"`
+	Checks       []string `json:"checks,omitempty"`
+	RDAP         bool     `json:"rdap,omitempty"`
+	Tarpits      bool     `json:"detect_tarpits,omitempty"`
+	Ping         bool     `json:"ping,omitempty"`
+	// Ports lists the probed ports when they do not form the range
+	// start_port to end_port, as with lists, --services and --top-ports.
+	Ports []int `json:"ports,omitempty"`
+	// TargetsFile names the --targets-file manifest, whose entries may
+	// override ports and mode per host.
+	TargetsFile string `json:"targets_file,omitempty"`
+}
+
+// scanSummary closes the stream.
+type scanSummary struct {
+	// Mode is the mode the scan actually ran in after any fallback.
+	Mode           string                `json:"mode,omitempty"`
+	Hosts          int                   `json:"hosts"`
+	Results        int                   `json:"results"`
+	Open           int                   `json:"open"`
+	ElapsedSeconds float64               `json:"elapsed_seconds"`
+	Warnings       []string              `json:"warnings,omitempty"`
+	HostSummaries  []scanner.HostSummary `json:"host_summaries,omitempty"`
+	Error          string                `json:"error,omitempty"`
+	// Partial is set when the scan was interrupted and not every port was probed.
+	Partial bool `json:"partial,omitempty"`
+}
+
+// eventWriter serializes events from the scanner callbacks onto w.
+type eventWriter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	started time.Time
+	hosts   int
+	results int
+	open    map[string]int
+}
+
+func newEventWriter(w io.Writer) *eventWriter {
+	return &eventWriter{encoder: json.NewEncoder(w), started: time.Now(), open: make(map[string]int)}
+}
+
+// emit writes e; the caller holds mu.
+func (w *eventWriter) emit(e event) {
+	e.SchemaVersion = eventsSchemaVersion
+	e.Time = time.Now().UTC()
+	// A closed stdout leaves nothing to report the failure to
+	_ = w.encoder.Encode(e)
+}
+
+func (w *eventWriter) scanConfig(config eventConfig) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.emit(event{Type: eventTypeScanConfig, Config: &config})
+}
+
+func (w *eventWriter) hostStarted(host, address string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.hosts++
+	w.emit(event{Type: eventTypeHostStarted, Host: host, Address: address})
+}
+
+func (w *eventWriter) result(result scanner.ScanResult) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.results++
+	if result.State == "Open" {
+		w.open[result.Host+"|"+result.Address]++
+	}
+	w.emit(event{Type: eventTypeResult, Result: &result})
+}
+
+func (w *eventWriter) hostFinished(host, address string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	open := w.open[host+"|"+address]
+	w.emit(event{Type: eventTypeHostFinished, Host: host, Address: address, Open: &open})
+}
+
+// summary writes the closing event. report may be nil when the scan failed.
+func (w *eventWriter) summary(report *scanner.Report, err error, partial bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	summary := scanSummary{Hosts: w.hosts, Results: w.results, ElapsedSeconds: time.Since(w.started).Seconds(), Partial: partial}
+	for _, open := range w.open {
+		summary.Open += open
+	}
+	if report != nil {
+		summary.Mode = string(report.Mode)
+		summary.Warnings = report.Warnings
+		summary.HostSummaries = report.Hosts
+	}
+	if err != nil {
+		summary.Error = err.Error()
+	}
+	w.emit(event{Type: eventTypeSummary, Summary: &summary})
+}
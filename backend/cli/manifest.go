@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Viktor25104/cortex/backend/scanner"
+
+	"gopkg.in/yaml.v3"
+)
+
+// manifestEntry is one target of a --targets-file manifest. Ports, mode and
+// tags are optional; the command-line port range and scan mode apply to
+// entries without them.
+type manifestEntry struct {
+	Host  string   `yaml:"host"`
+	Ports string   `yaml:"ports"`
+	Mode  string   `yaml:"mode"`
+	Tags  []string `yaml:"tags"`
+}
+
+// readManifest loads a target manifest. The file is YAML or JSON and holds
+// either a list of entries or an object with a targets list, e.g.
+//
+//	targets:
+//	  - host: web1.example.com
+//	    ports: 80,443,8080-8090
+//	    tags: [web]
+//	  - host: 10.0.0.20
+//	    ports: "5432"
+//	    tags: [db]
+//	  - host: 10.0.0.53
+//	    ports: "53,161"
+//	    mode: udp
+//
+// Every host may appear only once.
+func readManifest(path string) ([]manifestEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read targets file: %w", err)
+	}
+
+	// Peek at the top level to pick the shape, then decode strictly so that
+	// misspelled keys are reported instead of silently ignored
+	var shape interface{}
+	if err := yaml.Unmarshal(data, &shape); err != nil {
+		return nil, fmt.Errorf("targets file %s: %w", path, err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var entries []manifestEntry
+	if _, isList := shape.([]interface{}); isList {
+		if err := decoder.Decode(&entries); err != nil {
+			return nil, fmt.Errorf("targets file %s: %w", path, err)
+		}
+	} else {
+		var wrapped struct {
+			Targets []manifestEntry `yaml:"targets"`
+		}
+		if err := decoder.Decode(&wrapped); err != nil {
+			return nil, fmt.Errorf("targets file %s: %w", path, err)
+		}
+		entries = wrapped.Targets
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("targets file %s lists no targets", path)
+	}
+
+	seen := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		entry.Host = strings.TrimSpace(entry.Host)
+		if entry.Host == "" {
+			return nil, fmt.Errorf("targets file %s: entry %d has no host", path, i+1)
+		}
+		if seen[entry.Host] {
+			return nil, fmt.Errorf("targets file %s: %s is listed twice", path, entry.Host)
+		}
+		seen[entry.Host] = true
+		if entry.Mode != "" {
+			if _, err := scanner.ParseMode(entry.Mode); err != nil {
+				return nil, fmt.Errorf("targets file %s: %s: %w", path, entry.Host, err)
+			}
+		}
+		if entry.Ports != "" {
+			if _, err := scanner.ParsePortList(entry.Ports); err != nil {
+				return nil, fmt.Errorf("targets file %s: %s: %w", path, entry.Host, err)
+			}
+		}
+		entries[i] = entry
+	}
+	return entries, nil
+}
+
+// manifestRun is the part of a manifest scanned in one mode.
+type manifestRun struct {
+	mode      scanner.Mode
+	hosts     []string
+	hostPorts map[string][]int
+}
+
+// planManifest groups entries by scan mode, in the order each mode first
+// appears, since one scan run probes in a single mode. defaultPorts is nil
+// when no port range was given; every entry then needs its own ports.
+func planManifest(entries []manifestEntry, defaultMode scanner.Mode, defaultPorts []int) ([]manifestRun, error) {
+	var runs []manifestRun
+	index := make(map[scanner.Mode]int)
+	for _, entry := range entries {
+		mode := defaultMode
+		if entry.Mode != "" {
+			mode, _ = scanner.ParseMode(entry.Mode)
+		}
+		i, ok := index[mode]
+		if !ok {
+			i = len(runs)
+			index[mode] = i
+			runs = append(runs, manifestRun{mode: mode, hostPorts: make(map[string][]int)})
+		}
+		runs[i].hosts = append(runs[i].hosts, entry.Host)
+		if entry.Ports == "" {
+			if defaultPorts == nil {
+				return nil, fmt.Errorf("%s has no ports in the targets file and no port range was given", entry.Host)
+			}
+			continue
+		}
+		ports, _ := scanner.ParsePortList(entry.Ports)
+		runs[i].hostPorts[entry.Host] = ports
+	}
+	return runs, nil
+}
+
+// mergeReports appends the outcome of one more scan run to report, which is
+// nil before the first run. The mode of the first run is kept.
+func mergeReports(report, next *scanner.Report) *scanner.Report {
+	if report == nil || next == nil {
+		if report == nil {
+			return next
+		}
+		return report
+	}
+	report.Results = append(report.Results, next.Results...)
+	report.Warnings = append(report.Warnings, next.Warnings...)
+	report.Hosts = append(report.Hosts, next.Hosts...)
+	return report
+}
+
+// tagHosts adds the manifest tags of every host to its summary, creating
+// summaries for tagged hosts that have none.
+func tagHosts(summaries []scanner.HostSummary, entries []manifestEntry) []scanner.HostSummary {
+	for _, entry := range entries {
+		if len(entry.Tags) == 0 {
+			continue
+		}
+		found := false
+		for i := range summaries {
+			if summaries[i].Host == entry.Host {
+				summaries[i].Tags = entry.Tags
+				found = true
+			}
+		}
+		if !found {
+			summaries = append(summaries, scanner.HostSummary{Host: entry.Host, Tags: entry.Tags})
+		}
+	}
+	return summaries
+}
@@ -0,0 +1,401 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Service kinds emulated by cortex mock-target. dns listens on UDP, the
+// others on TCP.
+const (
+	mockHTTP = "http"
+	mockSSH  = "ssh"
+	mockSMTP = "smtp"
+	mockDNS  = "dns"
+	mockTCP  = "tcp"
+)
+
+// mockIdleTimeout closes connections of clients that stop talking.
+const mockIdleTimeout = 10 * time.Second
+
+// Default identities, chosen so the stock nmap-service-probes rules
+// fingerprint them.
+var mockDefaultBanners = map[string]string{
+	mockHTTP: "nginx/1.24.0",
+	mockSSH:  "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13",
+	mockSMTP: "220 mock.cortex.test ESMTP Postfix (Ubuntu)",
+	mockDNS:  "9.18.24-1ubuntu1-Ubuntu",
+}
+
+// mockConfig is the --config file of cortex mock-target.
+type mockConfig struct {
+	Services []mockService `yaml:"services"`
+}
+
+// mockService is one emulated service. Banner is the HTTP Server header, the
+// SSH identification string, the SMTP greeting, the DNS version.bind answer
+// or, for tcp, an optional greeting. Responses are tried in order against
+// every request and the first rule whose match text it contains answers it;
+// without a match the kind's default reply is sent.
+type mockService struct {
+	Kind      string         `yaml:"kind"`
+	Port      int            `yaml:"port"`
+	Banner    string         `yaml:"banner"`
+	Responses []mockResponse `yaml:"responses"`
+}
+
+// mockResponse is a scripted reply. For dns, match is compared with the
+// queried name and send is the IPv4 address of the A record returned.
+type mockResponse struct {
+	Match string `yaml:"match"`
+	Send  string `yaml:"send"`
+}
+
+// RunMockTarget implements `cortex mock-target` and returns the process exit
+// code. It serves emulated HTTP, SSH, SMTP and DNS services until interrupted,
+// so probe matching and full scan workflows can be exercised without
+// touching real infrastructure.
+func RunMockTarget(args []string) int {
+	fs := flag.NewFlagSet("mock-target", flag.ContinueOnError)
+	bind := fs.String("bind", "127.0.0.1", "Address to listen on")
+	configFile := fs.String("config", "", "YAML file listing the services to emulate; replaces the per-service flags")
+	httpPort := fs.Int("http", 8080, "HTTP port (0 disables)")
+	sshPort := fs.Int("ssh", 2222, "SSH port (0 disables)")
+	smtpPort := fs.Int("smtp", 2525, "SMTP port (0 disables)")
+	dnsPort := fs.Int("dns", 5353, "DNS port over UDP (0 disables)")
+	verbose := fs.Bool("verbose", false, "Print every request and the rule that answered it")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		printMockTargetUsage()
+		return 2
+	}
+
+	var services []mockService
+	if *configFile != "" {
+		var err error
+		if services, err = readMockConfig(*configFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	} else {
+		for _, service := range []mockService{
+			{Kind: mockHTTP, Port: *httpPort},
+			{Kind: mockSSH, Port: *sshPort},
+			{Kind: mockSMTP, Port: *smtpPort},
+			{Kind: mockDNS, Port: *dnsPort},
+		} {
+			if service.Port > 0 {
+				services = append(services, service)
+			}
+		}
+	}
+	if len(services) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no services to emulate")
+		return 2
+	}
+
+	for _, service := range services {
+		if service.Banner == "" {
+			service.Banner = mockDefaultBanners[service.Kind]
+		}
+		addr := net.JoinHostPort(*bind, fmt.Sprint(service.Port))
+		if err := service.listen(addr, *verbose); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s on %s: %v\n", service.Kind, addr, err)
+			return 1
+		}
+		fmt.Printf("%-5s %s %s\n", service.Kind, service.protocol(), addr)
+	}
+	fmt.Println("Serving until interrupted (Ctrl-C)")
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	<-interrupts
+	return 0
+}
+
+// printMockTargetUsage displays the help message for the mock-target subcommand.
+func printMockTargetUsage() {
+	fmt.Println("Usage: cortex mock-target [--bind addr] [--http port] [--ssh port] [--smtp port] [--dns port] [--config file] [--verbose]")
+	fmt.Println("  Emulate common services for probe and workflow testing, e.g. cortex 127.0.0.1 2222-8080 in another shell.")
+	fmt.Println("  --config file lists services instead, each with kind (http, ssh, smtp, dns, tcp), port, banner and responses:")
+	fmt.Println("    services:")
+	fmt.Println("      - kind: http")
+	fmt.Println("        port: 8080")
+	fmt.Println("        responses:")
+	fmt.Println("          - match: \"GET /admin\"")
+	fmt.Println("            send: \"HTTP/1.1 403 Forbidden\\r\\nContent-Length: 0\\r\\n\\r\\n\"")
+}
+
+// readMockConfig loads and validates a mock-target config file.
+func readMockConfig(path string) ([]mockService, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	defer file.Close()
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	var cfg mockConfig
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	seen := make(map[string]bool)
+	for i, service := range cfg.Services {
+		switch service.Kind {
+		case mockHTTP, mockSSH, mockSMTP, mockDNS, mockTCP:
+		default:
+			return nil, fmt.Errorf("config file %s: service %d has unknown kind %q", path, i+1, service.Kind)
+		}
+		if service.Port < 1 || service.Port > 65535 {
+			return nil, fmt.Errorf("config file %s: service %d needs a port between 1 and 65535", path, i+1)
+		}
+		key := fmt.Sprintf("%s/%d", service.protocol(), service.Port)
+		if seen[key] {
+			return nil, fmt.Errorf("config file %s: %s is listed twice", path, key)
+		}
+		seen[key] = true
+		if service.Kind == mockDNS {
+			for _, response := range service.Responses {
+				if ip := net.ParseIP(response.Send); ip == nil || ip.To4() == nil {
+					return nil, fmt.Errorf("config file %s: service %d: dns responses must send an IPv4 address, got %q", path, i+1, response.Send)
+				}
+			}
+		}
+	}
+	return cfg.Services, nil
+}
+
+// protocol returns the transport the service listens on.
+func (s mockService) protocol() string {
+	if s.Kind == mockDNS {
+		return "udp"
+	}
+	return "tcp"
+}
+
+// respond returns the scripted reply to request and its rule number, or
+// false when no rule matches.
+func (s mockService) respond(request string) (string, int, bool) {
+	for i, response := range s.Responses {
+		if strings.Contains(request, response.Match) {
+			return response.Send, i + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// listen starts serving s on addr in the background.
+func (s mockService) listen(addr string, verbose bool) error {
+	if s.Kind == mockDNS {
+		conn, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return err
+		}
+		go s.serveDNS(conn, verbose)
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serveTCP(conn, verbose)
+		}
+	}()
+	return nil
+}
+
+// serveTCP answers one connection: SSH, SMTP and a tcp service with a banner
+// greet first, SMTP then answers line by line and the others answer one
+// request before closing.
+func (s mockService) serveTCP(conn net.Conn, verbose bool) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(mockIdleTimeout))
+
+	if s.Kind == mockSSH || s.Kind == mockSMTP || (s.Kind == mockTCP && s.Banner != "") {
+		if _, err := conn.Write([]byte(s.Banner + "\r\n")); err != nil {
+			return
+		}
+	}
+
+	reader := bufio.NewReader(conn)
+	if s.Kind == mockSMTP {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			reply, rule, ok := s.respond(line)
+			if !ok {
+				reply = smtpReply(line)
+			}
+			s.log(verbose, conn.RemoteAddr(), line, rule)
+			if _, err := conn.Write([]byte(reply)); err != nil {
+				return
+			}
+			if strings.EqualFold(strings.TrimSpace(line), "QUIT") {
+				return
+			}
+		}
+	}
+
+	buf := make([]byte, 4096)
+	n, _ := reader.Read(buf)
+	if n == 0 {
+		return
+	}
+	request := string(buf[:n])
+	reply, rule, ok := s.respond(request)
+	if !ok && s.Kind == mockHTTP {
+		reply = httpReply(s.Banner)
+	}
+	s.log(verbose, conn.RemoteAddr(), request, rule)
+	conn.Write([]byte(reply))
+}
+
+// log prints a request and the rule that answered it (0 for the default).
+func (s mockService) log(verbose bool, from net.Addr, request string, rule int) {
+	if !verbose {
+		return
+	}
+	fmt.Printf("%s %d <- %s %q rule=%d\n", s.Kind, s.Port, from, request, rule)
+}
+
+// httpReply is the default HTTP response, identifying the server as server.
+func httpReply(server string) string {
+	body := "<html><head><title>cortex mock target</title></head><body>It works</body></html>\n"
+	return fmt.Sprintf("HTTP/1.1 200 OK\r\nServer: %s\r\nContent-Type: text/html\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s",
+		server, len(body), body)
+}
+
+// smtpReply is the default SMTP answer to one command line.
+func smtpReply(line string) string {
+	command, _, _ := strings.Cut(strings.ToUpper(strings.TrimSpace(line)), " ")
+	switch command {
+	case "EHLO", "HELO":
+		return "250 mock.cortex.test\r\n"
+	case "QUIT":
+		return "221 2.0.0 Bye\r\n"
+	case "NOOP", "RSET":
+		return "250 2.0.0 Ok\r\n"
+	default:
+		return "502 5.5.2 Error: command not recognized\r\n"
+	}
+}
+
+// DNS record types and classes answered by the mock.
+const (
+	dnsTypeA     = 1
+	dnsTypeTXT   = 16
+	dnsClassIN   = 1
+	dnsClassCH   = 3
+	dnsHeaderLen = 12
+)
+
+// serveDNS answers A queries with 127.0.0.1 or the address of a matching
+// rule and the version.bind CHAOS TXT query with the banner. Other queries
+// get an empty answer.
+func (s mockService) serveDNS(conn net.PacketConn, verbose bool) {
+	buf := make([]byte, 512)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		name, end, ok := parseDNSQuestion(buf[:n])
+		if !ok {
+			continue
+		}
+		qtype := binary.BigEndian.Uint16(buf[end-4:])
+		qclass := binary.BigEndian.Uint16(buf[end-2:])
+
+		var rdata []byte
+		rule := 0
+		switch {
+		case qtype == dnsTypeTXT && qclass == dnsClassCH && strings.EqualFold(name, "version.bind"):
+			banner := s.Banner
+			if len(banner) > 255 {
+				banner = banner[:255]
+			}
+			rdata = append([]byte{byte(len(banner))}, banner...)
+		case qtype == dnsTypeA && qclass == dnsClassIN:
+			address := "127.0.0.1"
+			if reply, matched, ok := s.respond(name); ok {
+				address, rule = reply, matched
+			}
+			rdata = net.ParseIP(address).To4()
+		}
+		s.log(verbose, from, name, rule)
+		conn.WriteTo(dnsResponse(buf[:end], rdata, qtype, qclass), from)
+	}
+}
+
+// parseDNSQuestion returns the name of the single question in query and the
+// offset just past its type and class.
+func parseDNSQuestion(query []byte) (string, int, bool) {
+	if len(query) < dnsHeaderLen || binary.BigEndian.Uint16(query[4:]) != 1 || query[2]&0x80 != 0 {
+		return "", 0, false
+	}
+	var labels []string
+	offset := dnsHeaderLen
+	for {
+		if offset >= len(query) {
+			return "", 0, false
+		}
+		length := int(query[offset])
+		offset++
+		if length == 0 {
+			break
+		}
+		if length > 63 || offset+length > len(query) {
+			return "", 0, false
+		}
+		labels = append(labels, string(query[offset:offset+length]))
+		offset += length
+	}
+	if offset+4 > len(query) {
+		return "", 0, false
+	}
+	return strings.Join(labels, "."), offset + 4, true
+}
+
+// dnsResponse turns question, the header and question of a query, into a
+// response carrying one answer with rdata, or none when rdata is nil.
+func dnsResponse(question, rdata []byte, qtype, qclass uint16) []byte {
+	response := append([]byte(nil), question...)
+	// QR and RA set, opcode and RD kept from the query, rcode NOERROR
+	response[2] = 0x80 | question[2]&0x79
+	response[3] = 0x80
+	binary.BigEndian.PutUint16(response[8:], 0)
+	binary.BigEndian.PutUint16(response[10:], 0)
+	if rdata == nil {
+		binary.BigEndian.PutUint16(response[6:], 0)
+		return response
+	}
+	binary.BigEndian.PutUint16(response[6:], 1)
+	answer := make([]byte, 12, 12+len(rdata))
+	binary.BigEndian.PutUint16(answer[0:], 0xC000|dnsHeaderLen) // pointer to the question name
+	binary.BigEndian.PutUint16(answer[2:], qtype)
+	binary.BigEndian.PutUint16(answer[4:], qclass)
+	binary.BigEndian.PutUint32(answer[6:], 60)
+	binary.BigEndian.PutUint16(answer[10:], uint16(len(rdata)))
+	return append(append(response, answer...), rdata...)
+}
@@ -0,0 +1,308 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Viktor25104/cortex/backend/scanner"
+)
+
+// defaultProbesFile is the probe database used when no file is given.
+const defaultProbesFile = "nmap-service-probes"
+
+// RunProbes implements the `cortex probes` maintenance subcommands and returns
+// the process exit code:
+//   - validate <file>: full parse with an error report; non-zero exit on any error
+//   - stats [--file f]: probe and match counts by protocol, plus skipped patterns
+//   - search [--file f] <service>: list probes and match rules for a service
+//   - bench [--file f] [--protocol p] [--rounds n] <corpus>: replay captured
+//     banners against the match rules and report latency and hit rates
+func RunProbes(args []string) int {
+	if len(args) == 0 {
+		printProbesUsage()
+		return 2
+	}
+
+	switch args[0] {
+	case "validate":
+		return probesValidate(args[1:])
+	case "stats":
+		return probesStats(args[1:])
+	case "search":
+		return probesSearch(args[1:])
+	case "bench":
+		return probesBench(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown probes subcommand %q\n", args[0])
+		printProbesUsage()
+		return 2
+	}
+}
+
+// printProbesUsage displays the help message for probes subcommands.
+func printProbesUsage() {
+	fmt.Println("Usage: cortex probes <validate|stats|search|bench> [options]")
+	fmt.Println("  cortex probes validate <file>              Parse a probe file and report every error")
+	fmt.Println("  cortex probes stats [--file f]             Show probe and match counts by protocol")
+	fmt.Println("  cortex probes search [--file f] <service>  List probes and matches for a service")
+	fmt.Println("  cortex probes bench [--file f] [--protocol TCP|UDP] [--rounds n] [--top n] <corpus-dir>")
+	fmt.Println("                                             Time match rules against captured banners, one per file")
+}
+
+// probesValidate parses the given file and reports every line that failed.
+func probesValidate(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: cortex probes validate <file>")
+		return 2
+	}
+
+	_, stats, err := scanner.LoadProbes(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	for _, e := range stats.ErrorLines {
+		fmt.Printf("%s:%d: %s\n", args[0], e.LineNumber, e.Message)
+	}
+
+	fmt.Printf("%d probes, %d match rules, %d skipped patterns, %d errors\n",
+		stats.ProbeCount, stats.MatchCount, stats.SkippedMatches, len(stats.ErrorLines))
+	if len(stats.ErrorLines) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// probesStats prints aggregate counts for a probe file.
+func probesStats(args []string) int {
+	fs := flag.NewFlagSet("probes stats", flag.ContinueOnError)
+	file := fs.String("file", defaultProbesFile, "Probe file to inspect")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	probes, stats, err := scanner.LoadProbes(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	probeCounts := make(map[string]int)
+	matchCounts := make(map[string]int)
+	services := make(map[string]struct{})
+	for _, probe := range probes {
+		probeCounts[probe.Protocol]++
+		matchCounts[probe.Protocol] += len(probe.Matches)
+		for _, match := range probe.Matches {
+			services[match.ServiceName] = struct{}{}
+		}
+	}
+
+	protocols := make([]string, 0, len(probeCounts))
+	for protocol := range probeCounts {
+		protocols = append(protocols, protocol)
+	}
+	sort.Strings(protocols)
+
+	fmt.Printf("--- Probe Statistics (%s) ---\n", *file)
+	fmt.Printf("Total lines processed: %d\n", stats.TotalLines)
+	for _, protocol := range protocols {
+		fmt.Printf("%s: %d probes, %d match rules\n", protocol, probeCounts[protocol], matchCounts[protocol])
+	}
+	fmt.Printf("Distinct services: %d\n", len(services))
+	fmt.Printf("Skipped patterns (unsupported regex): %d\n", stats.SkippedMatches)
+	fmt.Printf("Lines with parsing errors: %d\n", len(stats.ErrorLines))
+	return 0
+}
+
+// probesSearch lists every probe with match rules for the requested service.
+// The service name is compared case-insensitively as a substring so that
+// searching "http" also finds "http-proxy" and "ssl/http".
+func probesSearch(args []string) int {
+	fs := flag.NewFlagSet("probes search", flag.ContinueOnError)
+	file := fs.String("file", defaultProbesFile, "Probe file to search")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: cortex probes search [--file f] <service>")
+		return 2
+	}
+	query := strings.ToLower(fs.Arg(0))
+
+	probes, _, err := scanner.LoadProbes(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	found := 0
+	for _, probe := range probes {
+		printedHeader := false
+		for _, match := range probe.Matches {
+			if !strings.Contains(strings.ToLower(match.ServiceName), query) {
+				continue
+			}
+			if !printedHeader {
+				fmt.Printf("Probe %s %s\n", probe.Protocol, probe.Name)
+				printedHeader = true
+			}
+			fmt.Printf("  match %s %s\n", match.ServiceName, match.Pattern.String())
+			found++
+		}
+	}
+
+	if found == 0 {
+		fmt.Printf("No match rules found for service %q\n", fs.Arg(0))
+		return 1
+	}
+	fmt.Printf("%d match rules found\n", found)
+	return 0
+}
+
+// probeBench accumulates the cost and hits of one probe's match rules.
+type probeBench struct {
+	probe   *scanner.Probe
+	elapsed time.Duration
+	hits    int
+}
+
+// ruleBench accumulates the cost of one match rule evaluated on every banner.
+type ruleBench struct {
+	probe   string
+	match   *scanner.Match
+	elapsed time.Duration
+	hits    int
+}
+
+// probesBench replays every banner of a corpus directory against the match
+// rules of each probe. Per probe it reports the time to find the first
+// matching rule, as service detection does, and how many banners it
+// identified; it then lists the rules that cost the most when evaluated on
+// every banner, which are the candidates for reordering or rewriting.
+func probesBench(args []string) int {
+	fs := flag.NewFlagSet("probes bench", flag.ContinueOnError)
+	file := fs.String("file", defaultProbesFile, "Probe file to benchmark")
+	protocol := fs.String("protocol", "TCP", "Probes to benchmark: TCP or UDP")
+	rounds := fs.Int("rounds", 3, "How many times the corpus is replayed")
+	top := fs.Int("top", 10, "Number of slowest match rules to list")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 || *rounds < 1 || *top < 0 {
+		fmt.Fprintln(os.Stderr, "Usage: cortex probes bench [--file f] [--protocol TCP|UDP] [--rounds n] [--top n] <corpus-dir>")
+		return 2
+	}
+	*protocol = strings.ToUpper(*protocol)
+
+	banners, err := readBannerCorpus(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(banners) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no banners found in %s\n", fs.Arg(0))
+		return 1
+	}
+
+	probes, _, err := scanner.LoadProbes(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	var probeStats []*probeBench
+	var ruleStats []*ruleBench
+	for i := range probes {
+		probe := &probes[i]
+		if probe.Protocol != *protocol || len(probe.Matches) == 0 {
+			continue
+		}
+		stat := &probeBench{probe: probe}
+		probeStats = append(probeStats, stat)
+		for j := range probe.Matches {
+			ruleStats = append(ruleStats, &ruleBench{probe: probe.Name, match: &probe.Matches[j]})
+		}
+	}
+	if len(probeStats) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no %s probes with match rules in %s\n", *protocol, *file)
+		return 1
+	}
+
+	for round := 0; round < *rounds; round++ {
+		for _, stat := range probeStats {
+			for _, banner := range banners {
+				start := time.Now()
+				match := stat.probe.FindMatch(banner)
+				stat.elapsed += time.Since(start)
+				if match != nil && round == 0 {
+					stat.hits++
+				}
+			}
+		}
+		for _, stat := range ruleStats {
+			for _, banner := range banners {
+				start := time.Now()
+				matched := stat.match.Matches(banner)
+				stat.elapsed += time.Since(start)
+				if matched && round == 0 {
+					stat.hits++
+				}
+			}
+		}
+	}
+
+	evaluations := time.Duration(len(banners) * *rounds)
+	sort.Slice(probeStats, func(i, j int) bool { return probeStats[i].elapsed > probeStats[j].elapsed })
+	fmt.Printf("--- Probe Match Benchmark (%s, %d banners, %d rounds) ---\n", *file, len(banners), *rounds)
+	fmt.Printf("%-24s %6s %6s %7s %12s %12s\n", "PROBE", "RULES", "HITS", "HIT%", "AVG/BANNER", "TOTAL")
+	var total time.Duration
+	for _, stat := range probeStats {
+		total += stat.elapsed
+		fmt.Printf("%-24s %6d %6d %6.1f%% %12s %12s\n", stat.probe.Name, len(stat.probe.Matches), stat.hits,
+			100*float64(stat.hits)/float64(len(banners)), stat.elapsed/evaluations, stat.elapsed)
+	}
+	fmt.Printf("All %s probes: %s per banner\n", *protocol, total/evaluations)
+
+	if *top > 0 {
+		sort.Slice(ruleStats, func(i, j int) bool { return ruleStats[i].elapsed > ruleStats[j].elapsed })
+		if len(ruleStats) > *top {
+			ruleStats = ruleStats[:*top]
+		}
+		fmt.Printf("Slowest match rules (every rule tried on every banner):\n")
+		for _, stat := range ruleStats {
+			fmt.Printf("  %12s/banner  %4d hits  %s %s %s\n", stat.elapsed/evaluations, stat.hits,
+				stat.probe, stat.match.ServiceName, stat.match.Pattern.String())
+		}
+	}
+	return 0
+}
+
+// readBannerCorpus loads every regular file directly inside dir as one raw
+// service response, e.g. captured with nc host port > corpus/host_port.
+func readBannerCorpus(dir string) ([][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var banners [][]byte
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > 0 {
+			banners = append(banners, data)
+		}
+	}
+	return banners, nil
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// scanProfile holds the targets and port range of a --profile; its other
+// options are applied to the command-line flags.
+type scanProfile struct {
+	Hosts []string
+	Ports string
+}
+
+// defaultProfilesFile returns CORTEX_PROFILES or profiles.yaml in the user's
+// cortex configuration directory.
+func defaultProfilesFile() string {
+	if path := os.Getenv("CORTEX_PROFILES"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "profiles.yaml"
+	}
+	return filepath.Join(dir, "cortex", "profiles.yaml")
+}
+
+// applyProfile loads the profile called name from the YAML or JSON file at
+// path and sets every flag it names that the command line did not. The file
+// maps profile names to options, keyed by long flag name, e.g.
+//
+//	web-servers:
+//	  hosts: [10.0.0.0/24]
+//	  ports: 80,443,8000-8100
+//	  timing: aggressive
+//	  checks: [http, tls]
+//	  rdap: true
+//	databases:
+//	  mode: syn
+//	  services: [postgresql, mysql, redis]
+//
+// hosts and ports are returned rather than applied, and mode (connect, syn,
+// udp, fin, null, xmas or ack) stands for -sS, -sU, -sF, -sN, -sX and -sA.
+// Lists are joined with commas.
+func applyProfile(fs *flag.FlagSet, name, path string) (*scanProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles file: %w", err)
+	}
+	var profiles map[string]map[string]interface{}
+	if err := yaml.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("profiles file %s: %w", path, err)
+	}
+	options, ok := profiles[name]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for known := range profiles {
+			names = append(names, known)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("profile %q not found in %s (profiles: %s)", name, path, strings.Join(names, ", "))
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	profile := &scanProfile{}
+	for _, key := range keys {
+		value := options[key]
+		switch key {
+		case "hosts":
+			if list, isList := value.([]interface{}); isList {
+				for _, host := range list {
+					profile.Hosts = append(profile.Hosts, fmt.Sprint(host))
+				}
+			} else {
+				profile.Hosts = []string{fmt.Sprint(value)}
+			}
+		case "ports":
+			profile.Ports = profileValue(value)
+		case "mode":
+			if explicit["sS"] || explicit["syn-scan"] || explicit["sU"] || explicit["udp-scan"] ||
+				explicit["sF"] || explicit["fin-scan"] || explicit["sN"] || explicit["null-scan"] ||
+				explicit["sX"] || explicit["xmas-scan"] || explicit["sA"] || explicit["ack-scan"] {
+				continue
+			}
+			switch mode := profileValue(value); mode {
+			case "connect":
+			case "syn":
+				fs.Set("sS", "true")
+			case "udp":
+				fs.Set("sU", "true")
+			case "fin":
+				fs.Set("sF", "true")
+			case "null":
+				fs.Set("sN", "true")
+			case "xmas":
+				fs.Set("sX", "true")
+			case "ack":
+				fs.Set("sA", "true")
+			default:
+				return nil, fmt.Errorf("profile %s: mode must be connect, syn, udp, fin, null, xmas or ack, not %q", name, mode)
+			}
+		case "profile", "profiles-file":
+			return nil, fmt.Errorf("profile %s: %s cannot be set in a profile", name, key)
+		default:
+			if fs.Lookup(key) == nil {
+				return nil, fmt.Errorf("profile %s: unknown option %q", name, key)
+			}
+			if explicit[key] {
+				continue
+			}
+			if err := fs.Set(key, profileValue(value)); err != nil {
+				return nil, fmt.Errorf("profile %s: %s: %w", name, key, err)
+			}
+		}
+	}
+	return profile, nil
+}
+
+// profileValue renders a profile option as a flag value.
+func profileValue(value interface{}) string {
+	if list, isList := value.([]interface{}); isList {
+		items := make([]string, len(list))
+		for i, item := range list {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressInterval throttles redraws of the progress bar.
+const progressInterval = 200 * time.Millisecond
+
+// progressWidth is the number of cells of the progress bar.
+const progressWidth = 30
+
+// progressBar draws a single self-overwriting status line with the share of
+// probes finished and an estimate of the time left.
+type progressBar struct {
+	mu      sync.Mutex
+	w       io.Writer
+	started time.Time
+	drawn   time.Time
+	visible bool
+}
+
+// newProgressBar returns a bar drawing on stderr, or nil when stderr is not a
+// terminal.
+func newProgressBar() *progressBar {
+	info, err := os.Stderr.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	return &progressBar{w: os.Stderr, started: time.Now()}
+}
+
+// update redraws the bar for done of total probes, at most once per
+// progressInterval. It suits scanner.WithProgress.
+func (p *progressBar) update(done, total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if total <= 0 || now.Sub(p.drawn) < progressInterval {
+		return
+	}
+	p.drawn = now
+
+	fraction := float64(done) / float64(total)
+	if fraction > 1 {
+		fraction = 1
+	}
+	cells := int(fraction * progressWidth)
+	line := fmt.Sprintf("[%s%s] %5.1f%% %d/%d ports", strings.Repeat("#", cells), strings.Repeat(".", progressWidth-cells), fraction*100, done, total)
+	if done > 0 && done < total {
+		elapsed := now.Sub(p.started)
+		remaining := time.Duration(float64(elapsed) * float64(total-done) / float64(done))
+		line += " ETA " + remaining.Round(time.Second).String()
+	}
+	fmt.Fprintf(p.w, "\r%s\033[K", line)
+	p.visible = true
+}
+
+// clear erases the bar so other output starts on a clean line; the next
+// update draws it again.
+func (p *progressBar) clear() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.visible {
+		fmt.Fprint(p.w, "\r\033[K")
+		p.visible = false
+		p.drawn = time.Time{}
+	}
+}
@@ -1,432 +1,4819 @@
+// Package docs Code generated by swaggo/swag. DO NOT EDIT
 package docs
 
 import "github.com/swaggo/swag"
 
 const docTemplate = `{
-  "swagger": "2.0",
-  "info": {
-    "description": "Cortex exposes an asynchronous network reconnaissance pipeline that decouples request admission from long-running probe execution. Clients describe a scan once, receive a UUID acknowledging queue placement, and then poll for progress until workers deposit structured results.\nThe workflow follows three simple steps:\n1. Submit POST /scans with hosts, ports, and the probing mode best suited for the job.\n2. Store the returned identifier and periodically call GET /scans/{id} to observe lifecycle transitions from pending → running → completed (or failed).\n3. When the task reaches completed the response includes normalized port findings; failed states surface a diagnostic error message.\nEvery request—except for the interactive Swagger UI under /docs—must present the configured API key using the Authorization: Bearer <token> header. Missing or invalid credentials are rejected before any work begins.",
-    "title": "Cortex API",
-    "termsOfService": "http://swagger.io/terms/",
-    "contact": {
-      "email": "support@swagger.io",
-      "name": "API Support",
-      "url": "http://www.swagger.io/support"
-    },
-    "license": {
-      "name": "MIT",
-      "url": "https://opensource.org/licenses/MIT"
+    "schemes": {{ marshal .Schemes }},
+    "swagger": "2.0",
+    "info": {
+        "description": "{{escape .Description}}",
+        "title": "{{.Title}}",
+        "termsOfService": "http://swagger.io/terms/",
+        "contact": {
+            "name": "API Support",
+            "url": "http://www.swagger.io/support",
+            "email": "support@swagger.io"
+        },
+        "license": {
+            "name": "MIT",
+            "url": "https://opensource.org/licenses/MIT"
+        },
+        "version": "{{.Version}}"
     },
-    "version": "5.0"
-  },
-  "host": "localhost:8080",
-  "basePath": "/api/v1",
-  "schemes": [
-    "http"
-  ],
-  "paths": {
-    "/scans": {
-      "post": {
-        "consumes": [
-          "application/json"
-        ],
-        "produces": [
-          "application/json"
-        ],
-        "summary": "Create a new scan task",
-        "description": "Submit a scan definition and let Cortex execute it asynchronously. The handler validates input, persists the task, and enqueues it for background workers before returning a UUID.\n\n**Lifecycle**: POST /scans immediately answers with HTTP 202 Accepted plus the task identifier. Clients must poll GET /scans/{id} to observe status transitions (pending → running → completed/failed). Actual port findings are attached only after completion.\n\n**Common pitfalls**: malformed JSON, unsupported modes, or exceeding rate limits will return structured error responses containing a human-readable explanation.",
-        "operationId": "createScan",
-        "tags": [
-          "Scans"
-        ],
-        "security": [
-          {
-            "ApiKeyAuth": []
-          }
-        ],
-        "parameters": [
-          {
-            "description": "Scan request parameters",
-            "name": "scanRequest",
-            "in": "body",
-            "required": true,
-            "schema": {
-              "$ref": "#/definitions/CreateScanRequest"
-            }
-          }
-        ],
-        "responses": {
-          "202": {
-            "description": "Scan accepted. Poll GET /scans/{id} to track progress.",
-            "schema": {
-              "$ref": "#/definitions/ScanAcceptedResponse"
+    "host": "{{.Host}}",
+    "basePath": "{{.BasePath}}",
+    "paths": {
+        "/admin/keys": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List every managed API key, revoked ones included, oldest first. The keys themselves are never shown again; prefix identifies them.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "List API keys",
+                "responses": {
+                    "200": {
+                        "description": "Managed keys. Example: [{\\\"id\\\":\\\"6b1d2f0e-7c3a-4e59-8f21-0a9b8c7d6e5f\\\",\\\"name\\\":\\\"ci-pipeline\\\",\\\"prefix\\\":\\\"ctx_Yq3v9Kd1\\\",\\\"namespace\\\":\\\"default\\\",\\\"role\\\":\\\"submit\\\",\\\"created_at\\\":\\\"2024-01-02T15:04:05Z\\\",\\\"created_by\\\":\\\"admin\\\"}]",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.APIKey"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "The API key lacks administrative rights. Example: {\\\"error\\\":\\\"admin privileges required\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "The keys could not be read. Example: {\\\"error\\\":\\\"failed to list api keys\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
             },
-            "examples": {
-              "application/json": {
-                "id": "a3f5c62e-1234-4f72-a84a-1c2d3e4f5678",
-                "status": "pending"
-              }
-            }
-          },
-          "400": {
-            "description": "Malformed JSON body or failed validation.",
-            "schema": {
-              "$ref": "#/definitions/ErrorResponse"
-            },
-            "examples": {
-              "application/json": {
-                "error": "invalid request payload: validation failed on 'mode'"
-              }
-            }
-          },
-          "401": {
-            "description": "Missing or incorrect API key.",
-            "schema": {
-              "$ref": "#/definitions/ErrorResponse"
-            },
-            "examples": {
-              "application/json": {
-                "error": "unauthorized"
-              }
-            }
-          },
-          "429": {
-            "description": "Rate limit exceeded for the calling client.",
-            "schema": {
-              "$ref": "#/definitions/ErrorResponse"
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Create a bearer key for a tenant namespace with a role and, optionally, rate limits of its own. The key is returned only in this response; the server keeps just its hash. Keys configured through CORTEX_API_KEY and CORTEX_API_KEYS keep working alongside managed keys.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Create an API key",
+                "parameters": [
+                    {
+                        "description": "Key definition",
+                        "name": "keyRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.CreateAPIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Key created. Example: {\\\"id\\\":\\\"6b1d2f0e-7c3a-4e59-8f21-0a9b8c7d6e5f\\\",\\\"name\\\":\\\"ci-pipeline\\\",\\\"prefix\\\":\\\"ctx_Yq3v9Kd1\\\",\\\"namespace\\\":\\\"default\\\",\\\"role\\\":\\\"submit\\\",\\\"write_limit\\\":20,\\\"created_at\\\":\\\"2024-01-02T15:04:05Z\\\",\\\"created_by\\\":\\\"admin\\\",\\\"key\\\":\\\"ctx_Yq3v9Kd1c8Zr2Lw0Hs5Nt7Bx4Ma6Pe1Vf9Gj3Qu0Ek\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.CreatedAPIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed JSON body or failed validation. Example: {\\\"error\\\":\\\"invalid request payload\\\",\\\"details\\\":[{\\\"field\\\":\\\"role\\\",\\\"rule\\\":\\\"oneof\\\",\\\"message\\\":\\\"role must be one of: admin submit read-only\\\"}]}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ValidationErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "The API key lacks administrative rights. Example: {\\\"error\\\":\\\"admin privileges required\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "The key could not be stored. Example: {\\\"error\\\":\\\"failed to persist api key\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stop a managed API key from authenticating, effective with the next request. The key's record is kept with revoked_at set; scans it submitted are unaffected. Revoking a revoked key returns it unchanged.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Key revoked. Example: {\\\"id\\\":\\\"6b1d2f0e-7c3a-4e59-8f21-0a9b8c7d6e5f\\\",\\\"name\\\":\\\"ci-pipeline\\\",\\\"prefix\\\":\\\"ctx_Yq3v9Kd1\\\",\\\"namespace\\\":\\\"default\\\",\\\"role\\\":\\\"submit\\\",\\\"created_at\\\":\\\"2024-01-02T15:04:05Z\\\",\\\"created_by\\\":\\\"admin\\\",\\\"revoked_at\\\":\\\"2024-02-01T09:00:00Z\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.APIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed key identifier. Example: {\\\"error\\\":\\\"invalid api key id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "The API key lacks administrative rights. Example: {\\\"error\\\":\\\"admin privileges required\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No key with this ID exists. Example: {\\\"error\\\":\\\"api key not found\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "The key could not be revoked. Example: {\\\"error\\\":\\\"failed to revoke api key\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/queue": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Report whether the scan queue is paused, who paused it and when, and how many tasks are waiting. Each scan mode has its own queue and worker pool; pools lists their depth and the pool sizes of the answering node.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Get scan queue state",
+                "responses": {
+                    "200": {
+                        "description": "Current queue state. Example: {\\\"paused\\\":false,\\\"depth\\\":3,\\\"pools\\\":[{\\\"mode\\\":\\\"connect\\\",\\\"workers\\\":5,\\\"depth\\\":0},{\\\"mode\\\":\\\"syn\\\",\\\"workers\\\":2,\\\"depth\\\":1},{\\\"mode\\\":\\\"udp\\\",\\\"workers\\\":2,\\\"depth\\\":2}]}",
+                        "schema": {
+                            "$ref": "#/definitions/api.QueueStatus"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "The API key lacks administrative rights. Example: {\\\"error\\\":\\\"admin privileges required\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Queue state could not be read. Example: {\\\"error\\\":\\\"failed to read queue state\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/queue/pause": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stop workers from taking new tasks, for maintenance windows and incident freezes. Running tasks finish normally and new submissions are still accepted and queued. Pausing an already paused queue refreshes the pause metadata.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Pause the scan queue",
+                "responses": {
+                    "200": {
+                        "description": "Queue paused. Example: {\\\"paused\\\":true,\\\"paused_at\\\":\\\"2024-01-02T15:04:05Z\\\",\\\"paused_by\\\":\\\"admin\\\",\\\"depth\\\":3}",
+                        "schema": {
+                            "$ref": "#/definitions/api.QueueStatus"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "The API key lacks administrative rights. Example: {\\\"error\\\":\\\"admin privileges required\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Queue state could not be changed. Example: {\\\"error\\\":\\\"failed to pause queue\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/queue/resume": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Let workers take tasks from the queue again after a pause. Resuming a running queue is a no-op.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Resume the scan queue",
+                "responses": {
+                    "200": {
+                        "description": "Queue running. Example: {\\\"paused\\\":false,\\\"depth\\\":3}",
+                        "schema": {
+                            "$ref": "#/definitions/api.QueueStatus"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "The API key lacks administrative rights. Example: {\\\"error\\\":\\\"admin privileges required\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Queue state could not be changed. Example: {\\\"error\\\":\\\"failed to resume queue\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/workers": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List the processes currently taking scans from the queue: API servers running worker pools and standalone workers started with cortex --worker. Every node renews its entry each CORTEX_WORKER_HEARTBEAT; nodes silent for CORTEX_WORKER_STALE_AFTER are dropped.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "List worker nodes",
+                "responses": {
+                    "200": {
+                        "description": "Registered nodes ordered by ID. Example: [{\\\"id\\\":\\\"scanner-eu-1\\\",\\\"hostname\\\":\\\"scanner-eu-1.internal\\\",\\\"role\\\":\\\"worker\\\",\\\"version\\\":\\\"v1.4.0\\\",\\\"capabilities\\\":[\\\"connect\\\",\\\"syn\\\",\\\"udp\\\"],\\\"workers\\\":{\\\"connect\\\":5,\\\"syn\\\":2,\\\"udp\\\":2},\\\"started_at\\\":\\\"2024-01-02T15:04:05Z\\\",\\\"last_seen\\\":\\\"2024-01-02T15:10:05Z\\\"}]",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.WorkerNode"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "The API key lacks administrative rights. Example: {\\\"error\\\":\\\"admin privileges required\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "The registry could not be read. Example: {\\\"error\\\":\\\"failed to list workers\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/hosts": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List every host ever scanned with its currently open ports and last scan time, narrowed by optional filters. All filters must match; port and service must match the same open port. For example ?port=3389 answers where anything is listening on 3389.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Inventory"
+                ],
+                "summary": "Find hosts by open port, service or tag",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Only hosts with this port currently open",
+                        "name": "port",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "tcp",
+                            "udp"
+                        ],
+                        "type": "string",
+                        "description": "Restrict the port and service filters to tcp or udp",
+                        "name": "protocol",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only hosts with an open port whose service fingerprint contains this text (case-insensitive)",
+                        "name": "service",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only hosts carrying this tag",
+                        "name": "tag",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Matching hosts. Example: {\\\"count\\\":1,\\\"hosts\\\":[{\\\"host\\\":\\\"203.0.113.50\\\",\\\"last_seen\\\":\\\"2024-01-09T15:04:05Z\\\",\\\"ports\\\":[{\\\"port\\\":3389,\\\"protocol\\\":\\\"tcp\\\",\\\"service\\\":\\\"ms-wbt-server\\\"}]}]}",
+                        "schema": {
+                            "$ref": "#/definitions/api.HostListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter value. Example: {\\\"error\\\":\\\"invalid request payload\\\",\\\"details\\\":[{\\\"field\\\":\\\"port\\\",\\\"rule\\\":\\\"range\\\",\\\"message\\\":\\\"port must be an integer between 0 and 65535\\\"}]}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ValidationErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while loading the inventory. Example: {\\\"error\\\":\\\"failed to load inventory\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/inventory": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Return the aggregated inventory of every host the caller has scanned: currently open ports with their latest service fingerprint and first/last seen timestamps. The inventory is updated by every completed scan and is kept after the scans themselves expire.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Inventory"
+                ],
+                "summary": "List the asset inventory",
+                "responses": {
+                    "200": {
+                        "description": "Inventory records sorted by host.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.InventoryHost"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while loading the inventory. Example: {\\\"error\\\":\\\"failed to load inventory\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/inventory/{host}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Return the aggregated inventory record of a single host as submitted in scans.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Inventory"
+                ],
+                "summary": "Get the inventory of one host",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Host as submitted in scans",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Inventory record of the host.",
+                        "schema": {
+                            "$ref": "#/definitions/api.InventoryHost"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "The host has never been scanned. Example: {\\\"error\\\":\\\"host not found\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while loading the inventory. Example: {\\\"error\\\":\\\"failed to load inventory\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/monitors": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Return every monitor of the caller with the outcome of its last verification scan, oldest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Monitors"
+                ],
+                "summary": "List monitors",
+                "responses": {
+                    "200": {
+                        "description": "Monitors of the caller.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.Monitor"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while loading monitors. Example: {\\\"error\\\":\\\"failed to load monitors\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
             },
-            "examples": {
-              "application/json": {
-                "error": "rate limit exceeded"
-              }
-            }
-          },
-          "500": {
-            "description": "Internal error while persisting or queueing the task.",
-            "schema": {
-              "$ref": "#/definitions/ErrorResponse"
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Declare the desired state of an asset (the ports expected to be open within a range) and how often to verify it. The scheduler queues a verification scan right away and then once per interval; each scan is diffed against the previous one, so the completion webhook fires only when something changed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Monitors"
+                ],
+                "summary": "Create a monitor",
+                "parameters": [
+                    {
+                        "description": "Monitor definition",
+                        "name": "monitorRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.CreateMonitorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Monitor created. Example: {\\\"id\\\":\\\"0f8e3a6d-2c41-4b9e-9a57-3d6c1e2b4f80\\\",\\\"host\\\":\\\"203.0.113.50\\\",\\\"ports\\\":\\\"1-1024\\\",\\\"mode\\\":\\\"connect\\\",\\\"expected_open\\\":[80,443],\\\"interval\\\":\\\"1h\\\",\\\"created_at\\\":\\\"2024-01-02T15:04:05Z\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.Monitor"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed JSON body or failed validation. Example: {\\\"error\\\":\\\"invalid request payload\\\",\\\"details\\\":[{\\\"field\\\":\\\"interval\\\",\\\"rule\\\":\\\"min\\\",\\\"message\\\":\\\"interval must be at least 1m0s\\\"}]}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ValidationErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while persisting the monitor. Example: {\\\"error\\\":\\\"failed to persist monitor\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/monitors/drift": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List the monitors whose last verification scan found ports open that should not be, or expected ports that were not open. Monitors without a finished verification are counted as unchecked.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Monitors"
+                ],
+                "summary": "Report drifted assets",
+                "responses": {
+                    "200": {
+                        "description": "Assets out of compliance. Example: {\\\"monitors\\\":12,\\\"unchecked\\\":1,\\\"drifted\\\":[{\\\"id\\\":\\\"0f8e3a6d-2c41-4b9e-9a57-3d6c1e2b4f80\\\",\\\"host\\\":\\\"203.0.113.50\\\",\\\"compliant\\\":false,\\\"unexpected\\\":[3389]}]}",
+                        "schema": {
+                            "$ref": "#/definitions/api.DriftReport"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while loading monitors. Example: {\\\"error\\\":\\\"failed to load monitors\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/monitors/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Return one monitor with the outcome of its last verification scan.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Monitors"
+                ],
+                "summary": "Get a monitor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Monitor ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Monitor definition and last verification outcome.",
+                        "schema": {
+                            "$ref": "#/definitions/api.Monitor"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed monitor identifier. Example: {\\\"error\\\":\\\"invalid monitor id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Monitor with the provided ID does not exist. Example: {\\\"error\\\":\\\"monitor not found\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error when loading the monitor. Example: {\\\"error\\\":\\\"failed to load monitor\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
             },
-            "examples": {
-              "application/json": {
-                "error": "failed to queue task"
-              }
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stop verifying an asset. Verification scans already queued still run, and past scans are kept until the retention janitor removes them.",
+                "tags": [
+                    "Monitors"
+                ],
+                "summary": "Delete a monitor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Monitor ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Monitor deleted."
+                    },
+                    "400": {
+                        "description": "Malformed monitor identifier. Example: {\\\"error\\\":\\\"invalid monitor id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error when deleting the monitor. Example: {\\\"error\\\":\\\"failed to delete monitor\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
             }
-          }
-        }
-      }
-    },
-    "/scans/{id}": {
-      "get": {
-        "produces": [
-          "application/json"
-        ],
-        "summary": "Get scan status and results",
-        "description": "Retrieve a live snapshot of a scan task. Supply the UUID obtained from POST /scans and poll this endpoint until the lifecycle reaches completed.\n\n**Polling guidance**: responses with status pending or running will include metadata but results remains empty. Once the task is completed, results contains every observed port state and optional service fingerprints. If the task fails, the error field clarifies the reason.\n\n**Error handling**: invalid UUIDs, missing authorization, rate limiting, or unknown tasks all return structured ErrorResponse payloads so clients can adjust behavior programmatically.",
-        "operationId": "getScan",
-        "tags": [
-          "Scans"
-        ],
-        "security": [
-          {
-            "ApiKeyAuth": []
-          }
-        ],
-        "parameters": [
-          {
-            "type": "string",
-            "description": "Scan Task ID (UUID v4)",
-            "name": "id",
-            "in": "path",
-            "required": true
-          }
-        ],
-        "responses": {
-          "200": {
-            "description": "Current task snapshot including results when completed.",
-            "schema": {
-              "$ref": "#/definitions/ScanTask"
+        },
+        "/scans": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Enumerate the caller's scan tasks, newest first, narrowed by optional filters and paged with limit and offset. Shards of sharded scans are not listed separately; they appear in the shards of their task.\n**Paging**: request the following page with the next_offset of the response until it is absent. Tasks created between requests shift later pages, so use created_after to pin the window when that matters.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Scans"
+                ],
+                "summary": "List scans",
+                "parameters": [
+                    {
+                        "enum": [
+                            "pending",
+                            "held",
+                            "running",
+                            "pausing",
+                            "paused",
+                            "cancelling",
+                            "cancelled",
+                            "completed",
+                            "failed"
+                        ],
+                        "type": "string",
+                        "description": "Only tasks in this state",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "connect",
+                            "syn",
+                            "udp"
+                        ],
+                        "type": "string",
+                        "description": "Only tasks of this scan mode",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only tasks submitted by this API key name or JWT subject; me selects the caller's own",
+                        "name": "owner",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only tasks created at or after this RFC3339 timestamp",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 1-500 (default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Matching tasks to skip (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "One page of tasks. Example: {\\\"count\\\":1,\\\"scans\\\":[{\\\"id\\\":\\\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\\\",\\\"status\\\":\\\"completed\\\",\\\"hosts\\\":[\\\"scanme.nmap.org\\\"],\\\"ports\\\":\\\"1-1024\\\",\\\"mode\\\":\\\"connect\\\",\\\"created_at\\\":\\\"2024-01-02T15:04:05Z\\\"}],\\\"next_offset\\\":50}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ScanListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter or paging value. Example: {\\\"error\\\":\\\"invalid request payload\\\",\\\"details\\\":[{\\\"field\\\":\\\"limit\\\",\\\"rule\\\":\\\"range\\\",\\\"message\\\":\\\"limit must be an integer between 1 and 500\\\"}]}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ValidationErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while loading tasks. Example: {\\\"error\\\":\\\"failed to list tasks\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
             },
-            "examples": {
-              "application/json": {
-                "id": "a3f5c62e-1234-4f72-a84a-1c2d3e4f5678",
-                "status": "completed",
-                "results": [
-                  {
-                    "host": "scanme.nmap.org",
-                    "port": 443,
-                    "state": "Open",
-                    "service": "https"
-                  }
-                ]
-              }
-            }
-          },
-          "400": {
-            "description": "Malformed task identifier.",
-            "schema": {
-              "$ref": "#/definitions/ErrorResponse"
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Submit a scan definition and let Cortex execute it asynchronously. The handler validates input, persists the task, and enqueues it for background workers before returning a UUID.\n**Lifecycle**: POST /scans immediately answers with HTTP 202 Accepted plus the task identifier. Clients must poll GET /scans/{id} to observe status transitions (pending → running → completed/failed). Actual port findings are attached only after completion.\n**Result reuse**: with reuse_within set, ports another scan of the caller probed within that window are not probed again; their results are copied and marked reused. The window is capped by the server's CORTEX_RESULT_REUSE_MAX and rejected when reuse is disabled.\n**Sharding**: with shard_size set and more hosts than that, the scan is split into shard tasks of at most shard_size hosts that are queued separately, so several workers scan in parallel. The returned task lists them in shards and carries the combined results once the last shard finishes.\n**Common pitfalls**: malformed JSON, unsupported modes, or exceeding rate limits will return structured error responses containing a human-readable explanation.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Scans"
+                ],
+                "summary": "Create a new scan task",
+                "parameters": [
+                    {
+                        "description": "Scan request parameters",
+                        "name": "scanRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.CreateScanRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Scan accepted. Poll GET /scans/{id} to track progress. Example: {\\\"id\\\":\\\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\\\",\\\"status\\\":\\\"pending\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ScanAcceptedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed JSON body or failed validation. Example: {\\\"error\\\":\\\"invalid request payload\\\",\\\"details\\\":[{\\\"field\\\":\\\"mode\\\",\\\"rule\\\":\\\"oneof\\\",\\\"message\\\":\\\"mode must be one of: connect syn udp\\\"}]}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ValidationErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body larger than the configured limit. Example: {\\\"error\\\":\\\"request body exceeds the 1048576 byte limit\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while persisting or queueing the task. Example: {\\\"error\\\":\\\"failed to persist task\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scans/estimate": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Validate a scan definition exactly like POST /scans and predict its size and duration without queueing anything. Hostnames are resolved when all_addresses or prefer=both is set or a blocklist is configured, so the answer reflects the targets a worker would actually probe.\n**Prediction**: when recent completed scans of the same mode exist, the duration is derived from their measured probes per second (basis history). Otherwise it is computed from worker count, the timing template's initial probe timeout and parallelism, max_rate and host_rate, assuming every port is filtered (basis timing).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Scans"
+                ],
+                "summary": "Estimate a scan before submitting it",
+                "parameters": [
+                    {
+                        "description": "Scan request parameters",
+                        "name": "scanRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.CreateScanRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Predicted scan size and duration. Example: {\\\"targets\\\":2,\\\"ports\\\":1024,\\\"jobs\\\":2048,\\\"predicted_seconds\\\":41.5,\\\"basis\\\":\\\"history\\\",\\\"historical_throughput\\\":49.3,\\\"samples\\\":12}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ScanEstimateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed JSON body or failed validation. Example: {\\\"error\\\":\\\"invalid request payload\\\",\\\"details\\\":[{\\\"field\\\":\\\"ports\\\",\\\"rule\\\":\\\"format\\\",\\\"message\\\":\\\"invalid port range format. Use startPort-endPort\\\"}]}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ValidationErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body larger than the configured limit. Example: {\\\"error\\\":\\\"request body exceeds the 1048576 byte limit\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while loading scan history. Example: {\\\"error\\\":\\\"failed to load scan history\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scans/upload": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Submit a scan whose targets come from a file sent as multipart/form-data, for host lists too large to embed comfortably in a JSON body. The scan is validated, persisted and queued exactly like POST /scans.\n**Target file**: the targets part is either a plain list with one host per line (blank lines and # comments are ignored) or a target manifest in YAML or JSON, given as a list of entries or as an object with a targets list. Manifest entries take the fields of the targets request property: host, ports, mode and tags.\n**Options**: the optional options part holds every other CreateScanRequest property as a JSON object, e.g. {\"ports\":\"1-1024\",\"mode\":\"connect\"}. hosts and targets are not allowed there. The body limit of this endpoint is CORTEX_MAX_UPLOAD_BYTES instead of CORTEX_MAX_BODY_BYTES.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Scans"
+                ],
+                "summary": "Create a scan from an uploaded target file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Target file: plain host list or YAML/JSON manifest",
+                        "name": "targets",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Scan options as a JSON CreateScanRequest without hosts and targets",
+                        "name": "options",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Scan accepted. Poll GET /scans/{id} to track progress. Example: {\\\"id\\\":\\\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\\\",\\\"status\\\":\\\"pending\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ScanAcceptedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing or unreadable target file, malformed options or failed validation. Example: {\\\"error\\\":\\\"invalid request payload\\\",\\\"details\\\":[{\\\"field\\\":\\\"targets\\\",\\\"rule\\\":\\\"required\\\",\\\"message\\\":\\\"targets file is required\\\"}]}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ValidationErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body larger than the configured upload limit. Example: {\\\"error\\\":\\\"request body exceeds the 16777216 byte limit\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while persisting or queueing the task. Example: {\\\"error\\\":\\\"failed to persist task\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scans/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieve a live snapshot of a scan task. Supply the UUID obtained from POST /scans and poll this endpoint until the lifecycle reaches completed.\n**Polling guidance**: responses with status pending or running will include metadata but results remains empty unless partial=true is given, which fills results with the ports probed so far, in the order they finished. Once the task is completed, results contains every observed port state and optional service fingerprints. If the task fails, the error field clarifies the reason. GET /scans/{id}/events pushes results as they are found instead.\n**Error handling**: invalid UUIDs, missing authorization, rate limiting, or unknown tasks all return structured ErrorResponse payloads so clients can adjust behavior programmatically.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Scans"
+                ],
+                "summary": "Get scan status and results",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scan Task ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the results found so far while the task is unfinished",
+                        "name": "partial",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Current task snapshot including results when completed. Example: {\\\"id\\\":\\\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\\\",\\\"status\\\":\\\"completed\\\",\\\"results\\\":[{\\\"host\\\":\\\"scanme.nmap.org\\\",\\\"port\\\":443,\\\"state\\\":\\\"Open\\\",\\\"service\\\":\\\"https\\\"}]}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ScanTask"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed task identifier or partial flag. Example: {\\\"error\\\":\\\"invalid task id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Task with the provided ID does not exist. Example: {\\\"error\\\":\\\"task not found\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error when loading the task. Example: {\\\"error\\\":\\\"failed to load task\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
             },
-            "examples": {
-              "application/json": {
-                "error": "invalid task id format"
-              }
-            }
-          },
-          "401": {
-            "description": "Missing or incorrect API key.",
-            "schema": {
-              "$ref": "#/definitions/ErrorResponse"
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Irreversibly delete a finished or paused scan task together with its results, its shards and the entries it left in the result reuse cache. Requires an admin API key.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Scans"
+                ],
+                "summary": "Delete a scan and all its data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scan Task ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Task deleted. Example: {\\\"id\\\":\\\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\\\",\\\"results\\\":1024,\\\"recent_results\\\":1024,\\\"tasks\\\":1}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ScanPurgeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed task identifier. Example: {\\\"error\\\":\\\"invalid task id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks admin rights. Example: {\\\"error\\\":\\\"admin privileges required\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Task with the provided ID does not exist. Example: {\\\"error\\\":\\\"task not found\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Task is still active or is a shard. Example: {\\\"error\\\":\\\"task is a shard of 5b0e7c1a-9d2f-4e3b-8a6c-2f1d0e9b7a44; purge that task instead\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while deleting the task. Example: {\\\"error\\\":\\\"failed to delete task\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scans/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stop a scan for good. A pending, held or paused task is taken off the queue and cancelled at once. A running task reports cancelling while its worker lets in-flight probes finish, then cancelled. Cancelled is a terminal state: the results collected so far are kept, but no completion webhook is sent and the inventory is not updated.\n**Sharding**: cancelling a sharded task cancels each of its unfinished shards. The task becomes cancelled with the results of every shard once the last one stopped.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Scans"
+                ],
+                "summary": "Cancel a scan",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scan Task ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Cancellation accepted. Example: {\\\"id\\\":\\\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\\\",\\\"status\\\":\\\"cancelling\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ScanStateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed task identifier. Example: {\\\"error\\\":\\\"invalid task id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Task with the provided ID does not exist. Example: {\\\"error\\\":\\\"task not found\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Task already finished. Example: {\\\"error\\\":\\\"task is completed and cannot be cancelled\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while updating the task. Example: {\\\"error\\\":\\\"failed to cancel task\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scans/{id}/diff/{otherId}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Compare the results of two completed scans, typically two runs against the same targets. The task in the path is the baseline and otherId the later scan: ports open in otherId but not in the baseline are reported as opened, ports open in the baseline but no longer open as closed, and ports open in both whose service fingerprint, product or version differs as service_changed. Ports otherId did not probe are ignored, so comparing against a narrower scan does not report everything outside its range as closed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Scans"
+                ],
+                "summary": "Compare two scans",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Baseline Scan Task ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Later Scan Task ID (UUID v4)",
+                        "name": "otherId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Differences between the two scans",
+                        "schema": {
+                            "$ref": "#/definitions/api.ScanDiffResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed task identifier. Example: {\\\"error\\\":\\\"invalid task id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Either task does not exist. Example: {\\\"error\\\":\\\"task 5b0e7c1a-9d2f-4e3b-8a6c-2f1d0e9b7a44 not found\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Either task has not completed. Example: {\\\"error\\\":\\\"task 5b0e7c1a-9d2f-4e3b-8a6c-2f1d0e9b7a44 is running, not completed\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error when loading a task. Example: {\\\"error\\\":\\\"failed to load task\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scans/{id}/events": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Follow a scan while it runs. Every result is sent as a result event as soon as the worker has written it, at most about a second after it was found, and every status change as a status event. The stream ends after the status event of a terminal state (completed, failed or cancelled).\n**Reconnecting**: result events of unsharded tasks carry their position as the event id; a client that reconnects with Last-Event-ID continues after that result. Results carried over from before a pause are not sent again.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Scans"
+                ],
+                "summary": "Stream scan progress as server-sent events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scan Task ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Id of the last result event received before reconnecting",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event stream. Example: event: result\\nid: 1\\ndata: {\\\"host\\\":\\\"scanme.nmap.org\\\",\\\"port\\\":22,\\\"state\\\":\\\"Open\\\",\\\"service\\\":\\\"ssh\\\"}\\n\\nevent: status\\ndata: {\\\"id\\\":\\\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\\\",\\\"status\\\":\\\"completed\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/scanner.ScanResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed task identifier or Last-Event-ID. Example: {\\\"error\\\":\\\"invalid task id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Task with the provided ID does not exist. Example: {\\\"error\\\":\\\"task not found\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error when loading the task. Example: {\\\"error\\\":\\\"failed to load task\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scans/{id}/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Download the results of a finished scan in a format other tools read. format=xml (the default) produces the XML nmap writes with -oX: one host element per scanned host with its address, hostname and up or down status, port elements with state and reason, service elements with the product, version and CPE of identified services, and script elements for raw banners, TLS certificates and check findings. Tools that import nmap XML (Metasploit db_import, vulnerability managers, ndiff-style tooling) can consume it directly.\nformat=grep produces nmap's greppable -oG format, a Status and a Ports line per host, and format=csv one row per result with a header row (host, address, port, protocol, state, service, product, version, info, tls, cpe, service_guess).",
+                "produces": [
+                    "application/xml",
+                    "text/plain",
+                    "text/csv"
+                ],
+                "tags": [
+                    "Scans"
+                ],
+                "summary": "Export scan results",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scan Task ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "xml",
+                            "grep",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "description": "Export format (default xml)",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The exported results. Example: \u003cnmaprun scanner=\\\"cortex\\\" ...\u003e\u003chost\u003e\u003cstatus state=\\\"up\\\" .../\u003e\u003caddress addr=\\\"45.33.32.156\\\" addrtype=\\\"ipv4\\\"/\u003e\u003cports\u003e\u003cport protocol=\\\"tcp\\\" portid=\\\"22\\\"\u003e\u003cstate state=\\\"open\\\" .../\u003e\u003cservice name=\\\"ssh\\\" product=\\\"OpenSSH\\\" .../\u003e\u003c/port\u003e\u003c/ports\u003e\u003c/host\u003e\u003c/nmaprun\u003e",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed task identifier or unknown format. Example: {\\\"error\\\":\\\"format must be one of: xml grep csv\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Task with the provided ID does not exist. Example: {\\\"error\\\":\\\"task not found\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "The scan has not finished. Example: {\\\"error\\\":\\\"task is running; export it once it finishes\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error when loading the task. Example: {\\\"error\\\":\\\"failed to load task\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scans/{id}/pause": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stop dispatching the probes of a pending or running scan, for example when it impacts production. A pending task is taken off the queue and paused at once. A running task reports pausing while its worker lets in-flight probes finish, then paused; the results collected so far are stored with the task.\n**Sharding**: pausing a sharded task pauses each of its unfinished shards, which carry the paused state; the task itself keeps its status.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Scans"
+                ],
+                "summary": "Pause a scan",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scan Task ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Pause accepted. Example: {\\\"id\\\":\\\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\\\",\\\"status\\\":\\\"pausing\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ScanStateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed task identifier. Example: {\\\"error\\\":\\\"invalid task id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Task with the provided ID does not exist. Example: {\\\"error\\\":\\\"task not found\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Task is neither pending nor running. Example: {\\\"error\\\":\\\"task is completed and cannot be paused\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while updating the task. Example: {\\\"error\\\":\\\"failed to pause task\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scans/{id}/results": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Irreversibly remove the stored results, host summaries and baseline changes of a finished or paused scan, for data retention and right-to-erasure requests. Entries the scan left in the result reuse cache are removed too. The task itself stays with results_purged_at set.\n**Scope**: a sharded task is purged together with its shards; shards cannot be purged on their own. Inventory records keep only service names and are not touched, and archives written by the retention janitor are outside the store.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Scans"
+                ],
+                "summary": "Erase the results of a scan",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scan Task ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Results erased. Example: {\\\"id\\\":\\\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\\\",\\\"results\\\":1024,\\\"recent_results\\\":1024}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ScanPurgeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed task identifier. Example: {\\\"error\\\":\\\"invalid task id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Task with the provided ID does not exist. Example: {\\\"error\\\":\\\"task not found\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Task is still active or is a shard. Example: {\\\"error\\\":\\\"task is running; pause it or wait until it finishes\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while erasing the results. Example: {\\\"error\\\":\\\"failed to purge results\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scans/{id}/results/stream": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Download the results of a scan task as newline-delimited JSON, one ScanResult object per line, written as they are decoded from the store. Use it instead of GET /scans/{id} for scans with too many results to receive as one JSON document.\n**Backpressure**: results are fetched from the store a page at a time and decoded only as fast as the client reads them, so the server holds neither the whole response nor all results of the task. A task whose results are rewritten during the download, as by a purge, can yield a mix of both. The X-Task-Status header carries the task status; pending and running tasks have no results yet and yield an empty body.\n**Truncation**: if the stored results cannot be decoded part way, the stream ends early. Compare the line count with GET /scans/{id} when completeness matters.",
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "Scans"
+                ],
+                "summary": "Stream scan results as NDJSON",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scan Task ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "One result per line. Example: {\\\"host\\\":\\\"scanme.nmap.org\\\",\\\"port\\\":443,\\\"state\\\":\\\"Open\\\",\\\"service\\\":\\\"https\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/scanner.ScanResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed task identifier. Example: {\\\"error\\\":\\\"invalid task id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Task with the provided ID does not exist. Example: {\\\"error\\\":\\\"task not found\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error when loading the results. Example: {\\\"error\\\":\\\"failed to load results\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scans/{id}/resume": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Queue a paused scan again. The worker that picks it up keeps the results collected before the pause and only probes the remaining ports. Resuming a task that is still pausing withdraws the pause.\n**Sharding**: resuming a sharded task resumes each of its paused shards.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Scans"
+                ],
+                "summary": "Resume a paused scan",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scan Task ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Resume accepted. Example: {\\\"id\\\":\\\"a3f5c62e-1234-4f72-a84a-1c2d3e4f5678\\\",\\\"status\\\":\\\"pending\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ScanStateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed task identifier. Example: {\\\"error\\\":\\\"invalid task id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Task with the provided ID does not exist. Example: {\\\"error\\\":\\\"task not found\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Task is not paused. Example: {\\\"error\\\":\\\"task is running and cannot be resumed\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while updating or queueing the task. Example: {\\\"error\\\":\\\"failed to queue task\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/schedules": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Return every schedule of the caller with its next run and the scan it queued last, oldest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Schedules"
+                ],
+                "summary": "List schedules",
+                "responses": {
+                    "200": {
+                        "description": "Schedules of the caller.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.Schedule"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while loading schedules. Example: {\\\"error\\\":\\\"failed to load schedules\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
             },
-            "examples": {
-              "application/json": {
-                "error": "unauthorized"
-              }
-            }
-          },
-          "404": {
-            "description": "Task with the provided ID does not exist.",
-            "schema": {
-              "$ref": "#/definitions/ErrorResponse"
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Queue a scan from a template whenever a cron expression matches, for recurring sweeps of the same targets. The template is validated like a POST /scans body when the schedule is created. Every run after the first is compared with the previous run when that one completed, so the completion webhook only fires on changes; a run that comes due while the previous scan is still unfinished is skipped and noted in last_error.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Schedules"
+                ],
+                "summary": "Create a schedule",
+                "parameters": [
+                    {
+                        "description": "Schedule definition",
+                        "name": "scheduleRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.CreateScheduleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Schedule created. Example: {\\\"id\\\":\\\"6e1f4c2a-8b3d-4a5e-9f60-7d2c1b0a9e83\\\",\\\"cron\\\":\\\"0 2 * * *\\\",\\\"timezone\\\":\\\"UTC\\\",\\\"scan\\\":{\\\"hosts\\\":[\\\"203.0.113.0/28\\\"],\\\"ports\\\":\\\"1-1024\\\",\\\"mode\\\":\\\"connect\\\"},\\\"paused\\\":false,\\\"created_at\\\":\\\"2024-01-02T15:04:05Z\\\",\\\"next_run_at\\\":\\\"2024-01-03T02:00:00Z\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.Schedule"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed JSON body or failed validation. Example: {\\\"error\\\":\\\"invalid request payload\\\",\\\"details\\\":[{\\\"field\\\":\\\"cron\\\",\\\"rule\\\":\\\"cron\\\",\\\"message\\\":\\\"minute field value \\\\\\\"61\\\\\\\" must be 0-59\\\"}]}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ValidationErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while persisting the schedule. Example: {\\\"error\\\":\\\"failed to persist schedule\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/schedules/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Return one schedule with its next run and the scan it queued last.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Schedules"
+                ],
+                "summary": "Get a schedule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Schedule ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Schedule definition and state.",
+                        "schema": {
+                            "$ref": "#/definitions/api.Schedule"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed schedule identifier. Example: {\\\"error\\\":\\\"invalid schedule id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Schedule with the provided ID does not exist. Example: {\\\"error\\\":\\\"schedule not found\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error when loading the schedule. Example: {\\\"error\\\":\\\"failed to load schedule\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
             },
-            "examples": {
-              "application/json": {
-                "error": "task not found"
-              }
-            }
-          },
-          "429": {
-            "description": "Rate limit exceeded for the calling client.",
-            "schema": {
-              "$ref": "#/definitions/ErrorResponse"
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stop a schedule for good. Scans it already queued still run, and past scans are kept until the retention janitor removes them.",
+                "tags": [
+                    "Schedules"
+                ],
+                "summary": "Delete a schedule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Schedule ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Schedule deleted."
+                    },
+                    "400": {
+                        "description": "Malformed schedule identifier. Example: {\\\"error\\\":\\\"invalid schedule id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error when deleting the schedule. Example: {\\\"error\\\":\\\"failed to delete schedule\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/schedules/{id}/pause": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stop queueing scans for a schedule until it is resumed. Scans already queued still run. Pausing a paused schedule is not an error.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Schedules"
+                ],
+                "summary": "Pause a schedule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Schedule ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The paused schedule.",
+                        "schema": {
+                            "$ref": "#/definitions/api.Schedule"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed schedule identifier. Example: {\\\"error\\\":\\\"invalid schedule id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Schedule with the provided ID does not exist. Example: {\\\"error\\\":\\\"schedule not found\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error when updating the schedule. Example: {\\\"error\\\":\\\"failed to persist schedule\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/schedules/{id}/resume": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Let a paused schedule queue scans again, starting with the next time its cron expression matches; runs missed while it was paused are not made up. Resuming an active schedule is not an error.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Schedules"
+                ],
+                "summary": "Resume a schedule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Schedule ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The resumed schedule with its next run.",
+                        "schema": {
+                            "$ref": "#/definitions/api.Schedule"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed schedule identifier. Example: {\\\"error\\\":\\\"invalid schedule id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Schedule with the provided ID does not exist. Example: {\\\"error\\\":\\\"schedule not found\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error when updating the schedule. Example: {\\\"error\\\":\\\"failed to persist schedule\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/services": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Group every currently open port in the inventory by detected service, product and version, for rapid exposure assessment when a vulnerability is published. Text filters match case-insensitive substrings; min_version and max_version bound the version inclusively, comparing numeric segments numerically so 8.10 sorts after 8.9; max_version also covers every release it is a prefix of, so max_version=8 includes all 8.x. Ports with an unknown version never match a version bound.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Inventory"
+                ],
+                "summary": "Group the inventory by service and version",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only services whose name contains this text",
+                        "name": "service",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only products whose name contains this text, e.g. openssh",
+                        "name": "product",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only versions at or above this one, e.g. 8.0",
+                        "name": "min_version",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only versions at or below this one, including its sub-releases: 8.9 also matches 8.9p1",
+                        "name": "max_version",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only hosts carrying this tag",
+                        "name": "tag",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Matching service groups. Example: {\\\"count\\\":1,\\\"services\\\":[{\\\"service\\\":\\\"ssh\\\",\\\"product\\\":\\\"OpenSSH\\\",\\\"version\\\":\\\"8.2p1\\\",\\\"host_count\\\":1,\\\"endpoints\\\":[{\\\"host\\\":\\\"203.0.113.50\\\",\\\"port\\\":22,\\\"protocol\\\":\\\"tcp\\\",\\\"last_seen\\\":\\\"2024-01-09T15:04:05Z\\\"}]}]}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ServiceListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while loading the inventory. Example: {\\\"error\\\":\\\"failed to load inventory\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Summarize the caller's scans over a window of UTC calendar days ending today: scans per day, average durations, failure rates and the services most often found open. Only tasks still within the retention window can be counted.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Scans"
+                ],
+                "summary": "Scan statistics",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of UTC days to report, including today (1-90, default 7)",
+                        "name": "days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Statistics for the window. Example: {\\\"from\\\":\\\"2024-01-03T00:00:00Z\\\",\\\"to\\\":\\\"2024-01-09T15:04:05Z\\\",\\\"scans\\\":120,\\\"completed\\\":112,\\\"failed\\\":6,\\\"failure_rate\\\":0.05,\\\"average_duration_seconds\\\":42.7,\\\"days\\\":[],\\\"top_services\\\":[{\\\"service\\\":\\\"http\\\",\\\"count\\\":86}]}",
+                        "schema": {
+                            "$ref": "#/definitions/api.StatsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid window. Example: {\\\"error\\\":\\\"invalid request payload\\\",\\\"details\\\":[{\\\"field\\\":\\\"days\\\",\\\"rule\\\":\\\"range\\\",\\\"message\\\":\\\"days must be an integer between 1 and 90\\\"}]}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ValidationErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while loading tasks. Example: {\\\"error\\\":\\\"failed to load tasks\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Return every scan template of the caller, oldest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Templates"
+                ],
+                "summary": "List scan templates",
+                "responses": {
+                    "200": {
+                        "description": "Templates of the caller.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.ScanTemplate"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while loading templates. Example: {\\\"error\\\":\\\"failed to load templates\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
             },
-            "examples": {
-              "application/json": {
-                "error": "rate limit exceeded"
-              }
-            }
-          },
-          "500": {
-            "description": "Internal error when loading the task.",
-            "schema": {
-              "$ref": "#/definitions/ErrorResponse"
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Save a named scan configuration, such as the ports, mode, timing and detection options used for a class of assets. POST /scans and POST /scans/estimate take its id as template_id and fill every field the request leaves out from it, so a request can be as small as {\"template_id\": \"...\", \"hosts\": [\"10.0.0.5\"]}. The saved fields are validated as in POST /scans, except that hosts, ports and mode may be left to the requests.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Templates"
+                ],
+                "summary": "Create a scan template",
+                "parameters": [
+                    {
+                        "description": "Template definition",
+                        "name": "templateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.CreateTemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Template created. Example: {\\\"id\\\":\\\"3c9d2e1f-7a4b-4c5d-8e6f-1a2b3c4d5e6f\\\",\\\"name\\\":\\\"web-servers\\\",\\\"scan\\\":{\\\"ports\\\":\\\"80,443,8000-8100\\\",\\\"mode\\\":\\\"connect\\\",\\\"timing\\\":\\\"aggressive\\\",\\\"checks\\\":[\\\"http\\\",\\\"tls\\\"]},\\\"created_at\\\":\\\"2024-01-02T15:04:05Z\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ScanTemplate"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed JSON body or failed validation. Example: {\\\"error\\\":\\\"invalid request payload\\\",\\\"details\\\":[{\\\"field\\\":\\\"scan.mode\\\",\\\"rule\\\":\\\"oneof\\\",\\\"message\\\":\\\"mode must be one of: connect syn udp\\\"}]}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ValidationErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while persisting the template. Example: {\\\"error\\\":\\\"failed to persist template\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Return one scan template with its saved fields.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Templates"
+                ],
+                "summary": "Get a scan template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Template ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Template definition.",
+                        "schema": {
+                            "$ref": "#/definitions/api.ScanTemplate"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed template identifier. Example: {\\\"error\\\":\\\"invalid template id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Template with the provided ID does not exist. Example: {\\\"error\\\":\\\"template not found\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error when loading the template. Example: {\\\"error\\\":\\\"failed to load template\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
             },
-            "examples": {
-              "application/json": {
-                "error": "failed to load task"
-              }
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Remove a scan template. Scans submitted with it are unaffected; later requests naming it are rejected.",
+                "tags": [
+                    "Templates"
+                ],
+                "summary": "Delete a scan template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Template ID (UUID v4)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Template deleted."
+                    },
+                    "400": {
+                        "description": "Malformed template identifier. Example: {\\\"error\\\":\\\"invalid template id format\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error when deleting the template. Example: {\\\"error\\\":\\\"failed to delete template\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/version": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Report the version, source revision, and build date of the running Cortex server so bug reports and fleet inventories can pin exact builds.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "System"
+                ],
+                "summary": "Get build information",
+                "responses": {
+                    "200": {
+                        "description": "Build metadata. Example: {\\\"version\\\":\\\"v1.2.0\\\",\\\"commit\\\":\\\"4f1c2a9e7b3d\\\",\\\"build_date\\\":\\\"2024-01-02T15:04:05Z\\\",\\\"go_version\\\":\\\"go1.24.0\\\",\\\"platform\\\":\\\"linux/amd64\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/version.Info"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or incorrect API key. Example: {\\\"error\\\":\\\"unauthorized\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded for the calling client. Example: {\\\"error\\\":\\\"rate limit exceeded\\\"}",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
             }
-          }
-        }
-      }
-    }
-  },
-  "securityDefinitions": {
-    "ApiKeyAuth": {
-      "type": "apiKey",
-      "name": "Authorization",
-      "in": "header",
-      "description": "Supply the configured API key using the Authorization: Bearer <token> header."
-    }
-  },
-  "definitions": {
-    "CreateScanRequest": {
-      "type": "object",
-      "required": [
-        "hosts",
-        "mode",
-        "ports"
-      ],
-      "properties": {
-        "hosts": {
-          "type": "array",
-          "description": "Targets to scan. Accepts IPv4/IPv6 addresses and domain names that resolve via DNS. Provide at least one entry; multiple hosts are processed concurrently.",
-          "items": {
-            "type": "string"
-          },
-          "example": [
-            "scanme.nmap.org",
-            "203.0.113.50"
-          ]
-        },
-        "mode": {
-          "type": "string",
-          "description": "Scanning strategy. connect performs TCP connect() handshakes suitable for banner grabbing, syn uses half-open SYN probes for fast TCP discovery, udp sends UDP payloads to uncover datagram services.",
-          "enum": [
-            "connect",
-            "syn",
-            "udp"
-          ],
-          "example": "connect"
-        },
-        "ports": {
-          "type": "string",
-          "description": "Combination of single ports and inclusive ranges (e.g. 80,443,1000-1050). Leave no spaces for best readability; ranges must use a hyphen.",
-          "example": "443,8443,10000-10100"
-        }
-      },
-      "additionalProperties": false
-    },
-    "ErrorResponse": {
-      "type": "object",
-      "properties": {
-        "error": {
-          "type": "string",
-          "description": "Human readable error message describing why the request was rejected. The value is localized for operators rather than end users.",
-          "example": "task not found"
-        }
-      },
-      "additionalProperties": false
-    },
-    "ScanAcceptedResponse": {
-      "type": "object",
-      "properties": {
-        "id": {
-          "type": "string",
-          "description": "Identifier clients must supply to GET /scans/{id} when polling for status.",
-          "example": "a3f5c62e-1234-4f72-a84a-1c2d3e4f5678",
-          "format": "uuid"
-        },
-        "status": {
-          "type": "string",
-          "description": "Initial queue state assigned to every newly accepted scan request.",
-          "enum": [
-            "pending"
-          ],
-          "example": "pending"
         }
-      },
-      "additionalProperties": false
     },
-    "ScanResult": {
-      "type": "object",
-      "properties": {
-        "host": {
-          "type": "string",
-          "description": "Target host that produced the observation. Mirrors the input host field so clients can join results back to their original request.",
-          "example": "scanme.nmap.org"
-        },
-        "port": {
-          "type": "integer",
-          "format": "int32",
-          "description": "Network port that was probed. Expressed as an integer in the 0-65535 range.",
-          "example": 443
-        },
-        "service": {
-          "type": "string",
-          "description": "Optional service fingerprint (if detected) describing application protocol and banner. Empty when the probe could not identify an application.",
-          "example": "http (nginx)",
-          "x-nullable": true
-        },
-        "state": {
-          "type": "string",
-          "description": "Resulting port disposition derived from worker probes. Open indicates a responsive service, Closed means the port rejected connections, and Filtered signifies intermediary packet filtering.",
-          "enum": [
-            "Open",
-            "Closed",
-            "Filtered"
-          ],
-          "example": "Open"
+    "definitions": {
+        "api.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "CreatedAt is when the key was created, and CreatedBy by whom.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T15:04:05Z"
+                },
+                "created_by": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "id": {
+                    "description": "ID is the immutable identifier of the key (UUID v4).",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "6b1d2f0e-7c3a-4e59-8f21-0a9b8c7d6e5f"
+                },
+                "name": {
+                    "description": "Name labels the key in logs and audit fields.",
+                    "type": "string",
+                    "example": "ci-pipeline"
+                },
+                "namespace": {
+                    "description": "Namespace is the tenant the key acts for.",
+                    "type": "string",
+                    "example": "default"
+                },
+                "prefix": {
+                    "description": "Prefix is the start of the key, to recognise it without the secret.",
+                    "type": "string",
+                    "example": "ctx_Yq3v9Kd1"
+                },
+                "read_limit": {
+                    "description": "ReadLimit and WriteLimit replace the rate limits of the key when set.",
+                    "type": "integer",
+                    "example": 600
+                },
+                "revoked_at": {
+                    "description": "RevokedAt is when the key was revoked; revoked keys are rejected.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-02-01T09:00:00Z"
+                },
+                "role": {
+                    "description": "Role sets what the key may do.",
+                    "type": "string",
+                    "enum": [
+                        "admin",
+                        "submit",
+                        "read-only"
+                    ],
+                    "example": "submit"
+                },
+                "write_limit": {
+                    "type": "integer",
+                    "example": 20
+                }
+            }
+        },
+        "api.CallbackDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "Attempts counts the POSTs made so far.",
+                    "type": "integer",
+                    "example": 1
+                },
+                "delivered_at": {
+                    "description": "DeliveredAt is when the URL accepted the task.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T15:06:31Z"
+                },
+                "error": {
+                    "description": "Error describes why the last attempt failed.",
+                    "type": "string",
+                    "example": "callback answered 503 Service Unavailable"
+                },
+                "next_attempt_at": {
+                    "description": "NextAttemptAt is when the next attempt is due.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T15:06:35Z"
+                },
+                "response_status": {
+                    "description": "ResponseStatus is the HTTP status of the last attempt.",
+                    "type": "integer",
+                    "example": 200
+                },
+                "status": {
+                    "description": "Status is the state of the delivery.",
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "delivered",
+                        "failed"
+                    ],
+                    "example": "delivered"
+                }
+            }
+        },
+        "api.CreateAPIKeyRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "role"
+            ],
+            "properties": {
+                "name": {
+                    "description": "Name labels the key.",
+                    "type": "string",
+                    "maxLength": 200,
+                    "example": "ci-pipeline"
+                },
+                "namespace": {
+                    "description": "Namespace is the tenant the key acts for.",
+                    "type": "string",
+                    "example": "default"
+                },
+                "read_limit": {
+                    "description": "ReadLimit and WriteLimit replace the rate limits of the key when set.",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 600
+                },
+                "role": {
+                    "description": "Role sets what the key may do.",
+                    "type": "string",
+                    "enum": [
+                        "admin",
+                        "submit",
+                        "read-only"
+                    ],
+                    "example": "submit"
+                },
+                "write_limit": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 20
+                }
+            }
+        },
+        "api.CreateMonitorRequest": {
+            "type": "object",
+            "required": [
+                "host",
+                "interval",
+                "mode",
+                "ports"
+            ],
+            "properties": {
+                "expected_open": {
+                    "description": "ExpectedOpen lists the ports that should be open.",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        80,
+                        443
+                    ]
+                },
+                "host": {
+                    "description": "Host is the asset to verify.",
+                    "type": "string",
+                    "example": "203.0.113.50"
+                },
+                "interval": {
+                    "description": "Interval is how often the asset is verified.",
+                    "type": "string",
+                    "example": "1h"
+                },
+                "mode": {
+                    "description": "Mode selects the scan mode for verification scans.",
+                    "type": "string",
+                    "enum": [
+                        "connect",
+                        "syn",
+                        "udp"
+                    ],
+                    "example": "connect"
+                },
+                "name": {
+                    "description": "Name is an optional label for the asset.",
+                    "type": "string",
+                    "maxLength": 200,
+                    "example": "public web frontend"
+                },
+                "ports": {
+                    "description": "Ports are the ports covered by each verification scan.",
+                    "type": "string",
+                    "example": "1-1024"
+                }
+            }
+        },
+        "api.CreateScanRequest": {
+            "type": "object",
+            "required": [
+                "mode"
+            ],
+            "properties": {
+                "all_addresses": {
+                    "description": "AllAddresses scans every resolved address of multi-homed hostnames.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "baseline": {
+                    "description": "Baseline names an earlier task of the same tenant to diff against.",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "5b0e7c1a-9d2f-4e3b-8a6c-2f1d0e9b7a44"
+                },
+                "callback_url": {
+                    "description": "CallbackURL receives the task once it completed or failed.",
+                    "type": "string",
+                    "maxLength": 2048,
+                    "example": "https://ci.example.com/hooks/cortex"
+                },
+                "checks": {
+                    "description": "Checks opts into deeper check modules for open ports.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "snmp"
+                    ]
+                },
+                "detect_tarpits": {
+                    "description": "DetectTarpits opts into tarpit and honeypot detection.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "discovery": {
+                    "description": "Discovery skips hosts that do not answer a ping.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "dns_servers": {
+                    "description": "DNSServers pins the name servers targets are resolved with.",
+                    "type": "array",
+                    "maxItems": 4,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "10.0.0.53",
+                        "1.1.1.1"
+                    ]
+                },
+                "exclude": {
+                    "description": "Exclude removes hosts from the expanded Hosts.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "192.168.1.1",
+                        "192.168.1.250-254"
+                    ]
+                },
+                "host_rate": {
+                    "description": "HostRate optionally caps probes per second per target host.",
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 20
+                },
+                "hosts": {
+                    "description": "Hosts enumerates every hostname or IP address the scanner should probe.",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "scanme.nmap.org",
+                        "203.0.113.50"
+                    ]
+                },
+                "http_paths": {
+                    "description": "HTTPPaths replaces the paths the http check requests.",
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "/robots.txt",
+                        "/admin/"
+                    ]
+                },
+                "ip_version": {
+                    "description": "IPVersion restricts the scan to one address family.",
+                    "type": "integer",
+                    "enum": [
+                        4,
+                        6
+                    ],
+                    "example": 6
+                },
+                "max_duration_seconds": {
+                    "description": "MaxDurationSeconds optionally bounds how long the scan may run.",
+                    "type": "integer",
+                    "maximum": 604800,
+                    "minimum": 1,
+                    "example": 3600
+                },
+                "max_hostgroup": {
+                    "description": "MaxHostGroup scans the targets in groups of at most this many hosts.",
+                    "type": "integer",
+                    "maximum": 4096,
+                    "minimum": 1,
+                    "example": 64
+                },
+                "max_parallelism": {
+                    "description": "MaxParallelism optionally caps the probes in flight against one host.",
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 1,
+                    "example": 10
+                },
+                "max_rate": {
+                    "description": "MaxRate optionally caps probes per second across all hosts.",
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 500
+                },
+                "max_retries": {
+                    "description": "MaxRetries optionally sets how often a silent probe is sent again.",
+                    "type": "integer",
+                    "maximum": 10,
+                    "minimum": 0,
+                    "example": 1
+                },
+                "min_hostgroup": {
+                    "description": "MinHostGroup sets the size of the first host group.",
+                    "type": "integer",
+                    "maximum": 4096,
+                    "minimum": 1,
+                    "example": 8
+                },
+                "mode": {
+                    "description": "Mode selects which worker implementation will be used for probing.",
+                    "type": "string",
+                    "enum": [
+                        "connect",
+                        "syn",
+                        "udp",
+                        "fin",
+                        "null",
+                        "xmas",
+                        "ack"
+                    ],
+                    "example": "connect"
+                },
+                "no_fallback": {
+                    "description": "NoFallback opts out of the SYN to connect downgrade.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "ports": {
+                    "description": "Ports expresses the desired port selection using comma-separated values and ranges.",
+                    "type": "string",
+                    "example": "443,8443,10000-10100"
+                },
+                "prefer": {
+                    "description": "Prefer selects the address family scanned on dual-stack hostnames.",
+                    "type": "string",
+                    "enum": [
+                        "ipv4",
+                        "ipv6",
+                        "both"
+                    ],
+                    "example": "both"
+                },
+                "rdap": {
+                    "description": "RDAP opts into network ownership lookups for public targets.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "resolve_ptr": {
+                    "description": "ResolvePTR opts into reverse DNS lookups of the probed addresses.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "retry_backoff": {
+                    "description": "RetryBackoff optionally sets the wait before a silent probe is sent again.",
+                    "type": "string",
+                    "example": "250ms"
+                },
+                "reuse_within": {
+                    "description": "ReuseWithin opts into reusing recent results of other tasks.",
+                    "type": "string",
+                    "example": "15m"
+                },
+                "shard_size": {
+                    "description": "ShardSize splits large host lists into subtasks of at most this many hosts.",
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 256
+                },
+                "tags": {
+                    "description": "Tags label the scan and its hosts in the inventory.",
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "prod",
+                        "dmz"
+                    ]
+                },
+                "targets": {
+                    "description": "Targets lists hosts with their own ports, mode and tags.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.ScanTarget"
+                    }
+                },
+                "tarpit_downgrade": {
+                    "description": "TarpitDowngrade keeps flagged hosts out of the inventory.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "template_id": {
+                    "description": "TemplateID names a saved template whose fields are the defaults of the request.",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "3c9d2e1f-7a4b-4c5d-8e6f-1a2b3c4d5e6f"
+                },
+                "timing": {
+                    "description": "Timing selects a timing template.",
+                    "type": "string",
+                    "enum": [
+                        "0",
+                        "1",
+                        "2",
+                        "3",
+                        "4",
+                        "5",
+                        "paranoid",
+                        "sneaky",
+                        "polite",
+                        "normal",
+                        "aggressive",
+                        "insane"
+                    ],
+                    "example": "aggressive"
+                },
+                "top_ports": {
+                    "description": "TopPorts selects the most commonly open ports instead of Ports.",
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 100
+                },
+                "version_intensity": {
+                    "description": "VersionIntensity limits service detection to the less rare probes.",
+                    "type": "integer",
+                    "maximum": 9,
+                    "minimum": 0,
+                    "example": 7
+                }
+            }
+        },
+        "api.CreateScheduleRequest": {
+            "type": "object",
+            "required": [
+                "cron",
+                "scan"
+            ],
+            "properties": {
+                "cron": {
+                    "description": "Cron is the expression the scheduler follows.",
+                    "type": "string",
+                    "example": "0 2 * * *"
+                },
+                "name": {
+                    "description": "Name is an optional label.",
+                    "type": "string",
+                    "maxLength": 200,
+                    "example": "nightly dmz sweep"
+                },
+                "scan": {
+                    "description": "Scan is the template of every queued scan.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.CreateScanRequest"
+                        }
+                    ]
+                },
+                "timezone": {
+                    "description": "Timezone is the zone the cron expression is evaluated in.",
+                    "type": "string",
+                    "example": "Europe/Kyiv"
+                }
+            }
+        },
+        "api.CreateTemplateRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "description": {
+                    "description": "Description says what the template is for.",
+                    "type": "string",
+                    "maxLength": 2000,
+                    "example": "Web ports with HTTP and TLS checks"
+                },
+                "name": {
+                    "description": "Name labels the template.",
+                    "type": "string",
+                    "maxLength": 200,
+                    "example": "web-servers"
+                },
+                "scan": {
+                    "description": "Scan holds the fields to save.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.CreateScanRequest"
+                        }
+                    ]
+                }
+            }
+        },
+        "api.CreatedAPIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "CreatedAt is when the key was created, and CreatedBy by whom.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T15:04:05Z"
+                },
+                "created_by": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "id": {
+                    "description": "ID is the immutable identifier of the key (UUID v4).",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "6b1d2f0e-7c3a-4e59-8f21-0a9b8c7d6e5f"
+                },
+                "key": {
+                    "description": "Key is the bearer token; it cannot be retrieved again.",
+                    "type": "string",
+                    "example": "ctx_Yq3v9Kd1c8Zr2Lw0Hs5Nt7Bx4Ma6Pe1Vf9Gj3Qu0Ek"
+                },
+                "name": {
+                    "description": "Name labels the key in logs and audit fields.",
+                    "type": "string",
+                    "example": "ci-pipeline"
+                },
+                "namespace": {
+                    "description": "Namespace is the tenant the key acts for.",
+                    "type": "string",
+                    "example": "default"
+                },
+                "prefix": {
+                    "description": "Prefix is the start of the key, to recognise it without the secret.",
+                    "type": "string",
+                    "example": "ctx_Yq3v9Kd1"
+                },
+                "read_limit": {
+                    "description": "ReadLimit and WriteLimit replace the rate limits of the key when set.",
+                    "type": "integer",
+                    "example": 600
+                },
+                "revoked_at": {
+                    "description": "RevokedAt is when the key was revoked; revoked keys are rejected.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-02-01T09:00:00Z"
+                },
+                "role": {
+                    "description": "Role sets what the key may do.",
+                    "type": "string",
+                    "enum": [
+                        "admin",
+                        "submit",
+                        "read-only"
+                    ],
+                    "example": "submit"
+                },
+                "write_limit": {
+                    "type": "integer",
+                    "example": 20
+                }
+            }
+        },
+        "api.DailyStats": {
+            "type": "object",
+            "properties": {
+                "average_duration_seconds": {
+                    "description": "AverageDurationSeconds is the mean time from submission to completion.",
+                    "type": "number",
+                    "example": 39.2
+                },
+                "completed": {
+                    "description": "Completed counts those tasks that completed.",
+                    "type": "integer",
+                    "example": 16
+                },
+                "date": {
+                    "description": "Date is the UTC day.",
+                    "type": "string",
+                    "example": "2024-01-08"
+                },
+                "failed": {
+                    "description": "Failed counts those tasks that failed.",
+                    "type": "integer",
+                    "example": 1
+                },
+                "scans": {
+                    "description": "Scans counts the tasks created that day.",
+                    "type": "integer",
+                    "example": 17
+                }
+            }
+        },
+        "api.DriftReport": {
+            "type": "object",
+            "properties": {
+                "drifted": {
+                    "description": "Drifted lists the monitors out of compliance.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.Monitor"
+                    }
+                },
+                "monitors": {
+                    "description": "Monitors counts every monitor of the caller.",
+                    "type": "integer",
+                    "example": 12
+                },
+                "unchecked": {
+                    "description": "Unchecked counts monitors without an evaluated verification scan.",
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "api.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Error is a human-readable explanation of why the request failed.",
+                    "type": "string",
+                    "example": "task not found"
+                }
+            }
+        },
+        "api.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "description": "Field is the JSON path of the offending field.",
+                    "type": "string",
+                    "example": "mode"
+                },
+                "message": {
+                    "description": "Message explains the failure in plain language.",
+                    "type": "string",
+                    "example": "mode must be one of: connect syn udp"
+                },
+                "rule": {
+                    "description": "Rule names the validation rule that was violated.",
+                    "type": "string",
+                    "example": "oneof"
+                }
+            }
+        },
+        "api.HostListResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "description": "Count is the number of hosts that matched the filters.",
+                    "type": "integer",
+                    "example": 3
+                },
+                "hosts": {
+                    "description": "Hosts lists the matching inventory records.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.InventoryHost"
+                    }
+                }
+            }
+        },
+        "api.InventoryHost": {
+            "type": "object",
+            "properties": {
+                "addresses": {
+                    "description": "Addresses lists the probed addresses of multi-homed hosts.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "45.33.32.156"
+                    ]
+                },
+                "first_seen": {
+                    "description": "FirstSeen records when the host was first scanned.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T15:04:05Z"
+                },
+                "host": {
+                    "description": "Host is the target as submitted in scans.",
+                    "type": "string",
+                    "example": "scanme.nmap.org"
+                },
+                "last_seen": {
+                    "description": "LastSeen records when the host was last scanned.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-09T15:04:05Z"
+                },
+                "last_task_id": {
+                    "description": "LastTaskID is the latest scan that covered the host.",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "a3f5c62e-1234-4f72-a84a-1c2d3e4f5678"
+                },
+                "ports": {
+                    "description": "Ports lists the ports currently known to be open.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.InventoryPort"
+                    }
+                },
+                "tags": {
+                    "description": "Tags collects the tags of every scan that covered the host.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "prod"
+                    ]
+                }
+            }
+        },
+        "api.InventoryPort": {
+            "type": "object",
+            "properties": {
+                "first_seen": {
+                    "description": "FirstSeen records when the port was first found open.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T15:04:05Z"
+                },
+                "last_seen": {
+                    "description": "LastSeen records when the port was last found open.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-09T15:04:05Z"
+                },
+                "port": {
+                    "description": "Port is the open port number.",
+                    "type": "integer",
+                    "example": 22
+                },
+                "product": {
+                    "description": "Product is the latest detected product.",
+                    "type": "string",
+                    "example": "OpenSSH"
+                },
+                "protocol": {
+                    "description": "Protocol is tcp or udp.",
+                    "type": "string",
+                    "enum": [
+                        "tcp",
+                        "udp"
+                    ],
+                    "example": "tcp"
+                },
+                "service": {
+                    "description": "Service is the latest service fingerprint.",
+                    "type": "string",
+                    "example": "ssh"
+                },
+                "version": {
+                    "description": "Version is the latest detected product version.",
+                    "type": "string",
+                    "example": "8.2p1"
+                }
+            }
+        },
+        "api.Monitor": {
+            "type": "object",
+            "properties": {
+                "checked_at": {
+                    "description": "CheckedAt records when the last verification scan finished.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T16:01:12Z"
+                },
+                "compliant": {
+                    "description": "Compliant reports whether the last verification matched the desired state.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "created_at": {
+                    "description": "CreatedAt records when the monitor was created.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T15:04:05Z"
+                },
+                "error": {
+                    "description": "Error explains why the last verification could not be evaluated.",
+                    "type": "string",
+                    "example": "insufficient privileges for raw packet access"
+                },
+                "expected_open": {
+                    "description": "ExpectedOpen lists the ports that should be open.",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        80,
+                        443
+                    ]
+                },
+                "host": {
+                    "description": "Host is the asset being verified.",
+                    "type": "string",
+                    "example": "203.0.113.50"
+                },
+                "id": {
+                    "description": "ID is the immutable identifier of the monitor (UUID v4).",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "0f8e3a6d-2c41-4b9e-9a57-3d6c1e2b4f80"
+                },
+                "interval": {
+                    "description": "Interval is how often the asset is verified.",
+                    "type": "string",
+                    "example": "1h"
+                },
+                "last_run_at": {
+                    "description": "LastRunAt records when the last verification scan was queued.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T16:00:00Z"
+                },
+                "last_task_id": {
+                    "description": "LastTaskID is the most recent verification scan.",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "a3f5c62e-1234-4f72-a84a-1c2d3e4f5678"
+                },
+                "missing": {
+                    "description": "Missing lists expected ports that were not open.",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        443
+                    ]
+                },
+                "mode": {
+                    "description": "Mode is the scan mode used for verification scans.",
+                    "type": "string",
+                    "enum": [
+                        "connect",
+                        "syn",
+                        "udp"
+                    ],
+                    "example": "connect"
+                },
+                "name": {
+                    "description": "Name is an optional label for the asset.",
+                    "type": "string",
+                    "example": "public web frontend"
+                },
+                "ports": {
+                    "description": "Ports are the ports covered by each verification scan.",
+                    "type": "string",
+                    "example": "1-1024"
+                },
+                "unexpected": {
+                    "description": "Unexpected lists ports found open that are not expected.",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        3389
+                    ]
+                }
+            }
+        },
+        "api.QueuePool": {
+            "type": "object",
+            "properties": {
+                "depth": {
+                    "description": "Depth is the number of tasks of this mode waiting to be picked up.",
+                    "type": "integer",
+                    "example": 7
+                },
+                "mode": {
+                    "description": "Mode is the scan mode served by the pool.",
+                    "type": "string",
+                    "enum": [
+                        "connect",
+                        "syn",
+                        "udp"
+                    ],
+                    "example": "udp"
+                },
+                "workers": {
+                    "description": "Workers is the pool size on the answering node.",
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "api.QueueStatus": {
+            "type": "object",
+            "properties": {
+                "depth": {
+                    "description": "Depth is the number of tasks waiting to be picked up.",
+                    "type": "integer",
+                    "example": 12
+                },
+                "paused": {
+                    "description": "Paused reports whether workers are currently prevented from taking tasks.",
+                    "type": "boolean",
+                    "example": true
+                },
+                "paused_at": {
+                    "description": "PausedAt records when the queue was paused.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T15:04:05Z"
+                },
+                "paused_by": {
+                    "description": "PausedBy names the credential that paused the queue.",
+                    "type": "string",
+                    "example": "admin"
+                },
+                "pools": {
+                    "description": "Pools breaks the queue down by scan mode.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.QueuePool"
+                    }
+                }
+            }
+        },
+        "api.ScanAcceptedResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "ID mirrors the queued task identifier returned to clients for polling.",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "a3f5c62e-1234-4f72-a84a-1c2d3e4f5678"
+                },
+                "status": {
+                    "description": "Status is always pending immediately after acceptance.",
+                    "type": "string",
+                    "enum": [
+                        "pending"
+                    ],
+                    "example": "pending"
+                }
+            }
+        },
+        "api.ScanDiffResponse": {
+            "type": "object",
+            "properties": {
+                "baseline": {
+                    "description": "Baseline identifies the earlier task.",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "5b0e7c1a-9d2f-4e3b-8a6c-2f1d0e9b7a44"
+                },
+                "changes": {
+                    "description": "Changes lists every difference.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/scanner.ResultChange"
+                    }
+                },
+                "closed": {
+                    "description": "Closed counts ports no longer open.",
+                    "type": "integer",
+                    "example": 0
+                },
+                "current": {
+                    "description": "Current identifies the later task.",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "a3f5c62e-1234-4f72-a84a-1c2d3e4f5678"
+                },
+                "opened": {
+                    "description": "Opened counts ports open now that were not before.",
+                    "type": "integer",
+                    "example": 1
+                },
+                "service_changed": {
+                    "description": "ServiceChanged counts open ports whose service changed.",
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "api.ScanEstimateResponse": {
+            "type": "object",
+            "properties": {
+                "basis": {
+                    "description": "Basis names what the prediction was derived from.",
+                    "type": "string",
+                    "enum": [
+                        "history",
+                        "timing"
+                    ],
+                    "example": "history"
+                },
+                "historical_throughput": {
+                    "description": "HistoricalThroughput is the measured probes per second of recent scans.",
+                    "type": "number",
+                    "example": 49.3
+                },
+                "jobs": {
+                    "description": "Jobs is the total number of probes the scan would send.",
+                    "type": "integer",
+                    "example": 2048
+                },
+                "ports": {
+                    "description": "Ports counts the ports probed on each target.",
+                    "type": "integer",
+                    "example": 1024
+                },
+                "predicted_seconds": {
+                    "description": "PredictedSeconds is the expected wall-clock duration of the scan.",
+                    "type": "number",
+                    "example": 41.5
+                },
+                "samples": {
+                    "description": "Samples counts the completed scans the throughput was measured over.",
+                    "type": "integer",
+                    "example": 12
+                },
+                "targets": {
+                    "description": "Targets counts probe targets after expansion and blocklist filtering.",
+                    "type": "integer",
+                    "example": 2
+                },
+                "warnings": {
+                    "description": "Warnings lists targets the scan would skip.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "skipped scanme.nmap.org: 10.0.0.5 is in blocked range 10.0.0.0/8"
+                    ]
+                }
+            }
+        },
+        "api.ScanListResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "description": "Count is the number of tasks on this page.",
+                    "type": "integer",
+                    "example": 2
+                },
+                "next_offset": {
+                    "description": "NextOffset is the offset of the following page.",
+                    "type": "integer",
+                    "example": 50
+                },
+                "scans": {
+                    "description": "Scans lists the tasks of the page without their results.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.ScanTask"
+                    }
+                }
+            }
+        },
+        "api.ScanPurgeResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "ID identifies the purged task.",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "a3f5c62e-1234-4f72-a84a-1c2d3e4f5678"
+                },
+                "recent_results": {
+                    "description": "RecentResults counts the erased reuse cache entries.",
+                    "type": "integer",
+                    "example": 1024
+                },
+                "results": {
+                    "description": "Results counts the erased port results.",
+                    "type": "integer",
+                    "example": 1024
+                },
+                "tasks": {
+                    "description": "Tasks counts the deleted tasks of a full purge.",
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "api.ScanStateResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "ID identifies the task the request addressed.",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "a3f5c62e-1234-4f72-a84a-1c2d3e4f5678"
+                },
+                "status": {
+                    "description": "Status is the state the task moved to.",
+                    "type": "string",
+                    "enum": [
+                        "pausing",
+                        "paused",
+                        "running",
+                        "pending",
+                        "cancelling",
+                        "cancelled"
+                    ],
+                    "example": "pausing"
+                }
+            }
+        },
+        "api.ScanTarget": {
+            "type": "object",
+            "required": [
+                "host"
+            ],
+            "properties": {
+                "host": {
+                    "description": "Host is the hostname or IP address to scan.",
+                    "type": "string",
+                    "example": "db1.example.com"
+                },
+                "mode": {
+                    "description": "Mode overrides the request's scan mode for this host.",
+                    "type": "string",
+                    "enum": [
+                        "connect",
+                        "syn",
+                        "udp",
+                        "fin",
+                        "null",
+                        "xmas",
+                        "ack"
+                    ],
+                    "example": "udp"
+                },
+                "ports": {
+                    "description": "Ports overrides the request's ports for this host.",
+                    "type": "string",
+                    "example": "5432,6379"
+                },
+                "tags": {
+                    "description": "Tags label this host in the inventory next to the request's tags.",
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "db"
+                    ]
+                }
+            }
+        },
+        "api.ScanTask": {
+            "type": "object",
+            "properties": {
+                "all_addresses": {
+                    "description": "AllAddresses requests scanning every resolved address of each hostname.",
+                    "type": "boolean",
+                    "example": true
+                },
+                "baseline": {
+                    "description": "Baseline names an earlier task the results are compared against.",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "5b0e7c1a-9d2f-4e3b-8a6c-2f1d0e9b7a44"
+                },
+                "callback": {
+                    "description": "Callback reports the delivery of the task to CallbackURL.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.CallbackDelivery"
+                        }
+                    ]
+                },
+                "callback_url": {
+                    "description": "CallbackURL receives the finished task.",
+                    "type": "string",
+                    "example": "https://ci.example.com/hooks/cortex"
+                },
+                "changes": {
+                    "description": "Changes lists differences from the baseline once the task completes.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/scanner.ResultChange"
+                    }
+                },
+                "checks": {
+                    "description": "Checks lists the check modules selected for the task.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "snmp"
+                    ]
+                },
+                "completed_at": {
+                    "description": "CompletedAt is set once the task transitions to a terminal state.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T15:06:30Z"
+                },
+                "created_at": {
+                    "description": "CreatedAt records when the task was created.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T15:04:05Z"
+                },
+                "detect_tarpits": {
+                    "description": "DetectTarpits flags hosts whose open ports look fabricated.",
+                    "type": "boolean",
+                    "example": true
+                },
+                "discovery": {
+                    "description": "Discovery pings every host before the port scan.",
+                    "type": "boolean",
+                    "example": true
+                },
+                "dns_servers": {
+                    "description": "DNSServers are the name servers the task resolves targets with.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "1.1.1.1:53"
+                    ]
+                },
+                "error": {
+                    "description": "Error contains context when a task fails.",
+                    "type": "string",
+                    "example": "failed to resolve target host"
+                },
+                "eta_seconds": {
+                    "description": "ETASeconds estimates how long the running task still needs.",
+                    "type": "integer",
+                    "example": 95
+                },
+                "host_rate": {
+                    "description": "HostRate caps probes per second sent to each individual host.",
+                    "type": "number",
+                    "example": 20
+                },
+                "host_summaries": {
+                    "description": "HostSummaries describes each scanned host once the task completes.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/scanner.HostSummary"
+                    }
+                },
+                "hosts": {
+                    "description": "Hosts captures every hostname or IP submitted for the scan.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "scanme.nmap.org",
+                        "192.0.2.10"
+                    ]
+                },
+                "http_paths": {
+                    "description": "HTTPPaths replaces the paths the http check requests.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "/robots.txt",
+                        "/admin/"
+                    ]
+                },
+                "id": {
+                    "description": "ID is the immutable identifier of the scan task (UUID v4).",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "a3f5c62e-1234-4f72-a84a-1c2d3e4f5678"
+                },
+                "ip_version": {
+                    "description": "IPVersion restricts the task to one address family.",
+                    "type": "integer",
+                    "enum": [
+                        4,
+                        6
+                    ],
+                    "example": 6
+                },
+                "max_duration_seconds": {
+                    "description": "MaxDuration bounds how long the scan may run, in seconds.",
+                    "type": "integer",
+                    "example": 3600
+                },
+                "max_hostgroup": {
+                    "type": "integer",
+                    "example": 64
+                },
+                "max_parallelism": {
+                    "description": "MaxParallelism caps the probes in flight against one host.",
+                    "type": "integer",
+                    "example": 10
+                },
+                "max_rate": {
+                    "description": "MaxRate caps probes per second across all hosts of the task.",
+                    "type": "number",
+                    "example": 500
+                },
+                "max_retries": {
+                    "description": "MaxRetries is how often a silent probe is sent again.",
+                    "type": "integer",
+                    "example": 1
+                },
+                "min_hostgroup": {
+                    "description": "MinHostGroup and MaxHostGroup size the host groups scanned in turn.",
+                    "type": "integer",
+                    "example": 8
+                },
+                "mode": {
+                    "description": "Mode determines the underlying probing strategy executed by workers.",
+                    "type": "string",
+                    "enum": [
+                        "connect",
+                        "syn",
+                        "udp",
+                        "fin",
+                        "null",
+                        "xmas",
+                        "ack"
+                    ],
+                    "example": "syn"
+                },
+                "monitor": {
+                    "description": "Monitor names the monitor that scheduled this verification scan.",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "0f8e3a6d-2c41-4b9e-9a57-3d6c1e2b4f80"
+                },
+                "no_fallback": {
+                    "description": "NoFallback disables the automatic SYN to connect downgrade.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "owner": {
+                    "description": "Owner names the API key or JWT subject that submitted the task.",
+                    "type": "string",
+                    "example": "default"
+                },
+                "parent": {
+                    "description": "Parent names the sharded task this task is one chunk of.",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "7c2b9e14-5a3d-4f6e-8b1a-0d9c8e7f6a52"
+                },
+                "ports": {
+                    "description": "Ports defines the requested port selection as comma-separated values and ranges.",
+                    "type": "string",
+                    "example": "22,80,443,1000-1100"
+                },
+                "prefer": {
+                    "description": "Prefer selects the address family scanned on dual-stack hostnames.",
+                    "type": "string",
+                    "enum": [
+                        "ipv4",
+                        "ipv6",
+                        "both"
+                    ],
+                    "example": "both"
+                },
+                "progress": {
+                    "description": "Progress is the share of the task's probes finished so far.",
+                    "type": "number",
+                    "example": 42.5
+                },
+                "rdap": {
+                    "description": "RDAP requests network ownership lookups for public target addresses.",
+                    "type": "boolean",
+                    "example": true
+                },
+                "resolve_ptr": {
+                    "description": "ResolvePTR requests reverse DNS lookups of the probed addresses.",
+                    "type": "boolean",
+                    "example": true
+                },
+                "results": {
+                    "description": "Results becomes populated with port findings once the task completes.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/scanner.ScanResult"
+                    }
+                },
+                "results_purged_at": {
+                    "description": "ResultsPurgedAt is set once the results were erased on request.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-02-01T09:00:00Z"
+                },
+                "retry_backoff": {
+                    "description": "RetryBackoff is the wait before a silent probe is sent again.",
+                    "type": "string",
+                    "example": "250ms"
+                },
+                "reuse_within": {
+                    "description": "ReuseWithin lets the worker reuse recent results instead of probing again.",
+                    "type": "string",
+                    "example": "15m"
+                },
+                "schedule": {
+                    "description": "Schedule names the recurring schedule that queued this task.",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "6e1f4c2a-8b3d-4a5e-9f60-7d2c1b0a9e83"
+                },
+                "shards": {
+                    "description": "Shards lists the child tasks a sharded scan was split into.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "1d4e6f80-2b3c-4a5d-9e8f-7a6b5c4d3e21",
+                        "9a8b7c6d-5e4f-4321-8fed-cba987654321"
+                    ]
+                },
+                "shards_done": {
+                    "description": "ShardsDone counts the shards that reached a terminal state.",
+                    "type": "integer",
+                    "example": 1
+                },
+                "status": {
+                    "description": "Status reflects the asynchronous lifecycle state of the task.",
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "held",
+                        "running",
+                        "pausing",
+                        "paused",
+                        "cancelling",
+                        "cancelled",
+                        "completed",
+                        "failed"
+                    ],
+                    "example": "pending"
+                },
+                "tags": {
+                    "description": "Tags label the task and the inventory records of its hosts.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "prod",
+                        "dmz"
+                    ]
+                },
+                "targets": {
+                    "description": "Targets holds the per-host settings of a target manifest.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.ScanTarget"
+                    }
+                },
+                "tarpit_downgrade": {
+                    "description": "TarpitDowngrade rewrites the open ports of flagged hosts.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "template": {
+                    "description": "Template names the saved template the submission started from.",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "3c9d2e1f-7a4b-4c5d-8e6f-1a2b3c4d5e6f"
+                },
+                "timing": {
+                    "description": "Timing names the timing template the task runs with.",
+                    "type": "string",
+                    "example": "aggressive"
+                },
+                "version_intensity": {
+                    "description": "VersionIntensity limits service detection to the less rare probes.",
+                    "type": "integer",
+                    "example": 7
+                },
+                "warnings": {
+                    "description": "Warnings lists non-fatal issues encountered while executing the task.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "syn scan unavailable",
+                        " fell back to connect scan: insufficient privileges for raw packet access"
+                    ]
+                }
+            }
+        },
+        "api.ScanTemplate": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "CreatedAt records when the template was created.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T15:04:05Z"
+                },
+                "description": {
+                    "description": "Description says what the template is for.",
+                    "type": "string",
+                    "example": "Web ports with HTTP and TLS checks"
+                },
+                "id": {
+                    "description": "ID is the immutable identifier of the template (UUID v4).",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "3c9d2e1f-7a4b-4c5d-8e6f-1a2b3c4d5e6f"
+                },
+                "name": {
+                    "description": "Name labels the template.",
+                    "type": "string",
+                    "example": "web-servers"
+                },
+                "owner": {
+                    "description": "Owner names the API key or JWT subject that created the template.",
+                    "type": "string",
+                    "example": "default"
+                },
+                "scan": {
+                    "description": "Scan holds the saved fields of the scan request.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.CreateScanRequest"
+                        }
+                    ]
+                }
+            }
+        },
+        "api.Schedule": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "CreatedAt records when the schedule was created.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T15:04:05Z"
+                },
+                "cron": {
+                    "description": "Cron is the expression the scheduler follows.",
+                    "type": "string",
+                    "example": "0 2 * * *"
+                },
+                "id": {
+                    "description": "ID is the immutable identifier of the schedule (UUID v4).",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "6e1f4c2a-8b3d-4a5e-9f60-7d2c1b0a9e83"
+                },
+                "last_error": {
+                    "description": "LastError explains why the last run queued no scan.",
+                    "type": "string",
+                    "example": "previous run a3f5c62e-1234-4f72-a84a-1c2d3e4f5678 is still running"
+                },
+                "last_run_at": {
+                    "description": "LastRunAt records when the last scan was queued.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T00:00:00Z"
+                },
+                "last_task_id": {
+                    "description": "LastTaskID is the most recently queued scan.",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "a3f5c62e-1234-4f72-a84a-1c2d3e4f5678"
+                },
+                "name": {
+                    "description": "Name is an optional label.",
+                    "type": "string",
+                    "example": "nightly dmz sweep"
+                },
+                "next_run_at": {
+                    "description": "NextRunAt is when the scheduler queues the next scan.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-03T00:00:00Z"
+                },
+                "owner": {
+                    "description": "Owner names the API key or JWT subject that created the schedule.",
+                    "type": "string",
+                    "example": "default"
+                },
+                "paused": {
+                    "description": "Paused stops the schedule from queueing scans.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "scan": {
+                    "description": "Scan is the template of every queued scan.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.CreateScanRequest"
+                        }
+                    ]
+                },
+                "timezone": {
+                    "description": "Timezone is the zone the cron expression is evaluated in.",
+                    "type": "string",
+                    "example": "Europe/Kyiv"
+                }
+            }
+        },
+        "api.ServiceCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "description": "Count is the number of open ports running it.",
+                    "type": "integer",
+                    "example": 86
+                },
+                "service": {
+                    "description": "Service is the detected service name.",
+                    "type": "string",
+                    "example": "http"
+                }
+            }
+        },
+        "api.ServiceEndpoint": {
+            "type": "object",
+            "properties": {
+                "host": {
+                    "description": "Host is the inventory host.",
+                    "type": "string",
+                    "example": "203.0.113.50"
+                },
+                "last_seen": {
+                    "description": "LastSeen records when the port was last found open.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-09T15:04:05Z"
+                },
+                "port": {
+                    "description": "Port is the open port.",
+                    "type": "integer",
+                    "example": 22
+                },
+                "protocol": {
+                    "description": "Protocol is tcp or udp.",
+                    "type": "string",
+                    "enum": [
+                        "tcp",
+                        "udp"
+                    ],
+                    "example": "tcp"
+                }
+            }
+        },
+        "api.ServiceGroup": {
+            "type": "object",
+            "properties": {
+                "endpoints": {
+                    "description": "Endpoints lists every host and port in the group.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.ServiceEndpoint"
+                    }
+                },
+                "host_count": {
+                    "description": "HostCount is the number of distinct hosts in the group.",
+                    "type": "integer",
+                    "example": 14
+                },
+                "product": {
+                    "description": "Product is the detected product.",
+                    "type": "string",
+                    "example": "OpenSSH"
+                },
+                "service": {
+                    "description": "Service is the detected service name.",
+                    "type": "string",
+                    "example": "ssh"
+                },
+                "version": {
+                    "description": "Version is the detected product version.",
+                    "type": "string",
+                    "example": "8.2p1"
+                }
+            }
+        },
+        "api.ServiceListResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "description": "Count is the number of service groups that matched the filters.",
+                    "type": "integer",
+                    "example": 2
+                },
+                "services": {
+                    "description": "Services lists the matching groups.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.ServiceGroup"
+                    }
+                }
+            }
+        },
+        "api.StatsResponse": {
+            "type": "object",
+            "properties": {
+                "average_duration_seconds": {
+                    "description": "AverageDurationSeconds is the mean time from submission to completion.",
+                    "type": "number",
+                    "example": 42.7
+                },
+                "completed": {
+                    "description": "Completed counts the tasks that completed.",
+                    "type": "integer",
+                    "example": 112
+                },
+                "days": {
+                    "description": "Days breaks the window down per calendar day.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.DailyStats"
+                    }
+                },
+                "failed": {
+                    "description": "Failed counts the tasks that failed.",
+                    "type": "integer",
+                    "example": 6
+                },
+                "failure_rate": {
+                    "description": "FailureRate is the share of finished tasks that failed.",
+                    "type": "number",
+                    "example": 0.05
+                },
+                "from": {
+                    "description": "From is the start of the reporting window.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T00:00:00Z"
+                },
+                "scans": {
+                    "description": "Scans counts the tasks created in the window.",
+                    "type": "integer",
+                    "example": 120
+                },
+                "to": {
+                    "description": "To is the end of the reporting window.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-09T15:04:05Z"
+                },
+                "top_services": {
+                    "description": "TopServices lists the most frequently discovered services.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.ServiceCount"
+                    }
+                }
+            }
+        },
+        "api.ValidationErrorResponse": {
+            "type": "object",
+            "properties": {
+                "details": {
+                    "description": "Details lists every field-level problem that was detected.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.FieldError"
+                    }
+                },
+                "error": {
+                    "description": "Error summarizes the failure.",
+                    "type": "string",
+                    "example": "invalid request payload"
+                }
+            }
+        },
+        "api.WorkerNode": {
+            "type": "object",
+            "properties": {
+                "capabilities": {
+                    "description": "Capabilities lists the scan modes the node can run.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "connect",
+                        "syn",
+                        "udp"
+                    ]
+                },
+                "hostname": {
+                    "description": "Hostname is the host the node runs on.",
+                    "type": "string",
+                    "example": "scanner-eu-1.internal"
+                },
+                "id": {
+                    "description": "ID identifies the node in the registry.",
+                    "type": "string",
+                    "example": "scanner-eu-1"
+                },
+                "last_seen": {
+                    "description": "LastSeen is when the node last renewed its registration.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T15:10:05Z"
+                },
+                "role": {
+                    "description": "Role tells API servers from standalone workers.",
+                    "type": "string",
+                    "enum": [
+                        "api",
+                        "worker"
+                    ],
+                    "example": "worker"
+                },
+                "started_at": {
+                    "description": "StartedAt is when the node started.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-02T15:04:05Z"
+                },
+                "version": {
+                    "description": "Version is the build the node runs.",
+                    "type": "string",
+                    "example": "v1.4.0"
+                },
+                "workers": {
+                    "description": "Workers is the pool size of every scan mode on the node.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "scanner.Certificate": {
+            "type": "object",
+            "properties": {
+                "dns_names": {
+                    "description": "DNSNames lists the subject alternative names.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "www.example.com",
+                        "example.com"
+                    ]
+                },
+                "issuer": {
+                    "description": "Issuer is the distinguished name of the issuing authority.",
+                    "type": "string",
+                    "example": "CN=R3,O=Let's Encrypt,C=US"
+                },
+                "not_after": {
+                    "description": "NotAfter is when the certificate expires.",
+                    "type": "string",
+                    "example": "2024-03-31T23:59:59Z"
+                },
+                "not_before": {
+                    "description": "NotBefore is the start of the validity period.",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "subject": {
+                    "description": "Subject is the distinguished name the certificate was issued to.",
+                    "type": "string",
+                    "example": "CN=www.example.com,O=Example Inc,C=US"
+                }
+            }
+        },
+        "scanner.Finding": {
+            "type": "object",
+            "properties": {
+                "check": {
+                    "description": "Check names the module that produced the finding.",
+                    "type": "string",
+                    "example": "snmp"
+                },
+                "details": {
+                    "description": "Details carries module specific attributes.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "\"sys_descr\"": "\"Linux gw 5.10\"}",
+                        "{\"community\"": "\"public\""
+                    }
+                },
+                "severity": {
+                    "description": "Severity ranks the finding: info, low, medium or high.",
+                    "type": "string",
+                    "enum": [
+                        "info",
+                        "low",
+                        "medium",
+                        "high"
+                    ],
+                    "example": "medium"
+                },
+                "summary": {
+                    "description": "Summary is a one-line human readable description.",
+                    "type": "string",
+                    "example": "SNMP v2c readable with community public"
+                },
+                "type": {
+                    "description": "Type is a stable machine-readable finding identifier.",
+                    "type": "string",
+                    "example": "snmp-exposed"
+                }
+            }
+        },
+        "scanner.HostSummary": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "description": "Address is the IP the summary refers to.",
+                    "type": "string",
+                    "example": "45.33.32.156"
+                },
+                "host": {
+                    "description": "Host is the target as submitted.",
+                    "type": "string",
+                    "example": "scanme.nmap.org"
+                },
+                "owner": {
+                    "description": "Owner holds registry data about the network containing Address.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/scanner.NetworkOwner"
+                        }
+                    ]
+                },
+                "status": {
+                    "description": "Status tells whether host discovery found the host up or down.",
+                    "type": "string",
+                    "enum": [
+                        "up",
+                        "down"
+                    ],
+                    "example": "up"
+                },
+                "status_reason": {
+                    "description": "StatusReason names the ping that decided Status.",
+                    "type": "string",
+                    "example": "tcp-443"
+                },
+                "tags": {
+                    "description": "Tags are the labels a target manifest gave the host.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "web",
+                        "prod"
+                    ]
+                },
+                "tarpit": {
+                    "description": "Tarpit explains why the host looks like a tarpit or honeypot.",
+                    "type": "string",
+                    "example": "982 of 1000 probed ports report open"
+                }
+            }
+        },
+        "scanner.NetworkOwner": {
+            "type": "object",
+            "properties": {
+                "abuse_contact": {
+                    "description": "AbuseContact is the e-mail address for abuse reports.",
+                    "type": "string",
+                    "example": "abuse@linode.com"
+                },
+                "netname": {
+                    "description": "Netname is the registry name of the network.",
+                    "type": "string",
+                    "example": "LINODE-US"
+                },
+                "organization": {
+                    "description": "Organization is the registrant of the network.",
+                    "type": "string",
+                    "example": "Linode"
+                },
+                "range": {
+                    "description": "Range is the address block the network covers.",
+                    "type": "string",
+                    "example": "45.33.0.0 - 45.33.127.255"
+                }
+            }
+        },
+        "scanner.ResultChange": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string",
+                    "example": "45.33.32.156"
+                },
+                "change": {
+                    "type": "string",
+                    "enum": [
+                        "opened",
+                        "closed",
+                        "service_changed"
+                    ],
+                    "example": "opened"
+                },
+                "host": {
+                    "type": "string",
+                    "example": "scanme.nmap.org"
+                },
+                "port": {
+                    "type": "integer",
+                    "example": 3389
+                },
+                "previous_service": {
+                    "type": "string",
+                    "example": "ssh (OpenSSH 8.2p1)"
+                },
+                "previous_state": {
+                    "type": "string",
+                    "example": "Filtered"
+                },
+                "previous_version": {
+                    "type": "string",
+                    "example": "8.2p1"
+                },
+                "service": {
+                    "type": "string",
+                    "example": "ms-wbt-server"
+                },
+                "state": {
+                    "type": "string",
+                    "example": "Open"
+                },
+                "version": {
+                    "type": "string",
+                    "example": "9.6p1"
+                }
+            }
+        },
+        "scanner.ScanResult": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string",
+                    "example": "45.33.32.156"
+                },
+                "banner_base64": {
+                    "type": "string",
+                    "example": "AAAAGGZ0eXBpc29t"
+                },
+                "certificate": {
+                    "$ref": "#/definitions/scanner.Certificate"
+                },
+                "cpe": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "cpe:/a:openbsd:openssh:8.2p1",
+                        "cpe:/o:linux:linux_kernel"
+                    ]
+                },
+                "family": {
+                    "type": "string",
+                    "enum": [
+                        "ipv4",
+                        "ipv6"
+                    ],
+                    "example": "ipv4"
+                },
+                "findings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/scanner.Finding"
+                    }
+                },
+                "host": {
+                    "type": "string",
+                    "example": "scanme.nmap.org"
+                },
+                "hostname": {
+                    "type": "string",
+                    "example": "mail.example.com"
+                },
+                "info": {
+                    "type": "string",
+                    "example": "Ubuntu Linux; protocol 2.0"
+                },
+                "os": {
+                    "type": "string",
+                    "example": "Linux"
+                },
+                "port": {
+                    "type": "integer",
+                    "example": 443
+                },
+                "product": {
+                    "type": "string",
+                    "example": "OpenSSH"
+                },
+                "reused": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "reverse_hostname": {
+                    "type": "string",
+                    "example": "scanme.nmap.org"
+                },
+                "service": {
+                    "type": "string",
+                    "example": "http (nginx)"
+                },
+                "service_guess": {
+                    "type": "string",
+                    "example": "ms-wbt-server"
+                },
+                "state": {
+                    "type": "string",
+                    "enum": [
+                        "Open",
+                        "Closed",
+                        "Filtered",
+                        "Tarpit"
+                    ],
+                    "example": "Open"
+                },
+                "tls": {
+                    "type": "string",
+                    "enum": [
+                        "implicit",
+                        "starttls"
+                    ],
+                    "example": "implicit"
+                },
+                "version": {
+                    "type": "string",
+                    "example": "8.2p1"
+                }
+            }
+        },
+        "version.Info": {
+            "type": "object",
+            "properties": {
+                "build_date": {
+                    "type": "string",
+                    "example": "2024-01-02T15:04:05Z"
+                },
+                "commit": {
+                    "type": "string",
+                    "example": "4f1c2a9e7b3d"
+                },
+                "go_version": {
+                    "type": "string",
+                    "example": "go1.24.0"
+                },
+                "platform": {
+                    "type": "string",
+                    "example": "linux/amd64"
+                },
+                "version": {
+                    "type": "string",
+                    "example": "v1.2.0"
+                }
+            }
         }
-      },
-      "additionalProperties": false
     },
-    "ScanTask": {
-      "type": "object",
-      "properties": {
-        "completed_at": {
-          "type": "string",
-          "format": "date-time",
-          "description": "Timestamp (UTC, RFC3339 format) indicating when the task finished processing. Empty while the task is pending or running.",
-          "example": "2024-01-02T15:06:30Z"
-        },
-        "created_at": {
-          "type": "string",
-          "format": "date-time",
-          "description": "Timestamp (UTC, RFC3339 format) when the API accepted the scan request.",
-          "example": "2024-01-02T15:04:05Z"
-        },
-        "error": {
-          "type": "string",
-          "description": "Diagnostic message describing why the task entered the failed status. Present only when status equals failed.",
-          "example": "failed to resolve target host"
-        },
-        "hosts": {
-          "type": "array",
-          "description": "List of destination targets. Supports IPv4/IPv6 literals and resolvable domain names. The order is preserved so results can be mapped back to the original submission.",
-          "items": {
-            "type": "string"
-          },
-          "example": [
-            "scanme.nmap.org",
-            "192.0.2.10"
-          ]
-        },
-        "id": {
-          "type": "string",
-          "description": "Immutable UUIDv4 identifier assigned when the task is accepted. Persist this value and reuse it for subsequent polling requests.",
-          "example": "a3f5c62e-1234-4f72-a84a-1c2d3e4f5678",
-          "format": "uuid"
-        },
-        "mode": {
-          "type": "string",
-          "description": "Scanner transport mode. Use connect for TCP connect() handshakes, syn for half-open SYN scanning against TCP endpoints, or udp for stateless UDP datagram probes.",
-          "enum": [
-            "connect",
-            "syn",
-            "udp"
-          ],
-          "example": "syn"
-        },
-        "ports": {
-          "type": "string",
-          "description": "Port expression combining single ports and inclusive ranges using commas (for example 22,80,443,1000-1100). Whitespace is ignored and duplicate ports are automatically de-duplicated by the scheduler.",
-          "example": "22,80,443,1000-1100"
-        },
-        "results": {
-          "type": "array",
-          "description": "Collection of port states collected during scanning. Present only after the task reaches the completed status. The array is sorted by host then port for easy rendering.",
-          "items": {
-            "$ref": "#/definitions/ScanResult"
-          },
-          "example": [
-            {
-              "host": "scanme.nmap.org",
-              "port": 443,
-              "state": "Open",
-              "service": "https"
-            }
-          ]
-        },
-        "status": {
-          "type": "string",
-          "description": "Current processing state. pending indicates the request is queued, running signals active probing, completed denotes success with results attached, and failed highlights an unrecoverable worker-side issue.",
-          "enum": [
-            "pending",
-            "running",
-            "completed",
-            "failed"
-          ],
-          "example": "pending"
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "description": "Supply the configured API key using the Authorization: Bearer \u003ctoken\u003e header.",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
-      },
-      "additionalProperties": false
     }
-  },
-  "tags": [
-    {
-      "name": "Scans",
-      "description": "Cortex orchestrates distributed port scans. Submit new jobs, inspect intermediate task state, and retrieve final findings from this tag."
-    }
-  ]
-}
-`
+}`
 
-func init() {
-	swag.Register(swag.Name, &swaggerDoc{})
+// SwaggerInfo holds exported Swagger Info so clients can modify it
+var SwaggerInfo = &swag.Spec{
+	Version:          "5.0",
+	Host:             "localhost:8080",
+	BasePath:         "/api/v1",
+	Schemes:          []string{},
+	Title:            "Cortex API",
+	Description:      "Cortex exposes an asynchronous network reconnaissance pipeline that decouples request admission from long-running probe execution. Clients describe a scan once, receive a UUID acknowledging queue placement, and then poll for progress until workers deposit structured results.\nThe workflow follows three simple steps:\n1. Submit POST /scans with hosts, ports, and the probing mode best suited for the job.\n2. Store the returned identifier and periodically call GET /scans/{id} to observe lifecycle transitions from pending ➜ running ➜ completed (or failed).\n3. When the task reaches completed the response includes normalized port findings; failed states surface a diagnostic error message.\nEvery request—except for the interactive Swagger UI under /docs—must present the configured API key using the Authorization: Bearer <token> header. Missing or invalid credentials are rejected before any work begins.",
+	InfoInstanceName: "swagger",
+	SwaggerTemplate:  docTemplate,
+	LeftDelim:        "{{",
+	RightDelim:       "}}",
 }
 
-type swaggerDoc struct{}
-
-func (s *swaggerDoc) ReadDoc() string {
-	return docTemplate
+func init() {
+	swag.Register(SwaggerInfo.InstanceName(), SwaggerInfo)
 }
@@ -1,6 +1,8 @@
 package logging
 
 import (
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"sync"
@@ -9,12 +11,24 @@ import (
 var (
 	once   sync.Once
 	logger *slog.Logger
+	output = &reopenableFile{}
 )
 
 // Configure initializes the shared JSON logger. It is safe to call multiple times.
+// Logs go to stdout, or are appended to the file named by CORTEX_LOG_FILE.
 func Configure() *slog.Logger {
 	once.Do(func() {
-		handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo})
+		var w io.Writer = os.Stdout
+		if path := os.Getenv("CORTEX_LOG_FILE"); path != "" {
+			output.path = path
+			if err := output.Reopen(); err != nil {
+				fmt.Fprintf(os.Stderr, "cannot open CORTEX_LOG_FILE, logging to stdout: %v\n", err)
+				output.path = ""
+			} else {
+				w = output
+			}
+		}
+		handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelInfo})
 		logger = slog.New(handler)
 	})
 	return logger
@@ -27,3 +41,42 @@ func Logger() *slog.Logger {
 	}
 	return logger
 }
+
+// Reopen closes and reopens the CORTEX_LOG_FILE, so that logging continues in
+// a new file after the old one was moved away by log rotation. It does
+// nothing when logging to stdout.
+func Reopen() error {
+	if output.path == "" {
+		return nil
+	}
+	return output.Reopen()
+}
+
+// reopenableFile is a log file that can be swapped while loggers write to it.
+type reopenableFile struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+func (f *reopenableFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Write(p)
+}
+
+// Reopen opens path for appending and closes the previous file.
+func (f *reopenableFile) Reopen() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	previous := f.file
+	f.file = file
+	f.mu.Unlock()
+	if previous != nil {
+		return previous.Close()
+	}
+	return nil
+}
@@ -1,17 +1,45 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
-	"cortex/api"
-	"cortex/cli"
-	"cortex/logging"
+	"github.com/Viktor25104/cortex/backend/api"
+	"github.com/Viktor25104/cortex/backend/cli"
+	"github.com/Viktor25104/cortex/backend/logging"
+	"github.com/Viktor25104/cortex/backend/version"
 )
 
 func main() {
 	logging.Configure()
-	if isServerMode(os.Args[1:]) {
-		if err := api.Run(); err != nil {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "probes":
+			os.Exit(cli.RunProbes(os.Args[2:]))
+		case "queue":
+			os.Exit(cli.RunQueue(os.Args[2:]))
+		case "bench":
+			os.Exit(cli.RunBench(os.Args[2:]))
+		case "diff":
+			os.Exit(cli.RunDiff(os.Args[2:]))
+		case "mock-target":
+			os.Exit(cli.RunMockTarget(os.Args[2:]))
+		case "version", "--version":
+			fmt.Println(version.Get())
+			return
+		}
+	}
+
+	if hasFlag(os.Args[1:], "--worker") {
+		if err := api.RunWorker(os.Args[1:]); err != nil {
+			logging.Logger().Error("failed to start worker", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if hasFlag(os.Args[1:], "--server") {
+		if err := api.Run(os.Args[1:]); err != nil {
 			logging.Logger().Error("failed to start API server", "error", err)
 			os.Exit(1)
 		}
@@ -21,9 +49,9 @@ func main() {
 	cli.Run()
 }
 
-func isServerMode(args []string) bool {
+func hasFlag(args []string, flag string) bool {
 	for _, arg := range args {
-		if arg == "--server" {
+		if arg == flag {
 			return true
 		}
 	}
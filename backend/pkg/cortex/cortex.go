@@ -0,0 +1,206 @@
+// Package cortex embeds the Cortex port scanner in other Go programs.
+//
+// A Scanner is configured once with New and functional options and can then
+// run any number of scans, also concurrently:
+//
+//	s, err := cortex.New(cortex.WithPortList("22,80,443"), cortex.WithTiming("aggressive"))
+//	if err != nil {
+//		return err
+//	}
+//	report, err := s.Scan(ctx, "scanme.nmap.org", "192.168.1.0/24")
+//
+// Scans stop when ctx is cancelled or WithMaxDuration passes, returning the
+// results gathered so far. The package wraps the scanner package of this
+// module, which the CLI and API server use, and keeps its surface stable
+// while that package grows.
+package cortex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Viktor25104/cortex/backend/scanner"
+)
+
+// Mode selects the probing technique of a scan.
+type Mode string
+
+// Supported scan modes. Every mode but ModeConnect sends raw packets and
+// needs root or CAP_NET_RAW (Npcap on Windows).
+const (
+	ModeConnect Mode = Mode(scanner.ModeConnect)
+	ModeSyn     Mode = Mode(scanner.ModeSyn)
+	ModeUDP     Mode = Mode(scanner.ModeUDP)
+	ModeFin     Mode = Mode(scanner.ModeFin)
+	ModeNull    Mode = Mode(scanner.ModeNull)
+	ModeXmas    Mode = Mode(scanner.ModeXmas)
+	ModeAck     Mode = Mode(scanner.ModeAck)
+)
+
+// ErrInsufficientPrivileges is returned by Scan when a raw packet mode lacks
+// the privileges it needs and WithFallback(false) forbids a connect scan.
+var ErrInsufficientPrivileges = scanner.ErrInsufficientPrivileges
+
+// Scanner runs scans with a fixed configuration. It is safe for concurrent
+// use.
+type Scanner struct {
+	mode       Mode
+	fallback   bool
+	ports      []int
+	portList   string
+	timing     string
+	retries    int
+	rate       float64
+	hostRate   float64
+	workers    int
+	maxTargets int
+	maxDur     time.Duration
+	probesFile string
+	intensity  int
+	dnsServers []string
+	exclude    []string
+	allAddrs   bool
+	reverseDNS bool
+	onResult   func(Result)
+	onProgress func(done, total int)
+
+	options []scanner.Option
+}
+
+// New returns a Scanner configured by options. It fails when an option holds
+// an invalid value or the service probes file cannot be loaded. Without
+// options it connect scans the ports given to WithPorts or WithPortList, of
+// which there must be at least one.
+func New(options ...Option) (*Scanner, error) {
+	s := &Scanner{mode: ModeConnect, fallback: true, retries: -1, intensity: -1, maxTargets: scanner.DefaultMaxTargets}
+	for _, option := range options {
+		option(s)
+	}
+
+	mode, err := scanner.ParseMode(string(s.mode))
+	if err != nil {
+		return nil, err
+	}
+	s.mode = Mode(mode)
+
+	ports := append([]int(nil), s.ports...)
+	if s.portList != "" {
+		spec, err := scanner.ParsePortSpec(s.portList)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port list: %w", err)
+		}
+		protocol := "tcp"
+		if mode == scanner.ModeUDP {
+			protocol = "udp"
+		}
+		ports = append(ports, spec.Ports(protocol)...)
+	}
+	if len(ports) == 0 {
+		return nil, errors.New("no ports to scan")
+	}
+	for _, port := range ports {
+		if port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %d: must be between 1 and 65535", port)
+		}
+	}
+
+	timing, err := scanner.ParseTiming(s.timing)
+	if err != nil {
+		return nil, err
+	}
+	if s.retries >= 0 {
+		timing.MaxRetries = s.retries
+	}
+	if s.rate < 0 || s.hostRate < 0 || s.workers < 0 || s.maxDur < 0 {
+		return nil, errors.New("rates, workers and the maximum duration must not be negative")
+	}
+	if s.intensity > scanner.MaxVersionIntensity {
+		return nil, fmt.Errorf("version intensity must be between 0 and %d", scanner.MaxVersionIntensity)
+	}
+
+	s.options = []scanner.Option{
+		scanner.WithMode(mode),
+		scanner.WithFallback(s.fallback),
+		scanner.WithPorts(ports...),
+		scanner.WithTiming(timing),
+		scanner.WithRate(s.rate),
+		scanner.WithHostRate(s.hostRate),
+		scanner.WithAllAddresses(s.allAddrs),
+		scanner.WithReverseDNS(s.reverseDNS),
+		scanner.WithMaxDuration(s.maxDur),
+	}
+	if s.workers > 0 {
+		s.options = append(s.options, scanner.WithWorkers(s.workers))
+	}
+	if s.intensity >= 0 {
+		s.options = append(s.options, scanner.WithVersionIntensity(s.intensity))
+	}
+	if s.probesFile != "" {
+		probes, _, err := scanner.LoadProbes(s.probesFile)
+		if err != nil {
+			return nil, fmt.Errorf("load service probes: %w", err)
+		}
+		s.options = append(s.options, scanner.WithProbes(scanner.NewProbeCache(probes)))
+	}
+	if len(s.dnsServers) > 0 {
+		servers, err := scanner.ParseDNSServers(s.dnsServers)
+		if err != nil {
+			return nil, err
+		}
+		s.options = append(s.options, scanner.WithDNSServers(servers...))
+	}
+	if len(s.exclude) > 0 {
+		blocklist, err := scanner.ParseBlocklist(s.exclude)
+		if err != nil {
+			return nil, err
+		}
+		s.options = append(s.options, scanner.WithBlocklist(blocklist))
+	}
+	if s.onProgress != nil {
+		s.options = append(s.options, scanner.WithProgress(s.onProgress))
+	}
+	return s, nil
+}
+
+// Scan probes every target on the configured ports. Targets are hostnames,
+// IP addresses, CIDR blocks such as 10.0.0.0/24 and dashed IPv4 ranges such
+// as 10.0.0.1-50, each possibly a comma-separated list.
+//
+// When ctx is cancelled no new probes are sent, probes in flight finish and
+// the partial report is returned together with ctx.Err(). A scan stopped by
+// WithMaxDuration returns its partial report with a warning and no error.
+func (s *Scanner) Scan(ctx context.Context, targets ...string) (*Report, error) {
+	hosts, err := scanner.ExpandTargets(targets, nil, s.maxTargets)
+	if err != nil {
+		return nil, err
+	}
+
+	options := s.options
+	if s.onResult != nil {
+		protocol := s.protocol()
+		options = append(options[:len(options):len(options)], scanner.WithLifecycle(nil, func(result scanner.ScanResult) {
+			s.onResult(newResult(result, protocol))
+		}, nil))
+	}
+	report, err := scanner.Run(ctx, hosts, options...)
+	if report == nil {
+		return nil, err
+	}
+	return newReport(report), err
+}
+
+// Mode returns the scan mode the Scanner requests. Report.Mode tells the
+// mode a scan actually used.
+func (s *Scanner) Mode() Mode {
+	return s.mode
+}
+
+// protocol is the transport protocol the Scanner's probes use.
+func (s *Scanner) protocol() string {
+	if s.mode == ModeUDP {
+		return "udp"
+	}
+	return "tcp"
+}
@@ -0,0 +1,123 @@
+package cortex
+
+import "time"
+
+// Option configures a Scanner in New.
+type Option func(*Scanner)
+
+// WithMode selects the scan technique. The default is ModeConnect.
+func WithMode(mode Mode) Option {
+	return func(s *Scanner) { s.mode = mode }
+}
+
+// WithFallback controls whether a raw packet scan lacking privileges is
+// downgraded to a connect scan (the default) instead of failing with
+// ErrInsufficientPrivileges.
+func WithFallback(allow bool) Option {
+	return func(s *Scanner) { s.fallback = allow }
+}
+
+// WithPorts adds individual ports to scan.
+func WithPorts(ports ...int) Option {
+	return func(s *Scanner) { s.ports = append(s.ports, ports...) }
+}
+
+// WithPortList adds the ports of an nmap-style specification such as
+// "22,80,443,1000-1100" or "T:80,443,U:53", of which the entries for the
+// scan's protocol are used.
+func WithPortList(spec string) Option {
+	return func(s *Scanner) { s.portList = spec }
+}
+
+// WithTiming selects a timing template by level ("0" to "5") or name
+// (paranoid, sneaky, polite, normal, aggressive, insane). The default is
+// normal.
+func WithTiming(template string) Option {
+	return func(s *Scanner) { s.timing = template }
+}
+
+// WithRetries sets how often a probe that drew no answer is sent again,
+// replacing the timing template's value.
+func WithRetries(n int) Option {
+	return func(s *Scanner) { s.retries = n }
+}
+
+// WithRate caps the total probes per second across all hosts. Zero means
+// unlimited.
+func WithRate(perSecond float64) Option {
+	return func(s *Scanner) { s.rate = perSecond }
+}
+
+// WithHostRate caps the probes per second sent to any single host. Zero means
+// unlimited.
+func WithHostRate(perSecond float64) Option {
+	return func(s *Scanner) { s.hostRate = perSecond }
+}
+
+// WithWorkers overrides the number of concurrent probes of the scan mode.
+func WithWorkers(n int) Option {
+	return func(s *Scanner) { s.workers = n }
+}
+
+// WithMaxTargets refuses scans whose targets expand to more than n addresses.
+// The default is 65536.
+func WithMaxTargets(n int) Option {
+	return func(s *Scanner) { s.maxTargets = n }
+}
+
+// WithMaxDuration bounds every scan. Once d passes no further probes are sent
+// and Scan returns the results so far with a warning. Zero means no limit.
+func WithMaxDuration(d time.Duration) Option {
+	return func(s *Scanner) { s.maxDur = d }
+}
+
+// WithServiceProbes loads service fingerprinting rules from an nmap
+// nmap-service-probes file. Without it ports are classified but services are
+// not identified beyond a guess from the port number.
+func WithServiceProbes(path string) Option {
+	return func(s *Scanner) { s.probesFile = path }
+}
+
+// WithVersionIntensity (0-9) limits the service probes sent to a port to the
+// rarity given, like nmap's --version-intensity. The default is 7.
+func WithVersionIntensity(intensity int) Option {
+	return func(s *Scanner) { s.intensity = intensity }
+}
+
+// WithDNSServers sends every DNS query of a scan to servers, given as IP
+// addresses with an optional port, instead of the system's name servers.
+func WithDNSServers(servers ...string) Option {
+	return func(s *Scanner) { s.dnsServers = append(s.dnsServers, servers...) }
+}
+
+// WithExclude never probes the given IP addresses and CIDR blocks, even when
+// a target resolves to them.
+func WithExclude(ranges ...string) Option {
+	return func(s *Scanner) { s.exclude = append(s.exclude, ranges...) }
+}
+
+// WithAllAddresses scans every address of a hostname instead of one.
+func WithAllAddresses(all bool) Option {
+	return func(s *Scanner) { s.allAddrs = all }
+}
+
+// WithReverseDNS looks up the PTR name of every scanned address into
+// Result.ReverseHostname.
+func WithReverseDNS(enabled bool) Option {
+	return func(s *Scanner) { s.reverseDNS = enabled }
+}
+
+// WithResultHandler calls onResult for every result as soon as its port has
+// been probed. The calls of one scan come one after another from the
+// goroutine collecting its results, so onResult only needs to be safe for
+// concurrent use when the Scanner runs several scans at once; the report is
+// not complete before the last call returns.
+func WithResultHandler(onResult func(Result)) Option {
+	return func(s *Scanner) { s.onResult = onResult }
+}
+
+// WithProgress calls onProgress after every result with the probes finished
+// and planned so far.
+func WithProgress(onProgress func(done, total int)) Option {
+	return func(s *Scanner) { s.onProgress = onProgress }
+}
@@ -0,0 +1,242 @@
+package cortex
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/Viktor25104/cortex/backend/scanner"
+)
+
+// PortState is the disposition of a probed port.
+type PortState string
+
+// Port states a scan reports.
+const (
+	// StateOpen ports accepted the probe or answered it.
+	StateOpen PortState = "Open"
+	// StateClosed ports rejected the probe.
+	StateClosed PortState = "Closed"
+	// StateFiltered ports drew no usable answer, typically because a
+	// firewall dropped the probe.
+	StateFiltered PortState = "Filtered"
+	// StateOpenFiltered ports stayed silent where open and filtered ports
+	// look the same: UDP, FIN, NULL and Xmas scans.
+	StateOpenFiltered PortState = "Open|Filtered"
+	// StateUnfiltered ports answered an ACK scan, so no firewall stands in
+	// between; it does not tell open from closed.
+	StateUnfiltered PortState = "Unfiltered"
+	// StateTarpit replaces StateOpen on hosts flagged as likely tarpits.
+	StateTarpit PortState = "Tarpit"
+)
+
+// Certificate is the leaf certificate a TLS service presented.
+type Certificate struct {
+	// Subject and Issuer are distinguished names; they are equal for
+	// self-signed certificates.
+	Subject string
+	Issuer  string
+	// NotBefore and NotAfter bound the validity period.
+	NotBefore time.Time
+	NotAfter  time.Time
+	// DNSNames lists the subject alternative names.
+	DNSNames []string
+}
+
+// Finding is an observation of a check module about an open port.
+type Finding struct {
+	// Check names the module that produced the finding, such as "snmp".
+	Check string
+	// Type is a stable identifier such as "snmp-exposed", for filtering.
+	Type string
+	// Severity is "info", "low", "medium" or "high".
+	Severity string
+	// Summary is a one-line human readable description.
+	Summary string
+	// Details carries module specific attributes.
+	Details map[string]string
+}
+
+// Host summarizes a scanned host: its network owner, discovery status or
+// tarpit verdict.
+type Host struct {
+	// Host is the target as submitted and Address the IP the summary
+	// refers to.
+	Host    string
+	Address string
+	// Owner is the registry data of the network containing Address, set by
+	// WithRDAP when the lookup succeeded.
+	Owner *NetworkOwner
+	// Tags are the labels a target manifest gave the host.
+	Tags []string
+	// Tarpit explains why the host looks like a tarpit, empty unless it was
+	// flagged.
+	Tarpit string
+	// Status is "up" or "down" when host discovery ran, and StatusReason
+	// names what decided it, such as "echo-reply" or "no-response".
+	Status       string
+	StatusReason string
+}
+
+// NetworkOwner is what an RDAP registry publishes about an IP network.
+type NetworkOwner struct {
+	Netname      string
+	Range        string
+	Organization string
+	AbuseContact string
+}
+
+// Service describes what runs on an open port.
+type Service struct {
+	// Name is the service fingerprint such as "http (nginx)", or a printable
+	// preview of the raw banner when no rule identified it.
+	Name string
+	// Product, Version, Info, Hostname and OS are the fields the matching
+	// probe rule extracted from the response.
+	Product  string
+	Version  string
+	Info     string
+	Hostname string
+	OS       string
+	// CPE lists the Common Platform Enumeration names of the match.
+	CPE []string
+	// Guess is the service commonly found on the port number, set only for
+	// open ports no rule identified.
+	Guess string
+	// TLS is "implicit" or "starttls" when the service speaks TLS.
+	TLS string
+	// Certificate is the certificate presented over TLS, unverified.
+	Certificate *Certificate
+	// Banner holds the raw response when it was binary or not valid UTF-8.
+	Banner []byte
+}
+
+// Result is the outcome of probing one port of one host.
+type Result struct {
+	// Host is the target as submitted or expanded from a range.
+	Host string
+	// Address is the IP address that was probed when it differs from Host,
+	// for example on multi-homed hosts scanned with WithAllAddresses.
+	Address string
+	// Family is "ipv4" or "ipv6", empty when Host did not resolve.
+	Family string
+	// Port and Protocol ("tcp" or "udp") name the probed port.
+	Port     int
+	Protocol string
+	State    PortState
+	Service  Service
+	// ReverseHostname is the PTR name of the address, set by WithReverseDNS.
+	ReverseHostname string
+	// Findings are the observations of check modules on the port.
+	Findings []Finding
+}
+
+// Open reports whether the port is open.
+func (r Result) Open() bool {
+	return r.State == StateOpen
+}
+
+// Report is the outcome of a scan.
+type Report struct {
+	// Mode is the scan mode actually used, which is ModeConnect when a raw
+	// packet scan fell back for lack of privileges.
+	Mode Mode
+	// Results holds one entry per probed host and port.
+	Results []Result
+	// Warnings lists non-fatal issues such as a mode downgrade, excluded
+	// targets or a scan stopped by WithMaxDuration.
+	Warnings []string
+	// Hosts lists hosts with a discovery status, owner or tarpit verdict.
+	Hosts []Host
+}
+
+// Open returns the results of open ports.
+func (r *Report) Open() []Result {
+	var open []Result
+	for _, result := range r.Results {
+		if result.Open() {
+			open = append(open, result)
+		}
+	}
+	return open
+}
+
+func newReport(report *scanner.Report) *Report {
+	protocol := "tcp"
+	if report.Mode == scanner.ModeUDP {
+		protocol = "udp"
+	}
+	converted := &Report{Mode: Mode(report.Mode), Warnings: report.Warnings}
+	converted.Results = make([]Result, 0, len(report.Results))
+	for _, result := range report.Results {
+		converted.Results = append(converted.Results, newResult(result, protocol))
+	}
+	for _, host := range report.Hosts {
+		converted.Hosts = append(converted.Hosts, newHost(host))
+	}
+	return converted
+}
+
+func newHost(host scanner.HostSummary) Host {
+	converted := Host{
+		Host:         host.Host,
+		Address:      host.Address,
+		Tags:         host.Tags,
+		Tarpit:       host.Tarpit,
+		Status:       host.Status,
+		StatusReason: host.StatusReason,
+	}
+	if owner := host.Owner; owner != nil {
+		converted.Owner = &NetworkOwner{
+			Netname:      owner.Netname,
+			Range:        owner.Range,
+			Organization: owner.Organization,
+			AbuseContact: owner.AbuseContact,
+		}
+	}
+	return converted
+}
+
+func newResult(result scanner.ScanResult, protocol string) Result {
+	converted := Result{
+		Host:     result.Host,
+		Address:  result.Address,
+		Family:   result.Family,
+		Port:     result.Port,
+		Protocol: protocol,
+		State:    PortState(result.State),
+		Service: Service{
+			Name:     result.Service,
+			Product:  result.Product,
+			Version:  result.Version,
+			Info:     result.Info,
+			Hostname: result.Hostname,
+			OS:       result.OS,
+			CPE:      result.CPE,
+			Guess:    result.ServiceGuess,
+			TLS:      result.TLS,
+		},
+		ReverseHostname: result.ReverseHostname,
+	}
+	if certificate := result.Certificate; certificate != nil {
+		converted.Service.Certificate = &Certificate{
+			Subject:   certificate.Subject,
+			Issuer:    certificate.Issuer,
+			NotBefore: certificate.NotBefore,
+			NotAfter:  certificate.NotAfter,
+			DNSNames:  certificate.DNSNames,
+		}
+	}
+	for _, finding := range result.Findings {
+		converted.Findings = append(converted.Findings, Finding{
+			Check:    finding.Check,
+			Type:     finding.Type,
+			Severity: finding.Severity,
+			Summary:  finding.Summary,
+			Details:  finding.Details,
+		})
+	}
+	if result.BannerBase64 != "" {
+		converted.Service.Banner, _ = base64.StdEncoding.DecodeString(result.BannerBase64)
+	}
+	return converted
+}
@@ -0,0 +1,103 @@
+package scanner
+
+import (
+	"encoding/base64"
+	"net"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Banner capture defaults, matching the original single 4096-byte read.
+const (
+	DefaultBannerMaxBytes    = 4096
+	DefaultBannerReadTimeout = 3 * time.Second
+)
+
+// BannerOptions controls how service responses are collected after a probe.
+type BannerOptions struct {
+	// MaxBytes caps how much of a response is kept. Zero uses DefaultBannerMaxBytes.
+	MaxBytes int
+	// ReadTimeout is how long to wait for the first byte of a response.
+	// Zero uses DefaultBannerReadTimeout.
+	ReadTimeout time.Duration
+	// QuietPeriod enables multi-read accumulation: after the first chunk
+	// arrives, reading continues until the service stays silent for this long
+	// or MaxBytes is reached. Zero keeps the single-read behaviour.
+	QuietPeriod time.Duration
+}
+
+// withDefaults fills unset fields with the package defaults.
+func (o BannerOptions) withDefaults() BannerOptions {
+	if o.MaxBytes <= 0 {
+		o.MaxBytes = DefaultBannerMaxBytes
+	}
+	if o.ReadTimeout <= 0 {
+		o.ReadTimeout = DefaultBannerReadTimeout
+	}
+	return o
+}
+
+// readBanner collects a response from conn according to opts. A timeout before
+// any data is returned as an error so callers can tell silence from resets;
+// once data has arrived, a later timeout or close simply ends accumulation.
+func readBanner(conn net.Conn, opts BannerOptions) ([]byte, error) {
+	buffer := make([]byte, opts.MaxBytes)
+
+	_ = conn.SetReadDeadline(time.Now().Add(opts.ReadTimeout))
+	n, err := conn.Read(buffer)
+	if err != nil && n == 0 {
+		return nil, err
+	}
+	if opts.QuietPeriod <= 0 {
+		return buffer[:n], nil
+	}
+
+	// Multi-packet banners (SMTP continuation lines, SSH KEX after the
+	// version string) arrive in bursts; keep reading until the line goes quiet
+	for n < len(buffer) {
+		_ = conn.SetReadDeadline(time.Now().Add(opts.QuietPeriod))
+		m, err := conn.Read(buffer[n:])
+		n += m
+		if err != nil {
+			// Quiet period elapsed or the peer closed: the banner is complete
+			break
+		}
+	}
+	return buffer[:n], nil
+}
+
+// bannerText makes a raw service response safe to report as text. Printable
+// UTF-8 text is returned unchanged. Anything else, such as a binary protocol
+// greeting, yields a preview with every byte outside printable ASCII replaced
+// by '.', plus the raw bytes in standard base64 so the response is not lost.
+func bannerText(raw string) (text, encoded string) {
+	if isPrintableText(raw) {
+		return raw, ""
+	}
+	var preview strings.Builder
+	preview.Grow(len(raw))
+	for i := 0; i < len(raw); i++ {
+		if b := raw[i]; b == '\t' || b == '\r' || b == '\n' || (b >= 0x20 && b < 0x7f) {
+			preview.WriteByte(b)
+		} else {
+			preview.WriteByte('.')
+		}
+	}
+	return preview.String(), base64.StdEncoding.EncodeToString([]byte(raw))
+}
+
+// isPrintableText reports whether s is valid UTF-8 made of printable
+// characters and ordinary whitespace only.
+func isPrintableText(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r != '\t' && r != '\r' && r != '\n' && !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// Environment variables holding operator-supplied never-scan ranges. They are
+// honored by both the CLI and the API server.
+const (
+	BlockedRangesEnv     = "CORTEX_BLOCKED_RANGES"
+	BlockedRangesFileEnv = "CORTEX_BLOCKED_RANGES_FILE"
+)
+
+// Blocklist is a set of address ranges that must never be probed.
+// A nil Blocklist blocks nothing.
+type Blocklist struct {
+	nets []*net.IPNet
+}
+
+// ParseBlocklist builds a blocklist from CIDR blocks or single IP addresses.
+func ParseBlocklist(specs []string) (*Blocklist, error) {
+	b := &Blocklist{}
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		if !strings.Contains(spec, "/") {
+			ip := net.ParseIP(spec)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid blocked range %q: expected CIDR or IP address", spec)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			b.nets = append(b.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blocked range %q: %w", spec, err)
+		}
+		b.nets = append(b.nets, network)
+	}
+	return b, nil
+}
+
+// ReadBlocklistFile reads one CIDR or IP per line; blank lines and text after # are ignored.
+func ReadBlocklistFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var specs []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			specs = append(specs, line)
+		}
+	}
+	return specs, scanner.Err()
+}
+
+// BlocklistFromEnv loads never-scan ranges from CORTEX_BLOCKED_RANGES (comma
+// separated) and CORTEX_BLOCKED_RANGES_FILE, plus any extra entries given.
+func BlocklistFromEnv(extra ...string) (*Blocklist, error) {
+	specs := append([]string{}, extra...)
+	if raw := os.Getenv(BlockedRangesEnv); raw != "" {
+		specs = append(specs, strings.Split(raw, ",")...)
+	}
+	if path := os.Getenv(BlockedRangesFileEnv); path != "" {
+		fileSpecs, err := ReadBlocklistFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", BlockedRangesFileEnv, err)
+		}
+		specs = append(specs, fileSpecs...)
+	}
+	return ParseBlocklist(specs)
+}
+
+// Len reports the number of blocked ranges.
+func (b *Blocklist) Len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.nets)
+}
+
+// Match returns the blocked range containing ip, if any.
+func (b *Blocklist) Match(ip net.IP) (*net.IPNet, bool) {
+	if b == nil {
+		return nil, false
+	}
+	for _, network := range b.nets {
+		if network.Contains(ip) {
+			return network, true
+		}
+	}
+	return nil, false
+}
+
+// MatchLiteral reports whether host is an IP literal inside a blocked range.
+// Hostnames are not resolved; they are checked when scan jobs are generated.
+func (b *Blocklist) MatchLiteral(host string) (*net.IPNet, bool) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, false
+	}
+	return b.Match(ip)
+}
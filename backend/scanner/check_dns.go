@@ -0,0 +1,114 @@
+package scanner
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCheck(openResolverCheck{})
+}
+
+// openResolverProbeName is the external name queried to test recursion. It is
+// not served by the target itself, so an answer proves the server recursed.
+const openResolverProbeName = "example.com"
+
+// openResolverCheck asks a UDP/53 server to recursively resolve an external
+// name and flags servers that answer, since open resolvers are abused for
+// amplification attacks.
+type openResolverCheck struct{}
+
+func (openResolverCheck) Name() string { return "dns" }
+
+func (openResolverCheck) Description() string {
+	return "Detect open recursive DNS resolvers on UDP/53"
+}
+
+func (openResolverCheck) Intrusive() bool { return false }
+
+func (openResolverCheck) Applies(target CheckTarget) bool {
+	return target.Protocol == "udp" && target.Port == 53
+}
+
+func (openResolverCheck) Run(ctx context.Context, target CheckTarget) ([]Finding, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	answers, err := dnsRecursiveQuery(target.dialAddress(), openResolverProbeName, target.Timeout)
+	if err != nil || answers == 0 {
+		return nil, err
+	}
+	return []Finding{{
+		Type:     "open-resolver",
+		Severity: SeverityMedium,
+		Summary:  fmt.Sprintf("DNS server recursively resolved %s for an external client", openResolverProbeName),
+		Details: map[string]string{
+			"query":   openResolverProbeName,
+			"answers": fmt.Sprint(answers),
+		},
+	}}, nil
+}
+
+// DNS header flag bits.
+const (
+	dnsFlagResponse         = 0x8000
+	dnsFlagRecursionDesired = 0x0100
+	dnsFlagRecursionAvail   = 0x0080
+	dnsRcodeMask            = 0x000f
+)
+
+// dnsRecursiveQuery sends a recursion-desired A query and returns the number of
+// answers in a successful reply from a server advertising recursion.
+func dnsRecursiveQuery(address, name string, timeout time.Duration) (int, error) {
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	var idBytes [2]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return 0, err
+	}
+	id := binary.BigEndian.Uint16(idBytes[:])
+
+	query := make([]byte, 12, 64)
+	binary.BigEndian.PutUint16(query[0:], id)
+	binary.BigEndian.PutUint16(query[2:], dnsFlagRecursionDesired)
+	binary.BigEndian.PutUint16(query[4:], 1) // QDCOUNT
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		query = append(query, byte(len(label)))
+		query = append(query, label...)
+	}
+	query = append(query, 0, 0, 1, 0, 1) // root, QTYPE A, QCLASS IN
+
+	if _, err := conn.Write(query); err != nil {
+		return 0, err
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	reply := make([]byte, 1500)
+	n, err := conn.Read(reply)
+	if err != nil {
+		return 0, err
+	}
+	if n < 12 {
+		return 0, errors.New("truncated DNS reply")
+	}
+
+	flags := binary.BigEndian.Uint16(reply[2:])
+	switch {
+	case binary.BigEndian.Uint16(reply[0:]) != id:
+		return 0, errors.New("DNS reply does not match query id")
+	case flags&dnsFlagResponse == 0:
+		return 0, errors.New("DNS reply is not a response")
+	case flags&dnsFlagRecursionAvail == 0, flags&dnsRcodeMask != 0:
+		return 0, nil
+	}
+	return int(binary.BigEndian.Uint16(reply[6:])), nil
+}
@@ -0,0 +1,177 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCheck(ftpAnonymousCheck{})
+}
+
+// ftpListingLimit caps how much of the directory listing is read.
+const ftpListingLimit = 1 << 20
+
+// ftpAnonymousCheck attempts an anonymous FTP login and, when it succeeds,
+// lists the initial directory. Logging in is a credential attempt, so the
+// check is intrusive.
+type ftpAnonymousCheck struct{}
+
+func (ftpAnonymousCheck) Name() string { return "ftp" }
+
+func (ftpAnonymousCheck) Description() string {
+	return "Try an anonymous FTP login and measure the initial directory listing"
+}
+
+func (ftpAnonymousCheck) Intrusive() bool { return true }
+
+func (ftpAnonymousCheck) Applies(target CheckTarget) bool {
+	return target.Protocol == "tcp" && (target.Port == 21 || serviceLooksLike(target.Service, "ftp"))
+}
+
+func (ftpAnonymousCheck) Run(ctx context.Context, target CheckTarget) ([]Finding, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("tcp", target.dialAddress(), target.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(4 * target.Timeout))
+
+	control := textproto.NewConn(conn)
+	if _, _, err := control.ReadResponse(220); err != nil {
+		return nil, err
+	}
+
+	code, message, err := ftpCommand(control, "USER anonymous")
+	if err == nil && code == 331 {
+		code, message, err = ftpCommand(control, "PASS anonymous@example.com")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if code != 230 {
+		return []Finding{{
+			Type:     "ftp-anonymous-denied",
+			Severity: SeverityInfo,
+			Summary:  "Anonymous FTP login was refused",
+			Details:  map[string]string{"reply": fmt.Sprintf("%d %s", code, message)},
+		}}, nil
+	}
+
+	details := map[string]string{"user": "anonymous"}
+	if code, message, err := ftpCommand(control, "PWD"); err == nil && code == 257 {
+		if start, end := strings.IndexByte(message, '"'), strings.LastIndexByte(message, '"'); end > start {
+			details["directory"] = message[start+1 : end]
+		}
+	}
+
+	summary := "Anonymous FTP login accepted"
+	if listing, err := ftpList(control, target); err == nil {
+		entries := 0
+		for _, line := range strings.Split(string(listing), "\n") {
+			if strings.TrimSpace(line) != "" {
+				entries++
+			}
+		}
+		details["listing_entries"] = strconv.Itoa(entries)
+		details["listing_bytes"] = strconv.Itoa(len(listing))
+		summary += fmt.Sprintf(", initial directory lists %d entries", entries)
+	}
+	_, _, _ = ftpCommand(control, "QUIT")
+
+	return []Finding{{
+		Type:     "ftp-anonymous-login",
+		Severity: SeverityMedium,
+		Summary:  summary,
+		Details:  details,
+	}}, nil
+}
+
+// ftpCommand sends one command and returns the reply code and message.
+func ftpCommand(control *textproto.Conn, command string) (int, string, error) {
+	if err := control.PrintfLine("%s", command); err != nil {
+		return 0, "", err
+	}
+	// Expecting code 0 accepts any well-formed reply for the caller to interpret
+	return control.ReadResponse(0)
+}
+
+// ftpList opens a passive data connection and returns the LIST output.
+func ftpList(control *textproto.Conn, target CheckTarget) ([]byte, error) {
+	port, err := ftpPassivePort(control)
+	if err != nil {
+		return nil, err
+	}
+	// Always dial the target rather than the announced address, which may be
+	// private behind NAT or point elsewhere entirely
+	data, err := net.DialTimeout("tcp", net.JoinHostPort(target.Address, strconv.Itoa(port)), target.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer data.Close()
+	_ = data.SetDeadline(time.Now().Add(2 * target.Timeout))
+
+	code, message, err := ftpCommand(control, "LIST")
+	if err != nil {
+		return nil, err
+	}
+	if code != 125 && code != 150 {
+		return nil, fmt.Errorf("LIST refused: %d %s", code, message)
+	}
+	listing, err := io.ReadAll(io.LimitReader(data, ftpListingLimit))
+	if err != nil {
+		return nil, err
+	}
+	_, _, _ = control.ReadResponse(226)
+	return listing, nil
+}
+
+// ftpPassivePort enters passive mode with EPSV, falling back to PASV, and
+// returns the data port announced by the server.
+func ftpPassivePort(control *textproto.Conn) (int, error) {
+	code, message, err := ftpCommand(control, "EPSV")
+	if err != nil {
+		return 0, err
+	}
+	if code == 229 {
+		// 229 Entering Extended Passive Mode (|||port|)
+		start := strings.Index(message, "(|||")
+		end := strings.LastIndex(message, "|)")
+		if start >= 0 && end > start+4 {
+			return strconv.Atoi(message[start+4 : end])
+		}
+	}
+
+	code, message, err = ftpCommand(control, "PASV")
+	if err != nil {
+		return 0, err
+	}
+	if code != 227 {
+		return 0, fmt.Errorf("passive mode refused: %d %s", code, message)
+	}
+	// 227 Entering Passive Mode (h1,h2,h3,h4,p1,p2)
+	start := strings.IndexByte(message, '(')
+	end := strings.IndexByte(message, ')')
+	if start < 0 || end < start {
+		return 0, fmt.Errorf("malformed PASV reply %q", message)
+	}
+	fields := strings.Split(message[start+1:end], ",")
+	if len(fields) != 6 {
+		return 0, fmt.Errorf("malformed PASV reply %q", message)
+	}
+	high, errHigh := strconv.Atoi(strings.TrimSpace(fields[4]))
+	low, errLow := strconv.Atoi(strings.TrimSpace(fields[5]))
+	if errHigh != nil || errLow != nil {
+		return 0, fmt.Errorf("malformed PASV reply %q", message)
+	}
+	return high<<8 | low, nil
+}
@@ -0,0 +1,147 @@
+package scanner
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCheck(httpPathsCheck{paths: DefaultHTTPPaths})
+}
+
+// DefaultHTTPPaths are requested by the http check unless other paths are
+// configured with HTTPPathsCheck.
+var DefaultHTTPPaths = []string{"/robots.txt", "/.well-known/security.txt", "/server-status"}
+
+// httpBodyLimit caps how much of each response body is read.
+const httpBodyLimit = 64 << 10
+
+// httpPorts are ports where HTTP is expected even without a matching service name.
+var httpPorts = map[int]bool{80: true, 443: true, 8000: true, 8008: true, 8080: true, 8443: true, 8888: true}
+
+// httpPathsCheck requests a short list of well-known paths from web services
+// and records the status of each. Requesting paths that may not be meant for
+// the public (such as /server-status) is intrusive.
+type httpPathsCheck struct {
+	paths []string
+}
+
+// HTTPPathsCheck returns the http check configured to request paths instead
+// of DefaultHTTPPaths. Paths missing a leading slash get one.
+func HTTPPathsCheck(paths ...string) Check {
+	check := httpPathsCheck{}
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		check.paths = append(check.paths, path)
+	}
+	return check
+}
+
+// SetHTTPPaths replaces the http check among checks with one requesting
+// paths, as HTTPPathsCheck does. It reports false when checks holds no http
+// check, in which case paths would go unused.
+func SetHTTPPaths(checks []Check, paths ...string) bool {
+	found := false
+	for i, check := range checks {
+		if check.Name() == "http" {
+			checks[i] = HTTPPathsCheck(paths...)
+			found = true
+		}
+	}
+	return found
+}
+
+func (httpPathsCheck) Name() string { return "http" }
+
+func (httpPathsCheck) Description() string {
+	return "Request well-known paths (robots.txt, security.txt, server-status) from web services"
+}
+
+func (httpPathsCheck) Intrusive() bool { return true }
+
+func (httpPathsCheck) Applies(target CheckTarget) bool {
+	return target.Protocol == "tcp" && (httpPorts[target.Port] || serviceLooksLike(target.Service, "http"))
+}
+
+func (c httpPathsCheck) Run(ctx context.Context, target CheckTarget) ([]Finding, error) {
+	scheme := "http"
+	if tlsPorts[target.Port] || serviceLooksLike(target.Service, "https", "ssl", "tls") {
+		scheme = "https"
+	}
+
+	// Requests carry the submitted host name but always connect to the
+	// scanned address, and redirects are reported rather than followed
+	dialer := &net.Dialer{Timeout: target.Timeout}
+	client := &http.Client{
+		Timeout: target.Timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, target.dialAddress())
+			},
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true}, // certificates are not under test
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	base := scheme + "://" + net.JoinHostPort(target.Host, strconv.Itoa(target.Port))
+
+	details := make(map[string]string)
+	var found []string
+	var findings []Finding
+	for _, path := range c.paths {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, base+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("User-Agent", "cortex")
+		response, err := client.Do(request)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		body, _ := io.ReadAll(io.LimitReader(response.Body, httpBodyLimit))
+		response.Body.Close()
+
+		details[path] = strconv.Itoa(response.StatusCode)
+		if response.StatusCode != http.StatusOK {
+			continue
+		}
+		found = append(found, path)
+		if path == "/server-status" && strings.Contains(string(body), "Apache Server Status") {
+			findings = append(findings, Finding{
+				Type:     "http-server-status-exposed",
+				Severity: SeverityMedium,
+				Summary:  "Apache mod_status page is publicly readable",
+				Details:  map[string]string{"url": base + path},
+			})
+		}
+	}
+	if len(details) == 0 {
+		return nil, nil
+	}
+
+	summary := fmt.Sprintf("%d of %d paths answered 200", len(found), len(details))
+	if len(found) > 0 {
+		summary += ": " + strings.Join(found, ", ")
+	}
+	return append([]Finding{{
+		Type:     "http-paths",
+		Severity: SeverityInfo,
+		Summary:  summary,
+		Details:  details,
+	}}, findings...), nil
+}
@@ -0,0 +1,313 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+func init() {
+	registerCheck(smbCheck{})
+}
+
+// smbCheck negotiates SMB2 with the target and starts an anonymous NTLM
+// authentication to read the identity the server announces in its NTLM
+// challenge: NetBIOS and DNS names, domain, OS build and signing policy. No
+// credentials are sent and the session is abandoned after the challenge.
+type smbCheck struct{}
+
+func (smbCheck) Name() string { return "smb" }
+
+func (smbCheck) Description() string {
+	return "Negotiate SMB on 139/445 and report dialect, signing, computer name and domain"
+}
+
+func (smbCheck) Intrusive() bool { return false }
+
+func (smbCheck) Applies(target CheckTarget) bool {
+	if target.Protocol != "tcp" {
+		return false
+	}
+	return target.Port == 139 || target.Port == 445 || serviceLooksLike(target.Service, "microsoft-ds", "netbios-ssn", "smb")
+}
+
+func (smbCheck) Run(ctx context.Context, target CheckTarget) ([]Finding, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("tcp", target.dialAddress(), target.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(3 * target.Timeout))
+
+	// Port 139 carries SMB inside a NetBIOS session that must be opened first
+	if target.Port == 139 {
+		if err := netbiosSessionRequest(conn); err != nil {
+			return nil, err
+		}
+	}
+
+	info, err := smbProbe(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]string{
+		"dialect": info.dialect,
+		"signing": info.signing,
+	}
+	for key, value := range map[string]string{
+		"netbios_name":   info.netbiosName,
+		"netbios_domain": info.netbiosDomain,
+		"dns_name":       info.dnsName,
+		"dns_domain":     info.dnsDomain,
+		"os_version":     info.osVersion,
+	} {
+		if value != "" {
+			details[key] = value
+		}
+	}
+
+	name := info.netbiosName
+	if name == "" {
+		name = "unknown host"
+	}
+	summary := fmt.Sprintf("SMB %s on %s", info.dialect, name)
+	if info.netbiosDomain != "" {
+		summary += fmt.Sprintf(" (domain %s)", info.netbiosDomain)
+	}
+
+	findings := []Finding{{Type: "smb-info", Severity: SeverityInfo, Summary: summary, Details: details}}
+	if info.signing != "required" {
+		findings = append(findings, Finding{
+			Type:     "smb-signing-not-required",
+			Severity: SeverityMedium,
+			Summary:  "SMB message signing is not required, allowing NTLM relay attacks",
+			Details:  map[string]string{"signing": info.signing},
+		})
+	}
+	return findings, nil
+}
+
+// smbInfo is what the SMB negotiation and NTLM challenge reveal about a server.
+type smbInfo struct {
+	dialect       string
+	signing       string
+	netbiosName   string
+	netbiosDomain string
+	dnsName       string
+	dnsDomain     string
+	osVersion     string
+}
+
+// SMB2 protocol constants.
+const (
+	smb2HeaderSize          = 64
+	smb2CommandNegotiate    = 0x0000
+	smb2CommandSessionSetup = 0x0001
+	smb2SigningEnabled      = 0x0001
+	smb2SigningRequired     = 0x0002
+
+	statusMoreProcessingRequired = 0xc0000016
+)
+
+// smbDialects are offered in the negotiate request; 3.1.1 is left out because
+// it requires negotiate contexts that add nothing for identification.
+var smbDialects = []uint16{0x0202, 0x0210, 0x0300, 0x0302}
+
+// smbProbe runs SMB2 NEGOTIATE and the first leg of an anonymous NTLM
+// SESSION_SETUP on an established connection.
+func smbProbe(conn net.Conn) (*smbInfo, error) {
+	negotiate := make([]byte, 36, 36+2*len(smbDialects))
+	binary.LittleEndian.PutUint16(negotiate[0:], 36)
+	binary.LittleEndian.PutUint16(negotiate[2:], uint16(len(smbDialects)))
+	binary.LittleEndian.PutUint16(negotiate[4:], smb2SigningEnabled)
+	copy(negotiate[12:28], "cortex-scanner!!") // ClientGuid
+	for _, dialect := range smbDialects {
+		negotiate = binary.LittleEndian.AppendUint16(negotiate, dialect)
+	}
+
+	status, body, err := smb2Exchange(conn, smb2CommandNegotiate, 0, negotiate)
+	if err != nil {
+		return nil, err
+	}
+	if status != 0 || len(body) < 8 {
+		return nil, fmt.Errorf("SMB negotiate failed with status 0x%08x", status)
+	}
+
+	securityMode := binary.LittleEndian.Uint16(body[2:])
+	dialect := binary.LittleEndian.Uint16(body[4:])
+	info := &smbInfo{
+		dialect: fmt.Sprintf("%d.%d.%d", dialect>>8, (dialect>>4)&0xf, dialect&0xf),
+		signing: "disabled",
+	}
+	info.dialect = strings.TrimSuffix(info.dialect, ".0")
+	switch {
+	case securityMode&smb2SigningRequired != 0:
+		info.signing = "required"
+	case securityMode&smb2SigningEnabled != 0:
+		info.signing = "enabled"
+	}
+
+	// NTLMSSP NEGOTIATE: unicode, request target, NTLM, always sign, extended
+	// session security, target info, version, 128 and 56 bit keys
+	ntlmNegotiate := make([]byte, 40)
+	copy(ntlmNegotiate, "NTLMSSP\x00")
+	binary.LittleEndian.PutUint32(ntlmNegotiate[8:], 1)
+	binary.LittleEndian.PutUint32(ntlmNegotiate[12:], 0xa2888205)
+
+	setup := make([]byte, 24, 24+len(ntlmNegotiate))
+	binary.LittleEndian.PutUint16(setup[0:], 25)
+	setup[3] = smb2SigningEnabled
+	binary.LittleEndian.PutUint16(setup[12:], smb2HeaderSize+24)
+	binary.LittleEndian.PutUint16(setup[14:], uint16(len(ntlmNegotiate)))
+	setup = append(setup, ntlmNegotiate...)
+
+	status, body, err = smb2Exchange(conn, smb2CommandSessionSetup, 1, setup)
+	if err != nil || status != statusMoreProcessingRequired || len(body) < 8 {
+		// Dialect and signing are still useful without the NTLM identity
+		return info, nil
+	}
+	offset := int(binary.LittleEndian.Uint16(body[4:])) - smb2HeaderSize
+	length := int(binary.LittleEndian.Uint16(body[6:]))
+	if offset < 0 || offset+length > len(body) {
+		return info, nil
+	}
+	parseNTLMChallenge(body[offset:offset+length], info)
+	return info, nil
+}
+
+// smb2Exchange sends one SMB2 request framed for direct TCP transport and
+// returns the status and body of the response.
+func smb2Exchange(conn net.Conn, command uint16, messageID uint64, body []byte) (uint32, []byte, error) {
+	header := make([]byte, smb2HeaderSize)
+	copy(header, "\xfeSMB")
+	binary.LittleEndian.PutUint16(header[4:], smb2HeaderSize)
+	binary.LittleEndian.PutUint16(header[12:], command)
+	binary.LittleEndian.PutUint16(header[14:], 1) // CreditRequest
+	binary.LittleEndian.PutUint64(header[24:], messageID)
+
+	packet := append(header, body...)
+	frame := make([]byte, 4, 4+len(packet))
+	binary.BigEndian.PutUint32(frame, uint32(len(packet)))
+	if _, err := conn.Write(append(frame, packet...)); err != nil {
+		return 0, nil, err
+	}
+
+	var lengthBuf [4]byte
+	if _, err := io.ReadFull(conn, lengthBuf[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(lengthBuf[:]) & 0x00ffffff
+	if length < smb2HeaderSize || length > 1<<16 {
+		return 0, nil, fmt.Errorf("unexpected SMB response length %d", length)
+	}
+	response := make([]byte, length)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return 0, nil, err
+	}
+	if !bytes.HasPrefix(response, []byte("\xfeSMB")) {
+		return 0, nil, errors.New("response is not SMB2")
+	}
+	return binary.LittleEndian.Uint32(response[8:]), response[smb2HeaderSize:], nil
+}
+
+// NTLM AV_PAIR identifiers carried in the challenge target info.
+const (
+	avEOL             = 0
+	avNbComputerName  = 1
+	avNbDomainName    = 2
+	avDnsComputerName = 3
+	avDnsDomainName   = 4
+)
+
+// parseNTLMChallenge extracts names and OS version from an NTLM CHALLENGE
+// message, which may be wrapped in SPNEGO.
+func parseNTLMChallenge(blob []byte, info *smbInfo) {
+	start := bytes.Index(blob, []byte("NTLMSSP\x00"))
+	if start < 0 {
+		return
+	}
+	msg := blob[start:]
+	if len(msg) < 48 || binary.LittleEndian.Uint32(msg[8:]) != 2 {
+		return
+	}
+
+	if len(msg) >= 56 && binary.LittleEndian.Uint32(msg[20:])&0x02000000 != 0 {
+		info.osVersion = fmt.Sprintf("%d.%d.%d", msg[48], msg[49], binary.LittleEndian.Uint16(msg[50:]))
+	}
+
+	length := int(binary.LittleEndian.Uint16(msg[40:]))
+	offset := int(binary.LittleEndian.Uint32(msg[44:]))
+	if offset+length > len(msg) {
+		return
+	}
+	pairs := msg[offset : offset+length]
+	for len(pairs) >= 4 {
+		id := binary.LittleEndian.Uint16(pairs[0:])
+		size := int(binary.LittleEndian.Uint16(pairs[2:]))
+		if id == avEOL || 4+size > len(pairs) {
+			return
+		}
+		value := decodeUTF16LE(pairs[4 : 4+size])
+		switch id {
+		case avNbComputerName:
+			info.netbiosName = value
+		case avNbDomainName:
+			info.netbiosDomain = value
+		case avDnsComputerName:
+			info.dnsName = value
+		case avDnsDomainName:
+			info.dnsDomain = value
+		}
+		pairs = pairs[4+size:]
+	}
+}
+
+func decodeUTF16LE(data []byte) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// netbiosSessionRequest opens a NetBIOS session (RFC 1002) using the generic
+// *SMBSERVER name, which Windows and Samba accept on port 139.
+func netbiosSessionRequest(conn net.Conn) error {
+	payload := append(netbiosEncodeName("*SMBSERVER", 0x20), netbiosEncodeName("CORTEX", 0x00)...)
+	packet := []byte{0x81, 0, byte(len(payload) >> 8), byte(len(payload))}
+	if _, err := conn.Write(append(packet, payload...)); err != nil {
+		return err
+	}
+
+	var reply [4]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return err
+	}
+	if reply[0] != 0x82 {
+		return fmt.Errorf("NetBIOS session request rejected (type 0x%02x)", reply[0])
+	}
+	return nil
+}
+
+// netbiosEncodeName applies RFC 1001 first-level encoding to a 15 character
+// name padded with spaces plus a one byte suffix.
+func netbiosEncodeName(name string, suffix byte) []byte {
+	raw := []byte(fmt.Sprintf("%-15s", strings.ToUpper(name)))[:15]
+	raw = append(raw, suffix)
+	encoded := []byte{32}
+	for _, b := range raw {
+		encoded = append(encoded, 'A'+b>>4, 'A'+b&0x0f)
+	}
+	return append(encoded, 0)
+}
@@ -0,0 +1,273 @@
+package scanner
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+func init() {
+	registerCheck(snmpCheck{})
+}
+
+// snmpCommunities are the community strings tried, in order.
+var snmpCommunities = []string{"public", "private", "community", "manager"}
+
+// OIDs of sysDescr.0 and sysName.0 from SNMPv2-MIB.
+var (
+	oidSysDescr = []int{1, 3, 6, 1, 2, 1, 1, 1, 0}
+	oidSysName  = []int{1, 3, 6, 1, 2, 1, 1, 5, 0}
+)
+
+// SNMP protocol versions as encoded on the wire.
+const (
+	snmpV1  = 0
+	snmpV2c = 1
+)
+
+// snmpCheck attempts SNMPv2c and SNMPv1 GET requests with common community
+// strings and reports readable agents. Guessing communities is a credential
+// attempt, so the check is intrusive.
+type snmpCheck struct{}
+
+func (snmpCheck) Name() string { return "snmp" }
+
+func (snmpCheck) Description() string {
+	return "Try common SNMP v1/v2c communities on UDP/161 and read sysDescr/sysName"
+}
+
+func (snmpCheck) Intrusive() bool { return true }
+
+func (snmpCheck) Applies(target CheckTarget) bool {
+	return target.Protocol == "udp" && target.Port == 161
+}
+
+func (snmpCheck) Run(ctx context.Context, target CheckTarget) ([]Finding, error) {
+	for _, version := range []int{snmpV2c, snmpV1} {
+		for _, community := range snmpCommunities {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			values, err := snmpGet(target.dialAddress(), version, community, target.Timeout, oidSysDescr, oidSysName)
+			if err != nil {
+				continue
+			}
+
+			versionName := "v2c"
+			if version == snmpV1 {
+				versionName = "v1"
+			}
+			details := map[string]string{"version": versionName, "community": community}
+			if descr := values[0]; descr != "" {
+				details["sys_descr"] = descr
+			}
+			if name := values[1]; name != "" {
+				details["sys_name"] = name
+			}
+			severity := SeverityMedium
+			if community != "public" {
+				severity = SeverityHigh
+			}
+			return []Finding{{
+				Type:     "snmp-exposed",
+				Severity: severity,
+				Summary:  fmt.Sprintf("SNMP %s readable with community %q", versionName, community),
+				Details:  details,
+			}}, nil
+		}
+	}
+	return nil, nil
+}
+
+// snmpGet sends a single GetRequest and returns the string values of oids in order.
+func snmpGet(address string, version int, community string, timeout time.Duration, oids ...[]int) ([]string, error) {
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var idBytes [4]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, err
+	}
+	requestID := int(binary.BigEndian.Uint32(idBytes[:]) & 0x7fffffff)
+
+	if _, err := conn.Write(encodeSNMPGet(version, community, requestID, oids)); err != nil {
+		return nil, err
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	buffer := make([]byte, 4096)
+	n, err := conn.Read(buffer)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSNMPResponse(buffer[:n], requestID, len(oids))
+}
+
+// BER tags used by SNMP.
+const (
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berNull        = 0x05
+	berOID         = 0x06
+	berSequence    = 0x30
+	snmpGetRequest = 0xa0
+	snmpGetReply   = 0xa2
+)
+
+func encodeSNMPGet(version int, community string, requestID int, oids [][]int) []byte {
+	var varbinds []byte
+	for _, oid := range oids {
+		varbinds = append(varbinds, berTLV(berSequence, append(berTLV(berOID, encodeOID(oid)), berTLV(berNull, nil)...))...)
+	}
+
+	pdu := berTLV(berInteger, encodeBERInt(requestID))
+	pdu = append(pdu, berTLV(berInteger, encodeBERInt(0))...) // error-status
+	pdu = append(pdu, berTLV(berInteger, encodeBERInt(0))...) // error-index
+	pdu = append(pdu, berTLV(berSequence, varbinds)...)
+
+	message := berTLV(berInteger, encodeBERInt(version))
+	message = append(message, berTLV(berOctetString, []byte(community))...)
+	message = append(message, berTLV(snmpGetRequest, pdu)...)
+	return berTLV(berSequence, message)
+}
+
+func decodeSNMPResponse(data []byte, requestID, expected int) ([]string, error) {
+	_, message, _, err := readTLV(data)
+	if err != nil {
+		return nil, err
+	}
+	// version, community, PDU
+	if _, _, message, err = readTLV(message); err != nil {
+		return nil, err
+	}
+	if _, _, message, err = readTLV(message); err != nil {
+		return nil, err
+	}
+	tag, pdu, _, err := readTLV(message)
+	if err != nil {
+		return nil, err
+	}
+	if tag != snmpGetReply {
+		return nil, fmt.Errorf("unexpected SNMP PDU type 0x%x", tag)
+	}
+
+	_, id, pdu, err := readTLV(pdu)
+	if err != nil {
+		return nil, err
+	}
+	if decodeBERInt(id) != requestID {
+		return nil, errors.New("SNMP response does not match request id")
+	}
+	_, status, pdu, err := readTLV(pdu)
+	if err != nil {
+		return nil, err
+	}
+	if decodeBERInt(status) != 0 {
+		return nil, fmt.Errorf("SNMP error status %d", decodeBERInt(status))
+	}
+	if _, _, pdu, err = readTLV(pdu); err != nil { // error-index
+		return nil, err
+	}
+	_, varbinds, _, err := readTLV(pdu)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]string, expected)
+	for i := 0; i < expected && len(varbinds) > 0; i++ {
+		var varbind []byte
+		if _, varbind, varbinds, err = readTLV(varbinds); err != nil {
+			return nil, err
+		}
+		if _, _, varbind, err = readTLV(varbind); err != nil { // OID
+			return nil, err
+		}
+		tag, value, _, err := readTLV(varbind)
+		if err != nil {
+			return nil, err
+		}
+		if tag == berOctetString {
+			values[i] = string(value)
+		}
+	}
+	return values, nil
+}
+
+// berTLV encodes a tag-length-value triple with a definite length.
+func berTLV(tag byte, value []byte) []byte {
+	out := []byte{tag}
+	length := len(value)
+	switch {
+	case length < 0x80:
+		out = append(out, byte(length))
+	case length < 0x100:
+		out = append(out, 0x81, byte(length))
+	default:
+		out = append(out, 0x82, byte(length>>8), byte(length))
+	}
+	return append(out, value...)
+}
+
+// readTLV splits the first BER element off data, returning its tag, value and the remainder.
+func readTLV(data []byte) (byte, []byte, []byte, error) {
+	if len(data) < 2 {
+		return 0, nil, nil, errors.New("truncated BER element")
+	}
+	tag := data[0]
+	length := int(data[1])
+	offset := 2
+	if length&0x80 != 0 {
+		octets := length & 0x7f
+		if octets == 0 || octets > 3 || len(data) < offset+octets {
+			return 0, nil, nil, errors.New("unsupported BER length")
+		}
+		length = 0
+		for _, b := range data[offset : offset+octets] {
+			length = length<<8 | int(b)
+		}
+		offset += octets
+	}
+	if len(data) < offset+length {
+		return 0, nil, nil, errors.New("truncated BER element")
+	}
+	return tag, data[offset : offset+length], data[offset+length:], nil
+}
+
+func encodeBERInt(v int) []byte {
+	out := []byte{byte(v)}
+	for v >>= 8; v > 0; v >>= 8 {
+		out = append([]byte{byte(v)}, out...)
+	}
+	if out[0]&0x80 != 0 {
+		out = append([]byte{0}, out...)
+	}
+	return out
+}
+
+func decodeBERInt(data []byte) int {
+	v := 0
+	for _, b := range data {
+		v = v<<8 | int(b)
+	}
+	return v
+}
+
+func encodeOID(oid []int) []byte {
+	out := []byte{byte(oid[0]*40 + oid[1])}
+	for _, arc := range oid[2:] {
+		var chunk []byte
+		chunk = append(chunk, byte(arc&0x7f))
+		for arc >>= 7; arc > 0; arc >>= 7 {
+			chunk = append([]byte{byte(arc&0x7f | 0x80)}, chunk...)
+		}
+		out = append(out, chunk...)
+	}
+	return out
+}
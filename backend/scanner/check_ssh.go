@@ -0,0 +1,305 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func init() {
+	registerCheck(sshCheck{})
+}
+
+// sshCheck performs the SSH version exchange, reads the algorithms the server
+// offers in its KEXINIT and completes one key exchange per host key type to
+// collect fingerprints. Connections are dropped before authentication.
+type sshCheck struct{}
+
+func (sshCheck) Name() string { return "ssh" }
+
+func (sshCheck) Description() string {
+	return "Collect SSH version, host key fingerprints and offered kex/cipher/MAC algorithms"
+}
+
+func (sshCheck) Intrusive() bool { return false }
+
+func (sshCheck) Applies(target CheckTarget) bool {
+	return target.Protocol == "tcp" && (target.Port == 22 || serviceLooksLike(target.Service, "ssh"))
+}
+
+func (sshCheck) Run(ctx context.Context, target CheckTarget) ([]Finding, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	version, algorithms, err := sshKexInit(target.dialAddress(), target.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]string{
+		"version":             version,
+		"kex":                 strings.Join(algorithms.kex, ","),
+		"host_key_algorithms": strings.Join(algorithms.hostKey, ","),
+		"ciphers":             strings.Join(algorithms.ciphers, ","),
+		"macs":                strings.Join(algorithms.macs, ","),
+		"compression":         strings.Join(algorithms.compression, ","),
+	}
+	for _, algorithm := range sshFingerprintAlgorithms(algorithms.hostKey) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		key, err := sshHostKey(target.dialAddress(), algorithm, target.Timeout)
+		if err != nil {
+			continue
+		}
+		details["fingerprint_"+sshKeyLabel(key.Type())] = ssh.FingerprintSHA256(key)
+	}
+
+	findings := []Finding{{
+		Type:     "ssh-info",
+		Severity: SeverityInfo,
+		Summary:  version,
+		Details:  details,
+	}}
+	if strings.HasPrefix(version, "SSH-1.") && !strings.HasPrefix(version, "SSH-1.99-") {
+		findings = append(findings, Finding{
+			Type:     "ssh-protocol-v1",
+			Severity: SeverityHigh,
+			Summary:  "SSH server only speaks protocol version 1",
+			Details:  map[string]string{"version": version},
+		})
+	}
+	if weak := algorithms.weak(); len(weak) > 0 {
+		findings = append(findings, Finding{
+			Type:     "ssh-weak-algorithms",
+			Severity: SeverityLow,
+			Summary:  fmt.Sprintf("SSH server offers %d weak algorithms", len(weak)),
+			Details:  map[string]string{"algorithms": strings.Join(weak, ",")},
+		})
+	}
+	return findings, nil
+}
+
+// sshAlgorithms are the name-lists of a server KEXINIT. Client-to-server and
+// server-to-client lists are merged since servers almost always mirror them.
+type sshAlgorithms struct {
+	kex         []string
+	hostKey     []string
+	ciphers     []string
+	macs        []string
+	compression []string
+}
+
+// sshWeakAlgorithms are algorithms considered broken or deprecated.
+var sshWeakAlgorithms = map[string]bool{
+	"diffie-hellman-group1-sha1":         true,
+	"diffie-hellman-group14-sha1":        true,
+	"diffie-hellman-group-exchange-sha1": true,
+	"ssh-dss":                            true,
+	"3des-cbc":                           true,
+	"aes128-cbc":                         true,
+	"aes192-cbc":                         true,
+	"aes256-cbc":                         true,
+	"blowfish-cbc":                       true,
+	"cast128-cbc":                        true,
+	"arcfour":                            true,
+	"arcfour128":                         true,
+	"arcfour256":                         true,
+	"none":                               true,
+	"hmac-md5":                           true,
+	"hmac-md5-96":                        true,
+	"hmac-sha1-96":                       true,
+}
+
+// weak returns the offered algorithms listed in sshWeakAlgorithms.
+func (a sshAlgorithms) weak() []string {
+	var weak []string
+	for _, list := range [][]string{a.kex, a.hostKey, a.ciphers, a.macs} {
+		for _, name := range list {
+			if sshWeakAlgorithms[name] {
+				weak = append(weak, name)
+			}
+		}
+	}
+	return weak
+}
+
+// SSH transport constants.
+const (
+	sshMsgKexInit     = 20
+	sshMaxPacketSize  = 35000
+	sshMaxVersionLine = 255
+	sshClientVersion  = "SSH-2.0-cortex"
+)
+
+// sshKexInit exchanges version strings with the server and parses the
+// algorithms of its first, unencrypted KEXINIT packet.
+func sshKexInit(address string, timeout time.Duration) (string, *sshAlgorithms, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return "", nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * timeout))
+
+	if _, err := conn.Write([]byte(sshClientVersion + "\r\n")); err != nil {
+		return "", nil, err
+	}
+
+	// Servers may send other lines before the identification string (RFC 4253 4.2)
+	reader := bufio.NewReader(conn)
+	var version string
+	for lines := 0; version == ""; lines++ {
+		if lines > 32 {
+			return "", nil, errors.New("no SSH identification string received")
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", nil, err
+		}
+		if len(line) > sshMaxVersionLine {
+			return "", nil, errors.New("SSH identification line too long")
+		}
+		if strings.HasPrefix(line, "SSH-") {
+			version = strings.TrimRight(line, "\r\n")
+		}
+	}
+	if strings.HasPrefix(version, "SSH-1.") && !strings.HasPrefix(version, "SSH-1.99-") {
+		return version, &sshAlgorithms{}, nil
+	}
+
+	var header [5]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return "", nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	padding := uint32(header[4])
+	if length < 1+padding || length > sshMaxPacketSize {
+		return "", nil, fmt.Errorf("unexpected SSH packet length %d", length)
+	}
+	packet := make([]byte, length-1)
+	if _, err := io.ReadFull(reader, packet); err != nil {
+		return "", nil, err
+	}
+	payload := packet[:len(packet)-int(padding)]
+	if len(payload) < 17 || payload[0] != sshMsgKexInit {
+		return "", nil, errors.New("first SSH packet is not KEXINIT")
+	}
+
+	// Ten name-lists follow the message type and 16 byte cookie
+	rest := payload[17:]
+	lists := make([][]string, 10)
+	for i := range lists {
+		if len(rest) < 4 {
+			return "", nil, errors.New("truncated SSH KEXINIT")
+		}
+		size := binary.BigEndian.Uint32(rest)
+		if uint32(len(rest)-4) < size {
+			return "", nil, errors.New("truncated SSH KEXINIT")
+		}
+		if size > 0 {
+			lists[i] = strings.Split(string(rest[4:4+size]), ",")
+		}
+		rest = rest[4+size:]
+	}
+	return version, &sshAlgorithms{
+		kex:         lists[0],
+		hostKey:     lists[1],
+		ciphers:     mergeNames(lists[2], lists[3]),
+		macs:        mergeNames(lists[4], lists[5]),
+		compression: mergeNames(lists[6], lists[7]),
+	}, nil
+}
+
+// mergeNames returns the names of a followed by those of b not already in a.
+func mergeNames(a, b []string) []string {
+	seen := make(map[string]bool, len(a))
+	merged := append([]string(nil), a...)
+	for _, name := range a {
+		seen[name] = true
+	}
+	for _, name := range b {
+		if !seen[name] {
+			seen[name] = true
+			merged = append(merged, name)
+		}
+	}
+	return merged
+}
+
+// sshFingerprintAlgorithms picks one offered host key algorithm per key type,
+// so each distinct host key is fetched once.
+func sshFingerprintAlgorithms(offered []string) []string {
+	var algorithms []string
+	seen := make(map[string]bool)
+	for _, name := range offered {
+		var label string
+		switch name {
+		case ssh.KeyAlgoED25519, ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521, ssh.KeyAlgoDSA:
+			label = name
+		case ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA:
+			label = ssh.KeyAlgoRSA
+		default:
+			continue
+		}
+		if !seen[label] {
+			seen[label] = true
+			algorithms = append(algorithms, name)
+		}
+	}
+	return algorithms
+}
+
+// sshKeyLabel turns a key type into a short details key suffix.
+func sshKeyLabel(keyType string) string {
+	switch keyType {
+	case ssh.KeyAlgoED25519:
+		return "ed25519"
+	case ssh.KeyAlgoRSA:
+		return "rsa"
+	case ssh.KeyAlgoDSA:
+		return "dsa"
+	}
+	return strings.TrimPrefix(keyType, "ecdsa-sha2-")
+}
+
+// errSSHHostKeyCaptured aborts the handshake once the host key is known.
+var errSSHHostKeyCaptured = errors.New("host key captured")
+
+// sshHostKey completes a key exchange limited to algorithm and returns the
+// server host key without attempting authentication.
+func sshHostKey(address, algorithm string, timeout time.Duration) (ssh.PublicKey, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * timeout))
+
+	var hostKey ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User:              "cortex",
+		ClientVersion:     sshClientVersion,
+		HostKeyAlgorithms: []string{algorithm},
+		HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return errSSHHostKeyCaptured
+		},
+	}
+	_, _, _, err = ssh.NewClientConn(conn, address, config)
+	if hostKey == nil {
+		if err == nil {
+			err = errors.New("server did not present a host key")
+		}
+		return nil, err
+	}
+	return hostKey, nil
+}
@@ -0,0 +1,388 @@
+package scanner
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCheck(tlsCheck{})
+}
+
+// tlsHandshakeLimit bounds the handshakes one target may cost across all
+// protocol versions.
+const tlsHandshakeLimit = 64
+
+// tlsPorts are ports where TLS is expected even without a matching service name.
+var tlsPorts = map[int]bool{443: true, 465: true, 636: true, 853: true, 989: true, 990: true, 992: true, 993: true, 995: true, 5061: true, 5986: true, 8443: true}
+
+// tlsCheck enumerates the protocol versions and cipher suites a TLS server
+// accepts. SSLv3 to TLS 1.2 are probed with hand-built ClientHello messages so
+// that suites Go no longer implements (NULL, EXPORT, RC4, DES) can be offered;
+// TLS 1.3 is probed with crypto/tls.
+type tlsCheck struct{}
+
+func (tlsCheck) Name() string { return "tls" }
+
+func (tlsCheck) Description() string {
+	return "Enumerate TLS protocol versions and cipher suites and flag weak configurations"
+}
+
+func (tlsCheck) Intrusive() bool { return false }
+
+func (tlsCheck) Applies(target CheckTarget) bool {
+	return target.Protocol == "tcp" && (tlsPorts[target.Port] || serviceLooksLike(target.Service, "ssl", "tls", "https"))
+}
+
+func (tlsCheck) Run(ctx context.Context, target CheckTarget) ([]Finding, error) {
+	serverName := ""
+	if net.ParseIP(target.Host) == nil {
+		serverName = target.Host
+	}
+
+	details := make(map[string]string)
+	var protocols []string
+	var weakProtocols []string
+	weakCiphers := make(map[string]string)
+	budget := tlsHandshakeLimit
+
+	for _, version := range tlsLegacyVersions {
+		var accepted []string
+		remaining := make([]uint16, 0, len(tlsCipherSuites))
+		for id := range tlsCipherSuites {
+			remaining = append(remaining, id)
+		}
+		sort.Slice(remaining, func(i, j int) bool { return remaining[i] < remaining[j] })
+
+		// Each accepted suite is removed from the offer until the server refuses
+		for budget > 0 && len(remaining) > 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			budget--
+			chosen, err := tlsServerHello(target, serverName, version.id, remaining)
+			if err != nil {
+				break
+			}
+			index := -1
+			for i, id := range remaining {
+				if id == chosen {
+					index = i
+				}
+			}
+			if index < 0 {
+				break
+			}
+			remaining = append(remaining[:index], remaining[index+1:]...)
+			name := tlsCipherSuites[chosen]
+			accepted = append(accepted, name)
+			if weakness := tlsCipherWeakness(name); weakness != "" {
+				weakCiphers[name] = weakness
+			}
+		}
+
+		if len(accepted) > 0 {
+			protocols = append(protocols, version.name)
+			details["ciphers_"+version.key] = strings.Join(accepted, ",")
+			if version.id < tls.VersionTLS12 {
+				weakProtocols = append(weakProtocols, version.name)
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if suite, ok := tls13Handshake(target, serverName); ok {
+		protocols = append(protocols, "TLSv1.3")
+		details["ciphers_tls13"] = suite
+	}
+
+	if len(protocols) == 0 {
+		return nil, nil
+	}
+	details["protocols"] = strings.Join(protocols, ",")
+	findings := []Finding{{
+		Type:     "tls-info",
+		Severity: SeverityInfo,
+		Summary:  "TLS supports " + strings.Join(protocols, ", "),
+		Details:  details,
+	}}
+
+	if len(weakProtocols) > 0 {
+		severity := SeverityMedium
+		if weakProtocols[0] == "SSLv3" {
+			severity = SeverityHigh
+		}
+		findings = append(findings, Finding{
+			Type:     "tls-weak-protocol",
+			Severity: severity,
+			Summary:  "Deprecated protocol versions accepted: " + strings.Join(weakProtocols, ", "),
+			Details:  map[string]string{"protocols": strings.Join(weakProtocols, ",")},
+		})
+	}
+	if len(weakCiphers) > 0 {
+		names := make([]string, 0, len(weakCiphers))
+		severity := SeverityMedium
+		for name, weakness := range weakCiphers {
+			names = append(names, name)
+			if weakness == "null" || weakness == "export" || weakness == "anonymous" {
+				severity = SeverityHigh
+			}
+		}
+		sort.Strings(names)
+		findings = append(findings, Finding{
+			Type:     "tls-weak-cipher",
+			Severity: severity,
+			Summary:  fmt.Sprintf("%d weak cipher suites accepted", len(names)),
+			Details:  map[string]string{"ciphers": strings.Join(names, ",")},
+		})
+	}
+	return findings, nil
+}
+
+// tlsLegacyVersions are probed with raw ClientHello messages, oldest first.
+var tlsLegacyVersions = []struct {
+	id   uint16
+	name string
+	key  string
+}{
+	{0x0300, "SSLv3", "ssl3"}, // crypto/tls deprecates the SSLv3 constant
+	{tls.VersionTLS10, "TLSv1.0", "tls10"},
+	{tls.VersionTLS11, "TLSv1.1", "tls11"},
+	{tls.VersionTLS12, "TLSv1.2", "tls12"},
+}
+
+// tlsCipherSuites maps the SSLv3 to TLS 1.2 suites offered to their IANA names.
+var tlsCipherSuites = map[uint16]string{
+	0x0001: "TLS_RSA_WITH_NULL_MD5",
+	0x0002: "TLS_RSA_WITH_NULL_SHA",
+	0x0003: "TLS_RSA_EXPORT_WITH_RC4_40_MD5",
+	0x0004: "TLS_RSA_WITH_RC4_128_MD5",
+	0x0005: "TLS_RSA_WITH_RC4_128_SHA",
+	0x0006: "TLS_RSA_EXPORT_WITH_RC2_CBC_40_MD5",
+	0x0007: "TLS_RSA_WITH_IDEA_CBC_SHA",
+	0x0008: "TLS_RSA_EXPORT_WITH_DES40_CBC_SHA",
+	0x0009: "TLS_RSA_WITH_DES_CBC_SHA",
+	0x000A: "TLS_RSA_WITH_3DES_EDE_CBC_SHA",
+	0x0011: "TLS_DHE_DSS_EXPORT_WITH_DES40_CBC_SHA",
+	0x0012: "TLS_DHE_DSS_WITH_DES_CBC_SHA",
+	0x0013: "TLS_DHE_DSS_WITH_3DES_EDE_CBC_SHA",
+	0x0014: "TLS_DHE_RSA_EXPORT_WITH_DES40_CBC_SHA",
+	0x0015: "TLS_DHE_RSA_WITH_DES_CBC_SHA",
+	0x0016: "TLS_DHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	0x0017: "TLS_DH_anon_EXPORT_WITH_RC4_40_MD5",
+	0x0018: "TLS_DH_anon_WITH_RC4_128_MD5",
+	0x0019: "TLS_DH_anon_EXPORT_WITH_DES40_CBC_SHA",
+	0x002F: "TLS_RSA_WITH_AES_128_CBC_SHA",
+	0x0032: "TLS_DHE_DSS_WITH_AES_128_CBC_SHA",
+	0x0033: "TLS_DHE_RSA_WITH_AES_128_CBC_SHA",
+	0x0034: "TLS_DH_anon_WITH_AES_128_CBC_SHA",
+	0x0035: "TLS_RSA_WITH_AES_256_CBC_SHA",
+	0x0038: "TLS_DHE_DSS_WITH_AES_256_CBC_SHA",
+	0x0039: "TLS_DHE_RSA_WITH_AES_256_CBC_SHA",
+	0x003A: "TLS_DH_anon_WITH_AES_256_CBC_SHA",
+	0x003B: "TLS_RSA_WITH_NULL_SHA256",
+	0x003C: "TLS_RSA_WITH_AES_128_CBC_SHA256",
+	0x003D: "TLS_RSA_WITH_AES_256_CBC_SHA256",
+	0x0041: "TLS_RSA_WITH_CAMELLIA_128_CBC_SHA",
+	0x0062: "TLS_RSA_EXPORT1024_WITH_DES_CBC_SHA",
+	0x0064: "TLS_RSA_EXPORT1024_WITH_RC4_56_SHA",
+	0x0067: "TLS_DHE_RSA_WITH_AES_128_CBC_SHA256",
+	0x006B: "TLS_DHE_RSA_WITH_AES_256_CBC_SHA256",
+	0x0084: "TLS_RSA_WITH_CAMELLIA_256_CBC_SHA",
+	0x0096: "TLS_RSA_WITH_SEED_CBC_SHA",
+	0x009C: "TLS_RSA_WITH_AES_128_GCM_SHA256",
+	0x009D: "TLS_RSA_WITH_AES_256_GCM_SHA384",
+	0x009E: "TLS_DHE_RSA_WITH_AES_128_GCM_SHA256",
+	0x009F: "TLS_DHE_RSA_WITH_AES_256_GCM_SHA384",
+	0xC006: "TLS_ECDHE_ECDSA_WITH_NULL_SHA",
+	0xC007: "TLS_ECDHE_ECDSA_WITH_RC4_128_SHA",
+	0xC008: "TLS_ECDHE_ECDSA_WITH_3DES_EDE_CBC_SHA",
+	0xC009: "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA",
+	0xC00A: "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA",
+	0xC010: "TLS_ECDHE_RSA_WITH_NULL_SHA",
+	0xC011: "TLS_ECDHE_RSA_WITH_RC4_128_SHA",
+	0xC012: "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	0xC013: "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+	0xC014: "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+	0xC018: "TLS_ECDH_anon_WITH_AES_128_CBC_SHA",
+	0xC019: "TLS_ECDH_anon_WITH_AES_256_CBC_SHA",
+	0xC023: "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256",
+	0xC024: "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384",
+	0xC027: "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+	0xC028: "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384",
+	0xC02B: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	0xC02C: "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	0xC02F: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	0xC030: "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	0xCCA8: "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+	0xCCA9: "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+	0xCCAA: "TLS_DHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+}
+
+// tlsCipherWeakness classifies a suite name as null, export, anonymous or
+// weak (RC2, RC4, DES, 3DES), or returns "" for acceptable suites.
+func tlsCipherWeakness(name string) string {
+	switch {
+	case strings.Contains(name, "_NULL_"):
+		return "null"
+	case strings.Contains(name, "EXPORT"):
+		return "export"
+	case strings.Contains(name, "_anon_"):
+		return "anonymous"
+	case strings.Contains(name, "RC4"), strings.Contains(name, "RC2"), strings.Contains(name, "DES"):
+		return "weak"
+	}
+	return ""
+}
+
+// TLS record and handshake constants.
+const (
+	tlsRecordHandshake   = 0x16
+	tlsRecordAlert       = 0x15
+	tlsHandshakeClient   = 0x01
+	tlsHandshakeServer   = 0x02
+	tlsRenegotiationSCSV = 0x00ff
+)
+
+// tlsServerHello sends a ClientHello for version offering suites and returns
+// the suite the server selected. A refusal or a downgrade to another version
+// is reported as an error.
+func tlsServerHello(target CheckTarget, serverName string, version uint16, suites []uint16) (uint16, error) {
+	conn, err := net.DialTimeout("tcp", target.dialAddress(), target.Timeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(target.Timeout))
+
+	if _, err := conn.Write(buildClientHello(serverName, version, suites)); err != nil {
+		return 0, err
+	}
+
+	// The ServerHello may be split across records; collect enough handshake bytes
+	var handshake []byte
+	for len(handshake) < 4+2+32+1+32+2 {
+		var header [5]byte
+		if _, err := io.ReadFull(conn, header[:]); err != nil {
+			if len(handshake) > 0 {
+				break
+			}
+			return 0, err
+		}
+		length := int(binary.BigEndian.Uint16(header[3:]))
+		if length > 1<<14+2048 {
+			return 0, fmt.Errorf("unexpected TLS record length %d", length)
+		}
+		if header[0] == tlsRecordAlert {
+			return 0, errors.New("handshake refused with alert")
+		}
+		if header[0] != tlsRecordHandshake {
+			return 0, fmt.Errorf("unexpected TLS record type 0x%02x", header[0])
+		}
+		record := make([]byte, length)
+		if _, err := io.ReadFull(conn, record); err != nil {
+			return 0, err
+		}
+		handshake = append(handshake, record...)
+	}
+
+	if len(handshake) < 4+2+32+1 || handshake[0] != tlsHandshakeServer {
+		return 0, errors.New("first handshake message is not ServerHello")
+	}
+	body := handshake[4:]
+	if got := binary.BigEndian.Uint16(body); got != version {
+		return 0, fmt.Errorf("server negotiated version 0x%04x instead", got)
+	}
+	sessionIDLength := int(body[34])
+	if len(body) < 35+sessionIDLength+2 {
+		return 0, errors.New("truncated ServerHello")
+	}
+	return binary.BigEndian.Uint16(body[35+sessionIDLength:]), nil
+}
+
+// buildClientHello encodes a ClientHello record. TLS versions carry SNI and
+// the elliptic curve and signature algorithm extensions servers need before
+// they will pick ECDHE suites; SSLv3 hellos carry no extensions.
+func buildClientHello(serverName string, version uint16, suites []uint16) []byte {
+	hello := binary.BigEndian.AppendUint16(nil, version)
+	random := make([]byte, 32)
+	binary.BigEndian.PutUint32(random, uint32(time.Now().Unix()))
+	hello = append(hello, random...)
+	hello = append(hello, 0) // empty session id
+
+	hello = binary.BigEndian.AppendUint16(hello, uint16(2*len(suites)+2))
+	for _, suite := range suites {
+		hello = binary.BigEndian.AppendUint16(hello, suite)
+	}
+	hello = binary.BigEndian.AppendUint16(hello, tlsRenegotiationSCSV)
+	hello = append(hello, 1, 0) // null compression only
+
+	if version >= tls.VersionTLS10 {
+		var extensions []byte
+		if serverName != "" {
+			// server_name: list length, host_name type, name length, name
+			sni := binary.BigEndian.AppendUint16(nil, uint16(len(serverName)+3))
+			sni = append(sni, 0)
+			sni = binary.BigEndian.AppendUint16(sni, uint16(len(serverName)))
+			extensions = tlsAppendExtension(extensions, 0x0000, append(sni, serverName...))
+		}
+		// supported_groups: x25519, secp256r1, secp384r1, secp521r1
+		extensions = tlsAppendExtension(extensions, 0x000a, []byte{0, 8, 0, 0x1d, 0, 0x17, 0, 0x18, 0, 0x19})
+		// ec_point_formats: uncompressed
+		extensions = tlsAppendExtension(extensions, 0x000b, []byte{1, 0})
+		if version >= tls.VersionTLS12 {
+			// signature_algorithms: ECDSA, RSA-PSS, RSA-PKCS1 and their SHA-1 variants
+			extensions = tlsAppendExtension(extensions, 0x000d, []byte{
+				0, 20, 4, 3, 5, 3, 6, 3, 8, 4, 8, 5, 8, 6, 4, 1, 5, 1, 6, 1, 2, 1,
+			})
+		}
+		hello = binary.BigEndian.AppendUint16(hello, uint16(len(extensions)))
+		hello = append(hello, extensions...)
+	}
+
+	handshake := []byte{tlsHandshakeClient, byte(len(hello) >> 16), byte(len(hello) >> 8), byte(len(hello))}
+	handshake = append(handshake, hello...)
+
+	// Servers are most tolerant of a TLS 1.0 record version on the first flight
+	recordVersion := uint16(tls.VersionTLS10)
+	if version < tls.VersionTLS10 {
+		recordVersion = version
+	}
+	record := []byte{tlsRecordHandshake, byte(recordVersion >> 8), byte(recordVersion), byte(len(handshake) >> 8), byte(len(handshake))}
+	return append(record, handshake...)
+}
+
+// tlsAppendExtension appends one extension with its type and length prefix.
+func tlsAppendExtension(extensions []byte, extensionType uint16, data []byte) []byte {
+	extensions = binary.BigEndian.AppendUint16(extensions, extensionType)
+	extensions = binary.BigEndian.AppendUint16(extensions, uint16(len(data)))
+	return append(extensions, data...)
+}
+
+// tls13Handshake reports whether the target completes a TLS 1.3 handshake and
+// the suite it negotiated. Go offers all three TLS 1.3 suites at once, so only
+// the server's preferred one is visible.
+func tls13Handshake(target CheckTarget, serverName string) (string, bool) {
+	dialer := &net.Dialer{Timeout: target.Timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", target.dialAddress(), &tls.Config{
+		ServerName:         serverName,
+		MinVersion:         tls.VersionTLS13,
+		MaxVersion:         tls.VersionTLS13,
+		InsecureSkipVerify: true, // only the protocol matters, not the certificate
+	})
+	if err != nil {
+		return "", false
+	}
+	defer conn.Close()
+	return tls.CipherSuiteName(conn.ConnectionState().CipherSuite), true
+}
@@ -0,0 +1,214 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Finding severities, from purely informational to urgent.
+const (
+	SeverityInfo   = "info"
+	SeverityLow    = "low"
+	SeverityMedium = "medium"
+	SeverityHigh   = "high"
+)
+
+// Finding is an observation produced by a check module about an open port.
+type Finding struct {
+	// Check names the module that produced the finding.
+	Check string `json:"check" example:"snmp" description:"Name of the check module that produced the finding."`
+	// Type is a stable machine-readable finding identifier.
+	Type string `json:"type" example:"snmp-exposed" description:"Stable finding identifier suitable for filtering and alerting, for example snmp-exposed or open-resolver."`
+	// Severity ranks the finding: info, low, medium or high.
+	Severity string `json:"severity" enums:"info,low,medium,high" example:"medium" description:"How urgently the finding should be reviewed."`
+	// Summary is a one-line human readable description.
+	Summary string `json:"summary" example:"SNMP v2c readable with community public" description:"One-line human readable description of the finding."`
+	// Details carries module specific attributes.
+	Details map[string]string `json:"details,omitempty" example:"{\"community\":\"public\",\"sys_descr\":\"Linux gw 5.10\"}" description:"Module specific key/value attributes collected by the check."`
+}
+
+// CheckTarget describes the open port a check runs against.
+type CheckTarget struct {
+	// Host is the target as submitted; Address is what should be dialed.
+	Host    string
+	Address string
+	Port    int
+	// Protocol is "tcp" or "udp".
+	Protocol string
+	// Service is the fingerprint or banner reported by the port scan.
+	Service string
+	// Timeout bounds each network exchange of the check.
+	Timeout time.Duration
+}
+
+// dialAddress returns the host:port string to connect to.
+func (t CheckTarget) dialAddress() string {
+	return net.JoinHostPort(t.Address, strconv.Itoa(t.Port))
+}
+
+// Check is a module that inspects open ports more deeply than the port scan.
+// Checks only run when explicitly selected; intrusive ones (for example those
+// attempting logins) must be named or selected with "all".
+type Check interface {
+	// Name is the identifier used to select the check.
+	Name() string
+	// Description is a one-line summary shown in help output.
+	Description() string
+	// Intrusive reports whether the check does more than passive inspection.
+	Intrusive() bool
+	// Applies reports whether the check should run against the given target.
+	Applies(target CheckTarget) bool
+	// Run performs the check and returns its findings.
+	Run(ctx context.Context, target CheckTarget) ([]Finding, error)
+}
+
+// DefaultCheckTimeout bounds each network exchange of a check.
+const DefaultCheckTimeout = 5 * time.Second
+
+// checkConcurrency caps how many checks run at once after the port scan.
+const checkConcurrency = 16
+
+var checkRegistry = map[string]Check{}
+
+// registerCheck makes a check selectable by name. Called from init functions.
+func registerCheck(check Check) {
+	checkRegistry[check.Name()] = check
+}
+
+// AvailableChecks returns every registered check sorted by name.
+func AvailableChecks() []Check {
+	checks := make([]Check, 0, len(checkRegistry))
+	for _, check := range checkRegistry {
+		checks = append(checks, check)
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Name() < checks[j].Name() })
+	return checks
+}
+
+// ParseChecks resolves a list of check selectors. Each entry is a check name,
+// "safe" for every non-intrusive check, or "all" for every check.
+func ParseChecks(selectors []string) ([]Check, error) {
+	selected := make(map[string]Check)
+	for _, selector := range selectors {
+		selector = strings.ToLower(strings.TrimSpace(selector))
+		switch selector {
+		case "":
+			continue
+		case "all", "safe":
+			for name, check := range checkRegistry {
+				if selector == "all" || !check.Intrusive() {
+					selected[name] = check
+				}
+			}
+		default:
+			check, ok := checkRegistry[selector]
+			if !ok {
+				return nil, fmt.Errorf("unknown check %q (available: %s, safe, all)", selector, strings.Join(checkNames(), ", "))
+			}
+			selected[selector] = check
+		}
+	}
+
+	checks := make([]Check, 0, len(selected))
+	for _, check := range selected {
+		checks = append(checks, check)
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Name() < checks[j].Name() })
+	return checks, nil
+}
+
+func checkNames() []string {
+	names := make([]string, 0, len(checkRegistry))
+	for name := range checkRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runChecks runs the selected checks against every open port in results and
+// attaches their findings. Check errors are not fatal; they only mean the
+// check could not draw a conclusion.
+func runChecks(ctx context.Context, results []ScanResult, checks []Check, protocol string) {
+	if len(checks) == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	slots := make(chan struct{}, checkConcurrency)
+
+	for i := range results {
+		result := &results[i]
+		if result.State != "Open" && !(protocol == "udp" && result.State == "Open|Filtered") {
+			continue
+		}
+		// Reused results keep the findings of the scan that probed them
+		if result.Reused {
+			continue
+		}
+
+		target := CheckTarget{
+			Host:     result.Host,
+			Address:  result.Host,
+			Port:     result.Port,
+			Protocol: protocol,
+			Service:  result.Service,
+			Timeout:  DefaultCheckTimeout,
+		}
+		if result.Address != "" {
+			target.Address = result.Address
+		}
+
+		for _, check := range checks {
+			if !check.Applies(target) {
+				continue
+			}
+			wg.Add(1)
+			go func(check Check) {
+				defer wg.Done()
+				select {
+				case slots <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				defer func() { <-slots }()
+
+				findings, err := check.Run(ctx, target)
+				if err != nil || len(findings) == 0 {
+					return
+				}
+				for j := range findings {
+					findings[j].Check = check.Name()
+				}
+				mu.Lock()
+				result.Findings = append(result.Findings, findings...)
+				mu.Unlock()
+			}(check)
+		}
+	}
+	wg.Wait()
+
+	// Checks finish in any order; keep the output stable
+	for i := range results {
+		findings := results[i].Findings
+		sort.SliceStable(findings, func(a, b int) bool { return findings[a].Check < findings[b].Check })
+	}
+}
+
+// serviceLooksLike reports whether a detected service name or banner mentions any of names.
+func serviceLooksLike(service string, names ...string) bool {
+	service = strings.ToLower(service)
+	for _, name := range names {
+		if strings.Contains(service, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package scanner
+
+import (
+	"sync"
+	"time"
+)
+
+// Congestion control tuning. Values mirror the spirit of nmap's per-host timing:
+// start conservatively, learn the host's round-trip time, and grow or shrink the
+// number of simultaneous probes based on how the host responds. The timeouts
+// and the largest window are the defaults of Timing.
+const (
+	initialProbeTimeout = 2 * time.Second
+	minProbeTimeout     = 250 * time.Millisecond
+	maxProbeTimeout     = 10 * time.Second
+
+	initialHostWindow = 10.0
+	minHostWindow     = 1.0
+	maxHostWindow     = 100.0
+
+	// Smoothing factors for the short and long term timeout rate averages.
+	recentLossAlpha   = 0.2
+	baselineLossAlpha = 0.02
+	// lossRiseThreshold is how far the recent timeout rate may exceed the host's
+	// baseline before the window is treated as congested and halved.
+	lossRiseThreshold = 0.25
+)
+
+// congestionControl hands out per-host congestion state for a single scan.
+type congestionControl struct {
+	mu     sync.Mutex
+	timing Timing
+	hosts  map[string]*hostCongestion
+}
+
+func newCongestionControl(timing Timing) *congestionControl {
+	return &congestionControl{timing: timing.withDefaults(), hosts: make(map[string]*hostCongestion)}
+}
+
+// host returns the congestion state for the given target, creating it on first use.
+func (cc *congestionControl) host(host string) *hostCongestion {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	hc, ok := cc.hosts[host]
+	if !ok {
+		hc = newHostCongestion(cc.timing)
+		cc.hosts[host] = hc
+	}
+	return hc
+}
+
+// hostCongestion tracks round-trip timing and timeout rates for one target host.
+// It derives two values used by workers:
+// - the probe timeout, computed from smoothed RTT samples (RFC 6298 style)
+// - the congestion window, i.e. how many probes may be in flight at once
+//
+// Responses grow the window (slow start, then additive increase). A timeout rate
+// that rises well above what is normal for this host is treated as a sign of
+// rate limiting and halves the window. Hosts that simply filter most ports have a
+// consistently high timeout rate and therefore are not slowed down.
+type hostCongestion struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	timing Timing
+
+	srtt     time.Duration
+	rttvar   time.Duration
+	hasRTT   bool
+	window   float64
+	ssthresh float64
+	inFlight int
+
+	recentLoss   float64
+	baselineLoss float64
+	lastDecrease time.Time
+}
+
+// newHostCongestion creates the state of one host; timing must have its
+// defaults filled.
+func newHostCongestion(timing Timing) *hostCongestion {
+	hc := &hostCongestion{
+		timing:   timing,
+		window:   min(initialHostWindow, float64(timing.MaxParallelism)),
+		ssthresh: float64(timing.MaxParallelism),
+	}
+	hc.cond = sync.NewCond(&hc.mu)
+	return hc
+}
+
+// acquire blocks until the host's congestion window has room for another probe
+// and returns the timeout the caller should apply to that probe.
+func (hc *hostCongestion) acquire() time.Duration {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	for hc.inFlight >= int(hc.window) {
+		hc.cond.Wait()
+	}
+	hc.inFlight++
+	return hc.timeoutLocked()
+}
+
+// release records the outcome of a probe previously admitted by acquire.
+// responded reports whether the host produced any answer (data, SYN-ACK, RST,
+// ICMP error); rtt is the measured round-trip time for that answer.
+func (hc *hostCongestion) release(rtt time.Duration, responded bool) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	hc.inFlight--
+
+	loss := 0.0
+	if !responded {
+		loss = 1.0
+	}
+	hc.recentLoss += recentLossAlpha * (loss - hc.recentLoss)
+	hc.baselineLoss += baselineLossAlpha * (loss - hc.baselineLoss)
+
+	if responded {
+		hc.updateRTTLocked(rtt)
+		if hc.window < hc.ssthresh {
+			hc.window++ // Slow start: grow quickly until the first congestion event
+		} else {
+			hc.window += 1 / hc.window // Congestion avoidance: additive increase
+		}
+		if limit := float64(hc.timing.MaxParallelism); hc.window > limit {
+			hc.window = limit
+		}
+	} else if hc.hasRTT && hc.recentLoss-hc.baselineLoss > lossRiseThreshold {
+		// Only react once per timeout interval so a burst of concurrent
+		// timeouts from the same episode does not collapse the window.
+		now := time.Now()
+		if now.Sub(hc.lastDecrease) >= hc.timeoutLocked() {
+			hc.window /= 2
+			if hc.window < minHostWindow {
+				hc.window = minHostWindow
+			}
+			hc.ssthresh = hc.window
+			hc.lastDecrease = now
+		}
+	}
+
+	hc.cond.Broadcast()
+}
+
+// updateRTTLocked folds a new RTT sample into the smoothed estimates.
+func (hc *hostCongestion) updateRTTLocked(rtt time.Duration) {
+	if rtt <= 0 {
+		return
+	}
+	if !hc.hasRTT {
+		hc.srtt = rtt
+		hc.rttvar = rtt / 2
+		hc.hasRTT = true
+		return
+	}
+	delta := hc.srtt - rtt
+	if delta < 0 {
+		delta = -delta
+	}
+	hc.rttvar = (3*hc.rttvar + delta) / 4
+	hc.srtt = (7*hc.srtt + rtt) / 8
+}
+
+// timeoutLocked returns the current probe timeout for the host.
+func (hc *hostCongestion) timeoutLocked() time.Duration {
+	if !hc.hasRTT {
+		return hc.timing.InitialTimeout
+	}
+	timeout := hc.srtt + 4*hc.rttvar
+	if timeout < hc.timing.MinTimeout {
+		return hc.timing.MinTimeout
+	}
+	if timeout > hc.timing.MaxTimeout {
+		return hc.timing.MaxTimeout
+	}
+	return timeout
+}
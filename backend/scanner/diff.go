@@ -0,0 +1,106 @@
+package scanner
+
+import (
+	"sort"
+	"strconv"
+)
+
+// Kinds of ResultChange.
+const (
+	ChangeOpened         = "opened"
+	ChangeClosed         = "closed"
+	ChangeServiceChanged = "service_changed"
+)
+
+// ResultChange describes how one port differs between a baseline scan and a
+// later scan of the same target.
+type ResultChange struct {
+	Host            string `json:"host" example:"scanme.nmap.org" description:"Target host the change was observed on."`
+	Address         string `json:"address,omitempty" example:"45.33.32.156" description:"Probed address when the scans covered every address of a hostname."`
+	Port            int    `json:"port" example:"3389" description:"Port whose state or service changed."`
+	Change          string `json:"change" enums:"opened,closed,service_changed" example:"opened" description:"opened when the port is open now but was not in the baseline, closed when it was open in the baseline but is not any more, service_changed when it is open in both with a different service fingerprint, product or version."`
+	PreviousState   string `json:"previous_state,omitempty" example:"Filtered" description:"Port state in the baseline. Empty when the baseline did not cover the port."`
+	State           string `json:"state" example:"Open" description:"Port state in the current scan."`
+	PreviousService string `json:"previous_service,omitempty" example:"ssh (OpenSSH 8.2p1)" description:"Service fingerprint in the baseline."`
+	Service         string `json:"service,omitempty" example:"ms-wbt-server" description:"Service fingerprint in the current scan."`
+	PreviousVersion string `json:"previous_version,omitempty" example:"8.2p1" description:"Product version identified in the baseline."`
+	Version         string `json:"version,omitempty" example:"9.6p1" description:"Product version identified in the current scan."`
+}
+
+// ChangeCounts tallies a list of changes by kind.
+type ChangeCounts struct {
+	Opened         int
+	Closed         int
+	ServiceChanged int
+}
+
+// CountChanges tallies changes by kind.
+func CountChanges(changes []ResultChange) ChangeCounts {
+	var counts ChangeCounts
+	for _, change := range changes {
+		switch change.Change {
+		case ChangeOpened:
+			counts.Opened++
+		case ChangeClosed:
+			counts.Closed++
+		case ChangeServiceChanged:
+			counts.ServiceChanged++
+		}
+	}
+	return counts
+}
+
+// DiffResults reports the ports that opened, closed or changed service
+// between baseline and current. Ports the current scan did not probe are
+// ignored, so narrowing the port range does not report everything outside it
+// as closed. Changes are sorted by host, address and port.
+func DiffResults(baseline, current []ScanResult) []ResultChange {
+	previous := make(map[string]ScanResult, len(baseline))
+	for _, result := range baseline {
+		previous[resultKey(result)] = result
+	}
+
+	var changes []ResultChange
+	for _, result := range current {
+		before, seen := previous[resultKey(result)]
+		change := ResultChange{
+			Host:            result.Host,
+			Address:         result.Address,
+			Port:            result.Port,
+			PreviousState:   before.State,
+			State:           result.State,
+			PreviousService: before.Service,
+			Service:         result.Service,
+			PreviousVersion: before.Version,
+			Version:         result.Version,
+		}
+		wasOpen := seen && before.State == "Open"
+		switch {
+		case result.State == "Open" && !wasOpen:
+			change.Change = ChangeOpened
+		case result.State != "Open" && wasOpen:
+			change.Change = ChangeClosed
+		case result.State == "Open" && (before.Service != result.Service || before.Product != result.Product || before.Version != result.Version):
+			change.Change = ChangeServiceChanged
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Host != changes[j].Host {
+			return changes[i].Host < changes[j].Host
+		}
+		if changes[i].Address != changes[j].Address {
+			return changes[i].Address < changes[j].Address
+		}
+		return changes[i].Port < changes[j].Port
+	})
+	return changes
+}
+
+// resultKey identifies the probe a result belongs to across scans.
+func resultKey(result ScanResult) string {
+	return result.Host + "|" + result.Address + "|" + strconv.Itoa(result.Port)
+}
@@ -0,0 +1,425 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Host discovery defaults, after nmap's default ping probes.
+const (
+	DefaultDiscoveryTimeout = 2 * time.Second
+	// discoveryConcurrency caps the hosts pinged at the same time.
+	discoveryConcurrency = 64
+)
+
+// DefaultDiscoveryPorts are the ports TCP pings connect to.
+var DefaultDiscoveryPorts = []int{443, 80, 22}
+
+// Host states reported in HostSummary.Status.
+const (
+	HostUp   = "up"
+	HostDown = "down"
+)
+
+// DiscoveryOptions tunes the host discovery phase.
+// The zero value keeps the defaults.
+type DiscoveryOptions struct {
+	// Ports are tried with TCP pings: a connection accepted or refused proves
+	// the host is up. Empty uses DefaultDiscoveryPorts.
+	Ports []int
+	// Timeout is how long a host has to answer any ping. Zero uses
+	// DefaultDiscoveryTimeout.
+	Timeout time.Duration
+}
+
+// withDefaults fills unset fields with the package defaults.
+func (o DiscoveryOptions) withDefaults() DiscoveryOptions {
+	if len(o.Ports) == 0 {
+		o.Ports = DefaultDiscoveryPorts
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultDiscoveryTimeout
+	}
+	return o
+}
+
+// discoverHosts pings every target before the port scan and returns the
+// targets that answered, in their original order, together with a summary
+// per target stating whether it is up and which ping proved it. Targets on
+// the local IPv4 subnet are asked by ARP when raw packet access is available,
+// and a missing ARP reply counts as down; others get an ICMP echo request and
+// TCP pings to opts.Ports at the same time, the first answer winning. Targets
+// that do not resolve are down.
+func discoverHosts(ctx context.Context, targets []string, opts DiscoveryOptions, resolver *resolverCache) ([]string, []HostSummary) {
+	opts = opts.withDefaults()
+	arp, _ := openARPSession()
+	if arp != nil {
+		defer arp.close()
+	}
+
+	summaries := make([]HostSummary, len(targets))
+	sem := make(chan struct{}, discoveryConcurrency)
+	var wg sync.WaitGroup
+	for i, host := range targets {
+		summaries[i] = HostSummary{Host: host, Status: HostDown, StatusReason: "no-response"}
+		ips, err := resolver.lookup(host)
+		if err != nil {
+			summaries[i].StatusReason = "unresolved"
+			continue
+		}
+		ip := preferredAddress(ips, resolver.prefer)
+		if ip.String() != host {
+			summaries[i].Address = ip.String()
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(summary *HostSummary, ip net.IP) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if reason := pingHost(ctx, ip, opts, arp); reason != "" {
+				summary.Status, summary.StatusReason = HostUp, reason
+			}
+		}(&summaries[i], ip)
+	}
+	wg.Wait()
+
+	var up []string
+	for _, summary := range summaries {
+		if summary.Status == HostUp {
+			up = append(up, summary.Host)
+		}
+	}
+	return up, summaries
+}
+
+// mergeHostStatus copies the discovery status of every target in discovered
+// into summaries, adding entries for targets that have none yet.
+func mergeHostStatus(summaries, discovered []HostSummary) []HostSummary {
+	for _, host := range discovered {
+		found := false
+		for i := range summaries {
+			if summaries[i].Host == host.Host {
+				summaries[i].Status, summaries[i].StatusReason = host.Status, host.StatusReason
+				found = true
+			}
+		}
+		if !found {
+			summaries = append(summaries, host)
+		}
+	}
+	return summaries
+}
+
+// pingHost returns the reason ip is known to be up, or "" when it never
+// answered within opts.Timeout.
+func pingHost(ctx context.Context, ip net.IP, opts DiscoveryOptions, arp *arpSession) string {
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	if arp.covers(ip) {
+		if arp.resolve(ctx, ip) != nil {
+			return "arp-response"
+		}
+		return ""
+	}
+
+	answers := make(chan string, len(opts.Ports)+1)
+	go func() {
+		if icmpPing(ctx, ip) {
+			answers <- "echo-reply"
+		} else {
+			answers <- ""
+		}
+	}()
+	for _, port := range opts.Ports {
+		go func(port int) {
+			if tcpPing(ctx, ip, port) {
+				answers <- "tcp-" + strconv.Itoa(port)
+			} else {
+				answers <- ""
+			}
+		}(port)
+	}
+	for range len(opts.Ports) + 1 {
+		if reason := <-answers; reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// tcpPing reports whether ip answers a TCP connection attempt to port, with
+// either a handshake or a reset.
+func tcpPing(ctx context.Context, ip net.IP, port int) bool {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+	if err != nil {
+		return isConnectionRefused(err)
+	}
+	conn.Close()
+	return true
+}
+
+// icmpPing sends an ICMP echo request to ip and reports whether the matching
+// reply arrived before ctx ends. It uses a raw socket when privileged and
+// falls back to the unprivileged ICMP datagram sockets of Linux and macOS;
+// without either it reports false.
+func icmpPing(ctx context.Context, ip net.IP) bool {
+	network, fallback, protocol := "ip4:icmp", "udp4", 1
+	var echo icmp.Type = ipv4.ICMPTypeEcho
+	var echoReply icmp.Type = ipv4.ICMPTypeEchoReply
+	if ip.To4() == nil {
+		network, fallback, protocol = "ip6:ipv6-icmp", "udp6", 58
+		echo, echoReply = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+
+	privileged := true
+	conn, err := icmp.ListenPacket(network, "")
+	if err != nil {
+		privileged = false
+		if conn, err = icmp.ListenPacket(fallback, ""); err != nil {
+			return false
+		}
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	// Unblock the read below when ctx is cancelled early
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	id, seq := rand.Intn(0xffff), 1
+	request, err := (&icmp.Message{Type: echo, Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("cortex")}}).Marshal(nil)
+	if err != nil {
+		return false
+	}
+	var dst net.Addr = &net.IPAddr{IP: ip}
+	if !privileged {
+		dst = &net.UDPAddr{IP: ip}
+	}
+	if _, err := conn.WriteTo(request, dst); err != nil {
+		return false
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return false
+		}
+		if peerIP(peer) == nil || !peerIP(peer).Equal(ip) {
+			continue
+		}
+		reply, err := icmp.ParseMessage(protocol, buf[:n])
+		if err != nil || reply.Type != echoReply {
+			continue
+		}
+		// Datagram sockets rewrite the identifier; the kernel already
+		// delivers only replies to this socket
+		if body, ok := reply.Body.(*icmp.Echo); ok && body.Seq == seq && (body.ID == id || !privileged) {
+			return true
+		}
+	}
+}
+
+// peerIP returns the IP of an address read from an ICMP socket.
+func peerIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	}
+	return nil
+}
+
+// arpSession asks hosts on the local IPv4 subnet for their hardware address
+// through a pcap handle on the source interface. One read loop hands the
+// hardware address in every ARP reply to the callers waiting for its sender.
+type arpSession struct {
+	handle  *pcap.Handle
+	iface   *net.Interface
+	srcIP   net.IP
+	network *net.IPNet
+	// writeMu serializes injection; the read loop uses the handle alone
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	waiting map[string][]chan net.HardwareAddr
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// openARPSession opens the ARP handle for the source interface and starts
+// its read loop. It fails without raw packet access or an Ethernet interface.
+func openARPSession() (*arpSession, error) {
+	src, err := sourceInterface()
+	if err != nil {
+		return nil, err
+	}
+	srcIP, iface := src.ipv4, src.iface
+	if srcIP == nil {
+		return nil, fmt.Errorf("interface %s has no IPv4 address", iface.Name)
+	}
+	if len(iface.HardwareAddr) != 6 {
+		return nil, fmt.Errorf("interface %s has no Ethernet address", iface.Name)
+	}
+	var network *net.IPNet
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.Equal(srcIP) {
+			network = &net.IPNet{IP: srcIP.Mask(ipnet.Mask), Mask: ipnet.Mask}
+		}
+	}
+	if network == nil {
+		return nil, fmt.Errorf("no subnet found for %s", srcIP)
+	}
+	deviceName, err := captureDevice(iface, srcIP)
+	if err != nil {
+		return nil, err
+	}
+	handle, err := pcap.OpenLive(deviceName, captureSnapLen, false, captureReadTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := handle.SetBPFFilter("arp"); err != nil {
+		handle.Close()
+		return nil, err
+	}
+
+	a := &arpSession{
+		handle:  handle,
+		iface:   iface,
+		srcIP:   srcIP,
+		network: network,
+		waiting: make(map[string][]chan net.HardwareAddr),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go a.read()
+	return a, nil
+}
+
+// covers reports whether ip is another host on the session's subnet. A nil
+// session covers nothing.
+func (a *arpSession) covers(ip net.IP) bool {
+	return a != nil && ip.To4() != nil && a.network.Contains(ip) && !ip.Equal(a.srcIP)
+}
+
+// read hands the sender's hardware address in every ARP reply to the callers
+// waiting for it until close is called.
+func (a *arpSession) read() {
+	defer close(a.stopped)
+	linkType := a.handle.LinkType()
+	for {
+		select {
+		case <-a.done:
+			return
+		default:
+		}
+		data, _, err := a.handle.ReadPacketData()
+		if err == pcap.NextErrorTimeoutExpired {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		packet := gopacket.NewPacket(data, linkType, gopacket.Default)
+		reply, ok := packet.Layer(layers.LayerTypeARP).(*layers.ARP)
+		if !ok || reply.Operation != layers.ARPReply {
+			continue
+		}
+		sender := net.IP(reply.SourceProtAddress).String()
+		mac := net.HardwareAddr(append([]byte(nil), reply.SourceHwAddress...))
+		a.mu.Lock()
+		for _, waiter := range a.waiting[sender] {
+			select {
+			case waiter <- mac:
+			default:
+			}
+		}
+		a.mu.Unlock()
+	}
+}
+
+// resolve broadcasts an ARP request for ip and returns the hardware address
+// of its reply, or nil when none arrived before ctx ends.
+func (a *arpSession) resolve(ctx context.Context, ip net.IP) net.HardwareAddr {
+	key := ip.String()
+	replies := make(chan net.HardwareAddr, 1)
+	a.mu.Lock()
+	a.waiting[key] = append(a.waiting[key], replies)
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		waiters := a.waiting[key]
+		for i, waiter := range waiters {
+			if waiter == replies {
+				a.waiting[key] = append(waiters[:i], waiters[i+1:]...)
+				break
+			}
+		}
+		if len(a.waiting[key]) == 0 {
+			delete(a.waiting, key)
+		}
+	}()
+
+	ethernet := &layers.Ethernet{
+		SrcMAC:       a.iface.HardwareAddr,
+		DstMAC:       net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		EthernetType: layers.EthernetTypeARP,
+	}
+	request := &layers.ARP{
+		AddrType:          layers.LinkTypeEthernet,
+		Protocol:          layers.EthernetTypeIPv4,
+		HwAddressSize:     6,
+		ProtAddressSize:   4,
+		Operation:         layers.ARPRequest,
+		SourceHwAddress:   a.iface.HardwareAddr,
+		SourceProtAddress: a.srcIP.To4(),
+		DstHwAddress:      make([]byte, 6),
+		DstProtAddress:    ip.To4(),
+	}
+	buffer := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buffer, gopacket.SerializeOptions{FixLengths: true}, ethernet, request); err != nil {
+		return nil
+	}
+	a.writeMu.Lock()
+	err := a.handle.WritePacketData(buffer.Bytes())
+	a.writeMu.Unlock()
+	if err != nil {
+		return nil
+	}
+
+	select {
+	case mac := <-replies:
+		return mac
+	case <-a.stopped:
+		return nil
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// close stops the read loop and releases the capture handle.
+func (a *arpSession) close() {
+	close(a.done)
+	<-a.stopped
+	a.handle.Close()
+}
@@ -0,0 +1,65 @@
+package scanner
+
+import (
+	"math"
+	"time"
+)
+
+// Estimate is the predicted size and duration of a scan, computed without
+// sending any probes.
+type Estimate struct {
+	// Targets is the number of probe targets after expansion and blocklist
+	// filtering; with AllAddresses every resolved address counts separately.
+	Targets int
+	// Ports is the number of ports probed on each target; targets listed in
+	// ScanOptions.HostPorts probe their own count instead.
+	Ports int
+	// Jobs is the total number of probes, Targets times Ports unless
+	// HostPorts gives some targets other ports.
+	Jobs int
+	// Duration is a pessimistic prediction that assumes every probe waits for
+	// the initial probe timeout, as it does when ports are filtered.
+	Duration time.Duration
+}
+
+// EstimateScan expands hosts the way Run would and predicts how long probing
+// ports ports per target takes in mode. Throughput is bounded by the mode's
+// default worker count, the initial per-host congestion window, the timing
+// and the rate caps in opts; retries are not counted. Hostnames are resolved, so the call may block on DNS.
+func EstimateScan(hosts []string, ports int, mode Mode, opts ScanOptions) Estimate {
+	targets := expandTargets(hosts, opts, newResolverCache(opts.Prefer, opts.IPVersion, opts.DNSServers))
+	estimate := Estimate{Targets: len(targets), Ports: ports}
+	for _, target := range targets {
+		if own, ok := opts.HostPorts[target.Host]; ok {
+			estimate.Jobs += len(own)
+		} else {
+			estimate.Jobs += ports
+		}
+	}
+	if estimate.Jobs == 0 {
+		return estimate
+	}
+
+	workers := float64(connectWorkers)
+	if mode.RawTCP() {
+		workers = synWorkers
+	} else if mode == ModeUDP {
+		workers = udpWorkers
+	}
+	timing := opts.Timing.withDefaults()
+	window := math.Min(initialHostWindow, float64(timing.MaxParallelism))
+	concurrency := math.Min(workers, float64(len(targets))*window)
+	perSecond := concurrency / timing.InitialTimeout.Seconds()
+	if opts.Rate > 0 {
+		perSecond = math.Min(perSecond, opts.Rate)
+	}
+	hostRate := opts.HostRate
+	if hostRate <= 0 && timing.ScanDelay > 0 {
+		hostRate = 1 / timing.ScanDelay.Seconds()
+	}
+	if hostRate > 0 {
+		perSecond = math.Min(perSecond, hostRate*float64(len(targets)))
+	}
+	estimate.Duration = time.Duration(float64(estimate.Jobs) / perSecond * float64(time.Second))
+	return estimate
+}
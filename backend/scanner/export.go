@@ -0,0 +1,224 @@
+package scanner
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats understood by WriteExport.
+const (
+	// ExportXML is the XML nmap writes with -oX.
+	ExportXML = "xml"
+	// ExportGreppable is the one-line-per-host format nmap writes with -oG.
+	ExportGreppable = "grep"
+	// ExportCSV is one row per result with a header row.
+	ExportCSV = "csv"
+)
+
+// ExportFormats lists the formats of WriteExport.
+var ExportFormats = []string{ExportXML, ExportGreppable, ExportCSV}
+
+// ExportInfo describes the scan whose results are exported.
+type ExportInfo struct {
+	// Args is recorded as the command line of the scan.
+	Args string
+	// Mode sets the protocol of the ports; Ports are the ports scanned.
+	Mode  Mode
+	Ports []int
+	// Start and End bound the scan; a zero End is taken as now.
+	Start time.Time
+	End   time.Time
+	// Hosts supplies the up or down status discovery found; down hosts are
+	// listed without ports.
+	Hosts []HostSummary
+	// Version is the Cortex version recorded in the output.
+	Version string
+}
+
+func (info ExportInfo) protocol() string {
+	if info.Mode == ModeUDP {
+		return "udp"
+	}
+	return "tcp"
+}
+
+func (info ExportInfo) end() time.Time {
+	if info.End.IsZero() {
+		return time.Now()
+	}
+	return info.End
+}
+
+// WriteExport writes results to w in format, one of ExportFormats.
+func WriteExport(w io.Writer, format string, results []ScanResult, info ExportInfo) error {
+	switch format {
+	case ExportXML:
+		return WriteNmapXML(w, results, info)
+	case ExportGreppable:
+		return WriteGreppable(w, results, info)
+	case ExportCSV:
+		return WriteCSV(w, results, info)
+	}
+	return fmt.Errorf("unknown export format %q (use %s)", format, strings.Join(ExportFormats, ", "))
+}
+
+// sortedByHost returns a copy of results sorted by host, address and port.
+func sortedByHost(results []ScanResult) []ScanResult {
+	sorted := append([]ScanResult(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Host != sorted[j].Host {
+			return sorted[i].Host < sorted[j].Host
+		}
+		if sorted[i].Address != sorted[j].Address {
+			return sorted[i].Address < sorted[j].Address
+		}
+		return sorted[i].Port < sorted[j].Port
+	})
+	return sorted
+}
+
+// WriteGreppable writes results in nmap's greppable format: a Status line
+// and a Ports line per host, each port as
+// port/state/protocol/owner/service/rpc/version/, between comment lines
+// naming the scan. Slashes inside fields become '|' as nmap writes them.
+func WriteGreppable(w io.Writer, results []ScanResult, info ExportInfo) error {
+	// A failed write sticks to out, so every line below stops at it and
+	// Flush reports it
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "# Cortex %s scan initiated %s as: %s\n", info.Version, info.Start.Format(time.ANSIC), info.Args)
+
+	statuses := make(map[string]HostSummary, len(info.Hosts))
+	for _, summary := range info.Hosts {
+		statuses[summary.Host] = summary
+	}
+	up, down := 0, 0
+	listed := make(map[string]bool)
+	writeHost := func(host, address string, ports []string) {
+		listed[host] = true
+		target := greppableTarget(host, address)
+		status := "Up"
+		if summary, ok := statuses[host]; ok && summary.Status == HostDown {
+			status = "Down"
+		}
+		if status == "Up" {
+			up++
+		} else {
+			down++
+		}
+		fmt.Fprintf(out, "%s\tStatus: %s\n", target, status)
+		if len(ports) > 0 {
+			fmt.Fprintf(out, "%s\tPorts: %s\n", target, strings.Join(ports, ", "))
+		}
+	}
+
+	sorted := sortedByHost(results)
+	for start := 0; start < len(sorted); {
+		end := start
+		var ports []string
+		for ; end < len(sorted) && hostKey(sorted[end]) == hostKey(sorted[start]); end++ {
+			ports = append(ports, greppablePort(sorted[end], info.protocol()))
+		}
+		writeHost(sorted[start].Host, sorted[start].Address, ports)
+		start = end
+	}
+	for _, summary := range info.Hosts {
+		if summary.Status == HostDown && !listed[summary.Host] {
+			writeHost(summary.Host, summary.Address, nil)
+		}
+	}
+
+	end := info.end()
+	fmt.Fprintf(out, "# Cortex done at %s -- %d IP addresses (%d hosts up) scanned in %.2f seconds\n",
+		end.Format(time.ANSIC), up+down, up, end.Sub(info.Start).Seconds())
+	return out.Flush()
+}
+
+// greppableTarget formats the Host field: the probed address followed by the
+// hostname in parentheses, empty when the host was given as an address.
+func greppableTarget(host, address string) string {
+	name := host
+	if address == "" {
+		address, name = host, ""
+	}
+	if net.ParseIP(host) != nil {
+		name = ""
+	}
+	return fmt.Sprintf("Host: %s (%s)", address, name)
+}
+
+// greppablePort formats result as a Ports entry.
+func greppablePort(result ScanResult, protocol string) string {
+	state := strings.ToLower(result.State)
+	if result.State == "Tarpit" {
+		state = "open"
+	}
+	service := result.ServiceGuess
+	if serviceIdentified(result) {
+		service = strings.Replace(result.Service, "ssl/", "ssl|", 1)
+	}
+	details := strings.TrimSpace(result.Product + " " + result.Version)
+	if result.Info != "" {
+		details = strings.TrimSpace(details + " (" + result.Info + ")")
+	}
+	return fmt.Sprintf("%d/%s/%s//%s//%s/", result.Port, state, protocol, greppableField(service), greppableField(details))
+}
+
+// greppableField keeps the separators of the format out of a field.
+func greppableField(value string) string {
+	return strings.NewReplacer("/", "|", ",", ";", "\t", " ", "\n", " ", "\r", "").Replace(value)
+}
+
+// csvHeader names the columns WriteCSV writes.
+var csvHeader = []string{"host", "address", "port", "protocol", "state", "service", "product", "version", "info", "tls", "cpe", "service_guess"}
+
+// WriteCSV writes one row per result under a header row. The service column
+// holds the identified service name, or the raw banner when no probe rule
+// matched; cpe joins the CPE names with spaces. Cells a spreadsheet would
+// evaluate as a formula are escaped by csvCell.
+func WriteCSV(w io.Writer, results []ScanResult, info ExportInfo) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, result := range results {
+		record := []string{
+			result.Host,
+			result.Address,
+			strconv.Itoa(result.Port),
+			info.protocol(),
+			result.State,
+			result.Service,
+			result.Product,
+			result.Version,
+			result.Info,
+			result.TLS,
+			strings.Join(result.CPE, " "),
+			result.ServiceGuess,
+		}
+		for i, cell := range record {
+			record[i] = csvCell(cell)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvCell prefixes value with a quote when it starts like a spreadsheet
+// formula, so that banners and names taken from scanned hosts are shown as
+// text instead of being evaluated.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
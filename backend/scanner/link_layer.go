@@ -0,0 +1,290 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+)
+
+const (
+	// nextHopTimeout bounds how long finding the hardware address of a
+	// probe's next hop may take.
+	nextHopTimeout = 2 * time.Second
+	// nextHopPort is the UDP port of the datagram the OS is asked to send
+	// when the next hop has to be learned from the wire.
+	nextHopPort = 33434
+)
+
+// Some platforms report raw IP devices by their DLT_RAW value instead of
+// LINKTYPE_RAW: 12 on most of them, 14 on OpenBSD.
+const (
+	linkTypeRawAlt     layers.LinkType = 12
+	linkTypeRawOpenBSD layers.LinkType = 14
+)
+
+// linkFramer prepends the link-layer header a capture device expects to raw
+// IP probes. Ethernet devices, which include every Npcap adapter, need the
+// hardware address of the next hop: the target itself when it shares a
+// subnet with the interface, the router towards it otherwise. Devices that
+// carry bare IP packets get none.
+type linkFramer struct {
+	linkType layers.LinkType
+	device   string
+	src      sourceAddresses
+	// networks are the subnets of the interface; their hosts are neighbours
+	networks []*net.IPNet
+
+	// The ARP session is opened by the first IPv4 neighbour probed
+	arpOnce sync.Once
+	arp     *arpSession
+
+	mu   sync.Mutex
+	hops map[string]*nextHop
+}
+
+// nextHop is the hardware address of one neighbour or router, resolved once.
+// A failed resolution is kept too, so probes of an absent neighbour do not
+// each wait for it.
+type nextHop struct {
+	once sync.Once
+	mac  net.HardwareAddr
+	err  error
+}
+
+// newLinkFramer returns the framer for a capture device of linkType. It
+// fails for link types probes cannot be injected into.
+func newLinkFramer(linkType layers.LinkType, device string, src sourceAddresses) (*linkFramer, error) {
+	switch linkType {
+	case layers.LinkTypeEthernet:
+		if len(src.iface.HardwareAddr) != 6 {
+			return nil, fmt.Errorf("interface %s has no Ethernet address", src.iface.Name)
+		}
+	case layers.LinkTypeRaw, layers.LinkTypeIPv4, layers.LinkTypeIPv6, linkTypeRawAlt, linkTypeRawOpenBSD:
+	default:
+		return nil, fmt.Errorf("capture device %s has link type %s, raw probes need Ethernet or raw IP", device, linkType)
+	}
+	addrs, err := src.iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	f := &linkFramer{linkType: linkType, device: device, src: src, hops: make(map[string]*nextHop)}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
+			f.networks = append(f.networks, &net.IPNet{IP: ipnet.IP.Mask(ipnet.Mask), Mask: ipnet.Mask})
+		}
+	}
+	return f, nil
+}
+
+// frame prepends the link-layer header of a probe to dstIP to buffer, which
+// holds the serialized IP packet.
+func (f *linkFramer) frame(buffer gopacket.SerializeBuffer, dstIP net.IP) error {
+	if f.linkType != layers.LinkTypeEthernet {
+		return nil
+	}
+	dstMAC, err := f.nextHop(dstIP)
+	if err != nil {
+		return err
+	}
+	ethernet := &layers.Ethernet{
+		SrcMAC:       f.src.iface.HardwareAddr,
+		DstMAC:       dstMAC,
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	if dstIP.To4() == nil {
+		ethernet.EthernetType = layers.EthernetTypeIPv6
+	}
+	return ethernet.SerializeTo(buffer, gopacket.SerializeOptions{})
+}
+
+// nextHop returns the hardware address frames to dstIP are sent to. Every
+// neighbour is resolved on its own; all other targets of a family share the
+// router the first of them was routed through.
+func (f *linkFramer) nextHop(dstIP net.IP) (net.HardwareAddr, error) {
+	key := "router4"
+	if dstIP.To4() == nil {
+		key = "router6"
+	}
+	neighbour := f.onLink(dstIP)
+	if neighbour {
+		key = dstIP.String()
+	}
+	f.mu.Lock()
+	hop := f.hops[key]
+	if hop == nil {
+		hop = &nextHop{}
+		f.hops[key] = hop
+	}
+	f.mu.Unlock()
+
+	hop.once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), nextHopTimeout)
+		defer cancel()
+		hop.mac, hop.err = f.resolve(ctx, dstIP, neighbour)
+	})
+	return hop.mac, hop.err
+}
+
+// onLink reports whether dstIP is on one of the interface's subnets.
+func (f *linkFramer) onLink(dstIP net.IP) bool {
+	for _, network := range f.networks {
+		if network.Contains(dstIP) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve finds the hardware address of the next hop towards dstIP. IPv4
+// neighbours on the interface's primary subnet are asked by ARP and IPv6
+// neighbours by Neighbor Solicitation; for any other target the OS routes a
+// datagram and the address is read off its frame.
+func (f *linkFramer) resolve(ctx context.Context, dstIP net.IP, neighbour bool) (net.HardwareAddr, error) {
+	if neighbour && dstIP.To4() != nil {
+		if arp := f.arpSession(); arp.covers(dstIP) {
+			if mac := arp.resolve(ctx, dstIP); mac != nil {
+				return mac, nil
+			}
+			return nil, fmt.Errorf("no ARP reply from %s", dstIP)
+		}
+	}
+	if neighbour && dstIP.To4() == nil && f.src.ipv6 != nil {
+		return solicitNeighbour(ctx, f.device, f.src, dstIP)
+	}
+	return learnNextHop(ctx, f.device, dstIP)
+}
+
+// arpSession returns the framer's ARP session, opening it on first use, or
+// nil when it cannot be opened.
+func (f *linkFramer) arpSession() *arpSession {
+	f.arpOnce.Do(func() {
+		f.arp, _ = openARPSession()
+	})
+	return f.arp
+}
+
+// close releases the ARP session, if one was opened.
+func (f *linkFramer) close() {
+	if f.arp != nil {
+		f.arp.close()
+	}
+}
+
+// solicitNeighbour sends an IPv6 Neighbor Solicitation for dstIP to its
+// solicited-node multicast group and returns the hardware address of the
+// advertisement that answers it.
+func solicitNeighbour(ctx context.Context, device string, src sourceAddresses, dstIP net.IP) (net.HardwareAddr, error) {
+	handle, err := pcap.OpenLive(device, captureSnapLen, false, captureReadTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer handle.Close()
+	if err := handle.SetBPFFilter("icmp6 and dst host " + src.ipv6.String()); err != nil {
+		return nil, err
+	}
+
+	// ff02::1:ffXX:XXXX and 33:33:ff:XX:XX:XX carry the low 24 bits of
+	// the target address
+	target := dstIP.To16()
+	group := net.IP{0xff, 0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01, 0xff, target[13], target[14], target[15]}
+	ethernet := &layers.Ethernet{
+		SrcMAC:       src.iface.HardwareAddr,
+		DstMAC:       net.HardwareAddr{0x33, 0x33, 0xff, target[13], target[14], target[15]},
+		EthernetType: layers.EthernetTypeIPv6,
+	}
+	ipLayer := &layers.IPv6{Version: 6, SrcIP: src.ipv6, DstIP: group, NextHeader: layers.IPProtocolICMPv6, HopLimit: 255}
+	icmpLayer := &layers.ICMPv6{TypeCode: layers.CreateICMPv6TypeCode(layers.ICMPv6TypeNeighborSolicitation, 0)}
+	_ = icmpLayer.SetNetworkLayerForChecksum(ipLayer)
+	solicitation := &layers.ICMPv6NeighborSolicitation{
+		TargetAddress: target,
+		Options:       layers.ICMPv6Options{{Type: layers.ICMPv6OptSourceAddress, Data: src.iface.HardwareAddr}},
+	}
+	buffer := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buffer, opts, ethernet, ipLayer, icmpLayer, solicitation); err != nil {
+		return nil, err
+	}
+	if err := handle.WritePacketData(buffer.Bytes()); err != nil {
+		return nil, err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no neighbor advertisement from %s", dstIP)
+		default:
+		}
+		data, _, err := handle.ReadPacketData()
+		if err == pcap.NextErrorTimeoutExpired {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		packet := gopacket.NewPacket(data, layers.LinkTypeEthernet, gopacket.Default)
+		advert, ok := packet.Layer(layers.LayerTypeICMPv6NeighborAdvertisement).(*layers.ICMPv6NeighborAdvertisement)
+		if !ok || !advert.TargetAddress.Equal(dstIP) {
+			continue
+		}
+		for _, option := range advert.Options {
+			if option.Type == layers.ICMPv6OptTargetAddress && len(option.Data) == 6 {
+				return net.HardwareAddr(option.Data), nil
+			}
+		}
+		if ethernet, ok := packet.LinkLayer().(*layers.Ethernet); ok {
+			return ethernet.SrcMAC, nil
+		}
+	}
+}
+
+// learnNextHop has the OS send a UDP datagram to dstIP and reads the
+// destination hardware address off the frame it puts on the wire, leaving
+// the choice of router and the neighbour lookup to the OS's routing table
+// and neighbour cache.
+func learnNextHop(ctx context.Context, device string, dstIP net.IP) (net.HardwareAddr, error) {
+	handle, err := pcap.OpenLive(device, captureSnapLen, false, captureReadTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer handle.Close()
+	if err := handle.SetBPFFilter(fmt.Sprintf("udp dst port %d and dst host %s", nextHopPort, dstIP)); err != nil {
+		return nil, err
+	}
+	conn, err := net.Dial("udp", hostPort(dstIP, nextHopPort))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// The OS may drop the datagram while it resolves the next hop itself, so
+	// send it again now and then
+	resend := time.NewTicker(nextHopTimeout / 4)
+	defer resend.Stop()
+	_, _ = conn.Write([]byte("cortex"))
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no next hop found towards %s on %s", dstIP, device)
+		case <-resend.C:
+			_, _ = conn.Write([]byte("cortex"))
+		default:
+		}
+		data, _, err := handle.ReadPacketData()
+		if err == pcap.NextErrorTimeoutExpired {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		packet := gopacket.NewPacket(data, layers.LinkTypeEthernet, gopacket.Default)
+		if ethernet, ok := packet.LinkLayer().(*layers.Ethernet); ok {
+			return ethernet.DstMAC, nil
+		}
+	}
+}
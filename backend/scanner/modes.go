@@ -0,0 +1,101 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Mode selects the probing technique of a scan.
+type Mode string
+
+// Supported scan modes.
+const (
+	ModeConnect Mode = "connect"
+	ModeSyn     Mode = "syn"
+	ModeUDP     Mode = "udp"
+	ModeFin     Mode = "fin"
+	ModeNull    Mode = "null"
+	ModeXmas    Mode = "xmas"
+	ModeAck     Mode = "ack"
+)
+
+// RawTCP reports whether m probes with crafted TCP segments through the SYN
+// capture handle, needing the same privileges and worker pool as SYN scans.
+func (m Mode) RawTCP() bool {
+	switch m {
+	case ModeSyn, ModeFin, ModeNull, ModeXmas, ModeAck:
+		return true
+	}
+	return false
+}
+
+// Default worker counts per mode. Raw packet and UDP probes each hold a
+// capture handle or socket for the full timeout, so they use fewer workers.
+const (
+	connectWorkers = 100
+	synWorkers     = 50
+	udpWorkers     = 50
+)
+
+var (
+	synInitOnce sync.Once
+	synInitErr  error
+
+	udpInitOnce sync.Once
+	udpInitErr  error
+)
+
+// ParseMode converts a mode name into a Mode. An empty name selects ModeConnect.
+func ParseMode(name string) (Mode, error) {
+	switch mode := Mode(strings.ToLower(strings.TrimSpace(name))); mode {
+	case "":
+		return ModeConnect, nil
+	case ModeConnect, ModeSyn, ModeUDP, ModeFin, ModeNull, ModeXmas, ModeAck:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown scan mode %q (expected connect, syn, udp, fin, null, xmas or ack)", name)
+	}
+}
+
+// WorkerForMode returns the worker implementation and default worker count for
+// mode, checking platform prerequisites once per process. When raw TCP
+// scanning (syn, fin, null, xmas or ack) is unavailable for lack of privileges and
+// allowFallback is set, the connect worker is returned instead, along with the
+// effective mode and a warning describing the downgrade.
+func WorkerForMode(mode Mode, allowFallback bool) (WorkerFunc, int, Mode, string, error) {
+	switch mode {
+	case ModeSyn, ModeFin, ModeNull, ModeXmas, ModeAck:
+		synInitOnce.Do(func() {
+			synInitErr = InitSynScan()
+		})
+		if synInitErr != nil {
+			if allowFallback && errors.Is(synInitErr, ErrInsufficientPrivileges) {
+				warning := fmt.Sprintf("%s scan unavailable, fell back to connect scan: %v", mode, synInitErr)
+				return TCPConnectWorker, connectWorkers, ModeConnect, warning, nil
+			}
+			return nil, 0, mode, "", synInitErr
+		}
+		worker := map[Mode]WorkerFunc{
+			ModeSyn:  TCPSynWorker,
+			ModeFin:  TCPFinWorker,
+			ModeNull: TCPNullWorker,
+			ModeXmas: TCPXmasWorker,
+			ModeAck:  TCPAckWorker,
+		}[mode]
+		return worker, synWorkers, mode, "", nil
+	case ModeUDP:
+		udpInitOnce.Do(func() {
+			udpInitErr = InitUdpScan()
+		})
+		if udpInitErr != nil {
+			return nil, 0, mode, "", udpInitErr
+		}
+		return UDPWorker, udpWorkers, ModeUDP, "", nil
+	case ModeConnect, "":
+		return TCPConnectWorker, connectWorkers, ModeConnect, "", nil
+	default:
+		return nil, 0, mode, "", fmt.Errorf("unknown scan mode %q", mode)
+	}
+}
@@ -0,0 +1,350 @@
+package scanner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NmapXMLWriter writes scan results as the XML nmap produces with -oX, so
+// that tools importing nmap output can read them. Results are written as
+// they arrive; consecutive results of the same host share one host element,
+// so results should come sorted by host.
+type NmapXMLWriter struct {
+	w        io.Writer
+	enc      *xml.Encoder
+	info     ExportInfo
+	protocol string
+	statuses map[string]HostSummary
+	written  map[string]bool
+	host     *nmapHost
+	up, down int
+}
+
+// nmapXMLVersion is the nmap XML output format the writer follows.
+const nmapXMLVersion = "1.05"
+
+// NewNmapXMLWriter starts an nmap XML document on w.
+func NewNmapXMLWriter(w io.Writer, info ExportInfo) (*NmapXMLWriter, error) {
+	if _, err := io.WriteString(w, xml.Header+"<!DOCTYPE nmaprun>\n"); err != nil {
+		return nil, err
+	}
+	x := &NmapXMLWriter{
+		w:        w,
+		enc:      xml.NewEncoder(w),
+		info:     info,
+		protocol: info.protocol(),
+		statuses: make(map[string]HostSummary),
+		written:  make(map[string]bool),
+	}
+	for _, summary := range info.Hosts {
+		x.statuses[summary.Host] = summary
+	}
+	x.enc.Indent("", "  ")
+
+	start := xml.StartElement{Name: xml.Name{Local: "nmaprun"}, Attr: []xml.Attr{
+		{Name: xml.Name{Local: "scanner"}, Value: "cortex"},
+		{Name: xml.Name{Local: "args"}, Value: info.Args},
+		{Name: xml.Name{Local: "start"}, Value: strconv.FormatInt(info.Start.Unix(), 10)},
+		{Name: xml.Name{Local: "startstr"}, Value: info.Start.Format(time.ANSIC)},
+		{Name: xml.Name{Local: "version"}, Value: info.Version},
+		{Name: xml.Name{Local: "xmloutputversion"}, Value: nmapXMLVersion},
+	}}
+	if err := x.enc.EncodeToken(start); err != nil {
+		return nil, err
+	}
+	scanType := string(info.Mode)
+	if scanType == "" {
+		scanType = string(ModeConnect)
+	}
+	scanInfo := nmapScanInfo{Type: scanType, Protocol: x.protocol, NumServices: len(info.Ports), Services: FormatPortList(info.Ports)}
+	if err := x.enc.Encode(scanInfo); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// WriteResult adds result to the document.
+func (x *NmapXMLWriter) WriteResult(result ScanResult) error {
+	if x.host == nil || x.host.key != hostKey(result) {
+		if err := x.flushHost(); err != nil {
+			return err
+		}
+		x.host = x.newHost(result.Host, result.Address)
+		if result.ReverseHostname != "" {
+			x.host.Hostnames.Hostnames = append(x.host.Hostnames.Hostnames, nmapHostname{Name: result.ReverseHostname, Type: "PTR"})
+		}
+	}
+	x.host.Ports.Ports = append(x.host.Ports.Ports, x.port(result))
+	return nil
+}
+
+// Close lists the down hosts, writes the run statistics and ends the
+// document. It does not close the underlying writer.
+func (x *NmapXMLWriter) Close() error {
+	if err := x.flushHost(); err != nil {
+		return err
+	}
+	for _, summary := range x.info.Hosts {
+		if summary.Status == HostDown && !x.written[summary.Host] {
+			x.host = x.newHost(summary.Host, summary.Address)
+			if err := x.flushHost(); err != nil {
+				return err
+			}
+		}
+	}
+
+	end := x.info.end()
+	elapsed := end.Sub(x.info.Start).Seconds()
+	stats := nmapRunStats{
+		Finished: nmapFinished{
+			Time:    end.Unix(),
+			TimeStr: end.Format(time.ANSIC),
+			Elapsed: strconv.FormatFloat(elapsed, 'f', 2, 64),
+			Summary: fmt.Sprintf("Cortex done at %s; %d IP addresses (%d hosts up) scanned in %.2f seconds", end.Format(time.ANSIC), x.up+x.down, x.up, elapsed),
+			Exit:    "success",
+		},
+		Hosts: nmapHostStats{Up: x.up, Down: x.down, Total: x.up + x.down},
+	}
+	if err := x.enc.Encode(stats); err != nil {
+		return err
+	}
+	if err := x.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "nmaprun"}}); err != nil {
+		return err
+	}
+	if err := x.enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(x.w, "\n")
+	return err
+}
+
+// WriteNmapXML writes results as one nmap XML document, sorted by host.
+func WriteNmapXML(w io.Writer, results []ScanResult, info ExportInfo) error {
+	x, err := NewNmapXMLWriter(w, info)
+	if err != nil {
+		return err
+	}
+	for _, result := range sortedByHost(results) {
+		if err := x.WriteResult(result); err != nil {
+			return err
+		}
+	}
+	return x.Close()
+}
+
+func hostKey(result ScanResult) string {
+	return result.Host + "\x00" + result.Address
+}
+
+// newHost starts the host element of host, probed at address when set.
+func (x *NmapXMLWriter) newHost(host, address string) *nmapHost {
+	h := &nmapHost{key: host + "\x00" + address, name: host, Ports: &nmapPorts{}, Status: nmapStatus{State: "up", Reason: "user-set", ReasonTTL: "0"}}
+	if summary, ok := x.statuses[host]; ok && summary.Status != "" {
+		h.Status.State, h.Status.Reason = summary.Status, summary.StatusReason
+		if address == "" {
+			address = summary.Address
+		}
+	}
+	if address == "" && net.ParseIP(host) != nil {
+		address = host
+	}
+	if ip := net.ParseIP(address); ip != nil {
+		addrType := "ipv6"
+		if ip.To4() != nil {
+			addrType = "ipv4"
+		}
+		h.Addresses = append(h.Addresses, nmapAddress{Addr: address, AddrType: addrType})
+	}
+	if net.ParseIP(host) == nil {
+		h.Hostnames.Hostnames = append(h.Hostnames.Hostnames, nmapHostname{Name: host, Type: "user"})
+	}
+	return h
+}
+
+func (x *NmapXMLWriter) flushHost() error {
+	if x.host == nil {
+		return nil
+	}
+	host := x.host
+	x.host = nil
+	x.written[host.name] = true
+	if host.Status.State == HostDown {
+		x.down++
+	} else {
+		x.up++
+	}
+	if len(host.Ports.Ports) == 0 {
+		host.Ports = nil
+	}
+	return x.enc.Encode(host)
+}
+
+// serviceNamePattern matches the service names of probe rules, as opposed to
+// raw banners kept in ScanResult.Service when no rule matched.
+var serviceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.+/-]{0,31}$`)
+
+// serviceIdentified reports whether result.Service names the service a probe
+// rule matched rather than holding an unidentified banner.
+func serviceIdentified(result ScanResult) bool {
+	if result.Service == "" {
+		return false
+	}
+	return result.Product != "" || result.Version != "" || len(result.CPE) > 0 || serviceNamePattern.MatchString(result.Service)
+}
+
+// port converts result to a port element.
+func (x *NmapXMLWriter) port(result ScanResult) nmapPort {
+	port := nmapPort{Protocol: x.protocol, PortID: result.Port, State: nmapState{ReasonTTL: "0"}}
+	switch result.State {
+	case "Open", "Tarpit":
+		port.State.State, port.State.Reason = "open", "syn-ack"
+		if x.protocol == "udp" {
+			port.State.Reason = "udp-response"
+		}
+		if result.State == "Tarpit" {
+			port.State.Reason = "tarpit"
+		}
+	case "Closed":
+		port.State.State, port.State.Reason = "closed", "reset"
+		if x.protocol == "udp" {
+			port.State.Reason = "port-unreach"
+		}
+	case "Unfiltered":
+		port.State.State, port.State.Reason = "unfiltered", "reset"
+	default:
+		port.State.State, port.State.Reason = strings.ToLower(result.State), "no-response"
+	}
+
+	identified := serviceIdentified(result)
+	switch {
+	case identified:
+		service := &nmapService{Name: result.Service, Product: result.Product, Version: result.Version, ExtraInfo: result.Info,
+			Hostname: result.Hostname, OSType: result.OS, Method: "probed", Conf: 10, CPE: result.CPE}
+		if name, ok := strings.CutPrefix(result.Service, "ssl/"); ok {
+			service.Name, service.Tunnel = name, "ssl"
+		}
+		port.Service = service
+	case result.ServiceGuess != "":
+		port.Service = &nmapService{Name: result.ServiceGuess, Method: "table", Conf: 3}
+	}
+	if port.Service != nil && port.Service.Tunnel == "" && result.TLS == "implicit" && port.Service.Name != "ssl" {
+		port.Service.Tunnel = "ssl"
+	}
+
+	if !identified && result.Service != "" {
+		port.Scripts = append(port.Scripts, nmapScript{ID: "banner", Output: result.Service})
+	}
+	if cert := result.Certificate; cert != nil {
+		output := fmt.Sprintf("Subject: %s\nIssuer: %s\nNot valid before: %s\nNot valid after:  %s",
+			cert.Subject, cert.Issuer, cert.NotBefore.UTC().Format("2006-01-02T15:04:05"), cert.NotAfter.UTC().Format("2006-01-02T15:04:05"))
+		if len(cert.DNSNames) > 0 {
+			output += "\nSubject Alternative Name: DNS:" + strings.Join(cert.DNSNames, ", DNS:")
+		}
+		port.Scripts = append(port.Scripts, nmapScript{ID: "ssl-cert", Output: output})
+	}
+	for _, finding := range result.Findings {
+		port.Scripts = append(port.Scripts, nmapScript{ID: finding.Check + "-" + finding.Type, Output: fmt.Sprintf("[%s] %s", finding.Severity, finding.Summary)})
+	}
+	return port
+}
+
+type nmapScanInfo struct {
+	XMLName     xml.Name `xml:"scaninfo"`
+	Type        string   `xml:"type,attr"`
+	Protocol    string   `xml:"protocol,attr"`
+	NumServices int      `xml:"numservices,attr"`
+	Services    string   `xml:"services,attr"`
+}
+
+type nmapHost struct {
+	XMLName   xml.Name      `xml:"host"`
+	Status    nmapStatus    `xml:"status"`
+	Addresses []nmapAddress `xml:"address"`
+	Hostnames nmapHostnames `xml:"hostnames"`
+	Ports     *nmapPorts    `xml:"ports"`
+
+	key  string
+	name string
+}
+
+type nmapStatus struct {
+	State     string `xml:"state,attr"`
+	Reason    string `xml:"reason,attr"`
+	ReasonTTL string `xml:"reason_ttl,attr"`
+}
+
+type nmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+}
+
+type nmapHostnames struct {
+	Hostnames []nmapHostname `xml:"hostname"`
+}
+
+type nmapHostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type nmapPorts struct {
+	Ports []nmapPort `xml:"port"`
+}
+
+type nmapPort struct {
+	Protocol string       `xml:"protocol,attr"`
+	PortID   int          `xml:"portid,attr"`
+	State    nmapState    `xml:"state"`
+	Service  *nmapService `xml:"service"`
+	Scripts  []nmapScript `xml:"script"`
+}
+
+type nmapState struct {
+	State     string `xml:"state,attr"`
+	Reason    string `xml:"reason,attr"`
+	ReasonTTL string `xml:"reason_ttl,attr"`
+}
+
+type nmapService struct {
+	Name      string   `xml:"name,attr"`
+	Product   string   `xml:"product,attr,omitempty"`
+	Version   string   `xml:"version,attr,omitempty"`
+	ExtraInfo string   `xml:"extrainfo,attr,omitempty"`
+	Hostname  string   `xml:"hostname,attr,omitempty"`
+	OSType    string   `xml:"ostype,attr,omitempty"`
+	Tunnel    string   `xml:"tunnel,attr,omitempty"`
+	Method    string   `xml:"method,attr"`
+	Conf      int      `xml:"conf,attr"`
+	CPE       []string `xml:"cpe"`
+}
+
+type nmapScript struct {
+	ID     string `xml:"id,attr"`
+	Output string `xml:"output,attr"`
+}
+
+type nmapRunStats struct {
+	XMLName  xml.Name      `xml:"runstats"`
+	Finished nmapFinished  `xml:"finished"`
+	Hosts    nmapHostStats `xml:"hosts"`
+}
+
+type nmapFinished struct {
+	Time    int64  `xml:"time,attr"`
+	TimeStr string `xml:"timestr,attr"`
+	Elapsed string `xml:"elapsed,attr"`
+	Summary string `xml:"summary,attr"`
+	Exit    string `xml:"exit,attr"`
+}
+
+type nmapHostStats struct {
+	Up    int `xml:"up,attr"`
+	Down  int `xml:"down,attr"`
+	Total int `xml:"total,attr"`
+}
@@ -0,0 +1,68 @@
+package scanner
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+// captureSnapLen is the snapshot length written to the pcap header.
+const captureSnapLen = 65535
+
+// captureReadTimeout is how often a capture read loop checks whether to stop.
+const captureReadTimeout = 50 * time.Millisecond
+
+// packetRecorder appends probe traffic from every worker of a scan to one pcap
+// stream. Packets are stored from the IP header on (link type RAW) so sent
+// packets, which have no link layer, and captured ones can share a file.
+// A nil recorder records nothing. Write errors are ignored; a capture must
+// never change scan results.
+type packetRecorder struct {
+	mu     sync.Mutex
+	writer *pcapgo.Writer
+	err    error
+}
+
+// newPacketRecorder writes the pcap file header to w and returns a recorder,
+// or nil when w is nil.
+func newPacketRecorder(w io.Writer) *packetRecorder {
+	if w == nil {
+		return nil
+	}
+	writer := pcapgo.NewWriter(w)
+	return &packetRecorder{writer: writer, err: writer.WriteFileHeader(captureSnapLen, layers.LinkTypeRaw)}
+}
+
+// recordRaw stores a packet that starts with its IP header.
+func (r *packetRecorder) recordRaw(at time.Time, data []byte) {
+	if r == nil || len(data) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = r.writer.WritePacket(gopacket.CaptureInfo{Timestamp: at, CaptureLength: len(data), Length: len(data)}, data)
+}
+
+// recordPacket stores a captured packet without its link layer.
+func (r *packetRecorder) recordPacket(packet gopacket.Packet) {
+	if r == nil {
+		return
+	}
+	network := packet.NetworkLayer()
+	if network == nil {
+		return
+	}
+	data := packet.Data()
+	offset := len(data) - len(network.LayerContents()) - len(network.LayerPayload())
+	if offset < 0 {
+		return
+	}
+	r.recordRaw(packet.Metadata().Timestamp, data[offset:])
+}
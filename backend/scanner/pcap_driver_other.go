@@ -0,0 +1,12 @@
+//go:build !windows
+
+package scanner
+
+// checkPacketDriver is a no-op outside Windows: libpcap is linked at build time,
+// so its absence is reported by the compiler rather than at runtime.
+func checkPacketDriver() error {
+	return nil
+}
+
+// packetDriverHint explains how to fix device enumeration failures on Unix.
+const packetDriverHint = "run as root or grant the binary CAP_NET_RAW and CAP_NET_ADMIN"
@@ -0,0 +1,22 @@
+//go:build windows
+
+package scanner
+
+import (
+	"fmt"
+
+	"github.com/google/gopacket/pcap"
+)
+
+// checkPacketDriver verifies that Npcap is installed. On Windows the pcap
+// bindings load wpcap.dll at runtime, so a missing driver only surfaces as an
+// opaque load error unless it is checked up front.
+func checkPacketDriver() error {
+	if err := pcap.LoadWinPCAP(); err != nil {
+		return fmt.Errorf("SYN scan on Windows requires Npcap: install it from https://npcap.com with \"WinPcap API-compatible Mode\" enabled, then rerun as Administrator (%v)", err)
+	}
+	return nil
+}
+
+// packetDriverHint explains how to fix device enumeration failures on Windows.
+const packetDriverHint = "run as Administrator, or reinstall Npcap without \"Restrict Npcap driver's access to Administrators only\""
@@ -0,0 +1,131 @@
+package scanner
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PortSpec holds the ports an nmap-style port specification selects for each
+// protocol, ascending and de-duplicated.
+type PortSpec struct {
+	TCP []int
+	UDP []int
+}
+
+// ParsePortList parses a port expression such as "22,80,443,8000-8100" into
+// ascending, de-duplicated port numbers. Ranges may be open-ended as in
+// ParsePortSpec; whitespace is ignored.
+func ParsePortList(spec string) ([]int, error) {
+	selected := make(map[int]bool)
+	for _, part := range strings.Split(strings.ReplaceAll(spec, " ", ""), ",") {
+		if part == "" {
+			continue
+		}
+		if err := selectPorts(selected, part); err != nil {
+			return nil, err
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no ports in %q", spec)
+	}
+	return sortedPorts(selected), nil
+}
+
+// ParsePortSpec parses an nmap-style port specification: a comma-separated
+// list of ports and ranges such as "22,80,443,1000-1100", where a range may
+// leave out its start ("-1024") or end ("60000-") and "-" alone selects every
+// port. A "T:" or "U:" prefix limits the entries that follow to TCP or UDP, as
+// in "T:21-25,80,U:53,161"; entries before any prefix apply to both.
+// Whitespace is ignored.
+func ParsePortSpec(spec string) (PortSpec, error) {
+	selected := map[string]map[int]bool{"tcp": {}, "udp": {}}
+	protocols := []string{"tcp", "udp"}
+	for _, part := range strings.Split(strings.ReplaceAll(spec, " ", ""), ",") {
+		switch {
+		case strings.HasPrefix(strings.ToUpper(part), "T:"):
+			protocols, part = []string{"tcp"}, part[2:]
+		case strings.HasPrefix(strings.ToUpper(part), "U:"):
+			protocols, part = []string{"udp"}, part[2:]
+		}
+		if part == "" {
+			continue
+		}
+		for _, protocol := range protocols {
+			if err := selectPorts(selected[protocol], part); err != nil {
+				return PortSpec{}, err
+			}
+		}
+	}
+	if len(selected["tcp"]) == 0 && len(selected["udp"]) == 0 {
+		return PortSpec{}, fmt.Errorf("no ports in %q", spec)
+	}
+	return PortSpec{TCP: sortedPorts(selected["tcp"]), UDP: sortedPorts(selected["udp"])}, nil
+}
+
+// Ports returns the ports s selects for protocol (tcp or udp).
+func (s PortSpec) Ports(protocol string) []int {
+	if protocol == "udp" {
+		return s.UDP
+	}
+	return s.TCP
+}
+
+// FormatPortList writes ports as a port expression, collapsing consecutive
+// runs into ranges, so that ParsePortList reads back the same ports.
+func FormatPortList(ports []int) string {
+	sorted := append([]int(nil), ports...)
+	sort.Ints(sorted)
+	var parts []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] <= sorted[j]+1 {
+			j++
+		}
+		if sorted[j] == sorted[i] {
+			parts = append(parts, strconv.Itoa(sorted[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// selectPorts adds the port or range part names to selected. Open-ended
+// ranges reach down to 1 or up to 65535.
+func selectPorts(selected map[int]bool, part string) error {
+	first, last, isRange := strings.Cut(part, "-")
+	start, end := 1, 65535
+	var err error
+	if first != "" || !isRange {
+		if start, err = strconv.Atoi(first); err != nil {
+			return fmt.Errorf("invalid port %q", part)
+		}
+	}
+	if !isRange {
+		end = start
+	} else if last != "" {
+		if end, err = strconv.Atoi(last); err != nil {
+			return fmt.Errorf("invalid port range %q", part)
+		}
+	}
+	if start < 1 || end > 65535 || start > end {
+		return fmt.Errorf("invalid port range %q: ports must be between 1 and 65535, start first", part)
+	}
+	for port := start; port <= end; port++ {
+		selected[port] = true
+	}
+	return nil
+}
+
+// sortedPorts returns the ports in selected in ascending order.
+func sortedPorts(selected map[int]bool) []int {
+	ports := make([]int, 0, len(selected))
+	for port := range selected {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	return ports
+}
@@ -0,0 +1,104 @@
+package scanner
+
+import (
+	"bytes"
+	"regexp/syntax"
+	"unicode/utf8"
+)
+
+// Most nmap match rules embed a fixed string every matching response must
+// contain, such as "SSH-" or "220 " followed by a product name. Checking that
+// literal with bytes.Contains costs far less than running the regex, and on a
+// typical response almost every rule of a probe fails it, so detection only
+// runs the few regexes that can still match.
+
+// requiredLiteral returns the longest byte string that every match of pattern
+// contains, and whether it must be compared against an ASCII-lowercased
+// response because the pattern ignores case there. It returns nil when no
+// such literal can be derived, and the rule is then always evaluated.
+func requiredLiteral(pattern string) (literal []byte, fold bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, false
+	}
+	return longestLiteral(re.Simplify())
+}
+
+// longestLiteral walks the parts of re that every match must go through.
+// Alternations and optional parts are not descended into.
+func longestLiteral(re *syntax.Regexp) ([]byte, bool) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		fold := re.Flags&syntax.FoldCase != 0
+		return literalBytes(re.Rune, fold), fold
+	case syntax.OpCapture, syntax.OpPlus:
+		return longestLiteral(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min >= 1 {
+			return longestLiteral(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		var best []byte
+		var bestFold bool
+		for _, sub := range re.Sub {
+			if literal, fold := longestLiteral(sub); len(literal) > len(best) {
+				best, bestFold = literal, fold
+			}
+		}
+		return best, bestFold
+	}
+	return nil, false
+}
+
+// literalBytes returns the longest run of runes that bytes.Contains can look
+// for without missing a match. Go regexps match U+FFFD against any invalid
+// byte, so it ends a run. Case-insensitive runs are limited to ASCII, and to
+// letters other than k and s, which also fold to the Kelvin and long s signs.
+func literalBytes(runes []rune, fold bool) []byte {
+	var best, run []byte
+	for _, r := range runes {
+		usable := r != utf8.RuneError
+		if fold {
+			usable = r < utf8.RuneSelf && r != 'k' && r != 'K' && r != 's' && r != 'S'
+		}
+		if !usable {
+			run = nil
+			continue
+		}
+		if fold {
+			run = append(run, asciiLower(byte(r)))
+		} else {
+			run = utf8.AppendRune(run, r)
+		}
+		if len(run) > len(best) {
+			best = append(best[:0], run...)
+		}
+	}
+	return best
+}
+
+// mayMatch reports whether response contains the required literal of m, so
+// that its regex is worth running. lowered is the ASCII-lowercased response,
+// computed on first use and shared by the rules of one probe.
+func (m *Match) mayMatch(response []byte, lowered *[]byte) bool {
+	if len(m.literal) == 0 {
+		return true
+	}
+	if !m.foldLiteral {
+		return bytes.Contains(response, m.literal)
+	}
+	if *lowered == nil {
+		*lowered = make([]byte, len(response))
+		for i, b := range response {
+			(*lowered)[i] = asciiLower(b)
+		}
+	}
+	return bytes.Contains(*lowered, m.literal)
+}
+
+func asciiLower(b byte) byte {
+	if b >= 'A' && b <= 'Z' {
+		return b + ('a' - 'A')
+	}
+	return b
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -16,13 +17,28 @@ type Probe struct {
 	Name     string  // Probe name, e.g. "GetRequest"
 	Data     []byte  // Data to send to the server
 	Matches  []Match // List of patterns to match in response
+	Ports    []int   // Ports the probe is registered for, ascending (ports directive)
+	SSLPorts []int   // Ports where the probe is registered for TLS-wrapped services (sslports directive)
+	Rarity   int     // 1 (almost always useful) to 9 (rarely useful); DefaultProbeRarity when absent
 }
 
+// Version intensity bounds, see ProbeCache.TCPProbesFor.
+const (
+	DefaultVersionIntensity = 7
+	MaxVersionIntensity     = 9
+	// DefaultProbeRarity is the rarity of probes without a rarity directive.
+	DefaultProbeRarity = 5
+)
+
 // Match represents a single service detection rule.
 type Match struct {
 	ServiceName string            // Service name, e.g. "http"
 	Pattern     *regexp.Regexp    // Compiled regex pattern to match
-	VersionInfo map[string]string // Additional version information
+	VersionInfo map[string]string // Version templates keyed by field letter (p, v, i, h, o, d)
+	CPE         []string          // CPE templates in file order, without the cpe:/ prefix
+
+	literal     []byte // Substring every match contains, checked before the regex runs
+	foldLiteral bool   // literal is lowercase and compared against the lowercased response
 }
 
 // ParseError stores information about a parsing error on a specific line.
@@ -33,10 +49,11 @@ type ParseError struct {
 
 // LoadStats stores statistics about the probe loading process.
 type LoadStats struct {
-	TotalLines int
-	ProbeCount int
-	MatchCount int
-	ErrorLines []ParseError
+	TotalLines     int
+	ProbeCount     int
+	MatchCount     int
+	SkippedMatches int // Valid nmap patterns skipped because Go's RE2 engine cannot compile them
+	ErrorLines     []ParseError
 }
 
 // LoadProbes reads and parses probe definitions from a file.
@@ -87,6 +104,7 @@ func LoadProbes(filePath string) ([]Probe, LoadStats, error) {
 				if errors.As(err, &unsupportedErr) {
 					// Silently skip unsupported Perl regex patterns
 					// These are valid in nmap but not supported by Go's RE2 engine
+					stats.SkippedMatches++
 					continue
 				}
 				// Real parsing error - log it
@@ -96,12 +114,19 @@ func LoadProbes(filePath string) ([]Probe, LoadStats, error) {
 			currentProbe.Matches = append(currentProbe.Matches, match)
 			stats.MatchCount++
 
+		} else if directive, value, ok := strings.Cut(line, " "); ok && isSchedulingDirective(directive) {
+			if currentProbe == nil {
+				stats.ErrorLines = append(stats.ErrorLines, ParseError{stats.TotalLines, directive + " found without preceding Probe"})
+				continue
+			}
+			if err := currentProbe.setSchedulingDirective(directive, strings.TrimSpace(value)); err != nil {
+				stats.ErrorLines = append(stats.ErrorLines, ParseError{stats.TotalLines, fmt.Sprintf("%s parse error: %v", directive, err)})
+			}
+
 		} else if isKnownDirective(line) {
 			// Known directives that we currently ignore (not counted as errors)
 			// These directives are valid but not used in our implementation:
 			// - softmatch: Fuzzy service matching (we use only strict 'match')
-			// - ports/sslports: Port hints (we scan all specified ports)
-			// - rarity: Probe rarity level (we try all probes sequentially)
 			// - fallback: Fallback probe name (not implemented)
 			// - Exclude: Port exclusion (not implemented)
 			// - totalwaitms/tcpwrappedms: Global timeouts (we use fixed timeouts)
@@ -128,9 +153,6 @@ func LoadProbes(filePath string) ([]Probe, LoadStats, error) {
 func isKnownDirective(line string) bool {
 	knownDirectives := []string{
 		"softmatch",       // Fuzzy matching rules
-		"ports",           // Port hints for this probe
-		"sslports",        // SSL port hints
-		"rarity",          // Probe rarity (1-9, higher = more rare)
 		"fallback",        // Fallback probe name
 		"Exclude",         // Exclude specific ports
 		"totalwaitms",     // Global wait timeout
@@ -145,6 +167,45 @@ func isKnownDirective(line string) bool {
 	return false
 }
 
+// isSchedulingDirective reports whether directive is one of the probe
+// directives that decide which ports a probe is sent to.
+func isSchedulingDirective(directive string) bool {
+	return directive == "ports" || directive == "sslports" || directive == "rarity"
+}
+
+// setSchedulingDirective applies a ports, sslports or rarity line to p.
+func (p *Probe) setSchedulingDirective(directive, value string) error {
+	if directive == "rarity" {
+		rarity, err := strconv.Atoi(value)
+		if err != nil || rarity < 1 || rarity > MaxVersionIntensity {
+			return fmt.Errorf("rarity %q must be between 1 and %d", value, MaxVersionIntensity)
+		}
+		p.Rarity = rarity
+		return nil
+	}
+	ports, err := ParsePortList(value)
+	if err != nil {
+		return err
+	}
+	if directive == "ports" {
+		p.Ports = ports
+	} else {
+		p.SSLPorts = ports
+	}
+	return nil
+}
+
+// registeredFor reports whether p is registered for port by its ports or
+// sslports directive.
+func (p *Probe) registeredFor(port int) bool {
+	for _, ports := range [][]int{p.Ports, p.SSLPorts} {
+		if i := sort.SearchInts(ports, port); i < len(ports) && ports[i] == port {
+			return true
+		}
+	}
+	return false
+}
+
 // parseProbe parses a line like:
 // Probe TCP GetRequest q|GET / HTTP/1.0\r\n\r\n|
 func parseProbe(line string) (Probe, error) {
@@ -170,6 +231,7 @@ func parseProbe(line string) (Probe, error) {
 		Name:     name,
 		Data:     data,
 		Matches:  []Match{},
+		Rarity:   DefaultProbeRarity,
 	}, nil
 }
 
@@ -359,14 +421,15 @@ func parseMatch(line string) (Match, error) {
 	}
 
 	pattern := patternParts[0]
-	flagsAndVersion := patternParts[1]
+	// Flags directly follow the closing separator; version fields come after a space
+	flags, versionFields, _ := strings.Cut(patternParts[1], " ")
 
 	// Build regex with flags if present
 	regexStr := pattern
-	if strings.Contains(flagsAndVersion, "i") {
+	if strings.Contains(flags, "i") {
 		regexStr = "(?i)" + regexStr
 	}
-	if strings.Contains(flagsAndVersion, "s") {
+	if strings.Contains(flags, "s") {
 		regexStr = "(?s)" + regexStr
 	}
 
@@ -386,14 +449,126 @@ func parseMatch(line string) (Match, error) {
 		return Match{}, fmt.Errorf("cannot compile regex '%s': %w", regexStr, err)
 	}
 
-	// TODO: Parse version information (p/v/i/o) in future implementation
+	literal, foldLiteral := requiredLiteral(regexStr)
+	versionInfo, cpes := parseVersionFields(versionFields)
 	return Match{
 		ServiceName: serviceName,
 		Pattern:     regex,
-		VersionInfo: make(map[string]string),
+		VersionInfo: versionInfo,
+		CPE:         cpes,
+		literal:     literal,
+		foldLiteral: foldLiteral,
 	}, nil
 }
 
+// parseVersionFields splits the version part of a match line, such as
+// "p/OpenSSH/ v/$1/ cpe:/a:openbsd:openssh:$1/", into templates keyed by
+// field letter and the CPE templates, of which a line may have several. Each
+// field may use its own delimiter.
+func parseVersionFields(s string) (map[string]string, []string) {
+	fields := make(map[string]string)
+	var cpes []string
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return fields, cpes
+		}
+		key := s[:1]
+		if strings.HasPrefix(s, "cpe:") {
+			key = "cpe:"
+		}
+		s = s[len(key):]
+		if s == "" {
+			return fields, cpes
+		}
+		delimiter := s[:1]
+		value, rest, found := strings.Cut(s[1:], delimiter)
+		if !found {
+			return fields, cpes
+		}
+		if key == "cpe:" {
+			cpes = append(cpes, value)
+		} else {
+			fields[key] = value
+		}
+		// Skip field flags such as the "a" after a CPE
+		if i := strings.IndexByte(rest, ' '); i >= 0 {
+			rest = rest[i:]
+		} else {
+			rest = ""
+		}
+		s = rest
+	}
+}
+
+// versionTemplateRef matches the $N and $P(N) substitutions of version templates.
+var versionTemplateRef = regexp.MustCompile(`\$P\((\d)\)|\$(\d)`)
+
+// VersionDetails is what a match rule's version fields say about a service.
+type VersionDetails struct {
+	Product  string   // p/ field
+	Version  string   // v/ field
+	Info     string   // i/ field, extra details such as "protocol 2.0"
+	Hostname string   // h/ field, the host name the service reported
+	OS       string   // o/ field
+	CPE      []string // cpe:/ fields, with the cpe:/ prefix
+}
+
+// Version expands the product and version templates of m with the submatches
+// of response.
+func (m Match) Version(response []byte) (product, version string) {
+	details := m.Details(response)
+	return details.Product, details.Version
+}
+
+// Details expands every version template of m with the submatches of
+// response. A template that uses helpers other than $N and $P(N) is dropped
+// rather than reported half-expanded.
+func (m Match) Details(response []byte) VersionDetails {
+	submatches := m.Pattern.FindSubmatch(response)
+	if submatches == nil {
+		return VersionDetails{}
+	}
+	expand := func(template string) string {
+		if strings.Contains(versionTemplateRef.ReplaceAllString(template, ""), "$") {
+			return ""
+		}
+		expanded := versionTemplateRef.ReplaceAllStringFunc(template, func(ref string) string {
+			parts := versionTemplateRef.FindStringSubmatch(ref)
+			index := parts[1] + parts[2]
+			n := int(index[0] - '0')
+			if n >= len(submatches) {
+				return ""
+			}
+			value := string(submatches[n])
+			if parts[1] != "" {
+				value = strings.Map(func(r rune) rune {
+					if r < 0x20 || r > 0x7e {
+						return -1
+					}
+					return r
+				}, value)
+			}
+			return value
+		})
+		return strings.TrimSpace(expanded)
+	}
+	details := VersionDetails{
+		Product:  expand(m.VersionInfo["p"]),
+		Version:  expand(m.VersionInfo["v"]),
+		Info:     expand(m.VersionInfo["i"]),
+		Hostname: expand(m.VersionInfo["h"]),
+		OS:       expand(m.VersionInfo["o"]),
+	}
+	for _, template := range m.CPE {
+		// An empty substitution at the end leaves a dangling separator
+		if cpe := strings.TrimRight(expand(template), ":"); cpe != "" {
+			details.CPE = append(details.CPE, "cpe:/"+cpe)
+		}
+	}
+	return details
+}
+
 // UnsupportedRegexError indicates a Perl regex feature not supported by Go
 type UnsupportedRegexError struct {
 	Pattern string
@@ -430,12 +605,33 @@ func containsUnsupportedRegex(pattern string) bool {
 	return false
 }
 
+// FindMatch returns the first match rule of p that recognizes response, in
+// file order, or nil when none does. Rules whose required literal is missing
+// from response are skipped without running their regex.
+func (p *Probe) FindMatch(response []byte) *Match {
+	var lowered []byte
+	for i := range p.Matches {
+		if p.Matches[i].mayMatch(response, &lowered) && p.Matches[i].Pattern.Match(response) {
+			return &p.Matches[i]
+		}
+	}
+	return nil
+}
+
+// Matches reports whether m recognizes response, applying the same literal
+// prefilter as FindMatch.
+func (m *Match) Matches(response []byte) bool {
+	var lowered []byte
+	return m.mayMatch(response, &lowered) && m.Pattern.Match(response)
+}
+
 // ProbeCache caches loaded probes for fast access
 type ProbeCache struct {
 	allProbes   []Probe
 	tcpProbes   []Probe
 	udpProbes   []Probe
 	probeLookup map[string][]Probe // by probe name
+	sslPorts    map[int]bool       // ports any TCP probe lists in sslports
 }
 
 // NewProbeCache creates and initializes probe cache
@@ -443,11 +639,15 @@ func NewProbeCache(probes []Probe) *ProbeCache {
 	cache := &ProbeCache{
 		allProbes:   probes,
 		probeLookup: make(map[string][]Probe),
+		sslPorts:    make(map[int]bool),
 	}
 
 	for _, probe := range probes {
 		if probe.Protocol == "TCP" {
 			cache.tcpProbes = append(cache.tcpProbes, probe)
+			for _, port := range probe.SSLPorts {
+				cache.sslPorts[port] = true
+			}
 		} else if probe.Protocol == "UDP" {
 			cache.udpProbes = append(cache.udpProbes, probe)
 		}
@@ -462,6 +662,55 @@ func (pc *ProbeCache) GetTCPProbes() []Probe {
 	return pc.tcpProbes
 }
 
+// TCPProbesFor returns the TCP probes to try against port, in the order nmap
+// tries them: the NULL probe first, then the probes registered for port by
+// their ports or sslports directive, then the remaining probes whose rarity
+// does not exceed intensity (0-9). Registered probes are tried whatever their
+// rarity; ties keep file order.
+func (pc *ProbeCache) TCPProbesFor(port, intensity int) []Probe {
+	ordered := make([]Probe, 0, len(pc.tcpProbes))
+	for _, probe := range pc.tcpProbes {
+		if probe.Name == "NULL" {
+			ordered = append(ordered, probe)
+		}
+	}
+	for _, probe := range pc.tcpProbes {
+		if probe.Name != "NULL" && probe.registeredFor(port) {
+			ordered = append(ordered, probe)
+		}
+	}
+	var rest []Probe
+	for _, probe := range pc.tcpProbes {
+		if probe.Name != "NULL" && !probe.registeredFor(port) && probe.Rarity <= intensity {
+			rest = append(rest, probe)
+		}
+	}
+	sort.SliceStable(rest, func(i, j int) bool { return rest[i].Rarity < rest[j].Rarity })
+	return append(ordered, rest...)
+}
+
+// sslPort reports whether a TCP probe's sslports directive lists port, so
+// that a TLS service is expected there.
+func (pc *ProbeCache) sslPort(port int) bool {
+	return pc.sslPorts[port]
+}
+
+// UDPProbesFor returns the UDP probes registered for port by their ports
+// directive, in file order. Unlike TCP probes they are never sent to other
+// ports: every silent UDP probe costs a full timeout.
+func (pc *ProbeCache) UDPProbesFor(port int) []Probe {
+	if pc == nil {
+		return nil
+	}
+	var probes []Probe
+	for _, probe := range pc.udpProbes {
+		if probe.registeredFor(port) {
+			probes = append(probes, probe)
+		}
+	}
+	return probes
+}
+
 // GetUDPProbes returns all UDP probes
 func (pc *ProbeCache) GetUDPProbes() []Probe {
 	return pc.udpProbes
@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Pacer paces probes against a limit shared with other scans, such as a rate
+// budget shared by every worker node. Wait blocks until one more probe may be
+// sent; implementations must be safe for concurrent use.
+type Pacer interface {
+	Wait()
+}
+
+// rateLimiter paces callers to a fixed number of events per second.
+// A nil rateLimiter imposes no limit.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter returns a limiter allowing perSecond events per second,
+// or nil when perSecond is not positive.
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait blocks until the caller may send its next probe. It returns false as
+// soon as ctx is done, in which case the probe must not be sent.
+func (rl *rateLimiter) wait(ctx context.Context) bool {
+	if rl == nil {
+		return ctx.Err() == nil
+	}
+
+	rl.mu.Lock()
+	now := time.Now()
+	if rl.next.Before(now) {
+		rl.next = now
+	}
+	delay := rl.next.Sub(now)
+	rl.next = rl.next.Add(rl.interval)
+	rl.mu.Unlock()
+
+	return sleepContext(ctx, delay)
+}
+
+// sleepContext waits for delay to pass and reports whether it did before ctx
+// was done.
+func sleepContext(ctx context.Context, delay time.Duration) bool {
+	if delay <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// hostRateLimiters keeps an independent limiter for every target host.
+type hostRateLimiters struct {
+	mu        sync.Mutex
+	perSecond float64
+	limiters  map[string]*rateLimiter
+}
+
+func newHostRateLimiters(perSecond float64) *hostRateLimiters {
+	return &hostRateLimiters{perSecond: perSecond, limiters: make(map[string]*rateLimiter)}
+}
+
+// wait blocks until another probe may be sent to host, returning false when
+// ctx is done first.
+func (h *hostRateLimiters) wait(ctx context.Context, host string) bool {
+	if h.perSecond <= 0 {
+		return ctx.Err() == nil
+	}
+
+	h.mu.Lock()
+	rl, ok := h.limiters[host]
+	if !ok {
+		rl = newRateLimiter(h.perSecond)
+		h.limiters[host] = rl
+	}
+	h.mu.Unlock()
+
+	return rl.wait(ctx)
+}
@@ -0,0 +1,304 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RDAPURL is the RDAP bootstrap redirector queried for IP ownership.
+// It forwards each query to the registry responsible for the address.
+const RDAPURL = "https://rdap.org"
+
+// rdapTimeout bounds a single RDAP lookup including redirects.
+const rdapTimeout = 10 * time.Second
+
+// rdapConcurrency caps parallel RDAP lookups; registries rate limit aggressively.
+const rdapConcurrency = 4
+
+// rdapResponseLimit caps how much of an RDAP response is decoded.
+const rdapResponseLimit = 1 << 20
+
+// HostSummary describes a scanned host as a whole rather than a single port.
+type HostSummary struct {
+	// Host is the target as submitted.
+	Host string `json:"host" example:"scanme.nmap.org" description:"Target host as submitted."`
+	// Address is the IP the summary refers to.
+	Address string `json:"address,omitempty" example:"45.33.32.156" description:"IP address the ownership information was looked up for."`
+	// Owner holds registry data about the network containing Address.
+	Owner *NetworkOwner `json:"owner,omitempty" description:"Registry data about the network containing the address. Absent for private addresses or when the lookup failed."`
+	// Tags are the labels a target manifest gave the host.
+	Tags []string `json:"tags,omitempty" example:"web,prod" description:"Labels the host was given in the target manifest."`
+	// Tarpit explains why the host looks like a tarpit or honeypot.
+	Tarpit string `json:"tarpit,omitempty" example:"982 of 1000 probed ports report open" description:"Why the host is likely a tarpit or honeypot whose open ports cannot be trusted. Set only when tarpit detection was requested and the host was flagged."`
+	// Status tells whether host discovery found the host up or down.
+	Status string `json:"status,omitempty" enums:"up,down" example:"up" description:"Whether the host answered the discovery pings that ran before the port scan. Down hosts were not port scanned. Set only when discovery was requested."`
+	// StatusReason names the ping that decided Status.
+	StatusReason string `json:"status_reason,omitempty" example:"tcp-443" description:"What decided the status: arp-response, echo-reply or tcp-<port> for hosts that are up, no-response or unresolved for hosts that are down."`
+}
+
+// NetworkOwner is the ownership information an RDAP registry publishes for an
+// IP network.
+type NetworkOwner struct {
+	// Netname is the registry name of the network.
+	Netname string `json:"netname,omitempty" example:"LINODE-US" description:"Network name registered for the address block."`
+	// Range is the address block the network covers.
+	Range string `json:"range,omitempty" example:"45.33.0.0 - 45.33.127.255" description:"First and last address of the registered block."`
+	// Organization is the registrant of the network.
+	Organization string `json:"organization,omitempty" example:"Linode" description:"Organization the block is registered to."`
+	// AbuseContact is the e-mail address for abuse reports.
+	AbuseContact string `json:"abuse_contact,omitempty" example:"abuse@linode.com" description:"E-mail address the registry lists for abuse reports about the block."`
+}
+
+// rdapClient looks up network ownership and caches every answer for the
+// whole address range it covers, so scanning a block asks the registry once
+// per network rather than once per address.
+type rdapClient struct {
+	baseURL  string
+	http     *http.Client
+	mu       sync.Mutex
+	networks []rdapCachedNetwork
+	pending  map[string]chan struct{}
+}
+
+// rdapCachedNetwork is the owner of the addresses from start to end, both in
+// their 16-byte form.
+type rdapCachedNetwork struct {
+	start, end net.IP
+	owner      *NetworkOwner
+}
+
+func newRDAPClient() *rdapClient {
+	return &rdapClient{
+		baseURL: RDAPURL,
+		http:    &http.Client{Timeout: rdapTimeout},
+		pending: make(map[string]chan struct{}),
+	}
+}
+
+// summarizeHosts builds one summary per distinct host and address in results
+// and attaches RDAP ownership data for public addresses.
+func summarizeHosts(ctx context.Context, results []ScanResult, resolver *resolverCache, client *rdapClient) []HostSummary {
+	seen := make(map[[2]string]bool)
+	var summaries []HostSummary
+	for _, result := range results {
+		key := [2]string{result.Host, result.Address}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		summary := HostSummary{Host: result.Host, Address: result.Address}
+		if summary.Address == "" {
+			if address, err := resolver.dialAddress(result.Host, 0); err == nil {
+				summary.Address, _, _ = net.SplitHostPort(address)
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Host != summaries[j].Host {
+			return summaries[i].Host < summaries[j].Host
+		}
+		return summaries[i].Address < summaries[j].Address
+	})
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, rdapConcurrency)
+	for i := range summaries {
+		address := net.ParseIP(summaries[i].Address)
+		if address == nil || !isPublicAddress(address) {
+			continue
+		}
+		wg.Add(1)
+		go func(summary *HostSummary) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-slots }()
+			summary.Owner, _ = client.lookup(ctx, address)
+		}(&summaries[i])
+	}
+	wg.Wait()
+	return summaries
+}
+
+// isPublicAddress reports whether address is globally routable, so that
+// private and special-purpose ranges are never sent to a registry.
+func isPublicAddress(address net.IP) bool {
+	if !address.IsGlobalUnicast() || address.IsPrivate() {
+		return false
+	}
+	// Carrier-grade NAT space is not covered by IsPrivate
+	return !carrierGradeNAT.Contains(address)
+}
+
+var carrierGradeNAT = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// lookup returns the owner of the network containing address. While a query
+// for an address of the same /24 (IPv4) or /48 (IPv6) is in flight, lookup
+// waits for its answer, which most likely covers address too, before asking
+// the registry itself.
+func (c *rdapClient) lookup(ctx context.Context, address net.IP) (*NetworkOwner, error) {
+	block := rdapBlock(address)
+	for {
+		c.mu.Lock()
+		if owner, ok := c.cachedLocked(address); ok {
+			c.mu.Unlock()
+			return owner, nil
+		}
+		inFlight, busy := c.pending[block]
+		if !busy {
+			done := make(chan struct{})
+			c.pending[block] = done
+			c.mu.Unlock()
+			owner, err := c.query(ctx, address)
+			c.mu.Lock()
+			delete(c.pending, block)
+			c.mu.Unlock()
+			close(done)
+			return owner, err
+		}
+		c.mu.Unlock()
+		select {
+		case <-inFlight:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// rdapBlock names the block of address whose lookups wait for each other.
+func rdapBlock(address net.IP) string {
+	if v4 := address.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return address.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// cachedLocked returns the cached owner of the network containing address.
+func (c *rdapClient) cachedLocked(address net.IP) (*NetworkOwner, bool) {
+	address = address.To16()
+	for _, network := range c.networks {
+		if bytes.Compare(address, network.start) >= 0 && bytes.Compare(address, network.end) <= 0 {
+			return network.owner, true
+		}
+	}
+	return nil, false
+}
+
+// query asks the registry for the network containing address and caches the
+// answer for the range it reports, or for address alone when it reports none.
+func (c *rdapClient) query(ctx context.Context, address net.IP) (*NetworkOwner, error) {
+	key := address.String()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/ip/"+key, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/rdap+json")
+	response, err := c.http.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("RDAP lookup for %s returned %s", address, response.Status)
+	}
+
+	var network rdapNetwork
+	if err := json.NewDecoder(io.LimitReader(response.Body, rdapResponseLimit)).Decode(&network); err != nil {
+		return nil, err
+	}
+	owner := network.owner()
+
+	cached := rdapCachedNetwork{start: address.To16(), end: address.To16(), owner: owner}
+	start, end := net.ParseIP(network.StartAddress).To16(), net.ParseIP(network.EndAddress).To16()
+	if start != nil && end != nil && bytes.Compare(start, address.To16()) <= 0 && bytes.Compare(address.To16(), end) <= 0 {
+		cached.start, cached.end = start, end
+	}
+	c.mu.Lock()
+	c.networks = append(c.networks, cached)
+	c.mu.Unlock()
+	return owner, nil
+}
+
+// rdapNetwork is the subset of an RDAP IP network object (RFC 9083) used here.
+type rdapNetwork struct {
+	Name         string       `json:"name"`
+	StartAddress string       `json:"startAddress"`
+	EndAddress   string       `json:"endAddress"`
+	Entities     []rdapEntity `json:"entities"`
+}
+
+type rdapEntity struct {
+	Roles      []string          `json:"roles"`
+	VCardArray []json.RawMessage `json:"vcardArray"`
+	Entities   []rdapEntity      `json:"entities"`
+}
+
+func (n rdapNetwork) owner() *NetworkOwner {
+	owner := &NetworkOwner{Netname: n.Name}
+	if n.StartAddress != "" && n.EndAddress != "" {
+		owner.Range = n.StartAddress + " - " + n.EndAddress
+	}
+	// Registries nest the abuse contact inside the registrant entity (ARIN)
+	// or list it alongside (RIPE), so walk the whole tree
+	var walk func(entities []rdapEntity)
+	walk = func(entities []rdapEntity) {
+		for _, entity := range entities {
+			name, email := entity.vcard()
+			for _, role := range entity.Roles {
+				switch role {
+				case "registrant":
+					if owner.Organization == "" {
+						owner.Organization = name
+					}
+				case "abuse":
+					if owner.AbuseContact == "" {
+						owner.AbuseContact = email
+					}
+				}
+			}
+			walk(entity.Entities)
+		}
+	}
+	walk(n.Entities)
+	return owner
+}
+
+// vcard extracts the formatted name and first e-mail address from a jCard
+// (RFC 7095): ["vcard", [[name, params, type, value], ...]].
+func (e rdapEntity) vcard() (name, email string) {
+	if len(e.VCardArray) < 2 {
+		return "", ""
+	}
+	var properties [][]json.RawMessage
+	if err := json.Unmarshal(e.VCardArray[1], &properties); err != nil {
+		return "", ""
+	}
+	for _, property := range properties {
+		if len(property) < 4 {
+			continue
+		}
+		var key, value string
+		if json.Unmarshal(property[0], &key) != nil || json.Unmarshal(property[3], &value) != nil {
+			continue
+		}
+		switch {
+		case key == "fn" && name == "":
+			name = value
+		case key == "email" && email == "":
+			email = value
+		}
+	}
+	return name, email
+}
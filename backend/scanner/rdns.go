@@ -0,0 +1,107 @@
+package scanner
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ptrTimeout bounds a single reverse DNS lookup.
+const ptrTimeout = 3 * time.Second
+
+// ptrConcurrency caps parallel reverse DNS lookups so that large scans do
+// not flood the configured name servers.
+const ptrConcurrency = 16
+
+// ptrResolver looks up and caches the PTR names of addresses for one scan.
+// Failed and empty lookups are cached too, so every address is asked once.
+type ptrResolver struct {
+	resolver *net.Resolver
+	mu       sync.Mutex
+	cache    map[string]string
+}
+
+func newPTRResolver(servers []string) *ptrResolver {
+	return &ptrResolver{resolver: newDNSResolver(servers), cache: make(map[string]string)}
+}
+
+// resolveReverseNames sets ReverseHostname on every result with an IP
+// address: the probed Address, or the host when it was given as an address.
+// Hostnames probed on their single resolved address keep the name they were
+// submitted under and are not looked up.
+func resolveReverseNames(ctx context.Context, results []ScanResult, resolver *ptrResolver) {
+	var addresses []string
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if address := ptrAddress(result); address != "" && !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, ptrConcurrency)
+	for _, address := range addresses {
+		wg.Add(1)
+		go func(address string) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-slots }()
+			resolver.lookup(ctx, address)
+		}(address)
+	}
+	wg.Wait()
+
+	for i := range results {
+		if address := ptrAddress(results[i]); address != "" {
+			results[i].ReverseHostname = resolver.cached(address)
+		}
+	}
+}
+
+// ptrAddress returns the address of result to look up, or "" for none.
+func ptrAddress(result ScanResult) string {
+	if result.Address != "" {
+		return result.Address
+	}
+	if net.ParseIP(result.Host) != nil {
+		return result.Host
+	}
+	return ""
+}
+
+// lookup resolves the first PTR name of address unless it is cached.
+func (r *ptrResolver) lookup(ctx context.Context, address string) {
+	r.mu.Lock()
+	_, ok := r.cache[address]
+	r.mu.Unlock()
+	if ok {
+		return
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, ptrTimeout)
+	defer cancel()
+	name := ""
+	if names, err := r.resolver.LookupAddr(lookupCtx, address); err == nil && len(names) > 0 {
+		name = strings.TrimSuffix(names[0], ".")
+	} else if ctx.Err() != nil {
+		// Cancelled scans must not cache the address as nameless
+		return
+	}
+
+	r.mu.Lock()
+	r.cache[address] = name
+	r.mu.Unlock()
+}
+
+func (r *ptrResolver) cached(address string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cache[address]
+}
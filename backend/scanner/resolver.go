@@ -0,0 +1,252 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// AddressPreference selects which resolved addresses of a dual-stack
+// hostname are scanned.
+type AddressPreference string
+
+// Supported address preferences. The empty preference behaves like
+// PreferIPv4, except that with AllAddresses it keeps every address.
+const (
+	// PreferIPv4 scans the IPv4 address of a hostname when it has one.
+	PreferIPv4 AddressPreference = "ipv4"
+	// PreferIPv6 scans the IPv6 address of a hostname when it has one.
+	PreferIPv6 AddressPreference = "ipv6"
+	// PreferBoth scans one address of each family a hostname has.
+	PreferBoth AddressPreference = "both"
+)
+
+// Address families reported in ScanResult.Family.
+const (
+	familyIPv4 = "ipv4"
+	familyIPv6 = "ipv6"
+)
+
+// ParseAddressPreference converts a preference name into an
+// AddressPreference. An empty name selects the default.
+func ParseAddressPreference(name string) (AddressPreference, error) {
+	switch prefer := AddressPreference(strings.ToLower(strings.TrimSpace(name))); prefer {
+	case "", PreferIPv4, PreferIPv6, PreferBoth:
+		return prefer, nil
+	default:
+		return "", fmt.Errorf("unknown address preference %q (expected ipv4, ipv6 or both)", name)
+	}
+}
+
+// ipVersionMatches reports whether ip belongs to the family version
+// restricts a scan to, which every address does for version 0.
+func ipVersionMatches(ip net.IP, version int) bool {
+	switch version {
+	case 4:
+		return ip.To4() != nil
+	case 6:
+		return ip.To4() == nil
+	}
+	return true
+}
+
+// addressFamily returns the family label of ip.
+func addressFamily(ip net.IP) string {
+	if ip.To4() != nil {
+		return familyIPv4
+	}
+	return familyIPv6
+}
+
+// preferredAddress returns the first address of ips in the family prefer
+// asks for, or the first address when there is none of that family.
+func preferredAddress(ips []net.IP, prefer AddressPreference) net.IP {
+	for _, ip := range ips {
+		if (ip.To4() != nil) != (prefer == PreferIPv6) {
+			return ip
+		}
+	}
+	return ips[0]
+}
+
+// familyAddresses narrows the addresses of a host scanned on every address
+// to the family prefer asks for, when the host has any of that family.
+func familyAddresses(ips []net.IP, prefer AddressPreference) []net.IP {
+	if prefer != PreferIPv4 && prefer != PreferIPv6 {
+		return ips
+	}
+	var selected []net.IP
+	for _, ip := range ips {
+		if (ip.To4() != nil) != (prefer == PreferIPv6) {
+			selected = append(selected, ip)
+		}
+	}
+	if len(selected) == 0 {
+		return ips
+	}
+	return selected
+}
+
+// bothFamilies returns the first address of each family in ips.
+func bothFamilies(ips []net.IP) []net.IP {
+	var v4, v6 net.IP
+	for _, ip := range ips {
+		if ip.To4() != nil && v4 == nil {
+			v4 = ip
+		} else if ip.To4() == nil && v6 == nil {
+			v6 = ip
+		}
+	}
+	var selected []net.IP
+	for _, ip := range []net.IP{v4, v6} {
+		if ip != nil {
+			selected = append(selected, ip)
+		}
+	}
+	return selected
+}
+
+// MaxDNSServers caps the name servers a scan may be pinned to.
+const MaxDNSServers = 4
+
+// ParseDNSServers validates name server addresses, each an IP address with
+// an optional port (1.1.1.1, 9.9.9.9:5353, [2606:4700::1111]:53), and
+// returns them as host:port with port 53 filled in.
+func ParseDNSServers(servers []string) ([]string, error) {
+	if len(servers) > MaxDNSServers {
+		return nil, fmt.Errorf("at most %d DNS servers may be given", MaxDNSServers)
+	}
+	parsed := make([]string, 0, len(servers))
+	for _, server := range servers {
+		server = strings.TrimSpace(server)
+		host, port := server, "53"
+		if ip := net.ParseIP(strings.Trim(server, "[]")); ip != nil {
+			host = ip.String()
+		} else if h, p, err := net.SplitHostPort(server); err == nil && net.ParseIP(h) != nil {
+			if n, err := strconv.Atoi(p); err != nil || n < 1 || n > 65535 {
+				return nil, fmt.Errorf("invalid port in DNS server %q", server)
+			}
+			host, port = h, p
+		} else {
+			return nil, fmt.Errorf("DNS server %q must be an IP address with an optional port", server)
+		}
+		parsed = append(parsed, net.JoinHostPort(host, port))
+	}
+	return parsed, nil
+}
+
+// newDNSResolver returns a resolver that sends every query to servers, as
+// parsed by ParseDNSServers, instead of the system's name servers. Queries
+// rotate through servers, so a failing one is skipped on retry. Without
+// servers the system resolver is returned.
+func newDNSResolver(servers []string) *net.Resolver {
+	if len(servers) == 0 {
+		return net.DefaultResolver
+	}
+	var next atomic.Uint32
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			server := servers[int(next.Add(1)-1)%len(servers)]
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// resolverCache resolves each hostname at most once per scan and shares the
+// A/AAAA answers between all port jobs and workers.
+type resolverCache struct {
+	mu       sync.Mutex
+	entries  map[string]*resolvedHost
+	prefer   AddressPreference
+	version  int
+	resolver *net.Resolver
+}
+
+// resolvedHost is a single cached lookup. once guarantees that concurrent
+// workers asking for the same host wait for one in-flight query.
+type resolvedHost struct {
+	once sync.Once
+	ips  []net.IP
+	err  error
+}
+
+// newResolverCache returns a cache picking addresses by prefer, keeping only
+// those of IP version version unless it is 0.
+func newResolverCache(prefer AddressPreference, version int, servers []string) *resolverCache {
+	return &resolverCache{entries: make(map[string]*resolvedHost), prefer: prefer, version: version, resolver: newDNSResolver(servers)}
+}
+
+// lookup returns the addresses for host, querying DNS only on first use.
+// IP literals are returned directly without touching the resolver. With an
+// IP version set, addresses of the other family are left out, and a host
+// with none of that family does not resolve.
+func (r *resolverCache) lookup(host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		if !ipVersionMatches(ip, r.version) {
+			return nil, fmt.Errorf("%s is not an IPv%d address", host, r.version)
+		}
+		return []net.IP{ip}, nil
+	}
+
+	r.mu.Lock()
+	entry, ok := r.entries[host]
+	if !ok {
+		entry = &resolvedHost{}
+		r.entries[host] = entry
+	}
+	r.mu.Unlock()
+
+	entry.once.Do(func() {
+		network := "ip"
+		if r.version != 0 {
+			network = "ip" + strconv.Itoa(r.version)
+		}
+		entry.ips, entry.err = r.resolver.LookupIP(context.Background(), network, host)
+		if entry.err == nil && len(entry.ips) == 0 {
+			entry.err = fmt.Errorf("no addresses found for %s", host)
+		}
+	})
+	return entry.ips, entry.err
+}
+
+// address returns the address of host to probe, picked by the scan's
+// preference, IPv4 unless it asks for IPv6.
+func (r *resolverCache) address(host string) (net.IP, error) {
+	ips, err := r.lookup(host)
+	if err != nil {
+		return nil, err
+	}
+	return preferredAddress(ips, r.prefer), nil
+}
+
+// dialAddress returns a host:port string for dialing, using the cached
+// resolution so the standard library does not repeat the DNS query. IPv6
+// addresses are bracketed.
+func (r *resolverCache) dialAddress(host string, port int) (string, error) {
+	ip, err := r.address(host)
+	if err != nil {
+		return "", err
+	}
+	return hostPort(ip, port), nil
+}
+
+// hostPort formats ip and port as an address, bracketing IPv6 addresses.
+func hostPort(ip net.IP, port int) string {
+	return net.JoinHostPort(ip.String(), strconv.Itoa(port))
+}
+
+// family returns the family of the address dialAddress picks for host, or
+// "" when host does not resolve.
+func (r *resolverCache) family(host string) string {
+	ips, err := r.lookup(host)
+	if err != nil {
+		return ""
+	}
+	return addressFamily(preferredAddress(ips, r.prefer))
+}
@@ -0,0 +1,593 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Report is the outcome of Run.
+type Report struct {
+	// Mode is the scan mode that was actually used, which differs from the
+	// requested one when SYN scanning fell back to connect scanning.
+	Mode Mode
+	// Results holds one entry per probed host/port pair.
+	Results []ScanResult
+	// Warnings lists non-fatal issues such as a mode downgrade or targets
+	// skipped by the blocklist.
+	Warnings []string
+	// Hosts summarizes each scanned host with its network owner. It is only
+	// populated when RDAP enrichment was requested with WithRDAP; otherwise
+	// it lists just the hosts flagged by WithTarpitDetection and, with
+	// WithDiscovery, every target with its up or down status.
+	Hosts []HostSummary
+}
+
+// Option configures a Run call.
+type Option func(*runConfig)
+
+type runConfig struct {
+	mode          Mode
+	allowFallback bool
+	ports         []int
+	probes        *ProbeCache
+	worker        WorkerFunc
+	workers       int
+	checks        []Check
+	rdap          bool
+	reverseDNS    bool
+	minHostGroup  int
+	maxHostGroup  int
+	onHostGroup   func(results []ScanResult)
+	tarpit        *TarpitOptions
+	discovery     *DiscoveryOptions
+	opts          ScanOptions
+}
+
+// WithMode selects the scan technique. The default is ModeConnect.
+func WithMode(mode Mode) Option {
+	return func(c *runConfig) { c.mode = mode }
+}
+
+// WithFallback controls whether a SYN scan lacking raw packet privileges is
+// downgraded to a connect scan (the default) instead of failing.
+func WithFallback(allow bool) Option {
+	return func(c *runConfig) { c.allowFallback = allow }
+}
+
+// WithPorts adds individual ports to scan.
+func WithPorts(ports ...int) Option {
+	return func(c *runConfig) { c.ports = append(c.ports, ports...) }
+}
+
+// WithPortRange adds the inclusive port range start-end to scan.
+func WithPortRange(start, end int) Option {
+	return func(c *runConfig) {
+		for port := start; port <= end; port++ {
+			c.ports = append(c.ports, port)
+		}
+	}
+}
+
+// WithProbes supplies the service probes used for fingerprinting.
+// Without it ports are classified but services are not identified.
+func WithProbes(cache *ProbeCache) Option {
+	return func(c *runConfig) { c.probes = cache }
+}
+
+// WithRate caps the total probes per second across all hosts. Zero means unlimited.
+func WithRate(perSecond float64) Option {
+	return func(c *runConfig) { c.opts.Rate = perSecond }
+}
+
+// WithPacer consults pacer before every probe, for example to keep several
+// processes under one shared probe rate.
+func WithPacer(pacer Pacer) Option {
+	return func(c *runConfig) { c.opts.Pacer = pacer }
+}
+
+// WithReuse supplies earlier results for jobs that need not be probed again,
+// for example ports another scan covered moments ago.
+func WithReuse(reuse func(job ScanJob) (ScanResult, bool)) Option {
+	return func(c *runConfig) { c.opts.Reuse = reuse }
+}
+
+// WithLifecycle reports progress while the scan runs: onHostStarted and
+// onHostFinished bracket each target and onResult sees every result. Any of
+// them may be nil.
+func WithLifecycle(onHostStarted func(host, address string), onResult func(ScanResult), onHostFinished func(host, address string)) Option {
+	return func(c *runConfig) {
+		c.opts.OnHostStarted = onHostStarted
+		c.opts.OnResult = onResult
+		c.opts.OnHostFinished = onHostFinished
+	}
+}
+
+// WithProgress calls onProgress after every result with the jobs finished
+// and planned so far, see ScanOptions.OnProgress.
+func WithProgress(onProgress func(done, total int)) Option {
+	return func(c *runConfig) { c.opts.OnProgress = onProgress }
+}
+
+// WithHostGroups scans targets in groups of hosts, one group after another,
+// like nmap's --min-hostgroup and --max-hostgroup. The first group holds min
+// hosts and each following group doubles in size up to max, so results of
+// early groups are complete long before a large scan ends. Checks run per
+// group and onGroup, when set, receives the results of every group that
+// finished. A max of zero scans all targets as one group.
+func WithHostGroups(min, max int, onGroup func(results []ScanResult)) Option {
+	return func(c *runConfig) {
+		c.minHostGroup = min
+		c.maxHostGroup = max
+		c.onHostGroup = onGroup
+	}
+}
+
+// WithHostPorts probes the hosts listed in ports on their own port sets
+// instead of the scan's ports, so one scan can cover web servers and
+// databases with different port lists. Hosts not listed use the ports given
+// with WithPorts or WithPortRange.
+func WithHostPorts(ports map[string][]int) Option {
+	return func(c *runConfig) { c.opts.HostPorts = ports }
+}
+
+// WithServices labels open ports that no probe rule identified with the
+// service name table lists for their port. Run uses DefaultServices
+// unless another table is given; a table parsed from an empty file turns
+// the guesses off.
+func WithServices(table *ServiceTable) Option {
+	return func(c *runConfig) { c.opts.Services = table }
+}
+
+// WithHostRate caps the probes per second sent to any single host. Zero means unlimited.
+func WithHostRate(perSecond float64) Option {
+	return func(c *runConfig) { c.opts.HostRate = perSecond }
+}
+
+// WithTiming sets probe timeouts, per-host parallelism, the delay between
+// probes to a host and retries, see Timing and TimingTemplate.
+func WithTiming(timing Timing) Option {
+	return func(c *runConfig) { c.opts.Timing = timing }
+}
+
+// WithWorkers overrides the number of concurrent workers chosen for the mode.
+func WithWorkers(n int) Option {
+	return func(c *runConfig) { c.workers = n }
+}
+
+// WithWorker runs a custom worker implementation instead of the one selected
+// by the mode. It is mainly useful for tests and experiments.
+func WithWorker(worker WorkerFunc, n int) Option {
+	return func(c *runConfig) {
+		c.worker = worker
+		c.workers = n
+	}
+}
+
+// WithAllAddresses scans every resolved address of multi-homed hostnames.
+func WithAllAddresses(all bool) Option {
+	return func(c *runConfig) { c.opts.AllAddresses = all }
+}
+
+// WithAddressPreference selects the address family scanned on dual-stack
+// hostnames; see ScanOptions.Prefer.
+func WithAddressPreference(prefer AddressPreference) Option {
+	return func(c *runConfig) { c.opts.Prefer = prefer }
+}
+
+// WithIPVersion restricts the scan to IPv4 (4) or IPv6 (6) addresses; see
+// ScanOptions.IPVersion.
+func WithIPVersion(version int) Option {
+	return func(c *runConfig) { c.opts.IPVersion = version }
+}
+
+// WithMaxDuration bounds the whole scan, host discovery and checks included,
+// by d. When it passes the probes in flight finish and Run returns the
+// results so far with a warning instead of an error. Zero means no limit.
+func WithMaxDuration(d time.Duration) Option {
+	return func(c *runConfig) { c.opts.MaxDuration = d }
+}
+
+// ErrMaxDuration is the cause of the context of a scan that ran past its
+// ScanOptions.MaxDuration.
+var ErrMaxDuration = errors.New("scan exceeded its maximum duration")
+
+// WithDNSServers sends the scan's forward and reverse DNS queries to servers,
+// as returned by ParseDNSServers, instead of the system's name servers.
+func WithDNSServers(servers ...string) Option {
+	return func(c *runConfig) { c.opts.DNSServers = servers }
+}
+
+// WithBlocklist skips targets inside the given never-scan ranges.
+func WithBlocklist(blocklist *Blocklist) Option {
+	return func(c *runConfig) { c.opts.Blocklist = blocklist }
+}
+
+// WithBanner tunes service banner capture.
+func WithBanner(banner BannerOptions) Option {
+	return func(c *runConfig) { c.opts.Banner = banner }
+}
+
+// WithVersionIntensity limits service detection to probes no rarer than
+// intensity (0-9), besides the NULL probe and the probes registered for the
+// scanned port.
+func WithVersionIntensity(intensity int) Option {
+	return func(c *runConfig) { c.opts.VersionIntensity = &intensity }
+}
+
+// WithChecks runs the given check modules against open ports once the port
+// scan has finished. See ParseChecks for selecting checks by name.
+func WithChecks(checks ...Check) Option {
+	return func(c *runConfig) { c.checks = append(c.checks, checks...) }
+}
+
+// WithPacketCapture writes every packet sent and received by SYN and UDP
+// probes to w in pcap format. Connect scans record nothing.
+func WithPacketCapture(w io.Writer) Option {
+	return func(c *runConfig) { c.opts.PacketCapture = w }
+}
+
+// WithPacketTrace logs every probe sent and every response received to
+// logger, which is invaluable when working out why a port shows Filtered.
+func WithPacketTrace(logger *slog.Logger) Option {
+	return func(c *runConfig) { c.opts.PacketTrace = logger }
+}
+
+// WithRDAP looks up the network owner of every public target address via
+// RDAP once the scan has finished and reports it in Report.Hosts. Private
+// addresses are never sent to the registry.
+func WithRDAP(enabled bool) Option {
+	return func(c *runConfig) { c.rdap = enabled }
+}
+
+// WithReverseDNS looks up the PTR record of every probed IP address, as
+// nmap's -R does, and reports it as ScanResult.ReverseHostname. Lookups run
+// after each host group with bounded concurrency, and each address is asked
+// once per run.
+func WithReverseDNS(enabled bool) Option {
+	return func(c *runConfig) { c.reverseDNS = enabled }
+}
+
+// WithTarpitDetection flags hosts whose open ports look fabricated: too
+// large a share of the probed ports open or, in connect scans with service
+// probes, every open port accepting connections and then staying silent.
+// Flagged hosts get a warning and a Tarpit reason in Report.Hosts, and with
+// opts.Downgrade their open ports are reported as TarpitState before checks
+// run. Each host group is judged on its own.
+func WithTarpitDetection(opts TarpitOptions) Option {
+	return func(c *runConfig) { c.tarpit = &opts }
+}
+
+// WithDiscovery pings every target before the port scan and skips the hosts
+// that do not answer, like nmap does without -Pn. Each target's status is
+// reported in Report.Hosts.
+func WithDiscovery(opts DiscoveryOptions) Option {
+	return func(c *runConfig) { c.discovery = &opts }
+}
+
+// Run scans every target on the configured ports, runs any selected checks
+// against the open ports, and returns a report.
+// When ctx is cancelled no new probes are started and queued jobs are
+// dropped; probes already in flight finish and the partial report is
+// returned together with ctx.Err().
+func Run(ctx context.Context, targets []string, options ...Option) (report *Report, err error) {
+	cfg := runConfig{mode: ModeConnect, allowFallback: true}
+	for _, option := range options {
+		option(&cfg)
+	}
+	ctx, span := tracer.Start(ctx, "scanner.Run", trace.WithAttributes(
+		attribute.String("scan.mode", string(cfg.mode)),
+		attribute.Int("scan.targets", len(targets)),
+		attribute.Int("scan.ports", len(cfg.ports)),
+	))
+	defer func() {
+		if report != nil {
+			span.SetAttributes(attribute.String("scan.effective_mode", string(report.Mode)), attribute.Int("scan.results", len(report.Results)))
+		}
+		endSpan(span, err)
+	}()
+
+	if len(targets) == 0 {
+		return nil, errors.New("no targets to scan")
+	}
+	if len(cfg.ports) == 0 && len(cfg.opts.HostPorts) == 0 {
+		return nil, errors.New("no ports to scan")
+	}
+	for _, port := range cfg.ports {
+		if port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %d: must be between 1 and 65535", port)
+		}
+	}
+	for host, ports := range cfg.opts.HostPorts {
+		for _, port := range ports {
+			if port < 1 || port > 65535 {
+				return nil, fmt.Errorf("invalid port %d for %s: must be between 1 and 65535", port, host)
+			}
+		}
+	}
+	if intensity := cfg.opts.VersionIntensity; intensity != nil && (*intensity < 0 || *intensity > MaxVersionIntensity) {
+		return nil, fmt.Errorf("invalid version intensity %d: must be between 0 and %d", *intensity, MaxVersionIntensity)
+	}
+	if cfg.minHostGroup < 0 || cfg.maxHostGroup < 0 || (cfg.maxHostGroup > 0 && cfg.minHostGroup > cfg.maxHostGroup) {
+		return nil, fmt.Errorf("invalid host group sizes %d-%d: need 0 <= min <= max", cfg.minHostGroup, cfg.maxHostGroup)
+	}
+
+	report = &Report{Mode: cfg.mode}
+	worker := cfg.worker
+	workers := cfg.workers
+	if worker == nil {
+		selected, defaultWorkers, effective, warning, err := WorkerForMode(cfg.mode, cfg.allowFallback)
+		if err != nil {
+			return nil, err
+		}
+		worker = selected
+		report.Mode = effective
+		if warning != "" {
+			report.Warnings = append(report.Warnings, warning)
+		}
+		if workers <= 0 {
+			workers = defaultWorkers
+		}
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+
+	probes := cfg.probes
+	if probes == nil {
+		probes = NewProbeCache(nil)
+	}
+
+	opts := cfg.opts
+	if opts.Services == nil {
+		opts.Services = DefaultServices()
+	}
+	onBlocked := opts.OnBlocked
+	opts.OnBlocked = func(host, reason string) {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("skipped %s: %s", host, reason))
+		if onBlocked != nil {
+			onBlocked(host, reason)
+		}
+	}
+
+	if opts.PacketCapture != nil && report.Mode == ModeConnect {
+		report.Warnings = append(report.Warnings, "packet capture only records raw tcp and udp scans; nothing will be captured in connect mode")
+	}
+	if report.Mode.RawTCP() && (opts.Prefer == PreferIPv6 || opts.Prefer == PreferBoth || opts.IPVersion == 6) {
+		if src, err := sourceInterface(); err == nil && src.ipv6 == nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("interface %s has no global IPv6 address; IPv6 targets of %s scans will be reported as filtered", src.iface.Name, report.Mode))
+		}
+	}
+
+	protocol := "tcp"
+	if report.Mode == ModeUDP {
+		protocol = "udp"
+	}
+	// One state spans every group so rate limits, congestion history and the
+	// packet capture carry over
+	state := newScanState(opts)
+	defer state.close()
+	// Silent open ports only stand out when service probes were sent
+	stalls := report.Mode == ModeConnect && len(probes.GetTCPProbes()) > 0
+	// scanCtx bounds discovery, probes and checks by the scan's maximum
+	// duration; the lookups enriching the results afterwards still run
+	scanCtx := ctx
+	if opts.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		scanCtx, cancel = context.WithTimeoutCause(ctx, opts.MaxDuration, ErrMaxDuration)
+		defer cancel()
+	}
+	var discovered []HostSummary
+	if cfg.discovery != nil {
+		targets, discovered = discoverHosts(scanCtx, targets, *cfg.discovery, state.resolver)
+		if down := len(discovered) - len(targets); down > 0 {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%d of %d hosts did not answer host discovery and were not scanned", down, len(discovered)))
+		}
+	}
+	state.progress.total = plannedJobs(targets, cfg.ports, opts)
+	var results []ScanResult
+	var tarpits []HostSummary
+	var ptr *ptrResolver
+	if cfg.reverseDNS {
+		ptr = newPTRResolver(cfg.opts.DNSServers)
+	}
+	for _, group := range hostGroups(targets, cfg.minHostGroup, cfg.maxHostGroup) {
+		if len(group) == 0 {
+			break
+		}
+		var groupResults []ScanResult
+		groupResults, err = execute(scanCtx, group, cfg.ports, worker, workers, probes, opts, state)
+		if cfg.tarpit != nil {
+			tarpits = append(tarpits, detectTarpits(groupResults, *cfg.tarpit, stalls)...)
+		}
+		runChecks(scanCtx, groupResults, cfg.checks, protocol)
+		if ptr != nil {
+			resolveReverseNames(ctx, groupResults, ptr)
+		}
+		results = append(results, groupResults...)
+		if err != nil {
+			break
+		}
+		if cfg.onHostGroup != nil {
+			cfg.onHostGroup(groupResults)
+		}
+	}
+	if err != nil && ctx.Err() == nil && errors.Is(context.Cause(scanCtx), ErrMaxDuration) {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("scan stopped after its maximum duration of %s: %d of %d probes finished", opts.MaxDuration, state.progress.done, state.progress.total))
+		err = nil
+	}
+	if cfg.rdap {
+		report.Hosts = summarizeHosts(ctx, results, newResolverCache(cfg.opts.Prefer, cfg.opts.IPVersion, cfg.opts.DNSServers), newRDAPClient())
+	}
+	for _, tarpit := range tarpits {
+		target := tarpit.Host
+		if tarpit.Address != "" && tarpit.Address != tarpit.Host {
+			target = fmt.Sprintf("%s (%s)", tarpit.Host, tarpit.Address)
+		}
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%s looks like a tarpit or honeypot: %s", target, tarpit.Tarpit))
+	}
+	report.Hosts = mergeTarpitSummaries(report.Hosts, tarpits)
+	report.Hosts = mergeHostStatus(report.Hosts, discovered)
+	report.Results = results
+	return report, err
+}
+
+// plannedJobs counts the jobs hosts need before their names are resolved:
+// their own ports from opts.HostPorts or else ports.
+func plannedJobs(hosts []string, ports []int, opts ScanOptions) int {
+	jobs := 0
+	for _, host := range hosts {
+		if own, ok := opts.HostPorts[host]; ok {
+			jobs += len(own)
+		} else {
+			jobs += len(ports)
+		}
+	}
+	return jobs
+}
+
+// hostGroups splits targets into consecutive groups: the first of min hosts
+// (at least one), each next one twice as large, capped at max. A max of zero
+// keeps all targets in one group.
+func hostGroups(targets []string, min, max int) [][]string {
+	if max <= 0 || len(targets) <= max && min >= len(targets) {
+		return [][]string{targets}
+	}
+	size := min
+	if size < 1 {
+		size = 1
+	}
+	var groups [][]string
+	for start := 0; start < len(targets); {
+		end := start + size
+		if end > len(targets) {
+			end = len(targets)
+		}
+		groups = append(groups, targets[start:end])
+		start = end
+		if size *= 2; size > max {
+			size = max
+		}
+	}
+	return groups
+}
+
+// dropQueuedJobs takes back the jobs still buffered in jobs so a cancelled
+// scan only waits for probes already in flight.
+func dropQueuedJobs(jobs chan ScanJob, wg *sync.WaitGroup) {
+	for {
+		select {
+		case <-jobs:
+			wg.Done()
+		default:
+			return
+		}
+	}
+}
+
+// execute dispatches one job per target and port to the workers and collects
+// their results. Dispatch stops early when ctx is cancelled.
+func execute(ctx context.Context, hosts []string, ports []int, worker WorkerFunc, workerCount int, cache *ProbeCache, opts ScanOptions, state *ScanState) (scanResults []ScanResult, err error) {
+	ctx, span := tracer.Start(ctx, "scanner.execute", trace.WithAttributes(
+		attribute.Int("scan.hosts", len(hosts)),
+		attribute.Int("scan.workers", workerCount),
+	))
+	defer func() {
+		span.SetAttributes(attribute.Int("scan.results", len(scanResults)))
+		endSpan(span, err)
+	}()
+	state.ctx = ctx
+	var wg sync.WaitGroup
+	jobs := make(chan ScanJob, 1000)
+	targets := expandTargets(hosts, opts, state.resolver)
+	portsOf := func(host string) []int {
+		if own, ok := opts.HostPorts[host]; ok {
+			return own
+		}
+		return ports
+	}
+
+	// remaining counts the results each target still awaits, so the
+	// collector can tell when a host is finished
+	remaining := make(map[string]int, len(targets))
+	totalJobs := 0
+	for _, target := range targets {
+		remaining[target.Host+"|"+target.Address] += len(portsOf(target.Host))
+		totalJobs += len(portsOf(target.Host))
+	}
+	results := make(chan ScanResult, totalJobs)
+	state.progress.total += totalJobs - plannedJobs(hosts, ports, opts)
+
+	for w := 0; w < workerCount; w++ {
+		go worker(jobs, results, cache, state, &wg)
+	}
+
+	// The dispatcher holds its own count so Wait cannot return before every
+	// dispatched job has been added
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		started := make(map[string]bool, len(targets))
+		for _, target := range targets {
+			if key := target.Host + "|" + target.Address; opts.OnHostStarted != nil && !started[key] {
+				started[key] = true
+				opts.OnHostStarted(target.Host, target.Address)
+			}
+			for _, port := range portsOf(target.Host) {
+				job := ScanJob{Host: target.Host, Port: port, Address: target.Address}
+				if opts.Reuse != nil {
+					// results has room for every job, so this never blocks
+					if result, ok := opts.Reuse(job); ok {
+						result.Reused = true
+						results <- result
+						continue
+					}
+				}
+				if ctx.Err() != nil {
+					dropQueuedJobs(jobs, &wg)
+					return
+				}
+				wg.Add(1)
+				select {
+				case jobs <- job:
+				case <-ctx.Done():
+					wg.Done()
+					dropQueuedJobs(jobs, &wg)
+					return
+				}
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Pre-allocate slice with exact capacity to avoid reallocations
+	scanResults = make([]ScanResult, 0, totalJobs)
+	for result := range results {
+		scanResults = append(scanResults, result)
+		if opts.OnResult != nil {
+			opts.OnResult(result)
+		}
+		state.progress.done++
+		if opts.OnProgress != nil {
+			opts.OnProgress(state.progress.done, state.progress.total)
+		}
+		key := result.Host + "|" + result.Address
+		if remaining[key]--; remaining[key] == 0 && opts.OnHostFinished != nil {
+			opts.OnHostFinished(result.Host, result.Address)
+		}
+	}
+
+	return scanResults, ctx.Err()
+}
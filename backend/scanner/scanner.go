@@ -1,58 +1,341 @@
 package scanner
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"sync"
+	"time"
 )
 
 // ScanJob represents a single port scanning task.
 type ScanJob struct {
 	Host string
 	Port int
+	// Address pins the job to one resolved IP of Host. Empty means the
+	// worker resolves Host itself.
+	Address string
+}
+
+// target returns what workers should probe: the pinned address when set,
+// otherwise the original host.
+func (j ScanJob) target() string {
+	if j.Address != "" {
+		return j.Address
+	}
+	return j.Host
 }
 
 // ScanResult represents the outcome of a port scan attempt.
 type ScanResult struct {
         Host    string `json:"host" example:"scanme.nmap.org" description:"Target host that produced the observation. Mirrors the input host field so clients can join results back to their original request."`
         Port    int    `json:"port" example:"443" description:"Network port that was probed. Expressed as an integer in the 0-65535 range."`
-        State   string `json:"state" enums:"Open,Closed,Filtered" example:"Open" description:"Resulting port disposition derived from worker probes. Open indicates a responsive service, Closed means the port rejected connections, and Filtered signifies intermediary packet filtering."`
+        State   string `json:"state" enums:"Open,Closed,Filtered,Tarpit" example:"Open" description:"Resulting port disposition derived from worker probes. Open indicates a responsive service, Closed means the port rejected connections, Filtered signifies intermediary packet filtering, and Tarpit replaces Open on hosts flagged as likely tarpits or honeypots when the scan asked to downgrade them."`
         Service string `json:"service,omitempty" example:"http (nginx)" description:"Optional service fingerprint (if detected) describing application protocol and banner. Empty when the probe could not identify an application."`
+        BannerBase64 string `json:"banner_base64,omitempty" example:"AAAAGGZ0eXBpc29t" description:"Raw service response in standard base64 when it was binary or not valid UTF-8. service then holds a printable preview with non-printable bytes shown as dots."`
+        Product string `json:"product,omitempty" example:"OpenSSH" description:"Product name extracted from the service response by the matching probe rule. Empty when the rule carries no product or the service was not identified."`
+        Version string `json:"version,omitempty" example:"8.2p1" description:"Product version extracted from the service response by the matching probe rule. Empty when unknown."`
+        Info string `json:"info,omitempty" example:"Ubuntu Linux; protocol 2.0" description:"Extra details from the i/ field of the matching probe rule, such as the protocol version or distribution. Empty when the rule carries none."`
+        Hostname string `json:"hostname,omitempty" example:"mail.example.com" description:"Host name the service announced, from the h/ field of the matching probe rule. Empty when the rule carries none."`
+        OS string `json:"os,omitempty" example:"Linux" description:"Operating system the service response reveals, from the o/ field of the matching probe rule. Empty when the rule carries none."`
+        CPE []string `json:"cpe,omitempty" example:"cpe:/a:openbsd:openssh:8.2p1,cpe:/o:linux:linux_kernel" description:"Common Platform Enumeration names from the cpe:/ fields of the matching probe rule, for joining results with vulnerability databases."`
+        TLS string `json:"tls,omitempty" enums:"implicit,starttls" example:"implicit" description:"How the service speaks TLS: implicit when it does from the first byte, in which case service names what runs inside the tunnel prefixed with ssl/ (ssl alone when unidentified), or starttls when a plaintext SMTP, IMAP, POP3 or FTP service agreed to upgrade. Empty when no TLS was found."`
+        Certificate *Certificate `json:"certificate,omitempty" description:"Leaf certificate the TLS service presented, unverified. Absent when tls is empty."`
+        Address string `json:"address,omitempty" example:"45.33.32.156" description:"Resolved IP address that was probed when the scan was asked to cover every address of a multi-homed hostname or both address families. Empty when the host itself was probed."`
+        Family string `json:"family,omitempty" enums:"ipv4,ipv6" example:"ipv4" description:"Address family of the address that was probed, so results of dual-stack hosts show which stack they reflect. Empty when the host did not resolve."`
+        Findings []Finding `json:"findings,omitempty" description:"Observations from opt-in check modules (for example exposed SNMP) that ran against this port. Empty when no checks were selected or none applied."`
+        ServiceGuess string `json:"service_guess,omitempty" example:"ms-wbt-server" description:"Low-confidence service name guessed from the port number alone via the nmap-services table. Set only for open ports no probe rule identified; the service field then keeps any raw banner."`
+        Reused bool `json:"reused,omitempty" example:"false" description:"True when the port was not probed again because a recent enough result from another scan was reused."`
+        ReverseHostname string `json:"reverse_hostname,omitempty" example:"scanme.nmap.org" description:"Host name the PTR record of the probed address points to. Set only when reverse DNS resolution was requested and the address has a PTR record; hostname holds the name a service announced instead."`
+}
+
+// ScanOptions tunes how a scan is executed.
+// The zero value keeps the default behaviour.
+type ScanOptions struct {
+	// Rate caps the total number of probes per second across all hosts.
+	// Zero means no global cap.
+	Rate float64
+	// HostRate caps the number of probes per second sent to any single target
+	// host, independently of worker count. Zero means no per-host cap.
+	HostRate float64
+	// AllAddresses scans every A/AAAA record of a hostname separately instead
+	// of a single address, reporting each under the original hostname.
+	AllAddresses bool
+	// Prefer selects the address family scanned on dual-stack hostnames. With
+	// PreferBoth one address of each family is scanned; with AllAddresses,
+	// PreferIPv4 and PreferIPv6 keep only the addresses of that family.
+	Prefer AddressPreference
+	// IPVersion, 4 or 6, restricts the scan to addresses of that family:
+	// hostnames resolve to those alone and IP targets of the other family
+	// are skipped. Zero scans either family as Prefer selects.
+	IPVersion int
+	// MaxDuration bounds the whole scan. Once it passes no further probes
+	// are sent, probes in flight finish, and the results so far are
+	// returned; Run reports this as a warning rather than an error. Zero
+	// means no limit.
+	MaxDuration time.Duration
+	// DNSServers, when set, receive every DNS query of the scan instead of
+	// the system's name servers, as host:port addresses from
+	// ParseDNSServers.
+	DNSServers []string
+	// Blocklist holds ranges that are never probed. Targets are checked after
+	// resolution when jobs are generated; a hostname with any blocked address
+	// is skipped entirely unless AllAddresses is set, in which case only the
+	// blocked addresses are dropped.
+	Blocklist *Blocklist
+	// OnBlocked, when set, is called for every target skipped by Blocklist.
+	OnBlocked func(host, reason string)
+	// Banner tunes how much of a service response is captured and how long
+	// the connect scanner keeps reading. The zero value keeps the defaults.
+	Banner BannerOptions
+	// Timing sets probe timeouts, per-host parallelism, the delay between
+	// probes to a host and retries. The zero value is the normal template.
+	Timing Timing
+	// VersionIntensity (0-9) limits the connect scanner's service probes to
+	// those no rarer than it, besides the NULL probe and the probes registered
+	// for the port. Nil uses DefaultVersionIntensity.
+	VersionIntensity *int
+	// PacketCapture, when set, receives a pcap stream of every packet sent
+	// and received by SYN and UDP probes. Connect scans record nothing.
+	PacketCapture io.Writer
+	// PacketTrace, when set, receives a log record for every probe sent and
+	// every response received.
+	PacketTrace *slog.Logger
+	// Pacer, when set, is consulted before every probe in addition to Rate
+	// and HostRate, so a budget can be shared beyond this scan.
+	Pacer Pacer
+	// Reuse, when set, is asked before each job is dispatched. A result it
+	// returns is reported, marked Reused, instead of probing the port again.
+	Reuse func(job ScanJob) (ScanResult, bool)
+	// HostPorts, when set, replaces the scan's ports for the hosts it lists,
+	// keyed by host as submitted.
+	HostPorts map[string][]int
+	// Services, when set, names open ports that no probe rule identified
+	// after their port number, reported as ScanResult.ServiceGuess.
+	Services *ServiceTable
+	// OnHostStarted, when set, is called before the first job of a target is
+	// dispatched. Address is empty unless AllAddresses or PreferBoth split the
+	// hostname.
+	OnHostStarted func(host, address string)
+	// OnResult, when set, is called for every result as it arrives, before
+	// any checks run against it.
+	OnResult func(result ScanResult)
+	// OnHostFinished, when set, is called once every port of a target has a
+	// result. Targets cut short by cancellation never finish. The callbacks
+	// may run concurrently with each other.
+	OnHostFinished func(host, address string)
+	// OnProgress, when set, is called after every result with the number of
+	// jobs finished and the number planned. The plan counts every port of
+	// every host up front and is corrected when hostnames expand into
+	// several targets or blocked ones are dropped.
+	OnProgress func(done, total int)
+}
+
+// ScanState holds state shared by all workers of a single scan run.
+type ScanState struct {
+	congestion *congestionControl
+	timing     Timing
+	rate       *rateLimiter
+	pacer      Pacer
+	hostRates  *hostRateLimiters
+	resolver   *resolverCache
+	banner     BannerOptions
+	intensity  int
+	capture    *packetRecorder
+	trace      *packetTracer
+	services   *ServiceTable
+	progress   jobProgress
+	// ctx is the context of the running scan; workers stop retrying once it
+	// is done
+	ctx context.Context
+
+	// The SYN capture handle is opened by the first SYN probe
+	synOnce sync.Once
+	syn     *synCapture
+	synErr  error
+
+	// The ICMP listener is opened by the first UDP probe
+	icmpOnce sync.Once
+	icmp     *icmpCapture
+	icmpErr  error
+}
+
+// jobProgress counts the jobs of a scan across its host groups. Only the
+// goroutine collecting results touches it.
+type jobProgress struct {
+	done, total int
+}
+
+// newScanState creates fresh shared state for one scan run.
+func newScanState(opts ScanOptions) *ScanState {
+	timing := opts.Timing.withDefaults()
+	hostRate := opts.HostRate
+	if hostRate <= 0 && timing.ScanDelay > 0 {
+		hostRate = 1 / timing.ScanDelay.Seconds()
+	}
+	banner := opts.Banner
+	if banner.ReadTimeout <= 0 {
+		banner.ReadTimeout = timing.BannerTimeout
+	}
+	return &ScanState{
+		congestion: newCongestionControl(timing),
+		timing:     timing,
+		rate:       newRateLimiter(opts.Rate),
+		pacer:      opts.Pacer,
+		hostRates:  newHostRateLimiters(hostRate),
+		resolver:   newResolverCache(opts.Prefer, opts.IPVersion, opts.DNSServers),
+		banner:     banner.withDefaults(),
+		intensity:  versionIntensity(opts.VersionIntensity),
+		capture:    newPacketRecorder(opts.PacketCapture),
+		trace:      newPacketTracer(opts.PacketTrace),
+		services:   opts.Services,
+		ctx:        context.Background(),
+	}
+}
+
+// retryWait waits the backoff before retransmission attempt of a silent
+// probe. It returns false without waiting it out when the scan is cancelled
+// or its deadline passes, so the worker reports the port as it stands.
+func (s *ScanState) retryWait(attempt int) bool {
+	return sleepContext(s.ctx, s.timing.retryDelay(attempt))
+}
+
+// guessService sets the port-number based ServiceGuess of an open result
+// that no probe rule identified.
+func (s *ScanState) guessService(result *ScanResult, protocol string) {
+	if result.State == "Open" || result.State == "Open|Filtered" {
+		result.ServiceGuess = s.services.Lookup(result.Port, protocol)
+	}
+}
+
+// close releases resources the scan's workers opened, once all of them have
+// finished.
+func (s *ScanState) close() {
+	if s.syn != nil {
+		s.syn.close()
+	}
+	if s.icmp != nil {
+		s.icmp.close()
+	}
+}
+
+// admit paces and admits a probe against host. It returns the congestion state
+// the caller must release once the probe finishes, plus the timeout to apply.
+// When the scan is cancelled or its deadline passes while the probe waits for
+// its turn, admit returns false and the probe must not be sent; there is then
+// nothing to release.
+func (s *ScanState) admit(host string) (*hostCongestion, time.Duration, bool) {
+	if !s.rate.wait(s.ctx) {
+		return nil, 0, false
+	}
+	if s.pacer != nil {
+		s.pacer.Wait()
+	}
+	if !s.hostRates.wait(s.ctx, host) {
+		return nil, 0, false
+	}
+	hostCtl := s.congestion.host(host)
+	return hostCtl, hostCtl.acquire(), true
 }
 
 // WorkerFunc is the signature for scanner worker functions.
-type WorkerFunc func(jobs <-chan ScanJob, results chan<- ScanResult, cache *ProbeCache, wg *sync.WaitGroup)
+type WorkerFunc func(jobs <-chan ScanJob, results chan<- ScanResult, cache *ProbeCache, state *ScanState, wg *sync.WaitGroup)
 
 // ExecuteScan is the universal scan orchestrator.
 // It manages workers, distributes tasks, and collects results.
-func ExecuteScan(hosts []string, startPort int, endPort int, worker WorkerFunc, workerCount int, cache *ProbeCache) []ScanResult {
-	var wg sync.WaitGroup
-	jobs := make(chan ScanJob, 1000)
-	totalJobs := len(hosts) * (endPort - startPort + 1)
-	results := make(chan ScanResult, totalJobs)
+// It is kept for existing callers; new code should prefer Run.
+// Cancelling ctx, or reaching opts.MaxDuration, stops dispatching jobs and
+// retrying probes; the results collected so far are returned along with
+// the context's error.
+func ExecuteScan(ctx context.Context, hosts []string, startPort int, endPort int, worker WorkerFunc, workerCount int, cache *ProbeCache, opts ScanOptions) ([]ScanResult, error) {
+	ports := make([]int, 0, endPort-startPort+1)
+	for port := startPort; port <= endPort; port++ {
+		ports = append(ports, port)
+	}
+	if opts.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, opts.MaxDuration, ErrMaxDuration)
+		defer cancel()
+	}
+	state := newScanState(opts)
+	defer state.close()
+	state.progress.total = plannedJobs(hosts, ports, opts)
+	ctx, span := tracer.Start(ctx, "scanner.ExecuteScan")
+	defer span.End()
+	return execute(ctx, hosts, ports, worker, workerCount, cache, opts, state)
+}
 
-	for w := 0; w < workerCount; w++ {
-		go worker(jobs, results, cache, &wg)
+// versionIntensity returns the intensity selected by intensity, clamped to
+// the valid range.
+func versionIntensity(intensity *int) int {
+	if intensity == nil {
+		return DefaultVersionIntensity
 	}
+	return min(max(*intensity, 0), MaxVersionIntensity)
+}
 
-	wg.Add(totalJobs)
-	go func() {
-		for _, host := range hosts {
-			for port := startPort; port <= endPort; port++ {
-				jobs <- ScanJob{Host: host, Port: port}
+// expandTargets turns the requested hosts into probe targets. Normally every
+// host is a single target; with AllAddresses each resolved address of a
+// hostname becomes its own target, and with PreferBoth one address of each
+// family does. Hosts that fail to resolve are kept as-is so workers report
+// them like any other unreachable target; IP targets outside the family
+// IPVersion asks for are skipped like blocked ones.
+func expandTargets(hosts []string, opts ScanOptions, resolver *resolverCache) []ScanJob {
+	targets := make([]ScanJob, 0, len(hosts))
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil && !ipVersionMatches(ip, opts.IPVersion) {
+			reportBlocked(opts, host, fmt.Sprintf("not an IPv%d address", opts.IPVersion))
+			continue
+		}
+		var ips []net.IP
+		if opts.AllAddresses || opts.Prefer == PreferBoth || opts.Blocklist.Len() > 0 {
+			ips, _ = resolver.lookup(host)
+		}
+
+		if !opts.AllAddresses {
+			if network, ip, blocked := matchBlocked(opts.Blocklist, ips); blocked {
+				reportBlocked(opts, host, fmt.Sprintf("%s is in blocked range %s", ip, network))
+				continue
+			}
+			if opts.Prefer != PreferBoth || len(ips) == 0 {
+				targets = append(targets, ScanJob{Host: host})
+				continue
+			}
+			for _, ip := range bothFamilies(ips) {
+				targets = append(targets, ScanJob{Host: host, Address: ip.String()})
 			}
+			continue
 		}
-		close(jobs)
-	}()
 
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+		if len(ips) == 0 {
+			targets = append(targets, ScanJob{Host: host})
+			continue
+		}
+		for _, ip := range familyAddresses(ips, opts.Prefer) {
+			if network, blocked := opts.Blocklist.Match(ip); blocked {
+				reportBlocked(opts, host, fmt.Sprintf("%s is in blocked range %s", ip, network))
+				continue
+			}
+			targets = append(targets, ScanJob{Host: host, Address: ip.String()})
+		}
+	}
+	return targets
+}
 
-	// Pre-allocate slice with exact capacity to avoid reallocations
-	scanResults := make([]ScanResult, 0, totalJobs)
-	for result := range results {
-		scanResults = append(scanResults, result)
+// matchBlocked returns the first address of ips that falls in a blocked range.
+func matchBlocked(blocklist *Blocklist, ips []net.IP) (*net.IPNet, net.IP, bool) {
+	for _, ip := range ips {
+		if network, ok := blocklist.Match(ip); ok {
+			return network, ip, true
+		}
 	}
+	return nil, nil, false
+}
 
-	return scanResults
+func reportBlocked(opts ScanOptions, host, reason string) {
+	if opts.OnBlocked != nil {
+		opts.OnBlocked(host, reason)
+	}
 }
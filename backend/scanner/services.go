@@ -0,0 +1,207 @@
+package scanner
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The bundled table is nmap's full nmap-services file, whose open
+// frequencies rank ports for TopPorts; refresh it with go generate.
+//
+//go:generate curl -fsSLo nmap-services https://raw.githubusercontent.com/nmap/nmap/master/nmap-services
+//go:embed nmap-services
+var bundledServices string
+
+// ServiceTable maps port numbers to the service names registered for them,
+// as listed in an nmap-services file.
+type ServiceTable struct {
+	names       map[string]map[int]string  // protocol -> port -> name
+	frequencies map[string]map[int]float64 // protocol -> port -> open frequency
+}
+
+var (
+	defaultServicesOnce sync.Once
+	defaultServices     *ServiceTable
+)
+
+// DefaultServices returns the nmap-services table bundled with the scanner.
+func DefaultServices() *ServiceTable {
+	defaultServicesOnce.Do(func() {
+		table, err := ParseServices(strings.NewReader(bundledServices))
+		if err != nil {
+			panic(fmt.Sprintf("bundled nmap-services: %v", err))
+		}
+		defaultServices = table
+	})
+	return defaultServices
+}
+
+// LoadServices reads an nmap-services file.
+func LoadServices(filePath string) (*ServiceTable, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+	return ParseServices(file)
+}
+
+// ParseServices parses nmap-services lines of the form
+// "name port/protocol [frequency] [# comment]". Entries named unknown and
+// protocols other than tcp and udp are ignored; when a port is listed twice
+// the first name wins. The frequency, how often the port is found open, ranks
+// ports for TopPorts.
+func ParseServices(r io.Reader) (*ServiceTable, error) {
+	table := &ServiceTable{
+		names:       map[string]map[int]string{"tcp": {}, "udp": {}},
+		frequencies: map[string]map[int]float64{"tcp": {}, "udp": {}},
+	}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected name and port/protocol", lineNumber)
+		}
+		portText, protocol, found := strings.Cut(fields[1], "/")
+		port, err := strconv.Atoi(portText)
+		if !found || err != nil || port < 0 || port > 65535 {
+			return nil, fmt.Errorf("line %d: invalid port/protocol %q", lineNumber, fields[1])
+		}
+		protocol = strings.ToLower(protocol)
+		if len(fields) > 2 {
+			frequency, err := strconv.ParseFloat(fields[2], 64)
+			if err != nil || frequency < 0 {
+				return nil, fmt.Errorf("line %d: invalid frequency %q", lineNumber, fields[2])
+			}
+			if frequencies, ok := table.frequencies[protocol]; ok && port > 0 {
+				if _, taken := frequencies[port]; !taken {
+					frequencies[port] = frequency
+				}
+			}
+		}
+		ports, ok := table.names[protocol]
+		if !ok || fields[0] == "unknown" {
+			continue
+		}
+		if _, taken := ports[port]; !taken {
+			ports[port] = fields[0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading services: %w", err)
+	}
+	return table, nil
+}
+
+// Lookup returns the service name registered for port over protocol (tcp or
+// udp), or "" when the table has none. A nil table knows no ports.
+func (t *ServiceTable) Lookup(port int, protocol string) string {
+	if t == nil {
+		return ""
+	}
+	return t.names[protocol][port]
+}
+
+// Len returns the number of ports in the table.
+func (t *ServiceTable) Len() int {
+	if t == nil {
+		return 0
+	}
+	return len(t.names["tcp"]) + len(t.names["udp"])
+}
+
+// TopPorts returns the n ports most often found open over protocol (tcp or
+// udp) according to the frequencies of the table, most frequent first. It is
+// an error when the table ranks fewer than n ports.
+func (t *ServiceTable) TopPorts(n int, protocol string) ([]int, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("top ports count must be positive, got %d", n)
+	}
+	var ranked []int
+	if t != nil {
+		for port := range t.frequencies[protocol] {
+			ranked = append(ranked, port)
+		}
+	}
+	if n > len(ranked) {
+		return nil, fmt.Errorf("the services table ranks only %d %s ports, %d requested", len(ranked), protocol, n)
+	}
+	frequencies := t.frequencies[protocol]
+	sort.Slice(ranked, func(i, j int) bool {
+		if frequencies[ranked[i]] != frequencies[ranked[j]] {
+			return frequencies[ranked[i]] > frequencies[ranked[j]]
+		}
+		return ranked[i] < ranked[j]
+	})
+	return ranked[:n], nil
+}
+
+// serviceAliases maps everyday names to the names nmap-services uses.
+var serviceAliases = map[string]string{
+	"dns":      "domain",
+	"ident":    "auth",
+	"ldaps":    "ldapssl",
+	"mongodb":  "mongod",
+	"mssql":    "ms-sql-s",
+	"postgres": "postgresql",
+	"rdp":      "ms-wbt-server",
+	"smb":      "microsoft-ds",
+	"winrm":    "wsman",
+	"xmpp":     "xmpp-client",
+}
+
+// Ports resolves service names such as http, ssh or rdp to every port the
+// table registers them on for protocol, in ascending order. Names are
+// case-insensitive, common aliases are understood and plain port numbers are
+// passed through. An unknown name is an error.
+func (t *ServiceTable) Ports(names []string, protocol string) ([]int, error) {
+	selected := make(map[int]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if port, err := strconv.Atoi(name); err == nil {
+			if port < 1 || port > 65535 {
+				return nil, fmt.Errorf("invalid port %d: must be between 1 and 65535", port)
+			}
+			selected[port] = true
+			continue
+		}
+		registeredName := name
+		if alias, ok := serviceAliases[strings.ToLower(name)]; ok {
+			registeredName = alias
+		}
+		found := false
+		if t != nil {
+			for port, registered := range t.names[protocol] {
+				if strings.EqualFold(registered, registeredName) && port > 0 {
+					selected[port] = true
+					found = true
+				}
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown %s service %q", protocol, name)
+		}
+	}
+	ports := make([]int, 0, len(selected))
+	for port := range selected {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	return ports, nil
+}
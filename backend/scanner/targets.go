@@ -0,0 +1,174 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxTargets caps how many hosts ExpandTargets produces, so that a
+// mistyped prefix such as 10.0.0.0/8 is rejected instead of queued.
+const DefaultMaxTargets = 65536
+
+// addressRange is an inclusive range of addresses of one family.
+type addressRange struct {
+	first, last net.IP
+}
+
+// ExpandTargets turns target expressions into the hosts to scan. Every spec
+// may be a comma-separated list whose entries are CIDR blocks
+// (192.168.1.0/24, all addresses including network and broadcast), dashed
+// IPv4 ranges (10.0.0.1-10.0.0.50, or 10.0.0.1-50 for the last octet), IP
+// addresses or hostnames. Hosts matched by an exclude entry, which take the
+// same forms, are dropped, and duplicates are listed once in first-seen
+// order. More than max addresses before exclusion is an error.
+func ExpandTargets(specs, exclude []string, max int) ([]string, error) {
+	var excludedRanges []addressRange
+	excludedNames := make(map[string]bool)
+	for _, entry := range splitTargetSpecs(exclude) {
+		r, isRange, err := parseAddressRange(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude %q: %w", entry, err)
+		}
+		if isRange {
+			excludedRanges = append(excludedRanges, r)
+		} else {
+			excludedNames[strings.ToLower(entry)] = true
+		}
+	}
+	excluded := func(host string) bool {
+		if excludedNames[strings.ToLower(host)] {
+			return true
+		}
+		ip := net.ParseIP(host)
+		for _, r := range excludedRanges {
+			if ip != nil && r.contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var hosts []string
+	seen := make(map[string]bool)
+	add := func(host string) {
+		if !seen[host] && !excluded(host) {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	total := 0
+	for _, entry := range splitTargetSpecs(specs) {
+		r, isRange, err := parseAddressRange(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %q: %w", entry, err)
+		}
+		if !isRange {
+			total++
+			add(entry)
+			continue
+		}
+		size, ok := r.size(max)
+		if total += size; !ok || total > max {
+			return nil, fmt.Errorf("targets expand to more than %d addresses; narrow the ranges", max)
+		}
+		for ip := r.first; ; ip = nextIP(ip) {
+			add(ip.String())
+			if ip.Equal(r.last) {
+				break
+			}
+		}
+	}
+	if total > max {
+		return nil, fmt.Errorf("targets expand to more than %d addresses; narrow the ranges", max)
+	}
+	return hosts, nil
+}
+
+// splitTargetSpecs splits comma-separated specs into trimmed, non-empty
+// entries.
+func splitTargetSpecs(specs []string) []string {
+	var entries []string
+	for _, spec := range specs {
+		for _, entry := range strings.Split(spec, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries
+}
+
+// parseAddressRange parses a CIDR block or dashed IPv4 range. It reports
+// false for anything else, which is a single host.
+func parseAddressRange(entry string) (addressRange, bool, error) {
+	if strings.Contains(entry, "/") {
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return addressRange{}, false, err
+		}
+		first := network.IP
+		last := make(net.IP, len(first))
+		for i := range first {
+			last[i] = first[i] | ^network.Mask[i]
+		}
+		return addressRange{first: first, last: last}, true, nil
+	}
+
+	start, end, dashed := strings.Cut(entry, "-")
+	first := net.ParseIP(start).To4()
+	if !dashed || first == nil {
+		// Hostnames may contain dashes
+		return addressRange{}, false, nil
+	}
+	last := net.ParseIP(end).To4()
+	if last == nil {
+		octet, err := strconv.Atoi(end)
+		if err != nil || octet < 0 || octet > 255 {
+			return addressRange{}, false, fmt.Errorf("range end must be an IPv4 address or last octet")
+		}
+		last = append(net.IP{}, first...)
+		last[3] = byte(octet)
+	}
+	if bytes.Compare(first, last) > 0 {
+		return addressRange{}, false, fmt.Errorf("range start must not exceed its end")
+	}
+	return addressRange{first: first, last: last}, true, nil
+}
+
+// contains reports whether ip lies in r.
+func (r addressRange) contains(ip net.IP) bool {
+	if len(r.first) == net.IPv4len {
+		ip = ip.To4()
+	} else if ip.To4() != nil {
+		return false
+	}
+	return ip != nil && bytes.Compare(ip, r.first) >= 0 && bytes.Compare(ip, r.last) <= 0
+}
+
+// size returns the number of addresses in r, reporting false when it
+// exceeds max.
+func (r addressRange) size(max int) (int, bool) {
+	size := 0
+	for i := range r.first {
+		size = size<<8 + int(r.last[i]) - int(r.first[i])
+		if size >= max {
+			return 0, false
+		}
+	}
+	return size + 1, size+1 <= max
+}
+
+// nextIP returns the address following ip.
+func nextIP(ip net.IP) net.IP {
+	next := append(net.IP{}, ip...)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
@@ -0,0 +1,125 @@
+package scanner
+
+import "fmt"
+
+// Tarpit detection defaults.
+const (
+	DefaultTarpitMinPorts   = 20
+	DefaultTarpitOpenRatio  = 0.8
+	DefaultTarpitMinStalled = 8
+)
+
+// TarpitState replaces Open on the ports of a host flagged as a likely tarpit
+// or honeypot when TarpitOptions.Downgrade is set.
+const TarpitState = "Tarpit"
+
+// TarpitOptions tunes the detection of hosts whose open ports cannot be
+// trusted, such as LaBrea-style tarpits that accept every connection and
+// honeypots that pretend to run every service.
+type TarpitOptions struct {
+	// MinPorts is how many ports of a host must have been probed before its
+	// share of open ports is judged. Zero uses DefaultTarpitMinPorts.
+	MinPorts int
+	// OpenRatio is the share of probed ports reported Open from which a host
+	// is flagged. Zero uses DefaultTarpitOpenRatio.
+	OpenRatio float64
+	// MinStalled is how many open ports must all have accepted the
+	// connection without answering any service probe before a connect scan
+	// flags the host. Zero uses DefaultTarpitMinStalled.
+	MinStalled int
+	// Downgrade reports the open ports of flagged hosts as TarpitState so
+	// they no longer count as open in results, checks and inventories.
+	Downgrade bool
+}
+
+// withDefaults fills unset fields with the package defaults.
+func (o TarpitOptions) withDefaults() TarpitOptions {
+	if o.MinPorts <= 0 {
+		o.MinPorts = DefaultTarpitMinPorts
+	}
+	if o.OpenRatio <= 0 {
+		o.OpenRatio = DefaultTarpitOpenRatio
+	}
+	if o.MinStalled <= 0 {
+		o.MinStalled = DefaultTarpitMinStalled
+	}
+	return o
+}
+
+// tarpitTally counts what one host and address answered.
+type tarpitTally struct {
+	host, address string
+	probed        int
+	open          int
+	silent        int
+}
+
+// detectTarpits flags every host and address in results that looks like a
+// tarpit or honeypot and, with Downgrade, rewrites its open ports in place.
+// stalls enables the silent-port signal, which only means something when a
+// connect scan sent service probes. It returns one summary per flagged host
+// in the order the hosts first appear in results.
+func detectTarpits(results []ScanResult, opts TarpitOptions, stalls bool) []HostSummary {
+	opts = opts.withDefaults()
+	tallies := make(map[[2]string]*tarpitTally)
+	var order []*tarpitTally
+	for _, result := range results {
+		key := [2]string{result.Host, result.Address}
+		tally := tallies[key]
+		if tally == nil {
+			tally = &tarpitTally{host: result.Host, address: result.Address}
+			tallies[key] = tally
+			order = append(order, tally)
+		}
+		tally.probed++
+		if result.State == "Open" {
+			tally.open++
+			if result.Service == "" {
+				tally.silent++
+			}
+		}
+	}
+
+	flagged := make(map[[2]string]string)
+	var summaries []HostSummary
+	for _, tally := range order {
+		var reason string
+		switch {
+		case tally.probed >= opts.MinPorts && float64(tally.open) >= opts.OpenRatio*float64(tally.probed):
+			reason = fmt.Sprintf("%d of %d probed ports report open", tally.open, tally.probed)
+		case stalls && tally.open >= opts.MinStalled && tally.silent == tally.open:
+			reason = fmt.Sprintf("all %d open ports accepted connections but never answered a probe", tally.open)
+		default:
+			continue
+		}
+		flagged[[2]string{tally.host, tally.address}] = reason
+		summaries = append(summaries, HostSummary{Host: tally.host, Address: tally.address, Tarpit: reason})
+	}
+
+	if opts.Downgrade && len(flagged) > 0 {
+		for i := range results {
+			if _, ok := flagged[[2]string{results[i].Host, results[i].Address}]; ok && results[i].State == "Open" {
+				results[i].State = TarpitState
+			}
+		}
+	}
+	return summaries
+}
+
+// mergeTarpitSummaries marks the flagged hosts in summaries, adding entries
+// for hosts that have none yet.
+func mergeTarpitSummaries(summaries, flagged []HostSummary) []HostSummary {
+	for _, tarpit := range flagged {
+		found := false
+		for i := range summaries {
+			if summaries[i].Host == tarpit.Host && (tarpit.Address == "" || summaries[i].Address == tarpit.Address) {
+				summaries[i].Tarpit = tarpit.Tarpit
+				found = true
+			}
+		}
+		if !found {
+			summaries = append(summaries, tarpit)
+		}
+	}
+	return summaries
+}
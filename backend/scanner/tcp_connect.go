@@ -3,7 +3,6 @@ package scanner
 import (
 	"errors"
 	"net"
-	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -12,24 +11,27 @@ import (
 
 // probeService performs intelligent service detection using probe-based fingerprinting.
 // Reuses the already established connection to avoid connection failures and ensure consistency.
-// Returns service name, raw response banner, and connection validity flag.
+// The probes are tried in order (see ProbeCache.TCPProbesFor) and responses are
+// collected according to opts (see BannerOptions).
+// Returns the matching service rule (nil when unidentified), raw response banner, and connection validity flag.
 // If connectionValid is false, the connection was reset and port should be considered closed.
-func probeService(conn net.Conn, cache *ProbeCache) (string, string, bool) {
-	// Retrieve all TCP probes from cache
-	tcpProbes := cache.GetTCPProbes()
+func probeService(conn net.Conn, tcpProbes []Probe, opts BannerOptions) (*Match, string, bool) {
+	opts = opts.withDefaults()
 
 	// First, check if connection is still alive by trying to read with very short timeout
 	// This detects immediate RST from reverse proxies with no backend
 	_ = conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
 	testBuffer := make([]byte, 1)
-	_, err := conn.Read(testBuffer)
+	n, err := conn.Read(testBuffer)
+	// A byte received here is the start of the service's banner; keep it
+	early := testBuffer[:n]
 
 	// If we get a non-timeout error immediately, connection was reset
 	if err != nil {
 		var netErr net.Error
 		if !errors.As(err, &netErr) || !netErr.Timeout() {
 			// Non-timeout error means connection reset or closed
-			return "", "", false
+			return nil, "", false
 		}
 		// Timeout is fine - just means no immediate data
 	}
@@ -41,47 +43,47 @@ func probeService(conn net.Conn, cache *ProbeCache) (string, string, bool) {
 			_, err := conn.Write(probe.Data)
 			if err != nil {
 				// Write failed - connection is dead
-				return "", "", false
+				return nil, "", false
 			}
 		}
 
-		// Set read timeout for response collection
-		_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
-
 		// Collect server response
-		buffer := make([]byte, 4096)
-		n, err := conn.Read(buffer)
+		response, err := readBanner(conn, opts)
+		if len(early) > 0 {
+			response = append(early, response...)
+			if len(response) > opts.MaxBytes {
+				response = response[:opts.MaxBytes]
+			}
+			early = nil
+			err = nil
+		}
 
 		if err != nil {
 			// Check if it's a connection reset (not just timeout)
 			var netErr net.Error
 			if !errors.As(err, &netErr) || !netErr.Timeout() {
 				// Connection was reset during probing
-				return "", "", false
+				return nil, "", false
 			}
 			continue // Timeout - try next probe
 		}
 
-		if n == 0 {
+		if len(response) == 0 {
 			continue // Empty response - try next probe
 		}
 
-		response := buffer[:n]
-
 		// Match response against this probe's service patterns
-		for _, match := range probe.Matches {
-			if match.Pattern.Match(response) {
-				// Service identified successfully
-				return match.ServiceName, string(response), true
-			}
+		if match := probe.FindMatch(response); match != nil {
+			// Service identified successfully
+			return match, string(response), true
 		}
 
 		// Got a response but no match - return raw banner
-		return "", string(response), true
+		return nil, string(response), true
 	}
 
 	// No service identified but connection is still valid
-	return "", "", true
+	return nil, "", true
 }
 
 // TCPConnectWorker processes scan jobs using TCP Connect scan method.
@@ -91,12 +93,51 @@ func probeService(conn net.Conn, cache *ProbeCache) (string, string, bool) {
 // - Closed: Connection actively refused (RST received)
 // - Filtered: Timeout or no response (firewall blocking or accepting without backend)
 // - Open: Connection accepted AND service responds
-func TCPConnectWorker(jobs <-chan ScanJob, results chan<- ScanResult, cache *ProbeCache, wg *sync.WaitGroup) {
+// Dial timeouts and per-host concurrency adapt to the target via congestion control.
+func TCPConnectWorker(jobs <-chan ScanJob, results chan<- ScanResult, cache *ProbeCache, state *ScanState, wg *sync.WaitGroup) {
 	for job := range jobs {
-		address := job.Host + ":" + strconv.Itoa(job.Port)
+		// Resolve through the per-scan cache so each hostname is looked up once
+		address, err := state.resolver.dialAddress(job.target(), job.Port)
+		if err != nil {
+			// Unresolvable target - cannot determine port state
+			results <- ScanResult{Host: job.Host, Port: job.Port, State: "Filtered", Address: job.Address}
+			wg.Done()
+			continue
+		}
 
-		// Attempt TCP connection to determine basic accessibility
-		conn, err := net.DialTimeout("tcp", address, 2*time.Second)
+		// Respect per-host pacing and congestion window, using the adaptive
+		// timeout, and dial again while the host stays silent
+		var hostCtl *hostCongestion
+		var timeout, rtt time.Duration
+		var start time.Time
+		var conn net.Conn
+		probed := false
+		for attempt := 0; ; attempt++ {
+			ctl, probeTimeout, admitted := state.admit(job.target())
+			if !admitted {
+				break
+			}
+			hostCtl, timeout, probed = ctl, probeTimeout, true
+
+			// Attempt TCP connection to determine basic accessibility
+			start = time.Now()
+			state.trace.sent("tcp", "", address, "S", 0, "method", "connect")
+			conn, err = net.DialTimeout("tcp", address, timeout)
+			rtt = time.Since(start)
+			var netErr net.Error
+			if attempt >= state.timing.MaxRetries || !errors.As(err, &netErr) || !netErr.Timeout() || state.ctx.Err() != nil {
+				break
+			}
+			state.trace.silence("tcp", address, timeout)
+			hostCtl.release(rtt, false)
+			hostCtl = nil
+		}
+		if !probed {
+			// The scan ended before the port was probed
+			wg.Done()
+			continue
+		}
+		responded := true
 
 		var result ScanResult
 
@@ -108,17 +149,24 @@ func TCPConnectWorker(jobs <-chan ScanJob, results chan<- ScanResult, cache *Pro
 			var netErr net.Error
 			if errors.As(err, &netErr) && netErr.Timeout() {
 				// Timeout - packets are being silently dropped by firewall
+				responded = false
+				state.trace.silence("tcp", address, timeout)
 				result = ScanResult{Host: job.Host, Port: job.Port, State: "Filtered"}
 			} else if isConnectionRefused(err) {
 				// Connection actively refused (RST) - port is definitively closed
+				state.trace.received("tcp", address, "", "R", 0, rtt, "method", "connect")
 				result = ScanResult{Host: job.Host, Port: job.Port, State: "Closed"}
 			} else {
 				// Other network errors - treat as filtered (unreachable, no route, etc.)
+				responded = false
+				state.trace.received("tcp", address, "", "", 0, rtt, "method", "connect", "error", err.Error())
 				result = ScanResult{Host: job.Host, Port: job.Port, State: "Filtered"}
 			}
 		} else {
 			// TCP handshake succeeded - perform probe-based service identification
-			serviceName, rawBanner, connValid := probeService(conn, cache)
+			state.trace.received("tcp", address, conn.LocalAddr().String(), "SA", 0, rtt, "method", "connect")
+			match, rawBanner, connValid := probeService(conn, cache.TCPProbesFor(job.Port, state.intensity), state.banner)
+			state.trace.received("tcp", address, conn.LocalAddr().String(), "", len(rawBanner), time.Since(start), "method", "connect", "stage", "banner", "reset", !connValid)
 			_ = conn.Close() // Close connection after probing
 
 			// If connection was reset during probing, treat as closed
@@ -127,19 +175,41 @@ func TCPConnectWorker(jobs <-chan ScanJob, results chan<- ScanResult, cache *Pro
 				result = ScanResult{Host: job.Host, Port: job.Port, State: "Closed"}
 			} else {
 				// Connection remained valid - port is OPEN
-				serviceDescription := serviceName
-				if serviceDescription == "" && rawBanner != "" {
-					serviceDescription = rawBanner
+				result = ScanResult{Host: job.Host, Port: job.Port, State: "Open"}
+				if match != nil {
+					result.setMatch(match, rawBanner)
+				} else {
+					result.Service, result.BannerBase64 = bannerText(rawBanner)
+				}
+				identified := match != nil
+				if service := detectTLS(job, address, match, rawBanner, cache, state, timeout); service != nil {
+					identified = service.apply(&result, identified)
+				}
+				if !identified {
+					state.guessService(&result, "tcp")
 				}
-				result = ScanResult{Host: job.Host, Port: job.Port, State: "Open", Service: serviceDescription}
 			}
 		}
 
+		if hostCtl != nil {
+			hostCtl.release(rtt, responded)
+		}
+		result.Address = job.Address
+		result.Family = state.resolver.family(job.target())
+
 		results <- result
 		wg.Done()
 	}
 }
 
+// setMatch records the service m identified from banner in r.
+func (r *ScanResult) setMatch(m *Match, banner string) {
+	details := m.Details([]byte(banner))
+	r.Service, r.BannerBase64 = m.ServiceName, ""
+	r.Product, r.Version = details.Product, details.Version
+	r.Info, r.Hostname, r.OS, r.CPE = details.Info, details.Hostname, details.OS, details.CPE
+}
+
 // isConnectionRefused checks if the error is a connection refused error.
 // Connection refused (RST packet) indicates the port is definitively closed.
 func isConnectionRefused(err error) bool {
@@ -0,0 +1,133 @@
+package scanner
+
+import (
+	"sync"
+	"time"
+)
+
+// tcpProbeFlags selects the control flags a raw TCP probe carries.
+type tcpProbeFlags struct {
+	SYN, FIN, PSH, URG, ACK bool
+}
+
+// resetState is the state of a port that answers a probe with RST: Closed,
+// or Unfiltered for an ACK probe, which draws RST from open and closed ports
+// alike when no firewall stands in between.
+func (f tcpProbeFlags) resetState() string {
+	if f.ACK {
+		return "Unfiltered"
+	}
+	return "Closed"
+}
+
+// silentState is the state of a port that never answers a probe. Open ports
+// drop FIN, NULL and Xmas probes just as a firewall would, so those report
+// Open|Filtered.
+func (f tcpProbeFlags) silentState() string {
+	if f.SYN || f.ACK {
+		return "Filtered"
+	}
+	return "Open|Filtered"
+}
+
+// Flag combinations of the raw TCP scan modes. RFC 793 has a port that is not
+// listening answer any segment without RST by a RST, while a listening port
+// silently drops one without SYN, RST or ACK set; FIN, NULL and Xmas probes
+// rely on that to tell closed ports from the rest without a handshake. A bare
+// ACK draws a RST from open and closed ports alike.
+var (
+	synProbe  = tcpProbeFlags{SYN: true}
+	finProbe  = tcpProbeFlags{FIN: true}
+	nullProbe = tcpProbeFlags{}
+	xmasProbe = tcpProbeFlags{FIN: true, PSH: true, URG: true}
+	ackProbe  = tcpProbeFlags{ACK: true}
+)
+
+// TCPFinWorker processes scan jobs using TCP FIN scan: a lone FIN draws a RST
+// from closed ports and nothing from open ones. Ports that stay silent are
+// reported Open|Filtered, as a firewall dropping the probe looks the same.
+// Stacks that ignore RFC 793 here, notably Windows, answer every probe with
+// RST and so show all ports closed. Requires the privileges of SYN scanning.
+func TCPFinWorker(jobs <-chan ScanJob, results chan<- ScanResult, cache *ProbeCache, state *ScanState, wg *sync.WaitGroup) {
+	stealthWorker(finProbe, jobs, results, state, wg)
+}
+
+// TCPNullWorker processes scan jobs using TCP NULL scan, sending segments with
+// no flags set and reading the answers as TCPFinWorker does.
+func TCPNullWorker(jobs <-chan ScanJob, results chan<- ScanResult, cache *ProbeCache, state *ScanState, wg *sync.WaitGroup) {
+	stealthWorker(nullProbe, jobs, results, state, wg)
+}
+
+// TCPXmasWorker processes scan jobs using TCP Xmas scan, sending segments
+// with FIN, PSH and URG set and reading the answers as TCPFinWorker does.
+func TCPXmasWorker(jobs <-chan ScanJob, results chan<- ScanResult, cache *ProbeCache, state *ScanState, wg *sync.WaitGroup) {
+	stealthWorker(xmasProbe, jobs, results, state, wg)
+}
+
+// TCPAckWorker processes scan jobs using TCP ACK scan, which maps firewall
+// rules rather than open ports: a bare ACK that belongs to no connection
+// draws a RST from any reachable port, reported Unfiltered, while a stateful
+// firewall drops it or a router answers it with ICMP destination
+// unreachable, leaving the port Filtered. It cannot tell open from closed
+// ports.
+func TCPAckWorker(jobs <-chan ScanJob, results chan<- ScanResult, cache *ProbeCache, state *ScanState, wg *sync.WaitGroup) {
+	stealthWorker(ackProbe, jobs, results, state, wg)
+}
+
+// stealthWorker probes every job with flags through the scan's SYN capture
+// handle, sending silent ports again up to the timing's retries with
+// exponential backoff.
+func stealthWorker(flags tcpProbeFlags, jobs <-chan ScanJob, results chan<- ScanResult, state *ScanState, wg *sync.WaitGroup) {
+	for job := range jobs {
+		var portState string
+		for attempt := 0; ; attempt++ {
+			if attempt > 0 && !state.retryWait(attempt) {
+				break
+			}
+			hostCtl, timeout, admitted := state.admit(job.target())
+			if !admitted {
+				break
+			}
+			var rtt time.Duration
+			var err error
+			portState, rtt, err = performStealthScan(state, job.target(), job.Port, flags, timeout)
+			// Only answers carry a round-trip time: an ICMP unreachable
+			// reports the same state as silence to an ACK, and a local error
+			// that of silence to a SYN or ACK, yet neither is worth retrying
+			answered := rtt > 0
+			hostCtl.release(rtt, answered)
+			if answered || err != nil || attempt >= state.timing.MaxRetries {
+				break
+			}
+		}
+		if portState == "" {
+			// The scan ended before the port was probed
+			wg.Done()
+			continue
+		}
+
+		result := ScanResult{Host: job.Host, Port: job.Port, State: portState, Address: job.Address, Family: state.resolver.family(job.target())}
+		state.guessService(&result, "tcp")
+		results <- result
+		wg.Done()
+	}
+}
+
+// performStealthScan sends one probe carrying flags to host:port and returns
+// the port state along with the round-trip time of the answer, zero when
+// none arrived:
+// - "Closed" ("Unfiltered" for ACK probes): RST received
+// - "Open|Filtered" ("Filtered" for ACK probes): no answer within timeout
+// - "Filtered": ICMP unreachable received, or local errors (cannot
+// determine state), the latter also returned as the error
+func performStealthScan(state *ScanState, host string, port int, flags tcpProbeFlags, timeout time.Duration) (string, time.Duration, error) {
+	syn, err := state.synSession()
+	if err != nil {
+		return "Filtered", 0, err // Local error - no usable interface or capture handle
+	}
+	dstIP, err := state.resolver.address(host)
+	if err != nil {
+		return "Filtered", 0, err // DNS resolution failed - cannot determine port state
+	}
+	return syn.probe(dstIP, port, flags, timeout, state.capture, state.trace)
+}
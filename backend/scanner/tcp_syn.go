@@ -1,9 +1,12 @@
 package scanner
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
+	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,101 +21,228 @@ import (
 // Requires elevated privileges (root/administrator) for raw socket access.
 // Note: cache parameter is unused as SYN scan operates at packet level and cannot
 // perform application-layer service detection.
-func TCPSynWorker(jobs <-chan ScanJob, results chan<- ScanResult, cache *ProbeCache, wg *sync.WaitGroup) {
+func TCPSynWorker(jobs <-chan ScanJob, results chan<- ScanResult, cache *ProbeCache, state *ScanState, wg *sync.WaitGroup) {
 	_ = cache // Unused: SYN scanning operates at network layer only
 	for job := range jobs {
-		state := performSynScan(job.Host, job.Port)
-		result := ScanResult{Host: job.Host, Port: job.Port, State: state}
+		// Silent ports are probed again up to the timing's retries, backing off
+		// exponentially in between
+		var portState string
+		for attempt := 0; ; attempt++ {
+			if attempt > 0 && !state.retryWait(attempt) {
+				break
+			}
+			hostCtl, timeout, admitted := state.admit(job.target())
+			if !admitted {
+				break
+			}
+			var rtt time.Duration
+			var err error
+			portState, rtt, err = performSynScan(state, job.target(), job.Port, timeout)
+			// Only answers carry a round-trip time; local errors are not
+			// retried, as sending again cannot fix them
+			answered := rtt > 0
+			hostCtl.release(rtt, answered)
+			if answered || err != nil || attempt >= state.timing.MaxRetries {
+				break
+			}
+		}
+		if portState == "" {
+			// The scan ended before the port was probed
+			wg.Done()
+			continue
+		}
+
+		result := ScanResult{Host: job.Host, Port: job.Port, State: portState, Address: job.Address, Family: state.resolver.family(job.target())}
+		state.guessService(&result, "tcp")
 		results <- result
 		wg.Done()
 	}
 }
 
 // performSynScan executes a TCP SYN scan on a single target port.
-// Constructs and sends a raw TCP SYN packet, then analyzes the response
-// to determine port state. Returns:
+// Sends a raw TCP SYN packet through the scan's shared capture handle and
+// waits for the answer, returning the port state along with the round-trip
+// time of the answer, zero when none arrived:
 // - "Open": SYN-ACK received (port accepting connections)
 // - "Closed": RST received (port actively refusing connections)
-// - "Filtered": Timeout or local errors (cannot determine state)
-func performSynScan(host string, port int) string {
-	// Find all available network interfaces
-	ifaces, err := net.Interfaces()
+// - "Filtered": ICMP unreachable received, timeout, or local errors
+// (cannot determine state), the latter also returned as the error
+// Sent and received packets are recorded and traced when the scan asks for it.
+func performSynScan(state *ScanState, host string, port int, timeout time.Duration) (string, time.Duration, error) {
+	syn, err := state.synSession()
 	if err != nil {
-		return "Filtered" // Local error - cannot determine port state
+		return "Filtered", 0, err // Local error - no usable interface or capture handle
 	}
 
-	var srcIP net.IP
-	var device *net.Interface
+	// Resolve target hostname via the per-scan cache, picking the family the
+	// scan prefers
+	dstIP, err := state.resolver.address(host)
+	if err != nil {
+		return "Filtered", 0, err // DNS resolution failed - cannot determine port state
+	}
+	return syn.probe(dstIP, port, synProbe, timeout, state.capture, state.trace)
+}
 
-	// Select a suitable network interface and source IP address
-	// Criteria: interface must be up, not loopback, and have an IPv4 address
-	for _, iface := range ifaces {
-		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+// synSession returns the capture handle SYN probes of this scan share,
+// opening it on first use.
+func (s *ScanState) synSession() (*synCapture, error) {
+	s.synOnce.Do(func() {
+		s.syn, s.synErr = openSynCapture()
+	})
+	return s.syn, s.synErr
+}
+
+// synCapture sends SYN probes and receives their answers through a single
+// pcap handle on the source interface. One read loop hands every reply to the
+// probe waiting for it, so a scan opens one handle instead of one per port.
+// Probes are framed for the device's link layer before injection.
+type synCapture struct {
+	handle *pcap.Handle
+	src    sourceAddresses
+	link   *linkFramer
+	// writeMu serializes injection; the read loop uses the handle alone
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	waiting map[synKey]chan gopacket.Packet
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// synKey identifies the answer to one probe: the target address, the target
+// port and the local source port the probe was sent from.
+type synKey struct {
+	dstIP   string
+	dstPort uint16
+	srcPort uint16
+}
+
+// openSynCapture opens the capture handle for the source interface, limited
+// to TCP and ICMP destination unreachable messages addressed to its source
+// addresses, and starts its read loop.
+func openSynCapture() (*synCapture, error) {
+	src, err := sourceInterface()
+	if err != nil {
+		return nil, err
+	}
+	// Map the OS interface to its capture device (differs on Windows/Npcap)
+	deviceName, err := captureDevice(src.iface, src.primary())
+	if err != nil {
+		return nil, err
+	}
+	handle, err := pcap.OpenLive(deviceName, captureSnapLen, false, captureReadTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := handle.SetBPFFilter("(tcp or " + icmpUnreachableFilter + ") and " + src.hostFilter("dst ")); err != nil {
+		handle.Close()
+		return nil, err
+	}
+	link, err := newLinkFramer(handle.LinkType(), deviceName, src)
+	if err != nil {
+		handle.Close()
+		return nil, err
+	}
+
+	c := &synCapture{
+		handle:  handle,
+		src:     src,
+		link:    link,
+		waiting: make(map[synKey]chan gopacket.Packet),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go c.read()
+	return c, nil
+}
+
+// read demultiplexes captured packets, TCP answers and ICMP errors quoting a
+// probe, to the probes waiting for them until close is called. Packets
+// nobody waits for are dropped.
+func (c *synCapture) read() {
+	defer close(c.stopped)
+	linkType := c.handle.LinkType()
+	for {
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+		data, info, err := c.handle.ReadPacketData()
+		if err == pcap.NextErrorTimeoutExpired {
 			continue
 		}
-		addrs, err := iface.Addrs()
 		if err != nil {
+			return
+		}
+		packet := gopacket.NewPacket(data, linkType, gopacket.Default)
+		packet.Metadata().CaptureInfo = info
+		remote, _ := packetAddresses(packet)
+		if remote == nil {
 			continue
 		}
-		for _, addr := range addrs {
-			if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-				if ipnet.IP.To4() != nil {
-					srcIP = ipnet.IP.To4()
-					device = &iface
-					break
-				}
-			}
+		var key synKey
+		if tcpPacket, ok := packet.Layer(layers.LayerTypeTCP).(*layers.TCP); ok {
+			key = synKey{dstIP: remote.String(), dstPort: uint16(tcpPacket.SrcPort), srcPort: uint16(tcpPacket.DstPort)}
+		} else if key, _, ok = unreachableMessage(packet, layers.IPProtocolTCP); !ok {
+			continue
 		}
-		if srcIP != nil {
-			break
+		c.mu.Lock()
+		replies := c.waiting[key]
+		c.mu.Unlock()
+		if replies != nil {
+			select {
+			case replies <- packet:
+			default:
+			}
 		}
 	}
+}
 
-	if srcIP == nil || device == nil {
-		return "Filtered" // Local error - no suitable interface found
-	}
-
-	// Resolve target hostname to IP address
-	dstIPs, err := net.LookupIP(host)
-	if err != nil {
-		return "Filtered" // DNS resolution failed - cannot determine port state
-	}
-
-	dstIP := dstIPs[0].To4()
-	if dstIP == nil {
-		return "Filtered" // IPv6 or invalid IP - not supported
-	}
-
-	// Open packet capture handle for raw packet transmission and reception
-	handle, err := pcap.OpenLive(device.Name, 65535, false, 2*time.Second)
-	if err != nil {
-		return "Filtered" // Local error - cannot open pcap handle
+// register reserves a random ephemeral source port for a probe of dstIP:port
+// that no other probe in flight to the same port uses, and returns the
+// channel its answers arrive on.
+func (c *synCapture) register(dstIP net.IP, port uint16) (synKey, chan gopacket.Packet) {
+	replies := make(chan gopacket.Packet, 4)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		key := synKey{dstIP: dstIP.String(), dstPort: port, srcPort: uint16(rand.Intn(65535-1024) + 1024)}
+		if _, taken := c.waiting[key]; !taken {
+			c.waiting[key] = replies
+			return key, replies
+		}
 	}
-	defer handle.Close()
-
-	// Construct TCP SYN packet with randomized source port
-	srcPort := uint16(rand.Intn(65535-1024) + 1024) // Use ephemeral port range
-	dstPort := uint16(port)
+}
 
-	// Update BPF filter to include destination port for precise packet capture
-	// This prevents false positives from unrelated traffic
-	filter := fmt.Sprintf("tcp and src host %s and src port %d and dst host %s and dst port %d",
-		dstIP.String(), port, srcIP.String(), srcPort)
-	if err := handle.SetBPFFilter(filter); err != nil {
-		return "Filtered" // Local error - cannot set BPF filter
-	}
+func (c *synCapture) unregister(key synKey) {
+	c.mu.Lock()
+	delete(c.waiting, key)
+	c.mu.Unlock()
+}
 
-	ipLayer := &layers.IPv4{
-		SrcIP:    srcIP,
-		DstIP:    dstIP,
-		Protocol: layers.IPProtocolTCP,
-		TTL:      64,
+// probe sends one segment carrying flags to dstIP:port and classifies the
+// answer. A SYN is answered by SYN-ACK when the port is open; any other probe
+// is classified by whether it draws a RST, see tcpProbeFlags.resetState. An
+// ICMP destination unreachable message quoting the probe makes the port
+// Filtered whatever the flags.
+func (c *synCapture) probe(dstIP net.IP, port int, flags tcpProbeFlags, timeout time.Duration, capture *packetRecorder, trace *packetTracer) (string, time.Duration, error) {
+	ipLayer, srcIP := c.src.networkLayer(dstIP, layers.IPProtocolTCP)
+	if ipLayer == nil {
+		return "Filtered", 0, fmt.Errorf("no source address of the family of %s", dstIP) // Local error
 	}
+	key, replies := c.register(dstIP, uint16(port))
+	defer c.unregister(key)
+	srcPort := key.srcPort
+	local, remote := hostPort(srcIP, int(srcPort)), hostPort(dstIP, port)
 
 	tcpLayer := &layers.TCP{
 		SrcPort: layers.TCPPort(srcPort),
-		DstPort: layers.TCPPort(dstPort),
-		SYN:     true,
+		DstPort: layers.TCPPort(port),
+		SYN:     flags.SYN,
+		FIN:     flags.FIN,
+		PSH:     flags.PSH,
+		URG:     flags.URG,
+		ACK:     flags.ACK,
 		Seq:     rand.Uint32(),
 	}
 
@@ -127,54 +257,252 @@ func performSynScan(host string, port int) string {
 	}
 
 	if err := gopacket.SerializeLayers(buffer, opts, ipLayer, tcpLayer); err != nil {
-		return "Filtered" // Local error - cannot serialize packet
+		return "Filtered", 0, err // Local error - cannot serialize packet
+	}
+	// Captures keep the IP packet, without the link-layer header
+	ipPacket := append([]byte(nil), buffer.Bytes()...)
+	if err := c.link.frame(buffer, dstIP); err != nil {
+		return "Filtered", 0, err // No next hop - the target's neighbour or router did not answer
 	}
 
-	// Transmit the SYN packet to the target
-	if err := handle.WritePacketData(buffer.Bytes()); err != nil {
-		return "Filtered" // Local error - cannot send packet
+	// Transmit the probe to the target
+	sentAt := time.Now()
+	c.writeMu.Lock()
+	err := c.handle.WritePacketData(buffer.Bytes())
+	c.writeMu.Unlock()
+	if err != nil {
+		return "Filtered", 0, err // Local error - cannot send packet
 	}
+	capture.recordRaw(sentAt, ipPacket)
+	trace.sent("tcp", local, remote, tcpFlags(tcpLayer), len(ipPacket), "seq", tcpLayer.Seq)
 
 	// Listen for TCP response with timeout
-	timeout := time.After(2 * time.Second)
-	packetSource := gopacket.NewPacketSource(handle, handle.LinkType())
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
 
 	for {
 		select {
-		case packet := <-packetSource.Packets():
-			if packet == nil {
-				return "Filtered" // No packet received - ambiguous state
-			}
+		case packet := <-replies:
+			capture.recordPacket(packet)
 
-			// Extract TCP layer and analyze flags
-			if tcpPacket, ok := packet.Layer(layers.LayerTypeTCP).(*layers.TCP); ok {
-				if tcpPacket.SYN && tcpPacket.ACK {
-					return "Open" // SYN-ACK indicates open port
-				}
-				if tcpPacket.RST {
-					return "Closed" // RST indicates closed port
+			tcpPacket, ok := packet.Layer(layers.LayerTypeTCP).(*layers.TCP)
+			if !ok {
+				_, message, _ := unreachableMessage(packet, layers.IPProtocolTCP)
+				trace.received(message.protocol, remote, local, "", len(packet.Data()), time.Since(sentAt), "type", message.kind, "code", message.code)
+				if message.state != "" {
+					return message.state, time.Since(sentAt), nil // Unreachable - a host or router refused the probe
 				}
+				continue
+			}
+
+			// Analyze the TCP flags
+			trace.received("tcp", remote, local, tcpFlags(tcpPacket), len(packet.Data()), time.Since(sentAt), "ttl", packetTTL(packet))
+			if flags.SYN && tcpPacket.SYN && tcpPacket.ACK {
+				return "Open", time.Since(sentAt), nil // SYN-ACK indicates open port
+			}
+			if tcpPacket.RST {
+				return flags.resetState(), time.Since(sentAt), nil // RST indicates closed port, or no firewall to an ACK
 			}
 
-		case <-timeout:
-			return "Filtered" // Timeout - packets likely dropped by firewall
+		case <-c.stopped:
+			return "Filtered", 0, errors.New("packet capture stopped") // Capture failed - ambiguous state
+
+		case <-deadline.C:
+			trace.silence("tcp", remote, timeout)
+			return flags.silentState(), 0, nil // Timeout - dropped by a firewall, or by an open port
+		}
+	}
+}
+
+// close stops the read loop and releases the capture handle.
+func (c *synCapture) close() {
+	close(c.done)
+	<-c.stopped
+	c.handle.Close()
+	c.link.close()
+}
+
+// sourceAddresses are the interface raw probes are sent from and its source
+// address of each family, nil for a family it lacks.
+type sourceAddresses struct {
+	iface *net.Interface
+	ipv4  net.IP
+	ipv6  net.IP
+}
+
+// sourceInterface selects the interface raw probes are sent from: the first
+// interface that is up, not loopback, and has an IPv4 address, or one with a
+// global IPv6 address when no interface has IPv4. IPv6 probes leave from the
+// interface's first global IPv6 address.
+func sourceInterface() (sourceAddresses, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return sourceAddresses{}, err
+	}
+	var ipv6Only sourceAddresses
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		src := sourceAddresses{iface: &iface}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok || ipnet.IP.IsLoopback() {
+				continue
+			}
+			if v4 := ipnet.IP.To4(); v4 != nil && src.ipv4 == nil {
+				src.ipv4 = v4
+			} else if v4 == nil && ipnet.IP.IsGlobalUnicast() && src.ipv6 == nil {
+				src.ipv6 = ipnet.IP
+			}
+		}
+		if src.ipv4 != nil {
+			return src, nil
+		}
+		if src.ipv6 != nil && ipv6Only.iface == nil {
+			ipv6Only = src
+		}
+	}
+	if ipv6Only.iface != nil {
+		return ipv6Only, nil
+	}
+	return sourceAddresses{}, errors.New("no usable network interface found")
+}
+
+// primary returns the source address that identifies the interface's
+// capture device.
+func (s sourceAddresses) primary() net.IP {
+	if s.ipv4 != nil {
+		return s.ipv4
+	}
+	return s.ipv6
+}
+
+// hostFilter returns a BPF expression matching packets whose direction
+// ("dst ", "src " or "" for either) host is one of the source addresses.
+func (s sourceAddresses) hostFilter(direction string) string {
+	var hosts []string
+	for _, ip := range []net.IP{s.ipv4, s.ipv6} {
+		if ip != nil {
+			hosts = append(hosts, direction+"host "+ip.String())
+		}
+	}
+	return "(" + strings.Join(hosts, " or ") + ")"
+}
+
+// ipHeader is the IPv4 or IPv6 header of a raw probe.
+type ipHeader interface {
+	gopacket.NetworkLayer
+	gopacket.SerializableLayer
+}
+
+// networkLayer returns the header of a probe carrying protocol to dstIP
+// along with its source address, or nil when the interface has no address
+// of dstIP's family.
+func (s sourceAddresses) networkLayer(dstIP net.IP, protocol layers.IPProtocol) (ipHeader, net.IP) {
+	if v4 := dstIP.To4(); v4 != nil {
+		if s.ipv4 == nil {
+			return nil, nil
+		}
+		return &layers.IPv4{Version: 4, SrcIP: s.ipv4, DstIP: v4, Protocol: protocol, TTL: 64}, s.ipv4
+	}
+	if s.ipv6 == nil {
+		return nil, nil
+	}
+	return &layers.IPv6{Version: 6, SrcIP: s.ipv6, DstIP: dstIP, NextHeader: protocol, HopLimit: 64}, s.ipv6
+}
+
+// packetAddresses returns the source and destination address of an IPv4 or
+// IPv6 packet, or nils for other packets.
+func packetAddresses(packet gopacket.Packet) (src, dst net.IP) {
+	switch ip := packet.NetworkLayer().(type) {
+	case *layers.IPv4:
+		return ip.SrcIP, ip.DstIP
+	case *layers.IPv6:
+		return ip.SrcIP, ip.DstIP
+	}
+	return nil, nil
+}
+
+// packetTTL returns the TTL of an IPv4 packet or the hop limit of an IPv6
+// one.
+func packetTTL(packet gopacket.Packet) uint8 {
+	switch ip := packet.NetworkLayer().(type) {
+	case *layers.IPv4:
+		return ip.TTL
+	case *layers.IPv6:
+		return ip.HopLimit
+	}
+	return 0
+}
+
+// captureDevices caches capture device names by source IP, since enumerating
+// pcap devices is expensive and the answer does not change during a run.
+var captureDevices sync.Map
+
+// captureDevice returns the pcap device name for the interface owning srcIP.
+// libpcap on Unix uses the OS interface name, while Npcap on Windows exposes
+// devices as \Device\NPF_{GUID}; matching on the assigned address works for both.
+func captureDevice(iface *net.Interface, srcIP net.IP) (string, error) {
+	if name, ok := captureDevices.Load(srcIP.String()); ok {
+		return name.(string), nil
+	}
+
+	devices, err := pcap.FindAllDevs()
+	if err == nil {
+		for _, dev := range devices {
+			for _, addr := range dev.Addresses {
+				if addr.IP.Equal(srcIP) {
+					captureDevices.Store(srcIP.String(), dev.Name)
+					return dev.Name, nil
+				}
+			}
 		}
 	}
+
+	// Npcap device names never match the friendly interface name
+	if runtime.GOOS == "windows" {
+		return "", fmt.Errorf("no Npcap capture device found for interface %q (%s)", iface.Name, srcIP)
+	}
+	captureDevices.Store(srcIP.String(), iface.Name)
+	return iface.Name, nil
 }
 
+// ErrInsufficientPrivileges reports that the process may not capture or inject
+// raw packets. Callers can use it to fall back to an unprivileged scan mode.
+var ErrInsufficientPrivileges = errors.New("insufficient privileges for raw packet access")
+
 // InitSynScan validates that the system meets prerequisites for SYN scanning.
-// Checks for libpcap availability and verifies elevated privileges by attempting
-// to enumerate network devices. Returns error if requirements are not satisfied.
+// Checks for the packet capture driver (libpcap, or Npcap on Windows) and verifies
+// elevated privileges by enumerating network devices and opening one for capture.
+// Returns an actionable error if requirements are not satisfied; privilege problems
+// wrap ErrInsufficientPrivileges.
 func InitSynScan() error {
-	// Enumerate network devices (requires elevated privileges)
+	// Make sure the capture driver itself is present before using it
+	if err := checkPacketDriver(); err != nil {
+		return err
+	}
+
+	// Enumerate network devices (requires elevated privileges on some platforms)
 	devices, err := pcap.FindAllDevs()
 	if err != nil {
-		return fmt.Errorf("SYN scan requires root/administrator privileges and libpcap: %v", err)
+		return fmt.Errorf("SYN scan cannot enumerate capture devices (%s): %v: %w", packetDriverHint, err, ErrInsufficientPrivileges)
 	}
 
 	if len(devices) == 0 {
-		return fmt.Errorf("no network devices found for SYN scan")
+		return fmt.Errorf("no network devices found for SYN scan (%s): %w", packetDriverHint, ErrInsufficientPrivileges)
+	}
+
+	// Listing devices can succeed without privileges; opening one cannot
+	handle, err := pcap.OpenLive(devices[0].Name, 65535, false, time.Second)
+	if err != nil {
+		return fmt.Errorf("SYN scan cannot open capture device %s (%s): %v: %w", devices[0].Name, packetDriverHint, err, ErrInsufficientPrivileges)
 	}
+	handle.Close()
 
 	return nil
 }
@@ -0,0 +1,120 @@
+package scanner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timing tunes how patiently and how aggressively a scan probes each host.
+// The zero value is the normal template; templates for the other levels come
+// from TimingTemplate, and single fields may be overridden afterwards.
+type Timing struct {
+	// InitialTimeout is the probe timeout used until a host's round-trip
+	// time has been measured. Zero uses 2s.
+	InitialTimeout time.Duration
+	// MinTimeout and MaxTimeout bound the adaptive probe timeout. Zero uses
+	// 250ms and 10s.
+	MinTimeout time.Duration
+	MaxTimeout time.Duration
+	// MaxParallelism caps the probes in flight against one host, however
+	// well it responds. Zero uses 100.
+	MaxParallelism int
+	// ScanDelay is the minimum time between two probes to one host. It only
+	// applies when ScanOptions.HostRate is unset.
+	ScanDelay time.Duration
+	// MaxRetries is how often a probe that drew no answer is sent again
+	// before the port is reported filtered (open|filtered for UDP).
+	MaxRetries int
+	// RetryBackoff is how long SYN, raw TCP and UDP scans wait before sending
+	// a silent probe again, doubling for every further attempt up to
+	// MaxTimeout, so a burst of loss or a rate-limiting firewall has passed
+	// when the retransmission goes out. Zero uses 100ms.
+	RetryBackoff time.Duration
+	// BannerTimeout replaces DefaultBannerReadTimeout when
+	// BannerOptions.ReadTimeout is unset.
+	BannerTimeout time.Duration
+}
+
+// TimingNames names the timing templates by level, as nmap's -T0 to -T5 do.
+var TimingNames = []string{"paranoid", "sneaky", "polite", "normal", "aggressive", "insane"}
+
+// DefaultTimingLevel is the level of the zero Timing.
+const DefaultTimingLevel = 3
+
+// timingTemplates holds the template of every level. Paranoid to polite
+// probe one port of a host at a time with a delay in between, to stay below
+// intrusion detection thresholds; aggressive and insane assume a fast,
+// reliable network and give up on silent ports sooner.
+var timingTemplates = []Timing{
+	{InitialTimeout: 10 * time.Second, MaxParallelism: 1, ScanDelay: 5 * time.Minute, MaxRetries: 3, BannerTimeout: 5 * time.Second},
+	{InitialTimeout: 10 * time.Second, MaxParallelism: 1, ScanDelay: 15 * time.Second, MaxRetries: 2, BannerTimeout: 5 * time.Second},
+	{MaxParallelism: 1, ScanDelay: 400 * time.Millisecond, MaxRetries: 1},
+	{},
+	{InitialTimeout: 500 * time.Millisecond, MinTimeout: 100 * time.Millisecond, MaxTimeout: 1250 * time.Millisecond, BannerTimeout: 2 * time.Second},
+	{InitialTimeout: 250 * time.Millisecond, MinTimeout: 50 * time.Millisecond, MaxTimeout: 300 * time.Millisecond, BannerTimeout: time.Second},
+}
+
+// TimingTemplate returns the template of level, 0 (paranoid) to 5 (insane).
+func TimingTemplate(level int) (Timing, error) {
+	if level < 0 || level >= len(timingTemplates) {
+		return Timing{}, fmt.Errorf("timing level %d out of range 0-%d", level, len(timingTemplates)-1)
+	}
+	return timingTemplates[level], nil
+}
+
+// ParseTiming returns the template named by name: a level from 0 to 5 or a
+// name from TimingNames. An empty name is the normal template.
+func ParseTiming(name string) (Timing, error) {
+	if name == "" {
+		return Timing{}, nil
+	}
+	if level, err := strconv.Atoi(name); err == nil {
+		return TimingTemplate(level)
+	}
+	for level, known := range TimingNames {
+		if strings.EqualFold(name, known) {
+			return TimingTemplate(level)
+		}
+	}
+	return Timing{}, fmt.Errorf("unknown timing template %q (use 0-5 or %s)", name, strings.Join(TimingNames, ", "))
+}
+
+// withDefaults fills unset fields and keeps the timeouts ordered.
+func (t Timing) withDefaults() Timing {
+	if t.InitialTimeout <= 0 {
+		t.InitialTimeout = initialProbeTimeout
+	}
+	if t.MinTimeout <= 0 {
+		t.MinTimeout = minProbeTimeout
+	}
+	if t.MaxTimeout <= 0 {
+		t.MaxTimeout = maxProbeTimeout
+	}
+	if t.MaxParallelism <= 0 {
+		t.MaxParallelism = int(maxHostWindow)
+	}
+	if t.MaxRetries < 0 {
+		t.MaxRetries = 0
+	}
+	if t.RetryBackoff <= 0 {
+		t.RetryBackoff = defaultRetryBackoff
+	}
+	t.MinTimeout = min(t.MinTimeout, t.MaxTimeout)
+	t.InitialTimeout = min(max(t.InitialTimeout, t.MinTimeout), t.MaxTimeout)
+	return t
+}
+
+// defaultRetryBackoff is the RetryBackoff of the zero Timing.
+const defaultRetryBackoff = 100 * time.Millisecond
+
+// retryDelay returns the wait before retransmission attempt of a silent
+// probe, attempt 1 being the first one.
+func (t Timing) retryDelay(attempt int) time.Duration {
+	delay := t.RetryBackoff
+	for i := 1; i < attempt && delay < t.MaxTimeout; i++ {
+		delay *= 2
+	}
+	return min(delay, max(t.MaxTimeout, t.RetryBackoff))
+}
@@ -0,0 +1,205 @@
+package scanner
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"strings"
+	"time"
+)
+
+// Certificate describes the leaf certificate a TLS service presented.
+type Certificate struct {
+	// Subject is the distinguished name the certificate was issued to.
+	Subject string `json:"subject" example:"CN=www.example.com,O=Example Inc,C=US" description:"Distinguished name of the certificate subject."`
+	// Issuer is the distinguished name of the issuing authority.
+	Issuer string `json:"issuer" example:"CN=R3,O=Let's Encrypt,C=US" description:"Distinguished name of the certificate authority that issued the certificate. Equal to subject for self-signed certificates."`
+	// NotBefore is the start of the validity period.
+	NotBefore time.Time `json:"not_before" example:"2024-01-01T00:00:00Z" description:"Start of the certificate validity period (RFC3339)."`
+	// NotAfter is when the certificate expires.
+	NotAfter time.Time `json:"not_after" example:"2024-03-31T23:59:59Z" description:"Expiry of the certificate (RFC3339)."`
+	// DNSNames lists the subject alternative names.
+	DNSNames []string `json:"dns_names,omitempty" example:"www.example.com,example.com" description:"DNS subject alternative names the certificate is valid for."`
+}
+
+// TLS layers reported in ScanResult.TLS.
+const (
+	TLSImplicit = "implicit"
+	TLSStartTLS = "starttls"
+)
+
+// startTLSCommands holds the command that upgrades a plaintext service to TLS
+// and the prefix of the reply that accepts it, keyed by service name. SMTP
+// needs an EHLO first, which is sent by startTLS.
+var startTLSCommands = map[string]struct {
+	command, accepted string
+}{
+	"smtp": {"STARTTLS\r\n", "220"},
+	"imap": {"a001 STARTTLS\r\n", "a001 OK"},
+	"pop3": {"STLS\r\n", "+OK"},
+	"ftp":  {"AUTH TLS\r\n", "234"},
+}
+
+// tlsService is a TLS layer found on an open port.
+type tlsService struct {
+	layer  string
+	match  *Match
+	banner string
+	cert   *x509.Certificate
+}
+
+// detectTLS looks for a TLS layer on an open port after plaintext probing
+// found plain (nil when unidentified) and rawBanner. Ports listed by a probe's
+// sslports directive and services that answered like TLS are connected to
+// again over TLS and probed inside the tunnel; services that support
+// STARTTLS are upgraded instead, keeping the plaintext identification. It
+// returns nil when the port speaks no TLS.
+func detectTLS(job ScanJob, address string, plain *Match, rawBanner string, cache *ProbeCache, state *ScanState, timeout time.Duration) *tlsService {
+	serverName := ""
+	if net.ParseIP(job.Host) == nil {
+		serverName = job.Host
+	}
+
+	if plain != nil {
+		if _, ok := startTLSCommands[plain.ServiceName]; ok {
+			cert := startTLS(address, serverName, plain.ServiceName, timeout, state.banner)
+			if cert == nil {
+				return nil
+			}
+			return &tlsService{layer: TLSStartTLS, cert: cert}
+		}
+	}
+	speaksTLS := plain != nil && (plain.ServiceName == "ssl" || plain.ServiceName == "tls")
+	if !speaksTLS && !cache.sslPort(job.Port) && !looksLikeTLS([]byte(rawBanner)) {
+		return nil
+	}
+
+	conn, err := dialTLS(address, serverName, timeout, state.banner)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	service := &tlsService{layer: TLSImplicit, cert: leafCertificate(conn)}
+	match, banner, _ := probeService(conn, cache.TCPProbesFor(job.Port, state.intensity), state.banner)
+	service.match, service.banner = match, banner
+	return service
+}
+
+// dialTLS connects to address and completes a TLS handshake. Certificates are
+// not verified: the point is to see what the service presents.
+func dialTLS(address, serverName string, timeout time.Duration, opts BannerOptions) (*tls.Conn, error) {
+	raw, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := handshakeTLS(raw, serverName, opts)
+	if err != nil {
+		raw.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// handshakeTLS runs a TLS client handshake over raw within the banner read
+// timeout.
+func handshakeTLS(raw net.Conn, serverName string, opts BannerOptions) (*tls.Conn, error) {
+	conn := tls.Client(raw, &tls.Config{ServerName: serverName, InsecureSkipVerify: true})
+	_ = conn.SetDeadline(time.Now().Add(opts.ReadTimeout))
+	if err := conn.Handshake(); err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// startTLS connects to a plaintext service again, asks it to switch to TLS
+// and returns the certificate it presents, or nil when it refuses.
+func startTLS(address, serverName, service string, timeout time.Duration, opts BannerOptions) *x509.Certificate {
+	raw, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil
+	}
+	defer raw.Close()
+
+	// Greeting first, then EHLO for SMTP, then the upgrade command
+	steps := []string{""}
+	if service == "smtp" {
+		steps = append(steps, "EHLO cortex\r\n")
+	}
+	upgrade := startTLSCommands[service]
+	steps = append(steps, upgrade.command)
+	var reply []byte
+	for _, command := range steps {
+		if command != "" {
+			if _, err := raw.Write([]byte(command)); err != nil {
+				return nil
+			}
+		}
+		if reply, err = readBanner(raw, opts); err != nil {
+			return nil
+		}
+	}
+	// Leftovers of a multi-line reply may precede the answer
+	accepted := false
+	for _, line := range strings.Split(string(reply), "\n") {
+		accepted = accepted || strings.HasPrefix(line, upgrade.accepted)
+	}
+	if !accepted {
+		return nil
+	}
+
+	conn, err := handshakeTLS(raw, serverName, opts)
+	if err != nil {
+		return nil
+	}
+	return leafCertificate(conn)
+}
+
+// leafCertificate returns the certificate conn's peer presented first.
+func leafCertificate(conn *tls.Conn) *x509.Certificate {
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil
+	}
+	return certs[0]
+}
+
+// looksLikeTLS reports whether response starts with a TLS record: a handshake
+// or alert of SSLv3 or later, which is what TLS servers send back to
+// plaintext probes.
+func looksLikeTLS(response []byte) bool {
+	return len(response) >= 3 && (response[0] == 0x15 || response[0] == 0x16) && response[1] == 0x03 && response[2] <= 0x04
+}
+
+// apply records the TLS layer in result: the certificate and, for implicit
+// TLS, what was found inside the tunnel in place of the plaintext findings,
+// with an identified service prefixed by "ssl/". It reports whether the
+// service of result is identified by a probe rule afterwards.
+func (t *tlsService) apply(result *ScanResult, identified bool) bool {
+	result.TLS = t.layer
+	if t.cert != nil {
+		result.Certificate = &Certificate{
+			Subject:   t.cert.Subject.String(),
+			Issuer:    t.cert.Issuer.String(),
+			NotBefore: t.cert.NotBefore.UTC(),
+			NotAfter:  t.cert.NotAfter.UTC(),
+			DNSNames:  t.cert.DNSNames,
+		}
+	}
+	if t.layer != TLSImplicit {
+		return identified
+	}
+
+	*result = ScanResult{Host: result.Host, Port: result.Port, State: result.State, TLS: result.TLS, Certificate: result.Certificate}
+	switch {
+	case t.match != nil:
+		result.setMatch(t.match, t.banner)
+		result.Service = "ssl/" + result.Service
+		return true
+	case t.banner != "":
+		result.Service, result.BannerBase64 = bannerText(t.banner)
+	default:
+		result.Service = "ssl"
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package scanner
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/gopacket/layers"
+)
+
+// packetTracer logs every probe sent and every response received through the
+// structured logger, like nmap --packet-trace. Records carry the direction,
+// protocol, endpoints, TCP flags where known, size and round-trip time; the
+// logger adds the timestamp. A nil tracer logs nothing.
+type packetTracer struct {
+	logger *slog.Logger
+}
+
+// newPacketTracer returns a tracer writing to logger, or nil when logger is nil.
+func newPacketTracer(logger *slog.Logger) *packetTracer {
+	if logger == nil {
+		return nil
+	}
+	return &packetTracer{logger: logger}
+}
+
+// sent logs an outgoing probe.
+func (t *packetTracer) sent(protocol, src, dst, flags string, size int, args ...any) {
+	if t == nil {
+		return
+	}
+	t.logger.Info("packet sent", append([]any{"direction", "sent", "protocol", protocol, "src", src, "dst", dst, "flags", flags, "size", size}, args...)...)
+}
+
+// received logs a response to a probe.
+func (t *packetTracer) received(protocol, src, dst, flags string, size int, rtt time.Duration, args ...any) {
+	if t == nil {
+		return
+	}
+	t.logger.Info("packet received", append([]any{"direction", "rcvd", "protocol", protocol, "src", src, "dst", dst, "flags", flags, "size", size, "rtt", rtt}, args...)...)
+}
+
+// silence logs a probe that got no answer before timeout, which is what
+// turns a port Filtered.
+func (t *packetTracer) silence(protocol, dst string, timeout time.Duration) {
+	if t == nil {
+		return
+	}
+	t.logger.Info("no response", "direction", "rcvd", "protocol", protocol, "dst", dst, "timeout", timeout)
+}
+
+// tcpFlags renders the flags of segment in nmap's compact notation, e.g. "SA".
+func tcpFlags(segment *layers.TCP) string {
+	var flags strings.Builder
+	for _, flag := range []struct {
+		set  bool
+		name byte
+	}{
+		{segment.FIN, 'F'}, {segment.SYN, 'S'}, {segment.RST, 'R'}, {segment.PSH, 'P'},
+		{segment.ACK, 'A'}, {segment.URG, 'U'}, {segment.ECE, 'E'}, {segment.CWR, 'C'},
+	} {
+		if flag.set {
+			flags.WriteByte(flag.name)
+		}
+	}
+	return flags.String()
+}
@@ -0,0 +1,21 @@
+package scanner
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records the spans of scans. It does nothing until the program
+// installs a tracer provider, as the API server does when an OTLP endpoint
+// is configured.
+var tracer = otel.Tracer("github.com/Viktor25104/cortex/backend/scanner")
+
+// endSpan marks span failed when err is set and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -4,80 +4,197 @@ import (
 	"errors"
 	"fmt"
 	"net"
-	"strconv"
 	"sync"
 	"time"
 )
 
+// udpNullProbe is sent to ports no UDP probe is registered for: a single
+// null byte that services rarely answer but that still draws ICMP errors
+// from closed ports.
+var udpNullProbe = Probe{Protocol: "UDP", Name: "NULL", Data: []byte{0}}
+
 // UDPWorker processes scan jobs using UDP scan method.
 // Sends UDP probe packets and analyzes responses or ICMP error messages
 // to determine port state. UDP scanning is inherently less reliable than
 // TCP scanning due to the connectionless nature of the protocol.
-// Note: cache parameter is unused in current implementation.
-// Future enhancement: UDP probes from nmap-service-probes could be utilized.
-func UDPWorker(jobs <-chan ScanJob, results chan<- ScanResult, cache *ProbeCache, wg *sync.WaitGroup) {
-	_ = cache // Unused: UDP service detection not yet implemented
+// The UDP probes of cache registered for the port are sent in turn, so that
+// services such as DNS, NTP and SNMP answer protocol-correct payloads and are
+// identified by their match rules; other ports get udpNullProbe.
+func UDPWorker(jobs <-chan ScanJob, results chan<- ScanResult, cache *ProbeCache, state *ScanState, wg *sync.WaitGroup) {
 	for job := range jobs {
-		state := performUdpScan(job.Host, job.Port)
-		result := ScanResult{Host: job.Host, Port: job.Port, State: state}
+		probes := cache.UDPProbesFor(job.Port)
+		if len(probes) == 0 {
+			probes = []Probe{udpNullProbe}
+		}
+		// Silent ports are probed again up to the timing's retries, backing off
+		// exponentially in between
+		var portState string
+		var match *Match
+		var response []byte
+		for attempt := 0; ; attempt++ {
+			if attempt > 0 && !state.retryWait(attempt) {
+				break
+			}
+			hostCtl, timeout, admitted := state.admit(job.target())
+			if !admitted {
+				break
+			}
+			start := time.Now()
+			portState, match, response = performUdpScan(state, job.target(), job.Port, timeout, probes)
+			// Silence is the normal answer from open or filtered UDP ports, so only
+			// definitive answers count as responses for congestion purposes.
+			hostCtl.release(time.Since(start), portState != "Open|Filtered")
+			if portState != "Open|Filtered" || attempt >= state.timing.MaxRetries {
+				break
+			}
+		}
+		if portState == "" {
+			// The scan ended before the port was probed
+			wg.Done()
+			continue
+		}
+
+		result := ScanResult{Host: job.Host, Port: job.Port, State: portState, Address: job.Address, Family: state.resolver.family(job.target())}
+		if match != nil {
+			result.setMatch(match, string(response))
+		} else {
+			if len(response) > 0 {
+				result.Service, result.BannerBase64 = bannerText(string(response))
+			}
+			state.guessService(&result, "udp")
+		}
 		results <- result
 		wg.Done()
 	}
 }
 
 // performUdpScan executes a UDP scan on a single target port.
-// Sends a UDP probe packet and analyzes the response to determine port state.
-// Returns:
-// - "Open": Service responded with data
-// - "Closed": ICMP port unreachable received
-// - "Open|Filtered": No response (timeout) - port may be open or filtered by firewall
-func performUdpScan(host string, port int) string {
-	address := host + ":" + strconv.Itoa(port)
+// Sends the probes one after the other until one draws a response and
+// analyzes it to determine port state.
+// Returns the state, the rule matching the response (nil when unidentified)
+// and the response itself:
+//   - "Open": Service responded with data
+//   - "Closed": ICMP port unreachable received
+//   - "Filtered": ICMP host, protocol or administratively prohibited
+//     unreachable received (only seen with raw packet access, see icmpCapture)
+//   - "Open|Filtered": No response (timeout) - port may be open or filtered by firewall
+//
+// When the scan asks for it the probes, the answer and any ICMP error are
+// recorded to the packet capture and traced.
+func performUdpScan(state *ScanState, host string, port int, timeout time.Duration, probes []Probe) (string, *Match, []byte) {
+	trace := state.trace
+	address, err := state.resolver.dialAddress(host, port)
+	if err != nil {
+		return "Open|Filtered", nil, nil // Unresolvable target - cannot determine port state
+	}
 
 	// Establish UDP connection with timeout
-	conn, err := net.DialTimeout("udp", address, 2*time.Second)
+	conn, err := net.DialTimeout("udp", address, timeout)
 	if err != nil {
 		// Check for timeout error (handles wrapped errors properly)
 		var netErr net.Error
 		if errors.As(err, &netErr) && netErr.Timeout() {
-			return "Open|Filtered"
+			return "Open|Filtered", nil, nil
 		}
 		// Other errors (e.g., ICMP port unreachable) indicate closed port
-		return "Closed"
+		return "Closed", nil, nil
 	}
 	defer conn.Close()
 
-	// Set read deadline for response collection
-	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
-
-	// Send UDP probe packet (single null byte)
-	_, err = conn.Write([]byte{0})
-	if err != nil {
-		return "Open|Filtered"
+	// With raw packet access ICMP errors are read off the wire, so their code
+	// tells closed ports from filtered ones. The same listener records the
+	// probe's traffic when the scan writes a packet capture; without it the
+	// probe is left out of the pcap file.
+	var unreachable chan icmpUnreachable
+	if icmp, err := state.icmpSession(); err == nil {
+		key, answers := icmp.register(conn.LocalAddr().(*net.UDPAddr), conn.RemoteAddr().(*net.UDPAddr))
+		defer icmp.unregister(key)
+		unreachable = answers
 	}
 
-	// Listen for service response or ICMP error messages
-	buffer := make([]byte, 512)
-	n, err := conn.Read(buffer)
+	local, remote := conn.LocalAddr().String(), conn.RemoteAddr().String()
+	buffer := make([]byte, state.banner.MaxBytes)
+	for _, probe := range probes {
+		// Each probe gets the full timeout to be answered
+		_ = conn.SetReadDeadline(time.Now().Add(timeout))
+		if _, err := conn.Write(probe.Data); err != nil {
+			return "Open|Filtered", nil, nil
+		}
+		sentAt := time.Now()
+		trace.sent("udp", local, remote, "", len(probe.Data), "probe", probe.Name)
 
-	if err != nil {
-		// Check for timeout error (handles wrapped errors properly)
-		var netErr net.Error
-		if errors.As(err, &netErr) && netErr.Timeout() {
-			return "Open|Filtered"
+		// Listen for service response or ICMP error messages
+		n, answer, err := readUDPAnswer(conn, buffer, unreachable, timeout)
+		if answer != nil {
+			trace.received(answer.protocol, remote, local, "", answer.size, time.Since(sentAt), "type", answer.kind, "code", answer.code)
+			return answer.state, nil, nil
 		}
-		// Other errors (e.g., ICMP port unreachable) indicate closed port
-		return "Closed"
-	}
+		if err != nil {
+			// Check for timeout error (handles wrapped errors properly)
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				trace.silence("udp", remote, timeout)
+				continue // Silence - try the next probe
+			}
+			// Other errors (e.g., ICMP port unreachable) indicate closed port
+			trace.received("icmp", remote, local, "", 0, time.Since(sentAt), "error", err.Error())
+			return "Closed", nil, nil
+		}
+		trace.received("udp", remote, local, "", n, time.Since(sentAt), "probe", probe.Name)
 
-	// If we received response data, the port is definitively open
-	if n > 0 {
-		return "Open"
+		// If we received response data, the port is definitively open
+		if n > 0 {
+			response := append([]byte(nil), buffer[:n]...)
+			return "Open", probe.FindMatch(response), response
+		}
 	}
 
-	return "Open|Filtered"
+	return "Open|Filtered", nil, nil
+}
+
+// udpRead is the outcome of one read from a UDP socket.
+type udpRead struct {
+	n   int
+	err error
 }
 
+// readUDPAnswer waits for the answer to a probe on conn: a datagram, a socket
+// error or, when unreachable is set, an ICMP error captured off the wire. An
+// error the socket reports is matched with its captured message for up to
+// icmpGrace, as the socket alone cannot say which unreachable code it was.
+func readUDPAnswer(conn net.Conn, buffer []byte, unreachable chan icmpUnreachable, timeout time.Duration) (int, *icmpUnreachable, error) {
+	if unreachable == nil {
+		n, err := conn.Read(buffer)
+		return n, nil, err
+	}
+	reads := make(chan udpRead, 1)
+	go func() {
+		n, err := conn.Read(buffer)
+		reads <- udpRead{n: n, err: err}
+	}()
+	select {
+	case answer := <-unreachable:
+		// Unblock the pending read before the buffer is reused
+		_ = conn.SetReadDeadline(time.Now())
+		<-reads
+		return 0, &answer, nil
+	case read := <-reads:
+		var netErr net.Error
+		if read.err != nil && !(errors.As(read.err, &netErr) && netErr.Timeout()) {
+			select {
+			case answer := <-unreachable:
+				return 0, &answer, nil
+			case <-time.After(min(icmpGrace, timeout)):
+			}
+		}
+		return read.n, nil, read.err
+	}
+}
+
+// icmpGrace is how long a socket error waits for the ICMP message behind it
+// to come through the capture handle.
+const icmpGrace = 100 * time.Millisecond
+
 // InitUdpScan validates that the system meets prerequisites for UDP scanning.
 // Unlike SYN scanning, UDP scanning through net.Dial doesn't require elevated
 // privileges in most cases. Performs basic network capability check.
@@ -0,0 +1,270 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+)
+
+// icmpUnreachable is an ICMP or ICMPv6 destination unreachable message
+// quoting a UDP or TCP probe, with the state its code stands for.
+type icmpUnreachable struct {
+	// protocol is "icmp" or "icmp6", kind the message type of that protocol
+	protocol string
+	kind     uint8
+	code     uint8
+	state    string
+	size     int
+}
+
+// icmpUnreachableStates maps the destination unreachable codes UDP scans act
+// on to a port state, as nmap does: port unreachable comes from the target
+// itself, the others from a host or router refusing to forward the probe.
+var icmpUnreachableStates = map[uint8]string{
+	layers.ICMPv4CodePort:                "Closed",
+	layers.ICMPv4CodeHost:                "Filtered",
+	layers.ICMPv4CodeProtocol:            "Filtered",
+	layers.ICMPv4CodeNetAdminProhibited:  "Filtered",
+	layers.ICMPv4CodeHostAdminProhibited: "Filtered",
+	layers.ICMPv4CodeCommAdminProhibited: "Filtered",
+}
+
+// icmpv6UnreachableStates does the same for ICMPv6 destination unreachable
+// codes.
+var icmpv6UnreachableStates = map[uint8]string{
+	layers.ICMPv6CodePortUnreachable:    "Closed",
+	layers.ICMPv6CodeNoRouteToDst:       "Filtered",
+	layers.ICMPv6CodeAdminProhibited:    "Filtered",
+	layers.ICMPv6CodeAddressUnreachable: "Filtered",
+}
+
+// icmpUnreachableFilter is the BPF expression matching ICMP and ICMPv6
+// destination unreachable messages; ip6[40] is the ICMPv6 type when no
+// extension header precedes it.
+const icmpUnreachableFilter = "icmp[icmptype] == icmp-unreach or (icmp6 and ip6[40] == 1)"
+
+// icmpSession returns the ICMP listener UDP probes of this scan share,
+// opening it on first use. It fails without raw packet access, leaving UDP
+// probes to the errors their sockets report.
+func (s *ScanState) icmpSession() (*icmpCapture, error) {
+	s.icmpOnce.Do(func() {
+		s.icmp, s.icmpErr = openICMPCapture(s.capture)
+	})
+	return s.icmp, s.icmpErr
+}
+
+// icmpCapture receives the ICMP errors addressed to the source address
+// through a single pcap handle and hands each to the UDP probe it quotes.
+// Connected UDP sockets report only some of these errors, and only as an
+// errno that does not tell a closed port from a filtering router. When the
+// scan writes a packet capture, the handle also sees UDP traffic and records
+// the datagrams and ICMP messages of every registered probe, each once.
+type icmpCapture struct {
+	handle   *pcap.Handle
+	recorder *packetRecorder
+	mu       sync.Mutex
+	waiting  map[synKey]chan icmpUnreachable
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+// openICMPCapture opens the capture handle for the source interface, limited
+// to ICMP and ICMPv6 destination unreachable messages, or to the UDP, ICMP
+// and ICMPv6 traffic of the source addresses when recorder is set, and
+// starts its read loop.
+func openICMPCapture(recorder *packetRecorder) (*icmpCapture, error) {
+	if err := checkPacketDriver(); err != nil {
+		return nil, err
+	}
+	src, err := sourceInterface()
+	if err != nil {
+		return nil, err
+	}
+	deviceName, err := captureDevice(src.iface, src.primary())
+	if err != nil {
+		return nil, err
+	}
+	handle, err := pcap.OpenLive(deviceName, captureSnapLen, false, captureReadTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, ErrInsufficientPrivileges)
+	}
+	filter := "(" + icmpUnreachableFilter + ") and " + src.hostFilter("dst ")
+	if recorder != nil {
+		filter = "(udp or icmp or icmp6) and " + src.hostFilter("")
+	}
+	if err := handle.SetBPFFilter(filter); err != nil {
+		handle.Close()
+		return nil, err
+	}
+
+	c := &icmpCapture{
+		handle:   handle,
+		recorder: recorder,
+		waiting:  make(map[synKey]chan icmpUnreachable),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go c.read()
+	return c, nil
+}
+
+// read hands every destination unreachable message to the probe whose
+// datagram it quotes, and records the packets of registered probes, until
+// close is called.
+func (c *icmpCapture) read() {
+	defer close(c.stopped)
+	linkType := c.handle.LinkType()
+	for {
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+		data, info, err := c.handle.ReadPacketData()
+		if err == pcap.NextErrorTimeoutExpired {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		packet := gopacket.NewPacket(data, linkType, gopacket.Default)
+		packet.Metadata().CaptureInfo = info
+		key, message, ok := unreachableMessage(packet, layers.IPProtocolUDP)
+		if !ok {
+			if c.recorder != nil && c.probeDatagram(packet) {
+				c.recorder.recordPacket(packet)
+			}
+			continue
+		}
+		c.mu.Lock()
+		answers := c.waiting[key]
+		c.mu.Unlock()
+		if answers == nil {
+			continue
+		}
+		c.recorder.recordPacket(packet)
+		if message.state != "" {
+			message.size = len(data)
+			select {
+			case answers <- message:
+			default:
+			}
+		}
+	}
+}
+
+// unreachableMessage returns the probe of protocol (UDP or TCP) an ICMP or
+// ICMPv6 error packet quotes and the message, whose state is set when it is
+// a destination unreachable message scans act on. Only UDP probes can be
+// Closed this way: port unreachable answers a datagram nobody listens for,
+// while for a TCP segment every such code means something in between
+// refused it. It reports false for packets that are no ICMP error quoting a
+// probe of protocol.
+func unreachableMessage(packet gopacket.Packet, protocol layers.IPProtocol) (synKey, icmpUnreachable, bool) {
+	var key synKey
+	var message icmpUnreachable
+	var ok bool
+	if icmp, isICMP := packet.Layer(layers.LayerTypeICMPv4).(*layers.ICMPv4); isICMP {
+		key, ok = quotedProbe(icmp.Payload, protocol)
+		message = icmpUnreachable{protocol: "icmp", kind: icmp.TypeCode.Type(), code: icmp.TypeCode.Code()}
+		if message.kind == layers.ICMPv4TypeDestinationUnreachable {
+			message.state = icmpUnreachableStates[message.code]
+		}
+	} else if icmp, isICMP := packet.Layer(layers.LayerTypeICMPv6).(*layers.ICMPv6); isICMP {
+		// The message body starts with four unused bytes before the quote
+		if len(icmp.Payload) < 4 {
+			return synKey{}, icmpUnreachable{}, false
+		}
+		key, ok = quotedProbeV6(icmp.Payload[4:], protocol)
+		message = icmpUnreachable{protocol: "icmp6", kind: icmp.TypeCode.Type(), code: icmp.TypeCode.Code()}
+		if message.kind == layers.ICMPv6TypeDestinationUnreachable {
+			message.state = icmpv6UnreachableStates[message.code]
+		}
+	} else {
+		return synKey{}, icmpUnreachable{}, false
+	}
+	if protocol == layers.IPProtocolTCP && message.state != "" {
+		message.state = "Filtered"
+	}
+	return key, message, ok
+}
+
+// probeDatagram reports whether packet is a UDP datagram a registered probe
+// sent or received.
+func (c *icmpCapture) probeDatagram(packet gopacket.Packet) bool {
+	src, dst := packetAddresses(packet)
+	if src == nil {
+		return false
+	}
+	udp, ok := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+	if !ok {
+		return false
+	}
+	sent := synKey{dstIP: dst.String(), dstPort: uint16(udp.DstPort), srcPort: uint16(udp.SrcPort)}
+	received := synKey{dstIP: src.String(), dstPort: uint16(udp.SrcPort), srcPort: uint16(udp.DstPort)}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.waiting[sent] != nil || c.waiting[received] != nil
+}
+
+// quotedProbe extracts the target address, target port and source port of
+// the UDP datagram or TCP segment, as protocol says, whose IPv4 header and
+// first eight bytes an ICMP error quotes. Both carry the ports first.
+func quotedProbe(quote []byte, protocol layers.IPProtocol) (synKey, bool) {
+	if len(quote) < 20 {
+		return synKey{}, false
+	}
+	headerLen := int(quote[0]&0x0f) * 4
+	if headerLen < 20 || len(quote) < headerLen+4 || layers.IPProtocol(quote[9]) != protocol {
+		return synKey{}, false
+	}
+	return synKey{
+		dstIP:   net.IP(quote[16:20]).String(),
+		srcPort: binary.BigEndian.Uint16(quote[headerLen:]),
+		dstPort: binary.BigEndian.Uint16(quote[headerLen+2:]),
+	}, true
+}
+
+// quotedProbeV6 is quotedProbe for the IPv6 header and first eight bytes of
+// a probe quoted by an ICMPv6 error. Probes with extension headers are not
+// matched.
+func quotedProbeV6(quote []byte, protocol layers.IPProtocol) (synKey, bool) {
+	const headerLen = 40
+	if len(quote) < headerLen+4 || layers.IPProtocol(quote[6]) != protocol {
+		return synKey{}, false
+	}
+	return synKey{
+		dstIP:   net.IP(quote[24:40]).String(),
+		srcPort: binary.BigEndian.Uint16(quote[headerLen:]),
+		dstPort: binary.BigEndian.Uint16(quote[headerLen+2:]),
+	}, true
+}
+
+// register returns the channel the ICMP errors quoting probes from local to
+// remote arrive on.
+func (c *icmpCapture) register(local, remote *net.UDPAddr) (synKey, chan icmpUnreachable) {
+	key := synKey{dstIP: remote.IP.String(), dstPort: uint16(remote.Port), srcPort: uint16(local.Port)}
+	answers := make(chan icmpUnreachable, 1)
+	c.mu.Lock()
+	c.waiting[key] = answers
+	c.mu.Unlock()
+	return key, answers
+}
+
+func (c *icmpCapture) unregister(key synKey) {
+	c.mu.Lock()
+	delete(c.waiting, key)
+	c.mu.Unlock()
+}
+
+// close stops the read loop and releases the capture handle.
+func (c *icmpCapture) close() {
+	close(c.done)
+	<-c.stopped
+	c.handle.Close()
+}
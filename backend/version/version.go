@@ -0,0 +1,59 @@
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata injected at link time, for example:
+//
+//	go build -ldflags "-X github.com/Viktor25104/cortex/backend/version.Version=v1.2.0 -X github.com/Viktor25104/cortex/backend/version.Commit=$(git rev-parse HEAD) -X github.com/Viktor25104/cortex/backend/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// Info describes the running build.
+type Info struct {
+	Version   string `json:"version" example:"v1.2.0" description:"Release version the binary was built from, or dev for local builds."`
+	Commit    string `json:"commit" example:"4f1c2a9e7b3d" description:"Source control revision of the build."`
+	BuildDate string `json:"build_date" example:"2024-01-02T15:04:05Z" description:"UTC timestamp of when the binary was built."`
+	GoVersion string `json:"go_version" example:"go1.24.0" description:"Go toolchain used to compile the binary."`
+	Platform  string `json:"platform" example:"linux/amd64" description:"Operating system and architecture the binary targets."`
+}
+
+// Get returns the build information of the running binary. When the commit was
+// not injected via ldflags it falls back to the VCS stamp recorded by the Go toolchain.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if info.Commit == "unknown" {
+		if build, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range build.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					info.Commit = setting.Value
+				case "vcs.time":
+					if info.BuildDate == "unknown" {
+						info.BuildDate = setting.Value
+					}
+				}
+			}
+		}
+	}
+
+	return info
+}
+
+// String renders the build information on a single line.
+func (i Info) String() string {
+	return fmt.Sprintf("cortex %s (commit %s, built %s, %s %s)", i.Version, i.Commit, i.BuildDate, i.GoVersion, i.Platform)
+}